@@ -1,6 +1,8 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,6 +10,7 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
@@ -328,3 +331,118 @@ func GetRootNodes(c *fiber.Ctx) error {
 	// return the response
 	return c.Status(fiber.StatusOK).JSON(nodes)
 }
+
+// ImportHARHandler imports the entries of a HAR capture into the history of a workspace
+// @Summary Import a HAR file
+// @Description Imports the HTTP requests of a HAR capture, such as the ones exported by browsers, into the history of a workspace so they can be browsed and scanned
+// @Tags History
+// @Accept json
+// @Produce json
+// @Param workspace query int true "Workspace ID"
+// @Param har body object true "HAR document"
+// @Success 200 {object} map[string]interface{} "Returns 'count' (number of imported entries) and 'ids' (IDs of the created history items)"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/history/import/har [post]
+func ImportHARHandler(c *fiber.Ctx) error {
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid workspace",
+			Message: "The provided workspace ID does not seem valid",
+		})
+	}
+
+	histories, err := http_utils.ImportHAR(bytes.NewReader(c.Body()), workspaceID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if len(histories) == 0 {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(ErrorResponse{
+			Error:   "Cannot import HAR file",
+			Message: err.Error(),
+		})
+	}
+
+	ids := make([]uint, 0, len(histories))
+	for _, history := range histories {
+		ids = append(ids, history.ID)
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{"count": len(ids), "ids": ids})
+}
+
+// ExportHARHandler exports the history of a workspace as a HAR file
+// @Summary Export history as a HAR file
+// @Description Exports the history of a workspace as a HAR 1.2 document that can be opened by browsers and other tools. Binary bodies are base64 encoded.
+// @Tags History
+// @Produce json
+// @Param workspace query int true "Workspace ID"
+// @Success 200 {object} map[string]interface{} "HAR document"
+// @Failure 400 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/history/export/har [get]
+func ExportHARHandler(c *fiber.Ctx) error {
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid workspace",
+			Message: "The provided workspace ID does not seem valid",
+		})
+	}
+
+	c.Response().Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.Response().Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=workspace-%d.har", workspaceID))
+	// The history is streamed, so errors once the export has started can only be logged
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := http_utils.StreamHAR(w, workspaceID); err != nil {
+			log.Error().Err(err).Uint("workspace", workspaceID).Msg("Failed to export history to HAR")
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// GetHistoryDiff compares the responses of two history items
+// @Summary Compare two history responses
+// @Description Returns the differences between the responses of two history items: status code, headers added, removed or changed and the lines added and removed from the body
+// @Tags History
+// @Produce json
+// @Param a query int true "ID of the base history item"
+// @Param b query int true "ID of the history item to compare with the base one"
+// @Success 200 {object} http_utils.ResponseDiff
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/history/diff [get]
+func GetHistoryDiff(c *fiber.Ctx) error {
+	histories := make([]*db.History, 0, 2)
+	for _, param := range []string{"a", "b"} {
+		id, err := strconv.ParseUint(c.Query(param), 10, 0)
+		if err != nil || id == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "Invalid history ID",
+				Message: fmt.Sprintf("The provided history ID for '%s' is not valid", param),
+			})
+		}
+		history, err := db.Connection.GetHistoryByID(uint(id))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "History not found",
+				Message: fmt.Sprintf("The history item %d does not exist", id),
+			})
+		}
+		histories = append(histories, history)
+	}
+
+	diff, err := http_utils.DiffResponses(histories[0], histories[1])
+	if err != nil {
+		log.Error().Err(err).Uint("a", histories[0].ID).Uint("b", histories[1].ID).Msg("Failed to compare history responses")
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Cannot compare responses",
+			Message: err.Error(),
+		})
+	}
+	return c.Status(http.StatusOK).JSON(diff)
+}
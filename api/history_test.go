@@ -10,6 +10,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -151,3 +152,52 @@ func TestFindHistoryPost(t *testing.T) {
 	err = db.Connection.DeleteWorkspace(workspace.ID)
 	assert.Nil(t, err)
 }
+
+func TestGetHistoryDiff(t *testing.T) {
+	workspace, err := db.Connection.CreateDefaultWorkspace()
+	assert.Nil(t, err)
+
+	baseline, err := db.Connection.CreateHistory(&db.History{
+		URL:             "https://diff.example.com/item?id=1",
+		Method:          "GET",
+		StatusCode:      200,
+		ResponseHeaders: []byte(`{"Content-Type":["text/html"],"X-Cache":["HIT"]}`),
+		ResponseBody:    []byte("<h1>Item</h1>\n<p>Price: 10</p>"),
+		WorkspaceID:     &workspace.ID,
+	})
+	assert.Nil(t, err)
+	payload, err := db.Connection.CreateHistory(&db.History{
+		URL:             "https://diff.example.com/item?id=1'",
+		Method:          "GET",
+		StatusCode:      500,
+		ResponseHeaders: []byte(`{"Content-Type":["text/plain"],"X-Debug":["1"]}`),
+		ResponseBody:    []byte("<h1>Item</h1>\nunterminated quoted string"),
+		WorkspaceID:     &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	app := fiber.New()
+	app.Get("/api/v1/history/diff", GetHistoryDiff)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/history/diff?a=%d&b=%d", baseline.ID, payload.ID), nil)
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var diff http_utils.ResponseDiff
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&diff))
+	assert.True(t, diff.StatusCodeChanged)
+	assert.Equal(t, map[string][]string{"X-Debug": {"1"}}, diff.HeadersAdded)
+	assert.Equal(t, map[string][]string{"X-Cache": {"HIT"}}, diff.HeadersRemoved)
+	assert.Equal(t, []http_utils.HeaderChange{{Name: "Content-Type", Before: []string{"text/html"}, After: []string{"text/plain"}}}, diff.HeadersChanged)
+	assert.Equal(t, 1, diff.Body.AddedLines)
+	assert.Equal(t, 1, diff.Body.RemovedLines)
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/v1/history/diff?a=%d", baseline.ID), nil)
+	resp, _ = app.Test(req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/v1/history/diff?a=%d&b=999999999", baseline.ID), nil)
+	resp, _ = app.Test(req)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -1,13 +1,23 @@
 package api
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib/auth"
+	"github.com/pyneda/sukyan/pkg/manual"
 	"gorm.io/gorm"
 	"strings"
 
 	"github.com/rs/zerolog/log"
 	"net/http"
+	"strconv"
 )
 
 // FindIssues godoc
@@ -20,53 +30,192 @@ import (
 // @Param task query int false "Task ID"
 // @Param taskjob query int false "Task Job ID"
 // @Param codes query string false "Comma-separated list of issue codes to filter by"
-// @Success 200 {array} db.Issue
+// @Param severity query string false "Comma-separated list of severities to filter by"
+// @Param false_positive query bool false "Filter by the false positive flag"
+// @Param status query string false "Comma-separated list of triage statuses to filter by" Enums(open, confirmed, resolved, ignored, false_positive)
+// @Param assigned_to query string false "Filter by assignee: a user ID, me for the issues assigned to the authenticated user or none for the unassigned ones"
+// @Param code query string false "Issue code to filter by, combined with the codes parameter"
+// @Param url query string false "Filter the issues whose URL contains the value"
+// @Param confidence_min query int false "Minimum confidence of the issues, from 0 to 100"
+// @Param page query int false "Page number, only used along with page_size" default(1)
+// @Param page_size query int false "Page size, all the issues are returned when not provided"
+// @Param sort_by query string false "Field to sort by, by default issues are sorted by severity and title" Enums(id,created_at,updated_at,severity,confidence,title,code,url)
+// @Param sort_order query string false "Sort order" Enums(asc, desc) default("asc")
+// @Success 200 {object} map[string]interface{} "Returns 'data' (array of Issue) and 'count' (total number of records), along with 'page', 'page_size' and 'total_pages' when paginated"
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security ApiKeyAuth
 // @Router /api/v1/issues [get]
 func FindIssues(c *fiber.Ctx) error {
+	filter, errResponse := parseIssueFilter(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	if c.Query("page_size") != "" {
+		var err error
+		filter.Pagination.Page, err = strconv.Atoi(c.Query("page", "1"))
+		if err != nil || filter.Pagination.Page < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "Invalid page",
+				Message: "The provided page number is not valid",
+			})
+		}
+		filter.Pagination.PageSize, err = strconv.Atoi(c.Query("page_size"))
+		if err != nil || filter.Pagination.PageSize < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "Invalid page_size",
+				Message: "The provided page size is not valid",
+			})
+		}
+	}
+
+	issues, count, err := db.Connection.ListIssues(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get issues"})
+	}
+	log.Info().Int64("count", count).Uint("task", filter.TaskID).Msg("Found issues")
+
+	response := fiber.Map{"data": issues, "count": count}
+	if filter.Pagination.PageSize > 0 {
+		// The page size is capped when listing, so it is read back from the filter
+		_, pageSize := filter.Pagination.GetData()
+		response["page"] = filter.Pagination.Page
+		response["page_size"] = pageSize
+		response["total_pages"] = (count + int64(pageSize) - 1) / int64(pageSize)
+	}
+	return c.Status(http.StatusOK).JSON(response)
+}
+
+// parseIssueFilter builds the issue filter from the query parameters shared by the issue list and export endpoints
+func parseIssueFilter(c *fiber.Ctx) (db.IssueFilter, *ErrorResponse) {
 	workspaceID, err := parseWorkspaceID(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid workspace",
-			"message": "The provided workspace ID does not seem valid",
-		})
+		return db.IssueFilter{}, &ErrorResponse{
+			Error:   "Invalid workspace",
+			Message: "The provided workspace ID does not seem valid",
+		}
 	}
 
 	taskID, err := parseTaskID(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid task",
-			"message": "The provided task ID does not seem valid",
-		})
+		return db.IssueFilter{}, &ErrorResponse{
+			Error:   "Invalid task",
+			Message: "The provided task ID does not seem valid",
+		}
 	}
 
 	taskJobID, err := parseTaskJobID(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid task job",
-			"message": "The provided task job ID does not seem valid",
-		})
+		return db.IssueFilter{}, &ErrorResponse{
+			Error:   "Invalid task job",
+			Message: "The provided task job ID does not seem valid",
+		}
+	}
+
+	filter := db.IssueFilter{
+		WorkspaceID: workspaceID,
+		TaskID:      taskID,
+		TaskJobID:   taskJobID,
 	}
 
 	unparsedIssueCodes := c.Query("codes")
-	var issueCodes []string
 	if unparsedIssueCodes != "" {
 		// TODO: Validate issue codes
-		issueCodes = strings.Split(unparsedIssueCodes, ",")
+		filter.Codes = strings.Split(unparsedIssueCodes, ",")
+	}
+	if code := c.Query("code"); code != "" {
+		filter.Codes = append(filter.Codes, code)
 	}
 
-	issues, count, err := db.Connection.ListIssues(db.IssueFilter{
-		WorkspaceID: workspaceID,
-		TaskID:      taskID,
-		TaskJobID:   taskJobID,
-		Codes:       issueCodes,
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get issues"})
+	filter.URL = c.Query("url")
+
+	unparsedMinConfidence := c.Query("confidence_min")
+	if unparsedMinConfidence != "" {
+		minConfidence, err := strconv.Atoi(unparsedMinConfidence)
+		if err != nil || minConfidence < 0 || minConfidence > 100 {
+			return db.IssueFilter{}, &ErrorResponse{
+				Error:   "Invalid confidence",
+				Message: "The confidence_min parameter must be a number between 0 and 100",
+			}
+		}
+		filter.MinConfidence = minConfidence
+	}
+
+	filter.SortBy = c.Query("sort_by")
+	filter.SortOrder = c.Query("sort_order")
+	if err := validator.New().StructPartial(filter, "SortBy", "SortOrder"); err != nil {
+		return db.IssueFilter{}, &ErrorResponse{
+			Error:   "Invalid sorting",
+			Message: "The sort_by or sort_order parameters are not valid",
+		}
+	}
+
+	unparsedSeverities := c.Query("severity")
+	if unparsedSeverities != "" {
+		for _, value := range strings.Split(unparsedSeverities, ",") {
+			severity := db.NewSeverity(strings.TrimSpace(value))
+			if severity == db.Unknown && !strings.EqualFold(strings.TrimSpace(value), db.Unknown.String()) {
+				return db.IssueFilter{}, &ErrorResponse{
+					Error:   "Invalid severity",
+					Message: fmt.Sprintf("The provided severity %s is not valid", value),
+				}
+			}
+			filter.Severities = append(filter.Severities, severity.String())
+		}
+	}
+
+	unparsedFalsePositive := c.Query("false_positive")
+	if unparsedFalsePositive != "" {
+		falsePositive, err := strconv.ParseBool(unparsedFalsePositive)
+		if err != nil {
+			return db.IssueFilter{}, &ErrorResponse{
+				Error:   "Invalid false positive",
+				Message: "The false_positive parameter must be true or false",
+			}
+		}
+		filter.FalsePositive = &falsePositive
+	}
+
+	unparsedStatuses := c.Query("status")
+	if unparsedStatuses != "" {
+		for _, value := range strings.Split(unparsedStatuses, ",") {
+			status := db.IssueStatus(strings.TrimSpace(value))
+			if !status.IsValid() {
+				return db.IssueFilter{}, &ErrorResponse{
+					Error:   "Invalid status",
+					Message: fmt.Sprintf("The provided status %s is not valid", value),
+				}
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	switch assignedTo := c.Query("assigned_to"); assignedTo {
+	case "":
+	case "none":
+		filter.Unassigned = true
+	case "me":
+		claims, err := auth.ExtractTokenMetadata(c)
+		if err != nil {
+			return db.IssueFilter{}, &ErrorResponse{
+				Error:   "Invalid assignee",
+				Message: "The issues assigned to me can only be listed when authenticated",
+			}
+		}
+		filter.AssignedTo = &claims.UserID
+	default:
+		userID, err := uuid.Parse(assignedTo)
+		if err != nil {
+			return db.IssueFilter{}, &ErrorResponse{
+				Error:   "Invalid assignee",
+				Message: "The assigned_to parameter must be a user ID, me or none",
+			}
+		}
+		filter.AssignedTo = &userID
 	}
-	log.Info().Int64("count", count).Uint("task", taskID).Msg("Found issues")
-	return c.Status(http.StatusOK).JSON(fiber.Map{"data": issues, "count": count})
+
+	return filter, nil
 }
 
 // FindIssuesGrouped godoc
@@ -78,6 +227,7 @@ func FindIssues(c *fiber.Ctx) error {
 // @Param workspace query int true "Workspace ID"
 // @Param task query int false "Task ID"
 // @Param taskjob query int false "Task Job ID"
+// @Param group_by query string false "Return issue counts grouped by the provided field instead" Enums(code, host, cwe, severity)
 // @Success 200 {array} db.GroupedIssue
 // @Failure 500 {object} ErrorResponse
 // @Security ApiKeyAuth
@@ -107,11 +257,29 @@ func FindIssuesGrouped(c *fiber.Ctx) error {
 		})
 	}
 
-	issues, err := db.Connection.ListIssuesGrouped(db.IssueFilter{
+	filter := db.IssueFilter{
 		WorkspaceID: workspaceID,
 		TaskID:      taskID,
 		TaskJobID:   taskJobID,
-	})
+	}
+
+	if c.Query("group_by") != "" {
+		groupBy := db.IssueGroupBy(c.Query("group_by"))
+		if !groupBy.IsValid() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid group by",
+				"message": "The group_by parameter must be one of: code, host, cwe, severity",
+			})
+		}
+		groups, err := db.Connection.CountIssuesGroupedBy(filter, groupBy)
+		if err != nil {
+			log.Error().Err(err).Str("group_by", string(groupBy)).Msg("Failed to count issues grouped")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get issues grouped"})
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{"data": groups, "group_by": groupBy})
+	}
+
+	issues, err := db.Connection.ListIssuesGrouped(filter)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get issues grouped")
 
@@ -162,7 +330,7 @@ type IssueUpdateResponse struct {
 
 // SetFalsePositive godoc
 // @Summary Set an issue as a false positive
-// @Description Updates the FalsePositive attribute of a specific issue
+// @Description Changes the status of a specific issue to false positive, or reopens it when it was one and the value is false
 // @Tags Issues
 // @Accept  json
 // @Produce  json
@@ -171,6 +339,7 @@ type IssueUpdateResponse struct {
 // @Success 200 {object} IssueUpdateResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security ApiKeyAuth
 // @Router /api/v1/issues/{id}/set-false-positive [post]
@@ -205,7 +374,18 @@ func SetFalsePositive(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get issue"})
 	}
 
-	err = issue.UpdateFalsePositive(body.Value)
+	var userID *uuid.UUID
+	if claims, err := auth.ExtractTokenMetadata(c); err == nil {
+		userID = &claims.UserID
+	}
+
+	_, err = db.Connection.SetIssueFalsePositive(&issue, body.Value, userID)
+	if errors.Is(err, db.ErrInvalidIssueStatusTransition) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Invalid status transition",
+			"message": fmt.Sprintf("The false positive state of an issue with status %s cannot be changed, reopen it first", issue.Status),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update issue"})
 	}
@@ -215,3 +395,498 @@ func SetFalsePositive(c *fiber.Ctx) error {
 		"issue":   issue,
 	})
 }
+
+type IssueStatusInput struct {
+	Status db.IssueStatus `json:"status" validate:"required,oneof=open confirmed resolved ignored false_positive"`
+	Note   string         `json:"note" validate:"omitempty,max=2048"`
+}
+
+type IssueStatusUpdateResponse struct {
+	Message string                `json:"message"`
+	Issue   db.Issue              `json:"issue"`
+	Change  *db.IssueStatusChange `json:"change"`
+}
+
+// UpdateIssueStatus godoc
+// @Summary Update the triage status of an issue
+// @Description Changes the status of an issue, recording who changed it and when. Closed issues (resolved, ignored or false positive) have to be reopened before changing them to another status.
+// @Tags Issues
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Issue ID"
+// @Param input body IssueStatusInput true "New status and an optional note"
+// @Success 200 {object} IssueStatusUpdateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/{id}/status [post]
+func UpdateIssueStatus(c *fiber.Ctx) error {
+	issueID, err := c.ParamsInt("id")
+	if err != nil || issueID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid issue ID",
+			Message: "The provided issue ID is not valid",
+		})
+	}
+
+	input := new(IssueStatusInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Cannot parse JSON",
+			Message: "The provided JSON is invalid, check the syntax and logs for details",
+		})
+	}
+	if err := validator.New().Struct(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Validation failed",
+			Message: buildValidationErrorMessage(err),
+		})
+	}
+
+	issue, err := db.Connection.GetIssue(issueID, false)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "Issue not found",
+				Message: "The requested issue does not exist",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Failed to get issue",
+		})
+	}
+
+	var userID *uuid.UUID
+	if claims, err := auth.ExtractTokenMetadata(c); err == nil {
+		userID = &claims.UserID
+	}
+
+	change, err := db.Connection.UpdateIssueStatus(&issue, input.Status, userID, input.Note)
+	if errors.Is(err, db.ErrInvalidIssueStatusTransition) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Error:   "Invalid status transition",
+			Message: fmt.Sprintf("The issue status cannot be changed from %s to %s", issue.Status, input.Status),
+		})
+	}
+	if err != nil {
+		log.Error().Err(err).Int("id", issueID).Str("status", string(input.Status)).Msg("Failed to update issue status")
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Failed to update the issue status",
+		})
+	}
+
+	return c.Status(http.StatusOK).JSON(IssueStatusUpdateResponse{
+		Message: "Issue status updated successfully",
+		Issue:   issue,
+		Change:  change,
+	})
+}
+
+type IssueAssignmentInput struct {
+	// UserID is the user to assign the issue to, the issue is unassigned when it is not provided
+	UserID *uuid.UUID `json:"user_id"`
+}
+
+// AssignIssue godoc
+// @Summary Assign an issue to a user
+// @Description Assigns the issue to a user for triage, sending the configured issue notifications, or unassigns it when no user ID is provided
+// @Tags Issues
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Issue ID"
+// @Param input body IssueAssignmentInput true "User to assign the issue to"
+// @Success 200 {object} IssueUpdateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/{id}/assign [post]
+func AssignIssue(c *fiber.Ctx) error {
+	issueID, err := c.ParamsInt("id")
+	if err != nil || issueID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid issue ID",
+			Message: "The provided issue ID is not valid",
+		})
+	}
+
+	input := new(IssueAssignmentInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Cannot parse JSON",
+			Message: "The provided JSON is invalid, check the syntax and logs for details",
+		})
+	}
+
+	issue, err := db.Connection.GetIssue(issueID, false)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "Issue not found",
+				Message: "The requested issue does not exist",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Failed to get issue",
+		})
+	}
+
+	if err := db.Connection.AssignIssue(&issue, input.UserID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "Invalid user",
+				Message: "The provided user does not exist",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Failed to assign the issue",
+		})
+	}
+
+	message := "Issue assigned successfully"
+	if input.UserID == nil {
+		message = "Issue unassigned successfully"
+	}
+	return c.Status(http.StatusOK).JSON(IssueUpdateResponse{
+		Message: message,
+		Issue:   issue,
+	})
+}
+
+// GetIssueStatusChanges godoc
+// @Summary Get the status changes of an issue
+// @Description Retrieves the audit trail of the issue triage status, the most recent changes first
+// @Tags Issues
+// @Produce  json
+// @Param id path int true "Issue ID"
+// @Success 200 {array} db.IssueStatusChange
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/{id}/status-changes [get]
+func GetIssueStatusChanges(c *fiber.Ctx) error {
+	issueID, err := c.ParamsInt("id")
+	if err != nil || issueID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid issue ID",
+			Message: "The provided issue ID is not valid",
+		})
+	}
+
+	changes, err := db.Connection.ListIssueStatusChanges(uint(issueID))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Failed to get the issue status changes",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(changes)
+}
+
+// MinimizeIssue godoc
+// @Summary Generate a minimal proof of concept request for an issue
+// @Description Iteratively prunes the headers and parameters of the issue request, returning the smallest request that still reproduces the issue
+// @Tags Issues
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Issue ID"
+// @Success 200 {object} manual.MinimizationResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/{id}/minimize [post]
+func MinimizeIssue(c *fiber.Ctx) error {
+	issueID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid issue ID",
+			"message": "The provided issue ID is not valid",
+		})
+	}
+
+	issue, err := db.Connection.GetIssue(issueID, false)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   "Issue not found",
+				"message": "The requested issue does not exist",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get issue"})
+	}
+
+	result, err := manual.MinimizeIssue(&issue)
+	if err != nil {
+		log.Error().Err(err).Int("id", issueID).Msg("Failed to minimize issue request")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Minimization failed",
+			"message": err.Error(),
+		})
+	}
+
+	return c.Status(http.StatusOK).JSON(result)
+}
+
+// RevalidateIssue godoc
+// @Summary Revalidate an issue
+// @Description Sends the request that detected the issue again and checks if it still reproduces, recomputing its confidence or marking it as resolved when it does not
+// @Tags Issues
+// @Produce  json
+// @Param id path int true "Issue ID"
+// @Success 200 {object} manual.IssueRevalidationResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/{id}/revalidate [post]
+func RevalidateIssue(c *fiber.Ctx) error {
+	issueID, err := c.ParamsInt("id")
+	if err != nil || issueID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid issue ID",
+			"message": "The provided issue ID is not valid",
+		})
+	}
+
+	result, err := manual.RevalidateIssue(uint(issueID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   "Issue not found",
+				"message": "The requested issue does not exist",
+			})
+		}
+		if errors.Is(err, manual.ErrRevalidationNotSupported) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Revalidation not supported",
+				"message": err.Error(),
+			})
+		}
+		log.Error().Err(err).Int("id", issueID).Msg("Failed to revalidate issue")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Revalidation failed",
+			"message": err.Error(),
+		})
+	}
+
+	return c.Status(http.StatusOK).JSON(result)
+}
+
+// IssueEvidence is a request and response demonstrating an issue
+type IssueEvidence struct {
+	HistoryID   *uint  `json:"history_id,omitempty"`
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	StatusCode  int    `json:"status_code"`
+	Request     string `json:"request"`
+	Response    string `json:"response"`
+	CURLCommand string `json:"curl_command"`
+}
+
+// getIssueEvidence returns the evidence of the issue from its linked history items, falling back to the request and
+// response stored in the issue itself
+func getIssueEvidence(issue *db.Issue) []IssueEvidence {
+	evidence := make([]IssueEvidence, 0, len(issue.Requests))
+	for i := range issue.Requests {
+		history := &issue.Requests[i]
+		curl, err := history.CurlCommand()
+		if err != nil {
+			log.Warn().Err(err).Uint("history", history.ID).Uint("issue", issue.ID).Msg("Could not build the curl command of the issue evidence")
+		}
+		evidence = append(evidence, IssueEvidence{
+			HistoryID:   &history.ID,
+			Method:      history.Method,
+			URL:         history.URL,
+			StatusCode:  history.StatusCode,
+			Request:     string(history.RawRequest),
+			Response:    string(history.RawResponse),
+			CURLCommand: curl,
+		})
+	}
+	if len(evidence) == 0 && (len(issue.Request) > 0 || len(issue.Response) > 0 || issue.CURLCommand != "") {
+		evidence = append(evidence, IssueEvidence{
+			Method:      issue.HTTPMethod,
+			URL:         issue.URL,
+			StatusCode:  issue.StatusCode,
+			Request:     string(issue.Request),
+			Response:    string(issue.Response),
+			CURLCommand: issue.CURLCommand,
+		})
+	}
+	return evidence
+}
+
+// GetIssueEvidence godoc
+// @Summary Get the evidence of an issue
+// @Description Returns the raw requests and responses, including their headers, of the history items linked to an issue. Use format=raw to get them as plain text or format=curl to get runnable curl commands.
+// @Tags Issues
+// @Produce  json,plain
+// @Param id path int true "Issue ID"
+// @Param format query string false "Output format" Enums(json, raw, curl) default(json)
+// @Success 200 {array} IssueEvidence
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/{id}/evidence [get]
+func GetIssueEvidence(c *fiber.Ctx) error {
+	issueID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid issue ID",
+			"message": "The provided issue ID is not valid",
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "raw" && format != "curl" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid format",
+			"message": "The format must be one of json, raw or curl",
+		})
+	}
+
+	issue, err := db.Connection.GetIssue(issueID, true)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   "Issue not found",
+				"message": "The requested issue does not exist",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get issue"})
+	}
+
+	evidence := getIssueEvidence(&issue)
+	if len(evidence) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Evidence not found",
+			"message": "The requested issue does not have any request or response linked",
+		})
+	}
+
+	switch format {
+	case "raw":
+		var builder strings.Builder
+		for i, item := range evidence {
+			if i > 0 {
+				builder.WriteString("\n\n")
+			}
+			builder.WriteString(item.Request)
+			builder.WriteString("\n\n")
+			builder.WriteString(item.Response)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.Status(http.StatusOK).SendString(builder.String())
+	case "curl":
+		commands := make([]string, 0, len(evidence))
+		for _, item := range evidence {
+			if item.CURLCommand != "" {
+				commands = append(commands, item.CURLCommand)
+			}
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.Status(http.StatusOK).SendString(strings.Join(commands, "\n"))
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{"data": evidence, "count": len(evidence)})
+}
+
+// ExportIssuesSARIF godoc
+// @Summary Export issues as SARIF
+// @Description Exports the issues of a workspace as a SARIF 2.1.0 document, which can be uploaded to GitHub code scanning and other SARIF consumers
+// @Tags Issues
+// @Produce  json
+// @Param workspace query int true "Workspace ID"
+// @Success 200 {object} db.SARIFLog
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/export/sarif [get]
+func ExportIssuesSARIF(c *fiber.Ctx) error {
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid workspace",
+			"message": "The provided workspace ID does not seem valid",
+		})
+	}
+
+	data, err := db.Connection.ExportIssuesSARIF(workspaceID)
+	if err != nil {
+		log.Error().Err(err).Uint("workspace", workspaceID).Msg("Failed to export issues as SARIF")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export issues"})
+	}
+	c.Set(fiber.HeaderContentType, "application/sarif+json")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=sukyan-workspace-%d.sarif", workspaceID))
+	return c.Status(http.StatusOK).Send(data)
+}
+
+// ExportIssuesCSV godoc
+// @Summary Export issues as CSV
+// @Description Streams the issues of a workspace as CSV, including the code, title, severity, url, confidence, cwe and created_at columns
+// @Tags Issues
+// @Produce  text/csv
+// @Param workspace query int true "Workspace ID"
+// @Param task query int false "Task ID"
+// @Param taskjob query int false "Task Job ID"
+// @Param codes query string false "Comma-separated list of issue codes to filter by"
+// @Param severity query string false "Comma-separated list of severities to filter by"
+// @Param false_positive query bool false "Filter by the false positive flag"
+// @Param url query string false "Filter the issues whose URL contains the value"
+// @Param confidence_min query int false "Minimum confidence of the issues, from 0 to 100"
+// @Success 200 {string} string "CSV document"
+// @Failure 400 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/export/csv [get]
+func ExportIssuesCSV(c *fiber.Ctx) error {
+	return streamIssuesExport(c, "text/csv", "csv", db.NewIssueCSVWriter)
+}
+
+// ExportIssuesJSON godoc
+// @Summary Export issues as JSON
+// @Description Streams the issues of a workspace as a JSON array
+// @Tags Issues
+// @Produce  json
+// @Param workspace query int true "Workspace ID"
+// @Param task query int false "Task ID"
+// @Param taskjob query int false "Task Job ID"
+// @Param codes query string false "Comma-separated list of issue codes to filter by"
+// @Param severity query string false "Comma-separated list of severities to filter by"
+// @Param false_positive query bool false "Filter by the false positive flag"
+// @Param url query string false "Filter the issues whose URL contains the value"
+// @Param confidence_min query int false "Minimum confidence of the issues, from 0 to 100"
+// @Success 200 {array} db.Issue
+// @Failure 400 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/export/json [get]
+func ExportIssuesJSON(c *fiber.Ctx) error {
+	return streamIssuesExport(c, fiber.MIMEApplicationJSON, "json", db.NewIssueJSONWriter)
+}
+
+func streamIssuesExport(c *fiber.Ctx, contentType, extension string, newWriter func(io.Writer) (db.IssueWriter, error)) error {
+	filter, errResponse := parseIssueFilter(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=sukyan-workspace-%d-issues.%s", filter.WorkspaceID, extension))
+	// The issues are streamed, so errors once the export has started can only be logged
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writer, err := newWriter(w)
+		if err == nil {
+			err = db.Connection.StreamIssues(filter, writer)
+		}
+		if err != nil {
+			log.Error().Err(err).Uint("workspace", filter.WorkspaceID).Str("format", extension).Msg("Failed to export issues")
+		}
+		w.Flush()
+	})
+	return nil
+}
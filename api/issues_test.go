@@ -1,11 +1,21 @@
 package api
 
 import (
+	"encoding/json"
+	"html"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib/auth"
+	"github.com/pyneda/sukyan/pkg/manual"
+	"github.com/spf13/viper"
 
 	"fmt"
 
@@ -52,3 +62,421 @@ func TestGetIssueDetail(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
 }
+
+func TestFindIssuesGroupedByHost(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/issues/grouped", FindIssuesGrouped)
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "issues-grouped-by-host-test",
+		Title:       "issues grouped by host test workspace",
+		Description: "Workspace for issue grouping tests",
+	})
+	assert.Nil(t, err)
+
+	urls := []string{
+		"https://a.example.com/login",
+		"https://a.example.com/search?q=1",
+		"https://b.example.com/",
+	}
+	for _, url := range urls {
+		issue := db.GetIssueTemplateByCode(db.XssReflectedCode)
+		issue.URL = url
+		issue.WorkspaceID = &workspace.ID
+		_, err := db.Connection.CreateIssue(*issue)
+		assert.Nil(t, err)
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/issues/grouped?workspace=%d&group_by=host", workspace.ID), nil)
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	var result struct {
+		Data []db.IssueGroupCount `json:"data"`
+	}
+	err = json.Unmarshal(body, &result)
+	assert.Nil(t, err)
+
+	counts := make(map[string]int64)
+	for _, group := range result.Data {
+		counts[group.Key] = group.Count
+	}
+	assert.Equal(t, int64(2), counts["a.example.com"])
+	assert.Equal(t, int64(1), counts["b.example.com"])
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/v1/issues/grouped?workspace=%d&group_by=invalid", workspace.ID), nil)
+	resp, _ = app.Test(req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestFindIssuesFiltersAndPagination(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/issues", FindIssues)
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "find-issues-filters-test",
+		Title:       "find issues filters test workspace",
+		Description: "Workspace for issue filtering tests",
+	})
+	assert.Nil(t, err)
+
+	issues := []struct {
+		code          db.IssueCode
+		url           string
+		confidence    int
+		falsePositive bool
+	}{
+		{db.SqlInjectionCode, "https://filters.example.com/products?id=1", 90, false},
+		{db.SqlInjectionCode, "https://filters.example.com/search?q=1", 50, false},
+		{db.XssReflectedCode, "https://filters.example.com/search?q=2", 80, false},
+		{db.XssReflectedCode, "https://other.example.com/", 100, true},
+	}
+	for _, item := range issues {
+		issue := db.GetIssueTemplateByCode(item.code)
+		issue.URL = item.url
+		issue.Confidence = item.confidence
+		issue.FalsePositive = item.falsePositive
+		issue.WorkspaceID = &workspace.ID
+		_, err := db.Connection.CreateIssue(*issue)
+		assert.Nil(t, err)
+	}
+
+	type findIssuesResponse struct {
+		Data       []db.Issue `json:"data"`
+		Count      int64      `json:"count"`
+		Page       int        `json:"page"`
+		PageSize   int        `json:"page_size"`
+		TotalPages int64      `json:"total_pages"`
+	}
+	find := func(query string) (int, findIssuesResponse) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/issues?workspace=%d&%s", workspace.ID, query), nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		var result findIssuesResponse
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp.StatusCode, result
+	}
+
+	status, result := find(fmt.Sprintf("code=%s", db.SqlInjectionCode))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(2), result.Count)
+	for _, issue := range result.Data {
+		assert.Equal(t, string(db.SqlInjectionCode), issue.Code)
+	}
+
+	status, result = find("url=/search")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(2), result.Count)
+
+	// LIKE wildcards are matched literally
+	for _, query := range []string{"url=%25", "url=q_1", "url=" + url.QueryEscape("search?q=%")} {
+		status, result = find(query)
+		assert.Equal(t, http.StatusOK, status, query)
+		assert.Equal(t, int64(0), result.Count, query)
+	}
+
+	status, result = find("confidence_min=80&false_positive=false")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(2), result.Count)
+
+	status, result = find("sort_by=confidence&sort_order=desc")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, result.Data, 4)
+	assert.Equal(t, 100, result.Data[0].Confidence)
+	assert.Equal(t, 50, result.Data[3].Confidence)
+	assert.Equal(t, 0, result.PageSize, "Pagination metadata is only returned when paginating")
+
+	status, result = find("sort_by=confidence&sort_order=asc&page=2&page_size=3")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(4), result.Count)
+	assert.Len(t, result.Data, 1)
+	assert.Equal(t, 100, result.Data[0].Confidence)
+	assert.Equal(t, 2, result.Page)
+	assert.Equal(t, 3, result.PageSize)
+	assert.Equal(t, int64(2), result.TotalPages)
+
+	for _, query := range []string{"sort_by=password", "sort_order=random", "confidence_min=101", "page_size=abc", "page=0&page_size=10"} {
+		status, _ = find(query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
+
+func TestGetIssueEvidence(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/issues/:id/evidence", GetIssueEvidence)
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "issue-evidence-test",
+		Title:       "issue evidence test workspace",
+		Description: "Workspace for issue evidence tests",
+	})
+	assert.Nil(t, err)
+
+	rawRequest := "POST /login HTTP/1.1\r\nHost: evidence.example.com\r\nContent-Type: application/x-www-form-urlencoded\r\n\r\nuser=admin'--"
+	rawResponse := "HTTP/1.1 500 Internal Server Error\r\nContent-Type: text/html\r\n\r\nSQL syntax error"
+	history, err := db.Connection.CreateHistory(&db.History{
+		URL:            "https://evidence.example.com/login",
+		Method:         "POST",
+		StatusCode:     500,
+		RequestHeaders: []byte(`{"Content-Type":["application/x-www-form-urlencoded"],"Host":["evidence.example.com"]}`),
+		RequestBody:    []byte("user=admin'--"),
+		RawRequest:     []byte(rawRequest),
+		RawResponse:    []byte(rawResponse),
+		WorkspaceID:    &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	issue := db.GetIssueTemplateByCode(db.SqlInjectionCode)
+	issue.URL = history.URL
+	issue.WorkspaceID = &workspace.ID
+	issue.Requests = []db.History{*history}
+	createdIssue, err := db.Connection.CreateIssue(*issue)
+	assert.Nil(t, err)
+
+	get := func(query string) (int, string) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/issues/%d/evidence%s", createdIssue.ID, query), nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("")
+	assert.Equal(t, http.StatusOK, status)
+	var result struct {
+		Data  []IssueEvidence `json:"data"`
+		Count int             `json:"count"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(body), &result))
+	if assert.Len(t, result.Data, 1) {
+		assert.Equal(t, history.ID, *result.Data[0].HistoryID)
+		assert.Equal(t, rawRequest, result.Data[0].Request)
+		assert.Equal(t, rawResponse, result.Data[0].Response)
+	}
+
+	status, body = get("?format=raw")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, rawRequest+"\n\n"+rawResponse, body)
+
+	status, body = get("?format=curl")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `curl -i -s -k --path-as-is -X 'POST' 'https://evidence.example.com/login' -H 'Content-Type: application/x-www-form-urlencoded' --data-binary 'user=admin'\''--'`, body)
+
+	status, _ = get("?format=xml")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	req := httptest.NewRequest("GET", "/api/v1/issues/999999/evidence", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestRevalidateIssue(t *testing.T) {
+	payload := "<script>alert(1)</script>"
+	var encode atomic.Bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		query := r.URL.Query().Get("q")
+		if encode.Load() {
+			query = html.EscapeString(query)
+		}
+		fmt.Fprintf(w, "<p>Results for %s</p>", query)
+	}))
+	defer target.Close()
+
+	app := fiber.New()
+	app.Post("/api/v1/issues/:id/revalidate", RevalidateIssue)
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "issue-revalidation-test",
+		Title:       "issue revalidation test workspace",
+		Description: "Workspace for issue revalidation tests",
+	})
+	assert.Nil(t, err)
+	history, err := db.Connection.CreateHistory(&db.History{
+		URL:            target.URL + "/search?q=" + url.QueryEscape(payload),
+		Method:         "GET",
+		StatusCode:     200,
+		RequestHeaders: []byte(`{"Accept":["text/html"]}`),
+		WorkspaceID:    &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	issue := db.GetIssueTemplateByCode(db.XssReflectedCode)
+	issue.URL = history.URL
+	issue.Payload = payload
+	issue.Confidence = 60
+	issue.WorkspaceID = &workspace.ID
+	issue.Requests = []db.History{*history}
+	createdIssue, err := db.Connection.CreateIssue(*issue)
+	assert.Nil(t, err)
+
+	revalidate := func(issueID uint) (int, manual.IssueRevalidationResult) {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/issues/%d/revalidate", issueID), nil)
+		resp, err := app.Test(req, 10000)
+		assert.Nil(t, err)
+		var result manual.IssueRevalidationResult
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp.StatusCode, result
+	}
+
+	status, result := revalidate(createdIssue.ID)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, result.Reproduced)
+	assert.False(t, result.Resolved)
+	assert.Equal(t, 60, result.PreviousConfidence)
+	assert.Equal(t, 90, result.Confidence)
+	updated, err := db.Connection.GetIssue(int(createdIssue.ID), false)
+	assert.Nil(t, err)
+	assert.Equal(t, 90, updated.Confidence)
+	assert.Equal(t, db.IssueStatusOpen, updated.Status)
+	assert.NotNil(t, updated.RevalidatedAt)
+
+	encode.Store(true)
+	status, result = revalidate(createdIssue.ID)
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, result.Reproduced)
+	assert.True(t, result.Resolved)
+	assert.Equal(t, 90, result.Confidence)
+	updated, err = db.Connection.GetIssue(int(createdIssue.ID), false)
+	assert.Nil(t, err)
+	assert.Equal(t, db.IssueStatusResolved, updated.Status)
+	changes, err := db.Connection.ListIssueStatusChanges(createdIssue.ID)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, db.IssueStatusOpen, changes[0].FromStatus)
+	assert.Equal(t, db.IssueStatusResolved, changes[0].ToStatus)
+
+	// Issues without a revalidation check for their code are not revalidated
+	unsupported := db.GetIssueTemplateByCode(db.UnencryptedWebsocketConnectionCode)
+	unsupported.URL = history.URL
+	unsupported.WorkspaceID = &workspace.ID
+	unsupported.Requests = []db.History{*history}
+	createdUnsupported, err := db.Connection.CreateIssue(*unsupported)
+	assert.Nil(t, err)
+	status, _ = revalidate(createdUnsupported.ID)
+	assert.Equal(t, http.StatusBadRequest, status)
+	updated, err = db.Connection.GetIssue(int(createdUnsupported.ID), false)
+	assert.Nil(t, err)
+	assert.Nil(t, updated.RevalidatedAt)
+
+	status, _ = revalidate(999999999)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestUpdateIssueStatus(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/issues/:id/status", UpdateIssueStatus)
+	app.Get("/api/v1/issues/:id/status-changes", GetIssueStatusChanges)
+
+	issue, err := db.Connection.CreateIssue(*db.GetIssueTemplateByCode(db.OsCmdInjectionCode))
+	assert.Nil(t, err)
+	assert.Equal(t, db.IssueStatusOpen, issue.Status)
+
+	updateStatus := func(id uint, body string) *http.Response {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/issues/%d/status", id), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		return resp
+	}
+
+	resp := updateStatus(issue.ID, `{"status": "ignored", "note": "Accepted risk"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response IssueStatusUpdateResponse
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, db.IssueStatusIgnored, response.Issue.Status)
+	assert.Equal(t, db.IssueStatusOpen, response.Change.FromStatus)
+	assert.Equal(t, "Accepted risk", response.Change.Note)
+
+	// Ignored issues have to be reopened first
+	resp = updateStatus(issue.ID, `{"status": "confirmed"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = updateStatus(issue.ID, `{"status": "closed"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = updateStatus(999999999, `{"status": "open"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/issues/%d/status-changes", issue.ID), nil)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var changes []db.IssueStatusChange
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&changes))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, db.IssueStatusIgnored, changes[0].ToStatus)
+}
+
+func TestAssignIssueAndListAssignedToMe(t *testing.T) {
+	viper.Set("api.auth.jwt_secret_key", "issue-assignment-test-secret")
+	viper.Set("api.auth.jwt_secret_expire_minutes", 5)
+	app := fiber.New()
+	app.Post("/api/v1/issues/:id/assign", AssignIssue)
+	app.Get("/api/v1/issues", FindIssues)
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "issue-assignment-test",
+		Title:       "issue assignment test workspace",
+		Description: "Workspace for issue assignment tests",
+	})
+	assert.Nil(t, err)
+	user, err := db.Connection.CreateUser(&db.User{Email: "triage-" + uuid.NewString() + "@example.com", Active: true})
+	assert.Nil(t, err)
+	tokens, err := auth.GenerateNewTokens(user.ID.String(), []string{})
+	assert.Nil(t, err)
+
+	template := db.GetIssueTemplateByCode(db.OpenRedirectCode)
+	template.URL = "https://example.com/redirect?next=/"
+	template.WorkspaceID = &workspace.ID
+	issue, err := db.Connection.CreateIssue(*template)
+	assert.Nil(t, err)
+
+	assign := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/issues/%d/assign", issue.ID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		return resp
+	}
+	listAssignedToMe := func() []db.Issue {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/issues?workspace=%d&assigned_to=me", workspace.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.Access)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var response struct {
+			Data []db.Issue `json:"data"`
+		}
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&response))
+		return response.Data
+	}
+
+	resp := assign(fmt.Sprintf(`{"user_id": "%s"}`, user.ID))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response IssueUpdateResponse
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, user.ID, *response.Issue.AssignedToUserID)
+
+	assignedToMe := listAssignedToMe()
+	assert.Len(t, assignedToMe, 1)
+	assert.Equal(t, issue.ID, assignedToMe[0].ID)
+
+	resp = assign(fmt.Sprintf(`{"user_id": "%s"}`, uuid.New()))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = assign(`{"user_id": null}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, listAssignedToMe())
+
+	// Listing the issues assigned to me requires authentication
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/issues?workspace=%d&assigned_to=me", workspace.ID), nil)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
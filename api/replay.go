@@ -102,3 +102,60 @@ func ReplayRequest(c *fiber.Ctx) error {
 
 	return c.JSON(result)
 }
+
+// ReplayHistoryItem godoc
+// @Summary Replays a history item request
+// @Description Sends the request of a history item again, optionally modifying its method, URL, headers and body, stores the response as a new history item and returns a summary of it
+// @Tags History
+// @Accept  json
+// @Produce  json
+// @Param id path int true "History ID"
+// @Param input body manual.HistoryReplayOptions false "Changes to apply to the original request"
+// @Success 200 {object} manual.HistoryReplayResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/history/{id}/replay [post]
+func ReplayHistoryItem(c *fiber.Ctx) error {
+	historyID, err := c.ParamsInt("id")
+	if err != nil || historyID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid history ID",
+			Message: "The provided history ID is not valid",
+		})
+	}
+
+	input := new(manual.HistoryReplayOptions)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Cannot parse JSON body",
+			})
+		}
+	}
+	if err := validate.Struct(input); err != nil {
+		log.Error().Err(err).Msg("Invalid history replay input")
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid input",
+			Message: "The provided input is not valid",
+		})
+	}
+
+	history, err := db.Connection.GetHistoryByID(uint(historyID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Error:   "History not found",
+			Message: "The requested history item does not exist",
+		})
+	}
+
+	result, err := manual.ReplayHistory(history, *input)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Request Replay Failed",
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(result)
+}
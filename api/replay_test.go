@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/manual"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayHistoryItem(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/echo", http.StatusFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.Path, r.Header.Get("X-Replay"), body)
+	}))
+	defer target.Close()
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "history-replay-test",
+		Title:       "history replay test workspace",
+		Description: "Workspace for history replay tests",
+	})
+	assert.Nil(t, err)
+	original, err := db.Connection.CreateHistory(&db.History{
+		URL:            target.URL + "/echo",
+		Method:         "GET",
+		StatusCode:     200,
+		RequestHeaders: []byte(`{"X-Replay":["original"]}`),
+		WorkspaceID:    &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	app := fiber.New()
+	app.Post("/api/v1/history/:id/replay", ReplayHistoryItem)
+	replay := func(historyID uint, input map[string]interface{}) (int, manual.HistoryReplayResult) {
+		payload, _ := json.Marshal(input)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/history/%d/replay", historyID), bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, 10000)
+		assert.Nil(t, err)
+		var result manual.HistoryReplayResult
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp.StatusCode, result
+	}
+
+	status, result := replay(original.ID, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, original.ID, result.OriginalHistoryID)
+	assert.NotEqual(t, original.ID, result.HistoryID)
+	replayed, err := db.Connection.GetHistoryByID(result.HistoryID)
+	assert.Nil(t, err)
+	assert.Equal(t, "GET /echo original ", string(replayed.ResponseBody))
+	assert.Equal(t, db.SourceRepeater, replayed.Source)
+	assert.Equal(t, workspace.ID, *replayed.WorkspaceID)
+
+	status, result = replay(original.ID, map[string]interface{}{
+		"method":  "POST",
+		"headers": map[string][]string{"X-Replay": {"modified"}, "Content-Type": {"text/plain"}},
+		"body":    "payload",
+	})
+	assert.Equal(t, http.StatusOK, status)
+	replayed, err = db.Connection.GetHistoryByID(result.HistoryID)
+	assert.Nil(t, err)
+	assert.Equal(t, "POST", replayed.Method)
+	assert.Equal(t, []byte("payload"), replayed.RequestBody)
+	assert.Equal(t, "POST /echo modified payload", string(replayed.ResponseBody))
+
+	status, result = replay(original.ID, map[string]interface{}{"url": target.URL + "/redirect"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusFound, result.StatusCode)
+
+	status, result = replay(original.ID, map[string]interface{}{"url": target.URL + "/redirect", "follow_redirects": true})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, target.URL+"/echo", result.URL)
+
+	status, _ = replay(original.ID, map[string]interface{}{"url": "not a url"})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = replay(999999999, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
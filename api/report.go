@@ -3,10 +3,12 @@ package api
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/pkg/report"
 	"github.com/rs/zerolog/log"
 )
@@ -15,7 +17,7 @@ import (
 type ReportRequest struct {
 	WorkspaceID   uint                `json:"workspace_id" validate:"required"`
 	Title         string              `json:"title" validate:"required"`
-	Format        report.ReportFormat `json:"format" validate:"required,oneof=html json"`
+	Format        report.ReportFormat `json:"format" validate:"required,oneof=html json pdf md standalone-html"`
 	MinConfidence int                 `json:"min_confidence" validate:"omitempty"`
 }
 
@@ -88,17 +90,136 @@ func ReportHandler(c *fiber.Ctx) error {
 	}
 
 	// Set the content type based on the report format
-	contentType := "text/html"
-	fileExtension := "html"
-	if input.Format == report.ReportFormatJSON {
-		contentType = "application/json"
-		fileExtension = "json"
-	}
-	c.Response().Header.Set(fiber.HeaderContentType, contentType)
+	c.Response().Header.Set(fiber.HeaderContentType, input.Format.ContentType())
 
 	// Make the file downloadable
-	filename := "report." + fileExtension
+	filename := "report." + input.Format.Extension()
 	c.Response().Header.Set("Content-Disposition", "attachment; filename="+filename)
 
 	return c.Send(buf.Bytes())
 }
+
+// WorkspacePDFReportHandler godoc
+// @Summary Generate a workspace PDF report
+// @Description Generates a PDF report summarizing the issues of a workspace grouped by severity, excluding false positives
+// @Tags Reports
+// @Produce application/pdf
+// @Param id path int true "Workspace ID"
+// @Param title query string false "Report title"
+// @Param min_confidence query int false "Minimum issue confidence"
+// @Param min_severity query string false "Minimum issue severity" Enums(Critical, High, Medium, Low, Info, Unknown)
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/workspaces/{id}/report.pdf [get]
+func WorkspacePDFReportHandler(c *fiber.Ctx) error {
+	return generateWorkspaceReport(c, report.ReportFormatPDF)
+}
+
+// WorkspaceMarkdownReportHandler godoc
+// @Summary Generate a workspace Markdown report
+// @Description Generates a Markdown report summarizing the issues of a workspace grouped by severity, excluding false positives
+// @Tags Reports
+// @Produce text/markdown
+// @Param id path int true "Workspace ID"
+// @Param title query string false "Report title"
+// @Param min_confidence query int false "Minimum issue confidence"
+// @Param min_severity query string false "Minimum issue severity" Enums(Critical, High, Medium, Low, Info, Unknown)
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/workspaces/{id}/report.md [get]
+func WorkspaceMarkdownReportHandler(c *fiber.Ctx) error {
+	return generateWorkspaceReport(c, report.ReportFormatMarkdown)
+}
+
+// WorkspaceHTMLReportHandler godoc
+// @Summary Generate a workspace HTML report
+// @Description Generates a self-contained HTML report summarizing the issues of a workspace grouped by severity, excluding false positives
+// @Tags Reports
+// @Produce text/html
+// @Param id path int true "Workspace ID"
+// @Param title query string false "Report title"
+// @Param min_confidence query int false "Minimum issue confidence"
+// @Param min_severity query string false "Minimum issue severity" Enums(Critical, High, Medium, Low, Info, Unknown)
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/workspaces/{id}/report.html [get]
+func WorkspaceHTMLReportHandler(c *fiber.Ctx) error {
+	return generateWorkspaceReport(c, report.ReportFormatStandaloneHTML)
+}
+
+// generateWorkspaceReport responds with the report of the non false positive issues of the workspace in the format
+func generateWorkspaceReport(c *fiber.Ctx, format report.ReportFormat) error {
+	id, err := parseUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid workspace",
+			Message: "Invalid workspace ID",
+		})
+	}
+	workspace, err := db.Connection.GetWorkspaceByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Error:   "Workspace not found",
+			Message: "The provided workspace ID does not exist",
+		})
+	}
+	minConfidence, err := strconv.Atoi(c.Query("min_confidence", "0"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid min_confidence",
+			Message: "The min_confidence parameter must be a number",
+		})
+	}
+	minSeverity, err := report.ParseSeverityThreshold(c.Query("min_severity"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid min_severity",
+			Message: err.Error(),
+		})
+	}
+
+	falsePositive := false
+	issues, _, err := db.Connection.ListIssues(db.IssueFilter{
+		WorkspaceID:   workspace.ID,
+		MinConfidence: minConfidence,
+		FalsePositive: &falsePositive,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Error fetching issues",
+			Message: "There has been an error fetching issues to generate report",
+		})
+	}
+
+	title := c.Query("title")
+	if title == "" {
+		title = fmt.Sprintf("Report for workspace: %s", workspace.Title)
+	}
+	var buf bytes.Buffer
+	if err := report.GenerateReport(report.ReportOptions{
+		WorkspaceID: workspace.ID,
+		Issues:      issues,
+		Title:       title,
+		Format:      format,
+		MinSeverity: minSeverity,
+	}, &buf); err != nil {
+		log.Error().Err(err).Uint("workspace", workspace.ID).Str("format", string(format)).Msg("Failed to generate workspace report")
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Failed to generate report",
+			Message: "There has been an error generating the report",
+		})
+	}
+
+	c.Response().Header.Set(fiber.HeaderContentType, format.ContentType())
+	c.Response().Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-report.%s", lib.Slugify(workspace.Code), format.Extension()))
+	return c.Send(buf.Bytes())
+}
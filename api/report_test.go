@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/pkg/report"
 	"github.com/stretchr/testify/assert"
@@ -93,3 +94,93 @@ func TestReportHandler(t *testing.T) {
 	assert.Contains(t, buf.String(), "Test Issue 1")
 	assert.Contains(t, buf.String(), "Description 1")
 }
+
+func TestWorkspacePDFReportHandler(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "pdf-report-test",
+		Title:       "PDF report test",
+		Description: "Workspace for PDF report tests",
+	})
+	assert.Nil(t, err)
+
+	app := fiber.New()
+	app.Get("/api/v1/workspaces/:id/report.pdf", WorkspacePDFReportHandler)
+
+	_, err = db.Connection.CreateIssue(db.Issue{
+		Code:        "ISSUE1",
+		Title:       "PDF Issue",
+		Description: "PDF issue description",
+		Severity:    db.High,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/workspaces/%d/report.pdf", workspace.ID), nil)
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=pdf-report-test-report.pdf", resp.Header.Get("Content-Disposition"))
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(resp.Body)
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+
+	req = httptest.NewRequest("GET", "/api/v1/workspaces/999999/report.pdf", nil)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/v1/workspaces/%d/report.pdf?min_confidence=high", workspace.ID), nil)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestWorkspaceMarkdownAndHTMLReportHandlers(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "md-report-test",
+		Title:       "Markdown report test",
+		Description: "Workspace for Markdown and HTML report tests",
+	})
+	assert.Nil(t, err)
+
+	app := fiber.New()
+	app.Get("/api/v1/workspaces/:id/report.md", WorkspaceMarkdownReportHandler)
+	app.Get("/api/v1/workspaces/:id/report.html", WorkspaceHTMLReportHandler)
+
+	for _, issue := range []db.Issue{
+		{Code: "ISSUE1", Title: "High report issue", Severity: db.High, WorkspaceID: &workspace.ID},
+		{Code: "ISSUE2", Title: "Info report issue", Severity: db.Info, WorkspaceID: &workspace.ID},
+	} {
+		_, err := db.Connection.CreateIssue(issue)
+		assert.Nil(t, err)
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/workspaces/%d/report.md?min_severity=low", workspace.ID), nil)
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/markdown; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=md-report-test-report.md", resp.Header.Get("Content-Disposition"))
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(resp.Body)
+	assert.Contains(t, buf.String(), "# Report for workspace: Markdown report test")
+	assert.Contains(t, buf.String(), "High report issue")
+	assert.NotContains(t, buf.String(), "Info report issue")
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/v1/workspaces/%d/report.html", workspace.ID), nil)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=md-report-test-report.html", resp.Header.Get("Content-Disposition"))
+	buf.Reset()
+	buf.ReadFrom(resp.Body)
+	assert.Contains(t, buf.String(), "High report issue")
+	assert.Contains(t, buf.String(), "Info report issue")
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/v1/workspaces/%d/report.md?min_severity=severe", workspace.ID), nil)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
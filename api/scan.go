@@ -1,11 +1,14 @@
 package api
 
 import (
+	"strconv"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/pkg/scan/engine"
 	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/pyneda/sukyan/pkg/scope"
 	"github.com/rs/zerolog/log"
 )
 
@@ -163,7 +166,7 @@ func ActiveScanHandler(c *fiber.Ctx) error {
 
 // FullScanHandler godoc
 // @Summary Submit URLs for full scanning
-// @Description Receives a list of URLs and other parameters and schedules them for a full scan
+// @Description Receives a list of URLs and other parameters and schedules them for a full scan, or only for crawling when crawl_only is set
 // @Tags Scan
 // @Accept  json
 // @Produce  json
@@ -181,6 +184,15 @@ func FullScanHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	expanded, err := engine.ExpandScanProfile(*input)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid scan profile",
+			"message": err.Error(),
+		})
+	}
+	*input = expanded
+
 	if err := validate.Struct(input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "Validation failed",
@@ -196,6 +208,20 @@ func FullScanHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	if _, err := scope.NewURLExclusions(input.ExcludeURLs); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid exclude URLs",
+			"message": err.Error(),
+		})
+	}
+
+	if _, err := input.Scope.Compile(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid scope rules",
+			"message": err.Error(),
+		})
+	}
+
 	if !input.AuditCategories.ServerSide && !input.AuditCategories.ClientSide && !input.AuditCategories.Passive {
 		// return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 		// 	"error":   "Invalid audit categories",
@@ -208,7 +234,9 @@ func FullScanHandler(c *fiber.Ctx) error {
 		input.AuditCategories.Passive = true
 	}
 
-	if input.Title == "" {
+	if input.Title == "" && input.CrawlOnly {
+		input.Title = "Crawl"
+	} else if input.Title == "" {
 		input.Title = "Full scan"
 	}
 
@@ -219,3 +247,145 @@ func FullScanHandler(c *fiber.Ctx) error {
 		"message": "Full scan scheduled",
 	})
 }
+
+// PauseScanHandler godoc
+// @Summary Pause a scan
+// @Description Stops starting the pending active scans of a scan. They are kept so the scan can be resumed later, even after restarting.
+// @Tags Scan
+// @Produce  json
+// @Param id path int true "Scan (task) ID"
+// @Success 200 {object} ActionResponse
+// @Failure 400 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/{id}/pause [post]
+func PauseScanHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid ID",
+			Message: "The provided ID is not a valid number",
+		})
+	}
+
+	e := c.Locals("engine").(*engine.ScanEngine)
+	if err := e.PauseTask(uint(id)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Cannot pause scan",
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(ActionResponse{Message: "Scan paused"})
+}
+
+// ResumeScanHandler godoc
+// @Summary Resume a paused scan
+// @Description Schedules again the pending active scans of a paused scan
+// @Tags Scan
+// @Produce  json
+// @Param id path int true "Scan (task) ID"
+// @Success 200 {object} ActionResponse
+// @Failure 400 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/{id}/resume [post]
+func ResumeScanHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid ID",
+			Message: "The provided ID is not a valid number",
+		})
+	}
+
+	e := c.Locals("engine").(*engine.ScanEngine)
+	if err := e.ResumeTask(uint(id)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Cannot resume scan",
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(ActionResponse{Message: "Scan resumed"})
+}
+
+type PlanScanInput struct {
+	Items                []uint                            `json:"items" validate:"required,dive,min=0"`
+	Mode                 scan_options.ScanMode             `json:"mode" validate:"omitempty,oneof=fast smart fuzz"`
+	InsertionPoints      []string                          `json:"insertion_points" validate:"omitempty,dive,oneof=parameters urlpath body headers cookies json xml"`
+	FingerprintTags      []string                          `json:"fingerprint_tags" validate:"omitempty,dive"`
+	ExperimentalAudits   bool                              `json:"experimental_audits"`
+	AuditCategories      scan_options.AuditCategories      `json:"audit_categories"`
+	ExcludeURLs          []string                          `json:"exclude_urls" validate:"omitempty"`
+	Scope                scope.ScopeRules                  `json:"scope"`
+	InsertionPointFilter scan_options.InsertionPointFilter `json:"insertion_point_filter"`
+}
+
+// PlanScanHandler godoc
+// @Summary Plan an active scan without running it
+// @Description Returns the modules, insertion points and payload generators that an active scan of the provided items would test, without sending any request
+// @Tags Scan
+// @Accept  json
+// @Produce  json
+// @Param input body PlanScanInput true "Items to plan and scan configuration"
+// @Success 200 {object} engine.ScanPlan
+// @Failure 400 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/plan [post]
+func PlanScanHandler(c *fiber.Ctx) error {
+	input := new(PlanScanInput)
+
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot parse JSON",
+		})
+	}
+
+	if err := validate.Struct(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"message": err.Error(),
+		})
+	}
+
+	if input.Mode == "" {
+		input.Mode = scan_options.ScanModeSmart
+	}
+	if len(input.InsertionPoints) == 0 {
+		input.InsertionPoints = scan_options.GetValidInsertionPoints()
+	}
+	if !input.AuditCategories.ServerSide && !input.AuditCategories.ClientSide && !input.AuditCategories.Passive {
+		input.AuditCategories.ServerSide = true
+		input.AuditCategories.ClientSide = true
+		input.AuditCategories.Passive = true
+	}
+
+	items, err := db.Connection.GetHistoriesByID(input.Items)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Cannot get history items with provided IDs",
+			"message": err.Error(),
+		})
+	}
+	histories := make([]*db.History, 0, len(items))
+	for i := range items {
+		histories = append(histories, &items[i])
+	}
+
+	options := scan_options.HistoryItemScanOptions{
+		Mode:                 input.Mode,
+		InsertionPoints:      input.InsertionPoints,
+		FingerprintTags:      input.FingerprintTags,
+		ExperimentalAudits:   input.ExperimentalAudits,
+		AuditCategories:      input.AuditCategories,
+		ExcludeURLs:          input.ExcludeURLs,
+		Scope:                input.Scope,
+		InsertionPointFilter: input.InsertionPointFilter,
+	}
+	e := c.Locals("engine").(*engine.ScanEngine)
+	plan, err := e.PlanScan(histories, options)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Cannot plan scan",
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(plan)
+}
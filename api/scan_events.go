@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/scan/engine"
+	"github.com/rs/zerolog/log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FindScanEvents godoc
+// @Summary List the lifecycle events of a scan
+// @Description Retrieves the events recorded during a scan in the order they happened. When requested with the `Accept: text/event-stream` header, the progress of the scan is streamed instead as Server-Sent Events, starting with its latest snapshot and until the scan finishes.
+// @Tags Scan
+// @Produce  json
+// @Produce  text/event-stream
+// @Param id path int true "Scan (task) ID"
+// @Param type query string false "Comma-separated list of event types to filter"
+// @Param module query string false "Comma-separated list of modules to filter"
+// @Param task_job query int false "Task job ID to filter by"
+// @Param page_size query int false "Number of items per page" default(100)
+// @Param page query int false "Page number" default(1)
+// @Success 200 {array} db.ScanEvent
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/{id}/events [get]
+func FindScanEvents(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid ID",
+			Message: "The provided ID is not a valid number",
+		})
+	}
+
+	if strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") {
+		return streamScanProgress(c, uint(id))
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("page_size", "100"))
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing page size parameter query")
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "Invalid page size parameter"})
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing page parameter query")
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "Invalid page parameter"})
+	}
+
+	var taskJobID int
+	if unparsedTaskJob := c.Query("task_job"); unparsedTaskJob != "" {
+		taskJobID, err = strconv.Atoi(unparsedTaskJob)
+		if err != nil || taskJobID < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "Invalid task job parameter"})
+		}
+	}
+
+	var types, modules []string
+	if unparsedTypes := c.Query("type"); unparsedTypes != "" {
+		types = strings.Split(unparsedTypes, ",")
+	}
+	if unparsedModules := c.Query("module"); unparsedModules != "" {
+		modules = strings.Split(unparsedModules, ",")
+	}
+
+	events, count, err := db.Connection.ListScanEvents(db.ScanEventFilter{
+		TaskID:    uint(id),
+		Types:     types,
+		Modules:   modules,
+		TaskJobID: uint(taskJobID),
+		Pagination: db.Pagination{
+			Page: page, PageSize: pageSize,
+		},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: DefaultInternalServerErrorMessage})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{"data": events, "count": count})
+}
+
+// scanProgressHeartbeatInterval is how often a comment is sent to idle progress streams, which detects disconnected
+// clients and keeps proxies from closing the connection
+const scanProgressHeartbeatInterval = 15 * time.Second
+
+// streamScanProgress sends the progress of the scan as Server-Sent Events until the scan finishes or the client
+// disconnects
+func streamScanProgress(c *fiber.Ctx, taskID uint) error {
+	if _, err := db.Connection.GetTaskByID(taskID, false); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Error:   "Not found",
+			Message: "The requested scan does not exist",
+		})
+	}
+	e := c.Locals("engine").(*engine.ScanEngine)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		updates, unsubscribe := e.SubscribeProgress(taskID)
+		defer unsubscribe()
+		heartbeat := time.NewTicker(scanProgressHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case progress := <-updates:
+				data, err := json.Marshal(progress)
+				if err != nil {
+					log.Error().Err(err).Uint("task", taskID).Msg("Could not encode scan progress")
+					return
+				}
+				fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+				if err := w.Flush(); err != nil {
+					log.Debug().Err(err).Uint("task", taskID).Msg("Scan progress client disconnected")
+					return
+				}
+				if progress.Finished() {
+					return
+				}
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				if err := w.Flush(); err != nil {
+					log.Debug().Err(err).Uint("task", taskID).Msg("Scan progress client disconnected")
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
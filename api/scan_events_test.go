@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/scan/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindScanEvents(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/scan/:id/events", FindScanEvents)
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestFindScanEvents",
+		Title: "TestFindScanEvents",
+	})
+	assert.Nil(t, err)
+	task, err := db.Connection.NewTask(workspace.ID, nil, "Scan events test", db.TaskStatusCrawling, db.TaskTypeScan)
+	assert.Nil(t, err)
+	defer db.Connection.DeleteTask(task.ID)
+
+	lifecycle := []db.ScanEventType{
+		db.ScanEventScanStarted,
+		db.ScanEventCrawlStarted,
+		db.ScanEventCrawlFinished,
+		db.ScanEventModuleSlow,
+		db.ScanEventModuleFailed,
+		db.ScanEventScanCompleted,
+	}
+	for _, eventType := range lifecycle {
+		module := ""
+		if eventType == db.ScanEventModuleSlow || eventType == db.ScanEventModuleFailed {
+			module = "jsonp-callback"
+		}
+		_, err := db.Connection.NewScanEvent(task.ID, nil, eventType, module, string(eventType), map[string]interface{}{"test": true})
+		assert.Nil(t, err)
+	}
+
+	type eventsResponse struct {
+		Data  []db.ScanEvent `json:"data"`
+		Count int64          `json:"count"`
+	}
+	fetch := func(query string) eventsResponse {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/scan/%d/events%s", task.ID, query), nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		var result eventsResponse
+		assert.Nil(t, json.Unmarshal(body, &result))
+		return result
+	}
+
+	result := fetch("")
+	assert.Equal(t, int64(len(lifecycle)), result.Count)
+	var types []db.ScanEventType
+	for _, event := range result.Data {
+		assert.Equal(t, task.ID, event.TaskID)
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, lifecycle, types)
+
+	result = fetch("?type=scan_started,scan_completed")
+	assert.Equal(t, int64(2), result.Count)
+	assert.Equal(t, db.ScanEventScanStarted, result.Data[0].Type)
+	assert.Equal(t, db.ScanEventScanCompleted, result.Data[1].Type)
+
+	result = fetch("?module=jsonp-callback")
+	assert.Equal(t, int64(2), result.Count)
+	assert.Equal(t, db.ScanEventModuleSlow, result.Data[0].Type)
+
+	req := httptest.NewRequest("GET", "/api/v1/scan/invalid/events", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestStreamScanProgress(t *testing.T) {
+	scanEngine := engine.NewScanEngine(nil, 1, 1, nil)
+	defer scanEngine.Stop()
+	app := fiber.New()
+	app.Get("/api/v1/scan/:id/events", func(c *fiber.Ctx) error {
+		c.Locals("engine", scanEngine)
+		return c.Next()
+	}, FindScanEvents)
+
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestStreamScanProgress",
+		Title: "TestStreamScanProgress",
+	})
+	assert.Nil(t, err)
+	task, err := db.Connection.NewTask(workspace.ID, nil, "Scan progress stream test", db.TaskStatusFinished, db.TaskTypeScan)
+	assert.Nil(t, err)
+	defer db.Connection.DeleteTask(task.ID)
+	_, err = db.Connection.NewScanEvent(task.ID, nil, db.ScanEventScanCompleted, "", "Scan completed", nil)
+	assert.Nil(t, err)
+
+	// The latest snapshot is replayed on connect, and the stream ends as the scan has already finished
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/scan/%d/events", task.ID), nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := app.Test(req, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(body), "event: progress\ndata: "))
+	data := strings.TrimSpace(strings.TrimPrefix(string(body), "event: progress\ndata: "))
+	var progress engine.ScanProgress
+	assert.Nil(t, json.Unmarshal([]byte(data), &progress))
+	assert.Equal(t, task.ID, progress.TaskID)
+	assert.Equal(t, db.TaskStatusFinished, progress.Status)
+	if assert.NotNil(t, progress.LastEvent) {
+		assert.Equal(t, db.ScanEventScanCompleted, progress.LastEvent.Type)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/scan/99999999/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pyneda/sukyan/db"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+)
+
+type ScanProfileInput struct {
+	Name        string                          `json:"name" validate:"required,min=1,max=255"`
+	Description string                          `json:"description" validate:"omitempty,max=1024"`
+	Options     scan_options.ScanProfileOptions `json:"options"`
+}
+
+// validateScanProfileInput parses and validates the scan profile provided in the request body
+func validateScanProfileInput(c *fiber.Ctx) (*ScanProfileInput, *ErrorResponse) {
+	input := new(ScanProfileInput)
+	if err := c.BodyParser(input); err != nil {
+		log.Error().Err(err).Msg("Error parsing JSON")
+		return nil, &ErrorResponse{
+			Error:   "Cannot parse JSON",
+			Message: "The provided JSON is invalid, check the syntax and logs for details",
+		}
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(input); err != nil {
+		return nil, &ErrorResponse{
+			Error:   "Validation failed",
+			Message: buildValidationErrorMessage(err),
+		}
+	}
+
+	if _, err := input.Options.Scope.Compile(); err != nil {
+		return nil, &ErrorResponse{
+			Error:   "Invalid scope rules",
+			Message: err.Error(),
+		}
+	}
+	return input, nil
+}
+
+// CreateScanProfile handles the API request for creating a new scan profile
+// @Summary Create a new scan profile
+// @Description Creates a named preset of scan options that can be referenced when launching scans
+// @Tags Scan
+// @Accept json
+// @Produce json
+// @Param input body ScanProfileInput true "Scan profile to create"
+// @Success 201 {object} db.ScanProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/profiles [post]
+func CreateScanProfile(c *fiber.Ctx) error {
+	input, errorResponse := validateScanProfileInput(c)
+	if errorResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse)
+	}
+
+	profile, err := db.Connection.CreateScanProfile(&db.ScanProfile{
+		Name:        input.Name,
+		Description: input.Description,
+		Options:     input.Options,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(profile)
+}
+
+// UpdateScanProfile handles the API request for updating a scan profile
+// @Summary Update a scan profile
+// @Description Replaces the name, description and options of an existing scan profile
+// @Tags Scan
+// @Accept json
+// @Produce json
+// @Param id path int true "Scan profile ID"
+// @Param input body ScanProfileInput true "Scan profile to update"
+// @Success 200 {object} db.ScanProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/profiles/{id} [put]
+func UpdateScanProfile(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid ID",
+			Message: "The provided ID is not a valid number",
+		})
+	}
+
+	input, errorResponse := validateScanProfileInput(c)
+	if errorResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse)
+	}
+
+	if _, err := db.Connection.GetScanProfileByID(uint(id)); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Error:   "Not found",
+			Message: "Scan profile not found",
+		})
+	}
+
+	profile := db.ScanProfile{
+		Name:        input.Name,
+		Description: input.Description,
+		Options:     input.Options,
+	}
+	if _, err := db.Connection.UpdateScanProfile(uint(id), &profile); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+
+	updated, err := db.Connection.GetScanProfileByID(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+	return c.JSON(updated)
+}
+
+// GetScanProfile handles the API request for retrieving a scan profile by ID
+// @Summary Get a scan profile by ID
+// @Description Retrieves a scan profile by its ID
+// @Tags Scan
+// @Produce json
+// @Param id path int true "Scan profile ID"
+// @Success 200 {object} db.ScanProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/profiles/{id} [get]
+func GetScanProfile(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid ID",
+			Message: "The provided ID is not a valid number",
+		})
+	}
+
+	profile, err := db.Connection.GetScanProfileByID(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Error:   "Not found",
+			Message: "Scan profile not found",
+		})
+	}
+
+	return c.JSON(profile)
+}
+
+// DeleteScanProfile handles the API request for deleting a scan profile
+// @Summary Delete a scan profile
+// @Description Deletes an existing scan profile
+// @Tags Scan
+// @Produce json
+// @Param id path int true "Scan profile ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/profiles/{id} [delete]
+func DeleteScanProfile(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid ID",
+			Message: "The provided ID is not a valid number",
+		})
+	}
+
+	if err := db.Connection.DeleteScanProfile(uint(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListScanProfiles handles the API request for listing scan profiles
+// @Summary List scan profiles
+// @Description Retrieves the scan profiles, optionally filtered by name or description
+// @Tags Scan
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(50)
+// @Param query query string false "Search query for name and description"
+// @Success 200 {object} map[string]interface{} "Returns 'data' (array of ScanProfile) and 'count' (total number of records)"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/scan/profiles [get]
+func ListScanProfiles(c *fiber.Ctx) error {
+	filter := new(db.ScanProfileFilter)
+
+	var err error
+	filter.Pagination.Page, err = strconv.Atoi(c.Query("page", "1"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid page",
+			Message: "The provided page number is not valid",
+		})
+	}
+
+	filter.Pagination.PageSize, err = strconv.Atoi(c.Query("page_size", "50"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid page_size",
+			Message: "The provided page size is not valid",
+		})
+	}
+	filter.Query = c.Query("query")
+
+	validate := validator.New()
+	if err := validate.Struct(filter); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Validation failed",
+			Message: buildValidationErrorMessage(err),
+		})
+	}
+
+	items, count, err := db.Connection.ListScanProfiles(*filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Error listing scan profiles")
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+
+	return c.JSON(fiber.Map{"data": items, "count": count})
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/scan/engine"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanProfileCRUDAndExpansion(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/scan/profiles", CreateScanProfile)
+	app.Get("/api/v1/scan/profiles/:id", GetScanProfile)
+	app.Delete("/api/v1/scan/profiles/:id", DeleteScanProfile)
+
+	input := ScanProfileInput{
+		Name:        "TestScanProfileCRUDAndExpansion",
+		Description: "Quick scan",
+		Options: scan_options.ScanProfileOptions{
+			Mode:            scan_options.ScanModeFast,
+			InsertionPoints: []string{"parameters"},
+			PagesPoolSize:   4,
+			AuditCategories: scan_options.AuditCategories{ServerSide: true},
+		},
+	}
+	body, err := json.Marshal(input)
+	assert.Nil(t, err)
+	req := httptest.NewRequest("POST", "/api/v1/scan/profiles", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var created db.ScanProfile
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	assert.Nil(t, err)
+	assert.NotEqual(t, uint(0), created.ID)
+	assert.Equal(t, input.Options, created.Options)
+
+	resp, err = app.Test(httptest.NewRequest("GET", fmt.Sprintf("/api/v1/scan/profiles/%d", created.ID), nil))
+	assert.Nil(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	options, err := engine.ExpandScanProfile(scan_options.FullScanOptions{
+		StartURLs: []string{"https://example.com"},
+		Mode:      scan_options.ScanModeFuzz,
+		ProfileID: &created.ID,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, scan_options.ScanModeFuzz, options.Mode)
+	assert.Equal(t, []string{"parameters"}, options.InsertionPoints)
+	assert.Equal(t, 4, options.PagesPoolSize)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", fmt.Sprintf("/api/v1/scan/profiles/%d", created.ID), nil))
+	assert.Nil(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+	missing := uint(0)
+	_, err = engine.ExpandScanProfile(scan_options.FullScanOptions{ProfileID: &created.ID})
+	assert.NotNil(t, err)
+	_, err = engine.ExpandScanProfile(scan_options.FullScanOptions{ProfileID: &missing})
+	assert.NotNil(t, err)
+}
@@ -18,6 +18,7 @@ import (
 	_ "github.com/pyneda/sukyan/docs"
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/lib/notifications"
 	"github.com/pyneda/sukyan/pkg/payloads/generation"
 	"github.com/pyneda/sukyan/pkg/scan"
 	"github.com/pyneda/sukyan/pkg/scan/engine"
@@ -48,6 +49,8 @@ func StartAPI() {
 		GetAsnInfo:            false,
 		PollingInterval:       oobPollingInterval * time.Second,
 		OnInteractionCallback: scan.SaveInteractionCallback,
+		MaxPendingTests:       viper.GetInt("scan.oob.max_pending_tests"),
+		PendingTestTTL:        time.Duration(viper.GetInt("scan.oob.pending_test_ttl")) * time.Second,
 	}
 	interactionsManager.Start()
 	engine := engine.NewScanEngine(generators, viper.GetInt("scan.concurrency.passive"), viper.GetInt("scan.concurrency.active"), interactionsManager)
@@ -94,10 +97,26 @@ func StartAPI() {
 	api := app.Group("/api/v1")
 	api.Get("/history", JWTProtected(), FindHistory)
 	api.Post("/history", JWTProtected(), FindHistoryPost)
+	api.Post("/history/import/har", JWTProtected(), ImportHARHandler)
+	api.Get("/history/export/har", JWTProtected(), ExportHARHandler)
+	api.Get("/history/diff", JWTProtected(), GetHistoryDiff)
 	api.Get("/issues", JWTProtected(), FindIssues)
 	api.Get("/issues/grouped", JWTProtected(), FindIssuesGrouped)
+	api.Get("/issues/export/sarif", JWTProtected(), ExportIssuesSARIF)
+	api.Get("/issues/export/csv", JWTProtected(), ExportIssuesCSV)
+	api.Get("/issues/export/json", JWTProtected(), ExportIssuesJSON)
+	api.Get("/issues/suppression-rules", JWTProtected(), ListSuppressionRules)
+	api.Post("/issues/suppression-rules", JWTProtected(), CreateSuppressionRule)
+	api.Delete("/issues/suppression-rules/:id", JWTProtected(), DeleteSuppressionRule)
 	api.Get("/issues/:id", JWTProtected(), GetIssueDetail)
 	api.Post("/issues/:id/set-false-positive", SetFalsePositive)
+	api.Post("/issues/:id/status", JWTProtected(), UpdateIssueStatus)
+	api.Get("/issues/:id/status-changes", JWTProtected(), GetIssueStatusChanges)
+	api.Post("/issues/:id/assign", JWTProtected(), AssignIssue)
+	api.Post("/issues/:id/minimize", JWTProtected(), MinimizeIssue)
+	api.Get("/issues/:id/evidence", JWTProtected(), GetIssueEvidence)
+	api.Post("/issues/:id/revalidate", JWTProtected(), RevalidateIssue)
+	api.Post("/history/:id/replay", JWTProtected(), ReplayHistoryItem)
 	api.Get("/history/:id/children", JWTProtected(), GetChildren)
 	api.Get("/history/root-nodes", JWTProtected(), GetRootNodes)
 	api.Get("/history/websocket/connections/:id", JWTProtected(), FindWebSocketConnectionByID)
@@ -108,6 +127,9 @@ func StartAPI() {
 	api.Get("/workspaces/:id", JWTProtected(), GetWorkspaceDetail)
 	api.Delete("/workspaces/:id", JWTProtected(), DeleteWorkspace)
 	api.Put("/workspaces/:id", JWTProtected(), UpdateWorkspace)
+	api.Get("/workspaces/:id/report.pdf", JWTProtected(), WorkspacePDFReportHandler)
+	api.Get("/workspaces/:id/report.md", JWTProtected(), WorkspaceMarkdownReportHandler)
+	api.Get("/workspaces/:id/report.html", JWTProtected(), WorkspaceHTMLReportHandler)
 	api.Get("/interactions", JWTProtected(), FindInteractions)
 	api.Get("/interactions/:id", JWTProtected(), GetInteractionDetail)
 	api.Get("/tasks", JWTProtected(), FindTasks)
@@ -154,6 +176,15 @@ func StartAPI() {
 	scan_app.Post("/full", JWTProtected(), FullScanHandler)
 	scan_app.Post("/passive", JWTProtected(), PassiveScanHandler)
 	scan_app.Post("/active", JWTProtected(), ActiveScanHandler)
+	scan_app.Post("/plan", JWTProtected(), PlanScanHandler)
+	scan_app.Post("/profiles", JWTProtected(), CreateScanProfile)
+	scan_app.Get("/profiles", JWTProtected(), ListScanProfiles)
+	scan_app.Get("/profiles/:id", JWTProtected(), GetScanProfile)
+	scan_app.Put("/profiles/:id", JWTProtected(), UpdateScanProfile)
+	scan_app.Delete("/profiles/:id", JWTProtected(), DeleteScanProfile)
+	scan_app.Get("/:id/events", JWTProtected(), FindScanEvents)
+	scan_app.Post("/:id/pause", JWTProtected(), PauseScanHandler)
+	scan_app.Post("/:id/resume", JWTProtected(), ResumeScanHandler)
 
 	certPath := viper.GetString("server.cert.file")
 	keyPath := viper.GetString("server.key.file")
@@ -170,5 +201,7 @@ func StartAPI() {
 	if err := app.ListenTLS(listen_addres, certPath, keyPath); err != nil {
 		apiLogger.Warn().Err(err).Msg("Error starting server")
 	}
+	db.Connection.CloseIssueBatcher()
+	notifications.Flush()
 
 }
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pyneda/sukyan/db"
+	"github.com/rs/zerolog/log"
+)
+
+type SuppressionRuleInput struct {
+	Code            string `json:"code" validate:"required,max=255"`
+	URLPattern      string `json:"url_pattern" validate:"omitempty,max=2048"`
+	PayloadContains string `json:"payload_contains" validate:"omitempty,max=1024"`
+	Reason          string `json:"reason" validate:"omitempty,max=1024"`
+	WorkspaceID     uint   `json:"workspace_id"`
+	// ApplyToExisting also marks the existing issues matching the rule as false positives
+	ApplyToExisting bool `json:"apply_to_existing"`
+}
+
+type SuppressionRuleResponse struct {
+	Rule *db.SuppressionRule `json:"rule"`
+	// Suppressed is the number of existing issues marked as false positives
+	Suppressed int64 `json:"suppressed"`
+}
+
+// CreateSuppressionRule handles the API request for creating a new suppression rule
+// @Summary Create a suppression rule
+// @Description Creates a rule that marks new issues matching its code, URL pattern and payload substring as false positives. Existing issues are only marked when apply_to_existing is set, and are never deleted.
+// @Tags Issues
+// @Accept json
+// @Produce json
+// @Param input body SuppressionRuleInput true "Suppression rule to create"
+// @Success 201 {object} SuppressionRuleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/suppression-rules [post]
+func CreateSuppressionRule(c *fiber.Ctx) error {
+	input := new(SuppressionRuleInput)
+	if err := c.BodyParser(input); err != nil {
+		log.Error().Err(err).Msg("Error parsing JSON")
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Cannot parse JSON",
+			Message: "The provided JSON is invalid, check the syntax and logs for details",
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Validation failed",
+			Message: buildValidationErrorMessage(err),
+		})
+	}
+
+	if db.GetIssueTemplateByCode(db.IssueCode(input.Code)) == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid code",
+			Message: "The provided issue code does not exist",
+		})
+	}
+
+	rule := &db.SuppressionRule{
+		Code:            input.Code,
+		URLPattern:      input.URLPattern,
+		PayloadContains: input.PayloadContains,
+		Reason:          input.Reason,
+	}
+	if input.WorkspaceID != 0 {
+		if exists, _ := db.Connection.WorkspaceExists(input.WorkspaceID); !exists {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "Invalid workspace",
+				Message: "The provided workspace ID does not seem valid",
+			})
+		}
+		rule.WorkspaceID = &input.WorkspaceID
+	}
+
+	rule, err := db.Connection.CreateSuppressionRule(rule)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+
+	response := SuppressionRuleResponse{Rule: rule}
+	if input.ApplyToExisting {
+		response.Suppressed, err = db.Connection.ApplySuppressionRuleToExistingIssues(rule)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Error:   "Database error",
+				Message: "The rule has been created but could not be applied to existing issues, check logs for details",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// ListSuppressionRules handles the API request for listing suppression rules
+// @Summary List suppression rules
+// @Description Retrieves the suppression rules, optionally filtered by workspace, including the rules that apply to all workspaces
+// @Tags Issues
+// @Produce json
+// @Param workspace query int false "Workspace ID"
+// @Param codes query string false "Comma-separated list of issue codes to filter by"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(50)
+// @Success 200 {object} map[string]interface{} "Returns 'data' (array of SuppressionRule) and 'count' (total number of records)"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/suppression-rules [get]
+func ListSuppressionRules(c *fiber.Ctx) error {
+	filter := db.SuppressionRuleFilter{}
+
+	if c.Query("workspace") != "" {
+		workspaceID, err := parseWorkspaceID(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "Invalid workspace",
+				Message: "The provided workspace ID does not seem valid",
+			})
+		}
+		filter.WorkspaceID = workspaceID
+	}
+
+	if codes := c.Query("codes"); codes != "" {
+		filter.Codes = strings.Split(codes, ",")
+	}
+
+	var err error
+	filter.Pagination.Page, err = strconv.Atoi(c.Query("page", "1"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid page",
+			Message: "The provided page number is not valid",
+		})
+	}
+
+	filter.Pagination.PageSize, err = strconv.Atoi(c.Query("page_size", "50"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid page_size",
+			Message: "The provided page size is not valid",
+		})
+	}
+
+	items, count, err := db.Connection.ListSuppressionRules(filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Error listing suppression rules")
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+
+	return c.JSON(fiber.Map{"data": items, "count": count})
+}
+
+// DeleteSuppressionRule handles the API request for deleting a suppression rule
+// @Summary Delete a suppression rule
+// @Description Deletes a suppression rule, so new issues are not suppressed by it anymore. Issues already marked as false positives are kept as they are.
+// @Tags Issues
+// @Produce json
+// @Param id path int true "Suppression rule ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/v1/issues/suppression-rules/{id} [delete]
+func DeleteSuppressionRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "Invalid ID",
+			Message: "The provided ID is not a valid number",
+		})
+	}
+
+	if _, err := db.Connection.GetSuppressionRuleByID(uint(id)); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Error:   "Not found",
+			Message: "Suppression rule not found",
+		})
+	}
+
+	if err := db.Connection.DeleteSuppressionRule(uint(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Error:   "Database error",
+			Message: "Check logs for details",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -17,6 +17,7 @@ var (
 	reportFormat  string
 	reportOutput  string
 	minConfidence int
+	minSeverity   string
 )
 
 // reportCmd represents the report command
@@ -36,7 +37,7 @@ var reportCmd = &cobra.Command{
 				fmt.Printf("Error fetching task details: %v\n", err)
 				return
 			}
-			reportOutput = fmt.Sprintf("%s-report.%s", lib.Slugify(task.Title), reportFormat)
+			reportOutput = fmt.Sprintf("%s-report.%s", lib.Slugify(task.Title), report.ReportFormat(reportFormat).Extension())
 			if reportTitle == "" {
 				reportTitle = fmt.Sprintf("Report for task: %s", task.Title)
 			}
@@ -48,7 +49,7 @@ var reportCmd = &cobra.Command{
 				fmt.Printf("Error fetching workspace details: %v\n", err)
 				return
 			}
-			reportOutput = fmt.Sprintf("%s-report.%s", lib.Slugify(workspace.Code), reportFormat)
+			reportOutput = fmt.Sprintf("%s-report.%s", lib.Slugify(workspace.Code), report.ReportFormat(reportFormat).Extension())
 			if reportTitle == "" {
 				reportTitle = fmt.Sprintf("Report for workspace: %s", workspace.Code)
 			}
@@ -59,12 +60,17 @@ var reportCmd = &cobra.Command{
 			fmt.Println(err)
 			return
 		}
+		severityThreshold, err := report.ParseSeverityThreshold(minSeverity)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 		if reportTitle == "" {
 			reportTitle = "Sukyan report"
 		}
 		if reportOutput == "" {
 
-			reportOutput = fmt.Sprintf("%s.%s", lib.Slugify(reportTitle), reportFormat)
+			reportOutput = fmt.Sprintf("%s.%s", lib.Slugify(reportTitle), report.ReportFormat(reportFormat).Extension())
 		}
 
 		issues, _, err := db.Connection.ListIssues(db.IssueFilter{
@@ -84,6 +90,7 @@ var reportCmd = &cobra.Command{
 			Title:       reportTitle,
 			Format:      format,
 			TaskID:      taskID,
+			MinSeverity: severityThreshold,
 		}
 
 		var buf bytes.Buffer
@@ -110,6 +117,12 @@ func toReportFormat(format string) (report.ReportFormat, error) {
 		return report.ReportFormatHTML, nil
 	case string(report.ReportFormatJSON):
 		return report.ReportFormatJSON, nil
+	case string(report.ReportFormatPDF):
+		return report.ReportFormatPDF, nil
+	case string(report.ReportFormatMarkdown):
+		return report.ReportFormatMarkdown, nil
+	case string(report.ReportFormatStandaloneHTML):
+		return report.ReportFormatStandaloneHTML, nil
 	default:
 		return "", fmt.Errorf("invalid format provided: %s", format)
 	}
@@ -121,7 +134,8 @@ func init() {
 	reportCmd.Flags().UintVarP(&workspaceID, "workspace", "w", 0, "Workspace ID")
 	reportCmd.Flags().UintVarP(&taskID, "task", "t", 0, "Task ID")
 	reportCmd.Flags().StringVarP(&reportTitle, "title", "T", "", "Report Title")
-	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "html", "Report Format (html or json)")
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "html", "Report Format (html, json, pdf, md or standalone-html)")
 	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "Output file path)")
 	reportCmd.Flags().IntVarP(&minConfidence, "min-confidence", "c", 0, "Minimum issue confidence level to include in the report")
+	reportCmd.Flags().StringVar(&minSeverity, "min-severity", "", "Minimum issue severity to include in the pdf, md and standalone-html reports")
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/lib/notifications"
 	"github.com/pyneda/sukyan/pkg/payloads/generation"
 	"github.com/pyneda/sukyan/pkg/scan"
 	"github.com/pyneda/sukyan/pkg/scan/engine"
@@ -12,6 +13,7 @@ import (
 	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
 
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -24,6 +26,7 @@ var startURLs []string
 var crawlDepth int
 var crawlMaxPages int
 var crawlExcludePatterns []string
+var scanExcludeURLs []string
 var workspaceID uint
 var scanTitle string
 var requestsHeadersString string
@@ -34,6 +37,17 @@ var experimentalAudits bool
 var serverSideChecks bool
 var clientSideChecks bool
 var passiveChecks bool
+var includeParameters []string
+var excludeParameters []string
+var parameterLocations []string
+var parameterDataTypes []string
+var scanUserAgent string
+var scanExtraHeaders []string
+var scanHTTPVersion string
+var crawlOnly bool
+var maxDuration time.Duration
+var scanRandomSeed int64
+var scanWAFEvasion bool
 
 var validate = validator.New()
 
@@ -94,6 +108,15 @@ var scanCmd = &cobra.Command{
 
 		headers := lib.ParseHeadersStringToMap(requestsHeadersString)
 		log.Info().Interface("headers", headers).Msg("Parsed headers")
+		extraHeaders := make(map[string]string)
+		for _, header := range scanExtraHeaders {
+			kv := strings.SplitN(header, ":", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+				log.Error().Str("header", header).Msg("Invalid extra header, expected the Name: Value format")
+				os.Exit(1)
+			}
+			extraHeaders[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
 
 		options := scan_options.FullScanOptions{
 			Title:              scanTitle,
@@ -101,17 +124,33 @@ var scanCmd = &cobra.Command{
 			MaxDepth:           crawlDepth,
 			MaxPagesToCrawl:    crawlMaxPages,
 			ExcludePatterns:    crawlExcludePatterns,
+			ExcludeURLs:        scanExcludeURLs,
 			WorkspaceID:        workspaceID,
 			PagesPoolSize:      pagesPoolSize,
 			Headers:            headers,
+			ExtraHeaders:       extraHeaders,
+			UserAgent:          scanUserAgent,
+			HTTPVersion:        scanHTTPVersion,
 			InsertionPoints:    insertionPoints,
 			Mode:               scan_options.GetScanMode(scanMode),
 			ExperimentalAudits: experimentalAudits,
+			CrawlOnly:          crawlOnly,
+			MaxDuration:        maxDuration,
+			RandomSeed:         scanRandomSeed,
+			WAFEvasion:         scanWAFEvasion,
 			AuditCategories: options.AuditCategories{
 				ServerSide: serverSideChecks,
 				ClientSide: clientSideChecks,
 				Passive:    passiveChecks,
 			},
+			InsertionPointFilter: options.InsertionPointFilter{
+				IncludeNames: includeParameters,
+				ExcludeNames: excludeParameters,
+				Locations:    parameterLocations,
+			},
+		}
+		for _, dataType := range parameterDataTypes {
+			options.InsertionPointFilter.DataTypes = append(options.InsertionPointFilter.DataTypes, lib.DataType(dataType))
 		}
 		if err := validate.Struct(options); err != nil {
 			log.Error().Err(err).Msg("Validation failed")
@@ -124,6 +163,8 @@ var scanCmd = &cobra.Command{
 			GetAsnInfo:            false,
 			PollingInterval:       oobPollingInterval * time.Second,
 			OnInteractionCallback: scan.SaveInteractionCallback,
+			MaxPendingTests:       viper.GetInt("scan.oob.max_pending_tests"),
+			PendingTestTTL:        time.Duration(viper.GetInt("scan.oob.pending_test_ttl")) * time.Second,
 		}
 		interactionsManager.Start()
 		engine := engine.NewScanEngine(generators, viper.GetInt("scan.concurrency.passive"), viper.GetInt("scan.concurrency.active"), interactionsManager)
@@ -141,6 +182,8 @@ var scanCmd = &cobra.Command{
 		time.Sleep(oobWait * time.Second)
 		engine.Stop()
 		interactionsManager.Stop()
+		db.Connection.CloseIssueBatcher()
+		notifications.Flush()
 	},
 }
 
@@ -152,14 +195,26 @@ func init() {
 	scanCmd.Flags().IntVar(&pagesPoolSize, "pool-size", 4, "Page pool size (not used)")
 	scanCmd.Flags().IntVar(&crawlMaxPages, "max-pages", 0, "Max pages to crawl")
 	scanCmd.Flags().StringArrayVar(&crawlExcludePatterns, "exclude-pattern", nil, "URL patterns to ignore when crawling")
+	scanCmd.Flags().StringArrayVar(&scanExcludeURLs, "exclude-url", nil, "URLs that should never be requested during the scan, as globs or regular expressions prefixed with regex: (e.g. */logout*)")
 	scanCmd.Flags().IntVar(&crawlDepth, "depth", 5, "Max crawl depth")
 	// scanCmd.Flags().StringArrayVar(&scanTests, "test", nil, "Tests to run (all by default)")
 	scanCmd.Flags().StringVarP(&scanTitle, "title", "t", "Scan", "Scan title")
 	scanCmd.Flags().StringVar(&requestsHeadersString, "headers", "", "Headers to use for requests")
+	scanCmd.Flags().StringVar(&scanUserAgent, "user-agent", "", "User-Agent sent in all the requests made during the scan")
+	scanCmd.Flags().StringArrayVar(&scanExtraHeaders, "extra-header", nil, "Header added to all the requests made during the scan, unless already set by the request (e.g. \"X-Staging-Token: secret\")")
+	scanCmd.Flags().StringVar(&scanHTTPVersion, "http-version", "", "HTTP version used to send the requests (http1, http2, http3), falling back to older versions when not supported by the target")
 	scanCmd.Flags().StringVarP(&scanMode, "mode", "m", "smart", "Scan mode (fast, smart, fuzz)")
 	scanCmd.Flags().StringArrayVarP(&insertionPoints, "insertion-points", "I", scan_options.GetValidInsertionPoints(), "Insertion points to scan (all by default)")
+	scanCmd.Flags().StringArrayVar(&includeParameters, "include-param", nil, "Only scan the insertion points whose name matches these globs (e.g. user*)")
+	scanCmd.Flags().StringArrayVar(&excludeParameters, "exclude-param", nil, "Do not scan the insertion points whose name matches these globs (e.g. csrf*)")
+	scanCmd.Flags().StringArrayVar(&parameterLocations, "param-location", nil, "Only scan the insertion points in these locations (query, path, body, header, cookie)")
+	scanCmd.Flags().StringArrayVar(&parameterDataTypes, "param-type", nil, "Only scan the insertion points whose value has these data types (e.g. String, Integer, URL)")
 	scanCmd.Flags().BoolVar(&experimentalAudits, "experimental", false, "Enable experimental audits")
 	scanCmd.Flags().BoolVar(&serverSideChecks, "server-side", true, "Enable server-side audits")
 	scanCmd.Flags().BoolVar(&clientSideChecks, "client-side", true, "Enable client-side audits")
 	scanCmd.Flags().BoolVar(&passiveChecks, "passive", true, "Enable passive audits")
+	scanCmd.Flags().BoolVar(&crawlOnly, "crawl-only", false, "Only crawl the targets to build the site map, without running any audit")
+	scanCmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "Maximum duration of the scan, once reached no more audits are started and the scan is finalized (e.g. 30m, 2h)")
+	scanCmd.Flags().BoolVar(&scanWAFEvasion, "waf-evasion", false, "Retry the payloads blocked by a WAF with mutations such as case variation, comment insertion and alternate encodings")
+	scanCmd.Flags().Int64Var(&scanRandomSeed, "seed", 0, "Seed used to shuffle the order in which insertion points and payloads are tested, the same seed always gives the same order (0 keeps them sorted)")
 }
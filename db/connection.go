@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	stdlog "log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/spf13/viper"
@@ -15,8 +16,10 @@ import (
 )
 
 type DatabaseConnection struct {
-	db    *gorm.DB
-	sqlDb *sql.DB
+	db               *gorm.DB
+	sqlDb            *sql.DB
+	batcher          *IssueBatcher
+	issueBatcherOnce sync.Once
 }
 
 var Connection = InitDb()
@@ -65,11 +68,25 @@ func InitDb() *DatabaseConnection {
 	// 	os.Exit(1)
 	// }
 
+	// Issues created before the status was introduced need their status backfilled once, after adding the column
+	backfillIssueStatus := !db.Migrator().HasColumn(&Issue{}, "status")
+
 	// Migrate other tables
-	if err := db.AutoMigrate(&Workspace{}, &History{}, &Issue{}, &OOBTest{}, &OOBInteraction{}, &Task{}, &TaskJob{}, &WebSocketConnection{}, &WebSocketMessage{}, &JsonWebToken{}, &WorkspaceCookie{}, &StoredBrowserActions{}, &User{}, &RefreshToken{}); err != nil {
+	if err := db.AutoMigrate(&Workspace{}, &History{}, &Issue{}, &OOBTest{}, &OOBInteraction{}, &Task{}, &TaskJob{}, &WebSocketConnection{}, &WebSocketMessage{}, &JsonWebToken{}, &WorkspaceCookie{}, &StoredBrowserActions{}, &User{}, &RefreshToken{}, &ScanEvent{}, &ScanProfile{}, &SuppressionRule{}, &IssueStatusChange{}); err != nil {
 		log.Error().Err(err).Msg("Failed to migrate other tables")
 		os.Exit(1)
 	}
+	if backfillIssueStatus {
+		// Issues flagged as false positives before the status was introduced are created as open
+		db.Exec("UPDATE issues SET status = ? WHERE false_positive = ? AND status = ?", IssueStatusFalsePositive, true, IssueStatusOpen)
+	}
+	// The resolved flag set by revalidations is now part of the status
+	if db.Migrator().HasColumn(&Issue{}, "resolved") {
+		db.Exec("UPDATE issues SET status = ? WHERE resolved = ? AND status IN ?", IssueStatusResolved, true, []IssueStatus{IssueStatusOpen, IssueStatusConfirmed})
+		if err := db.Migrator().DropColumn(&Issue{}, "resolved"); err != nil {
+			log.Error().Err(err).Msg("Failed to drop the resolved column of the issues table")
+		}
+	}
 
 	if err := db.AutoMigrate(&PlaygroundCollection{}, &PlaygroundSession{}); err != nil {
 		log.Error().Err(err).Msg("Failed to migrate PlaygroundCollection or PlaygroundSession table")
@@ -18,33 +18,34 @@ import (
 type History struct {
 	// Similar schema: https://github.com/gilcrest/httplog
 	BaseModel
-	StatusCode           int               `gorm:"index" json:"status_code"`
-	URL                  string            `gorm:"index" json:"url"`
-	Depth                int               `gorm:"index" json:"depth"`
-	RequestHeaders       datatypes.JSON    `json:"request_headers"  swaggerignore:"true"`
-	RequestBody          []byte            `json:"request_body"`
-	RequestBodySize      int               `gorm:"index" json:"request_body_size"`
-	RequestContentLength int64             `json:"request_content_length"`
-	ResponseHeaders      datatypes.JSON    `json:"response_headers" swaggerignore:"true"`
-	ResponseBody         []byte            `json:"response_body"`
-	RequestContentType   string            `gorm:"index" json:"request_content_type"`
-	ResponseBodySize     int               `gorm:"index" json:"response_body_size"`
-	ResponseContentType  string            `gorm:"index" json:"response_content_type"`
-	RawRequest           []byte            `json:"raw_request"`
-	RawResponse          []byte            `json:"raw_response"`
-	Method               string            `gorm:"index" json:"method"`
-	Proto                string            `json:"proto" gorm:"index"`
-	ParametersCount      int               `gorm:"index" json:"parameters_count"`
-	Evaluated            bool              `gorm:"index" json:"evaluated"`
-	Note                 string            `json:"note"`
-	Source               string            `gorm:"index" json:"source"`
-	JsonWebTokens        []JsonWebToken    `gorm:"many2many:json_web_token_histories;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"json_web_tokens"`
-	Workspace            Workspace         `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	WorkspaceID          *uint             `json:"workspace_id" gorm:"index"`
-	TaskID               *uint             `json:"task_id" gorm:"index" `
-	Task                 Task              `json:"-" gorm:"foreignKey:TaskID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	PlaygroundSessionID  *uint             `json:"playground_session_id" gorm:"index" `
-	PlaygroundSession    PlaygroundSession `json:"-" gorm:"foreignKey:PlaygroundSessionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	StatusCode            int               `gorm:"index" json:"status_code"`
+	URL                   string            `gorm:"index" json:"url"`
+	Depth                 int               `gorm:"index" json:"depth"`
+	RequestHeaders        datatypes.JSON    `json:"request_headers"  swaggerignore:"true"`
+	RequestBody           []byte            `json:"request_body"`
+	RequestBodySize       int               `gorm:"index" json:"request_body_size"`
+	RequestContentLength  int64             `json:"request_content_length"`
+	ResponseHeaders       datatypes.JSON    `json:"response_headers" swaggerignore:"true"`
+	ResponseBody          []byte            `json:"response_body"`
+	RequestContentType    string            `gorm:"index" json:"request_content_type"`
+	ResponseBodySize      int               `gorm:"index" json:"response_body_size"`
+	ResponseBodyTruncated bool              `gorm:"index" json:"response_body_truncated"`
+	ResponseContentType   string            `gorm:"index" json:"response_content_type"`
+	RawRequest            []byte            `json:"raw_request"`
+	RawResponse           []byte            `json:"raw_response"`
+	Method                string            `gorm:"index" json:"method"`
+	Proto                 string            `json:"proto" gorm:"index"`
+	ParametersCount       int               `gorm:"index" json:"parameters_count"`
+	Evaluated             bool              `gorm:"index" json:"evaluated"`
+	Note                  string            `json:"note"`
+	Source                string            `gorm:"index" json:"source"`
+	JsonWebTokens         []JsonWebToken    `gorm:"many2many:json_web_token_histories;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"json_web_tokens"`
+	Workspace             Workspace         `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	WorkspaceID           *uint             `json:"workspace_id" gorm:"index"`
+	TaskID                *uint             `json:"task_id" gorm:"index" `
+	Task                  Task              `json:"-" gorm:"foreignKey:TaskID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	PlaygroundSessionID   *uint             `json:"playground_session_id" gorm:"index" `
+	PlaygroundSession     PlaygroundSession `json:"-" gorm:"foreignKey:PlaygroundSessionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
 func (h History) Logger() *zerolog.Logger {
@@ -174,6 +175,16 @@ func (h *History) GetResponseHeadersAsString() (string, error) {
 	return strings.Join(headers, "\n"), nil
 }
 
+// CurlCommand returns a curl command which sends the request of the history item again
+func (h *History) CurlCommand() (string, error) {
+	headers, err := h.GetRequestHeadersAsMap()
+	if err != nil {
+		log.Error().Err(err).Uint("history", h.ID).Msg("Error getting request headers as map")
+		return "", err
+	}
+	return lib.BuildCurlCommand(h.Method, h.URL, headers, h.RequestBody), nil
+}
+
 func (h *History) getCreateQueryData() (History, History) {
 	conditions := History{
 		URL:                 h.URL,
@@ -183,12 +194,13 @@ func (h *History) getCreateQueryData() (History, History) {
 		ResponseBodySize:    h.ResponseBodySize,
 	}
 	attrs := History{
-		RequestHeaders:       h.RequestHeaders,
-		RequestContentLength: h.RequestContentLength,
-		ResponseHeaders:      h.ResponseHeaders,
-		ResponseBody:         h.ResponseBody,
-		Evaluated:            h.Evaluated,
-		Note:                 h.Note,
+		RequestHeaders:        h.RequestHeaders,
+		RequestContentLength:  h.RequestContentLength,
+		ResponseHeaders:       h.ResponseHeaders,
+		ResponseBody:          h.ResponseBody,
+		ResponseBodyTruncated: h.ResponseBodyTruncated,
+		Evaluated:             h.Evaluated,
+		Note:                  h.Note,
 	}
 	return conditions, attrs
 }
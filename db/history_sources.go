@@ -7,6 +7,7 @@ var SourceHijack = "Hijack"
 var SourceRepeater = "Repeater"
 var SourceBrowser = "Browser"
 var SourceFuzzer = "Fuzzer"
+var SourceImport = "Import"
 
 var Sources = []string{
 	SourceScanner,
@@ -16,6 +17,7 @@ var Sources = []string{
 	SourceRepeater,
 	SourceBrowser,
 	SourceFuzzer,
+	SourceImport,
 }
 
 func IsValidSource(source string) bool {
@@ -34,5 +36,6 @@ func GetSitemapSources() []string {
 		SourceCrawler,
 		SourceBrowser,
 		SourceProxy,
+		SourceImport,
 	}
 }
@@ -3,10 +3,14 @@ package db
 import (
 	"fmt"
 	"sort"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/lib/notifications"
 
 	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
 )
 
 // Issue holds table for storing issues found
@@ -25,13 +29,17 @@ type Issue struct {
 	Request       []byte      `json:"request"`
 	Response      []byte      `json:"response"`
 	FalsePositive bool        `gorm:"index" json:"false_positive"`
-	Confidence    int         `gorm:"index" json:"confidence"`
-	References    StringSlice `json:"references"`
-	Severity      severity    `gorm:"index,type:severity;default:'Info'" json:"severity"`
-	CURLCommand   string      `json:"curl_command"`
-	Note          string      `json:"note"`
-	Workspace     Workspace   `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	WorkspaceID   *uint       `json:"workspace_id" gorm:"index"`
+	Status        IssueStatus `gorm:"index;default:'open'" json:"status"`
+	// AssignedToUserID is the user responsible for triaging the issue
+	AssignedToUserID *uuid.UUID  `json:"assigned_to_user_id" gorm:"type:uuid;index"`
+	AssignedTo       *User       `json:"-" gorm:"foreignKey:AssignedToUserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Confidence       int         `gorm:"index" json:"confidence"`
+	References       StringSlice `json:"references"`
+	Severity         severity    `gorm:"index,type:severity;default:'Info'" json:"severity"`
+	CURLCommand      string      `json:"curl_command"`
+	Note             string      `json:"note"`
+	Workspace        Workspace   `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	WorkspaceID      *uint       `json:"workspace_id" gorm:"index"`
 	// OriginalHistory   History          `json:"original_history" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	// OriginalHistoryID *uint            `json:"original_history_id" gorm:"index"`
 	Interactions          []OOBInteraction     `json:"interactions" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
@@ -42,6 +50,13 @@ type Issue struct {
 	TaskJob               TaskJob              `json:"-" gorm:"foreignKey:TaskJobID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	WebsocketConnectionID *uint                `json:"websocket_connection_id" gorm:"index;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	WebSocketConnection   *WebSocketConnection `json:"-" gorm:"foreignKey:WebsocketConnectionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	// InsertionPoint and NormalizedURL identify the issue across scans, together with the workspace and code
+	InsertionPoint string `json:"insertion_point" gorm:"index"`
+	NormalizedURL  string `json:"normalized_url" gorm:"index"`
+	// Occurrences is the number of times the issue has been found
+	Occurrences int `json:"occurrences" gorm:"default:1"`
+	// RevalidatedAt is the last time the issue has been revalidated, its outcome is reflected in the status
+	RevalidatedAt *time.Time `json:"revalidated_at"`
 }
 
 func (i Issue) TableHeaders() []string {
@@ -124,10 +139,50 @@ func (i Issue) AddInteraction(interaction OOBInteraction) error {
 	return Connection.db.Model(&i).Association("Interactions").Append(&interaction)
 }
 
-// UpdateFalsePositive updates the FalsePositive attribute of an issue in the database.
-func (i Issue) UpdateFalsePositive(value bool) error {
-	i.FalsePositive = value
-	return Connection.db.Model(&i).Update("false_positive", value).Error
+// UpdateIssueRevalidation stores the outcome of revalidating the issue and its new confidence. Issues no longer
+// reproduced are resolved and resolved issues reproduced again are reopened, recording the status change, while
+// issues closed with other statuses keep them.
+func (d *DatabaseConnection) UpdateIssueRevalidation(issue *Issue, confidence int, reproduced bool) (*IssueStatusChange, error) {
+	current := issue.Status
+	if current == "" {
+		current = IssueStatusOpen
+	}
+	var status IssueStatus
+	var note string
+	if !reproduced && current.CanTransitionTo(IssueStatusResolved) {
+		status = IssueStatusResolved
+		note = "Revalidation no longer reproduces the issue"
+	} else if reproduced && current == IssueStatusResolved {
+		status = IssueStatusOpen
+		note = "Revalidation reproduces the issue again"
+	}
+
+	now := time.Now()
+	var change *IssueStatusChange
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(issue).Updates(map[string]interface{}{
+			"confidence":     confidence,
+			"revalidated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		if status == "" {
+			return nil
+		}
+		var err error
+		change, err = updateIssueStatus(tx, issue, status, nil, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	issue.Confidence = confidence
+	issue.RevalidatedAt = &now
+	if status != "" {
+		issue.Status = status
+		issue.FalsePositive = false
+	}
+	return change, nil
 }
 
 func (i Issue) IsEmpty() bool {
@@ -136,68 +191,120 @@ func (i Issue) IsEmpty() bool {
 
 // IssueFilter represents available issue filters
 type IssueFilter struct {
-	Codes         []string
-	WorkspaceID   uint
-	TaskID        uint
-	TaskJobID     uint
+	Codes       []string
+	Severities  []string
+	WorkspaceID uint
+	TaskID      uint
+	TaskJobID   uint
+	// URL filters the issues whose URL contains the value
 	URL           string
 	MinConfidence int
+	// FalsePositive filters by the false positive flag when set
+	FalsePositive *bool
+	Statuses      []IssueStatus
+	// AssignedTo filters the issues assigned to the user, while Unassigned filters the ones not assigned to anyone
+	AssignedTo *uuid.UUID
+	Unassigned bool
+	// Pagination is only applied when the page size is set, otherwise all the matching issues are returned
+	Pagination Pagination
+	SortBy     string `validate:"omitempty,oneof=id created_at updated_at severity confidence title code url"`
+	SortOrder  string `validate:"omitempty,oneof=asc desc"`
 }
 
-// ListIssues Lists issues
-func (d *DatabaseConnection) ListIssues(filter IssueFilter) (issues []*Issue, count int64, err error) {
-	query := d.db
-
+// applyIssueFilter adds the conditions of the filter to the query
+func applyIssueFilter(query *gorm.DB, filter IssueFilter) *gorm.DB {
 	if len(filter.Codes) > 0 {
 		query = query.Where("code IN ?", filter.Codes)
 	}
-
+	if len(filter.Severities) > 0 {
+		query = query.Where("severity IN ?", filter.Severities)
+	}
 	if filter.WorkspaceID != 0 {
 		query = query.Where("workspace_id = ?", filter.WorkspaceID)
 	}
-
 	if filter.URL != "" {
-		query = query.Where("url = ?", filter.URL)
+		// strpos matches the value literally, so LIKE wildcards such as % or _ in URLs are not interpreted
+		query = query.Where("strpos(url, ?) > 0", filter.URL)
 	}
 	if filter.TaskID != 0 {
 		query = query.Where("task_id = ?", filter.TaskID)
 	}
-
 	if filter.TaskJobID != 0 {
 		query = query.Where("task_job_id = ?", filter.TaskJobID)
 	}
-
 	if filter.MinConfidence > 0 {
 		query = query.Where("confidence >= ?", filter.MinConfidence)
 	}
-
-	result := query.Order(severityOrderQuery).Order("title ASC, created_at DESC").Find(&issues).Count(&count)
-
-	if result.Error != nil {
-		err = result.Error
+	if filter.FalsePositive != nil {
+		query = query.Where("false_positive = ?", *filter.FalsePositive)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
 	}
+	if filter.AssignedTo != nil {
+		query = query.Where("assigned_to_user_id = ?", *filter.AssignedTo)
+	} else if filter.Unassigned {
+		query = query.Where("assigned_to_user_id IS NULL")
+	}
+	return query
+}
 
-	return issues, count, err
+// issueListOrder returns the order clauses of the issues list. By default the most severe issues come first, and
+// sorting by severity in descending order also lists them from critical to unknown.
+func issueListOrder(filter IssueFilter) []string {
+	validSortBy := map[string]bool{
+		"id":         true,
+		"created_at": true,
+		"updated_at": true,
+		"severity":   true,
+		"confidence": true,
+		"title":      true,
+		"code":       true,
+		"url":        true,
+	}
+	if !validSortBy[filter.SortBy] {
+		return []string{severityOrderQuery, "title ASC, created_at DESC, id DESC"}
+	}
+	sortOrder := "ASC"
+	if filter.SortOrder == "desc" {
+		sortOrder = "DESC"
+	}
+	if filter.SortBy == "severity" {
+		// The severity rank is lower for the most severe issues
+		rankOrder := "DESC"
+		if sortOrder == "DESC" {
+			rankOrder = "ASC"
+		}
+		return []string{severityOrderQuery + " " + rankOrder, "id DESC"}
+	}
+	return []string{filter.SortBy + " " + sortOrder, "id DESC"}
 }
 
-func (d *DatabaseConnection) ListIssuesGrouped(filter IssueFilter) ([]*GroupedIssue, error) {
-	var issues []Issue
-	query := d.db.Model(&Issue{}).Select("id, url, confidence, title, code, severity")
+// ListIssues Lists issues
+func (d *DatabaseConnection) ListIssues(filter IssueFilter) (issues []*Issue, count int64, err error) {
+	query := applyIssueFilter(d.db.Model(&Issue{}), filter)
 
-	// Apply filters
-	if len(filter.Codes) > 0 {
-		query = query.Where("code IN ?", filter.Codes)
+	if err = query.Count(&count).Error; err != nil {
+		return nil, 0, err
 	}
-	if filter.WorkspaceID != 0 {
-		query = query.Where("workspace_id = ?", filter.WorkspaceID)
+
+	for _, order := range issueListOrder(filter) {
+		query = query.Order(order)
 	}
-	if filter.TaskID != 0 {
-		query = query.Where("task_id = ?", filter.TaskID)
+	if filter.Pagination.PageSize > 0 {
+		query = query.Scopes(Paginate(&filter.Pagination))
 	}
-	if filter.TaskJobID != 0 {
-		query = query.Where("task_job_id = ?", filter.TaskJobID)
+	if err = query.Find(&issues).Error; err != nil {
+		return nil, 0, err
 	}
 
+	return issues, count, nil
+}
+
+func (d *DatabaseConnection) ListIssuesGrouped(filter IssueFilter) ([]*GroupedIssue, error) {
+	var issues []Issue
+	query := applyIssueFilter(d.db.Model(&Issue{}).Select("id, url, confidence, title, code, severity"), filter)
+
 	// Execute the query
 	err := query.Find(&issues).Error
 	if err != nil {
@@ -250,6 +357,49 @@ func (d *DatabaseConnection) ListIssuesGrouped(filter IssueFilter) ([]*GroupedIs
 	return groupedIssues, nil
 }
 
+// IssueGroupBy represents the available strategies to group issues
+type IssueGroupBy string
+
+const (
+	IssueGroupByCode     IssueGroupBy = "code"
+	IssueGroupByHost     IssueGroupBy = "host"
+	IssueGroupByCwe      IssueGroupBy = "cwe"
+	IssueGroupBySeverity IssueGroupBy = "severity"
+)
+
+var issueGroupByExpressions = map[IssueGroupBy]string{
+	IssueGroupByCode:     "code",
+	IssueGroupByHost:     "COALESCE(substring(url from '^[a-zA-Z][a-zA-Z0-9+.-]*://([^/?#]+)'), '')",
+	IssueGroupByCwe:      "CAST(cwe AS TEXT)",
+	IssueGroupBySeverity: "CAST(severity AS TEXT)",
+}
+
+// IsValid checks if the grouping strategy is supported
+func (g IssueGroupBy) IsValid() bool {
+	_, ok := issueGroupByExpressions[g]
+	return ok
+}
+
+// IssueGroupCount holds the number of issues for a given group
+type IssueGroupCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// CountIssuesGroupedBy aggregates the issues matching the filter by the provided grouping strategy
+func (d *DatabaseConnection) CountIssuesGroupedBy(filter IssueFilter, groupBy IssueGroupBy) ([]*IssueGroupCount, error) {
+	expression, ok := issueGroupByExpressions[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group by value: %s", groupBy)
+	}
+
+	query := applyIssueFilter(d.db.Model(&Issue{}).Select(expression+" AS key, COUNT(*) AS count"), filter)
+
+	var groups []*IssueGroupCount
+	err := query.Group(expression).Order("count DESC").Order("key ASC").Scan(&groups).Error
+	return groups, err
+}
+
 // ListIssuesGrouped Lists grouped issues
 // func (d *DatabaseConnection) ListIssuesGrouped(filter IssueFilter) (issues []*GroupedIssue, err error) {
 // 	query := d.db.Model(&Issue{}).Select("title, severity, code, COUNT(*)").Group("title,severity,code")
@@ -287,13 +437,165 @@ func (d *DatabaseConnection) CreateIssue(issue Issue) (Issue, error) {
 		issue.TaskJobID = nil
 	}
 
-	result := d.db.FirstOrCreate(&issue, issue)
+	if batcher := d.issueBatcher(); batcher != nil {
+		return batcher.Create(issue)
+	}
+	return d.createIssue(d.db, issue)
+}
+
+func (d *DatabaseConnection) createIssue(tx *gorm.DB, issue Issue) (Issue, error) {
+	result := tx.FirstOrCreate(&issue, issue)
 	if result.Error != nil {
 		log.Error().Err(result.Error).Interface("issue", issue).Msg("Failed to create web issue")
+	} else if result.RowsAffected > 0 {
+		notifyIssueCreated(issue)
 	}
 	return issue, result.Error
 }
 
+// findRepeatedIssue returns the existing issue in the same workspace with the same code, normalized URL and insertion
+// point. Only open and confirmed issues are matched, so issues found again after being resolved, ignored or marked as
+// false positives are reported as new ones, while issues suppressed by a rule are matched against the false positives.
+// When the insertion point is unknown, the details must also match, so different findings on the same URL are not
+// merged.
+func findRepeatedIssue(tx *gorm.DB, issue Issue) (Issue, bool, error) {
+	statuses := []IssueStatus{IssueStatusOpen, IssueStatusConfirmed}
+	if issue.FalsePositive {
+		statuses = []IssueStatus{IssueStatusFalsePositive}
+	}
+	var existing Issue
+	query := tx.Model(&Issue{}).Where("code = ? AND normalized_url = ? AND insertion_point = ? AND status IN ?", issue.Code, issue.NormalizedURL, issue.InsertionPoint, statuses)
+	if issue.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *issue.WorkspaceID)
+	} else {
+		query = query.Where("workspace_id IS NULL")
+	}
+	if issue.InsertionPoint == "" {
+		query = query.Where("details = ?", issue.Details)
+	}
+	result := query.Order("id asc").Limit(1).Find(&existing)
+	if result.Error != nil {
+		return existing, false, result.Error
+	}
+	return existing, result.RowsAffected > 0, nil
+}
+
+// repeatedIssueLockKey returns the key used to serialize the creation of issues that would be deduplicated together
+func repeatedIssueLockKey(issue Issue) string {
+	workspace := "none"
+	if issue.WorkspaceID != nil {
+		workspace = fmt.Sprint(*issue.WorkspaceID)
+	}
+	key := fmt.Sprintf("issue:%s:%s:%s:%s:%t", workspace, issue.Code, issue.NormalizedURL, issue.InsertionPoint, issue.FalsePositive)
+	if issue.InsertionPoint == "" {
+		key += ":" + issue.Details
+	}
+	return key
+}
+
+// CreateOrIncrementIssue creates the issue unless it has already been found in the workspace, in which case the
+// occurrences of the existing issue are incremented and the history item is added to its requests. It returns the
+// stored issue and whether it already existed. The lookup and the write run in a transaction holding an advisory
+// lock on the deduplication key, so concurrent scans reporting the same issue don't create duplicates. When issue
+// batching is enabled, the deduplication runs within the batch transaction.
+func (d *DatabaseConnection) CreateOrIncrementIssue(issue Issue, history *History) (Issue, bool, error) {
+	if issue.TaskID != nil && *issue.TaskID == 0 {
+		issue.TaskID = nil
+	}
+	if issue.TaskJobID != nil && *issue.TaskJobID == 0 {
+		issue.TaskJobID = nil
+	}
+
+	if batcher := d.issueBatcher(); batcher != nil {
+		return batcher.CreateOrIncrement(issue, history)
+	}
+	return d.createOrIncrementIssue(issue, history)
+}
+
+// createOrIncrementIssue deduplicates the issue in its own transaction, notifying it once committed when it is new
+func (d *DatabaseConnection) createOrIncrementIssue(issue Issue, history *History) (Issue, bool, error) {
+	var stored Issue
+	var found bool
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		stored, found, err = createOrIncrementIssueTx(tx, issue, history)
+		return err
+	})
+	if err == nil && !found {
+		notifyIssueCreated(stored)
+	}
+	return stored, found, err
+}
+
+// createOrIncrementIssueTx creates the issue or increments the occurrences of the repeated one within the transaction.
+// Notifications are left to the caller, as they must only be sent once the transaction has been committed.
+func createOrIncrementIssueTx(tx *gorm.DB, issue Issue, history *History) (Issue, bool, error) {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", repeatedIssueLockKey(issue)).Error; err != nil {
+		log.Error().Err(err).Str("code", issue.Code).Str("url", issue.URL).Msg("Failed to lock repeated issue lookup")
+		return issue, false, err
+	}
+	existing, found, err := findRepeatedIssue(tx, issue)
+	if err != nil {
+		log.Error().Err(err).Str("code", issue.Code).Str("url", issue.URL).Msg("Failed to look for repeated issues")
+		return issue, false, err
+	}
+	if !found {
+		if err := tx.Create(&issue).Error; err != nil {
+			log.Error().Err(err).Interface("issue", issue).Msg("Failed to create web issue")
+			return issue, false, err
+		}
+		return issue, false, nil
+	}
+
+	err = tx.Model(&existing).Updates(map[string]interface{}{
+		"occurrences": gorm.Expr("occurrences + 1"),
+		"updated_at":  time.Now(),
+	}).Error
+	if err != nil {
+		log.Error().Err(err).Uint("id", existing.ID).Msg("Failed to increment issue occurrences")
+		return existing, true, err
+	}
+	if history != nil && history.ID != 0 {
+		// Nested transactions run in a savepoint, so a failed append doesn't abort the increment
+		err := tx.Transaction(func(inner *gorm.DB) error {
+			return inner.Model(&existing).Association("Requests").Append(history)
+		})
+		if err != nil {
+			log.Warn().Err(err).Uint("id", existing.ID).Uint("history", history.ID).Msg("Failed to add request to repeated issue")
+		}
+	}
+	var stored Issue
+	err = tx.First(&stored, existing.ID).Error
+	return stored, true, err
+}
+
+// notifyIssue delivers the issue notifications, it can be replaced in tests
+var notifyIssue = notifications.NotifyIssue
+
+// notifyIssueCreated sends the notifications configured for new issues. Issues stored as false positives, such as
+// the ones matching a suppression rule, are not notified.
+func notifyIssueCreated(issue Issue) {
+	if issue.FalsePositive {
+		return
+	}
+	notification := notifications.IssueNotification{
+		Event:     notifications.IssueCreatedEvent,
+		IssueID:   issue.ID,
+		Code:      issue.Code,
+		Title:     issue.Title,
+		Severity:  issue.Severity.String(),
+		URL:       issue.URL,
+		CreatedAt: issue.CreatedAt,
+	}
+	if issue.WorkspaceID != nil {
+		notification.WorkspaceID = *issue.WorkspaceID
+	}
+	if issue.TaskID != nil {
+		notification.ScanID = *issue.TaskID
+	}
+	notifyIssue(notification)
+}
+
 // GetIssue get a single issue by ID
 func (d *DatabaseConnection) GetIssue(id int, includeRelated bool) (issue Issue, err error) {
 	query := d.db
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"github.com/google/uuid"
+	"github.com/pyneda/sukyan/lib/notifications"
+	"github.com/rs/zerolog/log"
+)
+
+// AssignIssue assigns the issue to the user, or unassigns it when no user is provided. The notifications configured
+// for issues are sent when it is assigned to a different user.
+func (d *DatabaseConnection) AssignIssue(issue *Issue, userID *uuid.UUID) error {
+	var user *User
+	if userID != nil {
+		var err error
+		user, err = d.GetUserByID(*userID)
+		if err != nil {
+			return err
+		}
+	}
+	if err := d.db.Model(issue).Update("assigned_to_user_id", userID).Error; err != nil {
+		log.Error().Err(err).Uint("issue", issue.ID).Msg("Failed to update the issue assignee")
+		return err
+	}
+	previous := issue.AssignedToUserID
+	issue.AssignedToUserID = userID
+	if user != nil && (previous == nil || *previous != user.ID) {
+		notifyIssueAssigned(*issue, user)
+	}
+	return nil
+}
+
+// notifyIssueAssigned sends the notifications configured for issues when one is assigned to a user
+func notifyIssueAssigned(issue Issue, user *User) {
+	notification := notifications.IssueNotification{
+		Event:      notifications.IssueAssignedEvent,
+		IssueID:    issue.ID,
+		Code:       issue.Code,
+		Title:      issue.Title,
+		Severity:   issue.Severity.String(),
+		URL:        issue.URL,
+		CreatedAt:  issue.CreatedAt,
+		AssignedTo: user.Email,
+	}
+	if issue.WorkspaceID != nil {
+		notification.WorkspaceID = *issue.WorkspaceID
+	}
+	if issue.TaskID != nil {
+		notification.ScanID = *issue.TaskID
+	}
+	notifications.NotifyIssue(notification)
+}
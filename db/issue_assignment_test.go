@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignIssue(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:        "TestAssignIssue",
+		Title:       "TestAssignIssue",
+		Description: "TestAssignIssue",
+	})
+	assert.Nil(t, err)
+	user, err := Connection.CreateUser(&User{Email: "assignee-" + uuid.NewString() + "@example.com", Active: true})
+	assert.Nil(t, err)
+
+	template := GetIssueTemplateByCode(XssReflectedCode)
+	template.URL = "https://example.com/assigned?q=1"
+	template.WorkspaceID = &workspace.ID
+	issue, err := Connection.CreateIssue(*template)
+	assert.Nil(t, err)
+	assert.Nil(t, issue.AssignedToUserID)
+
+	err = Connection.AssignIssue(&issue, &user.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, *issue.AssignedToUserID)
+
+	assigned, count, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, AssignedTo: &user.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, issue.ID, assigned[0].ID)
+
+	unknown := uuid.New()
+	err = Connection.AssignIssue(&issue, &unknown)
+	assert.NotNil(t, err)
+	assert.Equal(t, user.ID, *issue.AssignedToUserID)
+
+	err = Connection.AssignIssue(&issue, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, issue.AssignedToUserID)
+	stored, err := Connection.GetIssue(int(issue.ID), false)
+	assert.Nil(t, err)
+	assert.Nil(t, stored.AssignedToUserID)
+
+	_, count, err = Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, AssignedTo: &user.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+	unassigned, _, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Unassigned: true})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, unassigned)
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultIssueBatchWindow = 50 * time.Millisecond
+	defaultIssueBatchSize   = 100
+)
+
+type issueBatchResult struct {
+	issue    Issue
+	repeated bool
+	err      error
+}
+
+type issueBatchRequest struct {
+	issue Issue
+	// deduplicate is set for the issues which increment the occurrences of a repeated one instead of being created,
+	// adding the history item to its requests
+	deduplicate bool
+	history     *History
+	result      chan issueBatchResult
+}
+
+// IssueBatcher groups the issues created within a short window and inserts them in a single transaction, reducing the
+// database contention when many issues are created concurrently. Callers still block until their issue has been
+// persisted, so they receive the created (or already existing) issue as with unbatched creation.
+type IssueBatcher struct {
+	conn     *DatabaseConnection
+	window   time.Duration
+	maxSize  int
+	requests chan issueBatchRequest
+	mu       sync.RWMutex
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewIssueBatcher creates and starts an issue batcher which flushes every window or when maxSize issues are pending
+func NewIssueBatcher(conn *DatabaseConnection, window time.Duration, maxSize int) *IssueBatcher {
+	if window <= 0 {
+		window = defaultIssueBatchWindow
+	}
+	if maxSize <= 0 {
+		maxSize = defaultIssueBatchSize
+	}
+	b := &IssueBatcher{
+		conn:     conn,
+		window:   window,
+		maxSize:  maxSize,
+		requests: make(chan issueBatchRequest, maxSize),
+	}
+	b.wg.Add(1)
+	go b.run()
+	return b
+}
+
+// Create queues the issue to be inserted in the next batch and waits until it has been persisted. Once the batcher
+// has been closed, issues are created directly.
+func (b *IssueBatcher) Create(issue Issue) (Issue, error) {
+	result, ok := b.enqueue(issueBatchRequest{issue: issue})
+	if !ok {
+		return b.conn.createIssue(b.conn.db, issue)
+	}
+	return result.issue, result.err
+}
+
+// CreateOrIncrement queues the issue to be deduplicated in the next batch as CreateOrIncrementIssue does, waiting
+// until it has been persisted. Once the batcher has been closed, issues are deduplicated directly.
+func (b *IssueBatcher) CreateOrIncrement(issue Issue, history *History) (Issue, bool, error) {
+	result, ok := b.enqueue(issueBatchRequest{issue: issue, deduplicate: true, history: history})
+	if !ok {
+		return b.conn.createOrIncrementIssue(issue, history)
+	}
+	return result.issue, result.repeated, result.err
+}
+
+// enqueue sends the request to the next batch and waits for its result, returning false if the batcher is closed
+func (b *IssueBatcher) enqueue(request issueBatchRequest) (issueBatchResult, bool) {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return issueBatchResult{}, false
+	}
+	request.result = make(chan issueBatchResult, 1)
+	b.requests <- request
+	b.mu.RUnlock()
+
+	return <-request.result, true
+}
+
+// Close flushes the pending issues and stops the batcher
+func (b *IssueBatcher) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.requests)
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+func (b *IssueBatcher) run() {
+	defer b.wg.Done()
+	for {
+		first, ok := <-b.requests
+		if !ok {
+			return
+		}
+		batch := []issueBatchRequest{first}
+		timer := time.NewTimer(b.window)
+	collect:
+		for len(batch) < b.maxSize {
+			select {
+			case request, ok := <-b.requests:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, request)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		b.flush(batch)
+	}
+}
+
+// flush inserts the batch within a transaction. Issues are processed sequentially, so duplicated issues within the
+// same batch resolve to the same row, or increment the occurrences of the same repeated issue. If the transaction fails, issues are created one by one so that a single
+// invalid issue does not prevent the others from being stored.
+func (b *IssueBatcher) flush(batch []issueBatchRequest) {
+	results := make([]issueBatchResult, len(batch))
+	var created []Issue
+	err := b.conn.db.Transaction(func(tx *gorm.DB) error {
+		created = created[:0]
+		for i, request := range batch {
+			if request.deduplicate {
+				issue, repeated, err := createOrIncrementIssueTx(tx, request.issue, request.history)
+				if err != nil {
+					return err
+				}
+				if !repeated {
+					created = append(created, issue)
+				}
+				results[i] = issueBatchResult{issue: issue, repeated: repeated}
+				continue
+			}
+			issue := request.issue
+			result := tx.FirstOrCreate(&issue, issue)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, issue)
+			}
+			results[i] = issueBatchResult{issue: issue}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Int("issues", len(batch)).Msg("Failed to create issues batch, creating them individually")
+		for i, request := range batch {
+			if request.deduplicate {
+				issue, repeated, err := b.conn.createOrIncrementIssue(request.issue, request.history)
+				results[i] = issueBatchResult{issue: issue, repeated: repeated, err: err}
+				continue
+			}
+			issue, err := b.conn.createIssue(b.conn.db, request.issue)
+			results[i] = issueBatchResult{issue: issue, err: err}
+		}
+	} else {
+		log.Debug().Int("issues", len(batch)).Msg("Created issues batch")
+		// Notifications are only sent once the transaction has been committed
+		for _, issue := range created {
+			notifyIssueCreated(issue)
+		}
+	}
+	for i, request := range batch {
+		request.result <- results[i]
+	}
+}
+
+// issueBatcher returns the issue batcher when batching is enabled through the db.issues.batch.enabled setting
+func (d *DatabaseConnection) issueBatcher() *IssueBatcher {
+	d.issueBatcherOnce.Do(func() {
+		if !viper.GetBool("db.issues.batch.enabled") {
+			return
+		}
+		window := time.Duration(viper.GetInt("db.issues.batch.window")) * time.Millisecond
+		d.batcher = NewIssueBatcher(d, window, viper.GetInt("db.issues.batch.size"))
+		log.Info().Dur("window", d.batcher.window).Int("size", d.batcher.maxSize).Msg("Issue creation batching enabled")
+	})
+	return d.batcher
+}
+
+// CloseIssueBatcher flushes the issues pending to be created and disables batching. It should be called before exiting.
+func (d *DatabaseConnection) CloseIssueBatcher() {
+	d.issueBatcherOnce.Do(func() {})
+	if d.batcher != nil {
+		d.batcher.Close()
+	}
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBatchTestIssue(workspaceID uint, index int) Issue {
+	issue := GetIssueTemplateByCode(XssReflectedCode)
+	issue.URL = fmt.Sprintf("https://batch.example.com/%d", index)
+	issue.WorkspaceID = &workspaceID
+	issue.Confidence = 80
+	return *issue
+}
+
+func TestIssueBatcherPreservesDeduplication(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "TestIssueBatcherPreservesDeduplication",
+		Title: "TestIssueBatcherPreservesDeduplication",
+	})
+	assert.Nil(t, err)
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	batcher := NewIssueBatcher(Connection, 20*time.Millisecond, 10)
+	const distinct = 25
+	const duplicates = 3
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[int]map[uint]bool)
+	for i := 0; i < distinct; i++ {
+		ids[i] = make(map[uint]bool)
+		for j := 0; j < duplicates; j++ {
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				created, err := batcher.Create(newBatchTestIssue(workspace.ID, index))
+				assert.Nil(t, err)
+				assert.NotZero(t, created.ID)
+				mu.Lock()
+				ids[index][created.ID] = true
+				mu.Unlock()
+			}(i)
+		}
+	}
+	wg.Wait()
+	batcher.Close()
+
+	for i := 0; i < distinct; i++ {
+		assert.Len(t, ids[i], 1, "duplicated issues should resolve to the same row")
+	}
+	_, count, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(distinct), count)
+
+	// Issues created after closing the batcher are still persisted
+	created, err := batcher.Create(newBatchTestIssue(workspace.ID, distinct))
+	assert.Nil(t, err)
+	assert.NotZero(t, created.ID)
+}
+
+func BenchmarkCreateIssue(b *testing.B) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "BenchmarkCreateIssue",
+		Title: "BenchmarkCreateIssue",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	b.Run("unbatched", func(b *testing.B) {
+		var counter int64
+		var mu sync.Mutex
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mu.Lock()
+				counter++
+				index := int(counter)
+				mu.Unlock()
+				Connection.createIssue(Connection.db, newBatchTestIssue(workspace.ID, index))
+			}
+		})
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		batcher := NewIssueBatcher(Connection, defaultIssueBatchWindow, defaultIssueBatchSize)
+		defer batcher.Close()
+		var counter int64
+		var mu sync.Mutex
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mu.Lock()
+				counter++
+				index := int(counter) + 1<<30
+				mu.Unlock()
+				batcher.Create(newBatchTestIssue(workspace.ID, index))
+			}
+		})
+	})
+}
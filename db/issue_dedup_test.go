@@ -0,0 +1,239 @@
+package db
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/lib/notifications"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateIssueFromHistoryAndTemplateDeduplicates(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "TestCreateIssueFromHistoryAndTemplateDeduplicates",
+		Title: "TestCreateIssueFromHistoryAndTemplateDeduplicates",
+	})
+	assert.Nil(t, err)
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	first, err := Connection.CreateHistory(&History{
+		URL:         "https://dedup.example.com/products/view?id=1",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+	// A later scan requesting the same endpoint with a different value
+	second, err := Connection.CreateHistory(&History{
+		URL:         "https://dedup.example.com/products/list?id=2",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	options := IssueCreationOptions{InsertionPoint: "parameter: id"}
+	created, err := CreateIssueFromHistoryAndTemplateWithOptions(first, SqlInjectionCode, "first scan", 80, "", &workspace.ID, nil, nil, options)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, created.Occurrences)
+
+	repeated, err := CreateIssueFromHistoryAndTemplateWithOptions(second, SqlInjectionCode, "second scan", 80, "", &workspace.ID, nil, nil, options)
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID, repeated.ID)
+	assert.Equal(t, 2, repeated.Occurrences)
+	assert.True(t, repeated.UpdatedAt.After(created.UpdatedAt))
+
+	issues, count, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Codes: []string{string(SqlInjectionCode)}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, 2, issues[0].Occurrences)
+
+	stored, err := Connection.GetIssue(int(created.ID), true)
+	assert.Nil(t, err)
+	assert.Len(t, stored.Requests, 2)
+
+	// Other insertion points are different issues
+	other, err := CreateIssueFromHistoryAndTemplateWithOptions(first, SqlInjectionCode, "first scan", 80, "", &workspace.ID, nil, nil, IssueCreationOptions{InsertionPoint: "parameter: category"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.ID, other.ID)
+
+	// Duplicates can be explicitly allowed
+	duplicated, err := CreateIssueFromHistoryAndTemplateWithOptions(first, SqlInjectionCode, "third scan", 80, "", &workspace.ID, nil, nil, IssueCreationOptions{InsertionPoint: "parameter: id", AllowDuplicates: true})
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.ID, duplicated.ID)
+	assert.Equal(t, 1, duplicated.Occurrences)
+
+	_, count, err = Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Codes: []string{string(SqlInjectionCode)}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestCreateIssueFromHistoryAndTemplateDeduplicatesWithoutInsertionPoint(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "TestCreateIssueFromHistoryAndTemplateDeduplicatesWithoutInsertionPoint",
+		Title: "TestCreateIssueFromHistoryAndTemplateDeduplicatesWithoutInsertionPoint",
+	})
+	assert.Nil(t, err)
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	history, err := Connection.CreateHistory(&History{
+		URL:         "https://dedup.example.com/search?q=test",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	created, err := CreateIssueFromHistoryAndTemplate(history, XssReflectedCode, "reflected", 90, "", &workspace.ID, nil, nil)
+	assert.Nil(t, err)
+	// Without insertion point, only findings with the same details are merged
+	repeated, err := CreateIssueFromHistoryAndTemplate(history, XssReflectedCode, "reflected", 90, "", &workspace.ID, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID, repeated.ID)
+	different, err := CreateIssueFromHistoryAndTemplate(history, XssReflectedCode, "reflected in another parameter", 90, "", &workspace.ID, nil, nil)
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.ID, different.ID)
+	assert.Equal(t, 2, repeated.Occurrences)
+}
+
+func TestCreateIssueFromHistoryAndTemplateDeduplicatesConcurrently(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "TestCreateIssueFromHistoryAndTemplateDeduplicatesConcurrently",
+		Title: "TestCreateIssueFromHistoryAndTemplateDeduplicatesConcurrently",
+	})
+	assert.Nil(t, err)
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	history, err := Connection.CreateHistory(&History{
+		URL:         "https://dedup.example.com/concurrent?id=1",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	const scans = 10
+	var wg sync.WaitGroup
+	for i := 0; i < scans; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := CreateIssueFromHistoryAndTemplateWithOptions(history, SqlInjectionCode, "concurrent scan", 80, "", &workspace.ID, nil, nil, IssueCreationOptions{InsertionPoint: "parameter: id"})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	issues, count, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Codes: []string{string(SqlInjectionCode)}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, scans, issues[0].Occurrences)
+}
+
+func TestCreateOrIncrementIssueOnlyMatchesActiveIssues(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "TestCreateOrIncrementIssueOnlyMatchesActiveIssues",
+		Title: "TestCreateOrIncrementIssueOnlyMatchesActiveIssues",
+	})
+	assert.Nil(t, err)
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	history, err := Connection.CreateHistory(&History{
+		URL:         "https://dedup.example.com/status?id=1",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	// Notifications are sent once the issue has been committed, so it can be read from other connections
+	var notified []uint
+	original := notifyIssue
+	notifyIssue = func(notification notifications.IssueNotification) {
+		_, err := Connection.GetIssue(int(notification.IssueID), false)
+		assert.Nil(t, err)
+		notified = append(notified, notification.IssueID)
+	}
+	defer func() { notifyIssue = original }()
+
+	options := IssueCreationOptions{InsertionPoint: "parameter: id"}
+	created, err := CreateIssueFromHistoryAndTemplateWithOptions(history, SqlInjectionCode, "first scan", 80, "", &workspace.ID, nil, nil, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{created.ID}, notified)
+
+	// Resolved issues found again are reported as new ones
+	_, err = Connection.UpdateIssueStatus(&created, IssueStatusResolved, nil, "")
+	assert.Nil(t, err)
+	found, err := CreateIssueFromHistoryAndTemplateWithOptions(history, SqlInjectionCode, "second scan", 80, "", &workspace.ID, nil, nil, options)
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.ID, found.ID)
+	assert.Equal(t, IssueStatusOpen, found.Status)
+	assert.Equal(t, []uint{created.ID, found.ID}, notified)
+
+	// Confirmed issues are still incremented
+	_, err = Connection.UpdateIssueStatus(&found, IssueStatusConfirmed, nil, "")
+	assert.Nil(t, err)
+	repeated, err := CreateIssueFromHistoryAndTemplateWithOptions(history, SqlInjectionCode, "third scan", 80, "", &workspace.ID, nil, nil, options)
+	assert.Nil(t, err)
+	assert.Equal(t, found.ID, repeated.ID)
+	assert.Equal(t, 2, repeated.Occurrences)
+	assert.Len(t, notified, 2)
+}
+
+func TestCreateOrIncrementIssueWithBatching(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "TestCreateOrIncrementIssueWithBatching",
+		Title: "TestCreateOrIncrementIssueWithBatching",
+	})
+	assert.Nil(t, err)
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	history, err := Connection.CreateHistory(&History{
+		URL:         "https://dedup.example.com/batched?id=1",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	issue := FillIssueFromHistoryAndTemplate(history, SqlInjectionCode, "batched scan", 80, "", &workspace.ID, nil, nil)
+	issue.InsertionPoint = "parameter: id"
+	issue.NormalizedURL = history.URL
+
+	batcher := NewIssueBatcher(Connection, 20*time.Millisecond, 10)
+	const scans = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[uint]bool)
+	repeatedCount := 0
+	for i := 0; i < scans; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stored, repeated, err := batcher.CreateOrIncrement(*issue, history)
+			assert.Nil(t, err)
+			mu.Lock()
+			ids[stored.ID] = true
+			if repeated {
+				repeatedCount++
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	batcher.Close()
+
+	assert.Len(t, ids, 1)
+	assert.Equal(t, scans-1, repeatedCount)
+	issues, count, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Codes: []string{string(SqlInjectionCode)}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, scans, issues[0].Occurrences)
+
+	// Once closed, issues are deduplicated directly
+	stored, repeated, err := batcher.CreateOrIncrement(*issue, history)
+	assert.Nil(t, err)
+	assert.True(t, repeated)
+	assert.Equal(t, scans+1, stored.Occurrences)
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// issueExportBatchSize is the number of issues loaded from the database at a time while exporting
+const issueExportBatchSize = 100
+
+// IssueWriter writes issues to an export one at a time
+type IssueWriter interface {
+	Write(issue *Issue) error
+	Close() error
+}
+
+var issueCSVHeaders = []string{"code", "title", "severity", "url", "confidence", "cwe", "created_at"}
+
+type issueCSVWriter struct {
+	writer *csv.Writer
+}
+
+// NewIssueCSVWriter returns an IssueWriter exporting issues as CSV, starting by the header row
+func NewIssueCSVWriter(w io.Writer) (IssueWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(issueCSVHeaders); err != nil {
+		return nil, err
+	}
+	return &issueCSVWriter{writer: writer}, nil
+}
+
+// Write appends an issue as a CSV row, quoting the fields when needed
+func (iw *issueCSVWriter) Write(issue *Issue) error {
+	return iw.writer.Write([]string{
+		issue.Code,
+		issue.Title,
+		issue.Severity.String(),
+		issue.URL,
+		strconv.Itoa(issue.Confidence),
+		strconv.Itoa(issue.Cwe),
+		issue.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// Close flushes the buffered rows
+func (iw *issueCSVWriter) Close() error {
+	iw.writer.Flush()
+	return iw.writer.Error()
+}
+
+type issueJSONWriter struct {
+	w       io.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+// NewIssueJSONWriter returns an IssueWriter exporting issues as a JSON array
+func NewIssueJSONWriter(w io.Writer) (IssueWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &issueJSONWriter{w: w, encoder: encoder}, nil
+}
+
+// Write appends an issue to the JSON array
+func (iw *issueJSONWriter) Write(issue *Issue) error {
+	if iw.count > 0 {
+		if _, err := io.WriteString(iw.w, ","); err != nil {
+			return err
+		}
+	}
+	if err := iw.encoder.Encode(issue); err != nil {
+		return err
+	}
+	iw.count++
+	return nil
+}
+
+// Close finishes the JSON array
+func (iw *issueJSONWriter) Close() error {
+	_, err := io.WriteString(iw.w, "]\n")
+	return err
+}
+
+// StreamIssues writes the issues matching the filter to the writer, loading them in batches so exports do not need to
+// hold all the issues in memory. The writer is closed once all the issues have been written.
+func (d *DatabaseConnection) StreamIssues(filter IssueFilter, writer IssueWriter) error {
+	var batch []*Issue
+	exported := 0
+	query := applyIssueFilter(d.db.Model(&Issue{}), filter)
+	result := query.FindInBatches(&batch, issueExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, issue := range batch {
+			if err := writer.Write(issue); err != nil {
+				return err
+			}
+		}
+		exported += len(batch)
+		return nil
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Info().Uint("workspace", filter.WorkspaceID).Int("exported", exported).Msg("Exported issues")
+	return writer.Close()
+}
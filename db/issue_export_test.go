@@ -0,0 +1,72 @@
+package db
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueCSVWriterEscaping(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	issue := &Issue{
+		Code:       "sql_injection",
+		Title:      `SQL Injection, "union" based`,
+		Severity:   High,
+		URL:        "https://example.com/search?q=a,b&name=\"john\"",
+		Confidence: 90,
+		Cwe:        89,
+	}
+	issue.CreatedAt = createdAt
+	multiline := &Issue{
+		Code:     "custom",
+		Title:    "First line\nSecond line",
+		Severity: Info,
+		URL:      "https://example.com/",
+	}
+	multiline.CreatedAt = createdAt
+
+	var buf bytes.Buffer
+	writer, err := NewIssueCSVWriter(&buf)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Write(issue))
+	assert.Nil(t, writer.Write(multiline))
+	assert.Nil(t, writer.Close())
+
+	output := buf.String()
+	assert.Contains(t, output, "code,title,severity,url,confidence,cwe,created_at\n")
+	assert.Contains(t, output, `"SQL Injection, ""union"" based"`)
+	assert.Contains(t, output, `"https://example.com/search?q=a,b&name=""john"""`)
+	assert.Contains(t, output, "\"First line\nSecond line\"")
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{"sql_injection", `SQL Injection, "union" based`, "High", issue.URL, "90", "89", "2024-05-01T10:30:00Z"}, records[1])
+	assert.Equal(t, "First line\nSecond line", records[2][1])
+}
+
+func TestIssueJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewIssueJSONWriter(&buf)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	var empty []Issue
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &empty))
+	assert.Len(t, empty, 0)
+
+	buf.Reset()
+	writer, err = NewIssueJSONWriter(&buf)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Write(&Issue{Code: "first", URL: "https://example.com/?a=<b>"}))
+	assert.Nil(t, writer.Write(&Issue{Code: "second"}))
+	assert.Nil(t, writer.Close())
+	var issues []Issue
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &issues))
+	assert.Len(t, issues, 2)
+	assert.Equal(t, "https://example.com/?a=<b>", issues[0].URL)
+	assert.Equal(t, "second", issues[1].Code)
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IssueStatus is the triage status of an issue
+type IssueStatus string
+
+const (
+	IssueStatusOpen          IssueStatus = "open"
+	IssueStatusConfirmed     IssueStatus = "confirmed"
+	IssueStatusResolved      IssueStatus = "resolved"
+	IssueStatusIgnored       IssueStatus = "ignored"
+	IssueStatusFalsePositive IssueStatus = "false_positive"
+)
+
+// issueStatusTransitions are the statuses each status can be changed to. Closed issues have to be reopened before
+// being confirmed or closed with a different status.
+var issueStatusTransitions = map[IssueStatus][]IssueStatus{
+	IssueStatusOpen:          {IssueStatusConfirmed, IssueStatusResolved, IssueStatusIgnored, IssueStatusFalsePositive},
+	IssueStatusConfirmed:     {IssueStatusOpen, IssueStatusResolved, IssueStatusIgnored, IssueStatusFalsePositive},
+	IssueStatusResolved:      {IssueStatusOpen},
+	IssueStatusIgnored:       {IssueStatusOpen},
+	IssueStatusFalsePositive: {IssueStatusOpen},
+}
+
+// ErrInvalidIssueStatusTransition is returned when the issue status cannot be changed to the requested one
+var ErrInvalidIssueStatusTransition = errors.New("invalid issue status transition")
+
+// IsValid checks if the status is one of the supported ones
+func (s IssueStatus) IsValid() bool {
+	_, ok := issueStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo checks if the status can be changed to the provided one
+func (s IssueStatus) CanTransitionTo(status IssueStatus) bool {
+	for _, allowed := range issueStatusTransitions[s] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IssueStatusChange records who changed the status of an issue and when, building the triage audit trail
+type IssueStatusChange struct {
+	BaseModel
+	IssueID    uint        `json:"issue_id" gorm:"index"`
+	Issue      Issue       `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FromStatus IssueStatus `json:"from_status"`
+	ToStatus   IssueStatus `json:"to_status"`
+	// UserID is the user who changed the status, empty when it has been changed without authentication
+	UserID *uuid.UUID `json:"user_id" gorm:"type:uuid;index"`
+	User   *User      `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Note   string     `json:"note"`
+}
+
+// UpdateIssueStatus changes the status of the issue if the transition is allowed, recording the change. The false
+// positive flag is kept in sync with the status.
+func (d *DatabaseConnection) UpdateIssueStatus(issue *Issue, status IssueStatus, userID *uuid.UUID, note string) (*IssueStatusChange, error) {
+	var change *IssueStatusChange
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		change, err = updateIssueStatus(tx, issue, status, userID, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	issue.Status = status
+	issue.FalsePositive = status == IssueStatusFalsePositive
+	return change, nil
+}
+
+// updateIssueStatus changes the status of the issue and records the change within the provided transaction, leaving
+// the issue struct untouched so callers can update it once committed
+func updateIssueStatus(tx *gorm.DB, issue *Issue, status IssueStatus, userID *uuid.UUID, note string) (*IssueStatusChange, error) {
+	if !status.IsValid() {
+		return nil, fmt.Errorf("invalid issue status %s", status)
+	}
+	current := issue.Status
+	if current == "" {
+		current = IssueStatusOpen
+	}
+	if !current.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: the issue status cannot be changed from %s to %s", ErrInvalidIssueStatusTransition, current, status)
+	}
+
+	change := &IssueStatusChange{
+		IssueID:    issue.ID,
+		FromStatus: current,
+		ToStatus:   status,
+		UserID:     userID,
+		Note:       note,
+	}
+	updates := map[string]interface{}{
+		"status":         status,
+		"false_positive": status == IssueStatusFalsePositive,
+		"updated_at":     time.Now(),
+	}
+	if err := tx.Model(issue).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(change).Error; err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// SetIssueFalsePositive changes the status of the issue to false positive, or reopens it when it was one and the value
+// is false. Nothing is changed when the issue already has the requested value.
+func (d *DatabaseConnection) SetIssueFalsePositive(issue *Issue, value bool, userID *uuid.UUID) (*IssueStatusChange, error) {
+	if value == (issue.Status == IssueStatusFalsePositive) {
+		return nil, nil
+	}
+	status := IssueStatusOpen
+	if value {
+		status = IssueStatusFalsePositive
+	}
+	return d.UpdateIssueStatus(issue, status, userID, "")
+}
+
+// ListIssueStatusChanges returns the status changes of the issue, the most recent first
+func (d *DatabaseConnection) ListIssueStatusChanges(issueID uint) ([]*IssueStatusChange, error) {
+	var changes []*IssueStatusChange
+	err := d.db.Where("issue_id = ?", issueID).Order("created_at DESC").Order("id DESC").Find(&changes).Error
+	return changes, err
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from     IssueStatus
+		to       IssueStatus
+		expected bool
+	}{
+		{IssueStatusOpen, IssueStatusConfirmed, true},
+		{IssueStatusOpen, IssueStatusResolved, true},
+		{IssueStatusOpen, IssueStatusIgnored, true},
+		{IssueStatusOpen, IssueStatusFalsePositive, true},
+		{IssueStatusOpen, IssueStatusOpen, false},
+		{IssueStatusConfirmed, IssueStatusResolved, true},
+		{IssueStatusConfirmed, IssueStatusOpen, true},
+		{IssueStatusResolved, IssueStatusOpen, true},
+		{IssueStatusResolved, IssueStatusConfirmed, false},
+		{IssueStatusIgnored, IssueStatusFalsePositive, false},
+		{IssueStatusFalsePositive, IssueStatusOpen, true},
+		{IssueStatusFalsePositive, IssueStatusResolved, false},
+		{IssueStatusOpen, IssueStatus("closed"), false},
+		{IssueStatus("closed"), IssueStatusOpen, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+	assert.True(t, IssueStatusIgnored.IsValid())
+	assert.False(t, IssueStatus("").IsValid())
+}
+
+func TestUpdateIssueStatus(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:        "TestUpdateIssueStatus",
+		Title:       "TestUpdateIssueStatus",
+		Description: "TestUpdateIssueStatus",
+	})
+	assert.Nil(t, err)
+
+	template := GetIssueTemplateByCode(SqlInjectionCode)
+	template.URL = "https://example.com/triage?id=1"
+	template.WorkspaceID = &workspace.ID
+	issue, err := Connection.CreateIssue(*template)
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusOpen, issue.Status)
+
+	change, err := Connection.UpdateIssueStatus(&issue, IssueStatusConfirmed, nil, "Reproduced manually")
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusOpen, change.FromStatus)
+	assert.Equal(t, IssueStatusConfirmed, change.ToStatus)
+	assert.Equal(t, IssueStatusConfirmed, issue.Status)
+
+	_, err = Connection.UpdateIssueStatus(&issue, IssueStatusFalsePositive, nil, "")
+	assert.Nil(t, err)
+	assert.True(t, issue.FalsePositive)
+
+	_, err = Connection.UpdateIssueStatus(&issue, IssueStatusResolved, nil, "")
+	assert.True(t, errors.Is(err, ErrInvalidIssueStatusTransition))
+
+	stored, err := Connection.GetIssue(int(issue.ID), false)
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusFalsePositive, stored.Status)
+	assert.True(t, stored.FalsePositive)
+
+	_, err = Connection.UpdateIssueStatus(&issue, IssueStatusOpen, nil, "Reopened")
+	assert.Nil(t, err)
+	assert.False(t, issue.FalsePositive)
+
+	changes, err := Connection.ListIssueStatusChanges(issue.ID)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, IssueStatusOpen, changes[0].ToStatus)
+	assert.Equal(t, "Reopened", changes[0].Note)
+	assert.Equal(t, IssueStatusConfirmed, changes[2].ToStatus)
+
+	filtered, _, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Statuses: []IssueStatus{IssueStatusOpen}})
+	assert.Nil(t, err)
+	found := false
+	for _, item := range filtered {
+		assert.Equal(t, IssueStatusOpen, item.Status)
+		found = found || item.ID == issue.ID
+	}
+	assert.True(t, found)
+}
+
+func TestIssueStatusChangedByRevalidationAndFalsePositive(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:        "TestIssueStatusRevalidation",
+		Title:       "TestIssueStatusRevalidation",
+		Description: "TestIssueStatusRevalidation",
+	})
+	assert.Nil(t, err)
+
+	template := GetIssueTemplateByCode(SqlInjectionCode)
+	template.URL = "https://example.com/revalidate?id=1"
+	template.WorkspaceID = &workspace.ID
+	issue, err := Connection.CreateIssue(*template)
+	assert.Nil(t, err)
+
+	// Reproduced open issues keep their status
+	change, err := Connection.UpdateIssueRevalidation(&issue, 90, true)
+	assert.Nil(t, err)
+	assert.Nil(t, change)
+	assert.Equal(t, IssueStatusOpen, issue.Status)
+
+	change, err = Connection.UpdateIssueRevalidation(&issue, 90, false)
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusResolved, change.ToStatus)
+	stored, err := Connection.GetIssue(int(issue.ID), false)
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusResolved, stored.Status)
+	assert.NotNil(t, stored.RevalidatedAt)
+
+	change, err = Connection.UpdateIssueRevalidation(&issue, 80, true)
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusOpen, change.ToStatus)
+
+	change, err = Connection.SetIssueFalsePositive(&issue, true, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusFalsePositive, change.ToStatus)
+	assert.True(t, issue.FalsePositive)
+
+	// Issues closed as false positives are not resolved by revalidations
+	change, err = Connection.UpdateIssueRevalidation(&issue, 80, false)
+	assert.Nil(t, err)
+	assert.Nil(t, change)
+	assert.Equal(t, IssueStatusFalsePositive, issue.Status)
+
+	change, err = Connection.SetIssueFalsePositive(&issue, false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, IssueStatusOpen, change.ToStatus)
+
+	changes, err := Connection.ListIssueStatusChanges(issue.ID)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 4)
+}
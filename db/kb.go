@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pyneda/sukyan/lib"
 	"github.com/rs/zerolog/log"
 )
 
@@ -63,9 +64,40 @@ func FillIssueFromHistoryAndTemplate(history *History, code IssueCode, details s
 	return issue
 }
 
+// IssueCreationOptions customizes how issues are created from history items
+type IssueCreationOptions struct {
+	// InsertionPoint is where the issue has been found, used to tell repeated issues apart
+	InsertionPoint string
+	// AllowDuplicates skips the lookup of the same issue found in previous scans, so a new one is created instead of
+	// incrementing its occurrences
+	AllowDuplicates bool
+}
+
 func CreateIssueFromHistoryAndTemplate(history *History, code IssueCode, details string, confidence int, severity string, workspaceID, taskID, taskJobID *uint) (Issue, error) {
+	return CreateIssueFromHistoryAndTemplateWithOptions(history, code, details, confidence, severity, workspaceID, taskID, taskJobID, IssueCreationOptions{})
+}
+
+// CreateIssueFromHistoryAndTemplateWithOptions creates an issue from the history item and the issue template. Issues
+// already found in the workspace for the same normalized URL and insertion point get their occurrences incremented
+// instead of being duplicated, unless duplicates are allowed.
+func CreateIssueFromHistoryAndTemplateWithOptions(history *History, code IssueCode, details string, confidence int, severity string, workspaceID, taskID, taskJobID *uint, options IssueCreationOptions) (Issue, error) {
 	issue := FillIssueFromHistoryAndTemplate(history, code, details, confidence, severity, workspaceID, taskID, taskJobID)
-	createdIssue, err := Connection.CreateIssue(*issue)
+	issue.InsertionPoint = options.InsertionPoint
+	if normalizedURL, err := lib.NormalizeURL(history.URL); err == nil {
+		issue.NormalizedURL = normalizedURL
+	} else {
+		issue.NormalizedURL = history.URL
+	}
+	Connection.ApplySuppressionRules(issue)
+
+	var createdIssue Issue
+	var repeated bool
+	var err error
+	if options.AllowDuplicates {
+		createdIssue, err = Connection.CreateIssue(*issue)
+	} else {
+		createdIssue, repeated, err = Connection.CreateOrIncrementIssue(*issue, history)
+	}
 	if err != nil {
 		log.Error().Err(err).Str("issue", issue.Title).Str("url", history.URL).Msg("Failed to create issue")
 		return createdIssue, err
@@ -81,6 +113,10 @@ func CreateIssueFromHistoryAndTemplate(history *History, code IssueCode, details
 		taskIDValue = *taskID
 	}
 
+	if repeated {
+		log.Info().Uint("id", createdIssue.ID).Str("issue", issue.Title).Str("url", history.URL).Uint("workspace", workspaceIDValue).Uint("task", taskIDValue).Int("occurrences", createdIssue.Occurrences).Msg("Repeated issue found")
+		return createdIssue, nil
+	}
 	log.Warn().Uint("id", createdIssue.ID).Str("issue", issue.Title).Str("url", history.URL).Uint("workspace", workspaceIDValue).Uint("task", taskIDValue).Msg("New issue found")
 	return createdIssue, nil
 }
@@ -120,6 +156,7 @@ func CreateIssueFromWebSocketConnectionAndTemplate(connection *WebSocketConnecti
 		return Issue{}, err
 	}
 
+	Connection.ApplySuppressionRules(issue)
 	createdIssue, err := Connection.CreateIssue(*issue)
 	if err != nil {
 		log.Error().Err(err).Str("issue", issue.Title).Str("url", connection.URL).Msg("Failed to create issue")
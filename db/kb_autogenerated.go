@@ -14,6 +14,7 @@ var (
 	Base64EncodedDataInParameterCode     IssueCode = "base64_encoded_data_in_parameter"
 	BlindSqlInjectionCode                IssueCode = "blind_sql_injection"
 	CacheControlHeaderCode               IssueCode = "cache_control_header"
+	CacheDeceptionRiskCode               IssueCode = "cache_deception_risk"
 	CacheStorageUsageDetectedCode        IssueCode = "cache_storage_usage_detected"
 	CdnDetectedCode                      IssueCode = "cdn_detected"
 	CertificateErrorsCode                IssueCode = "certificate_errors"
@@ -23,6 +24,7 @@ var (
 	ConsoleUsageDetectedCode             IssueCode = "console_usage_detected"
 	CorsCode                             IssueCode = "cors"
 	CrlfInjectionCode                    IssueCode = "crlf_injection"
+	CspWeaknessCode                      IssueCode = "csp_weakness"
 	CsrfCode                             IssueCode = "csrf"
 	CstiCode                             IssueCode = "csti"
 	DatabaseErrorsCode                   IssueCode = "database_errors"
@@ -31,6 +33,7 @@ var (
 	DirectoryListingCode                 IssueCode = "directory_listing"
 	DjangoDebugExceptionCode             IssueCode = "django_debug_exception"
 	DockerApiDetectedCode                IssueCode = "docker_api_detected"
+	DomClobberingCode                    IssueCode = "dom_clobbering"
 	DomStorageEventsDetectedCode         IssueCode = "dom_storage_events_detected"
 	ElmahExposedCode                     IssueCode = "elmah_exposed"
 	EmailAddressesCode                   IssueCode = "email_addresses"
@@ -49,7 +52,10 @@ var (
 	FlashUsageDetectedCode               IssueCode = "flash_usage_detected"
 	ForbiddenBypassCode                  IssueCode = "forbidden_bypass"
 	GrailsExceptionCode                  IssueCode = "grails_exception"
+	GraphqlBatchingEnabledCode           IssueCode = "graphql_batching_enabled"
+	GraphqlFieldSuggestionsCode          IssueCode = "graphql_field_suggestions"
 	GraphqlIntrospectionEnabledCode      IssueCode = "graphql_introspection_enabled"
+	GraphqlSensitiveFieldsExposedCode    IssueCode = "graphql_sensitive_fields_exposed"
 	GraphqlEndpointDetectedCode          IssueCode = "graphql_endpoint_detected"
 	GrpcEndpointDetectedCode             IssueCode = "grpc_endpoint_detected"
 	HeaderInsightsReportCode             IssueCode = "header_insights_report"
@@ -68,13 +74,17 @@ var (
 	JbossInvokerDetectedCode             IssueCode = "jboss_invoker_detected"
 	JbossStatusDetectedCode              IssueCode = "jboss_status_detected"
 	JettyServerHeaderCode                IssueCode = "jetty_server_header"
+	JsEndpointsDiscoveredCode            IssueCode = "js_endpoints_discovered"
 	JsonpEndpointDetectedCode            IssueCode = "jsonp_endpoint_detected"
 	JwtDetectedCode                      IssueCode = "jwt_detected"
+	JwtKidInjectionCode                  IssueCode = "jwt_kid_injection"
 	JwtWeakSigningSecretCode             IssueCode = "jwt_weak_signing_secret"
+	JwtWeaknessCode                      IssueCode = "jwt_weakness"
 	KubernetesApiDetectedCode            IssueCode = "kubernetes_api_detected"
 	LdapInjectionCode                    IssueCode = "ldap_injection"
 	Log4shellCode                        IssueCode = "log4shell"
 	MissingContentTypeHeaderCode         IssueCode = "missing_content_type_header"
+	MissingSriCode                       IssueCode = "missing_sri"
 	MixedContentCode                     IssueCode = "mixed_content"
 	NetworkAuthChallengeDetectedCode     IssueCode = "network_auth_challenge_detected"
 	NosqlInjectionCode                   IssueCode = "nosql_injection"
@@ -90,11 +100,14 @@ var (
 	PhpInfoDetectedCode                  IssueCode = "php_info_detected"
 	PrivateIpsCode                       IssueCode = "private_ips"
 	PrivateKeysCode                      IssueCode = "private_keys"
+	RateLimitQuotaDisclosureCode         IssueCode = "rate_limit_quota_disclosure"
 	ReactDevelopmentModeCode             IssueCode = "react_development_mode"
 	ReflectedInputCode                   IssueCode = "reflected_input"
 	RemoteFileInclusionCode              IssueCode = "remote_file_inclusion"
+	RequestSmugglingCode                 IssueCode = "request_smuggling"
 	SecretsInJsCode                      IssueCode = "secrets_in_js"
 	SensitiveConfigDetectedCode          IssueCode = "sensitive_config_detected"
+	SensitiveDataInUrlCode               IssueCode = "sensitive_data_in_url"
 	ServerHeaderCode                     IssueCode = "server_header"
 	ServerInfoDetectedCode               IssueCode = "server_info_detected"
 	ServerSidePrototypePollutionCode     IssueCode = "server_side_prototype_pollution"
@@ -119,6 +132,7 @@ var (
 	VersionControlFileDetectedCode       IssueCode = "version_control_file_detected"
 	VulnerableJavascriptDependencyCode   IssueCode = "vulnerable_javascript_dependency"
 	WafDetectedCode                      IssueCode = "waf_detected"
+	WebCachePoisoningCode                IssueCode = "web_cache_poisoning"
 	WebassemblyDetectedCode              IssueCode = "webassembly_detected"
 	WebserverControlFileExposedCode      IssueCode = "webserver_control_file_exposed"
 	WebsocketDetectedCode                IssueCode = "websocket_detected"
@@ -131,6 +145,7 @@ var (
 	XpathInjectionCode                   IssueCode = "xpath_injection"
 	XsltInjectionCode                    IssueCode = "xslt_injection"
 	XssReflectedCode                     IssueCode = "xss_reflected"
+	XssReflectedJsonResponseCode         IssueCode = "xss_reflected_json_response"
 	XxeCode                              IssueCode = "xxe"
 )
 
@@ -260,6 +275,19 @@ var issueTemplates = []IssueTemplate{
 			"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Cache-Control",
 		},
 	},
+	{
+		Code:        CacheDeceptionRiskCode,
+		Title:       "Web Cache Poisoning Risk",
+		Description: "The response can be stored by a shared cache such as a CDN or reverse proxy and reflects the value of request headers which are not part of the cache key. An attacker could send a request with a malicious value in one of these headers and get the resulting response cached and served to other users requesting the same URL, leading to cross-site scripting, open redirects or denial of service. Cache status headers disclosing the cache key also help attackers to find the inputs which are not keyed.",
+		Remediation: "Avoid reflecting request headers which are not part of the cache key in cacheable responses, or include them in the cache key using the Vary header. Strip unexpected headers such as X-Forwarded-Host at the cache layer, disable caching for dynamic content and do not expose the cache key in response headers.",
+		Cwe:         524,
+		Severity:    "Medium",
+		References: []string{
+			"https://portswigger.net/web-security/web-cache-poisoning",
+			"https://portswigger.net/web-security/web-cache-deception",
+			"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Vary",
+		},
+	},
 	{
 		Code:        CacheStorageUsageDetectedCode,
 		Title:       "Cache Storage Usage Detection Report",
@@ -365,6 +393,19 @@ var issueTemplates = []IssueTemplate{
 			"https://owasp.org/www-community/vulnerabilities/CRLF_Injection",
 		},
 	},
+	{
+		Code:        CspWeaknessCode,
+		Title:       "Content Security Policy Weaknesses",
+		Description: "The Content-Security-Policy enforced by the application contains directives that weaken its protection. Sources such as 'unsafe-inline', 'unsafe-eval' or wildcards, missing fallback directives or predictable nonces can allow attackers to execute injected scripts or load content from hosts they control, reducing the effectiveness of the policy as a defense in depth mechanism against cross-site scripting and other content injection attacks.",
+		Remediation: "Define a strict policy with default-src set to a restrictive value and object-src set to 'none'. Avoid 'unsafe-inline' and 'unsafe-eval' in script-src, using nonces or hashes instead, and generate a new unpredictable nonce for every response. Do not allow scripts from wildcards or from whole schemes such as https or data.",
+		Cwe:         693,
+		Severity:    "Low",
+		References: []string{
+			"https://developer.mozilla.org/en-US/docs/Web/HTTP/CSP",
+			"https://cheatsheetseries.owasp.org/cheatsheets/Content_Security_Policy_Cheat_Sheet.html",
+			"https://csp-evaluator.withgoogle.com/",
+		},
+	},
 	{
 		Code:        CsrfCode,
 		Title:       "Cross-Site Request Forgery Detected",
@@ -458,6 +499,19 @@ var issueTemplates = []IssueTemplate{
 			"https://cheatsheetseries.owasp.org/cheatsheets/Docker_Security_Cheat_Sheet.html",
 		},
 	},
+	{
+		Code:        DomClobberingCode,
+		Title:       "DOM Clobbering",
+		Description: "The page reads global variables through 'window' or 'document' that can be overwritten (clobbered) by HTML elements\nwith matching 'id' or 'name' attributes, and passes their value to a dangerous sink. If an attacker is able to inject\nmarkup without scripts into the page (e.g. through an HTML sanitizer which allows 'id' and 'name' attributes), the\ninjected elements would take precedence over the expected globals, which could lead to Cross-Site Scripting or to\nredirecting resources to attacker controlled locations.\n",
+		Remediation: "Avoid relying on implicit global variables which may not be defined. Explicitly declare the globals that the\napplication uses, validate that they have the expected type before using them (e.g. checking they are not an\ninstance of Element or HTMLCollection), and avoid passing their values to dangerous sinks. Additionally, configure\nHTML sanitizers to strip 'id' and 'name' attributes or to namespace them.\n",
+		Cwe:         79,
+		Severity:    "Medium",
+		References: []string{
+			"https://portswigger.net/web-security/dom-based/dom-clobbering",
+			"https://cheatsheetseries.owasp.org/cheatsheets/DOM_Clobbering_Prevention_Cheat_Sheet.html",
+			"https://domclob.xyz/",
+		},
+	},
 	{
 		Code:        DomStorageEventsDetectedCode,
 		Title:       "DOM Storage Events Detection Report",
@@ -683,6 +737,32 @@ var issueTemplates = []IssueTemplate{
 		Severity:    "Medium",
 		References:  []string{},
 	},
+	{
+		Code:        GraphqlBatchingEnabledCode,
+		Title:       "GraphQL Query Batching Enabled",
+		Description: "The GraphQL endpoint processes multiple operations sent in a single HTTP request, either as an array of queries or as a single query containing multiple aliases of the same field.\n\nBatching allows attackers to bypass rate limiting and brute force protections that count HTTP requests instead of operations, for example to guess passwords, one-time codes or coupons with thousands of attempts in a single request. It can also be abused to cause denial of service by making the server resolve a large number of expensive operations at once.",
+		Remediation: "Disable array based batching if it is not required, or limit the number of operations accepted per request. Limit the number of aliases and the overall query cost or complexity accepted by the server, and apply rate limiting and brute force protections per operation instead of per HTTP request.",
+		Cwe:         770,
+		Severity:    "Low",
+		References: []string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/12-API_Testing/01-Testing_GraphQL",
+			"https://cheatsheetseries.owasp.org/cheatsheets/GraphQL_Cheat_Sheet.html#batching-attacks",
+			"https://portswigger.net/web-security/graphql#bypassing-rate-limiting-using-aliases",
+		},
+	},
+	{
+		Code:        GraphqlFieldSuggestionsCode,
+		Title:       "GraphQL Field Suggestions Enabled",
+		Description: "The GraphQL endpoint returns field suggestions (\"Did you mean ...?\") in the error messages of queries referencing unknown fields.\n\nEven when introspection is disabled, field suggestions can be abused to progressively reconstruct the API schema by sending queries with guessed or misspelled field names and collecting the suggested names, exposing queries, mutations, types and fields that were meant to remain undisclosed.",
+		Remediation: "Disable field suggestions in production environments, especially when introspection has been disabled. Most GraphQL server implementations allow to disable or mask suggestions in validation error messages (e.g. through a validation rule or error formatting hook).",
+		Cwe:         200,
+		Severity:    "Low",
+		References: []string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/12-API_Testing/01-Testing_GraphQL",
+			"https://github.com/nikitastupin/clairvoyance",
+			"https://www.apollographql.com/docs/apollo-server/security/hiding-field-suggestions",
+		},
+	},
 	{
 		Code:        GraphqlIntrospectionEnabledCode,
 		Title:       "GraphQL Introspection Enabled",
@@ -695,6 +775,18 @@ var issueTemplates = []IssueTemplate{
 			"https://graphql.org/learn/introspection/",
 		},
 	},
+	{
+		Code:        GraphqlSensitiveFieldsExposedCode,
+		Title:       "GraphQL Schema Exposes Sensitive Fields",
+		Description: "The GraphQL schema obtained through introspection contains types or fields whose names suggest they hold sensitive data, such as passwords, secrets, tokens, private keys, social security or credit card numbers.\n\nThis is not a vulnerability by itself, but these fields are good candidates to verify that proper authorization is enforced, as any client able to query them could retrieve the sensitive data they hold.\n",
+		Remediation: "This is an informational issue. Review the reported fields and ensure that they can only be queried by authorized clients, applying field level authorization where required. Avoid exposing fields such as password hashes or secrets through the GraphQL API and consider disabling introspection in production environments.\n",
+		Cwe:         213,
+		Severity:    "Info",
+		References: []string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/12-API_Testing/01-Testing_GraphQL",
+			"https://cheatsheetseries.owasp.org/cheatsheets/GraphQL_Cheat_Sheet.html",
+		},
+	},
 	{
 		Code:        GraphqlEndpointDetectedCode,
 		Title:       "GraphQL Endpoint Detected",
@@ -739,7 +831,7 @@ var issueTemplates = []IssueTemplate{
 		Title:       "Host Header Injection",
 		Description: "The application appears to be vulnerable to Host Header Injection. This vulnerability occurs when a user is able to manipulate the Host header and the application trusts the header without proper validation. This can lead to vulnerabilities such as web cache poisoning, password reset poisoning, and malicious redirections.",
 		Remediation: "To mitigate this vulnerability, validate and sanitize incoming Host headers. Use a whitelist of allowed domains and hostnames. Ensure that the application generates absolute URLs using a known good base URL, rather than relying on the incoming Host header. Additionally, implement proper logging of incorrect Host header attempts and regularly review for suspicious activities.",
-		Cwe:         601,
+		Cwe:         20,
 		Severity:    "Medium",
 		References: []string{
 			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/07-Input_Validation_Testing/17-Testing_for_Host_Header_Injection",
@@ -914,6 +1006,17 @@ var issueTemplates = []IssueTemplate{
 		Severity:    "Low",
 		References:  []string{},
 	},
+	{
+		Code:        JsEndpointsDiscoveredCode,
+		Title:       "API Endpoints Discovered in Javascript",
+		Description: "API endpoints have been found referenced in the JavaScript code of the application, such as the\nURLs passed to fetch, XMLHttpRequest or axios calls. Single page applications usually call endpoints\nwhich are not linked from any page, so these have been added as targets for crawling and scanning.\nSome of them might expose functionality not intended to be reachable by every user.\n",
+		Remediation: "Review the discovered endpoints to ensure that all of them are intended to be publicly reachable,\nand that they enforce proper authentication and authorization controls. Remove references to\ninternal, debug or deprecated endpoints from the code shipped to clients.\n",
+		Cwe:         200,
+		Severity:    "Info",
+		References: []string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/01-Information_Gathering/05-Review_Web_Page_Content_for_Information_Leakage",
+		},
+	},
 	{
 		Code:        JsonpEndpointDetectedCode,
 		Title:       "JSONP Endpoint Detected",
@@ -939,6 +1042,19 @@ var issueTemplates = []IssueTemplate{
 			"https://github.com/ticarpi/jwt_tool",
 		},
 	},
+	{
+		Code:        JwtKidInjectionCode,
+		Title:       "JWT Key ID (kid) Header Injection",
+		Description: "The application uses the `kid` (key id) header of JSON Web Tokens to look up the key used to verify their signature, without properly validating its value. By manipulating the `kid` header, for example with path traversal sequences pointing to a file with known content or with SQL injection payloads returning an attacker controlled value, it has been possible to force the server into using a predictable key and have a forged token accepted. This allows attackers to create arbitrary tokens, which can lead to authentication bypass and privilege escalation.",
+		Remediation: "Treat the `kid` header as untrusted input. Only accept key identifiers from a strict allowlist of known keys, never use its value to build file paths or database queries, and use parameterized queries when a key store lookup is required. Reject tokens whose key id cannot be resolved to a known key.",
+		Cwe:         347,
+		Severity:    "Critical",
+		References: []string{
+			"https://portswigger.net/web-security/jwt#injecting-self-signed-jwts-via-the-kid-parameter",
+			"https://book.hacktricks.xyz/pentesting-web/hacking-jwt-json-web-tokens#kid-issues-key-id",
+			"https://github.com/ticarpi/jwt_tool/wiki/Known-Exploits-and-Attacks",
+		},
+	},
 	{
 		Code:        JwtWeakSigningSecretCode,
 		Title:       "Weak Signing Secret in JWT",
@@ -952,6 +1068,20 @@ var issueTemplates = []IssueTemplate{
 			"https://github.com/ticarpi/jwt_tool/wiki",
 		},
 	},
+	{
+		Code:        JwtWeaknessCode,
+		Title:       "JWT Signature Verification Bypass",
+		Description: "The application accepts JSON Web Tokens which have not been signed with its own key. It has been possible to replace a token with a forged one, either without signature using the `none` algorithm, signed with HS256 using the server RSA public key as the HMAC secret (algorithm confusion), signed with a weak secret recovered through brute force, or signed with an arbitrary key when the signature is not verified at all. As the claims of the token can be modified at will, this allows attackers to impersonate other users and escalate privileges.",
+		Remediation: "Always verify the signature of the tokens, and configure the JWT library with the algorithm expected for each key instead of trusting the `alg` header. Reject tokens using the `none` algorithm, never use the same key material for asymmetric and symmetric algorithms, and sign HMAC tokens with long randomly generated secrets which are rotated if they could have been exposed.",
+		Cwe:         347,
+		Severity:    "High",
+		References: []string{
+			"https://portswigger.net/web-security/jwt",
+			"https://portswigger.net/web-security/jwt/algorithm-confusion",
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/06-Session_Management_Testing/10-Testing_JSON_Web_Tokens",
+			"https://github.com/ticarpi/jwt_tool/wiki/Known-Exploits-and-Attacks",
+		},
+	},
 	{
 		Code:        KubernetesApiDetectedCode,
 		Title:       "Kubernetes API Detected",
@@ -1000,6 +1130,18 @@ var issueTemplates = []IssueTemplate{
 			"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-Content-Type-Options",
 		},
 	},
+	{
+		Code:        MissingSriCode,
+		Title:       "Missing Subresource Integrity",
+		Description: "The page loads scripts or stylesheets from third-party origins without an integrity attribute. If the host serving these resources is compromised, or the content is modified in transit, the browser will execute the altered code in the context of the application, allowing attackers to steal data or perform actions on behalf of its users.",
+		Remediation: "Add an integrity attribute containing a cryptographic hash of the expected content, along with the crossorigin attribute, to every script and stylesheet loaded from a different origin. Pin the resources to specific versions so their content does not change, or host them on the same origin as the application.",
+		Cwe:         353,
+		Severity:    "Low",
+		References: []string{
+			"https://developer.mozilla.org/en-US/docs/Web/Security/Subresource_Integrity",
+			"https://www.w3.org/TR/SRI/",
+		},
+	},
 	{
 		Code:        MixedContentCode,
 		Title:       "Mixed Content",
@@ -1181,6 +1323,18 @@ var issueTemplates = []IssueTemplate{
 			"https://cheatsheetseries.owasp.org/cheatsheets/Key_Management_Cheat_Sheet.html",
 		},
 	},
+	{
+		Code:        RateLimitQuotaDisclosureCode,
+		Title:       "Rate Limit Internal Quota Details Disclosure",
+		Description: "The response includes rate limiting headers that disclose internal quota details, such as the name of the bucket, scope, key or plan used to track the client requests.\n\nWhile rate limit headers are commonly used to inform clients about their usage, internal quota identifiers can help attackers understand how limits are enforced, identify other quotas to target and time abusive requests to avoid being throttled.\n",
+		Remediation: "This is an informational issue. Limit the rate limiting headers to the standard ones needed by legitimate clients (limit, remaining and reset) and avoid exposing internal identifiers of the quotas enforced by the application or API gateway.\n",
+		Cwe:         200,
+		Severity:    "Info",
+		References: []string{
+			"https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/",
+			"https://owasp.org/API-Security/editions/2023/en/0xa4-unrestricted-resource-consumption/",
+		},
+	},
 	{
 		Code:        ReactDevelopmentModeCode,
 		Title:       "React Development Mode Detected",
@@ -1215,6 +1369,19 @@ var issueTemplates = []IssueTemplate{
 			"https://owasp.org/www-project-web-security-testing-guide/v42/4-Web_Application_Security_Testing/07-Input_Validation_Testing/11.2-Testing_for_Remote_File_Inclusion",
 		},
 	},
+	{
+		Code:        RequestSmugglingCode,
+		Title:       "HTTP Request Smuggling",
+		Description: "The front-end and back-end servers handling the application disagree on where a request ends when it contains both the Content-Length and Transfer-Encoding headers. An attacker can send an ambiguous request whose remaining part is prepended by the back-end to the next request received on the same connection, which can be used to bypass front-end security controls, capture the requests of other users including their cookies, poison web caches or deliver reflected cross-site scripting to other users without any interaction.",
+		Remediation: "Use HTTP/2 end to end between the front-end and back-end servers, or ensure that both servers process ambiguous requests in the same way. Configure the front-end server to normalize ambiguous requests and reject the ones containing both the Content-Length and Transfer-Encoding headers or malformed Transfer-Encoding headers, and disable the reuse of back-end connections if the servers can't be aligned.",
+		Cwe:         444,
+		Severity:    "High",
+		References: []string{
+			"https://portswigger.net/web-security/request-smuggling",
+			"https://portswigger.net/web-security/request-smuggling/finding",
+			"https://cwe.mitre.org/data/definitions/444.html",
+		},
+	},
 	{
 		Code:        SecretsInJsCode,
 		Title:       "Exposed Secrets in Javascript",
@@ -1237,6 +1404,18 @@ var issueTemplates = []IssueTemplate{
 			"https://cheatsheetseries.owasp.org/cheatsheets/Transport_Layer_Protection_Cheat_Sheet.html",
 		},
 	},
+	{
+		Code:        SensitiveDataInUrlCode,
+		Title:       "Sensitive Data In URL",
+		Description: "The application includes sensitive values such as email addresses, payment card numbers or API keys in the path or query string of URLs. URLs are stored in browser history, proxy and server logs and analytics tools, and can be sent to third parties through the Referer header, exposing this data to anyone with access to them.",
+		Remediation: "Do not include sensitive data in URLs. Send it in the body of POST requests or in request headers instead, and refer to resources using opaque identifiers rather than personal data. Revoke any credentials which have been exposed in URLs.",
+		Cwe:         598,
+		Severity:    "Low",
+		References: []string{
+			"https://owasp.org/www-community/vulnerabilities/Information_exposure_through_query_strings_in_url",
+			"https://cwe.mitre.org/data/definitions/598.html",
+		},
+	},
 	{
 		Code:        ServerHeaderCode,
 		Title:       "Server Header Disclosure",
@@ -1522,6 +1701,19 @@ var issueTemplates = []IssueTemplate{
 			"https://owasp.org/www-community/Web_Application_Firewall",
 		},
 	},
+	{
+		Code:        WebCachePoisoningCode,
+		Title:       "Web Cache Poisoning",
+		Description: "The application uses the value of a request header which is not part of the cache key to build its response, and the resulting response is stored by a shared cache such as a CDN or reverse proxy. An attacker can send a single request with a malicious value in this header to get a poisoned response cached and served to every user requesting the same URL, which can lead to stored cross-site scripting, redirections to attacker controlled hosts, loading of malicious resources or denial of service.",
+		Remediation: "Do not trust headers such as X-Forwarded-Host or X-Forwarded-Scheme unless they are set by a trusted proxy, and strip them at the cache layer otherwise. If the application needs to use them, include them in the cache key using the Vary header, or disable caching for the affected responses.",
+		Cwe:         349,
+		Severity:    "High",
+		References: []string{
+			"https://portswigger.net/web-security/web-cache-poisoning",
+			"https://portswigger.net/research/practical-web-cache-poisoning",
+			"https://owasp.org/www-community/attacks/Cache_Poisoning",
+		},
+	},
 	{
 		Code:        WebassemblyDetectedCode,
 		Title:       "WebAssembly (Wasm) Detection",
@@ -1661,6 +1853,19 @@ var issueTemplates = []IssueTemplate{
 			"https://portswigger.net/web-security/cross-site-scripting/reflected",
 		},
 	},
+	{
+		Code:        XssReflectedJsonResponseCode,
+		Title:       "Reflected XSS in JSON Response Rendered as HTML",
+		Description: "The application reflects user-supplied input without encoding HTML special characters into a JSON response\nthat a browser may render as HTML. The response is either served with an HTML content type, or with a missing\nor non JSON content type and without the 'X-Content-Type-Options: nosniff' header, allowing the browser to\nsniff it as HTML. An attacker can make a victim navigate directly to the endpoint with a crafted payload, which\nwould then be executed in the context of the application origin.\n",
+		Remediation: "Serve JSON responses with the 'application/json' content type and include the 'X-Content-Type-Options: nosniff'\nheader so browsers never render them as HTML. Additionally, escape HTML special characters such as '<', '>' and '&'\nwhen serializing user-controlled values into JSON (e.g. as \\u003c), and avoid rendering API response fields as\nHTML in the client side.\n",
+		Cwe:         79,
+		Severity:    "Medium",
+		References: []string{
+			"https://owasp.org/www-community/attacks/xss/",
+			"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-Content-Type-Options",
+			"https://portswigger.net/kb/issues/00200308_cross-site-scripting-reflected",
+		},
+	},
 	{
 		Code:        XxeCode,
 		Title:       "XML External Entity (XXE) Detected",
@@ -162,6 +162,20 @@ func (d *DatabaseConnection) GetInteraction(interactionID uint) (*OOBInteraction
 	return &interaction, nil
 }
 
+// OOBInteractionConfidence returns the confidence of an issue confirmed by an interaction of the given protocol. DNS-only
+// interactions are less reliable, as resolvers, proxies or security appliances can look up the domain without the
+// target ever sending a request to it.
+func OOBInteractionConfidence(protocol string) int {
+	switch strings.ToLower(protocol) {
+	case "http", "https":
+		return 90
+	case "dns":
+		return 60
+	default:
+		return 80
+	}
+}
+
 func (d *DatabaseConnection) MatchInteractionWithOOBTest(interaction OOBInteraction) (OOBTest, error) {
 	oobTest := OOBTest{}
 	fullID := strings.ToLower(interaction.FullID)
@@ -197,7 +211,7 @@ func (d *DatabaseConnection) MatchInteractionWithOOBTest(interaction OOBInteract
 			issue.HTTPMethod = history.Method
 			issue.Request = history.RawRequest
 			issue.Response = history.RawResponse
-			issue.Confidence = 80
+			issue.Confidence = OOBInteractionConfidence(interaction.Protocol)
 			issue.Details = details
 		}
 		d.CreateIssue(*issue)
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFLog is the root object of a SARIF 2.1.0 document
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name,omitempty"`
+	ShortDescription     SARIFMessage           `json:"shortDescription"`
+	FullDescription      *SARIFMessage          `json:"fullDescription,omitempty"`
+	Help                 *SARIFMessage          `json:"help,omitempty"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration SARIFRuleConfiguration `json:"defaultConfiguration"`
+	Properties           SARIFRuleProperties    `json:"properties"`
+}
+
+type SARIFRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SARIFRuleProperties struct {
+	Tags []string `json:"tags,omitempty"`
+	// SecuritySeverity is the score used by GitHub code scanning to rank security alerts
+	SecuritySeverity string `json:"security-severity,omitempty"`
+}
+
+type SARIFResult struct {
+	RuleID       string                 `json:"ruleId"`
+	RuleIndex    int                    `json:"ruleIndex"`
+	Level        string                 `json:"level"`
+	Message      SARIFMessage           `json:"message"`
+	Locations    []SARIFLocation        `json:"locations"`
+	Suppressions []SARIFSuppression     `json:"suppressions,omitempty"`
+	Properties   map[string]interface{} `json:"properties,omitempty"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFLogicalLocation struct {
+	Name               string `json:"name,omitempty"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind,omitempty"`
+}
+
+type SARIFSuppression struct {
+	Kind          string `json:"kind"`
+	Status        string `json:"status,omitempty"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// sarifLevel maps an issue severity to a SARIF result level
+func sarifLevel(s severity) string {
+	switch s {
+	case Critical, High:
+		return "error"
+	case Medium:
+		return "warning"
+	case Low, Info:
+		return "note"
+	default:
+		return "none"
+	}
+}
+
+// sarifSecuritySeverity maps an issue severity to the numeric score expected in the security-severity property
+func sarifSecuritySeverity(s severity) string {
+	switch s {
+	case Critical:
+		return "9.5"
+	case High:
+		return "8.0"
+	case Medium:
+		return "5.5"
+	case Low:
+		return "3.0"
+	default:
+		return "0.0"
+	}
+}
+
+// sarifRuleFromIssue builds the rule of an issue code, using the issue template metadata when available
+func sarifRuleFromIssue(issue *Issue) SARIFRule {
+	template := GetIssueTemplateByCode(IssueCode(issue.Code))
+	if template == nil {
+		template = issue
+	}
+	rule := SARIFRule{
+		ID:                   issue.Code,
+		Name:                 IssueCode(issue.Code).Name(),
+		ShortDescription:     SARIFMessage{Text: template.Title},
+		DefaultConfiguration: SARIFRuleConfiguration{Level: sarifLevel(template.Severity)},
+		Properties: SARIFRuleProperties{
+			Tags:             []string{"security"},
+			SecuritySeverity: sarifSecuritySeverity(template.Severity),
+		},
+	}
+	if template.Description != "" {
+		rule.FullDescription = &SARIFMessage{Text: template.Description}
+	}
+	if template.Remediation != "" {
+		rule.Help = &SARIFMessage{Text: template.Remediation}
+	}
+	if len(template.References) > 0 {
+		rule.HelpURI = template.References[0]
+	}
+	if template.Cwe != 0 {
+		rule.Properties.Tags = append(rule.Properties.Tags, fmt.Sprintf("external/cwe/cwe-%d", template.Cwe))
+	}
+	return rule
+}
+
+// sarifResultFromIssue builds the SARIF result of an issue, using its URL as the location
+func sarifResultFromIssue(issue *Issue, ruleIndex int) SARIFResult {
+	message := issue.Title
+	if issue.Details != "" {
+		message = fmt.Sprintf("%s\n\n%s", issue.Title, issue.Details)
+	}
+	location := SARIFLocation{
+		PhysicalLocation: SARIFPhysicalLocation{
+			ArtifactLocation: SARIFArtifactLocation{URI: issue.URL},
+		},
+	}
+	if u, err := url.Parse(issue.URL); err == nil {
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		location.LogicalLocations = []SARIFLogicalLocation{
+			{
+				Name:               path,
+				FullyQualifiedName: fmt.Sprintf("%s %s", issue.HTTPMethod, issue.URL),
+				Kind:               "resource",
+			},
+		}
+	}
+	result := SARIFResult{
+		RuleID:    issue.Code,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(issue.Severity),
+		Message:   SARIFMessage{Text: message},
+		Locations: []SARIFLocation{location},
+		Properties: map[string]interface{}{
+			"issue_id":    issue.ID,
+			"severity":    issue.Severity.String(),
+			"confidence":  issue.Confidence,
+			"http_method": issue.HTTPMethod,
+			"status_code": issue.StatusCode,
+		},
+	}
+	if issue.Cwe != 0 {
+		result.Properties["cwe"] = fmt.Sprintf("CWE-%d", issue.Cwe)
+	}
+	if issue.FalsePositive {
+		result.Suppressions = []SARIFSuppression{
+			{
+				Kind:          "external",
+				Status:        "accepted",
+				Justification: "Marked as false positive",
+			},
+		}
+	}
+	return result
+}
+
+// BuildSARIFLog builds a SARIF 2.1.0 log with a rule per issue code and a result per issue
+func BuildSARIFLog(issues []*Issue) SARIFLog {
+	driver := SARIFDriver{
+		Name:           "Sukyan",
+		InformationURI: "https://github.com/pyneda/sukyan",
+		Rules:          []SARIFRule{},
+	}
+	results := make([]SARIFResult, 0, len(issues))
+	ruleIndexes := make(map[string]int)
+	for _, issue := range issues {
+		ruleIndex, ok := ruleIndexes[issue.Code]
+		if !ok {
+			ruleIndex = len(driver.Rules)
+			ruleIndexes[issue.Code] = ruleIndex
+			driver.Rules = append(driver.Rules, sarifRuleFromIssue(issue))
+		}
+		results = append(results, sarifResultFromIssue(issue, ruleIndex))
+	}
+	return SARIFLog{
+		Schema:  sarifSchemaURI,
+		Version: sarifVersion,
+		Runs: []SARIFRun{
+			{
+				Tool:    SARIFTool{Driver: driver},
+				Results: results,
+			},
+		},
+	}
+}
+
+// ExportIssuesSARIF exports the issues of a workspace as a SARIF 2.1.0 document
+func (d *DatabaseConnection) ExportIssuesSARIF(workspaceID uint) ([]byte, error) {
+	issues, _, err := d.ListIssues(IssueFilter{WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(BuildSARIFLog(issues), "", "  ")
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	_ "embed"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// sarifSchema is the official OASIS SARIF 2.1.0 JSON schema
+//
+//go:embed testdata/sarif-schema-2.1.0.json
+var sarifSchema []byte
+
+// assertValidSARIF validates the document against the SARIF 2.1.0 schema and checks that the results reference the
+// rules they belong to
+func assertValidSARIF(t *testing.T, data []byte) map[string]interface{} {
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(sarifSchema), gojsonschema.NewBytesLoader(data))
+	assert.Nil(t, err)
+	if result != nil {
+		for _, validationError := range result.Errors() {
+			t.Errorf("invalid SARIF document: %s", validationError)
+		}
+	}
+
+	var document map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &document))
+	for _, r := range document["runs"].([]interface{}) {
+		run := r.(map[string]interface{})
+		rules := run["tool"].(map[string]interface{})["driver"].(map[string]interface{})["rules"].([]interface{})
+		for _, r := range run["results"].([]interface{}) {
+			result := r.(map[string]interface{})
+			ruleIndex := int(result["ruleIndex"].(float64))
+			assert.Less(t, ruleIndex, len(rules))
+			assert.Equal(t, rules[ruleIndex].(map[string]interface{})["id"], result["ruleId"])
+		}
+	}
+	return document
+}
+
+func TestBuildSARIFLog(t *testing.T) {
+	xss := GetIssueTemplateByCode(XssReflectedCode)
+	xss.ID = 1
+	xss.URL = "https://example.com/search?q=test"
+	xss.HTTPMethod = "GET"
+	xss.Details = "The q parameter is reflected"
+	xss.Confidence = 90
+	anotherXSS := *xss
+	anotherXSS.ID = 2
+	anotherXSS.URL = "https://example.com/profile"
+	anotherXSS.FalsePositive = true
+	sqli := GetIssueTemplateByCode(SqlInjectionCode)
+	sqli.ID = 3
+	sqli.URL = "https://example.com/users?id=1"
+	sqli.HTTPMethod = "POST"
+
+	log := BuildSARIFLog([]*Issue{xss, &anotherXSS, sqli})
+	data, err := json.Marshal(log)
+	assert.Nil(t, err)
+	assertValidSARIF(t, data)
+
+	driver := log.Runs[0].Tool.Driver
+	assert.Len(t, driver.Rules, 2)
+	assert.Equal(t, "xss_reflected", driver.Rules[0].ID)
+	assert.Equal(t, xss.Title, driver.Rules[0].ShortDescription.Text)
+	assert.Contains(t, driver.Rules[0].Properties.Tags, "external/cwe/cwe-79")
+	assert.Equal(t, "sql_injection", driver.Rules[1].ID)
+
+	results := log.Runs[0].Results
+	assert.Len(t, results, 3)
+	assert.Equal(t, 0, results[1].RuleIndex)
+	assert.Equal(t, 1, results[2].RuleIndex)
+	assert.Equal(t, sarifLevel(sqli.Severity), results[2].Level)
+	assert.Equal(t, "https://example.com/users?id=1", results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, "/users", results[2].Locations[0].LogicalLocations[0].Name)
+	assert.Empty(t, results[0].Suppressions)
+	assert.Len(t, results[1].Suppressions, 1)
+
+	assert.Equal(t, "error", sarifLevel(High))
+	assert.Equal(t, "warning", sarifLevel(Medium))
+	assert.Equal(t, "note", sarifLevel(Info))
+	assert.Equal(t, "none", sarifLevel(Unknown))
+}
+
+func TestExportIssuesSARIF(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:  "TestExportIssuesSARIF",
+		Title: "TestExportIssuesSARIF",
+	})
+	assert.Nil(t, err)
+	defer Connection.DeleteWorkspace(workspace.ID)
+
+	issue := GetIssueTemplateByCode(SqlInjectionCode)
+	issue.URL = "https://sarif.example.com/items?id=1"
+	issue.HTTPMethod = "GET"
+	issue.WorkspaceID = &workspace.ID
+	_, err = Connection.CreateIssue(*issue)
+	assert.Nil(t, err)
+
+	data, err := Connection.ExportIssuesSARIF(workspace.ID)
+	assert.Nil(t, err)
+	document := assertValidSARIF(t, data)
+	results := document["runs"].([]interface{})[0].(map[string]interface{})["results"].([]interface{})
+	assert.Len(t, results, 1)
+	assert.Equal(t, "sql_injection", results[0].(map[string]interface{})["ruleId"])
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/datatypes"
+)
+
+type ScanEventType string
+
+const (
+	ScanEventScanStarted          ScanEventType = "scan_started"
+	ScanEventCrawlStarted         ScanEventType = "crawl_started"
+	ScanEventCrawlFinished        ScanEventType = "crawl_finished"
+	ScanEventFingerprintsGathered ScanEventType = "fingerprints_gathered"
+	ScanEventNucleiStarted        ScanEventType = "nuclei_started"
+	ScanEventNucleiFinished       ScanEventType = "nuclei_finished"
+	ScanEventDiscoveryStarted     ScanEventType = "discovery_started"
+	ScanEventDiscoveryFinished    ScanEventType = "discovery_finished"
+	ScanEventActiveScansScheduled ScanEventType = "active_scans_scheduled"
+	ScanEventModuleSlow           ScanEventType = "module_slow"
+	ScanEventModuleFailed         ScanEventType = "module_failed"
+	ScanEventRateLimited          ScanEventType = "rate_limited"
+	ScanEventWAFDetected          ScanEventType = "waf_detected"
+	ScanEventScanPaused           ScanEventType = "scan_paused"
+	ScanEventScanResumed          ScanEventType = "scan_resumed"
+	ScanEventScanCompleted        ScanEventType = "scan_completed"
+	ScanEventScanTimeLimited      ScanEventType = "scan_time_limited"
+)
+
+// ScanEvent records a lifecycle event of a scan, providing an audit trail of what the scan has done
+type ScanEvent struct {
+	BaseModel
+	Type      ScanEventType  `gorm:"index" json:"type"`
+	Module    string         `gorm:"index" json:"module,omitempty"`
+	Message   string         `json:"message"`
+	Details   datatypes.JSON `json:"details,omitempty" swaggerignore:"true"`
+	TaskID    uint           `gorm:"index" json:"task_id"`
+	Task      Task           `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TaskJobID *uint          `gorm:"index" json:"task_job_id,omitempty"`
+}
+
+type ScanEventFilter struct {
+	TaskID     uint       `json:"task_id" validate:"required,numeric"`
+	Types      []string   `json:"types" validate:"omitempty,dive,ascii"`
+	Modules    []string   `json:"modules" validate:"omitempty,dive,ascii"`
+	TaskJobID  uint       `json:"task_job_id" validate:"omitempty,numeric"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// NewScanEvent creates a scan event for the provided task. Details are optional and stored as JSON.
+func (d *DatabaseConnection) NewScanEvent(taskID uint, taskJobID *uint, eventType ScanEventType, module, message string, details map[string]interface{}) (*ScanEvent, error) {
+	event := &ScanEvent{
+		Type:      eventType,
+		Module:    module,
+		Message:   message,
+		TaskID:    taskID,
+		TaskJobID: taskJobID,
+	}
+	if len(details) > 0 {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			log.Error().Err(err).Interface("details", details).Msg("Could not encode scan event details")
+		} else {
+			event.Details = datatypes.JSON(encoded)
+		}
+	}
+	return d.CreateScanEvent(event)
+}
+
+func (d *DatabaseConnection) CreateScanEvent(event *ScanEvent) (*ScanEvent, error) {
+	result := d.db.Create(event)
+	if result.Error != nil {
+		log.Error().Err(result.Error).Interface("event", event).Msg("Scan event creation failed")
+	}
+	return event, result.Error
+}
+
+// ListScanEvents returns the events of a scan in the order they have been recorded
+func (d *DatabaseConnection) ListScanEvents(filter ScanEventFilter) (items []*ScanEvent, count int64, err error) {
+	query := d.db.Model(&ScanEvent{}).Where("task_id = ?", filter.TaskID)
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if len(filter.Modules) > 0 {
+		query = query.Where("module IN ?", filter.Modules)
+	}
+	if filter.TaskJobID > 0 {
+		query = query.Where("task_job_id = ?", filter.TaskJobID)
+	}
+
+	if err = query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("created_at asc, id asc").Scopes(Paginate(&filter.Pagination)).Find(&items).Error
+	log.Debug().Interface("filters", filter).Int("gathered", len(items)).Int64("count", count).Msg("Getting scan events")
+	return items, count, err
+}
+
+// GetLatestScanEvent returns the last event recorded for the scan
+func (d *DatabaseConnection) GetLatestScanEvent(taskID uint) (*ScanEvent, error) {
+	var event ScanEvent
+	err := d.db.Where("task_id = ?", taskID).Order("created_at desc, id desc").First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
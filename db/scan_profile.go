@@ -0,0 +1,78 @@
+package db
+
+import (
+	"github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+)
+
+// ScanProfile is a named preset of scan options that can be reused when launching scans
+type ScanProfile struct {
+	BaseModel
+	Name        string                     `json:"name" gorm:"index"`
+	Description string                     `json:"description"`
+	Options     options.ScanProfileOptions `json:"options" gorm:"serializer:json"`
+}
+
+// CreateScanProfile creates a new ScanProfile record
+func (d *DatabaseConnection) CreateScanProfile(profile *ScanProfile) (*ScanProfile, error) {
+	result := d.db.Create(profile)
+	if result.Error != nil {
+		log.Error().Err(result.Error).Interface("scan_profile", profile).Msg("ScanProfile creation failed")
+	}
+	return profile, result.Error
+}
+
+// GetScanProfileByID retrieves a ScanProfile by its ID
+func (d *DatabaseConnection) GetScanProfileByID(id uint) (*ScanProfile, error) {
+	var profile ScanProfile
+	if err := d.db.Where("id = ?", id).First(&profile).Error; err != nil {
+		log.Error().Err(err).Uint("id", id).Msg("Unable to fetch ScanProfile by ID")
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// UpdateScanProfile updates an existing ScanProfile record, replacing all its options
+func (d *DatabaseConnection) UpdateScanProfile(id uint, profile *ScanProfile) (*ScanProfile, error) {
+	result := d.db.Model(&ScanProfile{}).Where("id = ?", id).Select("Name", "Description", "Options").Updates(profile)
+	if result.Error != nil {
+		log.Error().Err(result.Error).Interface("scan_profile", profile).Msg("ScanProfile update failed")
+	}
+	return profile, result.Error
+}
+
+// DeleteScanProfile deletes a ScanProfile record
+func (d *DatabaseConnection) DeleteScanProfile(id uint) error {
+	if err := d.db.Delete(&ScanProfile{}, id).Error; err != nil {
+		log.Error().Err(err).Uint("id", id).Msg("Error deleting ScanProfile")
+		return err
+	}
+	return nil
+}
+
+// ScanProfileFilter defines the filter for listing ScanProfiles
+type ScanProfileFilter struct {
+	Query      string     `json:"query" validate:"omitempty,ascii"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ListScanProfiles retrieves a list of ScanProfiles based on the provided filter
+func (d *DatabaseConnection) ListScanProfiles(filter ScanProfileFilter) (items []*ScanProfile, count int64, err error) {
+	query := d.db.Model(&ScanProfile{})
+
+	if filter.Query != "" {
+		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+filter.Query+"%", "%"+filter.Query+"%")
+	}
+
+	err = query.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Scopes(Paginate(&filter.Pagination)).Order("name asc").Find(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, count, nil
+}
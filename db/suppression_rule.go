@@ -0,0 +1,162 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SuppressionRule marks the issues matching it as false positives when they are created. Issues are matched by code,
+// a URL glob pattern where * matches any sequence of characters, and optionally a substring of the payload. Rules
+// without workspace apply to all of them.
+type SuppressionRule struct {
+	BaseModel
+	Code            string    `json:"code" gorm:"index"`
+	URLPattern      string    `json:"url_pattern"`
+	PayloadContains string    `json:"payload_contains"`
+	Reason          string    `json:"reason"`
+	Workspace       Workspace `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	WorkspaceID     *uint     `json:"workspace_id" gorm:"index"`
+}
+
+// Matches checks if the issue is suppressed by the rule
+func (r SuppressionRule) Matches(issue *Issue) bool {
+	if r.Code != issue.Code {
+		return false
+	}
+	if r.WorkspaceID != nil && (issue.WorkspaceID == nil || *r.WorkspaceID != *issue.WorkspaceID) {
+		return false
+	}
+	if r.PayloadContains != "" && !strings.Contains(issue.Payload, r.PayloadContains) {
+		return false
+	}
+	return matchURLPattern(r.URLPattern, issue.URL)
+}
+
+// matchURLPattern matches the URL against a glob pattern, where an empty pattern matches any URL
+func matchURLPattern(pattern, url string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	matched, err := regexp.MatchString(expression, url)
+	return err == nil && matched
+}
+
+// CreateSuppressionRule creates a new SuppressionRule record
+func (d *DatabaseConnection) CreateSuppressionRule(rule *SuppressionRule) (*SuppressionRule, error) {
+	if rule.WorkspaceID != nil && *rule.WorkspaceID == 0 {
+		rule.WorkspaceID = nil
+	}
+	result := d.db.Create(rule)
+	if result.Error != nil {
+		log.Error().Err(result.Error).Interface("suppression_rule", rule).Msg("SuppressionRule creation failed")
+	}
+	return rule, result.Error
+}
+
+// GetSuppressionRuleByID retrieves a SuppressionRule by its ID
+func (d *DatabaseConnection) GetSuppressionRuleByID(id uint) (*SuppressionRule, error) {
+	var rule SuppressionRule
+	if err := d.db.Where("id = ?", id).First(&rule).Error; err != nil {
+		log.Error().Err(err).Uint("id", id).Msg("Unable to fetch SuppressionRule by ID")
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteSuppressionRule deletes a SuppressionRule record. Issues already marked as false positives by it are kept as they are.
+func (d *DatabaseConnection) DeleteSuppressionRule(id uint) error {
+	if err := d.db.Delete(&SuppressionRule{}, id).Error; err != nil {
+		log.Error().Err(err).Uint("id", id).Msg("Error deleting SuppressionRule")
+		return err
+	}
+	return nil
+}
+
+// SuppressionRuleFilter defines the filter for listing SuppressionRules
+type SuppressionRuleFilter struct {
+	WorkspaceID uint       `json:"workspace_id"`
+	Codes       []string   `json:"codes"`
+	Pagination  Pagination `json:"pagination"`
+}
+
+// ListSuppressionRules retrieves the SuppressionRules matching the filter. When filtering by workspace, the rules
+// applying to all workspaces are included.
+func (d *DatabaseConnection) ListSuppressionRules(filter SuppressionRuleFilter) (items []*SuppressionRule, count int64, err error) {
+	query := d.db.Model(&SuppressionRule{})
+	if filter.WorkspaceID != 0 {
+		query = query.Where("workspace_id = ? OR workspace_id IS NULL", filter.WorkspaceID)
+	}
+	if len(filter.Codes) > 0 {
+		query = query.Where("code IN ?", filter.Codes)
+	}
+
+	err = query.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Pagination.PageSize > 0 {
+		query = query.Scopes(Paginate(&filter.Pagination))
+	}
+	err = query.Order("id asc").Find(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, count, nil
+}
+
+// ApplySuppressionRules marks the issue as a false positive when a suppression rule matches it, returning the
+// matching rule
+func (d *DatabaseConnection) ApplySuppressionRules(issue *Issue) *SuppressionRule {
+	filter := SuppressionRuleFilter{Codes: []string{issue.Code}}
+	if issue.WorkspaceID != nil {
+		filter.WorkspaceID = *issue.WorkspaceID
+	}
+	rules, _, err := d.ListSuppressionRules(filter)
+	if err != nil {
+		log.Error().Err(err).Str("code", issue.Code).Msg("Failed to get suppression rules")
+		return nil
+	}
+	for _, rule := range rules {
+		if rule.Matches(issue) {
+			issue.FalsePositive = true
+			issue.Status = IssueStatusFalsePositive
+			log.Info().Uint("rule", rule.ID).Str("code", issue.Code).Str("url", issue.URL).Msg("Issue marked as false positive by suppression rule")
+			return rule
+		}
+	}
+	return nil
+}
+
+// ApplySuppressionRuleToExistingIssues marks the existing issues matching the rule as false positives, returning the
+// number of updated issues. Issues are never deleted.
+func (d *DatabaseConnection) ApplySuppressionRuleToExistingIssues(rule *SuppressionRule) (int64, error) {
+	query := d.db.Model(&Issue{}).Select("id, code, url, payload, workspace_id").Where("code = ? AND false_positive = ?", rule.Code, false)
+	if rule.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *rule.WorkspaceID)
+	}
+	var issues []*Issue
+	if err := query.Find(&issues).Error; err != nil {
+		return 0, err
+	}
+	var ids []uint
+	for _, issue := range issues {
+		if rule.Matches(issue) {
+			ids = append(ids, issue.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := d.db.Model(&Issue{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"false_positive": true,
+		"status":         IssueStatusFalsePositive,
+	})
+	if result.Error != nil {
+		log.Error().Err(result.Error).Uint("rule", rule.ID).Msg("Failed to apply suppression rule to existing issues")
+	}
+	return result.RowsAffected, result.Error
+}
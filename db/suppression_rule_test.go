@@ -0,0 +1,108 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/lib/notifications"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppressionRuleMatches(t *testing.T) {
+	workspaceID := uint(1)
+	otherWorkspaceID := uint(2)
+	issue := &Issue{
+		Code:        "xss_reflected",
+		URL:         "https://example.com/search?q=test",
+		Payload:     "<script>alert(1)</script>",
+		WorkspaceID: &workspaceID,
+	}
+
+	assert.True(t, SuppressionRule{Code: "xss_reflected"}.Matches(issue))
+	assert.True(t, SuppressionRule{Code: "xss_reflected", URLPattern: "https://example.com/search*"}.Matches(issue))
+	assert.True(t, SuppressionRule{Code: "xss_reflected", URLPattern: "*://example.com/*"}.Matches(issue))
+	assert.True(t, SuppressionRule{Code: "xss_reflected", PayloadContains: "alert(1)"}.Matches(issue))
+	assert.True(t, SuppressionRule{Code: "xss_reflected", WorkspaceID: &workspaceID}.Matches(issue))
+
+	assert.False(t, SuppressionRule{Code: "sql_injection"}.Matches(issue))
+	assert.False(t, SuppressionRule{Code: "xss_reflected", URLPattern: "https://example.com/"}.Matches(issue))
+	// Regular expression characters in the pattern are matched literally
+	assert.False(t, SuppressionRule{Code: "xss_reflected", URLPattern: "https://example.com/search.q=*"}.Matches(issue))
+	assert.False(t, SuppressionRule{Code: "xss_reflected", PayloadContains: "onerror"}.Matches(issue))
+	assert.False(t, SuppressionRule{Code: "xss_reflected", WorkspaceID: &otherWorkspaceID}.Matches(issue))
+}
+
+func TestSuppressionRuleAppliesToFutureIssues(t *testing.T) {
+	workspace, err := Connection.GetOrCreateWorkspace(&Workspace{
+		Code:        "TestSuppressionRuleAppliesToFutureIssues",
+		Title:       "TestSuppressionRuleAppliesToFutureIssues",
+		Description: "TestSuppressionRuleAppliesToFutureIssues",
+	})
+	assert.Nil(t, err)
+
+	history, err := Connection.CreateHistory(&History{
+		URL:         "https://example.com/suppressed/path?id=1",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	existing, err := CreateIssueFromHistoryAndTemplate(history, SqlInjectionCode, "existing", 80, "", &workspace.ID, nil, nil)
+	assert.Nil(t, err)
+	assert.False(t, existing.FalsePositive)
+
+	rule, err := Connection.CreateSuppressionRule(&SuppressionRule{
+		Code:        string(SqlInjectionCode),
+		URLPattern:  "https://example.com/suppressed/*",
+		Reason:      "Parametrized query, the differences are caused by caching",
+		WorkspaceID: &workspace.ID,
+	})
+	assert.Nil(t, err)
+	assert.NotZero(t, rule.ID)
+
+	suppressed, err := CreateIssueFromHistoryAndTemplate(history, SqlInjectionCode, "new", 80, "", &workspace.ID, nil, nil)
+	assert.Nil(t, err)
+	assert.True(t, suppressed.FalsePositive)
+
+	// Other issue codes are not affected
+	other, err := CreateIssueFromHistoryAndTemplate(history, XssReflectedCode, "new", 80, "", &workspace.ID, nil, nil)
+	assert.Nil(t, err)
+	assert.False(t, other.FalsePositive)
+
+	// The existing issue is kept and is not marked as a false positive
+	fetched, err := Connection.GetIssue(int(existing.ID), false)
+	assert.Nil(t, err)
+	assert.False(t, fetched.FalsePositive)
+
+	updated, err := Connection.ApplySuppressionRuleToExistingIssues(rule)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), updated)
+	fetched, err = Connection.GetIssue(int(existing.ID), false)
+	assert.Nil(t, err)
+	assert.True(t, fetched.FalsePositive)
+
+	issues, _, err := Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Codes: []string{string(SqlInjectionCode)}})
+	assert.Nil(t, err)
+	assert.Len(t, issues, 2)
+
+	assert.Nil(t, Connection.DeleteSuppressionRule(rule.ID))
+	issues, _, err = Connection.ListIssues(IssueFilter{WorkspaceID: workspace.ID, Codes: []string{string(SqlInjectionCode)}})
+	assert.Nil(t, err)
+	assert.Len(t, issues, 2)
+}
+
+func TestSuppressedIssuesAreNotNotified(t *testing.T) {
+	var notified []notifications.IssueNotification
+	original := notifyIssue
+	notifyIssue = func(notification notifications.IssueNotification) {
+		notified = append(notified, notification)
+	}
+	defer func() { notifyIssue = original }()
+
+	notifyIssueCreated(Issue{Code: string(SqlInjectionCode), Title: "suppressed", FalsePositive: true})
+	assert.Empty(t, notified)
+
+	notifyIssueCreated(Issue{Code: string(SqlInjectionCode), Title: "reported"})
+	assert.Len(t, notified, 1)
+	assert.Equal(t, "reported", notified[0].Title)
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pyneda/sukyan/pkg/scan/options"
 	"github.com/rs/zerolog/log"
 )
 
@@ -14,6 +15,7 @@ var (
 	TaskJobRunning   TaskJobStatus = "running"
 	TaskJobFinished  TaskJobStatus = "finished"
 	TaskJobFailed    TaskJobStatus = "failed"
+	TaskJobCancelled TaskJobStatus = "cancelled"
 )
 
 type TaskJob struct {
@@ -26,11 +28,13 @@ type TaskJob struct {
 	CompletedAt time.Time     `json:"completed_at"`
 	HistoryID   uint          `json:"history_id"`
 	History     History       `json:"history" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// ScanOptions are persisted so that pending jobs can be resumed after a restart
+	ScanOptions options.HistoryItemScanOptions `gorm:"serializer:json" json:"-"`
 }
 
 type TaskJobFilter struct {
 	Query       string     `json:"query" validate:"omitempty,dive,ascii"`
-	Statuses    []string   `json:"statuses" validate:"omitempty,dive,oneof=scheduled running finished failed"`
+	Statuses    []string   `json:"statuses" validate:"omitempty,dive,oneof=scheduled running finished failed cancelled"`
 	Titles      []string   `json:"titles" validate:"omitempty,dive,ascii"`
 	Pagination  Pagination `json:"pagination"`
 	TaskID      uint       `json:"task_id" validate:"omitempty,numeric"`
@@ -135,6 +139,25 @@ func (d *DatabaseConnection) UpdateTaskJob(item *TaskJob) (*TaskJob, error) {
 	return item, result.Error
 }
 
+// ListPendingTaskJobs returns the scheduled and running jobs of a task, with their history item, in the order they were
+// scheduled
+func (d *DatabaseConnection) ListPendingTaskJobs(taskID uint) ([]*TaskJob, error) {
+	var items []*TaskJob
+	err := d.db.Preload("History").
+		Where("task_id = ? AND status IN ?", taskID, []TaskJobStatus{TaskJobScheduled, TaskJobRunning}).
+		Order("id asc").
+		Find(&items).Error
+	return items, err
+}
+
+// CancelScheduledTaskJobs marks the jobs of the task which have not been started as cancelled, returning how many
+func (d *DatabaseConnection) CancelScheduledTaskJobs(taskID uint) (int64, error) {
+	result := d.db.Model(&TaskJob{}).
+		Where("task_id = ? AND status = ?", taskID, TaskJobScheduled).
+		Updates(map[string]interface{}{"status": TaskJobCancelled, "completed_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
+
 func (d *DatabaseConnection) GetTaskJobByID(id uint) (*TaskJob, error) {
 	var item TaskJob
 	err := d.db.Where("id = ?", id).First(&item).Error
@@ -77,7 +77,7 @@ func (t Task) Pretty() string {
 
 type TaskFilter struct {
 	Query               string     `json:"query" validate:"omitempty,dive,ascii"`
-	Statuses            []string   `json:"statuses" validate:"omitempty,dive,oneof=crawling scanning nuclei running finished failed paused"`
+	Statuses            []string   `json:"statuses" validate:"omitempty,dive,oneof=crawling scanning nuclei running finished failed paused time_limited"`
 	Pagination          Pagination `json:"pagination"`
 	WorkspaceID         uint       `json:"workspace_id" validate:"omitempty,numeric"`
 	FetchStats          bool       `json:"fetch_stats"`
@@ -93,6 +93,7 @@ var (
 	TaskStatusFinished        string = "finished"
 	TaskStatusFailed          string = "failed"
 	TaskStatusPaused          string = "paused"
+	TaskStatusTimeLimited     string = "time_limited"
 	DefaultWorkspaceTaskTitle string = "Default task"
 )
 
@@ -345,3 +346,31 @@ func (d *DatabaseConnection) TaskHasPendingJobs(taskID uint) (bool, error) {
 
 	return count > 0, err
 }
+
+// CountTaskJobsByStatus returns the number of jobs of the task in each status
+func (d *DatabaseConnection) CountTaskJobsByStatus(taskID uint) (map[TaskJobStatus]int64, error) {
+	var rows []struct {
+		Status TaskJobStatus
+		Count  int64
+	}
+	err := d.db.Model(&TaskJob{}).
+		Select("status, COUNT(*) as count").
+		Where("task_id = ?", taskID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[TaskJobStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CountTaskIssues returns the number of issues found by the task
+func (d *DatabaseConnection) CountTaskIssues(taskID uint) (int64, error) {
+	var count int64
+	err := d.db.Model(&Issue{}).Where("task_id = ?", taskID).Count(&count).Error
+	return count, err
+}
@@ -39,12 +39,24 @@ func SetDefaultConfig() {
 	// Database
 	viper.SetDefault("db.max_iddle_conns", 10)
 	viper.SetDefault("db.max_open_conns", 80)
+	viper.SetDefault("db.issues.batch.enabled", false)
+	viper.SetDefault("db.issues.batch.window", 50) // milliseconds
+	viper.SetDefault("db.issues.batch.size", 100)
 
 	// Storage
 	viper.SetDefault("history.responses.ignored.max_size", 5*1024*1024)
 	viper.SetDefault("history.responses.ignored.extensions", []string{".jpg", ".jpeg", ".webp", ".png", ".gif", ".ico", ".mp4", ".mov", ".avi"})
 	viper.SetDefault("history.responses.ignored.content_types", []string{"video", "audio", "image"})
 
+	// HTTP
+	// Response bodies larger than this are truncated when read by the HTTP clients, 0 disables the limit
+	viper.SetDefault("http.max_body_bytes", 10*1024*1024)
+
+	// Tokens
+	// Wordlist of HMAC secrets tried against the JWTs after the embedded one, empty to only use the embedded wordlist
+	viper.SetDefault("tokens.jwt.wordlist", "")
+	viper.SetDefault("tokens.jwt.concurrency", 5)
+
 	// Navigation
 	viper.SetDefault("navigation.user_agent", "")
 	viper.SetDefault("navigation.timeout", 10)
@@ -67,30 +79,63 @@ func SetDefaultConfig() {
 	viper.SetDefault("crawl.page_setup_timeout", 15)
 	viper.SetDefault("crawl.interaction.timeout", 10)
 	viper.SetDefault("crawl.interaction.submit_forms", true)
+	viper.SetDefault("crawl.interaction.skip_forms_patterns", []string{"delete", "remove", "logout", "log out", "logoff", "signout", "sign out", "unsubscribe", "deactivate", "destroy", "cancel"})
 	viper.SetDefault("crawl.interaction.click_buttons", true)
 	viper.SetDefault("crawl.interaction.timeout", 10)
 	viper.SetDefault("crawl.common.files", []string{"/robots.txt", "/sitemap.xml"})
+	viper.SetDefault("crawl.seed.robots", true)
+	viper.SetDefault("crawl.seed.sitemaps", true)
+	viper.SetDefault("crawl.seed.max_sitemaps", 20)
+	viper.SetDefault("crawl.source_maps.enabled", true)
+	viper.SetDefault("crawl.js_endpoints.enabled", true)
 	viper.SetDefault("crawl.ignored_extensions", []string{".jpg", ".woff2", ".png", ".gif", ".webp", ".ico", ".css", ".svg", ".tif", ".tiff", ".bmp", ".raw", ".indd", ".ai", ".eps", ".pdf", ".exe", ".dll", ".psd", ".fla", ".avi", ".flv", ".mov", ".mp4", ".mpg", ".mpeg", ".swf", ".mkv", ".wav", ".mp3", ".flac", ".m4a", ".wma", ".aac", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".rtf", ".zip", ".rar", ".7z", ".tar.gz", ".iso", ".dmg"})
 	viper.SetDefault("crawl.max_pages_with_same_params", 20)
 
 	// Scan
 	viper.SetDefault("scan.magic_words", []string{"null", "None", "Undefined", "Blank"})
 	viper.SetDefault("scan.crawl.enabled", false)
+	viper.SetDefault("scan.max_duration.grace_period", 60)
+	viper.SetDefault("scan.events.slow_module_threshold", 60) // seconds a history item module can run before a module_slow event is recorded
 	viper.SetDefault("scan.concurrency.max_audits", 4)
 	viper.SetDefault("scan.concurrency.per_browser_audit", 4)
 	viper.SetDefault("scan.concurrency.per_http_audit", 16)
 	viper.SetDefault("scan.concurrency.passive", 30)
 	viper.SetDefault("scan.concurrency.active", 15)
 	viper.SetDefault("scan.browser.pool_size", 6)
+	viper.SetDefault("scan.browser.session_state_file", "")
 
 	viper.SetDefault("scan.oob.enabled", true)
 	viper.SetDefault("scan.oob.poll_interval", 10)
 	viper.SetDefault("scan.oob.wait_after_scan", 30)
 	viper.SetDefault("scan.oob.asn_info", false)
+	viper.SetDefault("scan.oob.max_pending_tests", 5000)
+	viper.SetDefault("scan.oob.pending_test_ttl", 600)
 	viper.SetDefault("scan.oob.server_urls", "oast.pro,oast.live,oast.site,oast.online,oast.fun,oast.me")
+	viper.SetDefault("scan.oob.provider", "oast") // oast uses the public servers, interactsh a self-hosted one
+	viper.SetDefault("scan.oob.interactsh.server_url", "")
+	viper.SetDefault("scan.oob.interactsh.token", "")
+
+	viper.SetDefault("scan.graphql.max_batch", 10)
+	viper.SetDefault("scan.graphql.auto_introspection", true)
+	viper.SetDefault("scan.rate_limit.requests_per_second", 0) // 0 disables rate limiting
+	viper.SetDefault("scan.rate_limit.burst", 0)               // 0 uses the requests per second as burst
+	viper.SetDefault("scan.rate_limit.backoff", true)
 
 	viper.SetDefault("scan.avoid_repeated_issues", true)
 
+	viper.SetDefault("scan.boolean.min_true_similarity", 0.95)
+	viper.SetDefault("scan.boolean.max_false_similarity", 0.85)
+	viper.SetDefault("scan.boolean.confirmations", 1)
+
+	viper.SetDefault("scan.time_based.attempts", 7)
+	viper.SetDefault("scan.time_based.retry_delay", 30) // seconds, multiplied by the attempt number
+	viper.SetDefault("scan.time_based.confidence_increment", 20)
+	viper.SetDefault("scan.time_based.confidence_decrement", 40)
+	viper.SetDefault("scan.time_based.min_confidence", 50)
+	viper.SetDefault("scan.time_based.baseline.samples", 5)
+	viper.SetDefault("scan.time_based.baseline.percentile", 95)
+	viper.SetDefault("scan.time_based.margin", 250) // milliseconds
+
 	// Generators
 	viper.SetDefault("generators.directory", "/etc/sukyan/generators")
 
@@ -101,6 +146,9 @@ func SetDefaultConfig() {
 	viper.SetDefault("passive.checks.js.enabled", true)
 	viper.SetDefault("passive.checks.missconfigurations.enabled", true)
 	viper.SetDefault("passive.checks.exceptions.enabled", true)
+	viper.SetDefault("passive.checks.secrets.enabled", true)
+	viper.SetDefault("passive.checks.secrets.min_entropy", 3.5)
+	viper.SetDefault("passive.checks.secrets.allowlist", []string{}) // regexes matched against the found secrets to ignore them
 
 	// Reporting
 	viper.SetDefault("reporting.issues.max_repeated_per_host", 20)
@@ -126,6 +174,18 @@ func SetDefaultConfig() {
 	viper.SetDefault("forms.auto_fill.names.password", "password")
 	viper.SetDefault("forms.auto_fill.names.email", "example@example.com")
 
+	// Notifications
+	viper.SetDefault("notifications.webhook.url", "")
+	viper.SetDefault("notifications.webhook.secret", "")
+	viper.SetDefault("notifications.webhook.min_severity", "medium")
+	viper.SetDefault("notifications.webhook.max_retries", 3)
+	viper.SetDefault("notifications.webhook.retry_delay", 1000) // milliseconds, doubled after each retry
+	viper.SetDefault("notifications.webhook.timeout", 10)       // seconds
+	viper.SetDefault("notifications.slack.webhook_url", "")
+	viper.SetDefault("notifications.slack.min_severity", "info")
+	viper.SetDefault("notifications.slack.issue_url", "")        // e.g. https://sukyan.example.com/issues/{id}
+	viper.SetDefault("notifications.slack.digest_interval", 300) // seconds, informational findings are sent as a digest
+
 	// Integrations
 	viper.SetDefault("integrations.nuclei.enabled", true)
 	viper.SetDefault("integrations.nuclei.host", "localhost")
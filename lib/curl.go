@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ShellQuote quotes the value to be used as a single argument in POSIX shells
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// BuildCurlCommand returns a curl command sending the provided request. Headers are sorted by name, the Content-Length
+// header is left to curl and the Host header is only kept when it does not match the URL, so injected values are
+// reproduced too. Paths are sent as they are, without curl normalizing them.
+func BuildCurlCommand(method, rawURL string, headers map[string][]string, body []byte) string {
+	parts := []string{"curl", "-i", "-s", "-k", "--path-as-is"}
+	if method != "" && (method != "GET" || len(body) > 0) {
+		parts = append(parts, "-X", ShellQuote(method))
+	}
+	parts = append(parts, ShellQuote(rawURL))
+
+	urlHost := ""
+	if parsed, err := url.Parse(rawURL); err == nil {
+		urlHost = parsed.Host
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.HasPrefix(name, ":") || strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		for _, value := range headers[name] {
+			if strings.EqualFold(name, "Host") && strings.EqualFold(value, urlHost) {
+				continue
+			}
+			parts = append(parts, "-H", ShellQuote(name+": "+value))
+		}
+	}
+
+	if len(body) > 0 {
+		parts = append(parts, "--data-binary", ShellQuote(string(body)))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,30 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'simple'`, ShellQuote("simple"))
+	assert.Equal(t, `'it'\''s'`, ShellQuote("it's"))
+	assert.Equal(t, `'$(id) `+"`id`"+`'`, ShellQuote("$(id) `id`"))
+}
+
+func TestBuildCurlCommand(t *testing.T) {
+	command := BuildCurlCommand("GET", "https://example.com/search?q=<script>", map[string][]string{
+		"User-Agent": {"sukyan"},
+		"Cookie":     {"session=abc"},
+		"Host":       {"example.com"},
+	}, nil)
+	assert.Equal(t, `curl -i -s -k --path-as-is 'https://example.com/search?q=<script>' -H 'Cookie: session=abc' -H 'User-Agent: sukyan'`, command)
+
+	command = BuildCurlCommand("POST", "https://example.com/login", map[string][]string{
+		"Content-Type":   {"application/json"},
+		"Content-Length": {"27"},
+		"Host":           {"evil.com"},
+		":authority":     {"example.com"},
+	}, []byte(`{"user":"o'brien","pw":"x"}`))
+	assert.Equal(t, `curl -i -s -k --path-as-is -X 'POST' 'https://example.com/login' -H 'Content-Type: application/json' -H 'Host: evil.com' --data-binary '{"user":"o'\''brien","pw":"x"}'`, command)
+}
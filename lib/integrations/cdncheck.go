@@ -1,6 +1,7 @@
 package integrations
 
 import (
+	"fmt"
 	"net"
 
 	"github.com/projectdiscovery/cdncheck"
@@ -83,6 +84,13 @@ func checkWAF(client *cdncheck.Client, ip net.IP, urlStr string, workspaceID, ta
 		if err != nil {
 			return db.Issue{}, err
 		}
+		if taskID != 0 {
+			db.Connection.NewScanEvent(taskID, nil, db.ScanEventWAFDetected, "cdncheck", fmt.Sprintf("%v is behind %v WAF", urlStr, val), map[string]interface{}{
+				"url": urlStr,
+				"ip":  ip.String(),
+				"waf": val,
+			})
+		}
 		log.Info().Str("check", "cdncheck").Uint("workspace", workspaceID).Msgf("%v is a %v", ip, val)
 		return createdIssue, nil
 	}
@@ -1,10 +1,8 @@
 package integrations
 
 import (
-	"github.com/projectdiscovery/interactsh/pkg/client"
 	"github.com/projectdiscovery/interactsh/pkg/server"
 	"github.com/rs/zerolog/log"
-	"github.com/spf13/viper"
 	"os"
 	"strings"
 	"time"
@@ -16,29 +14,35 @@ type InteractionDomain struct {
 }
 
 type InteractionsManager struct {
-	client                *client.Client
+	// Provider is the out of band backend used, when not set it is created from the config on start
+	Provider              OOBProvider
 	GetAsnInfo            bool
 	PollingInterval       time.Duration
 	OnInteractionCallback func(interaction *server.Interaction)
+	// MaxPendingTests caps the out of band tests waiting for an interaction, 0 means no limit
+	MaxPendingTests int
+	// PendingTestTTL is the time after which a test without interactions is no longer considered pending
+	PendingTestTTL time.Duration
+	pendingTests   *pendingOOBTests
 }
 
 func (i *InteractionsManager) Start() {
-	options := client.DefaultOptions
-	if viper.GetString("scan.oob.server_urls") != "" {
-		options.ServerURL = viper.GetString("scan.oob.server_urls")
-	}
-	var err error
-	i.client, err = client.New(options)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Could not create interactsh client")
-		os.Exit(1)
-	}
-	i.client.StartPolling(i.PollingInterval, func(interaction *server.Interaction) {
-		if i.GetAsnInfo {
-			i.client.TryGetAsnInfo(interaction)
+	if i.Provider == nil {
+		provider, err := NewOOBProviderFromConfig(i.GetAsnInfo)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not create out of band interactions provider")
+			os.Exit(1)
 		}
+		i.Provider = provider
+	}
+	i.pendingTests = newPendingOOBTests()
+	err := i.Provider.Poll(i.PollingInterval, func(interaction *server.Interaction) {
+		i.releasePendingTest(interaction.FullId)
 		i.OnInteractionCallback(interaction)
 	})
+	if err != nil {
+		log.Error().Err(err).Msg("Could not start polling out of band interactions")
+	}
 }
 
 func (i *InteractionsManager) GetIdentifierFromURL(url string) string {
@@ -50,14 +54,15 @@ func (i *InteractionsManager) GetIdentifierFromURL(url string) string {
 }
 
 func (i *InteractionsManager) GetURL() InteractionDomain {
-	url := i.client.URL()
-	return InteractionDomain{
-		ID:  i.GetIdentifierFromURL(url),
-		URL: url,
+	domain := i.newInteractionDomain()
+	if i.pendingTests != nil {
+		i.pendingTests.mu.Lock()
+		i.pendingTests.pending[strings.ToLower(domain.ID)] = time.Now()
+		i.pendingTests.mu.Unlock()
 	}
+	return domain
 }
 
 func (i *InteractionsManager) Stop() {
-	i.client.StopPolling()
-	i.client.Close()
+	i.Provider.Close()
 }
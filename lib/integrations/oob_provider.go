@@ -0,0 +1,101 @@
+package integrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/projectdiscovery/interactsh/pkg/client"
+	"github.com/projectdiscovery/interactsh/pkg/server"
+	"github.com/spf13/viper"
+)
+
+const (
+	// OOBProviderOast uses the public interactsh servers
+	OOBProviderOast = "oast"
+	// OOBProviderInteractsh uses a self-hosted interactsh server
+	OOBProviderInteractsh = "interactsh"
+)
+
+// OOBProvider is an out of band interactions backend, providing the domains inserted in the payloads and notifying
+// the interactions they receive
+type OOBProvider interface {
+	// RegisterDomain returns a new unique interaction domain
+	RegisterDomain() string
+	// Poll starts polling the interactions received by the registered domains, calling the callback for each of them
+	Poll(interval time.Duration, callback func(interaction *server.Interaction)) error
+	// Close stops polling and releases the provider resources
+	Close() error
+}
+
+// InteractshProviderOptions configures the interactsh client
+type InteractshProviderOptions struct {
+	// ServerURLs is a comma separated list of interactsh servers, a random one is used
+	ServerURLs string
+	// Token is required by servers configured with authentication
+	Token      string
+	GetAsnInfo bool
+}
+
+// InteractshProvider receives the interactions using an interactsh client
+type InteractshProvider struct {
+	client     *client.Client
+	getAsnInfo bool
+}
+
+// NewInteractshProvider registers a new interactsh client session
+func NewInteractshProvider(options InteractshProviderOptions) (*InteractshProvider, error) {
+	// Copied, as the default options are shared
+	clientOptions := *client.DefaultOptions
+	if options.ServerURLs != "" {
+		clientOptions.ServerURL = options.ServerURLs
+	}
+	clientOptions.Token = options.Token
+	c, err := client.New(&clientOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &InteractshProvider{client: c, getAsnInfo: options.GetAsnInfo}, nil
+}
+
+func (p *InteractshProvider) RegisterDomain() string {
+	return p.client.URL()
+}
+
+func (p *InteractshProvider) Poll(interval time.Duration, callback func(interaction *server.Interaction)) error {
+	return p.client.StartPolling(interval, func(interaction *server.Interaction) {
+		if p.getAsnInfo {
+			p.client.TryGetAsnInfo(interaction)
+		}
+		callback(interaction)
+	})
+}
+
+func (p *InteractshProvider) Close() error {
+	p.client.StopPolling()
+	return p.client.Close()
+}
+
+// NewOOBProviderFromConfig creates the out of band provider selected by the scan.oob.provider config
+func NewOOBProviderFromConfig(getAsnInfo bool) (OOBProvider, error) {
+	provider := viper.GetString("scan.oob.provider")
+	switch provider {
+	case OOBProviderOast, "":
+		return NewInteractshProvider(InteractshProviderOptions{
+			ServerURLs: viper.GetString("scan.oob.server_urls"),
+			GetAsnInfo: getAsnInfo,
+		})
+	case OOBProviderInteractsh:
+		serverURL := viper.GetString("scan.oob.interactsh.server_url")
+		if serverURL == "" {
+			return nil, errors.New("scan.oob.interactsh.server_url is required to use a self-hosted interactsh server")
+		}
+		return NewInteractshProvider(InteractshProviderOptions{
+			ServerURLs: serverURL,
+			Token:      viper.GetString("scan.oob.interactsh.token"),
+			GetAsnInfo: getAsnInfo,
+		})
+	default:
+		return nil, fmt.Errorf("unknown out of band provider %s", provider)
+	}
+}
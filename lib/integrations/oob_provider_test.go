@@ -0,0 +1,165 @@
+package integrations
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/interactsh/pkg/server"
+	"github.com/pyneda/sukyan/db"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOOBProvider hands out sequential domains and delivers the interactions sent through interact
+type fakeOOBProvider struct {
+	mu        sync.Mutex
+	generated int
+	callback  func(interaction *server.Interaction)
+	closed    bool
+}
+
+func (p *fakeOOBProvider) RegisterDomain() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.generated++
+	return fmt.Sprintf("token%d.oast.test", p.generated)
+}
+
+func (p *fakeOOBProvider) Poll(interval time.Duration, callback func(interaction *server.Interaction)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callback = callback
+	return nil
+}
+
+func (p *fakeOOBProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+// interact simulates an interaction received by the provided domain, identified as interactsh does by the subdomain
+// holding the correlation id
+func (p *fakeOOBProvider) interact(domain, protocol string) {
+	p.mu.Lock()
+	callback := p.callback
+	p.mu.Unlock()
+	id := strings.Split(domain, ".")[0]
+	callback(&server.Interaction{
+		Protocol:      protocol,
+		UniqueID:      id,
+		FullId:        id,
+		RemoteAddress: "203.0.113.10",
+		Timestamp:     time.Now(),
+	})
+}
+
+func TestInteractionsManagerCorrelatesProviderInteractions(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestInteractionsManagerCorrelatesProviderInteractions",
+		Title: "TestInteractionsManagerCorrelatesProviderInteractions",
+	})
+	assert.Nil(t, err)
+	defer db.Connection.DeleteWorkspace(workspace.ID)
+
+	provider := &fakeOOBProvider{}
+	var received []*server.Interaction
+	var matched []db.OOBTest
+	var stored []db.OOBInteraction
+	manager := &InteractionsManager{
+		Provider:        provider,
+		PollingInterval: time.Second,
+		MaxPendingTests: 10,
+		PendingTestTTL:  time.Minute,
+		// Interactions are stored and matched with their OOB test as the scan callback does
+		OnInteractionCallback: func(interaction *server.Interaction) {
+			received = append(received, interaction)
+			saved, err := db.Connection.CreateInteraction(&db.OOBInteraction{
+				Protocol:      interaction.Protocol,
+				FullID:        interaction.FullId,
+				UniqueID:      interaction.UniqueID,
+				RemoteAddress: interaction.RemoteAddress,
+				Timestamp:     interaction.Timestamp,
+			})
+			assert.Nil(t, err)
+			stored = append(stored, *saved)
+			test, err := db.Connection.MatchInteractionWithOOBTest(*saved)
+			if err == nil {
+				matched = append(matched, test)
+			}
+		},
+	}
+	manager.Start()
+
+	ssrf, _, err := manager.GetURLForTest(OOBTestKey{Target: "https://example.com/?url=x", InsertionPoint: "url parameter"})
+	assert.Nil(t, err)
+	xxe, _, err := manager.GetURLForTest(OOBTestKey{Target: "https://example.com/upload", InsertionPoint: "body"})
+	assert.Nil(t, err)
+	assert.Equal(t, "token1.oast.test", ssrf.URL)
+	assert.Equal(t, "token1", ssrf.ID)
+	assert.Equal(t, 2, manager.PendingTests())
+
+	// The OOB test is persisted with the id of the interaction url, as the active modules do
+	ssrfTest, err := db.Connection.CreateOOBTest(db.OOBTest{
+		Code:              db.SsrfCode,
+		TestName:          "SSRF",
+		Target:            "https://example.com/?url=x",
+		InteractionDomain: ssrf.URL,
+		InteractionFullID: ssrf.ID,
+		Payload:           "http://" + ssrf.URL,
+		InsertionPoint:    "url parameter",
+		WorkspaceID:       &workspace.ID,
+	})
+	assert.Nil(t, err)
+
+	provider.interact(ssrf.URL, "dns")
+	provider.interact(ssrf.URL, "http")
+	assert.Len(t, received, 2)
+	// The full id is what is stored and matched against the interaction full id of the OOB test
+	assert.Equal(t, ssrf.ID, received[0].FullId)
+	assert.Equal(t, "http", received[1].Protocol)
+	assert.Equal(t, 1, manager.PendingTests())
+
+	assert.Len(t, matched, 2)
+	for _, test := range matched {
+		assert.Equal(t, ssrfTest.ID, test.ID)
+	}
+	interaction, err := db.Connection.GetInteraction(stored[1].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, ssrfTest.ID, *interaction.OOBTestID)
+	assert.Equal(t, workspace.ID, *interaction.WorkspaceID)
+	interactions, _, err := db.Connection.ListInteractions(db.InteractionsFilter{WorkspaceID: workspace.ID})
+	assert.Nil(t, err)
+	assert.Len(t, interactions, 2)
+	issues, _, err := db.Connection.ListIssues(db.IssueFilter{WorkspaceID: workspace.ID, Codes: []string{string(db.SsrfCode)}})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, issues)
+
+	// Interactions without an OOB test are stored but not linked
+	provider.interact(xxe.URL, "dns")
+	assert.Equal(t, 0, manager.PendingTests())
+	assert.Len(t, matched, 2)
+	interaction, err = db.Connection.GetInteraction(stored[2].ID)
+	assert.Nil(t, err)
+	assert.Nil(t, interaction.OOBTestID)
+
+	manager.Stop()
+	assert.True(t, provider.closed)
+}
+
+func TestNewOOBProviderFromConfigValidation(t *testing.T) {
+	defer viper.Set("scan.oob.provider", viper.GetString("scan.oob.provider"))
+
+	viper.Set("scan.oob.provider", "unknown")
+	_, err := NewOOBProviderFromConfig(false)
+	assert.NotNil(t, err)
+
+	viper.Set("scan.oob.provider", OOBProviderInteractsh)
+	viper.Set("scan.oob.interactsh.server_url", "")
+	_, err = NewOOBProviderFromConfig(false)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,160 @@
+package integrations
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrPendingOOBTestsLimitReached is returned when a new interaction url is requested while the maximum number of
+// pending out of band tests has been reached
+var ErrPendingOOBTestsLimitReached = errors.New("maximum number of pending out of band tests reached")
+
+// OOBTestKey identifies an out of band test payload inserted in a target insertion point. Tests with the same key
+// send identical payloads, so they can share the same interaction url.
+type OOBTestKey struct {
+	Target         string
+	InsertionPoint string
+	Payload        string
+}
+
+type sharedInteractionURL struct {
+	domain    InteractionDomain
+	createdAt time.Time
+}
+
+// pendingOOBTests keeps track of the interaction urls handed out to out of band tests which have not received
+// any interaction yet. It is held through a pointer as the interactions manager is copied around.
+type pendingOOBTests struct {
+	mu      sync.Mutex
+	pending map[string]time.Time
+	shared  map[OOBTestKey]sharedInteractionURL
+}
+
+func newPendingOOBTests() *pendingOOBTests {
+	return &pendingOOBTests{
+		pending: make(map[string]time.Time),
+		shared:  make(map[OOBTestKey]sharedInteractionURL),
+	}
+}
+
+// expire removes the tests which have been pending for longer than the ttl
+func (p *pendingOOBTests) expire(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	for id, createdAt := range p.pending {
+		if now.Sub(createdAt) > ttl {
+			delete(p.pending, id)
+		}
+	}
+	for key, shared := range p.shared {
+		if now.Sub(shared.createdAt) > ttl {
+			delete(p.shared, key)
+		}
+	}
+}
+
+func (i *InteractionsManager) newInteractionDomain() InteractionDomain {
+	url := i.Provider.RegisterDomain()
+	return InteractionDomain{
+		ID:  i.GetIdentifierFromURL(url),
+		URL: url,
+	}
+}
+
+// GetURLForTest returns an interaction url to be used by an out of band test. Tests sending the same payload to the same
+// insertion point reuse the same interaction url, in which case reused is true. When the number of pending tests has
+// reached MaxPendingTests, ErrPendingOOBTestsLimitReached is returned.
+func (i *InteractionsManager) GetURLForTest(key OOBTestKey) (domain InteractionDomain, reused bool, err error) {
+	if i.pendingTests == nil {
+		return i.GetURL(), false, nil
+	}
+	i.pendingTests.mu.Lock()
+	defer i.pendingTests.mu.Unlock()
+	i.pendingTests.expire(i.PendingTestTTL)
+
+	if shared, ok := i.pendingTests.shared[key]; ok {
+		return shared.domain, true, nil
+	}
+	if i.MaxPendingTests > 0 && len(i.pendingTests.pending) >= i.MaxPendingTests {
+		return InteractionDomain{}, false, ErrPendingOOBTestsLimitReached
+	}
+	domain = i.newInteractionDomain()
+	now := time.Now()
+	i.pendingTests.pending[strings.ToLower(domain.ID)] = now
+	i.pendingTests.shared[key] = sharedInteractionURL{domain: domain, createdAt: now}
+	return domain, false, nil
+}
+
+// ClaimURLForTest registers an interaction url already embedded in a payload (e.g. by the payload generators) for the
+// provided test. If an identical test already has an interaction url, that one is returned with reused set to true
+// and the provided url is released. ErrPendingOOBTestsLimitReached is returned, also releasing the url, when
+// registering it would exceed MaxPendingTests.
+func (i *InteractionsManager) ClaimURLForTest(key OOBTestKey, domain InteractionDomain) (InteractionDomain, bool, error) {
+	if i.pendingTests == nil {
+		return domain, false, nil
+	}
+	i.pendingTests.mu.Lock()
+	defer i.pendingTests.mu.Unlock()
+	i.pendingTests.expire(i.PendingTestTTL)
+	id := strings.ToLower(domain.ID)
+
+	if shared, ok := i.pendingTests.shared[key]; ok {
+		if shared.domain.ID != domain.ID {
+			delete(i.pendingTests.pending, id)
+		}
+		return shared.domain, true, nil
+	}
+	if _, ok := i.pendingTests.pending[id]; !ok {
+		i.pendingTests.pending[id] = time.Now()
+	}
+	if i.MaxPendingTests > 0 && len(i.pendingTests.pending) > i.MaxPendingTests {
+		delete(i.pendingTests.pending, id)
+		return InteractionDomain{}, false, ErrPendingOOBTestsLimitReached
+	}
+	i.pendingTests.shared[key] = sharedInteractionURL{domain: domain, createdAt: time.Now()}
+	return domain, false, nil
+}
+
+// PendingTestsLimitReached returns true when no more out of band tests should be created
+func (i *InteractionsManager) PendingTestsLimitReached() bool {
+	if i.pendingTests == nil || i.MaxPendingTests <= 0 {
+		return false
+	}
+	i.pendingTests.mu.Lock()
+	defer i.pendingTests.mu.Unlock()
+	i.pendingTests.expire(i.PendingTestTTL)
+	return len(i.pendingTests.pending) >= i.MaxPendingTests
+}
+
+// PendingTests returns the number of out of band tests waiting for an interaction
+func (i *InteractionsManager) PendingTests() int {
+	if i.pendingTests == nil {
+		return 0
+	}
+	i.pendingTests.mu.Lock()
+	defer i.pendingTests.mu.Unlock()
+	return len(i.pendingTests.pending)
+}
+
+// releasePendingTest stops counting the test using the provided interaction id, or full domain, as pending
+func (i *InteractionsManager) releasePendingTest(id string) {
+	if i.pendingTests == nil {
+		return
+	}
+	if identifier := i.GetIdentifierFromURL(id); identifier != "" {
+		id = identifier
+	}
+	i.pendingTests.mu.Lock()
+	defer i.pendingTests.mu.Unlock()
+	id = strings.ToLower(id)
+	if _, ok := i.pendingTests.pending[id]; ok {
+		delete(i.pendingTests.pending, id)
+		log.Debug().Str("id", id).Int("pending", len(i.pendingTests.pending)).Msg("Released pending out of band test")
+	}
+}
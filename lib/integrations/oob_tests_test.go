@@ -0,0 +1,92 @@
+package integrations
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestInteractionsManager(maxPendingTests int) *InteractionsManager {
+	return &InteractionsManager{
+		Provider:        &fakeOOBProvider{},
+		MaxPendingTests: maxPendingTests,
+		PendingTestTTL:  time.Minute,
+		pendingTests:    newPendingOOBTests(),
+	}
+}
+
+func TestGetURLForTestReusesTokenForDuplicatePayloads(t *testing.T) {
+	manager := newTestInteractionsManager(10)
+	key := OOBTestKey{Target: "https://example.com/?url=x", InsertionPoint: "url parameter", Payload: "http://{{interactionAddress}}"}
+
+	first, reused, err := manager.GetURLForTest(key)
+	assert.Nil(t, err)
+	assert.False(t, reused)
+	assert.Equal(t, "token1", first.ID)
+
+	second, reused, err := manager.GetURLForTest(key)
+	assert.Nil(t, err)
+	assert.True(t, reused)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, manager.PendingTests())
+
+	// A different insertion point gets its own token
+	other, reused, err := manager.GetURLForTest(OOBTestKey{Target: key.Target, InsertionPoint: "Referer header", Payload: key.Payload})
+	assert.Nil(t, err)
+	assert.False(t, reused)
+	assert.NotEqual(t, first.ID, other.ID)
+	assert.Equal(t, 2, manager.PendingTests())
+}
+
+func TestGetURLForTestPendingLimit(t *testing.T) {
+	manager := newTestInteractionsManager(2)
+	for i := 0; i < 2; i++ {
+		_, _, err := manager.GetURLForTest(OOBTestKey{Target: "https://example.com", InsertionPoint: fmt.Sprintf("param%d", i)})
+		assert.Nil(t, err)
+	}
+	assert.True(t, manager.PendingTestsLimitReached())
+
+	_, _, err := manager.GetURLForTest(OOBTestKey{Target: "https://example.com", InsertionPoint: "param2"})
+	assert.ErrorIs(t, err, ErrPendingOOBTestsLimitReached)
+
+	// Duplicates can still reuse their token while the limit is reached
+	_, reused, err := manager.GetURLForTest(OOBTestKey{Target: "https://example.com", InsertionPoint: "param0"})
+	assert.Nil(t, err)
+	assert.True(t, reused)
+
+	// Receiving an interaction releases the pending test
+	manager.releasePendingTest("token1.oast.test")
+	assert.False(t, manager.PendingTestsLimitReached())
+	_, _, err = manager.GetURLForTest(OOBTestKey{Target: "https://example.com", InsertionPoint: "param2"})
+	assert.Nil(t, err)
+}
+
+func TestPendingTestsExpire(t *testing.T) {
+	manager := newTestInteractionsManager(1)
+	manager.PendingTestTTL = 10 * time.Millisecond
+	manager.GetURL()
+	assert.True(t, manager.PendingTestsLimitReached())
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, manager.PendingTestsLimitReached())
+}
+
+func TestClaimURLForTestReusesTokenForDuplicatePayloads(t *testing.T) {
+	manager := newTestInteractionsManager(10)
+	key := OOBTestKey{Target: "https://example.com/", InsertionPoint: "Referer header", Payload: "http://{{interactionAddress}}"}
+
+	// Token generated by the SSRF headers audit
+	shared, reused, err := manager.GetURLForTest(key)
+	assert.Nil(t, err)
+	assert.False(t, reused)
+
+	// A payload generator rendering an identical payload for the same insertion point reuses it
+	generated := manager.GetURL()
+	assert.Equal(t, 2, manager.PendingTests())
+	claimed, reused, err := manager.ClaimURLForTest(key, generated)
+	assert.Nil(t, err)
+	assert.True(t, reused)
+	assert.Equal(t, shared, claimed)
+	assert.Equal(t, 1, manager.PendingTests())
+}
@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	IssueCreatedEvent  = "issue.created"
+	IssueAssignedEvent = "issue.assigned"
+)
+
+// IssueNotification is the payload sent when a new issue is found or assigned
+type IssueNotification struct {
+	Event       string    `json:"event"`
+	IssueID     uint      `json:"issue_id"`
+	Code        string    `json:"code"`
+	Title       string    `json:"title"`
+	Severity    string    `json:"severity"`
+	URL         string    `json:"url"`
+	WorkspaceID uint      `json:"workspace_id"`
+	ScanID      uint      `json:"scan_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	// AssignedTo is the email of the user the issue has been assigned to, only set for assignment events
+	AssignedTo string `json:"assigned_to,omitempty"`
+}
+
+func severityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "info":
+		return 1
+	case "low":
+		return 2
+	case "medium":
+		return 3
+	case "high":
+		return 4
+	case "critical":
+		return 5
+	default:
+		return 0
+	}
+}
+
+// NotifyIssue sends the issue notification to the configured channels in the background, so issue creation is not
+// delayed by the delivery. Channels that are not configured or have a minimum severity above the issue one are skipped.
+func NotifyIssue(notification IssueNotification) {
+	if notification.Event == "" {
+		notification.Event = IssueCreatedEvent
+	}
+	if webhook := issueWebhook(); webhook != nil && webhook.ShouldNotify(notification.Severity) {
+		sendWebhookNotification(webhook, notification)
+	}
+	if slack := issueSlackNotifier(); slack != nil {
+		slack.Notify(notification)
+	}
+}
+
+// pendingWebhookNotifications tracks the webhook notifications being sent in the background
+var pendingWebhookNotifications sync.WaitGroup
+
+// sendWebhookNotification sends the notification in the background, Flush waits for it to be delivered
+func sendWebhookNotification(webhook *Webhook, notification IssueNotification) {
+	pendingWebhookNotifications.Add(1)
+	go func() {
+		defer pendingWebhookNotifications.Done()
+		if err := webhook.Send(context.Background(), notification.Event, notification); err != nil {
+			log.Error().Err(err).Uint("issue", notification.IssueID).Str("code", notification.Code).Msg("Failed to send issue webhook notification")
+		}
+	}()
+}
+
+// Flush sends the notifications that are pending to be delivered, such as the Slack digest, and waits for the ones
+// being sent in the background. It should be called before exiting.
+func Flush() {
+	if slack := issueSlackNotifier(); slack != nil {
+		if err := slack.Flush(); err != nil {
+			log.Error().Err(err).Msg("Failed to send pending Slack notifications")
+		}
+	}
+	pendingWebhookNotifications.Wait()
+}
@@ -0,0 +1,322 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	// slackDigestMaxGroups limits the findings grouped in a digest message, as Slack rejects messages with more
+	// than 50 blocks
+	slackDigestMaxGroups = 40
+	// slackDigestMaxURLs is the number of URLs listed for each group of findings in a digest message
+	slackDigestMaxURLs = 5
+)
+
+// SlackConfig holds the configuration of the Slack notifications
+type SlackConfig struct {
+	WebhookURL  string
+	MinSeverity string
+	// IssueURL is the URL used to link to the issues, where {id} is replaced by the issue ID
+	IssueURL string
+	// DigestInterval is how often the informational findings are grouped and sent. They are sent one by one when zero.
+	DigestInterval time.Duration
+	MaxRetries     int
+	RetryDelay     time.Duration
+	Timeout        time.Duration
+}
+
+// SlackConfigFromViper loads the Slack configuration from the notifications.slack settings
+func SlackConfigFromViper() SlackConfig {
+	return SlackConfig{
+		WebhookURL:     viper.GetString("notifications.slack.webhook_url"),
+		MinSeverity:    viper.GetString("notifications.slack.min_severity"),
+		IssueURL:       viper.GetString("notifications.slack.issue_url"),
+		DigestInterval: time.Duration(viper.GetInt("notifications.slack.digest_interval")) * time.Second,
+		MaxRetries:     viper.GetInt("notifications.webhook.max_retries"),
+		RetryDelay:     time.Duration(viper.GetInt("notifications.webhook.retry_delay")) * time.Millisecond,
+		Timeout:        time.Duration(viper.GetInt("notifications.webhook.timeout")) * time.Second,
+	}
+}
+
+// SlackMessage is a Slack incoming webhook message using Block Kit
+type SlackMessage struct {
+	Text        string            `json:"text"`
+	Blocks      []SlackBlock      `json:"blocks,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
+
+// SlackAttachment is used to display the blocks next to a bar of the severity color
+type SlackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+type SlackBlock struct {
+	Type     string         `json:"type"`
+	Text     *SlackText     `json:"text,omitempty"`
+	Fields   []SlackText    `json:"fields,omitempty"`
+	Elements []SlackElement `json:"elements,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackElement is an interactive element of an actions block, such as a link button
+type SlackElement struct {
+	Type string     `json:"type"`
+	Text *SlackText `json:"text,omitempty"`
+	URL  string     `json:"url,omitempty"`
+}
+
+// slackSeverityColor returns the color of the attachment bar for a severity
+func slackSeverityColor(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "#7b1fa2"
+	case "high":
+		return "#e01e5a"
+	case "medium":
+		return "#ecb22e"
+	case "low":
+		return "#2eb67d"
+	default:
+		return "#439fe0"
+	}
+}
+
+// slackEscape escapes the characters with a special meaning in Slack mrkdwn text
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+func slackMarkdown(text string) *SlackText {
+	return &SlackText{Type: "mrkdwn", Text: text}
+}
+
+// BuildSlackIssueMessage builds the message of a single finding. The link to the issue is only added when issueURL
+// is not empty.
+func BuildSlackIssueMessage(notification IssueNotification, issueURL string) SlackMessage {
+	blocks := []SlackBlock{
+		{
+			Type: "header",
+			Text: &SlackText{Type: "plain_text", Text: notification.Title},
+		},
+		{
+			Type: "section",
+			Fields: []SlackText{
+				{Type: "mrkdwn", Text: "*Severity*\n" + notification.Severity},
+				{Type: "mrkdwn", Text: "*Code*\n" + slackEscape(notification.Code)},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*Workspace*\n%d", notification.WorkspaceID)},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*Scan*\n%d", notification.ScanID)},
+			},
+		},
+		{
+			Type: "section",
+			Text: slackMarkdown("*URL*\n" + slackEscape(notification.URL)),
+		},
+	}
+	text := fmt.Sprintf("[%s] %s found at %s", notification.Severity, notification.Title, notification.URL)
+	if notification.Event == IssueAssignedEvent {
+		text = fmt.Sprintf("[%s] %s at %s assigned to %s", notification.Severity, notification.Title, notification.URL, notification.AssignedTo)
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: slackMarkdown("*Assigned to*\n" + slackEscape(notification.AssignedTo)),
+		})
+	}
+	if issueURL != "" {
+		blocks = append(blocks, SlackBlock{
+			Type: "actions",
+			Elements: []SlackElement{
+				{
+					Type: "button",
+					Text: &SlackText{Type: "plain_text", Text: "View issue"},
+					URL:  issueURL,
+				},
+			},
+		})
+	}
+	return SlackMessage{
+		Text: text,
+		Attachments: []SlackAttachment{
+			{
+				Color:  slackSeverityColor(notification.Severity),
+				Blocks: blocks,
+			},
+		},
+	}
+}
+
+// BuildSlackDigestMessage builds a message grouping the findings by title, listing a few of the affected URLs
+func BuildSlackDigestMessage(notifications []IssueNotification) SlackMessage {
+	groups := make(map[string][]IssueNotification)
+	for _, notification := range notifications {
+		groups[notification.Title] = append(groups[notification.Title], notification)
+	}
+	titles := make([]string, 0, len(groups))
+	for title := range groups {
+		titles = append(titles, title)
+	}
+	sort.Slice(titles, func(i, j int) bool {
+		if len(groups[titles[i]]) != len(groups[titles[j]]) {
+			return len(groups[titles[i]]) > len(groups[titles[j]])
+		}
+		return titles[i] < titles[j]
+	})
+
+	summary := fmt.Sprintf("%d informational findings", len(notifications))
+	blocks := []SlackBlock{
+		{
+			Type: "header",
+			Text: &SlackText{Type: "plain_text", Text: summary},
+		},
+	}
+	for i, title := range titles {
+		if i == slackDigestMaxGroups {
+			blocks = append(blocks, SlackBlock{
+				Type: "section",
+				Text: slackMarkdown(fmt.Sprintf("_and %d more types of findings_", len(titles)-slackDigestMaxGroups)),
+			})
+			break
+		}
+		group := groups[title]
+		var text strings.Builder
+		fmt.Fprintf(&text, "*%s* (%d)", slackEscape(title), len(group))
+		for j, notification := range group {
+			if j == slackDigestMaxURLs {
+				fmt.Fprintf(&text, "\n_and %d more_", len(group)-slackDigestMaxURLs)
+				break
+			}
+			fmt.Fprintf(&text, "\n• %s", slackEscape(notification.URL))
+		}
+		blocks = append(blocks, SlackBlock{Type: "section", Text: slackMarkdown(text.String())})
+	}
+	return SlackMessage{
+		Text: summary,
+		Attachments: []SlackAttachment{
+			{
+				Color:  slackSeverityColor("info"),
+				Blocks: blocks,
+			},
+		},
+	}
+}
+
+// SlackNotifier posts new findings to a Slack incoming webhook. Informational findings are grouped and sent
+// periodically as a digest to reduce the noise.
+type SlackNotifier struct {
+	config  SlackConfig
+	webhook *Webhook
+	mu      sync.Mutex
+	pending []IssueNotification
+	timer   *time.Timer
+	// sending tracks the notifications being sent in the background
+	sending sync.WaitGroup
+}
+
+// NewSlackNotifier creates a Slack notifier
+func NewSlackNotifier(config SlackConfig) *SlackNotifier {
+	return &SlackNotifier{
+		config: config,
+		webhook: NewWebhook(WebhookConfig{
+			URL:        config.WebhookURL,
+			MaxRetries: config.MaxRetries,
+			RetryDelay: config.RetryDelay,
+			Timeout:    config.Timeout,
+		}),
+	}
+}
+
+// ShouldNotify reports whether an issue of the given severity reaches the configured minimum severity
+func (s *SlackNotifier) ShouldNotify(severity string) bool {
+	if s.config.MinSeverity == "" {
+		return true
+	}
+	return severityRank(severity) >= severityRank(s.config.MinSeverity)
+}
+
+// Notify sends the notification in the background, or queues it for the next digest when it is an informational
+// finding. Assignments are always sent right away.
+func (s *SlackNotifier) Notify(notification IssueNotification) {
+	if !s.ShouldNotify(notification.Severity) {
+		return
+	}
+	if s.config.DigestInterval > 0 && notification.Event != IssueAssignedEvent && severityRank(notification.Severity) <= severityRank("info") {
+		s.mu.Lock()
+		s.pending = append(s.pending, notification)
+		if s.timer == nil {
+			s.timer = time.AfterFunc(s.config.DigestInterval, func() {
+				if err := s.Flush(); err != nil {
+					log.Error().Err(err).Msg("Failed to send Slack digest")
+				}
+			})
+		}
+		s.mu.Unlock()
+		return
+	}
+	message := BuildSlackIssueMessage(notification, s.issueURL(notification.IssueID))
+	s.sending.Add(1)
+	go func() {
+		defer s.sending.Done()
+		if err := s.webhook.Send(context.Background(), notification.Event, message); err != nil {
+			log.Error().Err(err).Uint("issue", notification.IssueID).Str("code", notification.Code).Msg("Failed to send Slack notification")
+		}
+	}()
+}
+
+// Flush sends the digest of the pending informational findings and waits for the notifications being sent in the
+// background
+func (s *SlackNotifier) Flush() error {
+	defer s.sending.Wait()
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	log.Debug().Int("findings", len(pending)).Msg("Sending Slack digest")
+	return s.webhook.Send(context.Background(), IssueCreatedEvent, BuildSlackDigestMessage(pending))
+}
+
+func (s *SlackNotifier) issueURL(id uint) string {
+	if s.config.IssueURL == "" {
+		return ""
+	}
+	return strings.ReplaceAll(s.config.IssueURL, "{id}", strconv.FormatUint(uint64(id), 10))
+}
+
+var (
+	defaultSlackNotifier     *SlackNotifier
+	defaultSlackNotifierOnce sync.Once
+)
+
+// issueSlackNotifier returns the Slack notifier configured through the notifications.slack settings, or nil when no
+// webhook URL is set
+func issueSlackNotifier() *SlackNotifier {
+	defaultSlackNotifierOnce.Do(func() {
+		config := SlackConfigFromViper()
+		if config.WebhookURL == "" {
+			return
+		}
+		defaultSlackNotifier = NewSlackNotifier(config)
+		log.Info().Str("min_severity", config.MinSeverity).Dur("digest_interval", config.DigestInterval).Msg("Slack notifications enabled")
+	})
+	return defaultSlackNotifier
+}
@@ -0,0 +1,158 @@
+package notifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSlackIssueMessage(t *testing.T) {
+	notification := IssueNotification{
+		Event:       IssueCreatedEvent,
+		IssueID:     42,
+		Code:        "sql_injection",
+		Title:       "SQL Injection",
+		Severity:    "High",
+		URL:         "https://example.com/products?id=1&sort=<asc>",
+		WorkspaceID: 3,
+		ScanID:      7,
+	}
+	message := BuildSlackIssueMessage(notification, "https://sukyan.example.com/issues/42")
+
+	assert.Equal(t, "[High] SQL Injection found at https://example.com/products?id=1&sort=<asc>", message.Text)
+	assert.Len(t, message.Attachments, 1)
+	attachment := message.Attachments[0]
+	assert.Equal(t, "#e01e5a", attachment.Color)
+	assert.Len(t, attachment.Blocks, 4)
+
+	header := attachment.Blocks[0]
+	assert.Equal(t, "header", header.Type)
+	assert.Equal(t, SlackText{Type: "plain_text", Text: "SQL Injection"}, *header.Text)
+
+	fields := attachment.Blocks[1]
+	assert.Equal(t, "section", fields.Type)
+	assert.Equal(t, []SlackText{
+		{Type: "mrkdwn", Text: "*Severity*\nHigh"},
+		{Type: "mrkdwn", Text: "*Code*\nsql_injection"},
+		{Type: "mrkdwn", Text: "*Workspace*\n3"},
+		{Type: "mrkdwn", Text: "*Scan*\n7"},
+	}, fields.Fields)
+
+	url := attachment.Blocks[2]
+	assert.Equal(t, "section", url.Type)
+	assert.Equal(t, "*URL*\nhttps://example.com/products?id=1&amp;sort=&lt;asc&gt;", url.Text.Text)
+
+	actions := attachment.Blocks[3]
+	assert.Equal(t, "actions", actions.Type)
+	assert.Len(t, actions.Elements, 1)
+	assert.Equal(t, "button", actions.Elements[0].Type)
+	assert.Equal(t, "View issue", actions.Elements[0].Text.Text)
+	assert.Equal(t, "https://sukyan.example.com/issues/42", actions.Elements[0].URL)
+
+	withoutLink := BuildSlackIssueMessage(notification, "")
+	assert.Len(t, withoutLink.Attachments[0].Blocks, 3)
+}
+
+func TestBuildSlackIssueAssignedMessage(t *testing.T) {
+	notification := IssueNotification{
+		Event:      IssueAssignedEvent,
+		IssueID:    42,
+		Code:       "sql_injection",
+		Title:      "SQL Injection",
+		Severity:   "High",
+		URL:        "https://example.com/products?id=1",
+		AssignedTo: "analyst@example.com",
+	}
+	message := BuildSlackIssueMessage(notification, "")
+
+	assert.Equal(t, "[High] SQL Injection at https://example.com/products?id=1 assigned to analyst@example.com", message.Text)
+	blocks := message.Attachments[0].Blocks
+	assert.Len(t, blocks, 4)
+	assert.Equal(t, "*Assigned to*\nanalyst@example.com", blocks[3].Text.Text)
+}
+
+func TestBuildSlackDigestMessage(t *testing.T) {
+	message := BuildSlackDigestMessage([]IssueNotification{
+		{Title: "Server Header Disclosure", Severity: "Info", URL: "https://example.com/a"},
+		{Title: "Email Address Disclosed", Severity: "Info", URL: "https://example.com/contact"},
+		{Title: "Server Header Disclosure", Severity: "Info", URL: "https://example.com/b"},
+	})
+	assert.Equal(t, "3 informational findings", message.Text)
+	blocks := message.Attachments[0].Blocks
+	assert.Len(t, blocks, 3)
+	assert.Equal(t, "*Server Header Disclosure* (2)\n• https://example.com/a\n• https://example.com/b", blocks[1].Text.Text)
+	assert.Equal(t, "*Email Address Disclosed* (1)\n• https://example.com/contact", blocks[2].Text.Text)
+}
+
+func TestSlackNotifierDigestsInformationalFindings(t *testing.T) {
+	var mu sync.Mutex
+	var messages []SlackMessage
+	received := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var message SlackMessage
+		assert.Nil(t, json.Unmarshal(body, &message))
+		mu.Lock()
+		messages = append(messages, message)
+		mu.Unlock()
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	slack := NewSlackNotifier(SlackConfig{
+		WebhookURL:     server.URL,
+		MinSeverity:    "info",
+		IssueURL:       "https://sukyan.example.com/issues/{id}",
+		DigestInterval: time.Hour,
+	})
+	slack.Notify(IssueNotification{IssueID: 1, Title: "Server Header Disclosure", Severity: "Info", URL: "https://example.com/"})
+	slack.Notify(IssueNotification{IssueID: 2, Title: "Server Header Disclosure", Severity: "Info", URL: "https://example.com/login"})
+	slack.Notify(IssueNotification{IssueID: 3, Title: "SQL Injection", Severity: "High", URL: "https://example.com/?id=1"})
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the high severity finding was not sent")
+	}
+	mu.Lock()
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "https://sukyan.example.com/issues/3", messages[0].Attachments[0].Blocks[3].Elements[0].URL)
+	mu.Unlock()
+
+	assert.Nil(t, slack.Flush())
+	mu.Lock()
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "2 informational findings", messages[1].Text)
+	mu.Unlock()
+
+	assert.Nil(t, slack.Flush())
+	mu.Lock()
+	assert.Len(t, messages, 2)
+	mu.Unlock()
+}
+
+func TestSlackNotifierFlushWaitsForNotifications(t *testing.T) {
+	var mu sync.Mutex
+	delivered := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		delivered++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	slack := NewSlackNotifier(SlackConfig{WebhookURL: server.URL})
+	slack.Notify(IssueNotification{IssueID: 1, Title: "SQL Injection", Severity: "High", URL: "https://example.com/?id=1"})
+
+	assert.Nil(t, slack.Flush())
+	mu.Lock()
+	assert.Equal(t, 1, delivered)
+	mu.Unlock()
+}
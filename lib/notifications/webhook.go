@@ -0,0 +1,155 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	// SignatureHeader holds the HMAC-SHA256 signature of the body, computed with the webhook secret
+	SignatureHeader = "X-Sukyan-Signature"
+	// EventHeader holds the type of event being notified
+	EventHeader = "X-Sukyan-Event"
+)
+
+// WebhookConfig holds the configuration of an outbound webhook
+type WebhookConfig struct {
+	URL         string
+	Secret      string
+	MinSeverity string
+	MaxRetries  int
+	RetryDelay  time.Duration
+	Timeout     time.Duration
+}
+
+// WebhookConfigFromViper loads the webhook configuration from the notifications.webhook settings
+func WebhookConfigFromViper() WebhookConfig {
+	return WebhookConfig{
+		URL:         viper.GetString("notifications.webhook.url"),
+		Secret:      viper.GetString("notifications.webhook.secret"),
+		MinSeverity: viper.GetString("notifications.webhook.min_severity"),
+		MaxRetries:  viper.GetInt("notifications.webhook.max_retries"),
+		RetryDelay:  time.Duration(viper.GetInt("notifications.webhook.retry_delay")) * time.Millisecond,
+		Timeout:     time.Duration(viper.GetInt("notifications.webhook.timeout")) * time.Second,
+	}
+}
+
+// Webhook posts JSON notifications to a configured URL, retrying with exponential backoff when the delivery fails
+type Webhook struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhook creates a webhook notifier
+func NewWebhook(config WebhookConfig) *Webhook {
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &Webhook{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// ShouldNotify reports whether an issue of the given severity reaches the configured minimum severity
+func (w *Webhook) ShouldNotify(severity string) bool {
+	if w.config.MinSeverity == "" {
+		return true
+	}
+	return severityRank(severity) >= severityRank(w.config.MinSeverity)
+}
+
+// Send posts the payload as JSON, signing the body when a secret is configured. Network errors, rate limiting and
+// server errors are retried up to the configured number of retries.
+func (w *Webhook) Send(ctx context.Context, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	delay := w.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := w.deliver(ctx, event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.config.MaxRetries {
+			return err
+		}
+		log.Debug().Err(err).Int("attempt", attempt+1).Dur("delay", delay).Str("event", event).Msg("Webhook delivery failed, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// deliver makes a single delivery attempt, returning whether it can be retried when it fails
+func (w *Webhook) deliver(ctx context.Context, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Sukyan-Webhook")
+	req.Header.Set(EventHeader, event)
+	if w.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(body, w.config.Secret))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook responded with status code %d", resp.StatusCode)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// Sign returns the signature of a body in the sha256=<hex digest> format, so receivers can verify the notifications
+// have been sent by sukyan
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	defaultWebhook     *Webhook
+	defaultWebhookOnce sync.Once
+)
+
+// issueWebhook returns the webhook configured through the notifications.webhook settings, or nil when no URL is set
+func issueWebhook() *Webhook {
+	defaultWebhookOnce.Do(func() {
+		config := WebhookConfigFromViper()
+		if config.URL == "" {
+			return
+		}
+		defaultWebhook = NewWebhook(config)
+		log.Info().Str("url", config.URL).Str("min_severity", config.MinSeverity).Msg("Issue webhook notifications enabled")
+	})
+	return defaultWebhook
+}
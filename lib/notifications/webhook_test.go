@@ -0,0 +1,114 @@
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	var received IssueNotification
+	var signature, event string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		event = r.Header.Get(EventHeader)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Nil(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(WebhookConfig{URL: server.URL, Secret: "s3cret"})
+	notification := IssueNotification{
+		Event:       IssueCreatedEvent,
+		IssueID:     12,
+		Code:        "sql_injection",
+		Title:       "SQL Injection",
+		Severity:    "Critical",
+		URL:         "https://example.com/?id=1",
+		WorkspaceID: 3,
+		ScanID:      5,
+	}
+	err := webhook.Send(context.Background(), IssueCreatedEvent, notification)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, signature)
+	assert.Equal(t, IssueCreatedEvent, event)
+	assert.Equal(t, notification, received)
+}
+
+func TestWebhookSendRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(WebhookConfig{URL: server.URL, MaxRetries: 3, RetryDelay: time.Millisecond})
+	assert.Nil(t, webhook.Send(context.Background(), IssueCreatedEvent, IssueNotification{}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	atomic.StoreInt32(&attempts, 0)
+	webhook = NewWebhook(WebhookConfig{URL: server.URL, MaxRetries: 1, RetryDelay: time.Millisecond})
+	assert.NotNil(t, webhook.Send(context.Background(), IssueCreatedEvent, IssueNotification{}))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestWebhookSendDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(WebhookConfig{URL: server.URL, MaxRetries: 3, RetryDelay: time.Millisecond})
+	assert.NotNil(t, webhook.Send(context.Background(), IssueCreatedEvent, IssueNotification{}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestWebhookShouldNotify(t *testing.T) {
+	webhook := NewWebhook(WebhookConfig{MinSeverity: "high"})
+	assert.True(t, webhook.ShouldNotify("Critical"))
+	assert.True(t, webhook.ShouldNotify("High"))
+	assert.False(t, webhook.ShouldNotify("Medium"))
+	assert.False(t, webhook.ShouldNotify("Info"))
+	assert.False(t, webhook.ShouldNotify("Unknown"))
+
+	webhook = NewWebhook(WebhookConfig{})
+	assert.True(t, webhook.ShouldNotify("Info"))
+}
+
+func TestFlushWaitsForWebhookNotifications(t *testing.T) {
+	var delivered int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&delivered, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(WebhookConfig{URL: server.URL})
+	sendWebhookNotification(webhook, IssueNotification{Event: IssueCreatedEvent, IssueID: 1, Severity: "High"})
+	sendWebhookNotification(webhook, IssueNotification{Event: IssueCreatedEvent, IssueID: 2, Severity: "High"})
+
+	Flush()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&delivered))
+}
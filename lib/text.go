@@ -57,3 +57,15 @@ func ContainsAnySubstringIgnoreCase(original string, substrings []string) bool {
 	}
 	return false
 }
+
+// RedactSecret keeps the first and last characters of the secret, enough to identify it without disclosing it
+func RedactSecret(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	visible := 4
+	if len(secret) < 16 {
+		visible = 2
+	}
+	return secret[:visible] + strings.Repeat("*", min(len(secret)-visible-2, 32)) + secret[len(secret)-2:]
+}
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -204,3 +205,24 @@ func TestContainsAnySubstringIgnoreCase(t *testing.T) {
 		})
 	}
 }
+
+func TestRedactSecret(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{"secret", "******"},
+		{"abcdefghyz", "ab******yz"},
+		{"your-256-bit-secret", "your*************et"},
+		{"AKIAUJZDE8GXD6NCF10E", "AKIA**************0E"},
+	}
+	for _, test := range tests {
+		if got := RedactSecret(test.secret); got != test.want {
+			t.Errorf("RedactSecret(%q) = %q, want %q", test.secret, got, test.want)
+		}
+	}
+	// Long secrets are capped so the redacted value does not reveal the length
+	if got := RedactSecret(strings.Repeat("a", 2000)); len(got) != 38 {
+		t.Errorf("expected the redacted long secret to be capped to 38 characters, got %d", len(got))
+	}
+}
@@ -30,12 +30,14 @@ type AlertAudit struct {
 	TaskID                     uint
 	TaskJobID                  uint
 	SkipInitialAlertValidation bool
-	detectedLocations          sync.Map
+	// ClientOptions are the settings of the HTTP clients of the scan
+	ClientOptions     *http_utils.ClientOptions
+	detectedLocations sync.Map
 }
 
 func (x *AlertAudit) requestHasAlert(history *db.History, browserPool *browser.BrowserPoolManager) bool {
 	b := browserPool.NewBrowser()
-	page := b.MustPage("")
+	page := browserPool.NewPage(b, x.ClientOptions.ScanHeaders())
 	defer browserPool.ReleaseBrowser(b)
 
 	taskLog := log.With().Uint("history", history.ID).Str("method", history.Method).Str("task", "ensure no alert").Str("url", history.URL).Logger()
@@ -77,6 +79,7 @@ func (x *AlertAudit) requestHasAlert(history *db.History, browserPool *browser.B
 		PlaygroundSessionID: 0,
 		Note:                note,
 		Source:              db.SourceScanner,
+		ClientOptions:       x.ClientOptions,
 	})
 	if navigationErr != nil {
 		taskLog.Error().Msg("Navigation error")
@@ -116,13 +119,54 @@ func (x *AlertAudit) RunWithPayloads(history *db.History, insertionPoints []scan
 	taskLog.Info().Msg("Completed tests")
 }
 
+// RunWithInsertionPointPayloads runs the audit testing each insertion point only with the payloads returned for it, so
+// that payloads can be selected according to the insertion point behaviour
+func (x *AlertAudit) RunWithInsertionPointPayloads(history *db.History, insertionPoints []scan.InsertionPoint, getPayloads func(insertionPoint scan.InsertionPoint) []payloads.PayloadInterface, issueCode db.IssueCode) {
+	taskLog := log.With().Uint("history", history.ID).Str("method", history.Method).Str("url", history.URL).Str("audit", string(issueCode)).Logger()
+
+	// Group the insertion points by payload, so each payload is still tested with a single browser
+	var values []string
+	payloadInsertionPoints := make(map[string][]scan.InsertionPoint)
+	for _, insertionPoint := range insertionPoints {
+		for _, payload := range getPayloads(insertionPoint) {
+			value := payload.GetValue()
+			if _, ok := payloadInsertionPoints[value]; !ok {
+				values = append(values, value)
+			}
+			payloadInsertionPoints[value] = append(payloadInsertionPoints[value], insertionPoint)
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	p := pool.New().WithMaxGoroutines(3)
+	browserPool := browser.GetScannerBrowserPoolManager()
+
+	if x.requestHasAlert(history, browserPool) {
+		taskLog.Warn().Msg("Skipping XSS tests as the original request triggers an alert dialog")
+		return
+	}
+
+	taskLog.Info().Int("payloads", len(values)).Int("insertion_points", len(insertionPoints)).Msg("Starting tests with insertion point payloads")
+	for _, value := range values {
+		p.Go(func() {
+			x.testPayload(browserPool, history, payloadInsertionPoints[value], value, issueCode)
+			taskLog.Debug().Str("payload", value).Msg("Finished testing payload")
+		})
+	}
+
+	p.Wait()
+	taskLog.Info().Msg("Completed tests")
+}
+
 func (x *AlertAudit) testPayload(browserPool *browser.BrowserPoolManager, history *db.History, insertionPoints []scan.InsertionPoint, payload string, issueCode db.IssueCode) {
 	b := browserPool.NewBrowser()
 	log.Debug().Msg("Got scan browser from the pool")
 
 	hijackResultsChannel := make(chan browser.HijackResult)
 	hijackContext, hijackCancel := context.WithCancel(context.Background())
-	browser.HijackWithContext(browser.HijackConfig{AnalyzeJs: false, AnalyzeHTML: false}, b, db.SourceScanner, hijackResultsChannel, hijackContext, x.WorkspaceID, x.TaskID)
+	browser.HijackWithContext(browser.HijackConfig{AnalyzeJs: false, AnalyzeHTML: false, ClientOptions: x.ClientOptions}, b, db.SourceScanner, hijackResultsChannel, hijackContext, x.WorkspaceID, x.TaskID)
 	defer browserPool.ReleaseBrowser(b)
 	defer hijackCancel()
 	go func() {
@@ -227,7 +271,7 @@ func (x *AlertAudit) reportIssue(history *db.History, scanRequest *http.Request,
 	if string(history.RequestBody) != "" {
 		sb.WriteString("\n\nThe request body:\n```\n" + string(history.RequestBody) + "\n```\n")
 	}
-	db.CreateIssueFromHistoryAndTemplate(history, issueCode, sb.String(), 90, "", &x.WorkspaceID, &x.TaskID, &x.TaskJobID)
+	db.CreateIssueFromHistoryAndTemplateWithOptions(history, issueCode, sb.String(), 90, "", &x.WorkspaceID, &x.TaskID, &x.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
 }
 
 func (x *AlertAudit) testRequest(scanRequest *http.Request, insertionPoint scan.InsertionPoint, payload string, b *rod.Browser, issueCode db.IssueCode) error {
@@ -248,7 +292,7 @@ func (x *AlertAudit) testRequest(scanRequest *http.Request, insertionPoint scan.
 	taskLog := log.With().Str("method", scanRequest.Method).Str("url", testurl).Interface("insertionPoint", insertionPoint).Str("payload", payload).Str("audit", string(issueCode)).Logger()
 
 	taskLog.Debug().Msg("Getting a browser page")
-	page := b.MustPage("")
+	page := browser.GetScannerBrowserPoolManager().NewPage(b, x.ClientOptions.ScanHeaders())
 	web.IgnoreCertificateErrors(page)
 
 	taskLog.Debug().Msg("Browser page gathered")
@@ -303,6 +347,7 @@ func (x *AlertAudit) testRequest(scanRequest *http.Request, insertionPoint scan.
 		PlaygroundSessionID: 0,
 		Note:                note,
 		Source:              db.SourceScanner,
+		ClientOptions:       x.ClientOptions,
 	})
 	if navigationErr != nil {
 		taskLog.Error().Str("url", testurl).Msg("Navigation error")
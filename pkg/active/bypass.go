@@ -20,6 +20,8 @@ type ActiveModuleOptions struct {
 	TaskJobID   uint
 	Concurrency int
 	ScanMode    options.ScanMode
+	// ClientOptions are the settings of the HTTP clients of the scan
+	ClientOptions *http_utils.ClientOptions
 }
 
 type HeaderTest struct {
@@ -111,7 +113,7 @@ func ForbiddenBypassScan(history *db.History, options ActiveModuleOptions) {
 	if options.Concurrency == 0 {
 		options.Concurrency = 5
 	}
-	client := http_utils.CreateHttpClient()
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
 
 	p := pool.New().WithMaxGoroutines(options.Concurrency)
 
@@ -0,0 +1,195 @@
+package active
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// https://portswigger.net/web-security/cors
+
+type corsBypass string
+
+const (
+	corsBypassArbitraryOrigin corsBypass = "arbitrary origin"
+	corsBypassNullOrigin      corsBypass = "null origin"
+	corsBypassSubdomain       corsBypass = "subdomain"
+	corsBypassSuffix          corsBypass = "trusted domain as a subdomain of an attacker domain"
+	corsBypassPrefix          corsBypass = "attacker domain ending with the trusted domain"
+)
+
+// corsProbe is an Origin sent to check if it is allowed by the CORS policy
+type corsProbe struct {
+	Bypass corsBypass
+	Origin string
+}
+
+// buildCORSProbes returns the origins to probe the host with. Fast mode only checks arbitrary and null origins.
+func buildCORSProbes(targetURL string, mode scan_options.ScanMode) ([]corsProbe, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Hostname()
+	scheme := u.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	attacker := lib.GenerateRandomLowercaseString(10)
+	probes := []corsProbe{
+		{Bypass: corsBypassArbitraryOrigin, Origin: fmt.Sprintf("%s://%s.com", scheme, attacker)},
+		{Bypass: corsBypassNullOrigin, Origin: "null"},
+	}
+	if mode == scan_options.ScanModeFast {
+		return probes, nil
+	}
+	return append(probes,
+		corsProbe{Bypass: corsBypassSubdomain, Origin: fmt.Sprintf("%s://%s.%s", scheme, attacker, host)},
+		corsProbe{Bypass: corsBypassSuffix, Origin: fmt.Sprintf("%s://%s.%s.com", scheme, host, attacker)},
+		corsProbe{Bypass: corsBypassPrefix, Origin: fmt.Sprintf("%s://%s%s", scheme, attacker, host)},
+	), nil
+}
+
+// corsMisconfiguration is a probed origin allowed by the CORS policy
+type corsMisconfiguration struct {
+	Probe       corsProbe
+	Credentials bool
+	Severity    string
+}
+
+// classifyCORSResponse checks if the response allows the probed origin, rating the severity according to the origins
+// an attacker would need to control and whether credentialed requests are allowed, which gives access to the data of
+// authenticated users
+func classifyCORSResponse(probe corsProbe, headers http.Header) (corsMisconfiguration, bool) {
+	allowedOrigin := strings.TrimSpace(headers.Get("Access-Control-Allow-Origin"))
+	if allowedOrigin == "" || allowedOrigin != probe.Origin {
+		return corsMisconfiguration{}, false
+	}
+	credentials := strings.EqualFold(strings.TrimSpace(headers.Get("Access-Control-Allow-Credentials")), "true")
+	misconfiguration := corsMisconfiguration{Probe: probe, Credentials: credentials}
+	switch probe.Bypass {
+	case corsBypassArbitraryOrigin, corsBypassSuffix, corsBypassPrefix:
+		misconfiguration.Severity = "Low"
+		if credentials {
+			misconfiguration.Severity = "High"
+		}
+	case corsBypassNullOrigin:
+		// Sandboxed iframes and local files send the null origin, so any site can get it allowed
+		misconfiguration.Severity = "Low"
+		if credentials {
+			misconfiguration.Severity = "Medium"
+		}
+	case corsBypassSubdomain:
+		// Requires controlling a subdomain, through XSS or a subdomain takeover
+		misconfiguration.Severity = "Info"
+		if credentials {
+			misconfiguration.Severity = "Low"
+		}
+	}
+	return misconfiguration, true
+}
+
+// mostSevereCORSMisconfiguration returns the index of the misconfiguration with the highest severity
+func mostSevereCORSMisconfiguration(misconfigurations []corsMisconfiguration) int {
+	mostSevere := 0
+	for i, misconfiguration := range misconfigurations {
+		// Lower order means higher severity
+		if db.GetSeverityOrder(misconfiguration.Severity) < db.GetSeverityOrder(misconfigurations[mostSevere].Severity) {
+			mostSevere = i
+		}
+	}
+	return mostSevere
+}
+
+// CORSScan sends the request with different Origin headers, such as random, null or lookalike ones, and reports the
+// ones allowed by the Access-Control-Allow-Origin header together with the severity of the most permissive one
+func CORSScan(history *db.History, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "cors").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
+	}
+	probes, err := buildCORSProbes(history.URL, options.ScanMode)
+	if err != nil {
+		auditLog.Error().Err(err).Msg("Error building CORS probes")
+		return
+	}
+
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+	results := make([]*corsMisconfiguration, len(probes))
+	histories := make([]*db.History, len(probes))
+
+	for i, probe := range probes {
+		i, probe := i, probe
+		p.Go(func() {
+			request, err := http_utils.BuildRequestFromHistoryItem(history)
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating request")
+				return
+			}
+			request.Header.Set("Origin", probe.Origin)
+			response, err := http_utils.SendRequest(client, request)
+			if err != nil {
+				auditLog.Error().Err(err).Str("origin", probe.Origin).Msg("Error during request")
+				return
+			}
+			newHistory, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+				Source:              db.SourceScanner,
+				WorkspaceID:         options.WorkspaceID,
+				TaskID:              options.TaskID,
+				TaskJobID:           options.TaskJobID,
+				CreateNewBodyStream: false,
+			})
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating history from response")
+				return
+			}
+			if misconfiguration, found := classifyCORSResponse(probe, response.Header); found {
+				results[i] = &misconfiguration
+				histories[i] = newHistory
+			}
+		})
+	}
+	p.Wait()
+
+	var misconfigurations []corsMisconfiguration
+	var misconfigurationHistories []*db.History
+	for i, result := range results {
+		if result != nil {
+			misconfigurations = append(misconfigurations, *result)
+			misconfigurationHistories = append(misconfigurationHistories, histories[i])
+		}
+	}
+	if len(misconfigurations) == 0 {
+		auditLog.Info().Msg("CORS audit completed")
+		return
+	}
+
+	mostSevere := mostSevereCORSMisconfiguration(misconfigurations)
+	var sb strings.Builder
+	sb.WriteString("The CORS policy allows the following origins, which could be used by attackers to read the responses of the application from their own sites:\n")
+	for _, misconfiguration := range misconfigurations {
+		credentials := "without credentials"
+		if misconfiguration.Credentials {
+			credentials = "with credentials (Access-Control-Allow-Credentials: true)"
+		}
+		sb.WriteString(fmt.Sprintf("\n - %s: the origin `%s` is allowed %s", misconfiguration.Probe.Bypass, misconfiguration.Probe.Origin, credentials))
+	}
+	if misconfigurations[mostSevere].Credentials {
+		sb.WriteString("\n\nAs credentials are allowed, requests sent from these origins include the cookies of the user, so the data of authenticated users can be read.")
+	}
+	auditLog.Info().Str("bypass", string(misconfigurations[mostSevere].Probe.Bypass)).Str("severity", misconfigurations[mostSevere].Severity).Msg("CORS misconfiguration found")
+	db.CreateIssueFromHistoryAndTemplate(misconfigurationHistories[mostSevere], db.CorsCode, sb.String(), 90, misconfigurations[mostSevere].Severity, &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+	auditLog.Info().Msg("CORS audit completed")
+}
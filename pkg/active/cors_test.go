@@ -0,0 +1,80 @@
+package active
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildCORSProbes(t *testing.T) {
+	probes, err := buildCORSProbes("https://trusted.com/api/me", scan_options.ScanModeSmart)
+	assert.Nil(t, err)
+	if assert.Len(t, probes, 5) {
+		assert.Equal(t, corsBypassArbitraryOrigin, probes[0].Bypass)
+		assert.True(t, strings.HasPrefix(probes[0].Origin, "https://"))
+		assert.Equal(t, "null", probes[1].Origin)
+		assert.True(t, strings.HasSuffix(probes[2].Origin, ".trusted.com"))
+		assert.True(t, strings.HasPrefix(probes[3].Origin, "https://trusted.com."))
+		assert.True(t, strings.HasSuffix(probes[4].Origin, "trusted.com"))
+		assert.NotContains(t, probes[4].Origin, ".trusted.com")
+	}
+
+	probes, err = buildCORSProbes("http://trusted.com:8080/", scan_options.ScanModeFast)
+	assert.Nil(t, err)
+	assert.Len(t, probes, 2)
+	assert.True(t, strings.HasPrefix(probes[0].Origin, "http://"))
+}
+
+func TestClassifyCORSResponse(t *testing.T) {
+	arbitrary := corsProbe{Bypass: corsBypassArbitraryOrigin, Origin: "https://abcdefghij.com"}
+	null := corsProbe{Bypass: corsBypassNullOrigin, Origin: "null"}
+	subdomain := corsProbe{Bypass: corsBypassSubdomain, Origin: "https://abcdefghij.trusted.com"}
+	suffix := corsProbe{Bypass: corsBypassSuffix, Origin: "https://trusted.com.abcdefghij.com"}
+
+	tests := []struct {
+		name        string
+		probe       corsProbe
+		origin      string
+		credentials string
+		found       bool
+		severity    string
+	}{
+		{"reflected with credentials", arbitrary, arbitrary.Origin, "true", true, "High"},
+		{"reflected without credentials", arbitrary, arbitrary.Origin, "", true, "Low"},
+		{"null origin allowed with credentials", null, "null", "true", true, "Medium"},
+		{"null origin allowed without credentials", null, "null", "", true, "Low"},
+		{"suffix bypass with credentials", suffix, suffix.Origin, "True", true, "High"},
+		{"subdomain with credentials", subdomain, subdomain.Origin, "true", true, "Low"},
+		{"subdomain without credentials", subdomain, subdomain.Origin, "false", true, "Info"},
+		{"wildcard", arbitrary, "*", "true", false, ""},
+		{"fixed origin", arbitrary, "https://trusted.com", "true", false, ""},
+		{"no cors headers", arbitrary, "", "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.origin != "" {
+				headers.Set("Access-Control-Allow-Origin", tt.origin)
+			}
+			if tt.credentials != "" {
+				headers.Set("Access-Control-Allow-Credentials", tt.credentials)
+			}
+			misconfiguration, found := classifyCORSResponse(tt.probe, headers)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.severity, misconfiguration.Severity)
+		})
+	}
+}
+
+func TestMostSevereCORSMisconfiguration(t *testing.T) {
+	misconfigurations := []corsMisconfiguration{
+		{Probe: corsProbe{Bypass: corsBypassSubdomain}, Severity: "Info"},
+		{Probe: corsProbe{Bypass: corsBypassNullOrigin}, Severity: "Medium"},
+		{Probe: corsProbe{Bypass: corsBypassArbitraryOrigin}, Severity: "High"},
+		{Probe: corsProbe{Bypass: corsBypassSuffix}, Severity: "High"},
+	}
+	assert.Equal(t, 2, mostSevereCORSMisconfiguration(misconfigurations))
+}
@@ -1,12 +1,18 @@
 package active
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/go-rod/rod"
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/pkg/browser"
+	"github.com/pyneda/sukyan/pkg/http_utils"
 	"github.com/pyneda/sukyan/pkg/web"
 	"github.com/spf13/viper"
 
@@ -21,14 +27,46 @@ type ClientSidePrototypePollutionAudit struct {
 	WorkspaceID uint
 	TaskID      uint
 	TaskJobID   uint
+	// ClientOptions are the settings of the HTTP clients of the scan
+	ClientOptions *http_utils.ClientOptions
 }
 
 func (a *ClientSidePrototypePollutionAudit) Run() {
+	browserPool := browser.GetScannerBrowserPoolManager()
+	b := browserPool.NewBrowser()
+	defer browserPool.ReleaseBrowser(b)
+
+	hijackResultsChannel := make(chan browser.HijackResult)
+	hijackContext, hijackCancel := context.WithCancel(context.Background())
+	defer hijackCancel()
+	browser.HijackWithContext(browser.HijackConfig{AnalyzeJs: false, AnalyzeHTML: false, ClientOptions: a.ClientOptions}, b, db.SourceScanner, hijackResultsChannel, hijackContext, a.WorkspaceID, a.TaskID)
+	go func() {
+		for {
+			select {
+			case hijackResult, ok := <-hijackResultsChannel:
+				if !ok {
+					return
+				}
+				a.requests.Store(hijackResult.History.URL, hijackResult.History)
+			case <-hijackContext.Done():
+				return
+			}
+		}
+	}()
+
+	page := browserPool.NewPage(b, a.ClientOptions.ScanHeaders())
+	defer page.Close()
+	web.IgnoreCertificateErrors(page)
+
 	if strings.Contains(a.HistoryItem.URL, "?") {
-		a.evaluate("&")
+		if a.evaluate(page, "&") {
+			return
+		}
 	} else {
-		a.evaluate("?")
-		a.evaluate("#")
+		if a.evaluate(page, "?") {
+			return
+		}
+		a.evaluate(page, "#")
 	}
 }
 
@@ -40,33 +78,39 @@ func (a *ClientSidePrototypePollutionAudit) GetHistory(url string) *db.History {
 	return &db.History{}
 }
 
-func (a *ClientSidePrototypePollutionAudit) evaluate(quote string) {
-	payloads := [4]string{
-		"constructor%5Bprototype%5D%5Bsukyan%5D=reserved",
-		"__proto__.sukyan=reserved",
-		"constructor.prototype.sukyan=reserved",
-		"__proto__%5Bsukyan%5D=reserved",
-	}
-	timeout := 30 * time.Second
-	b, err := browser.NewBrowserWithTimeout(timeout)
-	if err != nil {
-		log.Warn().Err(err).Uint("history", a.HistoryItem.ID).Msg("Canceling client-side prototype pollution tests due to an error launching a new browser")
-		return
+// getClientSidePrototypePollutionPayloads returns the query string payloads which set the property of
+// Object.prototype when the page parses them into an object without filtering the polluting keys
+func getClientSidePrototypePollutionPayloads(property, value string) []string {
+	return []string{
+		fmt.Sprintf("constructor%%5Bprototype%%5D%%5B%s%%5D=%s", property, value),
+		fmt.Sprintf("__proto__.%s=%s", property, value),
+		fmt.Sprintf("constructor.prototype.%s=%s", property, value),
+		fmt.Sprintf("__proto__%%5B%s%%5D=%s", property, value),
 	}
-	defer b.Close()
+}
 
-	hijackResultsChannel := make(chan browser.HijackResult)
-	browser.Hijack(browser.HijackConfig{AnalyzeJs: false, AnalyzeHTML: false}, b, "Scanner", hijackResultsChannel, a.WorkspaceID, a.TaskID)
-	page := b.MustIncognito().MustPage("")
-	web.IgnoreCertificateErrors(page)
-	go func() {
-		for hijackResult := range hijackResultsChannel {
-			a.requests.Store(hijackResult.History.URL, hijackResult.History)
+// getClientSidePrototypePollutionCheckScript returns the script evaluated in the page to get the value of the property
+// in Object.prototype, which is an empty string when it has not been polluted
+func getClientSidePrototypePollutionCheckScript(property string) string {
+	encoded, _ := json.Marshal(property)
+	return fmt.Sprintf(`() => {
+		const property = %s;
+		if (!Object.prototype.hasOwnProperty(property)) {
+			return "";
 		}
-	}()
+		return String(Object.prototype[property]);
+	}`, encoded)
+}
+
+// evaluate navigates to the history item URL with the payloads appended after the separator, returning true when the
+// prototype pollution has been detected
+func (a *ClientSidePrototypePollutionAudit) evaluate(page *rod.Page, separator string) bool {
+	property := "sukyan" + lib.GenerateRandomLowercaseString(6)
+	value := lib.GenerateRandomLowercaseString(10)
+	checkScript := getClientSidePrototypePollutionCheckScript(property)
 
-	for _, payload := range payloads {
-		url := string(a.HistoryItem.URL) + string(quote) + string(payload)
+	for _, payload := range getClientSidePrototypePollutionPayloads(property, value) {
+		url := a.HistoryItem.URL + separator + payload
 		taskLog := log.With().Str("url", url).Str("audit", "client-side-prototype-pollution").Logger()
 		navigationTimeout := time.Duration(viper.GetInt("navigation.timeout"))
 		navigateError := page.Timeout(navigationTimeout * time.Second).Navigate(url)
@@ -79,19 +123,17 @@ func (a *ClientSidePrototypePollutionAudit) evaluate(quote string) {
 			taskLog.Warn().Err(err).Msg("Error waiting for page complete load")
 			// continue
 		}
-		res := page.MustEval(`() => {
-			function getWindowValue() {
-				return window.sukyan;
-			}
-			return getWindowValue();
-		}`).Str()
-		var sb strings.Builder
-		if res == "reserved" {
-			taskLog.Debug().Msg("Client side prototype pollution detected, trying to find a known gadget")
-		} else {
+		result, err := page.Eval(checkScript)
+		if err != nil {
+			taskLog.Warn().Err(err).Msg("Error checking if the prototype has been polluted")
 			continue
 		}
-		sb.WriteString("The following payload has been inserted " + payload + " and it has been validated that the prototype has been polluted by checking that `window.sukyan` has the value `reflected`\n\n")
+		if result.Value.Str() != value {
+			continue
+		}
+		taskLog.Debug().Msg("Client side prototype pollution detected, trying to find a known gadget")
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("The following payload has been inserted %s and it has been validated that the prototype has been polluted by checking that `Object.prototype.%s` has the value `%s`\n\n", payload, property, value))
 		severity := ""
 		history := a.GetHistory(url)
 		log.Info().Str("url", history.URL).Int("status_code", history.StatusCode).Str("method", history.Method).Msg("History for prototype pollution item")
@@ -116,9 +158,9 @@ func (a *ClientSidePrototypePollutionAudit) evaluate(quote string) {
 		}
 		db.CreateIssueFromHistoryAndTemplate(history, db.ClientSidePrototypePollutionCode, sb.String(), 90, severity, &a.WorkspaceID, history.TaskID, &a.TaskJobID)
 		// Issue detected, stop checking
-		return
+		return true
 	}
-
+	return false
 }
 
 type KnownGadget struct {
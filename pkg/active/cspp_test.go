@@ -0,0 +1,30 @@
+package active
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClientSidePrototypePollutionPayloads(t *testing.T) {
+	payloads := getClientSidePrototypePollutionPayloads("sukyanabc", "xyz")
+	assert.Equal(t, []string{
+		"constructor%5Bprototype%5D%5Bsukyanabc%5D=xyz",
+		"__proto__.sukyanabc=xyz",
+		"constructor.prototype.sukyanabc=xyz",
+		"__proto__%5Bsukyanabc%5D=xyz",
+	}, payloads)
+}
+
+func TestGetClientSidePrototypePollutionCheckScript(t *testing.T) {
+	script := getClientSidePrototypePollutionCheckScript("sukyanabc")
+	assert.True(t, strings.HasPrefix(script, "() => {"))
+	assert.Contains(t, script, `const property = "sukyanabc";`)
+	assert.Contains(t, script, "Object.prototype.hasOwnProperty(property)")
+	assert.Contains(t, script, "String(Object.prototype[property])")
+
+	// The property is encoded, so it cannot break out of the string
+	script = getClientSidePrototypePollutionCheckScript(`a";alert(1);"`)
+	assert.Contains(t, script, `const property = "a\";alert(1);\"";`)
+}
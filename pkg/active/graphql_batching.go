@@ -0,0 +1,144 @@
+package active
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const graphQLBatchAliasPrefix = "sukyan"
+
+// GraphQLBatchingResult holds the largest batches processed by a GraphQL endpoint
+type GraphQLBatchingResult struct {
+	ArrayBatchSize int
+	AliasBatchSize int
+}
+
+// GraphQLBatchingScan checks if the GraphQL endpoint processes multiple operations sent in a single request, either as
+// an array of queries or as aliases of the same field, reporting the largest batch size which succeeded. The batch size
+// is capped by the scan.graphql.max_batch setting.
+func GraphQLBatchingScan(ctx context.Context, endpoint string, headers map[string][]string, client *http.Client, options ActiveModuleOptions) (GraphQLBatchingResult, error) {
+	auditLog := log.With().Str("audit", "graphql-batching").Str("url", endpoint).Uint("workspace", options.WorkspaceID).Logger()
+	result := GraphQLBatchingResult{}
+	if client == nil {
+		client = http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	}
+	maxBatch := viper.GetInt("scan.graphql.max_batch")
+	if maxBatch < 2 {
+		maxBatch = 2
+	}
+	batchSizes := []int{2}
+	if maxBatch > 2 {
+		batchSizes = append(batchSizes, maxBatch)
+	}
+
+	var arrayHistory, aliasHistory *db.History
+	for _, size := range batchSizes {
+		if ctx.Err() != nil {
+			auditLog.Info().Msg("Context cancelled, stopping GraphQL batching audit")
+			return result, ctx.Err()
+		}
+		history, err := sendGraphQLRequest(ctx, client, endpoint, headers, BuildGraphQLArrayBatch(size), options)
+		if err != nil {
+			auditLog.Error().Err(err).Int("size", size).Msg("Failed to send array batched query")
+			break
+		}
+		if CountGraphQLArrayBatchResults(history.ResponseBody) < size {
+			break
+		}
+		result.ArrayBatchSize = size
+		arrayHistory = history
+	}
+
+	for _, size := range batchSizes {
+		if ctx.Err() != nil {
+			auditLog.Info().Msg("Context cancelled, stopping GraphQL batching audit")
+			return result, ctx.Err()
+		}
+		body, _ := json.Marshal(map[string]string{"query": BuildGraphQLAliasBatch(size)})
+		history, err := sendGraphQLRequest(ctx, client, endpoint, headers, body, options)
+		if err != nil {
+			auditLog.Error().Err(err).Int("size", size).Msg("Failed to send alias batched query")
+			break
+		}
+		if CountGraphQLAliasBatchResults(history.ResponseBody) < size {
+			break
+		}
+		result.AliasBatchSize = size
+		aliasHistory = history
+	}
+
+	if arrayHistory != nil {
+		details := fmt.Sprintf("The endpoint processed an array of %d queries sent in a single request, returning a result for each of them.", result.ArrayBatchSize)
+		if result.AliasBatchSize > 0 {
+			details += fmt.Sprintf("\n\nIt also processed a single query containing %d aliases of the same field.", result.AliasBatchSize)
+		}
+		db.CreateIssueFromHistoryAndTemplate(arrayHistory, db.GraphqlBatchingEnabledCode, details, 90, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+	} else if aliasHistory != nil {
+		details := fmt.Sprintf("Array based batching is not supported, but the endpoint processed a single query containing %d aliases of the same field, which can be abused in the same way.", result.AliasBatchSize)
+		db.CreateIssueFromHistoryAndTemplate(aliasHistory, db.GraphqlBatchingEnabledCode, details, 80, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+	}
+
+	auditLog.Info().Int("array_batch_size", result.ArrayBatchSize).Int("alias_batch_size", result.AliasBatchSize).Msg("Completed GraphQL batching audit")
+	return result, nil
+}
+
+// BuildGraphQLArrayBatch returns a JSON array body containing size identical aliased queries
+func BuildGraphQLArrayBatch(size int) []byte {
+	batch := make([]map[string]string, size)
+	for i := range batch {
+		batch[i] = map[string]string{"query": fmt.Sprintf("query { %s: __typename }", graphQLBatchAliasPrefix)}
+	}
+	body, _ := json.Marshal(batch)
+	return body
+}
+
+// BuildGraphQLAliasBatch returns a single query requesting the same field size times through aliases
+func BuildGraphQLAliasBatch(size int) string {
+	aliases := make([]string, size)
+	for i := range aliases {
+		aliases[i] = fmt.Sprintf("%s%d: __typename", graphQLBatchAliasPrefix, i)
+	}
+	return fmt.Sprintf("query { %s }", strings.Join(aliases, " "))
+}
+
+// CountGraphQLArrayBatchResults returns the number of successful results in an array batched response
+func CountGraphQLArrayBatchResults(body []byte) int {
+	var responses []struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &responses); err != nil {
+		return 0
+	}
+	count := 0
+	for _, response := range responses {
+		if _, ok := response.Data[graphQLBatchAliasPrefix]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// CountGraphQLAliasBatchResults returns the number of aliases resolved in an alias batched response
+func CountGraphQLAliasBatchResults(body []byte) int {
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0
+	}
+	count := 0
+	for key := range response.Data {
+		if strings.HasPrefix(key, graphQLBatchAliasPrefix) {
+			count++
+		}
+	}
+	return count
+}
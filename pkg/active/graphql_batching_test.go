@@ -0,0 +1,31 @@
+package active
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildGraphQLBatches(t *testing.T) {
+	var batch []map[string]string
+	assert.Nil(t, json.Unmarshal(BuildGraphQLArrayBatch(3), &batch))
+	assert.Len(t, batch, 3)
+	for _, query := range batch {
+		assert.Equal(t, "query { sukyan: __typename }", query["query"])
+	}
+
+	assert.Equal(t, "query { sukyan0: __typename sukyan1: __typename }", BuildGraphQLAliasBatch(2))
+}
+
+func TestCountGraphQLBatchResults(t *testing.T) {
+	arrayResponse := []byte(`[{"data": {"sukyan": "Query"}}, {"data": {"sukyan": "Query"}}, {"data": {"sukyan": "Query"}}]`)
+	assert.Equal(t, 3, CountGraphQLArrayBatchResults(arrayResponse))
+
+	rejected := []byte(`{"errors": [{"message": "Batched queries are not allowed"}]}`)
+	assert.Equal(t, 0, CountGraphQLArrayBatchResults(rejected))
+	assert.Equal(t, 0, CountGraphQLAliasBatchResults(rejected))
+
+	aliasResponse := []byte(`{"data": {"sukyan0": "Query", "sukyan1": "Query"}}`)
+	assert.Equal(t, 2, CountGraphQLAliasBatchResults(aliasResponse))
+}
@@ -0,0 +1,170 @@
+package active
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+const graphQLIntrospectionQuery = `query IntrospectionQuery { __schema { queryType { name } mutationType { name } types { name kind fields { name } } } }`
+
+// Misspelled field names which GraphQL servers with suggestions enabled usually correct to existing fields
+var graphQLMisspelledFields = []string{"__typenam", "usr", "usrs", "acount", "produt", "ordr", "sesion", "tokn", "confg", "admn"}
+
+var graphQLSuggestionRegex = regexp.MustCompile(`(?i)did you mean (.+?)\?`)
+var graphQLQuotedNameRegex = regexp.MustCompile(`["'\x60]([_A-Za-z][_0-9A-Za-z]*)["'\x60]`)
+
+// GraphQLIntrospectionResult holds what a GraphQL endpoint discloses about its schema
+type GraphQLIntrospectionResult struct {
+	IntrospectionEnabled bool
+	SuggestionsEnabled   bool
+	SuggestedFields      []string
+}
+
+// Status returns a short description of the schema disclosure of the endpoint
+func (r GraphQLIntrospectionResult) Status() string {
+	switch {
+	case r.IntrospectionEnabled && r.SuggestionsEnabled:
+		return "introspection and field suggestions enabled"
+	case r.IntrospectionEnabled:
+		return "introspection enabled"
+	case r.SuggestionsEnabled:
+		return "introspection disabled but field suggestions leak schema information"
+	default:
+		return "introspection and field suggestions disabled"
+	}
+}
+
+// GraphQLIntrospectionScan sends an introspection query to the GraphQL endpoint and, if it is blocked, checks whether
+// field suggestions in error messages still leak schema information
+func GraphQLIntrospectionScan(endpoint string, headers map[string][]string, client *http.Client, options ActiveModuleOptions) (GraphQLIntrospectionResult, error) {
+	auditLog := log.With().Str("audit", "graphql-introspection").Str("url", endpoint).Uint("workspace", options.WorkspaceID).Logger()
+	result := GraphQLIntrospectionResult{}
+	if client == nil {
+		client = http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	}
+
+	introspectionHistory, err := sendGraphQLQuery(client, endpoint, headers, graphQLIntrospectionQuery, options)
+	if err != nil {
+		auditLog.Error().Err(err).Msg("Failed to send introspection query")
+		return result, err
+	}
+	if IsGraphQLIntrospectionResponse(introspectionHistory.ResponseBody) {
+		result.IntrospectionEnabled = true
+		details := fmt.Sprintf("A full introspection query sent to %s returned the schema of the API.", endpoint)
+		db.CreateIssueFromHistoryAndTemplate(introspectionHistory, db.GraphqlIntrospectionEnabledCode, details, 95, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+	}
+
+	suggestionQuery := fmt.Sprintf("query { %s }", strings.Join(graphQLMisspelledFields, " "))
+	suggestionHistory, err := sendGraphQLQuery(client, endpoint, headers, suggestionQuery, options)
+	if err != nil {
+		auditLog.Error().Err(err).Msg("Failed to send field suggestions query")
+	} else if suggestions := ParseGraphQLFieldSuggestions(suggestionHistory.ResponseBody); len(suggestions) > 0 {
+		result.SuggestionsEnabled = true
+		result.SuggestedFields = suggestions
+		// Only worth reporting when introspection is blocked, as otherwise the schema is already disclosed
+		if !result.IntrospectionEnabled {
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Introspection is disabled on %s, but querying misspelled fields returned suggestions disclosing the following schema fields:\n", endpoint))
+			for _, field := range suggestions {
+				sb.WriteString(fmt.Sprintf("\n - %s", field))
+			}
+			confidence := 90
+			if len(suggestions) == 1 && suggestions[0] == "__typename" {
+				confidence = 70
+			}
+			db.CreateIssueFromHistoryAndTemplate(suggestionHistory, db.GraphqlFieldSuggestionsCode, sb.String(), confidence, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+		}
+	}
+
+	auditLog.Info().Str("status", result.Status()).Strs("suggested_fields", result.SuggestedFields).Msg("Completed GraphQL introspection audit")
+	return result, nil
+}
+
+func sendGraphQLQuery(client *http.Client, endpoint string, headers map[string][]string, query string, options ActiveModuleOptions) (*db.History, error) {
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return nil, err
+	}
+	return sendGraphQLRequest(context.Background(), client, endpoint, headers, body, options)
+}
+
+// sendGraphQLRequest sends the provided JSON body to the GraphQL endpoint and stores the response
+func sendGraphQLRequest(ctx context.Context, client *http.Client, endpoint string, headers map[string][]string, body []byte, options ActiveModuleOptions) (*db.History, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := http_utils.SendRequest(client, request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	})
+}
+
+type graphQLResponse struct {
+	Data *struct {
+		Schema *struct {
+			Types []struct {
+				Name string `json:"name"`
+			} `json:"types"`
+		} `json:"__schema"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// IsGraphQLIntrospectionResponse checks if the response body contains the schema returned by an introspection query
+func IsGraphQLIntrospectionResponse(body []byte) bool {
+	var response graphQLResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+	return response.Data != nil && response.Data.Schema != nil && len(response.Data.Schema.Types) > 0
+}
+
+// ParseGraphQLFieldSuggestions extracts the field names suggested in the error messages of a GraphQL response
+func ParseGraphQLFieldSuggestions(body []byte) []string {
+	var response graphQLResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, e := range response.Errors {
+		for _, suggestion := range graphQLSuggestionRegex.FindAllStringSubmatch(e.Message, -1) {
+			for _, name := range graphQLQuotedNameRegex.FindAllStringSubmatch(suggestion[1], -1) {
+				seen[name[1]] = true
+			}
+		}
+	}
+	suggestions := make([]string, 0, len(seen))
+	for name := range seen {
+		suggestions = append(suggestions, name)
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
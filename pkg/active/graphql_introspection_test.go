@@ -0,0 +1,33 @@
+package active
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGraphQLFieldSuggestions(t *testing.T) {
+	body := []byte(`{"errors": [
+		{"message": "Cannot query field \"usr\" on type \"Query\". Did you mean \"user\" or \"users\"?"},
+		{"message": "Cannot query field \"acount\" on type \"Query\". Did you mean \"account\"?"},
+		{"message": "Cannot query field \"__typenam\" on type \"Query\". Did you mean \"__typename\"?"},
+		{"message": "Cannot query field \"produt\" on type \"Query\"."}
+	]}`)
+	assert.Equal(t, []string{"__typename", "account", "user", "users"}, ParseGraphQLFieldSuggestions(body))
+
+	disabled := []byte(`{"errors": [{"message": "Cannot query field \"usr\" on type \"Query\"."}]}`)
+	assert.Empty(t, ParseGraphQLFieldSuggestions(disabled))
+	assert.Empty(t, ParseGraphQLFieldSuggestions([]byte("<html>Not found</html>")))
+}
+
+func TestIsGraphQLIntrospectionResponse(t *testing.T) {
+	assert.True(t, IsGraphQLIntrospectionResponse([]byte(`{"data": {"__schema": {"queryType": {"name": "Query"}, "types": [{"name": "Query", "kind": "OBJECT"}]}}}`)))
+	assert.False(t, IsGraphQLIntrospectionResponse([]byte(`{"errors": [{"message": "GraphQL introspection is not allowed"}]}`)))
+	assert.False(t, IsGraphQLIntrospectionResponse([]byte(`{"data": null}`)))
+}
+
+func TestGraphQLIntrospectionResultStatus(t *testing.T) {
+	assert.Equal(t, "introspection and field suggestions disabled", GraphQLIntrospectionResult{}.Status())
+	assert.Equal(t, "introspection disabled but field suggestions leak schema information", GraphQLIntrospectionResult{SuggestionsEnabled: true}.Status())
+	assert.Equal(t, "introspection enabled", GraphQLIntrospectionResult{IntrospectionEnabled: true}.Status())
+}
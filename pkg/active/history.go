@@ -8,174 +8,382 @@ import (
 	"github.com/pyneda/sukyan/pkg/payloads/generation"
 	"github.com/pyneda/sukyan/pkg/scan"
 	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/pyneda/sukyan/pkg/scope"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
 const historyItemModulesConcurrency = 10
 
-func ScanHistoryItem(item *db.History, interactionsManager *integrations.InteractionsManager, payloadGenerators []*generation.PayloadGenerator, options scan_options.HistoryItemScanOptions) {
+// ScanHistoryItem runs the active modules against the history item, sending the requests through HTTP clients created
+// with the provided client options, which hold the settings of the scan the item belongs to
+func ScanHistoryItem(item *db.History, interactionsManager *integrations.InteractionsManager, payloadGenerators []*generation.PayloadGenerator, options scan_options.HistoryItemScanOptions, clientOptions *http_utils.ClientOptions) {
 	taskLog := log.With().Uint("workspace", options.WorkspaceID).Str("mode", options.Mode.String()).Str("item", item.URL).Str("method", item.Method).Int("ID", int(item.ID)).Logger()
+	exclusions, err := scope.NewURLExclusions(options.ExcludeURLs)
+	if err != nil {
+		taskLog.Error().Err(err).Msg("Invalid URL exclusions, skipping history item scan")
+		return
+	}
+	if pattern, excluded := exclusions.Match(item.URL); excluded {
+		taskLog.Info().Str("pattern", pattern).Msg("Skipping history item scan as the URL has been excluded")
+		return
+	}
+	scopeRules, err := options.Scope.Compile()
+	if err != nil {
+		taskLog.Error().Err(err).Msg("Invalid scope rules, skipping history item scan")
+		return
+	}
+	if !scopeRules.InScope(item.URL, item.Method) {
+		taskLog.Info().Msg("Skipping history item scan as it is out of scope")
+		return
+	}
 	taskLog.Info().Msg("Starting to scan history item")
 
 	activeOptions := ActiveModuleOptions{
-		Concurrency: historyItemModulesConcurrency,
-		WorkspaceID: options.WorkspaceID,
-		TaskID:      options.TaskID,
-		TaskJobID:   options.TaskJobID,
-		ScanMode:    options.Mode,
+		Concurrency:   historyItemModulesConcurrency,
+		WorkspaceID:   options.WorkspaceID,
+		TaskID:        options.TaskID,
+		TaskJobID:     options.TaskJobID,
+		ScanMode:      options.Mode,
+		ClientOptions: clientOptions,
 	}
-	historyCreateOptions := http_utils.HistoryCreationOptions{
-		Source:              db.SourceScanner,
-		WorkspaceID:         options.WorkspaceID,
-		TaskID:              options.TaskID,
-		CreateNewBodyStream: true,
-		TaskJobID:           options.TaskJobID,
+	ctx := &HistoryItemModuleContext{
+		Item:                item,
+		InteractionsManager: interactionsManager,
+		PayloadGenerators:   payloadGenerators,
+		Options:             options,
+		ActiveOptions:       activeOptions,
 	}
-	if item.StatusCode == 401 || item.StatusCode == 403 {
-		ForbiddenBypassScan(item, activeOptions)
+	if err := RunHistoryItemModules(historyItemModules(), ctx); err != nil {
+		taskLog.Error().Err(err).Msg("Could not run history item modules")
 	}
 
-	insertionPoints, err := scan.GetAndAnalyzeInsertionPoints(item, options.InsertionPoints, scan.InsertionPointAnalysisOptions{HistoryCreateOptions: historyCreateOptions})
-	taskLog.Debug().Interface("insertionPoints", insertionPoints).Msg("Insertion points")
-	if err != nil {
-		taskLog.Error().Err(err).Msg("Could not get insertion points")
+	log.Info().Str("item", item.URL).Str("method", item.Method).Int("ID", int(item.ID)).Msg("Finished scanning history item")
+}
+
+const (
+	insertionPointsContextKey      = "insertion_points"
+	auditInsertionPointsContextKey = "audit_insertion_points"
+	xssInsertionPointsContextKey   = "xss_insertion_points"
+)
+
+// getContextInsertionPoints returns the insertion points stored in the context under the provided key
+func getContextInsertionPoints(ctx *HistoryItemModuleContext, key string) []scan.InsertionPoint {
+	value, ok := ctx.Get(key)
+	if !ok {
+		return nil
 	}
+	return value.([]scan.InsertionPoint)
+}
 
-	if len(insertionPoints) > 0 {
-		var insertionPointsToAudit []scan.InsertionPoint
-		var xssInsertionPoints []scan.InsertionPoint
-		switch options.Mode {
-		case scan_options.ScanModeSmart:
-			for _, insertionPoint := range insertionPoints {
-				if insertionPoint.Behaviour.IsDynamic || insertionPoint.Behaviour.IsReflected || insertionPoint.Type == scan.InsertionPointTypeBody || insertionPoint.Type == scan.InsertionPointTypeParameter {
-					insertionPointsToAudit = append(insertionPointsToAudit, insertionPoint)
-					xssInsertionPoints = append(xssInsertionPoints, insertionPoint)
-				} else {
-					taskLog.Debug().Str("insertionPoint", insertionPoint.Name).Msg("Skipping insertion point")
-				}
-			}
-		case scan_options.ScanModeFast:
-			for _, insertionPoint := range insertionPoints {
-				if insertionPoint.Behaviour.IsDynamic || insertionPoint.Behaviour.IsReflected {
-					insertionPointsToAudit = append(insertionPointsToAudit, insertionPoint)
-					xssInsertionPoints = append(xssInsertionPoints, insertionPoint)
-				} else {
-					taskLog.Debug().Str("insertionPoint", insertionPoint.Name).Msg("Skipping insertion point")
-				}
-			}
+func serverSideEnabled(ctx *HistoryItemModuleContext) bool {
+	return ctx.Options.AuditCategories.ServerSide
+}
 
-		case scan_options.ScanModeFuzz:
-			insertionPointsToAudit = insertionPoints
-			xssInsertionPoints = insertionPoints
-		}
+func clientSideEnabled(ctx *HistoryItemModuleContext) bool {
+	return ctx.Options.AuditCategories.ClientSide
+}
 
-		if options.AuditCategories.ServerSide {
-			scanner := scan.TemplateScanner{
-				Concurrency:         historyItemModulesConcurrency,
-				InteractionsManager: interactionsManager,
-				AvoidRepeatedIssues: viper.GetBool("scan.avoid_repeated_issues"),
-				WorkspaceID:         options.WorkspaceID,
-				Mode:                options.Mode,
-			}
-			scanner.Run(item, payloadGenerators, insertionPointsToAudit, options)
-		}
+// historyItemModules returns the modules run against every history item. The insertion points analysis is shared by
+// the modules that depend on it, so reflection and behaviour details are only gathered once.
+func historyItemModules() []HistoryItemModule {
+	return []HistoryItemModule{
+		{
+			Name: "forbidden-bypass",
+			Enabled: func(ctx *HistoryItemModuleContext) bool {
+				return ctx.Item.StatusCode == 401 || ctx.Item.StatusCode == 403
+			},
+			Run: func(ctx *HistoryItemModuleContext) {
+				ForbiddenBypassScan(ctx.Item, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name: "insertion-points",
+			Run:  analyzeInsertionPointsModule,
+		},
+		{
+			Name:      "server-side-templates",
+			DependsOn: []string{"insertion-points"},
+			Enabled:   serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, auditInsertionPointsContextKey)
+				if len(insertionPoints) == 0 {
+					return
+				}
+				scanner := scan.TemplateScanner{
+					Concurrency:         historyItemModulesConcurrency,
+					InteractionsManager: ctx.InteractionsManager,
+					AvoidRepeatedIssues: viper.GetBool("scan.avoid_repeated_issues"),
+					WorkspaceID:         ctx.Options.WorkspaceID,
+					Mode:                ctx.Options.Mode,
+					ClientOptions:       ctx.ActiveOptions.ClientOptions,
+				}
+				scanner.Run(ctx.Item, ctx.PayloadGenerators, insertionPoints, ctx.Options)
+			},
+		},
+		{
+			Name:      "ssti",
+			DependsOn: []string{"insertion-points"},
+			Enabled:   serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, auditInsertionPointsContextKey)
+				if len(insertionPoints) == 0 {
+					return
+				}
+				SSTIScan(ctx.Item, insertionPoints, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:      "sqli-boolean",
+			DependsOn: []string{"insertion-points"},
+			Enabled:   serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, auditInsertionPointsContextKey)
+				if len(insertionPoints) == 0 {
+					return
+				}
+				BooleanSQLInjectionScan(ctx.Item, insertionPoints, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:      "nosql-injection",
+			DependsOn: []string{"insertion-points"},
+			Enabled:   serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, auditInsertionPointsContextKey)
+				if len(insertionPoints) == 0 {
+					return
+				}
+				NoSQLInjectionScan(ctx.Item, insertionPoints, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:      "ssrf",
+			DependsOn: []string{"insertion-points"},
+			Enabled:   serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, auditInsertionPointsContextKey)
+				SSRFScan(ctx.Item, ctx.InteractionsManager, insertionPoints, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name: "xxe",
+			Enabled: func(ctx *HistoryItemModuleContext) bool {
+				return ctx.Options.AuditCategories.ServerSide && isXMLRequest(ctx.Item)
+			},
+			Run: func(ctx *HistoryItemModuleContext) {
+				XXEScan(ctx.Item, ctx.InteractionsManager, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name: "server-side-prototype-pollution",
+			Enabled: func(ctx *HistoryItemModuleContext) bool {
+				return ctx.Options.AuditCategories.ServerSide && isJSONObjectRequest(ctx.Item)
+			},
+			Run: func(ctx *HistoryItemModuleContext) {
+				ServerSidePrototypePollutionScan(ctx.Item, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:      "client-side",
+			DependsOn: []string{"insertion-points", "server-side-templates"},
+			Enabled:   clientSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, xssInsertionPointsContextKey)
+				if len(insertionPoints) == 0 {
+					return
+				}
+				alert := AlertAudit{
+					WorkspaceID:                ctx.Options.WorkspaceID,
+					TaskID:                     ctx.Options.TaskID,
+					TaskJobID:                  ctx.Options.TaskJobID,
+					SkipInitialAlertValidation: false,
+					ClientOptions:              ctx.ActiveOptions.ClientOptions,
+				}
+				log.Info().Str("item", ctx.Item.URL).Msg("Starting client side audits")
 
-		// reflectedIssues := issues[db.ReflectedInputCode.String()]
-		// if len(issues) == 0 {
-		// 	taskLog.Info().Int("issues", len(issues)).Msg("Issues detected using template scanner, proceeding to client side audits")
-		// }
-		// taskLog.Info().Int("reflected_input_issues", len(reflectedIssues)).Msg("Input returned in response issues detected, proceeding to client side audits")
-		if options.AuditCategories.ClientSide {
-			alert := AlertAudit{
-				WorkspaceID:                options.WorkspaceID,
-				TaskID:                     options.TaskID,
-				TaskJobID:                  options.TaskJobID,
-				SkipInitialAlertValidation: false,
-			}
-			taskLog.Info().Msg("Starting client side audits")
-
-			xssPayloads := payloads.GetXSSPayloads()
-			alert.RunWithPayloads(item, xssInsertionPoints, xssPayloads, db.XssReflectedCode)
-
-			cstiPayloads := payloads.GetCSTIPayloads()
-			alert.RunWithPayloads(item, xssInsertionPoints, cstiPayloads, db.CstiCode)
-			taskLog.Info().Msg("Completed client side audits")
+				alert.RunWithInsertionPointPayloads(ctx.Item, insertionPoints, func(insertionPoint scan.InsertionPoint) []payloads.PayloadInterface {
+					return GetXSSPayloadsForReflectionContexts(insertionPoint.Behaviour.ReflectionContexts)
+				}, db.XssReflectedCode)
 
-		}
+				cstiPayloads := payloads.GetCSTIPayloads()
+				alert.RunWithPayloads(ctx.Item, insertionPoints, cstiPayloads, db.CstiCode)
+				log.Info().Str("item", ctx.Item.URL).Msg("Completed client side audits")
+			},
+		},
+		{
+			Name:      "json-reflected-xss",
+			DependsOn: []string{"insertion-points"},
+			Enabled:   clientSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, xssInsertionPointsContextKey)
+				if len(insertionPoints) == 0 {
+					return
+				}
+				JSONReflectedXSSScan(ctx.Item, insertionPoints, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:      "open-redirect",
+			DependsOn: []string{"insertion-points"},
+			Run: func(ctx *HistoryItemModuleContext) {
+				insertionPoints := getContextInsertionPoints(ctx, insertionPointsContextKey)
+				OpenRedirectScan(ctx.Item, ctx.ActiveOptions, insertionPoints)
+			},
+		},
+		{
+			Name: "log4shell",
+			Enabled: func(ctx *HistoryItemModuleContext) bool {
+				return ctx.Options.AuditCategories.ServerSide && (ctx.Options.Mode == scan_options.ScanModeFuzz || scan.PlatformJava.MatchesAnyFingerprint(ctx.Options.Fingerprints))
+			},
+			Run: func(ctx *HistoryItemModuleContext) {
+				log4shell := Log4ShellInjectionAudit{
+					URL:                 ctx.Item.URL,
+					Concurrency:         historyItemModulesConcurrency,
+					InteractionsManager: ctx.InteractionsManager,
+					WorkspaceID:         ctx.Options.WorkspaceID,
+					TaskID:              ctx.Options.TaskID,
+					TaskJobID:           ctx.Options.TaskJobID,
+					ClientOptions:       ctx.ActiveOptions.ClientOptions,
+				}
+				log4shell.Run()
+			},
+		},
+		{
+			Name:    "server-side-headers",
+			Enabled: serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				HostHeaderInjectionScan(ctx.Item, ctx.InteractionsManager, ctx.ActiveOptions)
+				JWTKidInjectionScan(ctx.Item, ctx.ActiveOptions)
+				// NOTE: Checks below are probably not worth to run against every history item,
+				// but also not only once per target. Should find a way to run them only in some cases
+				// but ensuring they are checked against X different history items per target.
+				sni := SNIAudit{
+					HistoryItem:         ctx.Item,
+					InteractionsManager: ctx.InteractionsManager,
+					WorkspaceID:         ctx.Options.WorkspaceID,
+					TaskID:              ctx.Options.TaskID,
+					TaskJobID:           ctx.Options.TaskJobID,
+					ClientOptions:       ctx.ActiveOptions.ClientOptions,
+				}
+				sni.Run()
 
-	} else {
-		taskLog.Info().Msg("No insertion points to audit")
+				HttpVersionsScan(ctx.Item, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:    "web-cache-poisoning",
+			Enabled: serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				WebCachePoisoningScan(ctx.Item, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:    "cors",
+			Enabled: serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				CORSScan(ctx.Item, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name:    "request-smuggling",
+			Enabled: serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				// Confirming the desync poisons the connections of the target, so it requires opting in to experimental audits
+				RequestSmugglingScan(ctx.Item, ctx.ActiveOptions, ctx.Options.ExperimentalAudits)
+			},
+		},
+		{
+			Name:    "jwt-attacks",
+			Enabled: serverSideEnabled,
+			Run: func(ctx *HistoryItemModuleContext) {
+				JWTAttacksScan(ctx.Item, ctx.ActiveOptions)
+			},
+		},
+		{
+			Name: "experimental",
+			Enabled: func(ctx *HistoryItemModuleContext) bool {
+				return ctx.Options.ExperimentalAudits
+			},
+			Run: func(ctx *HistoryItemModuleContext) {
+				cspp := ClientSidePrototypePollutionAudit{
+					HistoryItem:   ctx.Item,
+					WorkspaceID:   ctx.Options.WorkspaceID,
+					TaskID:        ctx.Options.TaskID,
+					TaskJobID:     ctx.Options.TaskJobID,
+					ClientOptions: ctx.ActiveOptions.ClientOptions,
+				}
+				cspp.Run()
+				methods := HTTPMethodsAudit{
+					HistoryItem:   ctx.Item,
+					Concurrency:   5,
+					WorkspaceID:   ctx.Options.WorkspaceID,
+					TaskID:        ctx.Options.TaskID,
+					TaskJobID:     ctx.Options.TaskJobID,
+					ClientOptions: ctx.ActiveOptions.ClientOptions,
+				}
+				methods.Run()
+			},
+		},
+		{
+			Name: "jsonp-callback",
+			Run: func(ctx *HistoryItemModuleContext) {
+				JSONPCallbackScan(ctx.Item, ctx.ActiveOptions)
+			},
+		},
 	}
+}
 
-	if item.StatusCode >= 300 || item.StatusCode < 400 {
-		OpenRedirectScan(item, activeOptions, insertionPoints)
-	} else {
-		var openRedirectInsertionPoints []scan.InsertionPoint
-		for _, insertionPoint := range insertionPoints {
-			if scan.IsCommonOpenRedirectParameter(insertionPoint.Name) {
-				openRedirectInsertionPoints = append(openRedirectInsertionPoints, insertionPoint)
-			}
-		}
-		if len(openRedirectInsertionPoints) > 0 {
-			OpenRedirectScan(item, activeOptions, openRedirectInsertionPoints)
-		}
+// analyzeInsertionPointsModule gets and analyzes the insertion points of the history item, storing them in the context
+// together with the subset of them that should be audited according to the scan mode
+func analyzeInsertionPointsModule(ctx *HistoryItemModuleContext) {
+	taskLog := log.With().Uint("workspace", ctx.Options.WorkspaceID).Str("mode", ctx.Options.Mode.String()).Str("item", ctx.Item.URL).Str("method", ctx.Item.Method).Int("ID", int(ctx.Item.ID)).Logger()
+	historyCreateOptions := http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         ctx.Options.WorkspaceID,
+		TaskID:              ctx.Options.TaskID,
+		CreateNewBodyStream: true,
+		TaskJobID:           ctx.Options.TaskJobID,
 	}
 
-	if options.AuditCategories.ServerSide && (options.Mode == scan_options.ScanModeFuzz || scan.PlatformJava.MatchesAnyFingerprint(options.Fingerprints)) {
-		log4shell := Log4ShellInjectionAudit{
-			URL:                 item.URL,
-			Concurrency:         historyItemModulesConcurrency,
-			InteractionsManager: interactionsManager,
-			WorkspaceID:         options.WorkspaceID,
-			TaskID:              options.TaskID,
-			TaskJobID:           options.TaskJobID,
-		}
-		log4shell.Run()
+	insertionPoints, err := scan.GetAndAnalyzeInsertionPoints(ctx.Item, ctx.Options.InsertionPoints, scan.InsertionPointAnalysisOptions{
+		HistoryCreateOptions: historyCreateOptions,
+		Filter:               ctx.Options.InsertionPointFilter,
+		ClientOptions:        ctx.ActiveOptions.ClientOptions,
+	})
+	taskLog.Debug().Interface("insertionPoints", insertionPoints).Msg("Insertion points")
+	if err != nil {
+		taskLog.Error().Err(err).Msg("Could not get insertion points")
 	}
-
-	if options.AuditCategories.ServerSide {
-		hostHeader := HostHeaderInjectionAudit{
-			URL:         item.URL,
-			Concurrency: historyItemModulesConcurrency,
-			WorkspaceID: options.WorkspaceID,
-			TaskID:      options.TaskID,
-			TaskJobID:   options.TaskJobID,
-		}
-		hostHeader.Run()
-		// NOTE: Checks below are probably not worth to run against every history item,
-		// but also not only once per target. Should find a way to run them only in some cases
-		// but ensuring they are checked against X different history items per target.
-		sni := SNIAudit{
-			HistoryItem:         item,
-			InteractionsManager: interactionsManager,
-			WorkspaceID:         options.WorkspaceID,
-			TaskID:              options.TaskID,
-			TaskJobID:           options.TaskJobID,
-		}
-		sni.Run()
-
-		HttpVersionsScan(item, activeOptions)
+	ctx.Set(insertionPointsContextKey, insertionPoints)
+	if len(insertionPoints) == 0 {
+		taskLog.Info().Msg("No insertion points to audit")
+		return
 	}
 
-	if options.ExperimentalAudits {
-		cspp := ClientSidePrototypePollutionAudit{
-			HistoryItem: item,
-			WorkspaceID: options.WorkspaceID,
-			TaskID:      options.TaskID,
-			TaskJobID:   options.TaskJobID,
-		}
-		cspp.Run()
-		methods := HTTPMethodsAudit{
-			HistoryItem: item,
-			Concurrency: 5,
-			WorkspaceID: options.WorkspaceID,
-			TaskID:      options.TaskID,
-			TaskJobID:   options.TaskJobID,
+	var insertionPointsToAudit []scan.InsertionPoint
+	var xssInsertionPoints []scan.InsertionPoint
+	for _, insertionPoint := range insertionPoints {
+		if isInsertionPointAudited(ctx.Options.Mode, insertionPoint) {
+			insertionPointsToAudit = append(insertionPointsToAudit, insertionPoint)
+			xssInsertionPoints = append(xssInsertionPoints, insertionPoint)
+		} else {
+			taskLog.Debug().Str("insertionPoint", insertionPoint.Name).Msg("Skipping insertion point")
 		}
-		methods.Run()
 	}
-	JSONPCallbackScan(item, activeOptions)
+	ctx.Set(auditInsertionPointsContextKey, insertionPointsToAudit)
+	ctx.Set(xssInsertionPointsContextKey, xssInsertionPoints)
+}
 
-	log.Info().Str("item", item.URL).Str("method", item.Method).Int("ID", int(item.ID)).Msg("Finished scanning history item")
+// isInsertionPointAudited checks if an analyzed insertion point should be audited according to the scan mode
+func isInsertionPointAudited(mode scan_options.ScanMode, insertionPoint scan.InsertionPoint) bool {
+	switch mode {
+	case scan_options.ScanModeSmart:
+		return insertionPoint.Behaviour.IsDynamic || insertionPoint.Behaviour.IsReflected || insertionPoint.Type == scan.InsertionPointTypeBody || insertionPoint.Type == scan.InsertionPointTypeParameter
+	case scan_options.ScanModeFast:
+		return insertionPoint.Behaviour.IsDynamic || insertionPoint.Behaviour.IsReflected
+	case scan_options.ScanModeFuzz:
+		return true
+	}
+	return false
 }
@@ -3,159 +3,237 @@ package active
 import (
 	"fmt"
 	"net/http"
-	"sync"
+	"net/url"
+	"regexp"
+	"strings"
 
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/lib"
-	"github.com/pyneda/sukyan/pkg/fuzz"
+	"github.com/pyneda/sukyan/lib/integrations"
 	"github.com/pyneda/sukyan/pkg/http_utils"
-	"github.com/pyneda/sukyan/pkg/payloads"
-
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
 	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
 )
 
-// TODO: Refactor required to work with History items, simpler concurrency and maybe even move to a YAML template
-
 // https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/07-Input_Validation_Testing/17-Testing_for_Host_Header_Injection.html
 
-// HostHeaderInjectionAudit configuration
-type HostHeaderInjectionAudit struct {
-	URL                string
-	Concurrency        int
-	HeuristicRecords   []fuzz.HeuristicRecord
-	ExpectedResponses  fuzz.ExpectedResponses
-	ExtraHeadersToTest []string
-	WorkspaceID        uint
-	TaskID             uint
-	TaskJobID          uint
+// hostHeaderInjectionHeader is a header used by applications or reverse proxies to build absolute URLs, where %s is
+// replaced by the marker host. The Host header itself is overridden, while the rest are sent along the original host.
+type hostHeaderInjectionHeader struct {
+	Name   string
+	Format string
+}
+
+var hostHeaderInjectionHeaders = []hostHeaderInjectionHeader{
+	{Name: "Host", Format: "%s"},
+	{Name: "X-Forwarded-Host", Format: "%s"},
+	{Name: "X-Forwarded-Server", Format: "%s"},
+	{Name: "X-Host", Format: "%s"},
+	{Name: "X-HTTP-Host-Override", Format: "%s"},
+	{Name: "Forwarded", Format: "host=%s"},
 }
 
-type hostHeaderInjectionAuditItem struct {
-	payload payloads.PayloadInterface
-	header  string // should be an injection point interface when implemented
+func getHostHeaderInjectionHeadersForMode(mode scan_options.ScanMode) []hostHeaderInjectionHeader {
+	if mode == scan_options.ScanModeFast {
+		return hostHeaderInjectionHeaders[:3]
+	}
+	return hostHeaderInjectionHeaders
 }
 
-// GetDefaultHeadersToTest returns the default headers that are tested in this audit
-func (a *HostHeaderInjectionAudit) GetDefaultHeadersToTest() (headers []string) {
-	return append(headers, []string{
-		"Host",
-		"X-Forwarded-Host",
-		"X-Host",
-		"X-Forwarded-Server",
-		"X-HTTP-Host-Override",
-		"X-Original-URL",
-		"X-Rewrite-URL",
-		"X-Originating-IP",
-		"X-Remote-IP",
-		"X-Client-IP",
-		"X-Forwarded-For",
-		"X-Target-IP",
-		"X-Remote-Addr",
-		"Fowarded",
-		"True-Client-IP",
-		"Via",
-		"X-Real-IP",
-		"X-Azure-ClientIP",
-		"X-Azure-SocketIP",
-	}...)
+type hostHeaderReflectionKind string
+
+const (
+	hostHeaderReflectionRedirect    hostHeaderReflectionKind = "redirect"
+	hostHeaderReflectionAbsoluteURL hostHeaderReflectionKind = "absolute URL in the response body"
+	hostHeaderReflectionHeader      hostHeaderReflectionKind = "response header"
+	hostHeaderReflectionBody        hostHeaderReflectionKind = "response body"
+)
+
+// hostHeaderReflection is an occurrence of the marker host in a response
+type hostHeaderReflection struct {
+	Kind hostHeaderReflectionKind
+	// Location is where the marker has been found, such as the redirect kind or the header name
+	Location string
+	Value    string
 }
 
-// GetHeadersToTest merges the default headers to test and the provided ExtraHeadersToTest
-func (a *HostHeaderInjectionAudit) GetHeadersToTest() (headers []string) {
-	headers = a.GetDefaultHeadersToTest()
-	for _, header := range a.ExtraHeadersToTest {
-		if !lib.SliceContains(headers, header) {
-			headers = append(headers, header)
+func (r hostHeaderReflection) confidence() int {
+	switch r.Kind {
+	case hostHeaderReflectionRedirect:
+		if r.Location == string(redirectKindLocation) {
+			return 90
 		}
+		return 85
+	case hostHeaderReflectionAbsoluteURL:
+		return 85
+	case hostHeaderReflectionHeader:
+		return 80
+	default:
+		return 60
 	}
-	return headers
 }
 
-// Run starts the audit
-func (a *HostHeaderInjectionAudit) Run() {
-	auditItemsChannel := make(chan hostHeaderInjectionAuditItem)
-	pendingChannel := make(chan int)
-	var wg sync.WaitGroup
+// hostHeaderAbsoluteURLRegex matches absolute and protocol relative URLs pointing to the marker host
+func hostHeaderAbsoluteURLRegex(marker string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:https?:)?//` + regexp.QuoteMeta(marker) + `(?:[:/?#"'\s<>\\]|$)`)
+}
 
-	// Schedule workers
-	for i := 0; i < a.Concurrency; i++ {
-		wg.Add(1)
-		go a.worker(auditItemsChannel, pendingChannel, &wg)
+// findHostHeaderReflection returns the most relevant occurrence of the marker host in the response: redirects to it,
+// response headers including it, absolute URLs in the body pointing to it and, as a last resort, any reflection in
+// the body
+func findHostHeaderReflection(statusCode int, headers http.Header, body []byte, marker string) (hostHeaderReflection, bool) {
+	for _, r := range findRedirects(statusCode, headers, body) {
+		if isRedirectToHost(r.Target, marker) {
+			return hostHeaderReflection{Kind: hostHeaderReflectionRedirect, Location: string(r.Kind), Value: r.Target}, true
+		}
 	}
-	// Schedule goroutine to monitor pending tasks
-	go a.monitor(auditItemsChannel, pendingChannel)
-	log.Info().Str("url", a.URL).Msg("Starting to schedule Host header injection audit items")
-
-	// Add tests to the channel
-	for _, header := range a.GetHeadersToTest() {
-		for _, payload := range payloads.GetHostHeaderInjectionPayloads() {
-			pendingChannel <- 1
-			auditItemsChannel <- hostHeaderInjectionAuditItem{
-				payload: payload,
-				header:  header,
+	lowerMarker := strings.ToLower(marker)
+	for name, values := range headers {
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), lowerMarker) {
+				return hostHeaderReflection{Kind: hostHeaderReflectionHeader, Location: name, Value: value}, true
 			}
 		}
 	}
-	wg.Wait()
-	log.Info().Str("url", a.URL).Msg("All host header injection audit items completed")
-}
-
-func (a *HostHeaderInjectionAudit) worker(auditItems chan hostHeaderInjectionAuditItem, pendingChannel chan int, wg *sync.WaitGroup) {
-	for auditItem := range auditItems {
-		a.testItem(auditItem)
-		pendingChannel <- -1
+	if match := hostHeaderAbsoluteURLRegex(marker).Find(body); match != nil {
+		return hostHeaderReflection{Kind: hostHeaderReflectionAbsoluteURL, Location: "body", Value: strings.TrimRight(string(match), "\"'<> \t\r\n\\")}, true
 	}
-	wg.Done()
+	if strings.Contains(strings.ToLower(string(body)), lowerMarker) {
+		return hostHeaderReflection{Kind: hostHeaderReflectionBody, Location: "body", Value: marker}, true
+	}
+	return hostHeaderReflection{}, false
 }
 
-func (a *HostHeaderInjectionAudit) monitor(auditItems chan hostHeaderInjectionAuditItem, pendingChannel chan int) {
-	count := 0
-	log.Debug().Str("url", a.URL).Msg("Host header audit monitor started")
-	for c := range pendingChannel {
-		count += c
-		if count == 0 {
-			log.Info().Str("url", a.URL).Msg("Host header audit finished, closing communication channels")
-			close(auditItems)
-			close(pendingChannel)
+// isPasswordResetRequest checks if the request looks like a password reset one, where the links sent by email are
+// usually built from the Host header
+func isPasswordResetRequest(history *db.History) bool {
+	u, err := url.Parse(history.URL)
+	if err != nil {
+		return false
+	}
+	path := strings.ToLower(u.Path)
+	for _, keyword := range []string{"forgot", "reset", "recover", "lost-password", "lostpassword"} {
+		if strings.Contains(path, keyword) {
+			return true
 		}
 	}
+	return false
 }
 
-func (a *HostHeaderInjectionAudit) testItem(item hostHeaderInjectionAuditItem) {
-	// Just basic implementation, by now just check if the payload appended in the host header appears in the response, still should:
-	// - Check if response differs when the header appears or not
-	// - Use the data gathered in previous steps to compare with the current implementation results
-	// - Could use interactsh payloads
-	// - Could also probably send all headers at once
-	client := http_utils.CreateHttpClient()
-	auditLog := log.With().Str("audit", "host-header-injection").Interface("auditItem", item).Str("url", a.URL).Logger()
-	request, err := http.NewRequest("GET", a.URL, nil)
-	if err != nil {
-		auditLog.Error().Err(err).Msg("Error creating request")
-		return
+func newHostHeaderInjectionOOBTest(history *db.History, header hostHeaderInjectionHeader, payload string, interactionData integrations.InteractionDomain, options ActiveModuleOptions) db.OOBTest {
+	return db.OOBTest{
+		Code:              db.HostHeaderInjectionCode,
+		TestName:          "Host header injection",
+		InteractionDomain: interactionData.URL,
+		InteractionFullID: interactionData.ID,
+		Target:            history.URL,
+		Payload:           payload,
+		HistoryID:         &history.ID,
+		InsertionPoint:    fmt.Sprintf("%s header", header.Name),
+		WorkspaceID:       &options.WorkspaceID,
+		TaskID:            &options.TaskID,
+		TaskJobID:         &options.TaskJobID,
 	}
+}
 
-	request.Header.Set(item.header, item.payload.GetValue())
-	response, err := client.Do(request)
-
-	if err != nil {
-		auditLog.Error().Err(err).Msg("Error during request")
-		return
+// HostHeaderInjectionScan overrides the Host header, or sends headers commonly trusted over it such as
+// X-Forwarded-Host, with a marker host and checks if it is used to build redirects, links or other absolute URLs.
+// When an interactions manager is provided the marker is an interaction domain, so the server using it to perform
+// requests, such as when sending password reset emails, is also detected.
+func HostHeaderInjectionScan(history *db.History, interactionsManager *integrations.InteractionsManager, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "host-header-injection").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
 	}
-	history, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
-		Source:              db.SourceScanner,
-		WorkspaceID:         uint(a.WorkspaceID),
-		TaskID:              uint(a.TaskID),
-		CreateNewBodyStream: true,
-	})
-	if err != nil {
-		auditLog.Error().Err(err).Msg("Error reading response body data")
-		return
+	passwordReset := isPasswordResetRequest(history)
+
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
 	}
-	isInResponse, _ := item.payload.MatchAgainstString(string(history.RawResponse))
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, header := range getHostHeaderInjectionHeadersForMode(options.ScanMode) {
+		header := header
+		p.Go(func() {
+			marker := lib.GenerateRandomLowercaseString(10) + ".com"
+			var interactionData integrations.InteractionDomain
+			if interactionsManager != nil {
+				data, reused, err := interactionsManager.GetURLForTest(integrations.OOBTestKey{
+					Target:         history.URL,
+					InsertionPoint: fmt.Sprintf("%s header", header.Name),
+					Payload:        fmt.Sprintf(header.Format, "{{interactionAddress}}"),
+				})
+				if err != nil {
+					auditLog.Warn().Err(err).Str("header", header.Name).Msg("Using a random marker host as no interaction url could be obtained")
+				} else if reused {
+					auditLog.Debug().Str("header", header.Name).Msg("Interaction url already used by an identical test, skipping")
+					return
+				} else {
+					interactionData = data
+					marker = data.URL
+				}
+			}
+			payload := fmt.Sprintf(header.Format, marker)
+
+			request, err := http_utils.BuildRequestFromHistoryItem(history)
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating request")
+				return
+			}
+			if header.Name == "Host" {
+				request.Host = payload
+			} else {
+				request.Header.Set(header.Name, payload)
+			}
+			response, err := http_utils.SendRequest(client, request)
+			if err != nil {
+				auditLog.Error().Err(err).Str("header", header.Name).Msg("Error during request")
+				return
+			}
+			newHistory, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+				Source:              db.SourceScanner,
+				WorkspaceID:         options.WorkspaceID,
+				TaskID:              options.TaskID,
+				TaskJobID:           options.TaskJobID,
+				CreateNewBodyStream: false,
+			})
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating history from response")
+				return
+			}
+
+			if interactionData.ID != "" {
+				if _, err := db.Connection.CreateOOBTest(newHostHeaderInjectionOOBTest(newHistory, header, payload, interactionData, options)); err != nil {
+					auditLog.Error().Err(err).Str("header", header.Name).Msg("Error creating OOB test")
+				}
+			}
 
-	if isInResponse {
-		details := fmt.Sprintf("A host header injection vulnerability has been detected in %s. The audit test send the following payload `%s` in `%s` header and it has been verified is included back in the response", a.URL, item.payload.GetValue(), item.header)
-		db.CreateIssueFromHistoryAndTemplate(history, db.HostHeaderInjectionCode, details, 75, "", &a.WorkspaceID, &a.TaskID, &a.TaskJobID)
+			reflection, found := findHostHeaderReflection(newHistory.StatusCode, response.Header, newHistory.ResponseBody, marker)
+			if !found {
+				return
+			}
+			auditLog.Info().Str("header", header.Name).Str("kind", string(reflection.Kind)).Str("location", reflection.Location).Msg("Host header injection found")
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("The `%s` header has been sent with the value `%s` and the marker host has been found in the response", header.Name, payload))
+			switch reflection.Kind {
+			case hostHeaderReflectionRedirect:
+				sb.WriteString(fmt.Sprintf(", which redirects to `%s` through a %s redirection.", reflection.Value, reflection.Location))
+			case hostHeaderReflectionHeader:
+				sb.WriteString(fmt.Sprintf(" `%s` header: `%s`.", reflection.Location, reflection.Value))
+			case hostHeaderReflectionAbsoluteURL:
+				sb.WriteString(fmt.Sprintf(" body as part of the absolute URL `%s`.", reflection.Value))
+			default:
+				sb.WriteString(" body.")
+			}
+			if passwordReset {
+				sb.WriteString("\n\nThis request looks like a password reset one. If the reset link sent by email is built from the host provided in the request, it could be poisoned to leak the reset token to an attacker controlled host.")
+			}
+			db.CreateIssueFromHistoryAndTemplateWithOptions(newHistory, db.HostHeaderInjectionCode, sb.String(), reflection.confidence(), "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: fmt.Sprintf("%s header", header.Name)})
+		})
 	}
+	p.Wait()
+	auditLog.Info().Msg("Host header injection audit completed")
 }
@@ -0,0 +1,66 @@
+package active
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindHostHeaderReflectionInLocation(t *testing.T) {
+	headers := http.Header{"Location": []string{"https://abcdefghij.com/login?next=%2Faccount"}}
+	reflection, found := findHostHeaderReflection(302, headers, nil, "abcdefghij.com")
+	assert.True(t, found)
+	assert.Equal(t, hostHeaderReflectionRedirect, reflection.Kind)
+	assert.Equal(t, string(redirectKindLocation), reflection.Location)
+	assert.Equal(t, 90, reflection.confidence())
+
+	// A different host containing the marker is not a redirect to it
+	headers = http.Header{"Location": []string{"https://example.com/?host=abcdefghij.com"}}
+	reflection, found = findHostHeaderReflection(302, headers, nil, "abcdefghij.com")
+	assert.True(t, found)
+	assert.Equal(t, hostHeaderReflectionHeader, reflection.Kind)
+	assert.Equal(t, "Location", reflection.Location)
+
+	_, found = findHostHeaderReflection(302, http.Header{"Location": []string{"https://example.com/login"}}, nil, "abcdefghij.com")
+	assert.False(t, found)
+}
+
+func TestFindHostHeaderReflectionInBody(t *testing.T) {
+	body := []byte(`<html><head><link rel="stylesheet" href="https://abcdefghij.com/static/main.css"></head><body>Welcome</body></html>`)
+	reflection, found := findHostHeaderReflection(200, http.Header{"Content-Type": []string{"text/html"}}, body, "abcdefghij.com")
+	assert.True(t, found)
+	assert.Equal(t, hostHeaderReflectionAbsoluteURL, reflection.Kind)
+	assert.Equal(t, "https://abcdefghij.com/", reflection.Value)
+	assert.Equal(t, 85, reflection.confidence())
+
+	body = []byte(`<script src="//abcdefghij.com"></script>`)
+	reflection, found = findHostHeaderReflection(200, http.Header{}, body, "abcdefghij.com")
+	assert.True(t, found)
+	assert.Equal(t, hostHeaderReflectionAbsoluteURL, reflection.Kind)
+	assert.Equal(t, "//abcdefghij.com", reflection.Value)
+
+	// Subdomains of the marker are a different host
+	body = []byte(`<a href="https://abcdefghij.com.example.com/">Home</a>`)
+	reflection, found = findHostHeaderReflection(200, http.Header{}, body, "abcdefghij.com")
+	assert.True(t, found)
+	assert.Equal(t, hostHeaderReflectionBody, reflection.Kind)
+	assert.Equal(t, 60, reflection.confidence())
+
+	_, found = findHostHeaderReflection(200, http.Header{}, []byte(`<a href="https://example.com/">Home</a>`), "abcdefghij.com")
+	assert.False(t, found)
+}
+
+func TestIsPasswordResetRequest(t *testing.T) {
+	assert.True(t, isPasswordResetRequest(&db.History{URL: "https://example.com/account/forgot-password"}))
+	assert.True(t, isPasswordResetRequest(&db.History{URL: "https://example.com/api/v1/password/reset"}))
+	assert.False(t, isPasswordResetRequest(&db.History{URL: "https://example.com/login?next=/reset"}))
+}
+
+func TestGetHostHeaderInjectionHeadersForMode(t *testing.T) {
+	fast := getHostHeaderInjectionHeadersForMode(scan_options.ScanModeFast)
+	assert.Equal(t, []string{"Host", "X-Forwarded-Host", "X-Forwarded-Server"}, []string{fast[0].Name, fast[1].Name, fast[2].Name})
+	assert.Len(t, getHostHeaderInjectionHeadersForMode(scan_options.ScanModeSmart), len(hostHeaderInjectionHeaders))
+}
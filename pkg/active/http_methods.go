@@ -16,6 +16,8 @@ type HTTPMethodsAudit struct {
 	WorkspaceID uint
 	TaskID      uint
 	TaskJobID   uint
+	// ClientOptions are the settings of the HTTP clients of the scan
+	ClientOptions *http_utils.ClientOptions
 }
 
 type httpMethodsAudiItem struct {
@@ -87,7 +89,7 @@ func (a *HTTPMethodsAudit) monitor(auditItems chan httpMethodsAudiItem, pendingC
 }
 
 func (a *HTTPMethodsAudit) testItem(item httpMethodsAudiItem) {
-	client := http_utils.CreateHttpClient()
+	client := http_utils.CreateHttpClientWithOptions(a.ClientOptions)
 	auditLog := log.With().Str("audit", "httpMethods").Interface("auditItem", item).Str("url", a.HistoryItem.URL).Uint("workspace", a.WorkspaceID).Logger()
 	request, err := http_utils.BuildRequestFromHistoryItem(a.HistoryItem)
 	if err != nil {
@@ -18,7 +18,7 @@ func HttpVersionsScan(history *db.History, options ActiveModuleOptions) (HttpVer
 	results := HttpVersionScanResults{}
 	auditLog := log.With().Str("audit", "http-versions").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
 
-	http2Client := http_utils.CreateHttp2Client()
+	http2Client := http_utils.CreateHttp2Client(options.ClientOptions)
 	http2History, err := sendRequest(http2Client, history, options)
 	if err == nil && http2History != nil && history.ID > 0 {
 		auditLog.Info().Msg("HTTP/2 is supported")
@@ -29,7 +29,7 @@ func HttpVersionsScan(history *db.History, options ActiveModuleOptions) (HttpVer
 		auditLog.Debug().Err(err).Msg("Failed to send HTTP/2 request")
 	}
 
-	http3Client := http_utils.CreateHttp3Client()
+	http3Client := http_utils.CreateHttp3Client(options.ClientOptions)
 	http3History, err := sendRequest(http3Client, history, options)
 	if err == nil && http3History != nil && history.ID > 0 {
 		auditLog.Info().Msg("HTTP/3 is supported")
@@ -0,0 +1,136 @@
+package active
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/scan"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// JSONReflectedXSSScan checks if the reflected insertion points of a JSON response reflect HTML unescaped while the
+// response can be rendered as HTML by the browser, either because of its content type or because it can be sniffed
+func JSONReflectedXSSScan(history *db.History, insertionPoints []scan.InsertionPoint, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "json-reflected-xss").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if !isJSONResponse(history) {
+		return
+	}
+
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
+	}
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, insertionPoint := range insertionPoints {
+		if !insertionPoint.Behaviour.IsReflected {
+			continue
+		}
+		insertionPoint := insertionPoint
+		p.Go(func() {
+			payload := fmt.Sprintf("<%s x=%s>", lib.GenerateRandomLowercaseString(6), lib.GenerateRandomLowercaseString(4))
+			request, err := scan.CreateRequestFromInsertionPoints(history, []scan.InsertionPointBuilder{
+				{
+					Point:   insertionPoint,
+					Payload: payload,
+				},
+			})
+			if err != nil {
+				auditLog.Error().Err(err).Str("insertion_point", insertionPoint.Name).Msg("Error creating request")
+				return
+			}
+
+			response, err := client.Do(request)
+			if err != nil {
+				auditLog.Error().Err(err).Str("insertion_point", insertionPoint.Name).Msg("Error during request")
+				return
+			}
+			newHistory, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+				Source:              db.SourceScanner,
+				WorkspaceID:         options.WorkspaceID,
+				TaskID:              options.TaskID,
+				TaskJobID:           options.TaskJobID,
+				CreateNewBodyStream: false,
+			})
+			if err != nil {
+				auditLog.Error().Err(err).Str("insertion_point", insertionPoint.Name).Msg("Error reading response")
+				return
+			}
+
+			renderable, reasons := IsJSONReflectionRenderableAsHTML(newHistory, payload)
+			if !renderable {
+				return
+			}
+			auditLog.Info().Str("insertion_point", insertionPoint.Name).Strs("reasons", reasons).Msg("Payload reflected in a JSON response that can be rendered as HTML")
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("The payload `%s` sent in the %s `%s` has been reflected without encoding in a JSON response which can be rendered as HTML by the browser:\n", payload, insertionPoint.Type, insertionPoint.Name))
+			for _, reason := range reasons {
+				sb.WriteString(fmt.Sprintf("\n - %s", reason))
+			}
+			sb.WriteString("\n\nAn attacker could make a victim navigate directly to this endpoint with a crafted payload to execute JavaScript in the context of the application.")
+			db.CreateIssueFromHistoryAndTemplateWithOptions(newHistory, db.XssReflectedJsonResponseCode, sb.String(), 80, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
+		})
+	}
+	p.Wait()
+}
+
+// IsJSONReflectionRenderableAsHTML checks if the payload is reflected unescaped in a JSON response which a browser
+// would render as HTML, returning the reasons why it would be rendered
+func IsJSONReflectionRenderableAsHTML(history *db.History, payload string) (bool, []string) {
+	if !isJSONResponse(history) || !bytes.Contains(history.ResponseBody, []byte(payload)) {
+		return false, nil
+	}
+
+	headers, err := history.GetResponseHeadersAsMap()
+	if err != nil {
+		return false, nil
+	}
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(getHeaderValue(headers, "Content-Type"), ";")[0]))
+	nosniff := strings.EqualFold(strings.TrimSpace(getHeaderValue(headers, "X-Content-Type-Options")), "nosniff")
+
+	var reasons []string
+	switch {
+	case contentType == "text/html" || contentType == "application/xhtml+xml":
+		reasons = append(reasons, fmt.Sprintf("The JSON response is served with the `%s` content type", contentType))
+	case nosniff:
+		return false, nil
+	case contentType == "":
+		reasons = append(reasons, "The JSON response does not specify a content type, so the browser sniffs it")
+	case contentType == "text/plain" || contentType == "application/octet-stream" || contentType == "unknown/unknown":
+		reasons = append(reasons, fmt.Sprintf("The JSON response is served with the sniffable `%s` content type", contentType))
+	default:
+		return false, nil
+	}
+	if !nosniff {
+		reasons = append(reasons, "The `X-Content-Type-Options: nosniff` header is missing")
+	}
+	return true, reasons
+}
+
+// isJSONResponse checks if the response of the history item is JSON, either by its content type or its body
+func isJSONResponse(history *db.History) bool {
+	if strings.Contains(strings.ToLower(history.ResponseContentType), "json") {
+		return true
+	}
+	body := bytes.TrimSpace(history.ResponseBody)
+	if len(body) == 0 || (body[0] != '{' && body[0] != '[') {
+		return false
+	}
+	return json.Valid(body)
+}
+
+func getHeaderValue(headers map[string][]string, name string) string {
+	for key, values := range headers {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package active
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestIsJSONReflectionRenderableAsHTML(t *testing.T) {
+	payload := "<skxss x=abcd>"
+	body := []byte(`{"query": "<skxss x=abcd>", "results": []}`)
+
+	tests := []struct {
+		name       string
+		history    *db.History
+		payload    string
+		renderable bool
+	}{
+		{
+			name: "sniffable content type without nosniff",
+			history: &db.History{
+				ResponseContentType: "text/plain",
+				ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["text/plain; charset=utf-8"]}`),
+				ResponseBody:        body,
+			},
+			payload:    payload,
+			renderable: true,
+		},
+		{
+			name: "missing content type and nosniff",
+			history: &db.History{
+				ResponseHeaders: datatypes.JSON(`{}`),
+				ResponseBody:    body,
+			},
+			payload:    payload,
+			renderable: true,
+		},
+		{
+			name: "html content type",
+			history: &db.History{
+				ResponseContentType: "text/html",
+				ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["text/html"], "X-Content-Type-Options": ["nosniff"]}`),
+				ResponseBody:        body,
+			},
+			payload:    payload,
+			renderable: true,
+		},
+		{
+			name: "sniffable content type with nosniff",
+			history: &db.History{
+				ResponseContentType: "text/plain",
+				ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["text/plain"], "X-Content-Type-Options": ["nosniff"]}`),
+				ResponseBody:        body,
+			},
+			payload:    payload,
+			renderable: false,
+		},
+		{
+			name: "json content type",
+			history: &db.History{
+				ResponseContentType: "application/json",
+				ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["application/json"]}`),
+				ResponseBody:        body,
+			},
+			payload:    payload,
+			renderable: false,
+		},
+		{
+			name: "escaped reflection",
+			history: &db.History{
+				ResponseContentType: "text/plain",
+				ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["text/plain"]}`),
+				ResponseBody:        []byte(`{"query": "\u003cskxss x=abcd\u003e"}`),
+			},
+			payload:    payload,
+			renderable: false,
+		},
+		{
+			name: "html response",
+			history: &db.History{
+				ResponseContentType: "text/html",
+				ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["text/html"]}`),
+				ResponseBody:        []byte(`<html><body><skxss x=abcd></body></html>`),
+			},
+			payload:    payload,
+			renderable: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderable, reasons := IsJSONReflectionRenderableAsHTML(tt.history, tt.payload)
+			assert.Equal(t, tt.renderable, renderable)
+			if tt.renderable {
+				assert.NotEmpty(t, reasons)
+			}
+		})
+	}
+}
@@ -59,7 +59,7 @@ func JSONPCallbackScan(history *db.History, options ActiveModuleOptions) {
 	hasJsonParam := hasJsonpParameter(history)
 	callbacksToTest := getCallbacksForMode(options.ScanMode, hasJsonParam)
 
-	client := http_utils.CreateHttpClient()
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
 	p := pool.New().WithMaxGoroutines(options.Concurrency)
 
 	for _, param := range callbacksToTest {
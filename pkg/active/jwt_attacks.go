@@ -0,0 +1,266 @@
+package active
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/tokens"
+	"github.com/rs/zerolog/log"
+)
+
+// https://portswigger.net/web-security/jwt
+
+// jwksPaths are the locations where servers usually publish the public keys used to verify their tokens
+var jwksPaths = []string{
+	"/.well-known/jwks.json",
+	"/jwks.json",
+	"/.well-known/openid-configuration",
+	"/oauth/jwks",
+	"/api/jwks",
+}
+
+var (
+	// jwtAttacksTestedTokens holds the tokens already tested against each origin
+	jwtAttacksTestedTokens sync.Map
+	// jwksKeysByOrigin caches the public keys found on each origin
+	jwksKeysByOrigin sync.Map
+)
+
+// forgedJWT is a token forged through one of the attacks
+type forgedJWT struct {
+	Attack      tokens.JWTAttack
+	Description string
+	Token       string
+}
+
+// buildNoneAlgorithmJWTs returns the token without signature using each casing of the none algorithm
+func buildNoneAlgorithmJWTs(token string) []forgedJWT {
+	var forged []forgedJWT
+	for _, alg := range tokens.GetNoneAlgorithmVariants() {
+		noneToken, err := tokens.ForgeJWTWithNoneAlgorithm(token, alg)
+		if err != nil {
+			continue
+		}
+		forged = append(forged, forgedJWT{
+			Attack:      tokens.JWTAttackNoneAlgorithm,
+			Description: fmt.Sprintf("The alg header has been set to `%s` and the signature removed", alg),
+			Token:       noneToken,
+		})
+	}
+	return forged
+}
+
+// buildAlgorithmConfusionJWTs returns the token signed with HS256 using each encoding of the public keys as secret
+func buildAlgorithmConfusionJWTs(token string, keys []*rsa.PublicKey) []forgedJWT {
+	var forged []forgedJWT
+	for i, key := range keys {
+		for _, secret := range tokens.GetPublicKeyHMACSecrets(key) {
+			confusionToken, err := tokens.ForgeJWTWithAlgorithmConfusion(token, secret)
+			if err != nil {
+				continue
+			}
+			format := "PKIX"
+			if strings.Contains(string(secret), "RSA PUBLIC KEY") {
+				format = "PKCS#1"
+			}
+			forged = append(forged, forgedJWT{
+				Attack:      tokens.JWTAttackAlgorithmConfusion,
+				Description: fmt.Sprintf("The token has been signed with HS256 using the public key %d of the JWKS in %s PEM format as the HMAC secret", i+1, format),
+				Token:       confusionToken,
+			})
+		}
+	}
+	return forged
+}
+
+// fetchJWKSKeys looks for the JSON Web Key Set of the origin, following the jwks_uri of the OpenID configuration
+func fetchJWKSKeys(client *http.Client, origin string, options ActiveModuleOptions) []*rsa.PublicKey {
+	if cached, ok := jwksKeysByOrigin.Load(origin); ok {
+		return cached.([]*rsa.PublicKey)
+	}
+	var keys []*rsa.PublicKey
+	for _, path := range jwksPaths {
+		body, err := fetchJWKSDocument(client, origin+path, options)
+		if err != nil || len(body) == 0 {
+			continue
+		}
+		if strings.HasSuffix(path, "openid-configuration") {
+			var configuration struct {
+				JwksURI string `json:"jwks_uri"`
+			}
+			if err := json.Unmarshal(body, &configuration); err != nil || configuration.JwksURI == "" {
+				continue
+			}
+			if body, err = fetchJWKSDocument(client, configuration.JwksURI, options); err != nil {
+				continue
+			}
+		}
+		if found, err := tokens.ParseJWKSRSAKeys(body); err == nil && len(found) > 0 {
+			keys = found
+			break
+		}
+	}
+	jwksKeysByOrigin.Store(origin, keys)
+	return keys
+}
+
+func fetchJWKSDocument(client *http.Client, documentURL string, options ActiveModuleOptions) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http_utils.SendRequest(client, request)
+	if err != nil {
+		return nil, err
+	}
+	history, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:      db.SourceScanner,
+		WorkspaceID: options.WorkspaceID,
+		TaskID:      options.TaskID,
+		TaskJobID:   options.TaskJobID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if history.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	return history.ResponseBody, nil
+}
+
+// getCrackedJWTSecret returns the secret of the HMAC signed token, cracking it with the embedded wordlist if it has not
+// been tested yet
+func getCrackedJWTSecret(history *db.History, token string) (string, bool) {
+	jwtModel, err := db.Connection.GetOrCreateJWTFromTokenAndHistory(token, history.ID)
+	if err != nil {
+		return "", false
+	}
+	if !jwtModel.Cracked && !jwtModel.TestedEmbeddedWordlist {
+		result, err := tokens.CrackJWTAndCreateIssue(history, jwtModel)
+		if err != nil || !result.Found {
+			return "", false
+		}
+		return result.Secret, true
+	}
+	return jwtModel.Secret, jwtModel.Cracked
+}
+
+// JWTAttacksScan looks for JWTs in the request and replays it with tokens forged through the none algorithm, the
+// RS/HS algorithm confusion using the public keys published by the server and the signing secret when it can be
+// cracked, reporting the attacks whose tokens get accepted. Each token is tested once per origin.
+func JWTAttacksScan(history *db.History, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "jwt-attacks").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+
+	jwts := lib.GetUniqueItems(requestJwtRegex.FindAllString(string(history.RawRequest), -1))
+	if len(jwts) == 0 {
+		return
+	}
+	if history.StatusCode >= 400 {
+		auditLog.Debug().Int("status", history.StatusCode).Msg("Skipping JWT attacks audit as the original request was not successful")
+		return
+	}
+	u, err := url.Parse(history.URL)
+	if err != nil {
+		return
+	}
+	origin := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for _, token := range jwts {
+		if _, tested := jwtAttacksTestedTokens.LoadOrStore(fmt.Sprintf("%d|%s|%s", options.WorkspaceID, origin, token), true); tested {
+			continue
+		}
+		alg, err := tokens.GetJWTAlgorithm(token)
+		if err != nil {
+			auditLog.Debug().Err(err).Str("token", token).Msg("Could not parse token, skipping")
+			continue
+		}
+
+		// A token signed with a random key must be rejected, otherwise the server does not verify signatures
+		invalidToken, err := tokens.ForgeJWTWithAlgorithmConfusion(token, []byte(lib.GenerateRandomString(32)))
+		if err != nil {
+			continue
+		}
+		invalidHistory, err := sendRequestWithReplacedToken(client, history, token, invalidToken, options)
+		if err != nil {
+			auditLog.Error().Err(err).Msg("Error sending request with invalid token")
+			continue
+		}
+		if isJwtAccepted(history, invalidHistory) {
+			reportJWTWeakness(history, invalidHistory, nil, token, forgedJWT{
+				Attack:      tokens.JWTAttackSignatureNotVerified,
+				Description: "The token has been signed with HS256 using a random secret",
+				Token:       invalidToken,
+			}, options)
+			continue
+		}
+
+		forged := buildNoneAlgorithmJWTs(token)
+		if strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS") {
+			if keys := fetchJWKSKeys(client, origin, options); len(keys) > 0 {
+				forged = append(forged, buildAlgorithmConfusionJWTs(token, keys)...)
+			}
+		}
+		if strings.HasPrefix(alg, "HS") {
+			if secret, found := getCrackedJWTSecret(history, token); found {
+				secretToken, err := tokens.ForgeJWTWithSecret(token, []byte(secret), "sukyan", lib.GenerateRandomLowercaseString(8))
+				if err == nil {
+					forged = append(forged, forgedJWT{
+						Attack:      tokens.JWTAttackWeakSecret,
+						Description: fmt.Sprintf("A claim has been added to the token, which has been signed with the cracked secret `%s`", secret),
+						Token:       secretToken,
+					})
+				}
+			}
+		}
+
+		reported := make(map[tokens.JWTAttack]bool)
+		for _, forgedToken := range forged {
+			if reported[forgedToken.Attack] {
+				continue
+			}
+			forgedHistory, err := sendRequestWithReplacedToken(client, history, token, forgedToken.Token, options)
+			if err != nil {
+				auditLog.Error().Err(err).Str("attack", string(forgedToken.Attack)).Msg("Error sending request with forged token")
+				continue
+			}
+			if !isJwtAccepted(history, forgedHistory) {
+				continue
+			}
+			reported[forgedToken.Attack] = true
+			reportJWTWeakness(history, forgedHistory, invalidHistory, token, forgedToken, options)
+		}
+	}
+	auditLog.Info().Int("tokens", len(jwts)).Msg("JWT attacks audit completed")
+}
+
+func reportJWTWeakness(history, forgedHistory, invalidHistory *db.History, token string, forged forgedJWT, options ActiveModuleOptions) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("A JWT sent in a %s request to %s has been replaced by a forged token, and the server has accepted it.\n\n", history.Method, history.URL))
+	sb.WriteString("Details:\n")
+	sb.WriteString(fmt.Sprintf("- Attack: %s\n", forged.Attack))
+	sb.WriteString(fmt.Sprintf("- Technique: %s\n", forged.Description))
+	sb.WriteString(fmt.Sprintf("- Original token: %s\n", token))
+	sb.WriteString(fmt.Sprintf("- Forged token: %s\n\n", forged.Token))
+	if invalidHistory != nil {
+		sb.WriteString(fmt.Sprintf("A token signed with a random key was rejected with a %d status code, while the forged token received a %d status code, the same as the original request.", invalidHistory.StatusCode, forgedHistory.StatusCode))
+	} else {
+		sb.WriteString(fmt.Sprintf("The forged token received a %d status code and a response similar to the one of the original request.", forgedHistory.StatusCode))
+	}
+	log.Warn().Str("url", history.URL).Str("attack", string(forged.Attack)).Msg("Forged JWT accepted")
+	db.CreateIssueFromHistoryAndTemplateWithOptions(forgedHistory, db.JwtWeaknessCode, sb.String(), 90, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{
+		InsertionPoint: string(forged.Attack),
+	})
+}
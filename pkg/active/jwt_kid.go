@@ -0,0 +1,127 @@
+package active
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/tokens"
+	"github.com/rs/zerolog/log"
+)
+
+var requestJwtRegex = regexp.MustCompile(`eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]*`)
+
+// jwtKidMinSimilarity is the minimum response body similarity to consider that a forged token has been accepted
+const jwtKidMinSimilarity = 0.9
+
+// JWTKidInjectionScan looks for JWTs in the request and replays it with tokens whose kid header has been manipulated
+// to make the server use a predictable key, reporting when the forged tokens get accepted.
+func JWTKidInjectionScan(history *db.History, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "jwt-kid-injection").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+
+	jwts := lib.GetUniqueItems(requestJwtRegex.FindAllString(string(history.RawRequest), -1))
+	if len(jwts) == 0 {
+		return
+	}
+	if history.StatusCode >= 400 {
+		auditLog.Debug().Int("status", history.StatusCode).Msg("Skipping JWT kid injection audit as the original request was not successful")
+		return
+	}
+
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for _, token := range jwts {
+		// Ensure that tokens signed with an unknown key are rejected, otherwise any forged token would be accepted
+		invalidToken, err := tokens.ForgeJWTWithKid(token, lib.GenerateRandomString(12), []byte(lib.GenerateRandomString(32)))
+		if err != nil {
+			auditLog.Debug().Err(err).Str("token", token).Msg("Could not parse token, skipping")
+			continue
+		}
+		invalidHistory, err := sendRequestWithReplacedToken(client, history, token, invalidToken, options)
+		if err != nil {
+			auditLog.Error().Err(err).Msg("Error sending request with invalid token")
+			continue
+		}
+		if isJwtAccepted(history, invalidHistory) {
+			auditLog.Info().Str("token", token).Msg("Token with an invalid signature is accepted, skipping kid injection checks")
+			continue
+		}
+
+		for _, payload := range tokens.GetKidInjectionPayloads(lib.GenerateRandomString(16)) {
+			forged, err := tokens.ForgeJWTWithKid(token, payload.Kid, payload.Key)
+			if err != nil {
+				auditLog.Error().Err(err).Str("kid", payload.Kid).Msg("Error forging token")
+				continue
+			}
+			forgedHistory, err := sendRequestWithReplacedToken(client, history, token, forged, options)
+			if err != nil {
+				auditLog.Error().Err(err).Str("kid", payload.Kid).Msg("Error sending request with forged token")
+				continue
+			}
+			if !isJwtAccepted(history, forgedHistory) {
+				continue
+			}
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("A JWT sent in a %s request to %s has been replaced by a forged token whose kid header has been manipulated, and the server has accepted it.\n\n", history.Method, history.URL))
+			sb.WriteString("Details:\n")
+			sb.WriteString(fmt.Sprintf("- Technique: %s\n", payload.Description))
+			sb.WriteString(fmt.Sprintf("- Injected kid: %s\n", payload.Kid))
+			sb.WriteString(fmt.Sprintf("- Signing key used: %q\n", string(payload.Key)))
+			sb.WriteString(fmt.Sprintf("- Original token: %s\n", token))
+			sb.WriteString(fmt.Sprintf("- Forged token: %s\n\n", forged))
+			sb.WriteString(fmt.Sprintf("A token signed with a random key was rejected with a %d status code, while the forged token received a %d status code, the same as the original request.", invalidHistory.StatusCode, forgedHistory.StatusCode))
+
+			db.CreateIssueFromHistoryAndTemplate(forgedHistory, db.JwtKidInjectionCode, sb.String(), 80, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+			break
+		}
+	}
+	auditLog.Info().Int("tokens", len(jwts)).Msg("JWT kid injection audit completed")
+}
+
+// isJwtAccepted compares the response received using a modified token against the original one
+func isJwtAccepted(original *db.History, modified *db.History) bool {
+	if original.StatusCode != modified.StatusCode {
+		return false
+	}
+	return lib.ComputeSimilarity(original.ResponseBody, modified.ResponseBody) >= jwtKidMinSimilarity
+}
+
+func sendRequestWithReplacedToken(client *http.Client, history *db.History, token, replacement string, options ActiveModuleOptions) (*db.History, error) {
+	request, err := http_utils.BuildRequestFromHistoryItem(history)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range request.Header {
+		for i, value := range values {
+			request.Header[name][i] = strings.ReplaceAll(value, token, replacement)
+		}
+	}
+	request.URL.RawQuery = strings.ReplaceAll(request.URL.RawQuery, token, replacement)
+	if len(history.RequestBody) > 0 {
+		body := bytes.ReplaceAll(history.RequestBody, []byte(token), []byte(replacement))
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		request.ContentLength = int64(len(body))
+	}
+
+	response, err := http_utils.SendRequest(client, request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	})
+}
@@ -27,6 +27,7 @@ type Log4ShellInjectionAudit struct {
 	TaskID              uint
 	TaskJobID           uint
 	Mode                scan_options.ScanMode
+	ClientOptions       *http_utils.ClientOptions
 }
 
 type log4ShellAuditItem struct {
@@ -130,6 +131,10 @@ func (a *Log4ShellInjectionAudit) GetHeadersToTest() (headers []string) {
 
 // Run starts the audit
 func (a *Log4ShellInjectionAudit) Run() {
+	if a.InteractionsManager.PendingTestsLimitReached() {
+		log.Warn().Str("url", a.URL).Msg("Skipping Log4Shell injection audit as the maximum number of pending out of band tests has been reached")
+		return
+	}
 	auditItemsChannel := make(chan log4ShellAuditItem)
 	pendingChannel := make(chan int)
 	var wg sync.WaitGroup
@@ -178,7 +183,7 @@ func (a *Log4ShellInjectionAudit) monitor(auditItems chan log4ShellAuditItem, pe
 }
 
 func (a *Log4ShellInjectionAudit) testItem(item log4ShellAuditItem) {
-	client := http_utils.CreateHttpClient()
+	client := http_utils.CreateHttpClientWithOptions(a.ClientOptions)
 	auditLog := log.With().Str("audit", "log4shell").Interface("auditItem", item).Str("url", a.URL).Logger()
 	request, err := http.NewRequest("GET", a.URL, nil)
 	if err != nil {
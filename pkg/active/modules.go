@@ -0,0 +1,179 @@
+package active
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/spf13/viper"
+)
+
+// HistoryItemModule is a module run against a history item. Modules can declare the modules they depend on, which are
+// always run before them, so they can reuse the results they share through the module context.
+type HistoryItemModule struct {
+	Name      string
+	DependsOn []string
+	// Enabled decides from the scan options and the history item if the module should be run, modules without it
+	// are always run
+	Enabled func(ctx *HistoryItemModuleContext) bool
+	Run     func(ctx *HistoryItemModuleContext)
+}
+
+// IsEnabled returns true when the module should be run with the provided context
+func (m HistoryItemModule) IsEnabled(ctx *HistoryItemModuleContext) bool {
+	return m.Enabled == nil || m.Enabled(ctx)
+}
+
+// HistoryItemModuleContext is shared between all the modules run against a history item
+type HistoryItemModuleContext struct {
+	Item                *db.History
+	InteractionsManager *integrations.InteractionsManager
+	PayloadGenerators   []*generation.PayloadGenerator
+	Options             scan_options.HistoryItemScanOptions
+	ActiveOptions       ActiveModuleOptions
+	shared              map[string]interface{}
+	mu                  sync.RWMutex
+}
+
+// Set stores a value to be used by the modules run afterwards
+func (c *HistoryItemModuleContext) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.shared == nil {
+		c.shared = make(map[string]interface{})
+	}
+	c.shared[key] = value
+}
+
+// Get retrieves a value stored by a previously run module
+func (c *HistoryItemModuleContext) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok := c.shared[key]
+	return value, ok
+}
+
+// SortHistoryItemModules returns the modules in an order where every module runs after its dependencies. Modules without
+// dependencies between them keep the order they have been provided in.
+func SortHistoryItemModules(modules []HistoryItemModule) ([]HistoryItemModule, error) {
+	indexes := make(map[string]int, len(modules))
+	for i, module := range modules {
+		if _, exists := indexes[module.Name]; exists {
+			return nil, fmt.Errorf("duplicated module %s", module.Name)
+		}
+		indexes[module.Name] = i
+	}
+
+	pending := make([]int, len(modules))
+	dependents := make([][]int, len(modules))
+	for i, module := range modules {
+		for _, dependency := range module.DependsOn {
+			dependencyIndex, exists := indexes[dependency]
+			if !exists {
+				return nil, fmt.Errorf("module %s depends on unknown module %s", module.Name, dependency)
+			}
+			pending[i]++
+			dependents[dependencyIndex] = append(dependents[dependencyIndex], i)
+		}
+	}
+
+	sorted := make([]HistoryItemModule, 0, len(modules))
+	done := make([]bool, len(modules))
+	for len(sorted) < len(modules) {
+		// Pick the first module in registration order whose dependencies have already been scheduled
+		next := -1
+		for i := range modules {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			return nil, fmt.Errorf("circular dependency detected between modules")
+		}
+		done[next] = true
+		sorted = append(sorted, modules[next])
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+	return sorted, nil
+}
+
+// RunHistoryItemModules runs the modules in dependency order using the provided context
+func RunHistoryItemModules(modules []HistoryItemModule, ctx *HistoryItemModuleContext) error {
+	sorted, err := SortHistoryItemModules(modules)
+	if err != nil {
+		return err
+	}
+	for _, module := range sorted {
+		if !module.IsEnabled(ctx) {
+			continue
+		}
+		ctx.runModule(module)
+	}
+	return nil
+}
+
+// PlanHistoryItemModules returns the names of the modules that would be run using the provided context, in the order
+// they would be run, without running them
+func PlanHistoryItemModules(modules []HistoryItemModule, ctx *HistoryItemModuleContext) ([]string, error) {
+	sorted, err := SortHistoryItemModules(modules)
+	if err != nil {
+		return nil, err
+	}
+	planned := make([]string, 0, len(sorted))
+	for _, module := range sorted {
+		if module.IsEnabled(ctx) {
+			planned = append(planned, module.Name)
+		}
+	}
+	return planned, nil
+}
+
+// runModule runs the module, recording a scan event only when it fails or runs longer than the slow module threshold,
+// so the events of a scan don't grow with every module run against every history item
+func (c *HistoryItemModuleContext) runModule(module HistoryItemModule) {
+	started := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			c.recordEvent(db.ScanEventModuleFailed, module.Name, fmt.Sprintf("Module %s failed against %s: %v", module.Name, c.itemURL(), r), map[string]interface{}{
+				"error": fmt.Sprint(r),
+			})
+			panic(r)
+		}
+		elapsed := time.Since(started)
+		threshold := time.Duration(viper.GetInt("scan.events.slow_module_threshold")) * time.Second
+		if threshold > 0 && elapsed >= threshold {
+			c.recordEvent(db.ScanEventModuleSlow, module.Name, fmt.Sprintf("Module %s took %s against %s", module.Name, elapsed.Round(time.Second), c.itemURL()), map[string]interface{}{
+				"duration_ms": elapsed.Milliseconds(),
+			})
+		}
+	}()
+	module.Run(c)
+}
+
+func (c *HistoryItemModuleContext) itemURL() string {
+	if c.Item == nil {
+		return ""
+	}
+	return c.Item.URL
+}
+
+// recordEvent stores a module event when the modules are run as part of a scan task
+func (c *HistoryItemModuleContext) recordEvent(eventType db.ScanEventType, module, message string, details map[string]interface{}) {
+	if c.Options.TaskID == 0 || c.Item == nil {
+		return
+	}
+	var taskJobID *uint
+	if c.Options.TaskJobID != 0 {
+		taskJobID = &c.Options.TaskJobID
+	}
+	details["history_id"] = c.Item.ID
+	details["url"] = c.Item.URL
+	db.Connection.NewScanEvent(c.Options.TaskID, taskJobID, eventType, module, message, details)
+}
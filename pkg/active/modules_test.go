@@ -0,0 +1,203 @@
+package active
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunHistoryItemModulesDependencyOrder(t *testing.T) {
+	var executed []string
+	modules := []HistoryItemModule{
+		{
+			Name:      "reflected-xss",
+			DependsOn: []string{"reflection-analysis"},
+			Run: func(ctx *HistoryItemModuleContext) {
+				executed = append(executed, "reflected-xss")
+				reflected, ok := ctx.Get("reflected_parameters")
+				assert.True(t, ok)
+				assert.Equal(t, []string{"q"}, reflected)
+			},
+		},
+		{
+			Name: "fingerprint",
+			Run: func(ctx *HistoryItemModuleContext) {
+				executed = append(executed, "fingerprint")
+			},
+		},
+		{
+			Name:      "reflection-analysis",
+			DependsOn: []string{"fingerprint"},
+			Run: func(ctx *HistoryItemModuleContext) {
+				executed = append(executed, "reflection-analysis")
+				ctx.Set("reflected_parameters", []string{"q"})
+			},
+		},
+		{
+			Name: "jsonp-callback",
+			Run: func(ctx *HistoryItemModuleContext) {
+				executed = append(executed, "jsonp-callback")
+			},
+		},
+	}
+
+	err := RunHistoryItemModules(modules, &HistoryItemModuleContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fingerprint", "reflection-analysis", "reflected-xss", "jsonp-callback"}, executed)
+}
+
+func TestHistoryItemModulesEnabled(t *testing.T) {
+	var executed []string
+	modules := []HistoryItemModule{
+		{
+			Name: "always",
+			Run: func(ctx *HistoryItemModuleContext) {
+				executed = append(executed, "always")
+			},
+		},
+		{
+			Name: "experimental",
+			Enabled: func(ctx *HistoryItemModuleContext) bool {
+				return ctx.Options.ExperimentalAudits
+			},
+			Run: func(ctx *HistoryItemModuleContext) {
+				executed = append(executed, "experimental")
+			},
+		},
+	}
+
+	ctx := &HistoryItemModuleContext{}
+	planned, err := PlanHistoryItemModules(modules, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"always"}, planned)
+	assert.Empty(t, executed)
+
+	assert.Nil(t, RunHistoryItemModules(modules, ctx))
+	assert.Equal(t, []string{"always"}, executed)
+
+	ctx.Options.ExperimentalAudits = true
+	planned, err = PlanHistoryItemModules(modules, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"always", "experimental"}, planned)
+}
+
+func TestSortHistoryItemModulesErrors(t *testing.T) {
+	noop := func(ctx *HistoryItemModuleContext) {}
+
+	_, err := SortHistoryItemModules([]HistoryItemModule{
+		{Name: "a", DependsOn: []string{"b"}, Run: noop},
+		{Name: "b", DependsOn: []string{"a"}, Run: noop},
+	})
+	assert.NotNil(t, err)
+
+	_, err = SortHistoryItemModules([]HistoryItemModule{
+		{Name: "a", DependsOn: []string{"missing"}, Run: noop},
+	})
+	assert.NotNil(t, err)
+
+	_, err = SortHistoryItemModules([]HistoryItemModule{
+		{Name: "a", Run: noop},
+		{Name: "a", Run: noop},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestHistoryItemModulesAreValid(t *testing.T) {
+	sorted, err := SortHistoryItemModules(historyItemModules())
+	assert.Nil(t, err)
+	position := make(map[string]int)
+	for i, module := range sorted {
+		position[module.Name] = i
+	}
+	assert.Less(t, position["insertion-points"], position["server-side-templates"])
+	assert.Less(t, position["server-side-templates"], position["client-side"])
+}
+
+func TestPlanHistoryItemScan(t *testing.T) {
+	item := &db.History{
+		URL:            "https://example.com/search?q=test&page=1",
+		Method:         "GET",
+		StatusCode:     200,
+		RequestHeaders: []byte(`{}`),
+	}
+	generators := []*generation.PayloadGenerator{
+		{ID: "always", IssueCode: "sql_injection", Templates: []string{"'", "\""}},
+		{
+			ID:        "fuzz-only",
+			IssueCode: "ssti",
+			Templates: []string{"{{7*7}}"},
+			Launch: generation.LaunchConditions{
+				Conditions: []generation.LaunchCondition{{Type: generation.ScanMode, Value: "fuzz"}},
+			},
+		},
+	}
+	options := scan_options.HistoryItemScanOptions{
+		Mode:            scan_options.ScanModeSmart,
+		InsertionPoints: []string{"parameters"},
+		AuditCategories: scan_options.AuditCategories{ServerSide: true},
+	}
+
+	plan, err := PlanHistoryItemScan(item, generators, options)
+	assert.Nil(t, err)
+	assert.Contains(t, plan.Modules, "server-side-templates")
+	assert.Contains(t, plan.Modules, "server-side-headers")
+	assert.NotContains(t, plan.Modules, "client-side")
+	assert.NotContains(t, plan.Modules, "forbidden-bypass")
+	assert.NotContains(t, plan.Modules, "experimental")
+	assert.Len(t, plan.InsertionPoints, 2)
+	for _, insertionPoint := range plan.InsertionPoints {
+		assert.False(t, insertionPoint.DependsOnAnalysis)
+		assert.Equal(t, 2, insertionPoint.Payloads)
+		assert.True(t, insertionPoint.Generators[0].Launched)
+		assert.False(t, insertionPoint.Generators[1].Launched)
+	}
+	assert.Equal(t, 4, plan.Payloads)
+
+	options.Mode = scan_options.ScanModeFuzz
+	options.AuditCategories = scan_options.AuditCategories{ClientSide: true}
+	plan, err = PlanHistoryItemScan(item, generators, options)
+	assert.Nil(t, err)
+	assert.Contains(t, plan.Modules, "client-side")
+	assert.NotContains(t, plan.Modules, "server-side-templates")
+	assert.Empty(t, plan.InsertionPoints)
+	assert.Equal(t, 0, plan.Payloads)
+}
+
+func TestRunHistoryItemModulesRecordsSlowAndFailedModules(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestRunHistoryItemModulesRecordsSlowAndFailedModules",
+		Title: "TestRunHistoryItemModulesRecordsSlowAndFailedModules",
+	})
+	assert.Nil(t, err)
+	task, err := db.Connection.NewTask(workspace.ID, nil, "module events test", "running", db.TaskTypeScan)
+	assert.Nil(t, err)
+	defer db.Connection.DeleteTask(task.ID)
+	history, err := db.Connection.CreateHistory(&db.History{URL: "https://example.com/modules", Method: "GET", StatusCode: 200, WorkspaceID: &workspace.ID, TaskID: &task.ID})
+	assert.Nil(t, err)
+
+	viper.Set("scan.events.slow_module_threshold", 1)
+	defer viper.Set("scan.events.slow_module_threshold", 60)
+
+	modules := []HistoryItemModule{
+		{Name: "fast", Run: func(ctx *HistoryItemModuleContext) {}},
+		{Name: "slow", Run: func(ctx *HistoryItemModuleContext) { time.Sleep(1100 * time.Millisecond) }},
+		{Name: "failing", Run: func(ctx *HistoryItemModuleContext) { panic("unexpected response") }},
+	}
+	ctx := &HistoryItemModuleContext{Item: history, Options: scan_options.HistoryItemScanOptions{WorkspaceID: workspace.ID, TaskID: task.ID}}
+	assert.Panics(t, func() {
+		RunHistoryItemModules(modules, ctx)
+	})
+
+	events, count, err := db.Connection.ListScanEvents(db.ScanEventFilter{TaskID: task.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, db.ScanEventModuleSlow, events[0].Type)
+	assert.Equal(t, "slow", events[0].Module)
+	assert.Equal(t, db.ScanEventModuleFailed, events[1].Type)
+	assert.Equal(t, "failing", events[1].Module)
+}
@@ -0,0 +1,327 @@
+package active
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/passive"
+	"github.com/pyneda/sukyan/pkg/scan"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// nosqlCondition is a MongoDB query operator injected in place of the value of an insertion point
+type nosqlCondition struct {
+	Operator string
+	Value    interface{}
+}
+
+// nosqlConditionPair holds an operator condition matching any document and one which does not match any
+type nosqlConditionPair struct {
+	True  nosqlCondition
+	False nosqlCondition
+}
+
+// getNoSQLConditionPairs returns the operator condition pairs to test an insertion point, the marker is a random value
+// which is not expected to be stored
+func getNoSQLConditionPairs(marker string) []nosqlConditionPair {
+	return []nosqlConditionPair{
+		{True: nosqlCondition{Operator: "$ne", Value: nil}, False: nosqlCondition{Operator: "$eq", Value: marker}},
+		{True: nosqlCondition{Operator: "$gt", Value: ""}, False: nosqlCondition{Operator: "$lt", Value: ""}},
+		{True: nosqlCondition{Operator: "$regex", Value: ".*"}, False: nosqlCondition{Operator: "$regex", Value: "^" + marker + "$"}},
+	}
+}
+
+// nosqlInjectionStyle is how the operator is injected, which depends on the insertion point
+type nosqlInjectionStyle string
+
+const (
+	// nosqlInjectionStyleJSON replaces the value of a JSON body field by an operator object: {"field": {"$ne": null}}
+	nosqlInjectionStyleJSON nosqlInjectionStyle = "json"
+	// nosqlInjectionStyleQuery replaces a query string parameter by its bracket notation, parsed as an object by
+	// libraries like qs: field[$ne]=
+	nosqlInjectionStyleQuery nosqlInjectionStyle = "query"
+	// nosqlInjectionStyleForm uses the bracket notation in url encoded bodies
+	nosqlInjectionStyleForm nosqlInjectionStyle = "form"
+)
+
+// getNoSQLInjectionStyle returns how operators can be injected in the insertion point
+func getNoSQLInjectionStyle(history *db.History, insertionPoint scan.InsertionPoint) (nosqlInjectionStyle, bool) {
+	switch insertionPoint.Type {
+	case scan.InsertionPointTypeParameter:
+		return nosqlInjectionStyleQuery, true
+	case scan.InsertionPointTypeBody:
+		contentType := strings.ToLower(history.RequestContentType)
+		if strings.Contains(contentType, "application/json") {
+			return nosqlInjectionStyleJSON, true
+		}
+		if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+			return nosqlInjectionStyleForm, true
+		}
+	}
+	return "", false
+}
+
+// buildNoSQLJSONBody returns the JSON body with the value of the field replaced by the operator condition
+func buildNoSQLJSONBody(body []byte, field string, condition nosqlCondition) ([]byte, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	if _, ok := document[field]; !ok {
+		return nil, fmt.Errorf("field %s not found in the JSON body", field)
+	}
+	document[field] = map[string]interface{}{condition.Operator: condition.Value}
+	return json.Marshal(document)
+}
+
+// buildNoSQLBracketValues replaces the parameter by its bracket notation holding the operator condition. Since these
+// values are always parsed as strings, conditions without value are sent empty.
+func buildNoSQLBracketValues(values url.Values, parameter string, condition nosqlCondition) (url.Values, error) {
+	if _, ok := values[parameter]; !ok {
+		return nil, fmt.Errorf("parameter %s not found", parameter)
+	}
+	value := ""
+	if condition.Value != nil {
+		value = fmt.Sprintf("%v", condition.Value)
+	}
+	values.Del(parameter)
+	values.Set(fmt.Sprintf("%s[%s]", parameter, condition.Operator), value)
+	return values, nil
+}
+
+// buildNoSQLQueryURL returns the URL with the query string parameter replaced by the operator condition
+func buildNoSQLQueryURL(rawURL string, parameter string, condition nosqlCondition) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	values, err := buildNoSQLBracketValues(parsed.Query(), parameter, condition)
+	if err != nil {
+		return "", err
+	}
+	parsed.RawQuery = values.Encode()
+	return parsed.String(), nil
+}
+
+// buildNoSQLFormBody returns the url encoded body with the parameter replaced by the operator condition
+func buildNoSQLFormBody(body []byte, parameter string, condition nosqlCondition) ([]byte, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	values, err = buildNoSQLBracketValues(values, parameter, condition)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(values.Encode()), nil
+}
+
+// buildNoSQLRequest builds the request of the history item with the operator condition injected in the insertion
+// point, or the original request when no condition is provided
+func buildNoSQLRequest(history *db.History, insertionPoint scan.InsertionPoint, style nosqlInjectionStyle, condition *nosqlCondition) (*http.Request, error) {
+	requestURL := history.URL
+	body := history.RequestBody
+	var err error
+	if condition != nil {
+		switch style {
+		case nosqlInjectionStyleQuery:
+			requestURL, err = buildNoSQLQueryURL(history.URL, insertionPoint.Name, *condition)
+		case nosqlInjectionStyleJSON:
+			body, err = buildNoSQLJSONBody(history.RequestBody, insertionPoint.Name, *condition)
+		case nosqlInjectionStyleForm:
+			body, err = buildNoSQLFormBody(history.RequestBody, insertionPoint.Name, *condition)
+		default:
+			err = errors.New("unsupported NoSQL injection style")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	var bodyReader io.Reader
+	if len(body) > 0 {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequest(history.Method, requestURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	http_utils.SetRequestHeadersFromHistoryItem(request, history)
+	return request, nil
+}
+
+// isNoSQLInjectionDifferential decides if the responses to an operator condition pair show the operators are evaluated.
+// The responses to both conditions must differ from each other, while one of them matches the original response: the
+// false condition when the original value does not match any document, as happens with authentication bypasses, or
+// the true condition when the original value already matched.
+func isNoSQLInjectionDifferential(comparison scan.BooleanComparison, thresholds scan.BooleanThresholds) bool {
+	if comparison.TrueFalseSimilarity > thresholds.MaxFalseSimilarity {
+		return false
+	}
+	return comparison.TrueSimilarity >= thresholds.MinTrueSimilarity || comparison.FalseSimilarity >= thresholds.MinTrueSimilarity
+}
+
+// nosqlInjectionTester sends the NoSQL injection payloads of an insertion point
+type nosqlInjectionTester struct {
+	client         *http.Client
+	history        *db.History
+	insertionPoint scan.InsertionPoint
+	style          nosqlInjectionStyle
+	options        http_utils.HistoryCreationOptions
+}
+
+func (t nosqlInjectionTester) send(condition *nosqlCondition) (*db.History, error) {
+	request, err := buildNoSQLRequest(t.history, t.insertionPoint, t.style, condition)
+	if err != nil {
+		return nil, err
+	}
+	response, err := t.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, t.options)
+}
+
+func (t nosqlInjectionTester) signature(history *db.History, reflections ...string) scan.ResponseSignature {
+	return scan.NewResponseSignature(history.StatusCode, history.ResponseBody, reflections...)
+}
+
+// nosqlErrorMatch returns the database error found in the response which was not already in the original response
+func nosqlErrorMatch(baseline *db.History, history *db.History) *passive.DatabaseErrorMatch {
+	match := passive.SearchDatabaseErrors(string(history.ResponseBody))
+	if match == nil || strings.Contains(string(baseline.ResponseBody), match.MatchStr) {
+		return nil
+	}
+	return match
+}
+
+// NoSQLInjectionScan injects MongoDB query operators in the query string parameters and the JSON and url encoded body
+// fields, reporting NoSQL injection when the responses to an always true and an always false operator differ, which
+// can lead to authentication and authorization bypasses, or when the operators cause database errors
+func NoSQLInjectionScan(history *db.History, insertionPoints []scan.InsertionPoint, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "nosql-injection").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
+	}
+	thresholds := scan.GetBooleanThresholds()
+	historyOptions := http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	}
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, insertionPoint := range insertionPoints {
+		insertionPoint := insertionPoint
+		style, ok := getNoSQLInjectionStyle(history, insertionPoint)
+		if !ok {
+			continue
+		}
+		p.Go(func() {
+			tester := nosqlInjectionTester{
+				client:         client,
+				history:        history,
+				insertionPoint: insertionPoint,
+				style:          style,
+				options:        historyOptions,
+			}
+			pointLog := auditLog.With().Str("insertion_point", insertionPoint.String()).Str("style", string(style)).Logger()
+			baseline, err := tester.send(nil)
+			if err != nil {
+				pointLog.Error().Err(err).Msg("Error sending the NoSQL injection baseline request")
+				return
+			}
+			baselineSignature := tester.signature(baseline, insertionPoint.Value)
+			marker := lib.GenerateRandomLowercaseString(10)
+
+			// reportError reports the issue when the condition caused a database error not present in the original response
+			reportError := func(item *db.History, condition nosqlCondition) bool {
+				match := nosqlErrorMatch(baseline, item)
+				if match == nil {
+					return false
+				}
+				pointLog.Info().Str("database", match.DatabaseName).Str("operator", condition.Operator).Msg("NoSQL injection database error found")
+				details := fmt.Sprintf("The %s `%s` has been tested injecting MongoDB query operators using the %s syntax.\n\nThe condition `%s` caused the following %s error in the response, which indicates the value is used to build a database query:\n\n%s", insertionPoint.Type, insertionPoint.Name, style, formatNoSQLCondition(insertionPoint.Name, style, condition), match.DatabaseName, match.MatchStr)
+				db.CreateIssueFromHistoryAndTemplateWithOptions(item, db.NosqlInjectionCode, details, 75, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
+				return true
+			}
+
+			// Unknown operators make MongoDB fail with an error, which is often returned in the response
+			probe := nosqlCondition{Operator: "$" + marker, Value: 1}
+			probeHistory, err := tester.send(&probe)
+			if err != nil {
+				pointLog.Error().Err(err).Str("operator", probe.Operator).Msg("Error sending NoSQL injection payload")
+				return
+			}
+			if reportError(probeHistory, probe) {
+				return
+			}
+
+			for _, pair := range getNoSQLConditionPairs(marker) {
+				var trueHistory, falseHistory *db.History
+				var comparison scan.BooleanComparison
+				differential := true
+				for attempt := 0; attempt <= thresholds.Confirmations && differential; attempt++ {
+					trueHistory, err = tester.send(&pair.True)
+					if err != nil {
+						pointLog.Error().Err(err).Str("operator", pair.True.Operator).Msg("Error sending NoSQL injection payload")
+						return
+					}
+					falseHistory, err = tester.send(&pair.False)
+					if err != nil {
+						pointLog.Error().Err(err).Str("operator", pair.False.Operator).Msg("Error sending NoSQL injection payload")
+						return
+					}
+					if attempt == 0 && (reportError(trueHistory, pair.True) || reportError(falseHistory, pair.False)) {
+						return
+					}
+					comparison = scan.CompareBooleanResponses(
+						baselineSignature,
+						tester.signature(trueHistory, insertionPoint.Value, marker),
+						tester.signature(falseHistory, insertionPoint.Value, marker),
+					)
+					differential = isNoSQLInjectionDifferential(comparison, thresholds)
+				}
+				if !differential {
+					continue
+				}
+
+				pointLog.Info().Str("true", pair.True.Operator).Str("false", pair.False.Operator).Msg("NoSQL injection found")
+				behaviour := "The response to the always true condition differs from the original response, while the always false condition one matches it. This is the behaviour expected when the original value does not match any document and the operator makes the query match all of them, which can allow bypassing authentication or authorization checks."
+				if comparison.TrueSimilarity >= thresholds.MinTrueSimilarity {
+					behaviour = "The response to the always true condition matches the original response, while the always false condition one differs from it, which indicates the operators are evaluated as part of the database query."
+				}
+				details := fmt.Sprintf("The %s `%s` has been tested injecting MongoDB query operators using the %s syntax.\n\nThe always true condition `%s` and the always false condition `%s` have been compared with the original value: the true condition response is %.0f%% similar to the original one and the false condition response is %.0f%% similar.\n\n%s The behaviour has been consistent across %d attempts.", insertionPoint.Type, insertionPoint.Name, style, formatNoSQLCondition(insertionPoint.Name, style, pair.True), formatNoSQLCondition(insertionPoint.Name, style, pair.False), comparison.TrueSimilarity*100, comparison.FalseSimilarity*100, behaviour, thresholds.Confirmations+1)
+				db.CreateIssueFromHistoryAndTemplateWithOptions(trueHistory, db.NosqlInjectionCode, details, 80, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
+				return
+			}
+		})
+	}
+	p.Wait()
+	auditLog.Info().Msg("NoSQL injection audit completed")
+}
+
+// formatNoSQLCondition returns how the condition is sent for the insertion point, to be shown in the issue details
+func formatNoSQLCondition(name string, style nosqlInjectionStyle, condition nosqlCondition) string {
+	if style == nosqlInjectionStyleJSON {
+		encoded, _ := json.Marshal(map[string]interface{}{name: map[string]interface{}{condition.Operator: condition.Value}})
+		return string(encoded)
+	}
+	value := ""
+	if condition.Value != nil {
+		value = fmt.Sprintf("%v", condition.Value)
+	}
+	return fmt.Sprintf("%s[%s]=%s", name, condition.Operator, value)
+}
@@ -0,0 +1,110 @@
+package active
+
+import (
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildNoSQLJSONBody(t *testing.T) {
+	body := []byte(`{"username":"admin","password":"secret"}`)
+	injected, err := buildNoSQLJSONBody(body, "password", nosqlCondition{Operator: "$ne", Value: nil})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"username":"admin","password":{"$ne":null}}`, string(injected))
+
+	injected, err = buildNoSQLJSONBody(body, "username", nosqlCondition{Operator: "$regex", Value: ".*"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"username":{"$regex":".*"},"password":"secret"}`, string(injected))
+
+	_, err = buildNoSQLJSONBody(body, "email", nosqlCondition{Operator: "$ne", Value: nil})
+	assert.NotNil(t, err)
+	_, err = buildNoSQLJSONBody([]byte(`[1, 2]`), "username", nosqlCondition{Operator: "$ne", Value: nil})
+	assert.NotNil(t, err)
+}
+
+func TestBuildNoSQLQueryURL(t *testing.T) {
+	injected, err := buildNoSQLQueryURL("https://example.com/login?user=admin&pass=secret", "pass", nosqlCondition{Operator: "$ne", Value: nil})
+	assert.Nil(t, err)
+	parsed, err := url.Parse(injected)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"user": {"admin"}, "pass[$ne]": {""}}, parsed.Query())
+
+	injected, err = buildNoSQLQueryURL("https://example.com/search?q=shoes", "q", nosqlCondition{Operator: "$gt", Value: ""})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/search?q%5B%24gt%5D=", injected)
+
+	_, err = buildNoSQLQueryURL("https://example.com/search?q=shoes", "page", nosqlCondition{Operator: "$gt", Value: ""})
+	assert.NotNil(t, err)
+}
+
+func TestBuildNoSQLFormBody(t *testing.T) {
+	injected, err := buildNoSQLFormBody([]byte("user=admin&pass=secret"), "pass", nosqlCondition{Operator: "$regex", Value: ".*"})
+	assert.Nil(t, err)
+	values, err := url.ParseQuery(string(injected))
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"user": {"admin"}, "pass[$regex]": {".*"}}, values)
+}
+
+func TestBuildNoSQLRequest(t *testing.T) {
+	history := &db.History{
+		URL:                "https://example.com/api/login?next=home",
+		Method:             "POST",
+		RequestHeaders:     []byte(`{"Content-Type":["application/json"]}`),
+		RequestContentType: "application/json",
+		RequestBody:        []byte(`{"username":"admin","password":"secret"}`),
+	}
+	insertionPoint := scan.InsertionPoint{Type: scan.InsertionPointTypeBody, Name: "password", Value: "secret"}
+	style, ok := getNoSQLInjectionStyle(history, insertionPoint)
+	assert.True(t, ok)
+	assert.Equal(t, nosqlInjectionStyleJSON, style)
+
+	request, err := buildNoSQLRequest(history, insertionPoint, style, &nosqlCondition{Operator: "$ne", Value: nil})
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(request.Body)
+	assert.JSONEq(t, `{"username":"admin","password":{"$ne":null}}`, string(body))
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+	assert.Equal(t, history.URL, request.URL.String())
+
+	request, err = buildNoSQLRequest(history, insertionPoint, style, nil)
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(request.Body)
+	assert.Equal(t, history.RequestBody, body)
+
+	queryPoint := scan.InsertionPoint{Type: scan.InsertionPointTypeParameter, Name: "next", Value: "home"}
+	style, ok = getNoSQLInjectionStyle(history, queryPoint)
+	assert.True(t, ok)
+	assert.Equal(t, nosqlInjectionStyleQuery, style)
+
+	_, ok = getNoSQLInjectionStyle(history, scan.InsertionPoint{Type: scan.InsertionPointTypeHeader, Name: "Content-Type"})
+	assert.False(t, ok)
+}
+
+func TestIsNoSQLInjectionDifferential(t *testing.T) {
+	thresholds := scan.BooleanThresholds{MinTrueSimilarity: 0.9, MaxFalseSimilarity: 0.8}
+	tests := []struct {
+		name       string
+		comparison scan.BooleanComparison
+		expected   bool
+	}{
+		{"authentication bypass", scan.BooleanComparison{TrueSimilarity: 0.3, FalseSimilarity: 0.98, TrueFalseSimilarity: 0.3}, true},
+		{"filter matching all", scan.BooleanComparison{TrueSimilarity: 0.95, FalseSimilarity: 0.4, TrueFalseSimilarity: 0.4}, true},
+		{"operators ignored", scan.BooleanComparison{TrueSimilarity: 0.99, FalseSimilarity: 0.99, TrueFalseSimilarity: 0.99}, false},
+		{"operators rejected", scan.BooleanComparison{TrueSimilarity: 0.2, FalseSimilarity: 0.2, TrueFalseSimilarity: 0.97}, false},
+		{"unstable responses", scan.BooleanComparison{TrueSimilarity: 0.5, FalseSimilarity: 0.6, TrueFalseSimilarity: 0.5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isNoSQLInjectionDifferential(tt.comparison, thresholds))
+		})
+	}
+}
+
+func TestFormatNoSQLCondition(t *testing.T) {
+	assert.Equal(t, `{"password":{"$ne":null}}`, formatNoSQLCondition("password", nosqlInjectionStyleJSON, nosqlCondition{Operator: "$ne", Value: nil}))
+	assert.Equal(t, "password[$ne]=", formatNoSQLCondition("password", nosqlInjectionStyleQuery, nosqlCondition{Operator: "$ne", Value: nil}))
+	assert.Equal(t, "q[$regex]=.*", formatNoSQLCondition("q", nosqlInjectionStyleForm, nosqlCondition{Operator: "$regex", Value: ".*"}))
+}
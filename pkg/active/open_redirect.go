@@ -3,6 +3,8 @@ package active
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/pyneda/sukyan/db"
@@ -15,14 +17,118 @@ import (
 
 const openRedirecTestDomain = "sukyan.com"
 
-func OpenRedirectScan(history *db.History, options ActiveModuleOptions, insertionPoints []scan.InsertionPoint) (bool, error) {
-	auditLog := log.With().Str("audit", "open-redirect").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+// openRedirectDiscoveryParameters is the number of common redirect parameters added to the requests without any
+// redirect-like parameter in smart mode, fuzz mode adds all of them
+const openRedirectDiscoveryParameters = 20
+
+type redirectKind string
+
+const (
+	redirectKindLocation    redirectKind = "Location header"
+	redirectKindMetaRefresh redirectKind = "meta refresh"
+	redirectKindJavaScript  redirectKind = "JavaScript"
+)
+
+// redirect is a redirection found in a response
+type redirect struct {
+	Kind   redirectKind
+	Target string
+}
+
+var (
+	metaRefreshRegex = regexp.MustCompile(`(?i)<meta[^>]+http-equiv\s*=\s*["']?refresh["']?[^>]*content\s*=\s*["']?\s*\d*\s*;?\s*url\s*=\s*['"]?([^"'>\s]+)`)
+	jsRedirectRegex  = regexp.MustCompile(`(?i)(?:window\.|document\.|self\.|top\.)?location(?:\.href)?\s*=\s*["']([^"']+)["']|location\.(?:replace|assign)\(\s*["']([^"']+)["']`)
+)
+
+// buildOpenRedirectPayloads returns the payloads pointing to the test domain, including the ones bypassing checks
+// that only verify the redirect URL starts with the original host
+func buildOpenRedirectPayloads(originalHost string) []string {
 	payloads := []string{
 		"https://" + openRedirecTestDomain,
 		"//" + openRedirecTestDomain,
 		"https%3A%2F%2F" + openRedirecTestDomain,
 		"//%5c" + openRedirecTestDomain,
+		"/\\" + openRedirecTestDomain,
+	}
+	if originalHost != "" {
+		payloads = append(payloads,
+			fmt.Sprintf("https://%s.%s", originalHost, openRedirecTestDomain),
+			fmt.Sprintf("https://%s@%s", originalHost, openRedirecTestDomain),
+		)
+	}
+	return payloads
+}
+
+// findRedirects returns the redirections of a response, either through the Location header of 3xx responses or
+// through meta refresh tags and JavaScript location changes in the body
+func findRedirects(statusCode int, headers http.Header, body []byte) []redirect {
+	var redirects []redirect
+	if statusCode >= 300 && statusCode < 400 {
+		if location := headers.Get("Location"); location != "" {
+			redirects = append(redirects, redirect{Kind: redirectKindLocation, Target: location})
+		}
+	}
+	for _, match := range metaRefreshRegex.FindAllSubmatch(body, -1) {
+		redirects = append(redirects, redirect{Kind: redirectKindMetaRefresh, Target: string(match[1])})
+	}
+	for _, match := range jsRedirectRegex.FindAllSubmatch(body, -1) {
+		target := match[1]
+		if len(target) == 0 {
+			target = match[2]
+		}
+		redirects = append(redirects, redirect{Kind: redirectKindJavaScript, Target: string(target)})
+	}
+	return redirects
+}
+
+// isRedirectToHost checks if a redirect target, resolved as a browser would, points to the host or any of its subdomains
+func isRedirectToHost(target, host string) bool {
+	target = strings.TrimSpace(target)
+	// Browsers treat backslashes as slashes in special URLs, so /\host is handled as //host
+	target = strings.ReplaceAll(target, "\\", "/")
+	if strings.HasPrefix(target, "//") {
+		target = "https:" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	hostname := strings.ToLower(u.Hostname())
+	host = strings.ToLower(host)
+	return hostname == host || strings.HasSuffix(hostname, "."+host)
+}
+
+// findOpenRedirect returns the first redirection of the response to the test domain
+func findOpenRedirect(statusCode int, headers http.Header, body []byte) (redirect, bool) {
+	for _, r := range findRedirects(statusCode, headers, body) {
+		if isRedirectToHost(r.Target, openRedirecTestDomain) {
+			return r, true
+		}
+	}
+	return redirect{}, false
+}
+
+func openRedirectConfidence(kind redirectKind) int {
+	switch kind {
+	case redirectKindLocation:
+		return 90
+	case redirectKindMetaRefresh:
+		return 85
+	default:
+		return 75
 	}
+}
+
+// OpenRedirectScan checks if the redirect-like insertion points, or all of them in fuzz mode, can be used to redirect
+// users to an external host. When the request has no redirect-like parameters, common redirect parameters are added
+// to discover hidden ones.
+func OpenRedirectScan(history *db.History, options ActiveModuleOptions, insertionPoints []scan.InsertionPoint) (bool, error) {
+	auditLog := log.With().Str("audit", "open-redirect").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	var originalHost string
+	if u, err := url.Parse(history.URL); err == nil {
+		originalHost = u.Hostname()
+	}
+	payloads := buildOpenRedirectPayloads(originalHost)
 
 	scanInsertionPoints := []scan.InsertionPoint{}
 	switch options.ScanMode {
@@ -46,15 +152,17 @@ func OpenRedirectScan(history *db.History, options ActiveModuleOptions, insertio
 
 	}
 
-	if len(scanInsertionPoints) == 0 {
-		auditLog.Info().Msg("No interesting insertion points to test for open redirect")
-		return false, nil
-	}
-	client := http_utils.CreateHttpClient()
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
 	// ensure that the client does not follow redirects
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
 	}
+
+	if len(scanInsertionPoints) == 0 {
+		auditLog.Info().Msg("No interesting insertion points to test for open redirect, trying common redirect parameters")
+		return openRedirectParameterDiscovery(client, history, options)
+	}
+
 	for _, insertionPoint := range scanInsertionPoints {
 		for _, payload := range payloads {
 			auditLog.Info().Str("insertionPoint", insertionPoint.Value).Str("payload", payload).Msg("Testing insertion point for open redirect")
@@ -69,38 +177,117 @@ func OpenRedirectScan(history *db.History, options ActiveModuleOptions, insertio
 				auditLog.Error().Err(err).Msg("Failed to create request from insertion points")
 				continue
 			}
-			response, err := client.Do(req)
-			if err != nil {
-				auditLog.Error().Err(err).Msg("Failed to send request")
-				continue
-			}
-			new, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
-				Source:              db.SourceScanner,
-				WorkspaceID:         options.WorkspaceID,
-				TaskID:              options.TaskID,
-				CreateNewBodyStream: true,
-			})
-
-			if err != nil {
-				auditLog.Error().Err(err).Msg("Failed to create history from response")
+			new, found, ok := sendOpenRedirectRequest(client, req, options)
+			if !ok {
 				continue
 			}
+			auditLog.Info().Str("insertionPoint", insertionPoint.String()).Str("payload", payload).Str("kind", string(found.Kind)).Msg("Open redirect found")
+			details := fmt.Sprintf("Using the payload %s in the insertion point %s, the server redirected the request to %s through a %s redirection.", payload, insertionPoint.String(), found.Target, found.Kind)
+			db.CreateIssueFromHistoryAndTemplateWithOptions(new, db.OpenRedirectCode, details, openRedirectConfidence(found.Kind), "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
+			return true, nil
+		}
+	}
+	return false, nil
+}
 
-			if new.StatusCode >= 300 && new.StatusCode < 400 {
-				newLocation := response.Header.Get("Location")
-				if newLocation != "" && newLocation == payload || strings.Contains(newLocation, openRedirecTestDomain) {
-					auditLog.Info().Str("insertionPoint", insertionPoint.String()).Str("payload", payload).Msg("Open redirect found")
+// getOpenRedirectDiscoveryParameters returns the common redirect parameters to add to a request according to the scan mode
+func getOpenRedirectDiscoveryParameters(history *db.History, mode scan_options.ScanMode) []string {
+	if mode == scan_options.ScanModeFast || history.Method != http.MethodGet {
+		return nil
+	}
+	var existing url.Values
+	if u, err := url.Parse(history.URL); err == nil {
+		existing = u.Query()
+	}
+	var parameters []string
+	seen := make(map[string]bool)
+	for _, parameter := range scan.GetCommonOpenRedirectParameters() {
+		if seen[parameter] || existing.Has(parameter) {
+			continue
+		}
+		seen[parameter] = true
+		parameters = append(parameters, parameter)
+	}
+	if mode != scan_options.ScanModeFuzz && len(parameters) > openRedirectDiscoveryParameters {
+		parameters = parameters[:openRedirectDiscoveryParameters]
+	}
+	return parameters
+}
 
-					details := fmt.Sprintf("Using the payload %s in the insertion point %s, the server redirected the request to %s.", payload, insertionPoint.String(), newLocation)
-					db.CreateIssueFromHistoryAndTemplate(new, db.OpenRedirectCode, details, 90, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+// openRedirectParameterDiscovery adds all the common redirect parameters at once to the request and, when it
+// redirects to the test domain, tests them one by one to find out which one is used
+func openRedirectParameterDiscovery(client *http.Client, history *db.History, options ActiveModuleOptions) (bool, error) {
+	auditLog := log.With().Str("audit", "open-redirect").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	parameters := getOpenRedirectDiscoveryParameters(history, options.ScanMode)
+	if len(parameters) == 0 {
+		return false, nil
+	}
+	payload := "https://" + openRedirecTestDomain
 
-					return true, nil
+	discoveryURL := history.URL
+	for _, parameter := range parameters {
+		var err error
+		discoveryURL, err = lib.BuildURLWithParam(discoveryURL, parameter, payload, true)
+		if err != nil {
+			return false, err
+		}
+	}
+	req, err := http_utils.BuildRequestFromHistoryItem(history)
+	if err != nil {
+		return false, err
+	}
+	if req.URL, err = url.Parse(discoveryURL); err != nil {
+		return false, err
+	}
+	new, found, ok := sendOpenRedirectRequest(client, req, options)
+	if !ok {
+		return false, nil
+	}
 
-				}
+	usedParameters := parameters
+	for _, parameter := range parameters {
+		parameterURL, err := lib.BuildURLWithParam(history.URL, parameter, payload, true)
+		if err != nil {
+			continue
+		}
+		req, err := http_utils.BuildRequestFromHistoryItem(history)
+		if err != nil {
+			continue
+		}
+		if req.URL, err = url.Parse(parameterURL); err != nil {
+			continue
+		}
+		if parameterHistory, parameterFound, ok := sendOpenRedirectRequest(client, req, options); ok {
+			new, found, usedParameters = parameterHistory, parameterFound, []string{parameter}
+			break
+		}
+	}
 
-			}
+	auditLog.Info().Strs("parameters", usedParameters).Str("kind", string(found.Kind)).Msg("Open redirect found through a discovered parameter")
+	details := fmt.Sprintf("Adding the payload %s in the %s parameter(s), which were not present in the original request, the server redirected the request to %s through a %s redirection.", payload, strings.Join(usedParameters, ", "), found.Target, found.Kind)
+	db.CreateIssueFromHistoryAndTemplate(new, db.OpenRedirectCode, details, openRedirectConfidence(found.Kind), "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+	return true, nil
+}
 
-		}
+// sendOpenRedirectRequest sends the request without following redirects and checks if the response redirects to the
+// test domain
+func sendOpenRedirectRequest(client *http.Client, req *http.Request, options ActiveModuleOptions) (*db.History, redirect, bool) {
+	response, err := client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Str("url", req.URL.String()).Msg("Failed to send open redirect request")
+		return nil, redirect{}, false
 	}
-	return false, nil
+	new, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: true,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("url", req.URL.String()).Msg("Failed to create history from response")
+		return nil, redirect{}, false
+	}
+	found, ok := findOpenRedirect(new.StatusCode, response.Header, new.ResponseBody)
+	return new, found, ok
 }
@@ -0,0 +1,95 @@
+package active
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRedirectToHost(t *testing.T) {
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"https://sukyan.com", true},
+		{"https://sukyan.com/path?a=b", true},
+		{"//sukyan.com", true},
+		{"/\\sukyan.com", true},
+		{"\\\\sukyan.com", true},
+		{"HTTPS://SUKYAN.COM", true},
+		{"https://trusted.com.sukyan.com/", true},
+		{"https://trusted.com@sukyan.com", true},
+		{"https://sukyan.com.trusted.com", false},
+		{"https://trusted.com/?next=https://sukyan.com", false},
+		{"/login?next=//sukyan.com", false},
+		{"https%3A%2F%2Fsukyan.com", false},
+		{"https://notsukyan.com", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isRedirectToHost(tt.target, openRedirecTestDomain), tt.target)
+	}
+}
+
+func TestFindOpenRedirect(t *testing.T) {
+	headers := http.Header{"Location": []string{"//sukyan.com"}}
+	found, ok := findOpenRedirect(302, headers, nil)
+	assert.True(t, ok)
+	assert.Equal(t, redirectKindLocation, found.Kind)
+
+	// The Location header is only followed on 3xx responses
+	_, ok = findOpenRedirect(200, headers, nil)
+	assert.False(t, ok)
+
+	found, ok = findOpenRedirect(200, http.Header{}, []byte(`<html><head><meta http-equiv="refresh" content="0; url=https://trusted.com.sukyan.com/"></head></html>`))
+	assert.True(t, ok)
+	assert.Equal(t, redirectKindMetaRefresh, found.Kind)
+	assert.Equal(t, "https://trusted.com.sukyan.com/", found.Target)
+
+	found, ok = findOpenRedirect(200, http.Header{}, []byte(`<script>window.location.href = "https://sukyan.com";</script>`))
+	assert.True(t, ok)
+	assert.Equal(t, redirectKindJavaScript, found.Kind)
+
+	found, ok = findOpenRedirect(200, http.Header{}, []byte(`<script>location.replace('//sukyan.com')</script>`))
+	assert.True(t, ok)
+	assert.Equal(t, redirectKindJavaScript, found.Kind)
+
+	// Reflections that do not redirect are not reported
+	_, ok = findOpenRedirect(200, http.Header{}, []byte(`<a href="https://sukyan.com">link</a><script>var next = "https://sukyan.com";</script>`))
+	assert.False(t, ok)
+	_, ok = findOpenRedirect(302, http.Header{"Location": []string{"/login?next=https://sukyan.com"}}, nil)
+	assert.False(t, ok)
+}
+
+func TestBuildOpenRedirectPayloads(t *testing.T) {
+	payloads := buildOpenRedirectPayloads("trusted.com")
+	assert.Contains(t, payloads, "//sukyan.com")
+	assert.Contains(t, payloads, "https://trusted.com.sukyan.com")
+	assert.Contains(t, payloads, "https://trusted.com@sukyan.com")
+	for _, payload := range payloads[:2] {
+		assert.True(t, isRedirectToHost(payload, openRedirecTestDomain), payload)
+	}
+	assert.Len(t, buildOpenRedirectPayloads(""), 5)
+}
+
+func TestGetOpenRedirectDiscoveryParameters(t *testing.T) {
+	history := &db.History{URL: "https://example.com/login?next=/home", Method: "GET"}
+	assert.Empty(t, getOpenRedirectDiscoveryParameters(history, scan_options.ScanModeFast))
+
+	smart := getOpenRedirectDiscoveryParameters(history, scan_options.ScanModeSmart)
+	assert.Len(t, smart, openRedirectDiscoveryParameters)
+	assert.NotContains(t, smart, "next")
+	assert.Equal(t, "url", smart[0])
+
+	fuzz := getOpenRedirectDiscoveryParameters(history, scan_options.ScanModeFuzz)
+	assert.Greater(t, len(fuzz), len(smart))
+	seen := make(map[string]bool)
+	for _, parameter := range fuzz {
+		assert.False(t, seen[parameter], parameter)
+		seen[parameter] = true
+	}
+
+	assert.Empty(t, getOpenRedirectDiscoveryParameters(&db.History{URL: "https://example.com/login", Method: "POST"}, scan_options.ScanModeFuzz))
+}
@@ -0,0 +1,70 @@
+package active
+
+import (
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/pyneda/sukyan/pkg/scan"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+)
+
+// HistoryItemScanPlan describes what the active scan of a history item would test
+type HistoryItemScanPlan struct {
+	HistoryID       uint                      `json:"history_id"`
+	URL             string                    `json:"url"`
+	Method          string                    `json:"method"`
+	Modules         []string                  `json:"modules"`
+	InsertionPoints []scan.InsertionPointPlan `json:"insertion_points"`
+	Payloads        int                       `json:"payloads"`
+}
+
+// PlanHistoryItemScan goes through the same module, insertion point and payload generator selection as ScanHistoryItem
+// without sending any request. Since insertion points are not analyzed, the ones that would only be audited when found
+// to be dynamic or reflected are included and flagged as depending on the analysis.
+func PlanHistoryItemScan(item *db.History, payloadGenerators []*generation.PayloadGenerator, options scan_options.HistoryItemScanOptions) (HistoryItemScanPlan, error) {
+	plan := HistoryItemScanPlan{
+		HistoryID: item.ID,
+		URL:       item.URL,
+		Method:    item.Method,
+	}
+	ctx := &HistoryItemModuleContext{
+		Item:              item,
+		PayloadGenerators: payloadGenerators,
+		Options:           options,
+	}
+	modules, err := PlanHistoryItemModules(historyItemModules(), ctx)
+	if err != nil {
+		return plan, err
+	}
+	plan.Modules = modules
+	if !lib.SliceContains(modules, "server-side-templates") {
+		return plan, nil
+	}
+
+	insertionPoints, err := scan.GetInsertionPoints(item, options.InsertionPoints)
+	if err != nil {
+		return plan, err
+	}
+	insertionPoints = scan.FilterInsertionPoints(insertionPoints, options.InsertionPointFilter)
+	var insertionPointsToAudit []scan.InsertionPoint
+	dependsOnAnalysis := make(map[string]bool)
+	for _, insertionPoint := range insertionPoints {
+		if isInsertionPointAudited(options.Mode, insertionPoint) {
+			insertionPointsToAudit = append(insertionPointsToAudit, insertionPoint)
+		} else if options.Mode == scan_options.ScanModeSmart || options.Mode == scan_options.ScanModeFast {
+			insertionPointsToAudit = append(insertionPointsToAudit, insertionPoint)
+			dependsOnAnalysis[insertionPoint.String()] = true
+		}
+	}
+
+	scanner := scan.TemplateScanner{
+		WorkspaceID: options.WorkspaceID,
+		Mode:        options.Mode,
+	}
+	plan.InsertionPoints = scanner.Plan(item, payloadGenerators, insertionPointsToAudit, options)
+	for i := range plan.InsertionPoints {
+		plan.InsertionPoints[i].DependsOnAnalysis = dependsOnAnalysis[plan.InsertionPoints[i].InsertionPoint]
+		plan.Payloads += plan.InsertionPoints[i].Payloads
+	}
+	return plan, nil
+}
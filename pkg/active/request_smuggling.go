@@ -0,0 +1,314 @@
+package active
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+)
+
+// https://portswigger.net/web-security/request-smuggling/finding
+
+// requestSmugglingTimeout is the time waited for a response before considering that the server is waiting for the rest
+// of the request
+const requestSmugglingTimeout = 5 * time.Second
+
+type smugglingTechnique string
+
+const (
+	// smugglingCLTE means the front-end uses the Content-Length header and the back-end the Transfer-Encoding one
+	smugglingCLTE smugglingTechnique = "CL.TE"
+	// smugglingTECL means the front-end uses the Transfer-Encoding header and the back-end the Content-Length one
+	smugglingTECL smugglingTechnique = "TE.CL"
+)
+
+// smugglingTransferEncoding is a way of writing the Transfer-Encoding header, as obfuscated versions can be processed
+// by only one of the servers
+type smugglingTransferEncoding struct {
+	Name    string
+	Headers []http_utils.RawHeader
+}
+
+var smugglingTransferEncodings = []smugglingTransferEncoding{
+	{Name: "standard", Headers: []http_utils.RawHeader{{Name: "Transfer-Encoding", Value: "chunked"}}},
+	{Name: "space before colon", Headers: []http_utils.RawHeader{{Name: "Transfer-Encoding ", Value: "chunked"}}},
+	{Name: "tab separator", Headers: []http_utils.RawHeader{{Name: "Transfer-Encoding", Value: "\tchunked"}}},
+	{Name: "duplicated header", Headers: []http_utils.RawHeader{{Name: "Transfer-Encoding", Value: "chunked"}, {Name: "Transfer-Encoding", Value: "identity"}}},
+	{Name: "lowercase name", Headers: []http_utils.RawHeader{{Name: "transfer-encoding", Value: "chunked"}}},
+}
+
+func getSmugglingTransferEncodingsForMode(mode scan_options.ScanMode) []smugglingTransferEncoding {
+	switch mode {
+	case scan_options.ScanModeFast:
+		return smugglingTransferEncodings[:1]
+	case scan_options.ScanModeSmart:
+		return smugglingTransferEncodings[:3]
+	default:
+		return smugglingTransferEncodings
+	}
+}
+
+// smugglingTarget holds what is needed to build the raw requests sent to the target
+type smugglingTarget struct {
+	Host    string
+	Path    string
+	Headers []http_utils.RawHeader
+}
+
+// rawRequestSender sends a raw request on a new connection
+type rawRequestSender func(request http_utils.RawRequest) (*http_utils.RawResponse, error)
+
+// buildSmugglingRequest builds a POST request with both the Content-Length and Transfer-Encoding headers
+func buildSmugglingRequest(target smugglingTarget, encoding smugglingTransferEncoding, contentLength int, body string) http_utils.RawRequest {
+	headers := []http_utils.RawHeader{{Name: "Host", Value: target.Host}}
+	headers = append(headers, target.Headers...)
+	headers = append(headers,
+		http_utils.RawHeader{Name: "Content-Type", Value: "application/x-www-form-urlencoded"},
+		http_utils.RawHeader{Name: "Content-Length", Value: fmt.Sprintf("%d", contentLength)},
+	)
+	headers = append(headers, encoding.Headers...)
+	headers = append(headers, http_utils.RawHeader{Name: "Connection", Value: "close"})
+	return http_utils.RawRequest{Method: "POST", Target: target.Path, Headers: headers, Body: body}
+}
+
+// buildSmugglingControlRequest returns a request whose body is the same for both headers, which should be answered
+// without delays
+func buildSmugglingControlRequest(target smugglingTarget, encoding smugglingTransferEncoding) http_utils.RawRequest {
+	body := "0\r\n\r\n"
+	return buildSmugglingRequest(target, encoding, len(body), body)
+}
+
+// buildSmugglingTimingRequest returns a request which makes the back-end wait for the rest of the body when the servers
+// are vulnerable to the technique, while it is rejected or answered at once otherwise
+func buildSmugglingTimingRequest(target smugglingTarget, encoding smugglingTransferEncoding, technique smugglingTechnique) http_utils.RawRequest {
+	if technique == smugglingCLTE {
+		// The front-end forwards the first chunk without the terminating one, so the back-end waits for the next chunk
+		return buildSmugglingRequest(target, encoding, 4, "1\r\nA\r\nX")
+	}
+	// The front-end forwards up to the terminating chunk, shorter than the Content-Length read by the back-end.
+	// When the front-end uses Content-Length instead, the extra byte is sent, so a CL.TE back-end isn't left waiting.
+	return buildSmugglingRequest(target, encoding, 6, "0\r\n\r\nX")
+}
+
+// buildSmugglingAttackRequest returns a request which smuggles the start of a request to the provided path, which is
+// prepended by the back-end to the next request received on the same connection
+func buildSmugglingAttackRequest(target smugglingTarget, encoding smugglingTransferEncoding, technique smugglingTechnique, smuggledPath string) http_utils.RawRequest {
+	if technique == smugglingCLTE {
+		body := fmt.Sprintf("0\r\n\r\nGET %s HTTP/1.1\r\nX-Ignore: X", smuggledPath)
+		return buildSmugglingRequest(target, encoding, len(body), body)
+	}
+	smuggled := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 15\r\n\r\nx=1", smuggledPath, target.Host)
+	sizeLine := fmt.Sprintf("%x\r\n", len(smuggled))
+	body := sizeLine + smuggled + "\r\n0\r\n\r\n"
+	// The back-end only reads the chunk size line, so the smuggled request is processed as a new one
+	return buildSmugglingRequest(target, encoding, len(sizeLine), body)
+}
+
+// buildSmugglingFollowUpRequest returns a regular request to the target, used to check if it is affected by the
+// smuggled one
+func buildSmugglingFollowUpRequest(target smugglingTarget) http_utils.RawRequest {
+	headers := []http_utils.RawHeader{{Name: "Host", Value: target.Host}}
+	headers = append(headers, target.Headers...)
+	headers = append(headers, http_utils.RawHeader{Name: "Connection", Value: "close"})
+	return http_utils.RawRequest{Method: "GET", Target: target.Path, Headers: headers}
+}
+
+// smugglingDelayed checks if no response has been received before the timeout
+func smugglingDelayed(response *http_utils.RawResponse, timeout time.Duration) bool {
+	return response != nil && (response.TimedOut || response.Duration >= timeout)
+}
+
+// smugglingTimingDetected decides if the timing probes show a desync: the control request has to be answered quickly,
+// while all the probes have to time out
+func smugglingTimingDetected(control *http_utils.RawResponse, probes []*http_utils.RawResponse, timeout time.Duration) bool {
+	if control == nil || control.TimedOut || control.Duration >= timeout/2 || len(probes) == 0 {
+		return false
+	}
+	for _, probe := range probes {
+		if !smugglingDelayed(probe, timeout) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestSmugglingResult holds the evidence of a detected request smuggling vulnerability
+type requestSmugglingResult struct {
+	Technique          smugglingTechnique
+	Encoding           smugglingTransferEncoding
+	Control            *http_utils.RawResponse
+	Probes             []*http_utils.RawResponse
+	ProbeRequest       http_utils.RawRequest
+	Confirmed          bool
+	AttackRequest      http_utils.RawRequest
+	BaselineStatusCode int
+	AffectedStatusCode int
+}
+
+// probeSmugglingTechnique sends the control request and the timing probe, which is repeated to discard network delays
+func probeSmugglingTechnique(send rawRequestSender, target smugglingTarget, encoding smugglingTransferEncoding, technique smugglingTechnique, timeout time.Duration) (*requestSmugglingResult, error) {
+	control, err := send(buildSmugglingControlRequest(target, encoding))
+	if err != nil {
+		return nil, err
+	}
+	if control.TimedOut || control.Duration >= timeout/2 {
+		return nil, nil
+	}
+	probeRequest := buildSmugglingTimingRequest(target, encoding, technique)
+	var probes []*http_utils.RawResponse
+	for i := 0; i < 2; i++ {
+		probe, err := send(probeRequest)
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, probe)
+		if !smugglingDelayed(probe, timeout) {
+			return nil, nil
+		}
+	}
+	// Check again that the server is still answering quickly, as it could have been overloaded
+	control, err = send(buildSmugglingControlRequest(target, encoding))
+	if err != nil {
+		return nil, err
+	}
+	if !smugglingTimingDetected(control, probes, timeout) {
+		return nil, nil
+	}
+	return &requestSmugglingResult{
+		Technique:    technique,
+		Encoding:     encoding,
+		Control:      control,
+		Probes:       probes,
+		ProbeRequest: probeRequest,
+	}, nil
+}
+
+// confirmSmugglingDifferential smuggles a request to a random path and checks if the next request gets its not found
+// response instead of the one it usually gets
+func confirmSmugglingDifferential(send rawRequestSender, target smugglingTarget, result *requestSmugglingResult) error {
+	followUp := buildSmugglingFollowUpRequest(target)
+	baseline, err := send(followUp)
+	if err != nil {
+		return err
+	}
+	if baseline.TimedOut || baseline.StatusCode == 404 {
+		return nil
+	}
+	result.BaselineStatusCode = baseline.StatusCode
+	smuggledPath := "/" + lib.GenerateRandomLowercaseString(12)
+	result.AttackRequest = buildSmugglingAttackRequest(target, result.Encoding, result.Technique, smuggledPath)
+	for i := 0; i < 2; i++ {
+		if _, err := send(result.AttackRequest); err != nil {
+			return err
+		}
+		affected, err := send(followUp)
+		if err != nil {
+			return err
+		}
+		if !affected.TimedOut && affected.StatusCode == 404 {
+			result.Confirmed = true
+			result.AffectedStatusCode = affected.StatusCode
+			return nil
+		}
+	}
+	return nil
+}
+
+// detectRequestSmuggling tries the techniques with each Transfer-Encoding variant. CL.TE is probed first, as the TE.CL
+// timing probe would poison the connection of a CL.TE back-end. The differential confirmation, which can affect the
+// responses received by other users of the target, is only attempted when confirm is set and not in fast mode.
+func detectRequestSmuggling(send rawRequestSender, target smugglingTarget, mode scan_options.ScanMode, timeout time.Duration, confirm bool) (*requestSmugglingResult, error) {
+	for _, encoding := range getSmugglingTransferEncodingsForMode(mode) {
+		for _, technique := range []smugglingTechnique{smugglingCLTE, smugglingTECL} {
+			result, err := probeSmugglingTechnique(send, target, encoding, technique, timeout)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				continue
+			}
+			if confirm && mode != scan_options.ScanModeFast {
+				if err := confirmSmugglingDifferential(send, target, result); err != nil {
+					log.Debug().Err(err).Str("technique", string(technique)).Msg("Error confirming request smuggling")
+				}
+			}
+			return result, nil
+		}
+	}
+	return nil, nil
+}
+
+// requestSmugglingTestedOrigins holds the origins already tested, as the desync depends on the servers and not on the
+// requested path
+var requestSmugglingTestedOrigins sync.Map
+
+// RequestSmugglingScan sends requests with both Content-Length and Transfer-Encoding headers to detect front-end and
+// back-end servers processing them differently through timing probes. When confirmDifferential is set, which is only
+// done for scans with experimental audits enabled, it also smuggles a request whose response is received by the next
+// one, except in fast mode. Each origin is only tested once per workspace.
+func RequestSmugglingScan(history *db.History, options ActiveModuleOptions, confirmDifferential bool) {
+	auditLog := log.With().Str("audit", "request-smuggling").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	u, err := url.Parse(history.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	if strings.HasPrefix(history.Proto, "HTTP/2") || strings.HasPrefix(history.Proto, "HTTP/3") {
+		auditLog.Debug().Str("proto", history.Proto).Msg("Skipping request smuggling audit as it only supports HTTP/1.1")
+		return
+	}
+	originKey := fmt.Sprintf("%d|%s://%s", options.WorkspaceID, u.Scheme, u.Host)
+	if _, tested := requestSmugglingTestedOrigins.LoadOrStore(originKey, true); tested {
+		return
+	}
+
+	requestHeaders, _ := history.GetRequestHeadersAsMap()
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	target := smugglingTarget{
+		Host:    u.Host,
+		Path:    path,
+		Headers: http_utils.ScanRawHeaders(requestHeaders, options.ClientOptions.ScanHeaders()),
+	}
+	send := func(request http_utils.RawRequest) (*http_utils.RawResponse, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), requestSmugglingTimeout*2)
+		defer cancel()
+		return http_utils.SendRawRequest(ctx, u, request.Bytes(), requestSmugglingTimeout, options.ClientOptions)
+	}
+
+	result, err := detectRequestSmuggling(send, target, options.ScanMode, requestSmugglingTimeout, confirmDifferential)
+	if err != nil {
+		auditLog.Error().Err(err).Msg("Error during request smuggling audit")
+		return
+	}
+	if result == nil {
+		auditLog.Info().Msg("Request smuggling audit completed")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The servers handling %s://%s appear to be vulnerable to %s request smuggling, using the %s Transfer-Encoding header.\n\n", u.Scheme, u.Host, result.Technique, result.Encoding.Name))
+	sb.WriteString(fmt.Sprintf("A control request with a body valid for both the Content-Length and Transfer-Encoding headers was answered in %s, while the following request timed out after %s in %d consecutive attempts, as the back-end kept waiting for the rest of the body:\n\n", result.Control.Duration.Round(time.Millisecond), requestSmugglingTimeout, len(result.Probes)))
+	sb.WriteString(fmt.Sprintf("```\n%s\n```", string(result.ProbeRequest.Bytes())))
+	confidence := 75
+	if result.Confirmed {
+		confidence = 95
+		sb.WriteString(fmt.Sprintf("\n\nThe vulnerability has been confirmed by smuggling the start of a request to a random path. A regular request sent right after it received a %d response instead of the usual %d one, as it was appended to the smuggled request by the back-end:\n\n", result.AffectedStatusCode, result.BaselineStatusCode))
+		sb.WriteString(fmt.Sprintf("```\n%s\n```", string(result.AttackRequest.Bytes())))
+	}
+	auditLog.Warn().Str("technique", string(result.Technique)).Str("encoding", result.Encoding.Name).Bool("confirmed", result.Confirmed).Msg("Request smuggling detected")
+	db.CreateIssueFromHistoryAndTemplate(history, db.RequestSmugglingCode, sb.String(), confidence, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+	auditLog.Info().Msg("Request smuggling audit completed")
+}
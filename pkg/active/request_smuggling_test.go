@@ -0,0 +1,181 @@
+package active
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSmugglingTarget = smugglingTarget{
+	Host:    "example.com",
+	Path:    "/login?next=/",
+	Headers: []http_utils.RawHeader{{Name: "User-Agent", Value: "Scanner"}},
+}
+
+func TestSmugglingRequestsBytes(t *testing.T) {
+	encoding := smugglingTransferEncodings[0]
+	prefix := "POST /login?next=/ HTTP/1.1\r\nHost: example.com\r\nUser-Agent: Scanner\r\nContent-Type: application/x-www-form-urlencoded\r\n"
+
+	assert.Equal(t, prefix+"Content-Length: 4\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n1\r\nA\r\nX",
+		string(buildSmugglingTimingRequest(testSmugglingTarget, encoding, smugglingCLTE).Bytes()))
+	assert.Equal(t, prefix+"Content-Length: 6\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n0\r\n\r\nX",
+		string(buildSmugglingTimingRequest(testSmugglingTarget, encoding, smugglingTECL).Bytes()))
+	assert.Equal(t, prefix+"Content-Length: 5\r\nTransfer-Encoding : chunked\r\nConnection: close\r\n\r\n0\r\n\r\n",
+		string(buildSmugglingControlRequest(testSmugglingTarget, smugglingTransferEncodings[1]).Bytes()))
+
+	attack := buildSmugglingAttackRequest(testSmugglingTarget, encoding, smugglingCLTE, "/smuggled")
+	assert.Equal(t, prefix+"Content-Length: 40\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n0\r\n\r\nGET /smuggled HTTP/1.1\r\nX-Ignore: X", string(attack.Bytes()))
+
+	attack = buildSmugglingAttackRequest(testSmugglingTarget, encoding, smugglingTECL, "/smuggled")
+	smuggled := "GET /smuggled HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 15\r\n\r\nx=1"
+	assert.Equal(t, prefix+"Content-Length: 4\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n75\r\n"+smuggled+"\r\n0\r\n\r\n", string(attack.Bytes()))
+	assert.Equal(t, 0x75, len(smuggled))
+}
+
+func TestSmugglingTimingDetected(t *testing.T) {
+	timeout := 5 * time.Second
+	fast := &http_utils.RawResponse{StatusCode: 200, Duration: 100 * time.Millisecond}
+	slow := &http_utils.RawResponse{StatusCode: 200, Duration: 3 * time.Second}
+	timedOut := &http_utils.RawResponse{TimedOut: true, Duration: timeout}
+
+	assert.True(t, smugglingTimingDetected(fast, []*http_utils.RawResponse{timedOut, timedOut}, timeout))
+	assert.False(t, smugglingTimingDetected(fast, []*http_utils.RawResponse{timedOut, fast}, timeout))
+	assert.False(t, smugglingTimingDetected(slow, []*http_utils.RawResponse{timedOut, timedOut}, timeout))
+	assert.False(t, smugglingTimingDetected(timedOut, []*http_utils.RawResponse{timedOut, timedOut}, timeout))
+	assert.False(t, smugglingTimingDetected(fast, nil, timeout))
+}
+
+// parseChunkedBody returns the length of the chunked body up to its terminating chunk, whether it is complete and
+// whether it is valid
+func parseChunkedBody(body string) (int, bool, bool) {
+	position := 0
+	for {
+		lineEnd := strings.Index(body[position:], "\r\n")
+		if lineEnd == -1 {
+			_, err := strconv.ParseInt(body[position:], 16, 64)
+			return 0, false, body[position:] == "" || err == nil
+		}
+		size, err := strconv.ParseInt(body[position:position+lineEnd], 16, 64)
+		if err != nil {
+			return 0, false, false
+		}
+		position += lineEnd + 2
+		if size == 0 {
+			if !strings.HasPrefix(body[position:], "\r\n") {
+				return 0, false, true
+			}
+			return position + 2, true, true
+		}
+		if len(body) < position+int(size)+2 {
+			return 0, false, true
+		}
+		position += int(size) + 2
+	}
+}
+
+// stubDesyncServers simulates a front-end and back-end which use either the Content-Length (CL) or the
+// Transfer-Encoding (TE) header to read the body, with the back-end keeping the unread bytes for the next request
+type stubDesyncServers struct {
+	frontEnd string
+	backEnd  string
+	leftover string
+	requests int
+}
+
+func (s *stubDesyncServers) read(using, body string, contentLength int) (int, *http_utils.RawResponse) {
+	if using == "CL" {
+		if len(body) < contentLength {
+			return 0, &http_utils.RawResponse{TimedOut: true, Duration: 5 * time.Second}
+		}
+		return contentLength, nil
+	}
+	end, complete, valid := parseChunkedBody(body)
+	if !valid {
+		return 0, &http_utils.RawResponse{StatusCode: 400, Duration: 10 * time.Millisecond}
+	}
+	if !complete {
+		return 0, &http_utils.RawResponse{TimedOut: true, Duration: 5 * time.Second}
+	}
+	return end, nil
+}
+
+func (s *stubDesyncServers) send(request http_utils.RawRequest) (*http_utils.RawResponse, error) {
+	s.requests++
+	if request.Method == "GET" {
+		if s.leftover != "" {
+			s.leftover = ""
+			return &http_utils.RawResponse{StatusCode: 404, Duration: 10 * time.Millisecond}, nil
+		}
+		return &http_utils.RawResponse{StatusCode: 200, Duration: 10 * time.Millisecond}, nil
+	}
+	contentLength := 0
+	for _, header := range request.Headers {
+		if header.Name == "Content-Length" {
+			contentLength, _ = strconv.Atoi(header.Value)
+		}
+	}
+	forwarded, response := s.read(s.frontEnd, request.Body, contentLength)
+	if response != nil {
+		return response, nil
+	}
+	body := request.Body[:forwarded]
+	read, response := s.read(s.backEnd, body, contentLength)
+	if response != nil {
+		return response, nil
+	}
+	s.leftover = body[read:]
+	return &http_utils.RawResponse{StatusCode: 200, Duration: 10 * time.Millisecond}, nil
+}
+
+func TestDetectRequestSmuggling(t *testing.T) {
+	timeout := 5 * time.Second
+
+	servers := &stubDesyncServers{frontEnd: "CL", backEnd: "TE"}
+	result, err := detectRequestSmuggling(servers.send, testSmugglingTarget, scan_options.ScanModeSmart, timeout, true)
+	assert.Nil(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, smugglingCLTE, result.Technique)
+		assert.Len(t, result.Probes, 2)
+		assert.True(t, result.Confirmed)
+		assert.Equal(t, 200, result.BaselineStatusCode)
+		assert.Equal(t, 404, result.AffectedStatusCode)
+	}
+
+	servers = &stubDesyncServers{frontEnd: "TE", backEnd: "CL"}
+	result, err = detectRequestSmuggling(servers.send, testSmugglingTarget, scan_options.ScanModeSmart, timeout, true)
+	assert.Nil(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, smugglingTECL, result.Technique)
+		assert.True(t, result.Confirmed)
+	}
+
+	// Fast mode only relies on the timing probes
+	servers = &stubDesyncServers{frontEnd: "TE", backEnd: "CL"}
+	result, err = detectRequestSmuggling(servers.send, testSmugglingTarget, scan_options.ScanModeFast, timeout, true)
+	assert.Nil(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, smugglingTECL, result.Technique)
+		assert.False(t, result.Confirmed)
+	}
+
+	// Without opting in, only the timing probes are sent
+	servers = &stubDesyncServers{frontEnd: "TE", backEnd: "CL"}
+	result, err = detectRequestSmuggling(servers.send, testSmugglingTarget, scan_options.ScanModeSmart, timeout, false)
+	assert.Nil(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, smugglingTECL, result.Technique)
+		assert.False(t, result.Confirmed)
+		assert.Empty(t, result.AttackRequest.Method)
+	}
+
+	servers = &stubDesyncServers{frontEnd: "TE", backEnd: "TE"}
+	result, err = detectRequestSmuggling(servers.send, testSmugglingTarget, scan_options.ScanModeFuzz, timeout, true)
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+	assert.Empty(t, servers.leftover)
+}
@@ -19,6 +19,7 @@ type SNIAudit struct {
 	WorkspaceID         uint
 	TaskID              uint
 	TaskJobID           uint
+	ClientOptions       *http_utils.ClientOptions
 }
 
 // Run starts the audit
@@ -30,14 +31,26 @@ func (a *SNIAudit) Run() {
 		return
 	}
 	auditLog.Info().Msg("Starting SNI Injection audit")
-	interactionData := a.InteractionsManager.GetURL()
+	interactionData, reused, err := a.InteractionsManager.GetURLForTest(integrations.OOBTestKey{
+		Target:         a.HistoryItem.URL,
+		InsertionPoint: "sni",
+		Payload:        "{{interactionAddress}}",
+	})
+	if err != nil {
+		auditLog.Warn().Err(err).Msg("Skipping SNI Injection audit")
+		return
+	}
+	if reused {
+		auditLog.Info().Msg("SNI Injection has already been tested against this URL, skipping audit")
+		return
+	}
 	transport := &http.Transport{
 		DialContext: (&net.Dialer{}).DialContext,
 		TLSClientConfig: &tls.Config{
 			ServerName: interactionData.URL,
 		},
 	}
-	client := &http.Client{Transport: transport}
+	client := &http.Client{Transport: a.ClientOptions.WrapTransport(transport)}
 	request, err := http_utils.BuildRequestFromHistoryItem(a.HistoryItem)
 
 	if err != nil {
@@ -0,0 +1,89 @@
+package active
+
+import (
+	"fmt"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/scan"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// sqliBooleanSuffixes are appended to the original value of the insertion point to build the true and false condition
+// payloads, covering numeric, quoted and parenthesized contexts
+var sqliBooleanSuffixes = []scan.BooleanPayloadPair{
+	{True: " AND 1=1", False: " AND 1=2"},
+	{True: "' AND '1'='1", False: "' AND '1'='2"},
+	{True: "\" AND \"1\"=\"1", False: "\" AND \"1\"=\"2"},
+	{True: "') AND ('1'='1", False: "') AND ('1'='2"},
+	{True: " AND 1=1-- -", False: " AND 1=2-- -"},
+	{True: "' AND 1=1-- -", False: "' AND 1=2-- -"},
+}
+
+// getSQLiBooleanPayloadPairs returns the payload pairs to test an insertion point. Numeric values are tested first
+// in numeric context, and the fast scan mode only tests the most common contexts.
+func getSQLiBooleanPayloadPairs(insertionPoint scan.InsertionPoint, mode scan_options.ScanMode) []scan.BooleanPayloadPair {
+	suffixes := sqliBooleanSuffixes
+	if mode == scan_options.ScanModeFast {
+		suffixes = suffixes[:2]
+	}
+	pairs := make([]scan.BooleanPayloadPair, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		pairs = append(pairs, scan.BooleanPayloadPair{
+			True:  insertionPoint.Value + suffix.True,
+			False: insertionPoint.Value + suffix.False,
+		})
+	}
+	if insertionPoint.ValueType != lib.TypeInt && len(pairs) > 1 {
+		// Quoted contexts are more likely for non numeric values
+		pairs[0], pairs[1] = pairs[1], pairs[0]
+	}
+	return pairs
+}
+
+// BooleanSQLInjectionScan injects true and false SQL conditions in the insertion points, reporting blind SQL injection
+// when the true condition response matches the original one while the false condition response differs
+func BooleanSQLInjectionScan(history *db.History, insertionPoints []scan.InsertionPoint, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "sqli-boolean").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
+	}
+	thresholds := scan.GetBooleanThresholds()
+	historyOptions := http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	}
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, insertionPoint := range insertionPoints {
+		insertionPoint := insertionPoint
+		if insertionPoint.Type == scan.InsertionPointTypeFullBody {
+			continue
+		}
+		p.Go(func() {
+			for _, pair := range getSQLiBooleanPayloadPairs(insertionPoint, options.ScanMode) {
+				result, err := scan.BooleanTest(client, history, insertionPoint, pair, thresholds, historyOptions)
+				if err != nil {
+					auditLog.Error().Err(err).Str("insertion_point", insertionPoint.String()).Str("payload", pair.True).Msg("Error sending boolean SQL injection payloads")
+					return
+				}
+				if !result.Vulnerable {
+					continue
+				}
+				auditLog.Info().Str("insertion_point", insertionPoint.String()).Str("true", pair.True).Str("false", pair.False).Msg("Boolean based SQL injection found")
+				details := fmt.Sprintf("The %s `%s` has been tested injecting a true and a false SQL condition.\n\nThe response to the true condition payload `%s` is %.0f%% similar to the response to the original value, while the response to the false condition payload `%s` is only %.0f%% similar. The behaviour has been consistent across %d attempts, which indicates the condition is evaluated by the database.", insertionPoint.Type, insertionPoint.Name, pair.True, result.Comparison.TrueSimilarity*100, pair.False, result.Comparison.FalseSimilarity*100, thresholds.Confirmations+1)
+				db.CreateIssueFromHistoryAndTemplateWithOptions(result.False, db.BlindSqlInjectionCode, details, 80, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
+				return
+			}
+		})
+	}
+	p.Wait()
+	auditLog.Info().Msg("Boolean SQL injection audit completed")
+}
@@ -0,0 +1,26 @@
+package active
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/scan"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSQLiBooleanPayloadPairs(t *testing.T) {
+	numeric := scan.InsertionPoint{Name: "id", Value: "42", ValueType: lib.TypeInt}
+	pairs := getSQLiBooleanPayloadPairs(numeric, scan_options.ScanModeSmart)
+	assert.Len(t, pairs, len(sqliBooleanSuffixes))
+	assert.Equal(t, scan.BooleanPayloadPair{True: "42 AND 1=1", False: "42 AND 1=2"}, pairs[0])
+
+	text := scan.InsertionPoint{Name: "q", Value: "shoes", ValueType: lib.TypeString}
+	pairs = getSQLiBooleanPayloadPairs(text, scan_options.ScanModeFast)
+	assert.Len(t, pairs, 2)
+	assert.Equal(t, scan.BooleanPayloadPair{True: "shoes' AND '1'='1", False: "shoes' AND '1'='2"}, pairs[0])
+	assert.Equal(t, "shoes AND 1=1", pairs[1].True)
+
+	// The shared suffixes are not modified
+	assert.Equal(t, " AND 1=1", sqliBooleanSuffixes[0].True)
+}
@@ -0,0 +1,154 @@
+package active
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+// serverSidePrototypePollutionStatus is the status code polluted to detect prototype pollution through the error
+// responses, which is unusual enough not to be returned by the application
+const serverSidePrototypePollutionStatus = 510
+
+// serverSidePrototypePollutionKeys are the keys which reach Object.prototype when the JSON body is merged into an
+// object without filtering them
+var serverSidePrototypePollutionKeys = []string{"__proto__", "constructor.prototype"}
+
+// isJSONObjectRequest checks if the history item request body is a JSON object
+func isJSONObjectRequest(history *db.History) bool {
+	if !strings.Contains(strings.ToLower(history.RequestContentType), "json") {
+		return false
+	}
+	var document map[string]interface{}
+	return json.Unmarshal(history.RequestBody, &document) == nil
+}
+
+// buildServerSidePrototypePollutionBody returns the JSON body with the polluting object added under the provided key,
+// nesting it under constructor and prototype for the constructor.prototype key
+func buildServerSidePrototypePollutionBody(body []byte, key string, pollution map[string]interface{}) ([]byte, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	switch key {
+	case "__proto__":
+		document["__proto__"] = pollution
+	case "constructor.prototype":
+		document["constructor"] = map[string]interface{}{"prototype": pollution}
+	default:
+		return nil, fmt.Errorf("unsupported prototype pollution key %s", key)
+	}
+	return json.Marshal(document)
+}
+
+// isPollutedPropertyReflected checks if the polluted property is returned in the response while the polluting keys are
+// not, which happens when the response is built from an object that inherits the property
+func isPollutedPropertyReflected(responseBody []byte, property, value string) bool {
+	reflected := fmt.Sprintf(`"%s":"%s"`, property, value)
+	compact := bytes.Join(bytes.Fields(responseBody), nil)
+	return bytes.Contains(compact, []byte(reflected)) && !bytes.Contains(responseBody, []byte("__proto__")) && !bytes.Contains(responseBody, []byte(`"prototype"`))
+}
+
+type serverSidePrototypePollutionTester struct {
+	client  *http.Client
+	history *db.History
+	options http_utils.HistoryCreationOptions
+}
+
+func (t serverSidePrototypePollutionTester) send(body []byte) (*db.History, error) {
+	request, err := http.NewRequest(t.history.Method, t.history.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	http_utils.SetRequestHeadersFromHistoryItem(request, t.history)
+	response, err := t.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, t.options)
+}
+
+// pollute sends the JSON body with the polluting object under the key
+func (t serverSidePrototypePollutionTester) pollute(key string, pollution map[string]interface{}) (*db.History, error) {
+	body, err := buildServerSidePrototypePollutionBody(t.history.RequestBody, key, pollution)
+	if err != nil {
+		return nil, err
+	}
+	return t.send(body)
+}
+
+// ServerSidePrototypePollutionScan sends JSON bodies with objects under the __proto__ and constructor.prototype keys,
+// reporting server side prototype pollution when a polluted property is reflected in the response or when polluting
+// the status property changes the status code of the error responses, as done by the Express body parser
+func ServerSidePrototypePollutionScan(history *db.History, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "server-side-prototype-pollution").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	tester := serverSidePrototypePollutionTester{
+		client:  http_utils.CreateHttpClientWithOptions(options.ClientOptions),
+		history: history,
+		options: http_utils.HistoryCreationOptions{
+			Source:              db.SourceScanner,
+			WorkspaceID:         options.WorkspaceID,
+			TaskID:              options.TaskID,
+			TaskJobID:           options.TaskJobID,
+			CreateNewBodyStream: false,
+		},
+	}
+
+	for _, key := range serverSidePrototypePollutionKeys {
+		property := "sukyan" + lib.GenerateRandomLowercaseString(6)
+		value := lib.GenerateRandomLowercaseString(10)
+		polluted, err := tester.pollute(key, map[string]interface{}{property: value})
+		if err != nil {
+			auditLog.Error().Err(err).Str("key", key).Msg("Error sending prototype pollution payload")
+			return
+		}
+		if isPollutedPropertyReflected(polluted.ResponseBody, property, value) {
+			auditLog.Info().Str("key", key).Msg("Server side prototype pollution found through a reflected property")
+			details := fmt.Sprintf("The request JSON body has been sent with the `%s` key holding an object with the `%s` property set to `%s`.\n\nThe property has been returned in the response without the polluting key, which indicates the object the response is built from has inherited it from the polluted prototype.", key, property, value)
+			db.CreateIssueFromHistoryAndTemplate(polluted, db.ServerSidePrototypePollutionCode, details, 80, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+			return
+		}
+	}
+
+	// Malformed JSON bodies make the Express body parser fail with the status property of the error, which is
+	// inherited from the prototype when it is not set
+	malformed := append(append([]byte{}, history.RequestBody...), []byte(`{"`)...)
+	before, err := tester.send(malformed)
+	if err != nil {
+		auditLog.Error().Err(err).Msg("Error sending malformed JSON body")
+		return
+	}
+	if before.StatusCode == serverSidePrototypePollutionStatus {
+		return
+	}
+	for _, key := range serverSidePrototypePollutionKeys {
+		if _, err := tester.pollute(key, map[string]interface{}{"status": serverSidePrototypePollutionStatus}); err != nil {
+			auditLog.Error().Err(err).Str("key", key).Msg("Error sending prototype pollution payload")
+			return
+		}
+		after, err := tester.send(malformed)
+		if err != nil {
+			auditLog.Error().Err(err).Msg("Error sending malformed JSON body")
+			return
+		}
+		if after.StatusCode != serverSidePrototypePollutionStatus {
+			continue
+		}
+		// Restore the default status, as a falsy status makes the error fall back to its own one
+		if _, err := tester.pollute(key, map[string]interface{}{"status": 0}); err != nil {
+			auditLog.Warn().Err(err).Str("key", key).Msg("Error restoring the polluted status property")
+		}
+		auditLog.Info().Str("key", key).Msg("Server side prototype pollution found through the error status code")
+		details := fmt.Sprintf("The request JSON body has been sent with the `%s` key holding an object with the `status` property set to `%d`.\n\nBefore sending it, a malformed JSON body was answered with the status code %d, while afterwards the same malformed body has been answered with the status code %d. This indicates the error has inherited the status property from the polluted prototype.\n\nThe polluted status property has been set to 0 afterwards to restore the default error status codes.", key, serverSidePrototypePollutionStatus, before.StatusCode, after.StatusCode)
+		db.CreateIssueFromHistoryAndTemplate(after, db.ServerSidePrototypePollutionCode, details, 90, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID)
+		return
+	}
+	auditLog.Info().Msg("Server side prototype pollution audit completed")
+}
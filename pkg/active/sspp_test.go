@@ -0,0 +1,40 @@
+package active
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildServerSidePrototypePollutionBody(t *testing.T) {
+	body := []byte(`{"name":"sukyan","age":3}`)
+	pollution := map[string]interface{}{"status": 510}
+
+	polluted, err := buildServerSidePrototypePollutionBody(body, "__proto__", pollution)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"__proto__":{"status":510},"age":3,"name":"sukyan"}`, string(polluted))
+
+	polluted, err = buildServerSidePrototypePollutionBody(body, "constructor.prototype", pollution)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"name":"sukyan","age":3,"constructor":{"prototype":{"status":510}}}`, string(polluted))
+
+	_, err = buildServerSidePrototypePollutionBody(body, "prototype", pollution)
+	assert.NotNil(t, err)
+	_, err = buildServerSidePrototypePollutionBody([]byte(`[1]`), "__proto__", pollution)
+	assert.NotNil(t, err)
+}
+
+func TestIsPollutedPropertyReflected(t *testing.T) {
+	assert.True(t, isPollutedPropertyReflected([]byte(`{"name":"sukyan","sukyanabc":"xyz"}`), "sukyanabc", "xyz"))
+	assert.True(t, isPollutedPropertyReflected([]byte("{\n  \"sukyanabc\": \"xyz\"\n}"), "sukyanabc", "xyz"))
+	assert.False(t, isPollutedPropertyReflected([]byte(`{"__proto__":{"sukyanabc":"xyz"}}`), "sukyanabc", "xyz"))
+	assert.False(t, isPollutedPropertyReflected([]byte(`{"constructor":{"prototype":{"sukyanabc":"xyz"}}}`), "sukyanabc", "xyz"))
+	assert.False(t, isPollutedPropertyReflected([]byte(`{"name":"sukyan"}`), "sukyanabc", "xyz"))
+}
+
+func TestIsJSONObjectRequest(t *testing.T) {
+	assert.True(t, isJSONObjectRequest(&db.History{RequestContentType: "application/json; charset=utf-8", RequestBody: []byte(`{"a":1}`)}))
+	assert.False(t, isJSONObjectRequest(&db.History{RequestContentType: "application/json", RequestBody: []byte(`[1]`)}))
+	assert.False(t, isJSONObjectRequest(&db.History{RequestContentType: "application/x-www-form-urlencoded", RequestBody: []byte(`{"a":1}`)}))
+}
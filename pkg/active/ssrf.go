@@ -0,0 +1,211 @@
+package active
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/scan"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// ssrfPayloadFormats are the formats used to inject the interaction address, where %s is the address. The first ones
+// are the most likely to be fetched, so only those are used in the fast and smart scan modes.
+var ssrfPayloadFormats = []string{
+	"http://%s/",
+	"https://%s/",
+	"//%s/",
+	"%s",
+	"ftp://%s/",
+	"gopher://%s/",
+	"dict://%s/",
+	"ldap://%s/",
+	"file://%s/",
+}
+
+func getSSRFPayloadFormatsForMode(mode scan_options.ScanMode) []string {
+	switch mode {
+	case scan_options.ScanModeFuzz:
+		return ssrfPayloadFormats
+	case scan_options.ScanModeSmart:
+		return ssrfPayloadFormats[:4]
+	default:
+		return ssrfPayloadFormats[:1]
+	}
+}
+
+// ssrfMetadataEndpoint is a cloud metadata service URL, together with the content that proves the server has
+// fetched it
+type ssrfMetadataEndpoint struct {
+	Provider string
+	URL      string
+	Markers  []string
+}
+
+var ssrfMetadataEndpoints = []ssrfMetadataEndpoint{
+	{Provider: "AWS", URL: "http://169.254.169.254/latest/meta-data/", Markers: []string{"ami-id", "instance-id", "local-hostname"}},
+	// GCP and Azure require a header the server will not send, but their error messages prove the service has been reached
+	{Provider: "GCP", URL: "http://metadata.google.internal/computeMetadata/v1/", Markers: []string{"Metadata-Flavor"}},
+	{Provider: "Azure", URL: "http://169.254.169.254/metadata/instance?api-version=2021-02-01", Markers: []string{"Required metadata header not specified", "\"compute\""}},
+	{Provider: "DigitalOcean", URL: "http://169.254.169.254/metadata/v1/", Markers: []string{"droplet_id"}},
+	{Provider: "Alibaba Cloud", URL: "http://100.100.100.200/latest/meta-data/", Markers: []string{"image-id", "instance-id"}},
+}
+
+func getSSRFMetadataEndpointsForMode(mode scan_options.ScanMode) []ssrfMetadataEndpoint {
+	if mode == scan_options.ScanModeFast {
+		return ssrfMetadataEndpoints[:1]
+	}
+	return ssrfMetadataEndpoints
+}
+
+// isSSRFInsertionPoint checks if the insertion point is likely to hold an address fetched by the server
+func isSSRFInsertionPoint(insertionPoint scan.InsertionPoint) bool {
+	switch insertionPoint.Type {
+	case scan.InsertionPointTypeParameter, scan.InsertionPointTypeBody, scan.InsertionPointTypeHeader, scan.InsertionPointTypeCookie:
+	default:
+		return false
+	}
+	return insertionPoint.ValueType == lib.TypeURL || scan.IsCommonSSRFParameter(insertionPoint.Name)
+}
+
+// getSSRFInsertionPoints returns the insertion points to test, which are all of them in fuzz mode
+func getSSRFInsertionPoints(insertionPoints []scan.InsertionPoint, mode scan_options.ScanMode) []scan.InsertionPoint {
+	if mode == scan_options.ScanModeFuzz {
+		return insertionPoints
+	}
+	var result []scan.InsertionPoint
+	for _, insertionPoint := range insertionPoints {
+		if isSSRFInsertionPoint(insertionPoint) {
+			result = append(result, insertionPoint)
+		}
+	}
+	return result
+}
+
+// matchSSRFMetadata returns the first marker of the metadata endpoint found in the response which was not already in
+// the original one
+func matchSSRFMetadata(original, response []byte, endpoint ssrfMetadataEndpoint) (string, bool) {
+	for _, marker := range endpoint.Markers {
+		if bytes.Contains(response, []byte(marker)) && !bytes.Contains(original, []byte(marker)) {
+			return marker, true
+		}
+	}
+	return "", false
+}
+
+func newSSRFOOBTest(history *db.History, insertionPoint scan.InsertionPoint, payload string, interactionData integrations.InteractionDomain, options ActiveModuleOptions) db.OOBTest {
+	return db.OOBTest{
+		Code:              db.SsrfCode,
+		TestName:          fmt.Sprintf("Server Side Request Forgery via %s", insertionPoint.Type),
+		InteractionDomain: interactionData.URL,
+		InteractionFullID: interactionData.ID,
+		Target:            history.URL,
+		Payload:           payload,
+		HistoryID:         &history.ID,
+		InsertionPoint:    insertionPoint.String(),
+		WorkspaceID:       &options.WorkspaceID,
+		TaskID:            &options.TaskID,
+		TaskJobID:         &options.TaskJobID,
+	}
+}
+
+// SSRFScan injects interaction addresses in the insertion points likely to hold URLs, or all of them in fuzz mode, and
+// in the headers commonly fetched by servers. Blind SSRF is confirmed when the interaction is matched with the OOB test,
+// with a higher confidence for HTTP interactions than DNS-only ones. Cloud metadata endpoints are also injected to
+// detect SSRF in-band through the response content.
+func SSRFScan(history *db.History, interactionsManager *integrations.InteractionsManager, insertionPoints []scan.InsertionPoint, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "ssrf").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
+	}
+
+	SSRFHeadersScan(history, interactionsManager, options)
+
+	scanInsertionPoints := getSSRFInsertionPoints(insertionPoints, options.ScanMode)
+	if len(scanInsertionPoints) == 0 {
+		auditLog.Debug().Msg("No insertion points likely to hold URLs, skipping SSRF audit")
+		return
+	}
+
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, insertionPoint := range scanInsertionPoints {
+		insertionPoint := insertionPoint
+		p.Go(func() {
+			if interactionsManager != nil {
+				for _, format := range getSSRFPayloadFormatsForMode(options.ScanMode) {
+					interactionData, reused, err := interactionsManager.GetURLForTest(integrations.OOBTestKey{
+						Target:         history.URL,
+						InsertionPoint: insertionPoint.String(),
+						Payload:        fmt.Sprintf(format, "{{interactionAddress}}"),
+					})
+					if err != nil {
+						auditLog.Warn().Err(err).Str("insertion_point", insertionPoint.String()).Msg("Skipping SSRF OOB test")
+						continue
+					}
+					if reused {
+						auditLog.Debug().Str("insertion_point", insertionPoint.String()).Msg("Interaction url already used by an identical test, skipping")
+						continue
+					}
+					payload := fmt.Sprintf(format, interactionData.URL)
+					newHistory, err := sendSSRFPayload(client, history, insertionPoint, payload, options)
+					if err != nil {
+						auditLog.Error().Err(err).Str("insertion_point", insertionPoint.String()).Str("payload", payload).Msg("Error sending SSRF OOB payload")
+						continue
+					}
+					if _, err := db.Connection.CreateOOBTest(newSSRFOOBTest(newHistory, insertionPoint, payload, interactionData, options)); err != nil {
+						auditLog.Error().Err(err).Str("insertion_point", insertionPoint.String()).Msg("Error creating OOB test")
+					}
+				}
+			}
+
+			for _, endpoint := range getSSRFMetadataEndpointsForMode(options.ScanMode) {
+				newHistory, err := sendSSRFPayload(client, history, insertionPoint, endpoint.URL, options)
+				if err != nil {
+					auditLog.Error().Err(err).Str("insertion_point", insertionPoint.String()).Str("payload", endpoint.URL).Msg("Error sending SSRF metadata payload")
+					continue
+				}
+				marker, found := matchSSRFMetadata(history.ResponseBody, newHistory.ResponseBody, endpoint)
+				if !found {
+					continue
+				}
+				auditLog.Info().Str("insertion_point", insertionPoint.String()).Str("provider", endpoint.Provider).Msg("Cloud metadata service reached through SSRF")
+				details := fmt.Sprintf("The %s cloud metadata endpoint `%s` has been injected in the %s `%s` and the response contains `%s`, which indicates the server has fetched it and returned its content.\n\nThe metadata service can expose instance credentials and configuration which could be used to compromise the cloud account.", endpoint.Provider, endpoint.URL, insertionPoint.Type, insertionPoint.Name, marker)
+				db.CreateIssueFromHistoryAndTemplateWithOptions(newHistory, db.SsrfCode, details, 90, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
+				return
+			}
+		})
+	}
+	p.Wait()
+	auditLog.Info().Msg("SSRF audit completed")
+}
+
+func sendSSRFPayload(client *http.Client, history *db.History, insertionPoint scan.InsertionPoint, payload string, options ActiveModuleOptions) (*db.History, error) {
+	request, err := scan.CreateRequestFromInsertionPoints(history, []scan.InsertionPointBuilder{
+		{
+			Point:   insertionPoint,
+			Payload: payload,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	})
+}
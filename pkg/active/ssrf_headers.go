@@ -0,0 +1,150 @@
+package active
+
+import (
+	"fmt"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+type ssrfHeaderPayloadFormat int
+
+const (
+	ssrfHeaderPayloadURL ssrfHeaderPayloadFormat = iota
+	ssrfHeaderPayloadHost
+	ssrfHeaderPayloadEmail
+)
+
+// ssrfHeaders contains headers that some servers use to fetch remote resources (link previews, webhooks, analytics, etc.)
+// along with the format the collaborator address should be injected with
+var ssrfHeaders = []struct {
+	name   string
+	format ssrfHeaderPayloadFormat
+}{
+	{"Referer", ssrfHeaderPayloadURL},
+	{"Origin", ssrfHeaderPayloadURL},
+	{"X-Forwarded-Host", ssrfHeaderPayloadHost},
+	{"X-Wap-Profile", ssrfHeaderPayloadURL},
+	{"X-Forwarded-For", ssrfHeaderPayloadHost},
+	{"X-Real-IP", ssrfHeaderPayloadHost},
+	{"X-Host", ssrfHeaderPayloadHost},
+	{"X-Original-URL", ssrfHeaderPayloadURL},
+	{"X-Client-IP", ssrfHeaderPayloadHost},
+	{"True-Client-IP", ssrfHeaderPayloadHost},
+	{"Forwarded", ssrfHeaderPayloadHost},
+	{"Contact", ssrfHeaderPayloadEmail},
+	{"From", ssrfHeaderPayloadEmail},
+}
+
+func getSSRFHeadersForMode(mode scan_options.ScanMode) int {
+	switch mode {
+	case scan_options.ScanModeFuzz:
+		return len(ssrfHeaders)
+	case scan_options.ScanModeSmart:
+		return 7
+	default:
+		return 3
+	}
+}
+
+func buildSSRFHeaderPayload(format ssrfHeaderPayloadFormat, interactionURL string) string {
+	switch format {
+	case ssrfHeaderPayloadHost:
+		return interactionURL
+	case ssrfHeaderPayloadEmail:
+		return fmt.Sprintf("root@%s", interactionURL)
+	default:
+		return fmt.Sprintf("http://%s/", interactionURL)
+	}
+}
+
+func newSSRFHeaderOOBTest(history *db.History, header, payload string, interactionData integrations.InteractionDomain, options ActiveModuleOptions) db.OOBTest {
+	return db.OOBTest{
+		Code:              db.SsrfCode,
+		TestName:          "Server Side Request Forgery via headers",
+		InteractionDomain: interactionData.URL,
+		InteractionFullID: interactionData.ID,
+		Target:            history.URL,
+		Payload:           payload,
+		HistoryID:         &history.ID,
+		InsertionPoint:    fmt.Sprintf("%s header", header),
+		WorkspaceID:       &options.WorkspaceID,
+		TaskID:            &options.TaskID,
+		TaskJobID:         &options.TaskJobID,
+	}
+}
+
+// SSRFHeadersScan injects collaborator addresses into headers such as Referer, Origin or X-Forwarded-Host,
+// which are commonly fetched by servers and missed when only testing parameters. Findings are confirmed via OOB interactions.
+func SSRFHeadersScan(history *db.History, interactionsManager *integrations.InteractionsManager, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "ssrf-headers").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+
+	if interactionsManager == nil {
+		auditLog.Warn().Msg("Skipping SSRF headers audit as no interactions manager has been provided")
+		return
+	}
+
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
+	}
+
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, header := range ssrfHeaders[:getSSRFHeadersForMode(options.ScanMode)] {
+		header := header
+		p.Go(func() {
+			interactionData, reused, err := interactionsManager.GetURLForTest(integrations.OOBTestKey{
+				Target:         history.URL,
+				InsertionPoint: fmt.Sprintf("%s header", header.name),
+				Payload:        buildSSRFHeaderPayload(header.format, "{{interactionAddress}}"),
+			})
+			if err != nil {
+				auditLog.Warn().Err(err).Str("header", header.name).Msg("Skipping SSRF header test")
+				return
+			}
+			if reused {
+				auditLog.Debug().Str("header", header.name).Msg("Interaction url already used by an identical test, skipping")
+				return
+			}
+			payload := buildSSRFHeaderPayload(header.format, interactionData.URL)
+
+			request, err := http_utils.BuildRequestFromHistoryItem(history)
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating request")
+				return
+			}
+			request.Header.Set(header.name, payload)
+
+			response, err := http_utils.SendRequest(client, request)
+			if err != nil {
+				auditLog.Error().Err(err).Str("header", header.name).Msg("Error during request")
+				return
+			}
+
+			newHistory, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+				Source:              db.SourceScanner,
+				WorkspaceID:         options.WorkspaceID,
+				TaskID:              options.TaskID,
+				TaskJobID:           options.TaskJobID,
+				CreateNewBodyStream: false,
+			})
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating history from response")
+				return
+			}
+
+			oobTest := newSSRFHeaderOOBTest(newHistory, header.name, payload, interactionData, options)
+			if _, err := db.Connection.CreateOOBTest(oobTest); err != nil {
+				auditLog.Error().Err(err).Str("header", header.name).Msg("Error creating OOB test")
+			}
+		})
+	}
+
+	p.Wait()
+	auditLog.Info().Msg("SSRF headers audit completed")
+}
@@ -0,0 +1,62 @@
+package active
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSSRFHeaderPayload(t *testing.T) {
+	assert.Equal(t, "http://abc.oast.fun/", buildSSRFHeaderPayload(ssrfHeaderPayloadURL, "abc.oast.fun"))
+	assert.Equal(t, "abc.oast.fun", buildSSRFHeaderPayload(ssrfHeaderPayloadHost, "abc.oast.fun"))
+	assert.Equal(t, "root@abc.oast.fun", buildSSRFHeaderPayload(ssrfHeaderPayloadEmail, "abc.oast.fun"))
+}
+
+func TestSSRFHeaderOOBTestCorrelation(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "ssrf-headers-test",
+		Title:       "ssrf headers test workspace",
+		Description: "Workspace for SSRF headers tests",
+	})
+	assert.Nil(t, err)
+	task, err := db.Connection.NewTask(workspace.ID, nil, "ssrf headers test", "running", db.TaskTypeScan)
+	assert.Nil(t, err)
+
+	history := &db.History{URL: "https://example.com/preview", Method: "GET", StatusCode: 200, WorkspaceID: &workspace.ID, TaskID: &task.ID}
+	_, err = db.Connection.CreateHistory(history)
+	assert.Nil(t, err)
+	taskJob, err := db.Connection.NewTaskJob(task.ID, "ssrf headers test", db.TaskJobRunning, history.ID)
+	assert.Nil(t, err)
+
+	id := strings.ToLower(lib.GenerateRandomString(20))
+	interactionData := integrations.InteractionDomain{ID: id, URL: id + ".oast.fun"}
+	payload := buildSSRFHeaderPayload(ssrfHeaderPayloadURL, interactionData.URL)
+	options := ActiveModuleOptions{WorkspaceID: workspace.ID, TaskID: task.ID, TaskJobID: taskJob.ID}
+
+	oobTest := newSSRFHeaderOOBTest(history, "Referer", payload, interactionData, options)
+	_, err = db.Connection.CreateOOBTest(oobTest)
+	assert.Nil(t, err)
+
+	interaction := db.OOBInteraction{
+		Protocol:      "http",
+		FullID:        id,
+		UniqueID:      id,
+		RawRequest:    "GET / HTTP/1.1\r\nHost: " + interactionData.URL + "\r\n\r\n",
+		RemoteAddress: "127.0.0.1",
+		Timestamp:     time.Now(),
+	}
+	_, err = db.Connection.CreateInteraction(&interaction)
+	assert.Nil(t, err)
+
+	matched, err := db.Connection.MatchInteractionWithOOBTest(interaction)
+	assert.Nil(t, err)
+	assert.Equal(t, db.SsrfCode, matched.Code)
+	assert.Equal(t, "Referer header", matched.InsertionPoint)
+	assert.Equal(t, payload, matched.Payload)
+	assert.Equal(t, history.URL, matched.Target)
+}
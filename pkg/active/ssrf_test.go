@@ -0,0 +1,135 @@
+package active
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/pkg/scan"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSSRFInsertionPoints(t *testing.T) {
+	insertionPoints := []scan.InsertionPoint{
+		{Type: scan.InsertionPointTypeParameter, Name: "q", Value: "shoes", ValueType: lib.TypeString},
+		{Type: scan.InsertionPointTypeParameter, Name: "next", Value: "https://example.com/cart", ValueType: lib.TypeURL},
+		{Type: scan.InsertionPointTypeBody, Name: "webhook", Value: "", ValueType: lib.TypeString},
+		{Type: scan.InsertionPointTypeURLPath, Name: "url", Value: "url", ValueType: lib.TypeString},
+	}
+
+	smart := getSSRFInsertionPoints(insertionPoints, scan_options.ScanModeSmart)
+	assert.Len(t, smart, 2)
+	assert.Equal(t, "next", smart[0].Name)
+	assert.Equal(t, "webhook", smart[1].Name)
+
+	assert.Len(t, getSSRFInsertionPoints(insertionPoints, scan_options.ScanModeFuzz), 4)
+}
+
+func TestGetSSRFPayloadFormatsForMode(t *testing.T) {
+	assert.Equal(t, []string{"http://%s/"}, getSSRFPayloadFormatsForMode(scan_options.ScanModeFast))
+	assert.Len(t, getSSRFPayloadFormatsForMode(scan_options.ScanModeSmart), 4)
+	assert.Len(t, getSSRFPayloadFormatsForMode(scan_options.ScanModeFuzz), len(ssrfPayloadFormats))
+	assert.Len(t, getSSRFMetadataEndpointsForMode(scan_options.ScanModeFast), 1)
+}
+
+func TestSSRFPayloadPlacement(t *testing.T) {
+	history := &db.History{
+		URL:                "https://example.com/preview?url=https%3A%2F%2Fexample.com%2Fimage.png&size=1",
+		Method:             "POST",
+		RequestHeaders:     []byte(`{"Content-Type":["application/x-www-form-urlencoded"]}`),
+		RequestBody:        []byte("callback=https%3A%2F%2Fexample.com%2Fdone&name=test"),
+		RequestContentType: "application/x-www-form-urlencoded",
+	}
+	insertionPoints, err := scan.GetInsertionPoints(history, []string{"parameters"})
+	assert.Nil(t, err)
+	scanInsertionPoints := getSSRFInsertionPoints(insertionPoints, scan_options.ScanModeSmart)
+	assert.Len(t, scanInsertionPoints, 2)
+
+	payload := "http://abc.oast.fun/"
+	for _, insertionPoint := range scanInsertionPoints {
+		request, err := scan.CreateRequestFromInsertionPoints(history, []scan.InsertionPointBuilder{
+			{Point: insertionPoint, Payload: payload},
+		})
+		assert.Nil(t, err)
+		body, err := io.ReadAll(request.Body)
+		assert.Nil(t, err)
+		switch insertionPoint.Name {
+		case "url":
+			assert.Equal(t, payload, request.URL.Query().Get("url"))
+			assert.Equal(t, "1", request.URL.Query().Get("size"))
+			assert.Contains(t, string(body), "callback=https%3A%2F%2Fexample.com%2Fdone")
+		case "callback":
+			assert.Equal(t, "https://example.com/image.png", request.URL.Query().Get("url"))
+			assert.Contains(t, string(body), "callback=http%3A%2F%2Fabc.oast.fun%2F")
+			assert.Contains(t, string(body), "name=test")
+		default:
+			t.Errorf("unexpected insertion point %s", insertionPoint.String())
+		}
+	}
+}
+
+func TestMatchSSRFMetadata(t *testing.T) {
+	aws := ssrfMetadataEndpoints[0]
+	marker, found := matchSSRFMetadata([]byte("<img src=\"preview.png\">"), []byte("ami-id\nami-launch-index\nhostname\ninstance-id"), aws)
+	assert.True(t, found)
+	assert.Equal(t, "ami-id", marker)
+
+	_, found = matchSSRFMetadata([]byte("instance-id: 1"), []byte("instance-id: 1"), aws)
+	assert.False(t, found)
+
+	marker, found = matchSSRFMetadata(nil, []byte(`{"error": "Bad request. Required metadata header not specified"}`), ssrfMetadataEndpoints[2])
+	assert.True(t, found)
+	assert.Equal(t, "Required metadata header not specified", marker)
+}
+
+func TestSSRFOOBTestCorrelation(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "ssrf-test",
+		Title:       "ssrf test workspace",
+		Description: "Workspace for SSRF tests",
+	})
+	assert.Nil(t, err)
+	task, err := db.Connection.NewTask(workspace.ID, nil, "ssrf test", "running", db.TaskTypeScan)
+	assert.Nil(t, err)
+
+	history := &db.History{URL: "https://example.com/preview?url=https%3A%2F%2Fexample.com%2F", Method: "GET", StatusCode: 200, WorkspaceID: &workspace.ID, TaskID: &task.ID}
+	_, err = db.Connection.CreateHistory(history)
+	assert.Nil(t, err)
+	taskJob, err := db.Connection.NewTaskJob(task.ID, "ssrf test", db.TaskJobRunning, history.ID)
+	assert.Nil(t, err)
+
+	id := strings.ToLower(lib.GenerateRandomString(20))
+	interactionData := integrations.InteractionDomain{ID: id, URL: id + ".oast.fun"}
+	insertionPoint := scan.InsertionPoint{Type: scan.InsertionPointTypeParameter, Name: "url", Value: "https://example.com/", ValueType: lib.TypeURL}
+	payload := "http://" + interactionData.URL + "/"
+	options := ActiveModuleOptions{WorkspaceID: workspace.ID, TaskID: task.ID, TaskJobID: taskJob.ID}
+
+	_, err = db.Connection.CreateOOBTest(newSSRFOOBTest(history, insertionPoint, payload, interactionData, options))
+	assert.Nil(t, err)
+
+	interaction := db.OOBInteraction{
+		Protocol:      "http",
+		FullID:        id,
+		UniqueID:      id,
+		RawRequest:    "GET / HTTP/1.1\r\nHost: " + interactionData.URL + "\r\n\r\n",
+		RemoteAddress: "127.0.0.1",
+		Timestamp:     time.Now(),
+	}
+	_, err = db.Connection.CreateInteraction(&interaction)
+	assert.Nil(t, err)
+
+	matched, err := db.Connection.MatchInteractionWithOOBTest(interaction)
+	assert.Nil(t, err)
+	assert.Equal(t, db.SsrfCode, matched.Code)
+	assert.Equal(t, "parameter: url", matched.InsertionPoint)
+	assert.Equal(t, "Server Side Request Forgery via parameter", matched.TestName)
+	assert.Equal(t, payload, matched.Payload)
+	assert.Equal(t, history.URL, matched.Target)
+
+	assert.Greater(t, db.OOBInteractionConfidence("http"), db.OOBInteractionConfidence("dns"))
+}
@@ -0,0 +1,205 @@
+package active
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/scan"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// sstiProbe is a template expression syntax, where %s is replaced by a multiplication, together with the engines that
+// evaluate it
+type sstiProbe struct {
+	Syntax       string
+	Engines      []string
+	Fingerprints []sstiFingerprint
+}
+
+// sstiFingerprint is an expression whose result depends on the template engine evaluating it
+type sstiFingerprint struct {
+	Engine     string
+	Expression string
+	Result     string
+}
+
+var sstiProbes = []sstiProbe{
+	{
+		Syntax:  "{{%s}}",
+		Engines: []string{"Jinja2", "Twig", "Nunjucks", "Tornado"},
+		Fingerprints: []sstiFingerprint{
+			// Python repeats the string while PHP and JavaScript convert it to a number
+			{Engine: "Jinja2", Expression: "{{7*'7'}}", Result: "7777777"},
+			{Engine: "Twig", Expression: "{{7*'7'}}", Result: "49"},
+		},
+	},
+	{
+		Syntax:  "${%s}",
+		Engines: []string{"Freemarker", "Mako", "Java EL"},
+		Fingerprints: []sstiFingerprint{
+			{Engine: "Freemarker", Expression: `${"sukyan"?upper_case}`, Result: "SUKYAN"},
+			{Engine: "Mako", Expression: `${"sukyan".upper()}`, Result: "SUKYAN"},
+			{Engine: "Java EL", Expression: `${"sukyan".toUpperCase()}`, Result: "SUKYAN"},
+		},
+	},
+	{
+		Syntax:  "<%%= %s %%>",
+		Engines: []string{"ERB", "EJS"},
+		Fingerprints: []sstiFingerprint{
+			{Engine: "ERB", Expression: `<%= "sukyan".upcase %>`, Result: "SUKYAN"},
+			{Engine: "EJS", Expression: `<%= "sukyan".toUpperCase() %>`, Result: "SUKYAN"},
+		},
+	},
+	{
+		Syntax:  "#{%s}",
+		Engines: []string{"Ruby interpolation (Slim, Haml)", "Java EL (JSF)"},
+		Fingerprints: []sstiFingerprint{
+			{Engine: "Ruby interpolation (Slim, Haml)", Expression: `#{"sukyan".upcase}`, Result: "SUKYAN"},
+			{Engine: "Java EL (JSF)", Expression: `#{"sukyan".toUpperCase()}`, Result: "SUKYAN"},
+		},
+	},
+	{
+		Syntax:  "*{%s}",
+		Engines: []string{"Thymeleaf"},
+	},
+	{
+		Syntax:  "@(%s)",
+		Engines: []string{"Razor"},
+	},
+	{
+		Syntax:  "#set($x=%s)${x}",
+		Engines: []string{"Velocity"},
+	},
+}
+
+// sstiPayload is a probe wrapped between random markers, so the evaluated result can be told apart from any other
+// occurrence of the number in the response
+type sstiPayload struct {
+	Probe    sstiProbe
+	Prefix   string
+	Suffix   string
+	Value    string
+	Expected string
+}
+
+func newSSTIPayload(probe sstiProbe, a, b int, prefix, suffix string) sstiPayload {
+	expression := fmt.Sprintf("%d*%d", a, b)
+	return sstiPayload{
+		Probe:    probe,
+		Prefix:   prefix,
+		Suffix:   suffix,
+		Value:    prefix + fmt.Sprintf(probe.Syntax, expression) + suffix,
+		Expected: prefix + strconv.Itoa(a*b) + suffix,
+	}
+}
+
+// IsEvaluated checks if the response contains the result of the expression instead of the expression itself
+func (p sstiPayload) IsEvaluated(body []byte) bool {
+	return bytes.Contains(body, []byte(p.Expected))
+}
+
+// fingerprintValue returns the value to send to check a fingerprint of the payload probe
+func (p sstiPayload) fingerprintValue(fingerprint sstiFingerprint) string {
+	return p.Prefix + fingerprint.Expression + p.Suffix
+}
+
+// detectSSTIEngine returns the engine whose fingerprint result is found in the response to its expression. When no
+// fingerprint matches, the engines using the probe syntax are returned.
+func detectSSTIEngine(payload sstiPayload, responses map[string][]byte) (string, bool) {
+	for _, fingerprint := range payload.Probe.Fingerprints {
+		body, ok := responses[fingerprint.Expression]
+		if !ok {
+			continue
+		}
+		if bytes.Contains(body, []byte(payload.Prefix+fingerprint.Result+payload.Suffix)) {
+			return fingerprint.Engine, true
+		}
+	}
+	return strings.Join(payload.Probe.Engines, ", "), false
+}
+
+// SSTIScan injects template expressions using the syntax of the most common template engines in the insertion points,
+// reporting the ones that get evaluated and fingerprinting the template engine
+func SSTIScan(history *db.History, insertionPoints []scan.InsertionPoint, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "ssti").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if options.Concurrency == 0 {
+		options.Concurrency = 5
+	}
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, insertionPoint := range insertionPoints {
+		insertionPoint := insertionPoint
+		p.Go(func() {
+			for _, probe := range sstiProbes {
+				payload := newSSTIPayload(probe, lib.GenerateRandInt(100, 999), lib.GenerateRandInt(100, 999), lib.GenerateRandomLowercaseString(4), lib.GenerateRandomLowercaseString(4))
+				newHistory, err := sendSSTIPayload(client, history, insertionPoint, payload.Value, options)
+				if err != nil {
+					auditLog.Error().Err(err).Str("insertion_point", insertionPoint.Name).Str("payload", payload.Value).Msg("Error sending SSTI payload")
+					continue
+				}
+				if !payload.IsEvaluated(newHistory.ResponseBody) {
+					continue
+				}
+
+				responses := make(map[string][]byte)
+				for _, fingerprint := range probe.Fingerprints {
+					if _, sent := responses[fingerprint.Expression]; sent {
+						continue
+					}
+					fingerprintHistory, err := sendSSTIPayload(client, history, insertionPoint, payload.fingerprintValue(fingerprint), options)
+					if err != nil {
+						auditLog.Error().Err(err).Str("insertion_point", insertionPoint.Name).Str("engine", fingerprint.Engine).Msg("Error sending SSTI fingerprint")
+						continue
+					}
+					responses[fingerprint.Expression] = fingerprintHistory.ResponseBody
+				}
+				engine, fingerprinted := detectSSTIEngine(payload, responses)
+				auditLog.Info().Str("insertion_point", insertionPoint.Name).Str("payload", payload.Value).Str("engine", engine).Msg("Template expression evaluated")
+
+				var sb strings.Builder
+				sb.WriteString(fmt.Sprintf("The template expression `%s` sent in the %s `%s` has been evaluated by the server, as the response contains its result `%s`.\n\n", payload.Value, insertionPoint.Type, insertionPoint.Name, payload.Expected))
+				confidence := 90
+				if fingerprinted {
+					sb.WriteString(fmt.Sprintf("The template engine has been identified as %s.", engine))
+					confidence = 95
+				} else {
+					sb.WriteString(fmt.Sprintf("The expression syntax is used by the following template engines: %s.", engine))
+				}
+				db.CreateIssueFromHistoryAndTemplateWithOptions(newHistory, db.SstiCode, sb.String(), confidence, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: insertionPoint.String()})
+				return
+			}
+		})
+	}
+	p.Wait()
+}
+
+func sendSSTIPayload(client *http.Client, history *db.History, insertionPoint scan.InsertionPoint, payload string, options ActiveModuleOptions) (*db.History, error) {
+	request, err := scan.CreateRequestFromInsertionPoints(history, []scan.InsertionPointBuilder{
+		{
+			Point:   insertionPoint,
+			Payload: payload,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	})
+}
@@ -0,0 +1,77 @@
+package active
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSTIPayloadIsEvaluated(t *testing.T) {
+	payload := newSSTIPayload(sstiProbes[0], 123, 456, "abcd", "wxyz")
+	assert.Equal(t, "abcd{{123*456}}wxyz", payload.Value)
+	assert.Equal(t, "abcd56088wxyz", payload.Expected)
+
+	assert.True(t, payload.IsEvaluated([]byte("<p>Hello abcd56088wxyz</p>")))
+	// Reflected without being evaluated
+	assert.False(t, payload.IsEvaluated([]byte("<p>Hello abcd{{123*456}}wxyz</p>")))
+	// The result without the markers could be part of any other content
+	assert.False(t, payload.IsEvaluated([]byte("<p>Order 56088</p>")))
+
+	erb := newSSTIPayload(sstiProbes[2], 12, 34, "abcd", "wxyz")
+	assert.Equal(t, "abcd<%= 12*34 %>wxyz", erb.Value)
+	assert.True(t, erb.IsEvaluated([]byte("abcd408wxyz")))
+
+	velocity := newSSTIPayload(sstiProbes[6], 2, 3, "abcd", "wxyz")
+	assert.Equal(t, "abcd#set($x=2*3)${x}wxyz", velocity.Value)
+}
+
+func TestDetectSSTIEngine(t *testing.T) {
+	curly := newSSTIPayload(sstiProbes[0], 123, 456, "abcd", "wxyz")
+	assert.Equal(t, "abcd{{7*'7'}}wxyz", curly.fingerprintValue(curly.Probe.Fingerprints[0]))
+
+	engine, fingerprinted := detectSSTIEngine(curly, map[string][]byte{"{{7*'7'}}": []byte("abcd7777777wxyz")})
+	assert.True(t, fingerprinted)
+	assert.Equal(t, "Jinja2", engine)
+
+	engine, fingerprinted = detectSSTIEngine(curly, map[string][]byte{"{{7*'7'}}": []byte("abcd49wxyz")})
+	assert.True(t, fingerprinted)
+	assert.Equal(t, "Twig", engine)
+
+	engine, fingerprinted = detectSSTIEngine(curly, map[string][]byte{"{{7*'7'}}": []byte("an error occurred")})
+	assert.False(t, fingerprinted)
+	assert.Equal(t, "Jinja2, Twig, Nunjucks, Tornado", engine)
+
+	dollar := newSSTIPayload(sstiProbes[1], 2, 3, "abcd", "wxyz")
+	engine, fingerprinted = detectSSTIEngine(dollar, map[string][]byte{
+		`${"sukyan"?upper_case}`:    []byte(`abcd${"sukyan"?upper_case}wxyz`),
+		`${"sukyan".upper()}`:       []byte("Internal Server Error"),
+		`${"sukyan".toUpperCase()}`: []byte("abcdSUKYANwxyz"),
+	})
+	assert.True(t, fingerprinted)
+	assert.Equal(t, "Java EL", engine)
+
+	erb := newSSTIPayload(sstiProbes[2], 2, 3, "abcd", "wxyz")
+	engine, fingerprinted = detectSSTIEngine(erb, map[string][]byte{`<%= "sukyan".upcase %>`: []byte("abcdSUKYANwxyz")})
+	assert.True(t, fingerprinted)
+	assert.Equal(t, "ERB", engine)
+
+	engine, fingerprinted = detectSSTIEngine(newSSTIPayload(sstiProbes[5], 2, 3, "abcd", "wxyz"), nil)
+	assert.False(t, fingerprinted)
+	assert.Equal(t, "Razor", engine)
+}
+
+func TestSSTIFingerprintEnginesMatchProbeEngines(t *testing.T) {
+	for _, probe := range sstiProbes {
+		for _, fingerprint := range probe.Fingerprints {
+			assert.Contains(t, probe.Engines, fingerprint.Engine, "probe %s", probe.Syntax)
+		}
+	}
+
+	hash := newSSTIPayload(sstiProbes[3], 2, 3, "abcd", "wxyz")
+	engine, fingerprinted := detectSSTIEngine(hash, map[string][]byte{
+		`#{"sukyan".upcase}`:        []byte("Internal Server Error"),
+		`#{"sukyan".toUpperCase()}`: []byte("abcdSUKYANwxyz"),
+	})
+	assert.True(t, fingerprinted)
+	assert.Equal(t, "Java EL (JSF)", engine)
+}
@@ -0,0 +1,243 @@
+package active
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// https://portswigger.net/research/practical-web-cache-poisoning
+
+// webCachePoisoningCacheBusterParameter is added with a random value to the probed URLs, so only a cache entry
+// unique to the probe can be poisoned and not the one served to the rest of users
+const webCachePoisoningCacheBusterParameter = "cb"
+
+// webCachePoisoningHeader is an unkeyed header commonly trusted by applications, where %s is replaced by the marker.
+// Headers without %s are detected when the response they cause, such as a redirect, is served without sending them.
+type webCachePoisoningHeader struct {
+	Name   string
+	Format string
+}
+
+var webCachePoisoningHeaders = []webCachePoisoningHeader{
+	{Name: "X-Forwarded-Host", Format: "%s"},
+	{Name: "X-Host", Format: "%s"},
+	{Name: "X-Forwarded-Scheme", Format: "http"},
+	{Name: "X-Forwarded-Server", Format: "%s"},
+	{Name: "X-Forwarded-Proto", Format: "http"},
+	{Name: "X-Original-Host", Format: "%s"},
+}
+
+func getWebCachePoisoningHeadersForMode(mode scan_options.ScanMode) []webCachePoisoningHeader {
+	if mode == scan_options.ScanModeFast {
+		return webCachePoisoningHeaders[:3]
+	}
+	return webCachePoisoningHeaders
+}
+
+// webCachePoisoningResponse is a response read by a poisoning attempt, keeping its body readable
+type webCachePoisoningResponse struct {
+	Response *http.Response
+	Body     []byte
+}
+
+// webCachePoisoningAttempt holds the responses of sending the unkeyed header and then requesting the same URL
+// without it
+type webCachePoisoningAttempt struct {
+	Header  webCachePoisoningHeader
+	Payload string
+	Marker  string
+	Poison  webCachePoisoningResponse
+	Verify  webCachePoisoningResponse
+}
+
+// addCacheBuster sets the cache buster parameter of the request URL to the provided value
+func addCacheBuster(request *http.Request, value string) {
+	query := request.URL.Query()
+	query.Set(webCachePoisoningCacheBusterParameter, value)
+	request.URL.RawQuery = query.Encode()
+}
+
+func readWebCachePoisoningResponse(client http_utils.RequestExecutor, request *http.Request) (webCachePoisoningResponse, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return webCachePoisoningResponse{}, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return webCachePoisoningResponse{}, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return webCachePoisoningResponse{Response: response, Body: body}, nil
+}
+
+// runWebCachePoisoningAttempt sends a request with the unkeyed header and then the same request without it, using the
+// same cache buster for both, so the second one is served the first response when it has been cached
+func runWebCachePoisoningAttempt(client http_utils.RequestExecutor, newRequest func() (*http.Request, error), header webCachePoisoningHeader, marker, cacheBuster string) (*webCachePoisoningAttempt, error) {
+	attempt := &webCachePoisoningAttempt{Header: header, Marker: marker}
+	if strings.Contains(header.Format, "%s") {
+		attempt.Payload = fmt.Sprintf(header.Format, marker)
+	} else {
+		attempt.Payload = header.Format
+	}
+
+	poisonRequest, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	addCacheBuster(poisonRequest, cacheBuster)
+	poisonRequest.Header.Set(header.Name, attempt.Payload)
+	attempt.Poison, err = readWebCachePoisoningResponse(client, poisonRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyRequest, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	addCacheBuster(verifyRequest, cacheBuster)
+	verifyRequest.Header.Del(header.Name)
+	attempt.Verify, err = readWebCachePoisoningResponse(client, verifyRequest)
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// poisoned checks if the response to the request without the header has been affected by the one sending it. Marker
+// headers are detected when the marker is also reflected in the second response, while the rest when the second
+// response keeps a status code or redirect different to the one of the original request.
+func (a *webCachePoisoningAttempt) poisoned(baselineStatusCode int) (string, bool) {
+	if a.Poison.Response == nil || a.Verify.Response == nil {
+		return "", false
+	}
+	if strings.Contains(a.Header.Format, "%s") {
+		if _, found := findHostHeaderReflection(a.Poison.Response.StatusCode, a.Poison.Response.Header, a.Poison.Body, a.Marker); !found {
+			return "", false
+		}
+		reflection, found := findHostHeaderReflection(a.Verify.Response.StatusCode, a.Verify.Response.Header, a.Verify.Body, a.Marker)
+		if !found {
+			return "", false
+		}
+		switch reflection.Kind {
+		case hostHeaderReflectionRedirect:
+			return fmt.Sprintf("redirects to `%s` through a %s redirection", reflection.Value, reflection.Location), true
+		case hostHeaderReflectionHeader:
+			return fmt.Sprintf("contains the marker in the `%s` header: `%s`", reflection.Location, reflection.Value), true
+		case hostHeaderReflectionAbsoluteURL:
+			return fmt.Sprintf("contains the absolute URL `%s` in the body", reflection.Value), true
+		default:
+			return fmt.Sprintf("contains the marker `%s` in the body", a.Marker), true
+		}
+	}
+
+	poisonStatus := a.Poison.Response.StatusCode
+	if poisonStatus == baselineStatusCode || a.Verify.Response.StatusCode != poisonStatus {
+		return "", false
+	}
+	if a.Poison.Response.Header.Get("Location") != a.Verify.Response.Header.Get("Location") {
+		return "", false
+	}
+	evidence := fmt.Sprintf("has the status code %d instead of %d", poisonStatus, baselineStatusCode)
+	if location := a.Verify.Response.Header.Get("Location"); location != "" {
+		evidence += fmt.Sprintf(" and redirects to `%s`", location)
+	}
+	return evidence, true
+}
+
+// WebCachePoisoningScan sends unkeyed headers such as X-Forwarded-Host with a marker and then requests the same URL
+// without them, reporting the headers whose effect is served from the cache to requests not sending them. A random
+// cache buster parameter is added to both requests so the real cache entries are never poisoned.
+func WebCachePoisoningScan(history *db.History, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "web-cache-poisoning").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if method := strings.ToUpper(history.Method); method != "GET" && method != "HEAD" {
+		auditLog.Debug().Str("method", history.Method).Msg("Skipping web cache poisoning audit for a non cacheable method")
+		return
+	}
+	if options.Concurrency == 0 {
+		options.Concurrency = 3
+	}
+
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	newRequest := func() (*http.Request, error) {
+		return http_utils.BuildRequestFromHistoryItem(history)
+	}
+	historyOptions := http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	}
+	p := pool.New().WithMaxGoroutines(options.Concurrency)
+
+	for _, header := range getWebCachePoisoningHeadersForMode(options.ScanMode) {
+		header := header
+		p.Go(func() {
+			marker := lib.GenerateRandomLowercaseString(10) + ".com"
+			cacheBuster := lib.GenerateRandomLowercaseString(12)
+			attempt, err := runWebCachePoisoningAttempt(client, newRequest, header, marker, cacheBuster)
+			if err != nil {
+				auditLog.Error().Err(err).Str("header", header.Name).Msg("Error during web cache poisoning attempt")
+				return
+			}
+			poisonHistory, err := http_utils.ReadHttpResponseAndCreateHistory(attempt.Poison.Response, historyOptions)
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating history from response")
+				return
+			}
+			verifyHistory, err := http_utils.ReadHttpResponseAndCreateHistory(attempt.Verify.Response, historyOptions)
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error creating history from response")
+				return
+			}
+
+			evidence, poisoned := attempt.poisoned(history.StatusCode)
+			if !poisoned {
+				return
+			}
+			auditLog.Info().Str("header", header.Name).Str("evidence", evidence).Msg("Web cache poisoning found")
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("The `%s` header has been sent with the value `%s` to `%s`. ", header.Name, attempt.Payload, poisonHistory.URL))
+			sb.WriteString(fmt.Sprintf("The same URL has then been requested without the header, and the response %s, which shows that the response to the first request has been cached and served to a request not sending the header.", evidence))
+			sb.WriteString(fmt.Sprintf("\n\nThe `%s` cache buster parameter has been used so only a cache entry unique to this test has been poisoned.", webCachePoisoningCacheBusterParameter))
+			confidence := 80
+			cache := http_utils.ParseCacheHeaders(attempt.Verify.Response.Header)
+			if cache != nil && cache.Hit {
+				confidence = 95
+			}
+			if cache != nil && len(cache.Headers) > 0 {
+				names := make([]string, 0, len(cache.Headers))
+				for name := range cache.Headers {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				sb.WriteString("\n\nThe following cache headers have been observed in the second response:\n")
+				for _, name := range names {
+					sb.WriteString(fmt.Sprintf("\n - %s: %s", name, cache.Headers[name]))
+				}
+			}
+			db.CreateIssueFromHistoryAndTemplateWithOptions(verifyHistory, db.WebCachePoisoningCode, sb.String(), confidence, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{
+				InsertionPoint: fmt.Sprintf("%s header", header.Name),
+			})
+		})
+	}
+	p.Wait()
+	auditLog.Info().Msg("Web cache poisoning audit completed")
+}
@@ -0,0 +1,131 @@
+package active
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubCachingClient simulates an application behind a cache which is keyed on the URL and, optionally, on some
+// request headers
+type stubCachingClient struct {
+	keyedHeaders []string
+	cache        map[string]*http.Response
+	cacheBodies  map[string]string
+	requests     []*http.Request
+	handler      func(r *http.Request) (int, http.Header, string)
+}
+
+func newStubCachingClient(handler func(r *http.Request) (int, http.Header, string), keyedHeaders ...string) *stubCachingClient {
+	return &stubCachingClient{
+		keyedHeaders: keyedHeaders,
+		cache:        make(map[string]*http.Response),
+		cacheBodies:  make(map[string]string),
+		handler:      handler,
+	}
+}
+
+func (c *stubCachingClient) Do(r *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, r)
+	key := r.URL.String()
+	for _, header := range c.keyedHeaders {
+		key += "|" + r.Header.Get(header)
+	}
+	if cached, ok := c.cache[key]; ok {
+		header := cached.Header.Clone()
+		header.Set("X-Cache", "HIT")
+		return &http.Response{StatusCode: cached.StatusCode, Header: header, Body: io.NopCloser(strings.NewReader(c.cacheBodies[key])), Request: r}, nil
+	}
+	status, header, body := c.handler(r)
+	header.Set("X-Cache", "MISS")
+	response := &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: r}
+	c.cache[key] = response
+	c.cacheBodies[key] = body
+	return response, nil
+}
+
+func reflectForwardedHost(r *http.Request) (int, http.Header, string) {
+	host := r.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+	return 200, http.Header{"Content-Type": {"text/html"}}, fmt.Sprintf(`<html><head><script src="https://%s/static/app.js"></script></head></html>`, host)
+}
+
+func newStubRequest() (*http.Request, error) {
+	return http.NewRequest("GET", "https://example.com/en/index.html?page=1", nil)
+}
+
+func TestWebCachePoisoningAttemptPoisoned(t *testing.T) {
+	client := newStubCachingClient(reflectForwardedHost)
+	header := webCachePoisoningHeader{Name: "X-Forwarded-Host", Format: "%s"}
+	attempt, err := runWebCachePoisoningAttempt(client, newStubRequest, header, "abcdefghij.com", "buster")
+	assert.Nil(t, err)
+
+	if assert.Len(t, client.requests, 2) {
+		assert.Equal(t, "abcdefghij.com", client.requests[0].Header.Get("X-Forwarded-Host"))
+		assert.Empty(t, client.requests[1].Header.Get("X-Forwarded-Host"))
+		for _, request := range client.requests {
+			assert.Equal(t, "buster", request.URL.Query().Get(webCachePoisoningCacheBusterParameter))
+			assert.Equal(t, "1", request.URL.Query().Get("page"))
+		}
+	}
+	assert.Equal(t, "HIT", attempt.Verify.Response.Header.Get("X-Cache"))
+
+	evidence, poisoned := attempt.poisoned(200)
+	assert.True(t, poisoned)
+	assert.Contains(t, evidence, "https://abcdefghij.com/")
+}
+
+func TestWebCachePoisoningAttemptNotCached(t *testing.T) {
+	// Keying the cache on the header means the second request gets its own response
+	client := newStubCachingClient(reflectForwardedHost, "X-Forwarded-Host")
+	header := webCachePoisoningHeader{Name: "X-Forwarded-Host", Format: "%s"}
+	attempt, err := runWebCachePoisoningAttempt(client, newStubRequest, header, "abcdefghij.com", "buster")
+	assert.Nil(t, err)
+	assert.Equal(t, "MISS", attempt.Verify.Response.Header.Get("X-Cache"))
+	_, poisoned := attempt.poisoned(200)
+	assert.False(t, poisoned)
+}
+
+func TestWebCachePoisoningAttemptNotReflected(t *testing.T) {
+	client := newStubCachingClient(func(r *http.Request) (int, http.Header, string) {
+		return 200, http.Header{}, "<html>Hello</html>"
+	})
+	header := webCachePoisoningHeader{Name: "X-Forwarded-Host", Format: "%s"}
+	attempt, err := runWebCachePoisoningAttempt(client, newStubRequest, header, "abcdefghij.com", "buster")
+	assert.Nil(t, err)
+	_, poisoned := attempt.poisoned(200)
+	assert.False(t, poisoned)
+}
+
+func TestWebCachePoisoningAttemptSchemeRedirect(t *testing.T) {
+	handler := func(r *http.Request) (int, http.Header, string) {
+		if r.Header.Get("X-Forwarded-Scheme") == "http" {
+			return 301, http.Header{"Location": {"https://example.com/en/index.html"}}, ""
+		}
+		return 200, http.Header{}, "<html>Hello</html>"
+	}
+	header := webCachePoisoningHeader{Name: "X-Forwarded-Scheme", Format: "http"}
+
+	attempt, err := runWebCachePoisoningAttempt(newStubCachingClient(handler), newStubRequest, header, "abcdefghij.com", "buster")
+	assert.Nil(t, err)
+	evidence, poisoned := attempt.poisoned(200)
+	assert.True(t, poisoned)
+	assert.Equal(t, "has the status code 301 instead of 200 and redirects to `https://example.com/en/index.html`", evidence)
+
+	attempt, err = runWebCachePoisoningAttempt(newStubCachingClient(handler, "X-Forwarded-Scheme"), newStubRequest, header, "abcdefghij.com", "buster")
+	assert.Nil(t, err)
+	_, poisoned = attempt.poisoned(200)
+	assert.False(t, poisoned)
+}
+
+func TestGetWebCachePoisoningHeadersForMode(t *testing.T) {
+	assert.Len(t, getWebCachePoisoningHeadersForMode(scan_options.ScanModeFast), 3)
+	assert.Len(t, getWebCachePoisoningHeadersForMode(scan_options.ScanModeFuzz), len(webCachePoisoningHeaders))
+}
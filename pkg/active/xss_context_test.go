@@ -0,0 +1,69 @@
+package active
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/pkg/payloads"
+	"github.com/pyneda/sukyan/pkg/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func payloadValues(selected []payloads.PayloadInterface) []string {
+	values := make([]string, 0, len(selected))
+	for _, payload := range selected {
+		values = append(values, payload.GetValue())
+	}
+	return values
+}
+
+func TestGetXSSPayloadsForReflectionContexts(t *testing.T) {
+	tests := []struct {
+		context  scan.ReflectionContext
+		contains []string
+		excludes []string
+	}{
+		{scan.ReflectionContextHTML, []string{`<script>alert(1)</script>`, `<img src=1 onerror=alert(1)>`}, []string{`javascript:alert(1)`, `'-alert()-'`, `" onmouseover="alert(1)"`}},
+		{scan.ReflectionContextDoubleQuotedAttribute, []string{`"><svg onload=alert()>`, `" onmouseover="alert(1)"`}, []string{`' onmouseover='alert(1)'`, `<script>alert(1)</script>`}},
+		{scan.ReflectionContextSingleQuotedAttribute, []string{`'><svg onload=alert()>`, `' onmouseover='alert(1)'`}, []string{`" onmouseover="alert(1)"`}},
+		{scan.ReflectionContextUnquotedAttribute, []string{`><svg onload=alert()>`, ` onmouseover=alert(1) `}, []string{`"><svg onload=alert()>`}},
+		{scan.ReflectionContextScriptString, []string{`'-alert()-'`, `";alert(1)//`, `</script><svg onload=alert()>`}, []string{`alert(1)`, `<img src=1 onerror=alert(1)>`}},
+		{scan.ReflectionContextScript, []string{`alert(1)`, `;alert(1)//`, `</script><svg onload=alert()>`}, []string{`'-alert()-'`, `javascript:alert(1)`}},
+		{scan.ReflectionContextURL, []string{`javascript:alert(1)`}, []string{`<script>alert(1)</script>`}},
+		{scan.ReflectionContextStyle, []string{`</style><svg onload=alert(1)>`}, []string{`alert(1)`}},
+		{scan.ReflectionContextComment, []string{`--><svg onload=alert(1)>`}, []string{`alert(1)`}},
+	}
+	all := payloads.GetXSSPayloads()
+	for _, tt := range tests {
+		t.Run(string(tt.context), func(t *testing.T) {
+			values := payloadValues(GetXSSPayloadsForReflectionContexts([]string{string(tt.context)}))
+			assert.Less(t, len(values), len(all))
+			for _, value := range tt.contains {
+				assert.Contains(t, values, value)
+			}
+			for _, value := range tt.excludes {
+				assert.NotContains(t, values, value)
+			}
+		})
+	}
+}
+
+func TestGetXSSPayloadsForMultipleReflectionContexts(t *testing.T) {
+	values := payloadValues(GetXSSPayloadsForReflectionContexts([]string{
+		string(scan.ReflectionContextScriptString),
+		string(scan.ReflectionContextScript),
+	}))
+	assert.Contains(t, values, `'-alert()-'`)
+	assert.Contains(t, values, `;alert(1);`)
+	seen := make(map[string]bool)
+	for _, value := range values {
+		assert.False(t, seen[value], "Duplicated payload %s", value)
+		seen[value] = true
+	}
+}
+
+func TestGetXSSPayloadsForUnknownReflectionContexts(t *testing.T) {
+	all := payloadValues(payloads.GetXSSPayloads())
+	assert.NotEmpty(t, all)
+	assert.Equal(t, all, payloadValues(GetXSSPayloadsForReflectionContexts(nil)))
+	assert.Equal(t, all, payloadValues(GetXSSPayloadsForReflectionContexts([]string{"unknown"})))
+}
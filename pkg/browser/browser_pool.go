@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-rod/rod"
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -17,7 +18,16 @@ var (
 // GetBrowserPoolManager returns a singleton instance of BrowserPoolManager used by active scanners
 func GetScannerBrowserPoolManager() *BrowserPoolManager {
 	once.Do(func() {
-		scannerBrowserPool = NewBrowserPoolManager(BrowserPoolManagerConfig{PoolSize: viper.GetInt("scan.browser.pool_size"), Source: db.SourceScanner}, 0, 0)
+		config := BrowserPoolManagerConfig{PoolSize: viper.GetInt("scan.browser.pool_size"), Source: db.SourceScanner}
+		if path := viper.GetString("scan.browser.session_state_file"); path != "" {
+			state, err := LoadBrowserStateFromFile(path)
+			if err != nil {
+				log.Error().Err(err).Str("path", path).Msg("Could not load browser session state, scanner pages will start without it")
+			} else {
+				config.SessionState = state
+			}
+		}
+		scannerBrowserPool = NewBrowserPoolManager(config, 0, 0)
 	})
 	return scannerBrowserPool
 }
@@ -25,6 +35,8 @@ func GetScannerBrowserPoolManager() *BrowserPoolManager {
 type BrowserPoolManagerConfig struct {
 	PoolSize int
 	Source   string
+	// SessionState is restored in every page created through NewPage
+	SessionState *BrowserState
 }
 
 type BrowserPoolManager struct {
@@ -84,6 +96,21 @@ func (b *BrowserPoolManager) NewBrowser() *rod.Browser {
 	return browser
 }
 
+// NewPage creates a page in the provided browser sending the provided scan headers, restoring the configured session
+// state if any
+func (b *BrowserPoolManager) NewPage(browser *rod.Browser, headers http_utils.ScanHeadersConfig) *rod.Page {
+	page := browser.MustPage("")
+	if err := ApplyScanHeaders(page, headers); err != nil {
+		log.Error().Err(err).Msg("Error setting scan headers on page")
+	}
+	if b.config.SessionState != nil {
+		if err := RestoreBrowserState(page, b.config.SessionState); err != nil {
+			log.Error().Err(err).Msg("Error restoring browser session state")
+		}
+	}
+	return page
+}
+
 func (b *BrowserPoolManager) ReleaseBrowser(browser *rod.Browser) {
 	b.pool.Put(browser)
 }
@@ -11,12 +11,14 @@ import (
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/pkg/http_utils"
 	"github.com/pyneda/sukyan/pkg/passive"
+	"github.com/pyneda/sukyan/pkg/scope"
 
 	"fmt"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/proto"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
 	"gorm.io/datatypes"
 )
 
@@ -24,6 +26,9 @@ import (
 type HijackConfig struct {
 	AnalyzeJs   bool
 	AnalyzeHTML bool
+	Exclusions  *scope.URLExclusions
+	// ClientOptions are the settings of the HTTP clients loading the hijacked requests
+	ClientOptions *http_utils.ClientOptions
 }
 
 type HijackResult struct {
@@ -34,6 +39,7 @@ type HijackResult struct {
 func HijackWithContext(config HijackConfig, browser *rod.Browser, source string, resultsChannel chan HijackResult, ctx context.Context, workspaceID, taskID uint) *rod.HijackRouter {
 	router := browser.HijackRequests()
 	ignoreKeywords := []string{"google", "pinterest", "facebook", "instagram", "tiktok", "hotjar", "doubleclick", "yandex", "127.0.0.2"}
+	sourceMapsClient := http_utils.CreateHttpClientWithOptions(config.ClientOptions)
 	router.MustAdd("*", func(hj *rod.Hijack) {
 
 		if hj == nil || hj.Request == nil || hj.Request.URL() == nil {
@@ -83,13 +89,9 @@ func HijackWithContext(config HijackConfig, browser *rod.Browser, source string,
 				}()
 				// Additional check for context cancellation
 				history := CreateHistoryFromHijack(hj.Request, hj.Response, source, "Create history from hijack", workspaceID, taskID, 0)
-				linksFound := passive.ExtractedURLS{}
-				if hj.Request.Type() != "Image" && hj.Request.Type() != "Font" && hj.Request.Type() != "Media" {
-					linksFound = passive.ExtractURLsFromHistoryItem(history)
-				}
 				hijackResult := HijackResult{
 					History:        history,
-					DiscoveredURLs: linksFound.Web,
+					DiscoveredURLs: discoverHijackURLs(history, hj.Request.Type(), sourceMapsClient),
 				}
 
 				select {
@@ -107,10 +109,27 @@ func HijackWithContext(config HijackConfig, browser *rod.Browser, source string,
 
 }
 
+// discoverHijackURLs extracts the web URLs referenced by a hijacked response and, when enabled, the endpoints called
+// from its javascript code and the ones referenced by the original sources of its source map
+func discoverHijackURLs(history *db.History, resourceType proto.NetworkResourceType, client *http.Client) []string {
+	if resourceType == "Image" || resourceType == "Font" || resourceType == "Media" {
+		return []string{}
+	}
+	urls := passive.ExtractURLsFromHistoryItem(history).Web
+	isJavascript := strings.Contains(history.ResponseContentType, "javascript") || strings.Contains(history.ResponseContentType, "ecmascript")
+	if isJavascript && viper.GetBool("crawl.js_endpoints.enabled") {
+		urls = append(urls, passive.ExtractJavascriptEndpoints(string(history.ResponseBody), history.URL)...)
+	}
+	if viper.GetBool("crawl.source_maps.enabled") {
+		urls = append(urls, passive.SourceMapScan(history, client)...)
+	}
+	return urls
+}
+
 func Hijack(config HijackConfig, browser *rod.Browser, source string, resultsChannel chan HijackResult, workspaceID, taskID uint) {
 	router := browser.HijackRequests()
 	ignoreKeywords := []string{"google", "pinterest", "facebook", "instagram", "tiktok", "hotjar", "doubleclick", "yandex", "127.0.0.2"}
-	httpClient := http_utils.CreateHttpClient()
+	httpClient := http_utils.CreateHttpClientWithOptions(config.ClientOptions)
 	router.MustAdd("*", func(ctx *rod.Hijack) {
 		if ctx == nil || ctx.Request == nil || ctx.Request.URL() == nil {
 			log.Error().Msg("Invalid hijack object, request, or URL")
@@ -125,6 +144,11 @@ func Hijack(config HijackConfig, browser *rod.Browser, source string, resultsCha
 			ctx.ContinueRequest(&proto.FetchContinueRequest{})
 			return
 		}
+		if config.Exclusions.IsExcluded(ctx.Request.URL().String()) {
+			log.Debug().Str("url", ctx.Request.URL().String()).Msg("Hijack blocking request to excluded URL")
+			ctx.Response.Fail(proto.NetworkErrorReasonBlockedByClient)
+			return
+		}
 		err := ctx.LoadResponse(httpClient, true)
 		mustSkip := false
 
@@ -143,14 +167,9 @@ func Hijack(config HijackConfig, browser *rod.Browser, source string, resultsCha
 		} else {
 			go func() {
 				history := CreateHistoryFromHijack(ctx.Request, ctx.Response, source, "Create history from hijack", workspaceID, taskID, 0)
-				linksFound := passive.ExtractedURLS{}
-				if ctx.Request.Type() != "Image" && ctx.Request.Type() != "Font" && ctx.Request.Type() != "Media" {
-					linksFound = passive.ExtractURLsFromHistoryItem(history)
-
-				}
 				hijackResult := HijackResult{
 					History:        history,
-					DiscoveredURLs: linksFound.Web,
+					DiscoveredURLs: discoverHijackURLs(history, ctx.Request.Type(), httpClient),
 				}
 				if resultsChannel != nil {
 					resultsChannel <- hijackResult
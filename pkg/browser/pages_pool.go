@@ -4,13 +4,19 @@ import (
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/scope"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
 type PagePoolManagerConfig struct {
-	PoolSize  int
-	UserAgent string
+	PoolSize     int
+	UserAgent    string
+	Exclusions   *scope.URLExclusions
+	SessionState *BrowserState
+	// ClientOptions are the settings of the HTTP clients loading the hijacked requests
+	ClientOptions *http_utils.ClientOptions
 }
 
 type PagePoolManager struct {
@@ -58,7 +64,7 @@ func (b *PagePoolManager) Start(hijack bool, source string) {
 		poolSize = b.config.PoolSize
 	}
 	if hijack {
-		Hijack(HijackConfig{AnalyzeJs: true, AnalyzeHTML: true}, b.browser, source, b.HijackResultsChannel, b.workspaceID, b.taskID)
+		Hijack(HijackConfig{AnalyzeJs: true, AnalyzeHTML: true, Exclusions: b.config.Exclusions, ClientOptions: b.config.ClientOptions}, b.browser, source, b.HijackResultsChannel, b.workspaceID, b.taskID)
 	}
 	// b.pool = rod.NewPagePool(poolSize)
 	b.pool = rod.NewPagePool(poolSize)
@@ -68,15 +74,18 @@ func (b *PagePoolManager) Start(hijack bool, source string) {
 func (b *PagePoolManager) NewPage() *rod.Page {
 	page, err := b.pool.Get(b.createPage)
 	// page.HandleDialog()
-	// Set user-agent provided by browser manager config or config file
 	if err != nil {
 		log.Error().Err(err).Msg("Error getting page from pool")
+		return page
 	}
 
+	// Set user-agent provided by browser manager config, scan options or config file and the scan extra headers
+	headers := b.config.ClientOptions.ScanHeaders()
 	if b.config.UserAgent != "" {
-		_ = page.SetUserAgent(&proto.NetworkSetUserAgentOverride{UserAgent: "Test"})
-	} else if viper.GetString("navigation.user_agent") != "" {
-		_ = page.SetUserAgent(&proto.NetworkSetUserAgentOverride{UserAgent: viper.GetString("navigation.user_agent")})
+		headers.UserAgent = b.config.UserAgent
+	}
+	if err := ApplyScanHeaders(page, headers); err != nil {
+		log.Error().Err(err).Msg("Error setting scan headers on page")
 	}
 
 	return page
@@ -87,7 +96,16 @@ func (b *PagePoolManager) ReleasePage(page *rod.Page) {
 }
 
 func (b *PagePoolManager) createPage() (*rod.Page, error) {
-	return b.browser.Page(proto.TargetCreateTarget{})
+	page, err := b.browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return nil, err
+	}
+	if b.config.SessionState != nil {
+		if err := RestoreBrowserState(page, b.config.SessionState); err != nil {
+			log.Error().Err(err).Msg("Error restoring browser session state")
+		}
+	}
+	return page, nil
 }
 
 func (b *PagePoolManager) Close() {
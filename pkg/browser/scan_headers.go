@@ -0,0 +1,32 @@
+package browser
+
+import (
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/spf13/viper"
+)
+
+// ApplyScanHeaders sets the User-Agent and extra headers of the scan on the page, falling back to the
+// navigation.user_agent config, and keeping the browser default User-Agent when none has been configured.
+func ApplyScanHeaders(page *rod.Page, config http_utils.ScanHeadersConfig) error {
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = viper.GetString("navigation.user_agent")
+	}
+	if userAgent != "" {
+		if err := page.SetUserAgent(&proto.NetworkSetUserAgentOverride{UserAgent: userAgent}); err != nil {
+			return err
+		}
+	}
+	if len(config.ExtraHeaders) == 0 {
+		return nil
+	}
+	dict := make([]string, 0, len(config.ExtraHeaders)*2)
+	for name, value := range config.ExtraHeaders {
+		dict = append(dict, name, value)
+	}
+	// The returned cleanup would disable the network domain, which is needed to keep sending the headers
+	_, err := page.SetExtraHeaders(dict)
+	return err
+}
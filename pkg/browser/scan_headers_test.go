@@ -0,0 +1,43 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyScanHeaders(t *testing.T) {
+	received := make(chan http.Header, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			received <- r.Header.Clone()
+		}
+		w.Write([]byte("<html><body>scan headers</body></html>"))
+	}))
+	defer server.Close()
+
+	config := http_utils.ScanHeadersConfig{
+		UserAgent:    "Scanner/1.0",
+		ExtraHeaders: map[string]string{"X-Staging-Token": "secret"},
+	}
+
+	browser := setupRodBrowser(t, true)
+	defer browser.MustClose()
+	page := browser.MustPage("")
+	assert.Nil(t, ApplyScanHeaders(page, config))
+	page.MustNavigate(server.URL).MustWaitLoad()
+
+	headers := <-received
+	assert.Equal(t, "Scanner/1.0", headers.Get("User-Agent"))
+	assert.Equal(t, "secret", headers.Get("X-Staging-Token"))
+
+	config.UserAgent = "PoolAgent/2.0"
+	assert.Nil(t, ApplyScanHeaders(page, config))
+	page.MustNavigate(server.URL).MustWaitLoad()
+	headers = <-received
+	assert.Equal(t, "PoolAgent/2.0", headers.Get("User-Agent"))
+	assert.Equal(t, "secret", headers.Get("X-Staging-Token"))
+}
@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/browser/actions"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+// sessionRenewalTimeout caps the time spent replaying the login actions
+const sessionRenewalTimeout = 2 * time.Minute
+
+// NewBrowserSessionRenewer returns a session renewal function that replays the login actions in a fresh incognito
+// page sending the provided scan headers, returning the cookies set during the login and storing them in the workspace
+// cookie jar
+func NewBrowserSessionRenewer(loginActions []actions.Action, workspaceID uint, headers http_utils.ScanHeadersConfig) http_utils.SessionRenewFunc {
+	return func(ctx context.Context) ([]*http.Cookie, error) {
+		ctx, cancel := context.WithTimeout(ctx, sessionRenewalTimeout)
+		defer cancel()
+
+		browserPool := GetScannerBrowserPoolManager()
+		b := browserPool.NewBrowser()
+		defer browserPool.ReleaseBrowser(b)
+		incognito, err := b.Incognito()
+		if err != nil {
+			return nil, fmt.Errorf("could not create incognito context: %w", err)
+		}
+		defer incognito.Close()
+		page, err := incognito.Page(proto.TargetCreateTarget{})
+		if err != nil {
+			return nil, fmt.Errorf("could not create page: %w", err)
+		}
+		defer page.Close()
+		if err := ApplyScanHeaders(page, headers); err != nil {
+			log.Error().Err(err).Msg("Error setting scan headers on login page")
+		}
+
+		results, err := actions.ExecuteActions(ctx, page.Context(ctx), loginActions)
+		if err != nil {
+			log.Error().Err(err).Interface("logs", results.Logs).Msg("Could not replay login actions")
+			return nil, err
+		}
+		networkCookies, err := incognito.GetCookies()
+		if err != nil {
+			return nil, fmt.Errorf("could not get cookies: %w", err)
+		}
+		cookies := networkCookiesToHTTP(networkCookies)
+		if workspaceID != 0 {
+			storeWorkspaceCookies(workspaceID, cookies)
+		}
+		return cookies, nil
+	}
+}
+
+// networkCookiesToHTTP converts the cookies retrieved from the browser
+func networkCookiesToHTTP(networkCookies []*proto.NetworkCookie) []*http.Cookie {
+	cookies := make([]*http.Cookie, 0, len(networkCookies))
+	for _, c := range networkCookies {
+		cookie := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.TrimPrefix(c.Domain, "."),
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+		}
+		// Session cookies are reported with a negative expiration
+		if !c.Session && c.Expires > 0 {
+			cookie.Expires = c.Expires.Time()
+		}
+		switch c.SameSite {
+		case proto.NetworkCookieSameSiteStrict:
+			cookie.SameSite = http.SameSiteStrictMode
+		case proto.NetworkCookieSameSiteLax:
+			cookie.SameSite = http.SameSiteLaxMode
+		case proto.NetworkCookieSameSiteNone:
+			cookie.SameSite = http.SameSiteNoneMode
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// storeWorkspaceCookies saves the cookies in the workspace cookie jar, grouped by domain
+func storeWorkspaceCookies(workspaceID uint, cookies []*http.Cookie) {
+	byDomain := make(map[string][]*http.Cookie)
+	for _, cookie := range cookies {
+		byDomain[cookie.Domain] = append(byDomain[cookie.Domain], cookie)
+	}
+	for domain, domainCookies := range byDomain {
+		if err := db.Connection.SetCookiesForURL(workspaceID, &url.URL{Host: domain}, domainCookies); err != nil {
+			log.Error().Err(err).Uint("workspace", workspaceID).Str("domain", domain).Msg("Could not store renewed session cookies")
+		}
+	}
+}
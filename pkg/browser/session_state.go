@@ -0,0 +1,215 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/rs/zerolog/log"
+)
+
+// BrowserState holds a snapshot of the session state of a page, so it can be restored in a different page without
+// needing to replay the login process
+type BrowserState struct {
+	URL            string                      `json:"url"`
+	Origin         string                      `json:"origin"`
+	Cookies        []*proto.NetworkCookie      `json:"cookies"`
+	LocalStorage   map[string]string           `json:"local_storage"`
+	SessionStorage map[string]string           `json:"session_storage"`
+	IndexedDB      []IndexedDBDatabaseSnapshot `json:"indexed_db"`
+}
+
+// IndexedDBDatabaseSnapshot holds the contents of an IndexedDB database. Values are stored as JSON, so data that
+// cannot be serialized (such as blobs) is not preserved.
+type IndexedDBDatabaseSnapshot struct {
+	Name    string                   `json:"name"`
+	Version int                      `json:"version"`
+	Stores  []IndexedDBStoreSnapshot `json:"stores"`
+}
+
+type IndexedDBStoreSnapshot struct {
+	Name          string                    `json:"name"`
+	KeyPath       interface{}               `json:"keyPath"`
+	AutoIncrement bool                      `json:"autoIncrement"`
+	Records       []IndexedDBRecordSnapshot `json:"records"`
+}
+
+type IndexedDBRecordSnapshot struct {
+	Key   interface{} `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+const captureStorageJS = `() => ({
+	localStorage: Object.assign({}, window.localStorage),
+	sessionStorage: Object.assign({}, window.sessionStorage),
+})`
+
+const captureIndexedDBJS = `async () => {
+	if (!window.indexedDB || !indexedDB.databases) {
+		return [];
+	}
+	const request = (r) => new Promise((resolve, reject) => {
+		r.onsuccess = () => resolve(r.result);
+		r.onerror = () => reject(r.error);
+	});
+	const snapshots = [];
+	for (const info of await indexedDB.databases()) {
+		const database = await request(indexedDB.open(info.name, info.version));
+		const snapshot = { name: database.name, version: database.version, stores: [] };
+		for (const storeName of Array.from(database.objectStoreNames)) {
+			const store = database.transaction(storeName, "readonly").objectStore(storeName);
+			const keys = await request(store.getAllKeys());
+			const values = await request(store.getAll());
+			snapshot.stores.push({
+				name: storeName,
+				keyPath: store.keyPath,
+				autoIncrement: store.autoIncrement,
+				records: keys.map((key, i) => ({ key: key, value: values[i] })),
+			});
+		}
+		database.close();
+		snapshots.push(snapshot);
+	}
+	return JSON.parse(JSON.stringify(snapshots));
+}`
+
+// restoreStateJS populates the storage of the page when its origin matches the captured one. It is formatted with
+// the JSON encoded state and is meant to be evaluated before any page script runs.
+const restoreStateJS = `(function(state) {
+	if (window.location.origin !== state.origin) {
+		return;
+	}
+	for (const [key, value] of Object.entries(state.local_storage || {})) {
+		window.localStorage.setItem(key, value);
+	}
+	for (const [key, value] of Object.entries(state.session_storage || {})) {
+		window.sessionStorage.setItem(key, value);
+	}
+	if (!window.indexedDB) {
+		return;
+	}
+	for (const snapshot of state.indexed_db || []) {
+		const open = indexedDB.open(snapshot.name, snapshot.version);
+		open.onupgradeneeded = () => {
+			const database = open.result;
+			for (const store of snapshot.stores) {
+				if (!database.objectStoreNames.contains(store.name)) {
+					const options = { autoIncrement: store.autoIncrement };
+					if (store.keyPath !== null && store.keyPath !== "") {
+						options.keyPath = store.keyPath;
+					}
+					database.createObjectStore(store.name, options);
+				}
+			}
+		};
+		open.onsuccess = () => {
+			const database = open.result;
+			for (const store of snapshot.stores) {
+				if (!database.objectStoreNames.contains(store.name)) {
+					continue;
+				}
+				const objectStore = database.transaction(store.name, "readwrite").objectStore(store.name);
+				for (const record of store.records) {
+					if (objectStore.keyPath !== null) {
+						objectStore.put(record.value);
+					} else {
+						objectStore.put(record.value, record.key);
+					}
+				}
+			}
+			database.close();
+		};
+	}
+})(%s);`
+
+// CaptureBrowserState captures the cookies, localStorage, sessionStorage and IndexedDB contents of the page
+func CaptureBrowserState(page *rod.Page) (*BrowserState, error) {
+	info, err := page.Info()
+	if err != nil {
+		return nil, fmt.Errorf("could not get page info: %w", err)
+	}
+	parsedURL, err := url.Parse(info.URL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse page url: %w", err)
+	}
+	state := &BrowserState{
+		URL:            info.URL,
+		Origin:         fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host),
+		LocalStorage:   make(map[string]string),
+		SessionStorage: make(map[string]string),
+	}
+
+	state.Cookies, err = page.Cookies([]string{info.URL})
+	if err != nil {
+		return nil, fmt.Errorf("could not get page cookies: %w", err)
+	}
+
+	storage, err := page.Eval(captureStorageJS)
+	if err != nil {
+		return nil, fmt.Errorf("could not capture page storage: %w", err)
+	}
+	var storageValues struct {
+		LocalStorage   map[string]string `json:"localStorage"`
+		SessionStorage map[string]string `json:"sessionStorage"`
+	}
+	if err := storage.Value.Unmarshal(&storageValues); err != nil {
+		return nil, fmt.Errorf("could not parse page storage: %w", err)
+	}
+	if storageValues.LocalStorage != nil {
+		state.LocalStorage = storageValues.LocalStorage
+	}
+	if storageValues.SessionStorage != nil {
+		state.SessionStorage = storageValues.SessionStorage
+	}
+
+	indexedDB, err := page.Eval(captureIndexedDBJS)
+	if err != nil {
+		// Not being able to read IndexedDB should not invalidate the rest of the captured state
+		log.Warn().Err(err).Str("url", info.URL).Msg("Could not capture IndexedDB contents")
+	} else if err := indexedDB.Value.Unmarshal(&state.IndexedDB); err != nil {
+		log.Warn().Err(err).Str("url", info.URL).Msg("Could not parse IndexedDB contents")
+	}
+
+	log.Debug().Str("url", info.URL).Int("cookies", len(state.Cookies)).Int("local_storage", len(state.LocalStorage)).Int("session_storage", len(state.SessionStorage)).Int("indexed_db", len(state.IndexedDB)).Msg("Captured browser state")
+	return state, nil
+}
+
+// RestoreBrowserState restores a previously captured state into the page. Cookies are set straight away, while the
+// storage is populated every time a document from the captured origin is loaded in the page.
+func RestoreBrowserState(page *rod.Page, state *BrowserState) error {
+	if state == nil {
+		return nil
+	}
+	if len(state.Cookies) > 0 {
+		if err := page.SetCookies(proto.CookiesToParams(state.Cookies)); err != nil {
+			return fmt.Errorf("could not restore cookies: %w", err)
+		}
+	}
+
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("could not encode browser state: %w", err)
+	}
+	script := fmt.Sprintf(restoreStateJS, encoded)
+	if _, err := page.EvalOnNewDocument(script); err != nil {
+		return fmt.Errorf("could not restore storage: %w", err)
+	}
+	log.Debug().Str("origin", state.Origin).Int("cookies", len(state.Cookies)).Msg("Restored browser state")
+	return nil
+}
+
+// LoadBrowserStateFromFile loads a JSON encoded browser state from the provided path
+func LoadBrowserStateFromFile(path string) (*BrowserState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state BrowserState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("could not parse browser state file: %w", err)
+	}
+	return &state, nil
+}
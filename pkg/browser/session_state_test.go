@@ -0,0 +1,50 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureAndRestoreBrowserState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "authenticated-session", Path: "/"})
+			w.Write([]byte(`<html><body><script>
+				localStorage.setItem("access_token", "stored-token");
+				sessionStorage.setItem("tab_id", "42");
+			</script></body></html>`))
+		default:
+			w.Write([]byte("<html><body>dashboard</body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	b := setupRodBrowser(t, true)
+	defer b.MustClose()
+
+	loggedInPage := b.MustPage(server.URL + "/login").MustWaitLoad()
+	state, err := CaptureBrowserState(loggedInPage)
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL, state.Origin)
+	assert.Equal(t, "stored-token", state.LocalStorage["access_token"])
+	assert.Equal(t, "42", state.SessionStorage["tab_id"])
+	assert.Len(t, state.Cookies, 1)
+	assert.Equal(t, "session", state.Cookies[0].Name)
+
+	// A fresh incognito context does not share any state with the previous page
+	freshPage := b.MustIncognito().MustPage("")
+	err = RestoreBrowserState(freshPage, state)
+	assert.Nil(t, err)
+	freshPage.MustNavigate(server.URL + "/dashboard").MustWaitLoad()
+
+	assert.Equal(t, "stored-token", freshPage.MustEval(`() => localStorage.getItem("access_token")`).String())
+	assert.Equal(t, "42", freshPage.MustEval(`() => sessionStorage.getItem("tab_id")`).String())
+	cookies, err := freshPage.Cookies([]string{server.URL})
+	assert.Nil(t, err)
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "authenticated-session", cookies[0].Value)
+}
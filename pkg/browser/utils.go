@@ -81,6 +81,8 @@ type ReplayAndCreateHistoryOptions struct {
 	PlaygroundSessionID uint
 	Note                string
 	Source              string
+	// ClientOptions are the settings of the HTTP client sending the replayed request
+	ClientOptions *http_utils.ClientOptions
 }
 
 func ReplayRequestInBrowserAndCreateHistory(opts ReplayAndCreateHistoryOptions) (history *db.History, err error) {
@@ -130,7 +132,7 @@ func ReplayRequestInBrowserAndCreateHistory(opts ReplayAndCreateHistoryOptions)
 			contentLength := len(bodyBytes)
 			ctx.Request.Req().Header.Set("Content-Length", strconv.Itoa(contentLength))
 		}
-		client := http_utils.CreateHttpClient()
+		client := http_utils.CreateHttpClientWithOptions(opts.ClientOptions)
 		err := ctx.LoadResponse(client, true)
 		if err != nil {
 			log.Error().Err(err).Msg("Error loading hijacked response in replay function")
@@ -2,6 +2,10 @@ package crawl
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,6 +15,7 @@ import (
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/pkg/browser"
+	"github.com/pyneda/sukyan/pkg/http_utils"
 	"github.com/pyneda/sukyan/pkg/scope"
 	"github.com/pyneda/sukyan/pkg/web"
 	"github.com/rs/zerolog/log"
@@ -28,6 +33,8 @@ type Crawler struct {
 	scope                   scope.Scope
 	startURLs               []string
 	excludePatterns         []string
+	exclusions              *scope.URLExclusions
+	scopeRules              *scope.CompiledScopeRules
 	ignoredExtensions       []string
 	browser                 *browser.PagePoolManager
 	pages                   sync.Map
@@ -44,6 +51,8 @@ type Crawler struct {
 	normalizedURLCounts     sync.Map
 	eventStore              sync.Map
 	maxPagesWithSameParams  int
+	interestingURLs         sync.Map
+	clientOptions           *http_utils.ClientOptions
 }
 
 type CrawlItem struct {
@@ -68,7 +77,7 @@ type SubmittedForm struct {
 	xpath string
 }
 
-func NewCrawler(startURLs []string, maxPagesToCrawl int, maxDepth int, poolSize int, excludePatterns []string, workspaceID, taskID uint, extraHeaders map[string][]string) *Crawler {
+func NewCrawler(startURLs []string, maxPagesToCrawl int, maxDepth int, poolSize int, excludePatterns []string, exclusions *scope.URLExclusions, scopeRules *scope.CompiledScopeRules, workspaceID, taskID uint, extraHeaders map[string][]string, clientOptions *http_utils.ClientOptions) *Crawler {
 	hijackChan := make(chan browser.HijackResult)
 	options := CrawlOptions{
 		ExtraHeaders:    extraHeaders,
@@ -77,7 +86,9 @@ func NewCrawler(startURLs []string, maxPagesToCrawl int, maxDepth int, poolSize
 	}
 	browser := browser.NewHijackedPagePoolManager(
 		browser.PagePoolManagerConfig{
-			PoolSize: poolSize,
+			PoolSize:      poolSize,
+			Exclusions:    exclusions,
+			ClientOptions: clientOptions,
 		},
 		"Crawler",
 		hijackChan,
@@ -88,6 +99,8 @@ func NewCrawler(startURLs []string, maxPagesToCrawl int, maxDepth int, poolSize
 		Options:                options,
 		startURLs:              startURLs,
 		excludePatterns:        excludePatterns,
+		exclusions:             exclusions,
+		scopeRules:             scopeRules,
 		concLimit:              make(chan struct{}, poolSize+2), // Set max concurrency
 		hijackChan:             hijackChan,
 		browser:                browser,
@@ -95,6 +108,7 @@ func NewCrawler(startURLs []string, maxPagesToCrawl int, maxDepth int, poolSize
 		workspaceID:            workspaceID,
 		taskID:                 taskID,
 		maxPagesWithSameParams: viper.GetInt("crawl.max_pages_with_same_params"),
+		clientOptions:          clientOptions,
 	}
 }
 
@@ -143,6 +157,7 @@ func (c *Crawler) Run() []*db.History {
 		}
 	}()
 	taskLog.Info().Interface("start_urls", c.startURLs).Msg("Crawling start urls")
+	seededBaseURLs := make(map[string]bool)
 	for _, url := range c.startURLs {
 		c.wg.Add(1)
 		go c.crawlPage(&CrawlItem{url: url, depth: lib.CalculateURLDepth(url)})
@@ -154,6 +169,13 @@ func (c *Crawler) Run() []*db.History {
 			c.wg.Add(1)
 			go c.crawlPage(&CrawlItem{url: baseURL + u, depth: lib.CalculateURLDepth(u)})
 		}
+		if !seededBaseURLs[baseURL] {
+			seededBaseURLs[baseURL] = true
+			for _, u := range c.discoverSeedURLs(baseURL) {
+				c.wg.Add(1)
+				go c.crawlPage(&CrawlItem{url: u, depth: lib.CalculateURLDepth(u)})
+			}
+		}
 	}
 
 	c.wg.Wait()
@@ -169,6 +191,69 @@ func (c *Crawler) Run() []*db.History {
 	return inScopeHistoryItems
 }
 
+// discoverSeedURLs fetches the robots.txt and sitemaps of the base URL and returns the URLs they reference. Paths
+// disallowed by robots.txt are crawled too, and also stored as interesting, since they often point to sensitive areas.
+func (c *Crawler) discoverSeedURLs(baseURL string) []string {
+	var urls []string
+	sitemaps := []string{strings.TrimSuffix(baseURL, "/") + "/sitemap.xml"}
+	client := http_utils.CreateHttpClientWithOptions(c.clientOptions)
+
+	if viper.GetBool("crawl.seed.robots") {
+		content, err := fetchRobots(client, baseURL)
+		if err != nil {
+			log.Debug().Err(err).Uint("workspace", c.workspaceID).Str("url", baseURL).Msg("Could not fetch robots.txt")
+		} else {
+			robots := http_utils.ParseRobots(content)
+			for _, path := range robots.Allowed {
+				if u, ok := http_utils.ResolveRobotsPath(baseURL, path); ok {
+					urls = append(urls, u)
+				}
+			}
+			for _, path := range robots.Disallowed {
+				if u, ok := http_utils.ResolveRobotsPath(baseURL, path); ok {
+					urls = append(urls, u)
+					c.interestingURLs.Store(u, true)
+				}
+			}
+			sitemaps = append(sitemaps, robots.Sitemaps...)
+		}
+	}
+
+	if viper.GetBool("crawl.seed.sitemaps") {
+		urls = append(urls, http_utils.FetchSitemapURLs(client, sitemaps, viper.GetInt("crawl.seed.max_sitemaps"))...)
+	}
+	log.Info().Uint("workspace", c.workspaceID).Uint("task", c.taskID).Str("url", baseURL).Int("urls", len(urls)).Msg("Seeding crawl with URLs from robots.txt and sitemaps")
+	return urls
+}
+
+func fetchRobots(client *http.Client, baseURL string) (string, error) {
+	response, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/robots.txt")
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, 512*1024))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// InterestingURLs returns the URLs found during the crawl that are likely to point to sensitive areas, such as the
+// paths disallowed by robots.txt
+func (c *Crawler) InterestingURLs() []string {
+	var urls []string
+	c.interestingURLs.Range(func(key, value interface{}) bool {
+		urls = append(urls, key.(string))
+		return true
+	})
+	sort.Strings(urls)
+	return urls
+}
+
 // CreateScopeFromProvidedUrls creates scope items given the received urls
 func (c *Crawler) CreateScopeFromProvidedUrls() {
 	// When it can be provided via CLI, the initial scope should be reused
@@ -191,6 +276,14 @@ func (c *Crawler) shouldCrawl(item *CrawlItem) bool {
 			return false
 		}
 	}
+	if pattern, excluded := c.exclusions.Match(item.url); excluded {
+		log.Debug().Uint("workspace", c.workspaceID).Uint("task", c.taskID).Str("url", item.url).Str("pattern", pattern).Msg("Skipping page because it has been excluded from the scan")
+		return false
+	}
+	if !c.scopeRules.InScope(item.url, "GET") {
+		log.Debug().Uint("workspace", c.workspaceID).Uint("task", c.taskID).Str("url", item.url).Msg("Skipping page because it is out of the scope rules")
+		return false
+	}
 	// Check if the url has an ignored extension
 	for _, extension := range c.ignoredExtensions {
 		if strings.HasSuffix(item.url, extension) {
@@ -394,6 +487,11 @@ func (c *Crawler) handleForms(page *rod.Page) (err error) {
 		}
 		_, submitted := c.submittedForms.Load(e)
 		if !submitted {
+			if destructive, pattern := isDestructiveForm(form); destructive {
+				c.submittedForms.Store(e, true)
+				log.Info().Uint("workspace", c.workspaceID).Str("xpath", xpath).Str("pattern", pattern).Msg("Skipping form submission as it might be destructive")
+				continue
+			}
 			web.AutoFillForm(form, page)
 			web.SubmitForm(form, page)
 			c.submittedForms.Store(e, true)
@@ -403,6 +501,20 @@ func (c *Crawler) handleForms(page *rod.Page) (err error) {
 	return err
 }
 
+// isDestructiveForm checks if the form matches any of the crawl.interaction.skip_forms_patterns, such as delete or
+// logout forms, which should not be submitted
+func isDestructiveForm(form *rod.Element) (bool, string) {
+	html, err := form.HTML()
+	if err != nil {
+		return false, ""
+	}
+	forms, err := web.ExtractForms(html)
+	if err != nil || len(forms) == 0 {
+		return false, ""
+	}
+	return forms[0].IsDestructive(viper.GetStringSlice("crawl.interaction.skip_forms_patterns"))
+}
+
 func (c *Crawler) handleClickableElements(page *rod.Page) {
 	c.getAndClickElements("button", page)
 
@@ -11,13 +11,99 @@ import (
 var AdminPaths = []string{
 	"admin", "administrator", "admin.php", "controlpanel", "dashboard",
 	"manage", "wp-admin", "cpanel", "auth/admin", "secure/admin",
-	"backend", "system/console", "admin-console",
+	"backend", "system/console", "admin-console", "manager/html",
+	"phpmyadmin", "wp-login.php",
+}
+
+// AdminInterfaceSignature identifies a known admin interface. An interface matches when any of its header patterns
+// is found, or when at least MinBodyMatches of its body patterns are found in the response.
+type AdminInterfaceSignature struct {
+	Name           string
+	BodyPatterns   []string
+	HeaderPatterns []string
+	MinBodyMatches int
+}
+
+// AdminInterfaceSignatures contains the known admin interfaces that can be identified. It can be extended with
+// additional signatures, and their paths should be added to AdminPaths.
+var AdminInterfaceSignatures = []AdminInterfaceSignature{
+	{
+		Name:           "Apache Tomcat Manager",
+		BodyPatterns:   []string{"tomcat web application manager", "/manager/html", "manager-gui", "tomcat-users.xml"},
+		HeaderPatterns: []string{"tomcat manager application"},
+		MinBodyMatches: 2,
+	},
+	{
+		Name:           "phpMyAdmin",
+		BodyPatterns:   []string{"phpmyadmin", "pma_username", "pma_password", "pma_navigation", "pmahomme"},
+		HeaderPatterns: []string{"phpmyadmin"},
+		MinBodyMatches: 2,
+	},
+	{
+		Name:           "WordPress Admin",
+		BodyPatterns:   []string{"wp-login.php", "user_login", "wp-submit", "wp-admin", "wordpress"},
+		MinBodyMatches: 3,
+	},
+	{
+		Name:           "Joomla Administrator",
+		BodyPatterns:   []string{"joomla", "/administrator/", "mod-login-username", "com_login"},
+		MinBodyMatches: 2,
+	},
+	{
+		Name:           "cPanel",
+		BodyPatterns:   []string{"cpanel", "cpsess", "login_submit"},
+		MinBodyMatches: 2,
+	},
+}
+
+// IdentifyAdminInterface returns the known admin interface matching the response, if any, along with the patterns
+// that have been found. The Location header is not matched, as redirects usually echo the requested path.
+func IdentifyAdminInterface(history *db.History) (*AdminInterfaceSignature, []string) {
+	body := strings.ToLower(string(history.ResponseBody))
+	var sb strings.Builder
+	if headersMap, err := history.GetResponseHeadersAsMap(); err == nil {
+		for name, values := range headersMap {
+			if strings.EqualFold(name, "Location") {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%s: %s\n", name, strings.Join(values, ", ")))
+		}
+	}
+	headers := strings.ToLower(sb.String())
+
+	for i := range AdminInterfaceSignatures {
+		signature := &AdminInterfaceSignatures[i]
+		for _, pattern := range signature.HeaderPatterns {
+			if headers != "" && strings.Contains(headers, pattern) {
+				return signature, []string{pattern}
+			}
+		}
+		var matched []string
+		for _, pattern := range signature.BodyPatterns {
+			if strings.Contains(body, pattern) {
+				matched = append(matched, pattern)
+			}
+		}
+		if len(matched) > 0 && len(matched) >= signature.MinBodyMatches {
+			return signature, matched
+		}
+	}
+	return nil, nil
 }
 
 func IsAdminInterfaceValidationFunc(history *db.History) (bool, string, int) {
 	details := fmt.Sprintf("Potential admin interface detected: %s\n", history.URL)
 	confidence := 0
 
+	if history.StatusCode == 200 || history.StatusCode == 401 || history.StatusCode == 403 {
+		if signature, matched := IdentifyAdminInterface(history); signature != nil {
+			details = fmt.Sprintf("%s interface detected: %s\n", signature.Name, history.URL)
+			details += fmt.Sprintf("- Identified interface: %s\n", signature.Name)
+			details += fmt.Sprintf("- Matched fingerprints: %s\n", strings.Join(matched, ", "))
+			return true, details, 95
+		}
+	}
+
 	headers, err := history.GetResponseHeadersAsMap()
 	if err != nil {
 		return false, "", 0
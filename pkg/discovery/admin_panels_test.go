@@ -0,0 +1,129 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+const samplePhpMyAdminLogin = `<!DOCTYPE HTML>
+<html lang="en" dir="ltr">
+<head>
+<title>phpMyAdmin</title>
+<link rel="stylesheet" type="text/css" href="./themes/pmahomme/css/theme.css">
+</head>
+<body class="loginform">
+<form method="post" id="login_form" action="index.php" name="login_form" class="disableAjax login hide js-show">
+<input type="text" name="pma_username" id="input_username" value="" size="24" class="textfield" autocomplete="username">
+<input type="password" name="pma_password" id="input_password" value="" size="24" class="textfield" autocomplete="current-password">
+<input value="Go" type="submit" id="input_go">
+</form>
+</body>
+</html>`
+
+const sampleTomcatManagerUnauthorized = `<!doctype html><html lang="en"><head><title>401 Unauthorized</title></head>
+<body><h1>401 Unauthorized</h1>
+<p>You are not authorized to view this page. If you have not changed any configuration files, please examine the file
+<tt>conf/tomcat-users.xml</tt> in your installation.</p>
+<p>For example, to add the <tt>manager-gui</tt> role to a user named <tt>tomcat</tt> with a password of <tt>s3cret</tt>, add the following to the config file listed above.</p>
+</body></html>`
+
+func TestIdentifyAdminInterface(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  *db.History
+		expected string
+	}{
+		{
+			name: "phpMyAdmin login page",
+			history: &db.History{
+				URL:                 "https://example.com/phpmyadmin/",
+				StatusCode:          200,
+				ResponseContentType: "text/html; charset=utf-8",
+				ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["text/html; charset=utf-8"]}`),
+				ResponseBody:        []byte(samplePhpMyAdminLogin),
+			},
+			expected: "phpMyAdmin",
+		},
+		{
+			name: "Tomcat Manager basic auth prompt",
+			history: &db.History{
+				URL:                 "https://example.com/manager/html",
+				StatusCode:          401,
+				ResponseContentType: "text/html;charset=utf-8",
+				ResponseHeaders:     datatypes.JSON(`{"Www-Authenticate": ["Basic realm=\"Tomcat Manager Application\""]}`),
+				ResponseBody:        []byte(sampleTomcatManagerUnauthorized),
+			},
+			expected: "Apache Tomcat Manager",
+		},
+		{
+			name: "Tomcat Manager error page without headers",
+			history: &db.History{
+				URL:                 "https://example.com/manager/html",
+				StatusCode:          403,
+				ResponseContentType: "text/html;charset=utf-8",
+				ResponseHeaders:     datatypes.JSON(`{}`),
+				ResponseBody:        []byte(sampleTomcatManagerUnauthorized),
+			},
+			expected: "Apache Tomcat Manager",
+		},
+		{
+			name: "Generic page",
+			history: &db.History{
+				URL:                 "https://example.com/admin",
+				StatusCode:          200,
+				ResponseContentType: "text/html",
+				ResponseHeaders:     datatypes.JSON(`{}`),
+				ResponseBody:        []byte("<html><body>Welcome to our shop</body></html>"),
+			},
+			expected: "",
+		},
+		{
+			name: "Redirect to a path containing a fingerprint",
+			history: &db.History{
+				URL:                 "https://example.com/phpmyadmin",
+				StatusCode:          302,
+				ResponseContentType: "text/html",
+				ResponseHeaders:     datatypes.JSON(`{"Location": ["https://example.com/login?next=/phpmyadmin/"]}`),
+				ResponseBody:        []byte("<html><body>Redirecting</body></html>"),
+			},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, matched := IdentifyAdminInterface(tt.history)
+			if tt.expected == "" {
+				assert.Nil(t, signature)
+				return
+			}
+			assert.NotNil(t, signature)
+			assert.Equal(t, tt.expected, signature.Name)
+			assert.NotEmpty(t, matched)
+
+			valid, details, confidence := IsAdminInterfaceValidationFunc(tt.history)
+			assert.True(t, valid)
+			assert.Equal(t, 95, confidence)
+			assert.Contains(t, details, tt.expected)
+		})
+	}
+}
+
+func TestIsAdminInterfaceValidationFuncRequiresFingerprintStatus(t *testing.T) {
+	history := &db.History{
+		URL:                 "https://example.com/phpmyadmin/",
+		StatusCode:          302,
+		ResponseContentType: "text/html; charset=utf-8",
+		ResponseHeaders:     datatypes.JSON(`{"Content-Type": ["text/html; charset=utf-8"]}`),
+		ResponseBody:        []byte(samplePhpMyAdminLogin),
+	}
+	signature, _ := IdentifyAdminInterface(history)
+	assert.NotNil(t, signature)
+
+	// The fingerprint is only trusted when the interface is served or protected
+	_, _, confidence := IsAdminInterfaceValidationFunc(history)
+	assert.NotEqual(t, 95, confidence)
+}
@@ -2,6 +2,8 @@ package discovery
 
 import (
 	"net/http"
+
+	"github.com/pyneda/sukyan/pkg/http_utils"
 )
 
 func min(a, b int) int {
@@ -13,7 +15,7 @@ func min(a, b int) int {
 
 func setDefaultHeaders(req *http.Request, hasBody bool) {
 	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", DefaultUserAgent)
+		req.Header.Set("User-Agent", http_utils.GetUserAgent())
 	}
 	if req.Header.Get("Connection") == "" {
 		req.Header.Set("Connection", "keep-alive")
@@ -81,7 +81,7 @@ func CheckSiteBehavior(options SiteBehaviourCheckOptions) (*SiteBehavior, error)
 		}
 	}
 	if baseReq.Header.Get("User-Agent") == "" {
-		baseReq.Header.Set("User-Agent", DefaultUserAgent)
+		baseReq.Header.Set("User-Agent", GetUserAgent())
 	}
 	baseReq.Header.Set("Connection", "keep-alive")
 
@@ -129,7 +129,7 @@ func CheckSiteBehavior(options SiteBehaviourCheckOptions) (*SiteBehavior, error)
 				}
 			}
 			if req.Header.Get("User-Agent") == "" {
-				req.Header.Set("User-Agent", DefaultUserAgent)
+				req.Header.Set("User-Agent", GetUserAgent())
 			}
 			req.Header.Set("Connection", "keep-alive")
 
@@ -0,0 +1,101 @@
+package http_utils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// cacheStatusHeaders are set by caches and CDNs to report how a response has been served
+var cacheStatusHeaders = []string{"Age", "X-Cache", "X-Cache-Status", "X-Cache-Key", "X-Served-By", "Cf-Cache-Status", "X-Proxy-Cache", "X-Varnish"}
+
+// CacheInfo holds the caching details disclosed by a response through its headers
+type CacheInfo struct {
+	// Cacheable is true when the response can be stored by a shared cache
+	Cacheable bool
+	// Hit is true when the response has been served from the cache
+	Hit bool
+	// Key is the cache key disclosed by the X-Cache-Key header
+	Key string
+	// Vary contains the lowercased request headers the cache is keyed on, besides the URL
+	Vary []string
+	// Headers contains the cache status headers found in the response
+	Headers map[string]string
+}
+
+// ParseCacheHeaders extracts the caching details from the provided response headers, returning nil when there are
+// neither cache status headers nor a Cache-Control header allowing shared caches to store the response
+func ParseCacheHeaders(headers map[string][]string) *CacheInfo {
+	info := &CacheInfo{Headers: make(map[string]string)}
+	var cacheControl string
+	for name, values := range headers {
+		if len(values) == 0 {
+			continue
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		value := strings.TrimSpace(strings.Join(values, ", "))
+		switch canonical {
+		case "Cache-Control":
+			cacheControl = strings.ToLower(value)
+			continue
+		case "Vary":
+			for _, field := range strings.Split(value, ",") {
+				if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+					info.Vary = append(info.Vary, field)
+				}
+			}
+			continue
+		}
+		for _, statusHeader := range cacheStatusHeaders {
+			if canonical == statusHeader {
+				info.Headers[canonical] = value
+			}
+		}
+	}
+
+	status := strings.ToLower(info.Headers["X-Cache"] + " " + info.Headers["X-Cache-Status"] + " " + info.Headers["Cf-Cache-Status"] + " " + info.Headers["X-Proxy-Cache"])
+	info.Hit = strings.Contains(status, "hit")
+	info.Key = info.Headers["X-Cache-Key"]
+	age, _ := strconv.Atoi(info.Headers["Age"])
+	// A cache reporting a hit, a miss which gets stored or an age means that the response is cached
+	cached := info.Hit || age > 0 || strings.Contains(status, "miss") || strings.Contains(status, "expired") || strings.Contains(status, "stale")
+	info.Cacheable = cached || cacheControlAllowsSharedCaching(cacheControl)
+	if strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "private") || info.VariesOn("*") {
+		info.Cacheable = false
+	}
+	if len(info.Headers) == 0 && !info.Cacheable {
+		return nil
+	}
+	return info
+}
+
+// cacheControlAllowsSharedCaching checks if the Cache-Control directives allow shared caches to store the response
+func cacheControlAllowsSharedCaching(cacheControl string) bool {
+	if strings.Contains(cacheControl, "no-cache") {
+		return false
+	}
+	if strings.Contains(cacheControl, "public") {
+		return true
+	}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || (name != "max-age" && name != "s-maxage") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// VariesOn checks if the cache is keyed on the request header
+func (i *CacheInfo) VariesOn(header string) bool {
+	header = strings.ToLower(header)
+	for _, vary := range i.Vary {
+		if vary == header {
+			return true
+		}
+	}
+	return false
+}
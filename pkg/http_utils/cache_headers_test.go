@@ -0,0 +1,54 @@
+package http_utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCacheHeaders(t *testing.T) {
+	info := ParseCacheHeaders(map[string][]string{
+		"Age":             {"120"},
+		"X-Cache":         {"HIT, HIT"},
+		"X-Cache-Key":     {"/en/index.html?lang=en"},
+		"X-Served-By":     {"cache-mad22044-MAD"},
+		"Vary":            {"Accept-Encoding, Origin"},
+		"Cache-Control":   {"public, max-age=3600"},
+		"Content-Type":    {"text/html"},
+		"Cf-Cache-Status": {"HIT"},
+	})
+	assert.NotNil(t, info)
+	assert.True(t, info.Cacheable)
+	assert.True(t, info.Hit)
+	assert.Equal(t, "/en/index.html?lang=en", info.Key)
+	assert.Equal(t, []string{"accept-encoding", "origin"}, info.Vary)
+	assert.True(t, info.VariesOn("Origin"))
+	assert.False(t, info.VariesOn("X-Forwarded-Host"))
+	assert.Len(t, info.Headers, 5)
+}
+
+func TestParseCacheHeadersCacheability(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   map[string][]string
+		cacheable bool
+	}{
+		{"shared max age", map[string][]string{"Cache-Control": {"s-maxage=600"}}, true},
+		{"cache miss", map[string][]string{"X-Cache": {"MISS from varnish"}}, true},
+		{"cloudflare dynamic", map[string][]string{"Cf-Cache-Status": {"DYNAMIC"}}, false},
+		{"private", map[string][]string{"Cache-Control": {"private, max-age=600"}, "X-Cache": {"MISS"}}, false},
+		{"no store", map[string][]string{"Cache-Control": {"no-store"}, "Age": {"10"}}, false},
+		{"vary on everything", map[string][]string{"Vary": {"*"}, "X-Cache": {"HIT"}}, false},
+		{"zero max age", map[string][]string{"Cache-Control": {"max-age=0"}, "X-Served-By": {"cache-1"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := ParseCacheHeaders(tt.headers)
+			assert.NotNil(t, info)
+			assert.Equal(t, tt.cacheable, info.Cacheable)
+		})
+	}
+
+	assert.Nil(t, ParseCacheHeaders(map[string][]string{"Content-Type": {"text/html"}}))
+	assert.Nil(t, ParseCacheHeaders(map[string][]string{"Cache-Control": {"no-cache"}}))
+}
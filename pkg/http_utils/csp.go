@@ -0,0 +1,157 @@
+package http_utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// cspFetchDirectiveFallbacks lists the directives each fetch directive falls back to when it is not set, in order
+var cspFetchDirectiveFallbacks = map[string][]string{
+	"script-src-elem": {"script-src", "default-src"},
+	"script-src-attr": {"script-src", "default-src"},
+	"style-src-elem":  {"style-src", "default-src"},
+	"style-src-attr":  {"style-src", "default-src"},
+	"worker-src":      {"child-src", "script-src", "default-src"},
+	"frame-src":       {"child-src", "default-src"},
+	"script-src":      {"default-src"},
+	"style-src":       {"default-src"},
+	"object-src":      {"default-src"},
+	"img-src":         {"default-src"},
+	"connect-src":     {"default-src"},
+	"font-src":        {"default-src"},
+	"media-src":       {"default-src"},
+	"manifest-src":    {"default-src"},
+	"child-src":       {"default-src"},
+}
+
+// CSPPolicy is a parsed Content-Security-Policy, with the directive names lowercased
+type CSPPolicy struct {
+	Raw        string
+	Directives map[string][]string
+}
+
+// ParseCSP parses a Content-Security-Policy header value. As browsers do, only the first occurrence of a directive is
+// taken into account.
+func ParseCSP(policy string) *CSPPolicy {
+	csp := &CSPPolicy{Raw: policy, Directives: make(map[string][]string)}
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, exists := csp.Directives[name]; exists {
+			continue
+		}
+		csp.Directives[name] = fields[1:]
+	}
+	return csp
+}
+
+// ParseCSPFromHeaders parses the enforced Content-Security-Policy of the response headers, returning nil when there is
+// none. Report only policies are ignored, as they do not restrict anything.
+func ParseCSPFromHeaders(headers map[string][]string) *CSPPolicy {
+	for name, values := range headers {
+		if http.CanonicalHeaderKey(name) != "Content-Security-Policy" {
+			continue
+		}
+		for _, value := range values {
+			if strings.TrimSpace(value) != "" {
+				return ParseCSP(value)
+			}
+		}
+	}
+	return nil
+}
+
+// HasDirective checks if the directive is explicitly set
+func (p *CSPPolicy) HasDirective(directive string) bool {
+	_, ok := p.Directives[strings.ToLower(directive)]
+	return ok
+}
+
+// Sources returns the sources explicitly set for the directive
+func (p *CSPPolicy) Sources(directive string) []string {
+	return p.Directives[strings.ToLower(directive)]
+}
+
+// EffectiveSources returns the sources applied to a fetch directive, taking into account its fallback directives. The
+// second value is false when neither the directive nor its fallbacks are set, meaning that anything is allowed.
+func (p *CSPPolicy) EffectiveSources(directive string) ([]string, bool) {
+	directive = strings.ToLower(directive)
+	if sources, ok := p.Directives[directive]; ok {
+		return sources, true
+	}
+	for _, fallback := range cspFetchDirectiveFallbacks[directive] {
+		if sources, ok := p.Directives[fallback]; ok {
+			return sources, true
+		}
+	}
+	return nil, false
+}
+
+// AllowsSource checks if the effective sources of the directive contain the provided keyword or source, case insensitive
+func (p *CSPPolicy) AllowsSource(directive, source string) bool {
+	sources, _ := p.EffectiveSources(directive)
+	for _, s := range sources {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesNoncesOrHashes checks if the effective sources of the directive contain nonces or hashes, in which case browsers
+// supporting them ignore 'unsafe-inline'
+func (p *CSPPolicy) UsesNoncesOrHashes(directive string) bool {
+	sources, _ := p.EffectiveSources(directive)
+	for _, s := range sources {
+		lower := strings.ToLower(s)
+		if strings.HasPrefix(lower, "'nonce-") || strings.HasPrefix(lower, "'sha256-") || strings.HasPrefix(lower, "'sha384-") || strings.HasPrefix(lower, "'sha512-") {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsUnsafeInline checks if inline code is allowed by the directive, which is not the case when nonces or hashes
+// are also used
+func (p *CSPPolicy) AllowsUnsafeInline(directive string) bool {
+	return p.AllowsSource(directive, "'unsafe-inline'") && !p.UsesNoncesOrHashes(directive)
+}
+
+// AllowsUnsafeEval checks if the directive allows evaluating strings as code
+func (p *CSPPolicy) AllowsUnsafeEval(directive string) bool {
+	return p.AllowsSource(directive, "'unsafe-eval'")
+}
+
+// WildcardSources returns the effective sources of the directive that allow loading content from any host
+func (p *CSPPolicy) WildcardSources(directive string) []string {
+	sources, _ := p.EffectiveSources(directive)
+	var wildcards []string
+	for _, s := range sources {
+		switch strings.ToLower(s) {
+		case "*", "http:", "https:", "data:", "blob:", "http://*", "https://*":
+			wildcards = append(wildcards, s)
+		}
+	}
+	return wildcards
+}
+
+// Nonces returns the nonce values used in the policy
+func (p *CSPPolicy) Nonces() []string {
+	var nonces []string
+	seen := make(map[string]bool)
+	for _, sources := range p.Directives {
+		for _, s := range sources {
+			if len(s) > len("'nonce-'") && strings.HasPrefix(strings.ToLower(s), "'nonce-") && strings.HasSuffix(s, "'") {
+				nonce := s[len("'nonce-") : len(s)-1]
+				if !seen[nonce] {
+					seen[nonce] = true
+					nonces = append(nonces, nonce)
+				}
+			}
+		}
+	}
+	return nonces
+}
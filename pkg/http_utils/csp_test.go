@@ -0,0 +1,53 @@
+package http_utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSP(t *testing.T) {
+	policy := ParseCSP("default-src 'self'; Script-Src 'self' 'nonce-abc123' https://cdn.example.com;; object-src 'none'; script-src *")
+	assert.True(t, policy.HasDirective("default-src"))
+	assert.True(t, policy.HasDirective("SCRIPT-SRC"))
+	assert.False(t, policy.HasDirective("style-src"))
+	// Only the first occurrence of a directive is used
+	assert.Equal(t, []string{"'self'", "'nonce-abc123'", "https://cdn.example.com"}, policy.Sources("script-src"))
+	assert.Equal(t, []string{"abc123"}, policy.Nonces())
+	assert.Empty(t, policy.WildcardSources("script-src"))
+}
+
+func TestCSPPolicyEffectiveSources(t *testing.T) {
+	policy := ParseCSP("default-src 'self' https:; script-src 'self'")
+
+	sources, ok := policy.EffectiveSources("img-src")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"'self'", "https:"}, sources)
+	assert.Equal(t, []string{"https:"}, policy.WildcardSources("img-src"))
+
+	sources, ok = policy.EffectiveSources("script-src-elem")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"'self'"}, sources)
+
+	_, ok = ParseCSP("frame-ancestors 'none'").EffectiveSources("script-src")
+	assert.False(t, ok)
+}
+
+func TestCSPPolicyUnsafeSources(t *testing.T) {
+	assert.True(t, ParseCSP("script-src 'self' 'unsafe-inline'").AllowsUnsafeInline("script-src"))
+	assert.True(t, ParseCSP("default-src 'self' 'UNSAFE-INLINE'").AllowsUnsafeInline("script-src"))
+	// Browsers ignore 'unsafe-inline' when nonces or hashes are present
+	assert.False(t, ParseCSP("script-src 'unsafe-inline' 'nonce-r4nd0m'").AllowsUnsafeInline("script-src"))
+	assert.False(t, ParseCSP("script-src 'unsafe-inline' 'sha256-B2yPHKaXnvFWtRChIbabYmUBFZdVfKKXHbWtWidDVF8='").AllowsUnsafeInline("script-src"))
+	assert.True(t, ParseCSP("script-src 'self' 'unsafe-eval'").AllowsUnsafeEval("script-src"))
+	assert.False(t, ParseCSP("script-src 'self'; style-src 'unsafe-eval'").AllowsUnsafeEval("script-src"))
+}
+
+func TestParseCSPFromHeaders(t *testing.T) {
+	assert.Nil(t, ParseCSPFromHeaders(map[string][]string{"Content-Type": {"text/html"}}))
+	assert.Nil(t, ParseCSPFromHeaders(map[string][]string{"Content-Security-Policy-Report-Only": {"default-src 'self'"}}))
+
+	policy := ParseCSPFromHeaders(map[string][]string{"content-security-policy": {"default-src 'none'"}})
+	assert.NotNil(t, policy)
+	assert.Equal(t, []string{"'none'"}, policy.Sources("default-src"))
+}
@@ -0,0 +1,211 @@
+package http_utils
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// defaultThrottleBackoff is how long requests to a domain are paused after a 429 response without Retry-After
+	defaultThrottleBackoff = 1 * time.Second
+	// maxThrottleBackoff caps the pause requested through Retry-After headers
+	maxThrottleBackoff = 5 * time.Minute
+	// throttleRecoveryFactor is applied to the rate of a domain after each successful response until reaching the
+	// configured rate again
+	throttleRecoveryFactor = 1.05
+)
+
+// DomainRateLimitConfig holds the limits applied to the requests sent to each registrable domain
+type DomainRateLimitConfig struct {
+	// RequestsPerSecond caps the requests sent per second to each domain, disabled when zero
+	RequestsPerSecond float64
+	// Burst is the number of requests that can be sent at once before being paced
+	Burst int
+	// Backoff slows down requests to a domain when it responds with 429, Retry-After or rate limit headers
+	Backoff bool
+}
+
+type domainRateLimit struct {
+	bucket      *TokenBucket
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// DomainRateLimiter paces the requests sent to each registrable domain, so that all the hosts of the same site share
+// the same limit, backing off when the target signals it is throttling
+type DomainRateLimiter struct {
+	config  DomainRateLimitConfig
+	domains map[string]*domainRateLimit
+	mu      sync.RWMutex
+}
+
+// NewDomainRateLimiter creates a rate limiter applying the provided config to each domain
+func NewDomainRateLimiter(config DomainRateLimitConfig) *DomainRateLimiter {
+	if config.Burst <= 0 {
+		config.Burst = int(math.Max(1, math.Ceil(config.RequestsPerSecond)))
+	}
+	return &DomainRateLimiter{
+		config:  config,
+		domains: make(map[string]*domainRateLimit),
+	}
+}
+
+// Enabled returns true when requests are being rate limited
+func (l *DomainRateLimiter) Enabled() bool {
+	return l != nil && l.config.RequestsPerSecond > 0
+}
+
+func (l *DomainRateLimiter) getDomain(host string) *domainRateLimit {
+	domain := RegistrableDomain(host)
+	l.mu.RLock()
+	limit, ok := l.domains[domain]
+	l.mu.RUnlock()
+	if ok {
+		return limit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit, ok := l.domains[domain]; ok {
+		return limit
+	}
+	// Allow backing off down to a tenth of the configured rate
+	bucket := NewTokenBucket(l.config.RequestsPerSecond, float64(l.config.Burst), l.config.RequestsPerSecond/10)
+	bucket.SetMaxRate(l.config.RequestsPerSecond)
+	limit = &domainRateLimit{bucket: bucket}
+	l.domains[domain] = limit
+	return limit
+}
+
+// Wait blocks until a request can be sent to the host or the context is done
+func (l *DomainRateLimiter) Wait(ctx context.Context, host string) error {
+	if !l.Enabled() {
+		return nil
+	}
+	limit := l.getDomain(host)
+	for {
+		limit.mu.Lock()
+		pause := time.Until(limit.pausedUntil)
+		limit.mu.Unlock()
+		if pause <= 0 && limit.bucket.HasToken() {
+			return nil
+		}
+		if pause <= 0 {
+			pause = 50 * time.Millisecond
+		}
+		timer := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Observe adjusts the rate of the domain according to the response, halving it and pausing requests when the target
+// responds with 429 or a Retry-After header, and slowly recovering the configured rate otherwise. The rate is also
+// capped to the one disclosed by the rate limit headers of the response.
+func (l *DomainRateLimiter) Observe(host string, response *http.Response) {
+	if !l.Enabled() || !l.config.Backoff || response == nil {
+		return
+	}
+	limit := l.getDomain(host)
+	if info := ParseRateLimitHeaders(response.Header); info != nil {
+		limit.applyRateLimitInfo(info, l.config.RequestsPerSecond)
+	}
+	retryAfter := response.Header.Get("Retry-After")
+	if response.StatusCode != http.StatusTooManyRequests && retryAfter == "" {
+		if response.StatusCode < http.StatusInternalServerError {
+			limit.bucket.AdjustRate(limit.bucket.Rate() * throttleRecoveryFactor)
+		}
+		return
+	}
+
+	backoff := defaultThrottleBackoff
+	if retryAfter != "" {
+		if reset, ok := parseRateLimitReset(retryAfter); ok {
+			backoff = min(reset, maxThrottleBackoff)
+		}
+	}
+	limit.bucket.AdjustRate(limit.bucket.Rate() / 2)
+	limit.bucket.Drain()
+	limit.pause(backoff)
+	log.Warn().Str("host", host).Int("status", response.StatusCode).Str("retry_after", retryAfter).Dur("pause", backoff).Float64("rate", limit.bucket.Rate()).Msg("Target is throttling requests, backing off")
+}
+
+// pause stops sending requests to the domain for the provided duration, unless it is already paused for longer
+func (d *domainRateLimit) pause(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if until := time.Now().Add(duration); until.After(d.pausedUntil) {
+		d.pausedUntil = until
+	}
+}
+
+// applyRateLimitInfo caps the rate of the domain to the one allowed by the disclosed rate limit, without exceeding the
+// configured rate, and pauses the requests until the window is reset when the quota has been exhausted
+func (d *domainRateLimit) applyRateLimitInfo(info *RateLimitInfo, configuredRate float64) {
+	safeRate, ok := info.SafeRate()
+	if !ok {
+		return
+	}
+	d.bucket.SetMaxRate(math.Min(safeRate, configuredRate))
+	if info.HasRemaining && info.Remaining == 0 {
+		d.bucket.Drain()
+		d.pause(min(info.Reset, maxThrottleBackoff))
+	}
+}
+
+// RegistrableDomain returns the registrable domain (eTLD+1) of the host, or the host itself for IP addresses and
+// hosts without a known public suffix
+func RegistrableDomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+type rateLimitedRoundTripper struct {
+	next    http.RoundTripper
+	limiter *DomainRateLimiter
+}
+
+func (t *rateLimitedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+	response, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.limiter.Observe(req.URL.Host, response)
+	}
+	return response, err
+}
+
+// WrapRateLimitedTransport returns a transport that paces requests according to the provided domain rate limiter,
+// returning the transport as is when the limiter is not enabled
+func WrapRateLimitedTransport(next http.RoundTripper, limiter *DomainRateLimiter) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if !limiter.Enabled() {
+		return next
+	}
+	return &rateLimitedRoundTripper{next: next, limiter: limiter}
+}
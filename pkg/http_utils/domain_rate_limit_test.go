@@ -0,0 +1,142 @@
+package http_utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"www.example.com":         "example.com",
+		"api.example.com:8443":    "example.com",
+		"shop.example.co.uk":      "example.co.uk",
+		"127.0.0.1:8080":          "127.0.0.1",
+		"localhost":               "localhost",
+		"Sub.Domain.Example.COM.": "example.com",
+	}
+	for host, expected := range tests {
+		assert.Equal(t, expected, RegistrableDomain(host), host)
+	}
+}
+
+func TestDomainRateLimiterSharesLimitPerDomain(t *testing.T) {
+	limiter := NewDomainRateLimiter(DomainRateLimitConfig{RequestsPerSecond: 10, Burst: 2})
+	assert.True(t, limiter.Enabled())
+	assert.Same(t, limiter.getDomain("a.example.com"), limiter.getDomain("b.example.com:443"))
+	assert.NotSame(t, limiter.getDomain("a.example.com"), limiter.getDomain("example.org"))
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, limiter.Wait(ctx, "www.example.com"))
+	}
+	// The burst allows 2 requests straight away, while the other 2 are paced at 10 requests per second
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	assert.False(t, NewDomainRateLimiter(DomainRateLimitConfig{}).Enabled())
+}
+
+func TestDomainRateLimiterBacksOffOnThrottling(t *testing.T) {
+	limiter := NewDomainRateLimiter(DomainRateLimitConfig{RequestsPerSecond: 20, Burst: 1, Backoff: true})
+	limit := limiter.getDomain("example.com")
+
+	limiter.Observe("example.com", &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}}})
+	assert.Equal(t, 10.0, limit.bucket.Rate())
+	assert.InDelta(t, time.Second.Seconds(), time.Until(limit.pausedUntil).Seconds(), 0.1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, "example.com"), context.DeadlineExceeded)
+
+	// Successful responses recover the rate up to the configured one
+	for i := 0; i < 50; i++ {
+		limiter.Observe("example.com", &http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+	}
+	assert.Equal(t, 20.0, limit.bucket.Rate())
+}
+
+func TestDomainRateLimiterPacesWithRateLimitHeaders(t *testing.T) {
+	limiter := NewDomainRateLimiter(DomainRateLimitConfig{RequestsPerSecond: 10, Burst: 1, Backoff: true})
+	limit := limiter.getDomain("example.com")
+
+	limiter.Observe("api.example.com", &http.Response{StatusCode: http.StatusOK, Header: http.Header{
+		"X-Ratelimit-Limit":     {"60"},
+		"X-Ratelimit-Remaining": {"30"},
+		"X-Ratelimit-Reset":     {"60"},
+	}})
+	assert.Equal(t, 0.5, limit.bucket.Rate())
+
+	// Successful responses do not recover the rate over the disclosed limit
+	for i := 0; i < 10; i++ {
+		limiter.Observe("example.com", &http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+	}
+	assert.Equal(t, 0.5, limit.bucket.Rate())
+
+	// Requests are paced at the disclosed rate once the burst has been used
+	ctx := context.Background()
+	assert.NoError(t, limiter.Wait(ctx, "example.com"))
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(ctx, "example.com"))
+	assert.GreaterOrEqual(t, time.Since(start), 1500*time.Millisecond)
+}
+
+func TestRateLimitedTransportPausesWhenQuotaExhausted(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "1")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	limiter := NewDomainRateLimiter(DomainRateLimitConfig{RequestsPerSecond: 50, Backoff: true})
+	client := CreateHttpClientWithOptions(&ClientOptions{RateLimiter: limiter})
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		response, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		response.Body.Close()
+	}
+	// The second request waits until the rate limit window is reset
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	limiter := NewDomainRateLimiter(DomainRateLimitConfig{RequestsPerSecond: 50, Backoff: true})
+	client := CreateHttpClientWithOptions(&ClientOptions{RateLimiter: limiter})
+	start := time.Now()
+	for _, expected := range []int{http.StatusTooManyRequests, http.StatusOK} {
+		response, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, response.StatusCode)
+		response.Body.Close()
+	}
+	// The second request waits for the Retry-After pause
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	// Clients created with other options are not affected by the pause
+	start = time.Now()
+	response, err := CreateHttpClient().Get(server.URL)
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
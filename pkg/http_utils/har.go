@@ -0,0 +1,282 @@
+package http_utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/rs/zerolog/log"
+	"gorm.io/datatypes"
+)
+
+// harDocument holds the subset of the HAR 1.2 format used to import requests
+type harDocument struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []harNameValue `json:"params,omitempty"`
+	// Encoding is not part of the HAR 1.2 specification, but is used as in the response content to store binary
+	// request bodies
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// ParseHAR parses the entries of a HAR capture into history records, without storing them. Entries that are not
+// HTTP requests, such as data URIs or the ones blocked by the browser, are skipped.
+func ParseHAR(r io.Reader, workspaceID uint) ([]db.History, error) {
+	var document harDocument
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid HAR file: %w", err)
+	}
+
+	histories := make([]db.History, 0, len(document.Log.Entries))
+	for i, entry := range document.Log.Entries {
+		history, err := historyFromHAREntry(entry, workspaceID)
+		if err != nil {
+			log.Debug().Err(err).Int("entry", i).Str("url", entry.Request.URL).Msg("Skipping HAR entry")
+			continue
+		}
+		histories = append(histories, history)
+	}
+	return histories, nil
+}
+
+// ImportHAR parses the entries of a HAR capture and stores them as history records of the workspace
+func ImportHAR(r io.Reader, workspaceID uint) ([]db.History, error) {
+	histories, err := ParseHAR(r, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	imported := make([]db.History, 0, len(histories))
+	for i := range histories {
+		created, err := db.Connection.CreateHistory(&histories[i])
+		if err != nil {
+			return imported, err
+		}
+		imported = append(imported, *created)
+	}
+	log.Info().Uint("workspace", workspaceID).Int("imported", len(imported)).Msg("Imported HAR entries")
+	return imported, nil
+}
+
+func historyFromHAREntry(entry harEntry, workspaceID uint) (db.History, error) {
+	requestURL, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return db.History{}, err
+	}
+	if requestURL.Scheme != "http" && requestURL.Scheme != "https" {
+		return db.History{}, fmt.Errorf("unsupported scheme %s", requestURL.Scheme)
+	}
+	if entry.Response.Status == 0 {
+		return db.History{}, fmt.Errorf("request did not receive a response")
+	}
+
+	method := strings.ToUpper(entry.Request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	requestHeaders := harHeaders(entry.Request.Headers)
+	requestBody, err := harRequestBody(entry.Request.PostData)
+	if err != nil {
+		return db.History{}, err
+	}
+	responseHeaders := harHeaders(entry.Response.Headers)
+	responseBody, err := harResponseBody(entry.Response.Content)
+	if err != nil {
+		return db.History{}, err
+	}
+	// HAR captures store the decoded content, so the encoding headers are removed as the transport does when it
+	// transparently decompresses a response
+	if responseHeaders.Get("Content-Encoding") != "" {
+		responseHeaders.Del("Content-Encoding")
+		responseHeaders.Del("Content-Length")
+	}
+
+	requestHeadersJSON, err := json.Marshal(requestHeaders)
+	if err != nil {
+		return db.History{}, err
+	}
+	responseHeadersJSON, err := json.Marshal(responseHeaders)
+	if err != nil {
+		return db.History{}, err
+	}
+
+	requestProto := harProto(entry.Request.HTTPVersion)
+	responseProto := harProto(entry.Response.HTTPVersion)
+	statusText := entry.Response.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(entry.Response.Status)
+	}
+
+	var rawRequest bytes.Buffer
+	fmt.Fprintf(&rawRequest, "%s %s %s\r\n", method, requestURL.RequestURI(), requestProto)
+	if requestHeaders.Get("Host") == "" {
+		fmt.Fprintf(&rawRequest, "Host: %s\r\n", requestURL.Host)
+	}
+	requestHeaders.Write(&rawRequest)
+	rawRequest.WriteString("\r\n")
+	rawRequest.Write(requestBody)
+
+	var rawResponse bytes.Buffer
+	fmt.Fprintf(&rawResponse, "%s %d %s\r\n", responseProto, entry.Response.Status, statusText)
+	responseHeaders.Write(&rawResponse)
+	rawResponse.WriteString("\r\n")
+	rawResponse.Write(responseBody)
+
+	responseContentType := responseHeaders.Get("Content-Type")
+	if responseContentType == "" {
+		responseContentType = entry.Response.Content.MimeType
+	}
+	requestContentType := requestHeaders.Get("Content-Type")
+	if requestContentType == "" && entry.Request.PostData != nil {
+		requestContentType = entry.Request.PostData.MimeType
+	}
+
+	history := db.History{
+		URL:                  requestURL.String(),
+		Depth:                lib.CalculateURLDepth(requestURL.String()),
+		StatusCode:           entry.Response.Status,
+		Method:               method,
+		Proto:                responseProto,
+		RequestHeaders:       datatypes.JSON(requestHeadersJSON),
+		RequestBody:          requestBody,
+		RequestBodySize:      len(requestBody),
+		RequestContentLength: int64(len(requestBody)),
+		RequestContentType:   requestContentType,
+		ResponseHeaders:      datatypes.JSON(responseHeadersJSON),
+		ResponseBody:         responseBody,
+		ResponseBodySize:     len(responseBody),
+		ResponseContentType:  responseContentType,
+		RawRequest:           rawRequest.Bytes(),
+		RawResponse:          rawResponse.Bytes(),
+		Source:               db.SourceImport,
+		WorkspaceID:          &workspaceID,
+	}
+	// Keep the time the request was made in the capture
+	if startedAt, err := time.Parse(time.RFC3339Nano, entry.StartedDateTime); err == nil {
+		history.CreatedAt = startedAt
+		history.UpdatedAt = startedAt
+	}
+	return history, nil
+}
+
+// harHeaders converts HAR headers to http.Header, ignoring HTTP/2 pseudo headers
+func harHeaders(headers []harNameValue) http.Header {
+	result := make(http.Header)
+	for _, header := range headers {
+		if header.Name == "" || strings.HasPrefix(header.Name, ":") {
+			continue
+		}
+		result.Add(header.Name, header.Value)
+	}
+	return result
+}
+
+func harRequestBody(postData *harPostData) ([]byte, error) {
+	if postData == nil {
+		return nil, nil
+	}
+	if postData.Encoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(postData.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 request body: %w", err)
+		}
+		return body, nil
+	}
+	if postData.Text != "" || len(postData.Params) == 0 {
+		return []byte(postData.Text), nil
+	}
+	values := url.Values{}
+	for _, param := range postData.Params {
+		values.Add(param.Name, param.Value)
+	}
+	return []byte(values.Encode()), nil
+}
+
+func harResponseBody(content harContent) ([]byte, error) {
+	if content.Encoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 response body: %w", err)
+		}
+		return body, nil
+	}
+	return []byte(content.Text), nil
+}
+
+// harProto normalizes the HTTP versions reported by browsers, such as h2 or http/2.0
+func harProto(version string) string {
+	switch strings.ToLower(version) {
+	case "h2", "http/2", "http/2.0":
+		return "HTTP/2.0"
+	case "h3", "http/3", "http/3.0":
+		return "HTTP/3.0"
+	case "http/1.0":
+		return "HTTP/1.0"
+	default:
+		return "HTTP/1.1"
+	}
+}
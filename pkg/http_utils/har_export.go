@@ -0,0 +1,223 @@
+package http_utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	harVersion = "1.2"
+	// harExportPageSize is the number of history items loaded from the database at a time while exporting
+	harExportPageSize = 100
+)
+
+// harWriter writes a HAR 1.2 document entry by entry, so exports do not need to hold all the history items in memory
+type harWriter struct {
+	w       io.Writer
+	entries int
+	encoder *json.Encoder
+}
+
+// newHARWriter starts a HAR document by writing the log header to w
+func newHARWriter(w io.Writer) (*harWriter, error) {
+	creator, err := json.Marshal(harNameVersion{Name: "Sukyan", Version: harVersion})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, `{"log":{"version":%q,"creator":%s,"entries":[`, harVersion, creator); err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &harWriter{w: w, encoder: encoder}, nil
+}
+
+type harNameVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Write appends a history item to the HAR document
+func (hw *harWriter) Write(history *db.History) error {
+	entry, err := harEntryFromHistory(history)
+	if err != nil {
+		return err
+	}
+	if hw.entries > 0 {
+		if _, err := io.WriteString(hw.w, ","); err != nil {
+			return err
+		}
+	}
+	if err := hw.encoder.Encode(entry); err != nil {
+		return err
+	}
+	hw.entries++
+	return nil
+}
+
+// Close finishes the HAR document
+func (hw *harWriter) Close() error {
+	_, err := io.WriteString(hw.w, "]}}\n")
+	return err
+}
+
+// StreamHAR writes the history of a workspace to w as a HAR 1.2 document, loading the history items in pages
+func StreamHAR(w io.Writer, workspaceID uint) error {
+	hw, err := newHARWriter(w)
+	if err != nil {
+		return err
+	}
+	filter := db.HistoryFilter{
+		WorkspaceID: workspaceID,
+		SortBy:      "id",
+		SortOrder:   "asc",
+		Pagination:  db.Pagination{Page: 1, PageSize: harExportPageSize},
+	}
+	for {
+		items, _, err := db.Connection.ListHistory(filter)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := hw.Write(item); err != nil {
+				log.Error().Err(err).Uint("history", item.ID).Msg("Could not export history item to HAR")
+				return err
+			}
+		}
+		if len(items) < harExportPageSize {
+			break
+		}
+		filter.Pagination.Page++
+	}
+	log.Info().Uint("workspace", workspaceID).Int("exported", hw.entries).Msg("Exported history to HAR")
+	return hw.Close()
+}
+
+// ExportHAR exports the history of a workspace as a HAR 1.2 document
+func ExportHAR(workspaceID uint) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := StreamHAR(&buf, workspaceID); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func harEntryFromHistory(history *db.History) (harEntry, error) {
+	requestHeaders := map[string][]string{}
+	if len(history.RequestHeaders) > 0 {
+		headers, err := history.GetRequestHeadersAsMap()
+		if err != nil {
+			return harEntry{}, err
+		}
+		requestHeaders = headers
+	}
+	responseHeaders := map[string][]string{}
+	if len(history.ResponseHeaders) > 0 {
+		headers, err := history.GetResponseHeadersAsMap()
+		if err != nil {
+			return harEntry{}, err
+		}
+		responseHeaders = headers
+	}
+
+	proto := history.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+
+	request := harRequest{
+		Method:      history.Method,
+		URL:         history.URL,
+		HTTPVersion: proto,
+		Cookies:     harCookies(http.Header(requestHeaders), false),
+		Headers:     harNameValues(requestHeaders),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(history.RequestBody),
+	}
+	if u, err := url.Parse(history.URL); err == nil {
+		request.QueryString = harNameValues(u.Query())
+	}
+	if len(history.RequestBody) > 0 {
+		text, encoding := harBodyText(history.RequestBody)
+		request.PostData = &harPostData{
+			MimeType: history.RequestContentType,
+			Text:     text,
+			Encoding: encoding,
+		}
+	}
+
+	text, encoding := harBodyText(history.ResponseBody)
+	response := harResponse{
+		Status:      history.StatusCode,
+		StatusText:  http.StatusText(history.StatusCode),
+		HTTPVersion: proto,
+		Cookies:     harCookies(http.Header(responseHeaders), true),
+		Headers:     harNameValues(responseHeaders),
+		Content: harContent{
+			Size:     len(history.ResponseBody),
+			MimeType: history.ResponseContentType,
+			Text:     text,
+			Encoding: encoding,
+		},
+		RedirectURL: http.Header(responseHeaders).Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(history.ResponseBody),
+	}
+
+	return harEntry{
+		StartedDateTime: history.CreatedAt.Format(time.RFC3339Nano),
+		Request:         request,
+		Response:        response,
+	}, nil
+}
+
+// harBodyText returns the text to store a body in a HAR document, base64 encoding the bodies that are not valid UTF-8
+func harBodyText(body []byte) (text string, encoding string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// harNameValues converts headers or query parameters to HAR name/value pairs, sorted by name so exports are deterministic
+func harNameValues(headers map[string][]string) []harNameValue {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]harNameValue, 0, len(headers))
+	for _, name := range names {
+		for _, value := range headers[name] {
+			result = append(result, harNameValue{Name: name, Value: value})
+		}
+	}
+	return result
+}
+
+// harCookies returns the cookies sent in the request Cookie header or set with the response Set-Cookie headers
+func harCookies(headers http.Header, response bool) []harNameValue {
+	var cookies []*http.Cookie
+	if response {
+		cookies = (&http.Response{Header: headers}).Cookies()
+	} else {
+		cookies = (&http.Request{Header: headers}).Cookies()
+	}
+	result := make([]harNameValue, 0, len(cookies))
+	for _, cookie := range cookies {
+		result = append(result, harNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+	return result
+}
@@ -0,0 +1,172 @@
+package http_utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+const harFixture = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "Firefox", "version": "125.0"},
+    "entries": [
+      {
+        "startedDateTime": "2024-05-01T10:00:00.123Z",
+        "time": 85.5,
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/v1/users?invite=true",
+          "httpVersion": "h2",
+          "headers": [
+            {"name": ":authority", "value": "api.example.com"},
+            {"name": "content-type", "value": "application/json"},
+            {"name": "authorization", "value": "Bearer token"}
+          ],
+          "postData": {"mimeType": "application/json", "text": "{\"name\":\"alice\",\"admin\":false}"}
+        },
+        "response": {
+          "status": 201,
+          "statusText": "",
+          "httpVersion": "h2",
+          "headers": [
+            {"name": "content-type", "value": "application/json"},
+            {"name": "content-encoding", "value": "gzip"},
+            {"name": "content-length", "value": "40"}
+          ],
+          "content": {"size": 12, "mimeType": "application/json", "text": "eyJpZCI6NDJ9", "encoding": "base64"}
+        }
+      },
+      {
+        "startedDateTime": "2024-05-01T10:00:01Z",
+        "request": {
+          "method": "POST",
+          "url": "http://example.com/login",
+          "httpVersion": "HTTP/1.1",
+          "headers": [{"name": "Host", "value": "example.com"}],
+          "postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "bob"}, {"name": "pass", "value": "s3cret"}]}
+        },
+        "response": {
+          "status": 302,
+          "statusText": "Found",
+          "httpVersion": "HTTP/1.1",
+          "headers": [{"name": "Location", "value": "/home"}],
+          "content": {"size": 0, "mimeType": "text/html", "text": ""}
+        }
+      },
+      {
+        "startedDateTime": "2024-05-01T10:00:02Z",
+        "request": {"method": "GET", "url": "data:image/png;base64,iVBORw0KGgo=", "headers": []},
+        "response": {"status": 200, "headers": [], "content": {"size": 8, "mimeType": "image/png"}}
+      },
+      {
+        "startedDateTime": "2024-05-01T10:00:03Z",
+        "request": {"method": "GET", "url": "https://blocked.example.com/tracker.js", "headers": []},
+        "response": {"status": 0, "headers": [], "content": {"size": 0}}
+      }
+    ]
+  }
+}`
+
+func TestParseHAR(t *testing.T) {
+	histories, err := ParseHAR(strings.NewReader(harFixture), 7)
+	assert.Nil(t, err)
+	assert.Len(t, histories, 2)
+
+	post := histories[0]
+	assert.Equal(t, "POST", post.Method)
+	assert.Equal(t, "https://api.example.com/v1/users?invite=true", post.URL)
+	assert.Equal(t, 201, post.StatusCode)
+	assert.Equal(t, "HTTP/2.0", post.Proto)
+	assert.Equal(t, db.SourceImport, post.Source)
+	assert.Equal(t, uint(7), *post.WorkspaceID)
+	assert.Equal(t, `{"name":"alice","admin":false}`, string(post.RequestBody))
+	assert.Equal(t, "application/json", post.RequestContentType)
+	assert.Equal(t, `{"id":42}`, string(post.ResponseBody))
+	assert.Equal(t, 9, post.ResponseBodySize)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC), post.CreatedAt.UTC())
+
+	var requestHeaders map[string][]string
+	assert.Nil(t, json.Unmarshal(post.RequestHeaders, &requestHeaders))
+	assert.Equal(t, []string{"Bearer token"}, requestHeaders["Authorization"])
+	assert.NotContains(t, requestHeaders, ":authority")
+	var responseHeaders map[string][]string
+	assert.Nil(t, json.Unmarshal(post.ResponseHeaders, &responseHeaders))
+	assert.NotContains(t, responseHeaders, "Content-Encoding")
+
+	rawRequest := string(post.RawRequest)
+	assert.True(t, strings.HasPrefix(rawRequest, "POST /v1/users?invite=true HTTP/2.0\r\nHost: api.example.com\r\n"))
+	assert.True(t, strings.HasSuffix(rawRequest, "\r\n\r\n"+`{"name":"alice","admin":false}`))
+	assert.True(t, strings.HasPrefix(string(post.RawResponse), "HTTP/2.0 201 Created\r\n"))
+
+	form := histories[1]
+	assert.Equal(t, "pass=s3cret&user=bob", string(form.RequestBody))
+	assert.Equal(t, "application/x-www-form-urlencoded", form.RequestContentType)
+	assert.Equal(t, 302, form.StatusCode)
+	assert.Equal(t, "HTTP/1.1", form.Proto)
+	assert.Equal(t, 1, strings.Count(string(form.RawRequest), "Host: example.com"))
+
+	_, err = ParseHAR(strings.NewReader("not a har file"), 7)
+	assert.NotNil(t, err)
+}
+
+func TestHARRoundTrip(t *testing.T) {
+	imported, err := ParseHAR(strings.NewReader(harFixture), 7)
+	assert.Nil(t, err)
+
+	binary := db.History{
+		URL:                 "https://example.com/logo.png?v=2",
+		Method:              "GET",
+		Proto:               "HTTP/1.1",
+		StatusCode:          200,
+		RequestHeaders:      []byte(`{"Cookie":["session=abc"]}`),
+		ResponseHeaders:     []byte(`{"Content-Type":["image/png"]}`),
+		ResponseBody:        []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0xff, 0x00},
+		ResponseContentType: "image/png",
+	}
+	binary.CreatedAt = time.Date(2024, 5, 1, 10, 0, 5, 0, time.UTC)
+	imported = append(imported, binary)
+
+	var buf strings.Builder
+	hw, err := newHARWriter(&buf)
+	assert.Nil(t, err)
+	for i := range imported {
+		assert.Nil(t, hw.Write(&imported[i]))
+	}
+	assert.Nil(t, hw.Close())
+
+	var document struct {
+		Log struct {
+			Version string     `json:"version"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(buf.String()), &document))
+	assert.Equal(t, "1.2", document.Log.Version)
+	assert.Len(t, document.Log.Entries, 3)
+	png := document.Log.Entries[2]
+	assert.Equal(t, "base64", png.Response.Content.Encoding)
+	assert.Equal(t, []harNameValue{{Name: "session", Value: "abc"}}, png.Request.Cookies)
+	assert.Equal(t, []harNameValue{{Name: "v", Value: "2"}}, png.Request.QueryString)
+	assert.Equal(t, "/home", document.Log.Entries[1].Response.RedirectURL)
+
+	exported, err := ParseHAR(strings.NewReader(buf.String()), 7)
+	assert.Nil(t, err)
+	assert.Len(t, exported, len(imported))
+	for i := range imported {
+		assert.Equal(t, imported[i].Method, exported[i].Method)
+		assert.Equal(t, imported[i].URL, exported[i].URL)
+		assert.Equal(t, imported[i].Proto, exported[i].Proto)
+		assert.Equal(t, imported[i].StatusCode, exported[i].StatusCode)
+		assert.Equal(t, imported[i].RequestBody, exported[i].RequestBody)
+		assert.Equal(t, imported[i].ResponseBody, exported[i].ResponseBody)
+		assert.Equal(t, imported[i].ResponseContentType, exported[i].ResponseContentType)
+		assert.Equal(t, imported[i].CreatedAt.UTC(), exported[i].CreatedAt.UTC())
+		assert.JSONEq(t, string(imported[i].RequestHeaders), string(exported[i].RequestHeaders))
+		assert.JSONEq(t, string(imported[i].ResponseHeaders), string(exported[i].ResponseHeaders))
+	}
+}
@@ -0,0 +1,122 @@
+package http_utils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// HTTPProtocol is the HTTP version used by the scanner HTTP clients to send requests to the targets
+type HTTPProtocol string
+
+const (
+	// HTTPProtocolDefault keeps the default transport, which sends HTTP/1.1 requests
+	HTTPProtocolDefault HTTPProtocol = ""
+	HTTPProtocolHTTP1   HTTPProtocol = "http1"
+	// HTTPProtocolHTTP2 negotiates HTTP/2 through ALPN, falling back to HTTP/1.1 when the server does not support it
+	HTTPProtocolHTTP2 HTTPProtocol = "http2"
+	// HTTPProtocolHTTP3 attempts HTTP/3 over QUIC, falling back to HTTP/2 or HTTP/1.1 when it cannot be established
+	HTTPProtocolHTTP3 HTTPProtocol = "http3"
+)
+
+// http3HandshakeTimeout keeps the fallback fast when the target does not listen for QUIC connections
+const http3HandshakeTimeout = 3 * time.Second
+
+var (
+	sharedHttp3Transport     *http3.RoundTripper
+	sharedHttp3TransportOnce sync.Once
+
+	// http3UnsupportedHosts holds the hosts where HTTP/3 could not be established, to avoid retrying on every request
+	http3UnsupportedHosts sync.Map
+)
+
+// CreateProtocolTransport creates a transport sending the requests with the provided HTTP version
+func CreateProtocolTransport(protocol HTTPProtocol) http.RoundTripper {
+	switch protocol {
+	case HTTPProtocolHTTP2:
+		return createHttp2FallbackTransport()
+	case HTTPProtocolHTTP3:
+		return &http3FallbackTransport{
+			http3:    getSharedHttp3Transport(),
+			fallback: createHttp2FallbackTransport(),
+		}
+	default:
+		return CreateHttpTransport()
+	}
+}
+
+// createHttp2FallbackTransport creates a transport offering HTTP/2 through ALPN, which uses HTTP/1.1 when the server
+// does not select h2 or the connection is not encrypted
+func createHttp2FallbackTransport() *http.Transport {
+	transport := CreateHttpTransport()
+	transport.ForceAttemptHTTP2 = true
+	return transport
+}
+
+// getSharedHttp3Transport returns a single HTTP/3 transport, as each one keeps its own UDP socket and connections open
+func getSharedHttp3Transport() *http3.RoundTripper {
+	sharedHttp3TransportOnce.Do(func() {
+		sharedHttp3Transport = CreateHttp3Transport()
+		sharedHttp3Transport.QUICConfig = &quic.Config{
+			HandshakeIdleTimeout: http3HandshakeTimeout,
+			EnableDatagrams:      sharedHttp3Transport.EnableDatagrams,
+		}
+	})
+	return sharedHttp3Transport
+}
+
+type http3FallbackTransport struct {
+	http3    http.RoundTripper
+	fallback http.RoundTripper
+}
+
+func (t *http3FallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// QUIC connections can't go through the HTTP proxy, so the fallback is used to keep the traffic in it
+	if req.URL.Scheme != "https" || viper.GetString("navigation.proxy") != "" {
+		return t.fallback.RoundTrip(req)
+	}
+	if _, unsupported := http3UnsupportedHosts.Load(req.URL.Host); unsupported {
+		return t.fallback.RoundTrip(req)
+	}
+
+	// The body has to be readable again to send the request through the fallback
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	response, err := t.http3.RoundTrip(req)
+	if err == nil {
+		return response, nil
+	}
+	if req.Context().Err() != nil {
+		return nil, err
+	}
+	log.Debug().Err(err).Str("host", req.URL.Host).Msg("Could not establish an HTTP/3 connection, falling back to HTTP/2 or HTTP/1.1")
+	http3UnsupportedHosts.Store(req.URL.Host, true)
+
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return t.fallback.RoundTrip(req)
+}
@@ -0,0 +1,123 @@
+package http_utils
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+)
+
+// protocolHandler answers with the protocol of the request and its body
+var protocolHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	fmt.Fprintf(w, "%s %s", r.Proto, body)
+})
+
+// newHttp2OnlyTestServer returns a TLS server rejecting the requests not sent over HTTP/2
+func newHttp2OnlyTestServer() *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		protocolHandler(w, r)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	return server
+}
+
+func TestHTTP2ProtocolNegotiated(t *testing.T) {
+	server := newHttp2OnlyTestServer()
+	defer server.Close()
+
+	client := &http.Client{Transport: CreateProtocolTransport(HTTPProtocolHTTP2)}
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	history, err := ReadHttpResponseAndCreateHistory(resp, HistoryCreationOptions{Source: db.SourceScanner})
+	assert.Nil(t, err)
+	assert.Equal(t, "HTTP/2.0", history.Proto)
+
+	client = &http.Client{Transport: CreateProtocolTransport(HTTPProtocolHTTP1)}
+	resp, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusHTTPVersionNotSupported, resp.StatusCode)
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+}
+
+func TestClientOptionsHTTPProtocol(t *testing.T) {
+	server := newHttp2OnlyTestServer()
+	defer server.Close()
+
+	client := CreateHttpClientWithOptions(&ClientOptions{Protocol: HTTPProtocolHTTP2})
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	// Clients created without options keep the default protocol
+	resp, err = CreateHttpClient().Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+}
+
+func TestHTTP2ProtocolFallback(t *testing.T) {
+	server := httptest.NewTLSServer(protocolHandler)
+	defer server.Close()
+
+	client := &http.Client{Transport: CreateProtocolTransport(HTTPProtocolHTTP2)}
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+}
+
+func TestHTTP3Protocol(t *testing.T) {
+	tlsServer := httptest.NewUnstartedServer(protocolHandler)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := &http3.Server{Handler: protocolHandler, TLSConfig: http3.ConfigureTLSConfig(tlsServer.TLS)}
+	go server.Serve(conn)
+	defer server.Close()
+
+	client := &http.Client{Transport: CreateProtocolTransport(HTTPProtocolHTTP3)}
+	resp, err := client.Post("https://"+conn.LocalAddr().String(), "text/plain", strings.NewReader("quic"))
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/3.0", resp.Proto)
+	assert.Equal(t, "HTTP/3.0 quic", string(body))
+}
+
+func TestHTTP3ProtocolFallback(t *testing.T) {
+	server := newHttp2OnlyTestServer()
+	defer server.Close()
+
+	client := &http.Client{Transport: CreateProtocolTransport(HTTPProtocolHTTP3)}
+	// The body is sent again through the fallback after the HTTP/3 attempt fails
+	resp, err := client.Post(server.URL, "text/plain", io.NopCloser(strings.NewReader("fallback")))
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+	assert.Equal(t, "HTTP/2.0 fallback", string(body))
+
+	// Following requests to the host skip the HTTP/3 attempt
+	_, unsupported := http3UnsupportedHosts.Load(strings.TrimPrefix(server.URL, "https://"))
+	assert.True(t, unsupported)
+}
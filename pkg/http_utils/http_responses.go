@@ -15,8 +15,28 @@ import (
 
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/lib"
+	"github.com/spf13/viper"
 )
 
+// GetMaxBodyBytes returns the maximum size of the response bodies read, 0 meaning there is no limit
+func GetMaxBodyBytes() int64 {
+	return viper.GetInt64("http.max_body_bytes")
+}
+
+// ReadLimitedBody reads up to limit bytes from the body, returning whether it was larger and has been truncated.
+// The rest of the body is not read, so huge responses are never fully loaded into memory.
+func ReadLimitedBody(body io.Reader, limit int64) ([]byte, bool, error) {
+	if limit <= 0 {
+		bodyBytes, err := io.ReadAll(body)
+		return bodyBytes, false, err
+	}
+	bodyBytes, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if int64(len(bodyBytes)) > limit {
+		return bodyBytes[:limit], true, err
+	}
+	return bodyBytes, false, err
+}
+
 type ResponseBodyData struct {
 	Content string
 	Size    int
@@ -25,10 +45,13 @@ type ResponseBodyData struct {
 
 // ReadResponseBodyData reads an http response body and returns it as string + its length as bytes
 func ReadResponseBodyData(response *http.Response) (body []byte, size int, err error) {
-	bodyBytes, err := io.ReadAll(response.Body)
+	bodyBytes, truncated, err := ReadLimitedBody(response.Body, GetMaxBodyBytes())
 	if err != nil {
 		log.Error().Err(err).Msg("Error reading response body in ReadResponseBodyData")
 	}
+	if truncated {
+		log.Warn().Int("size", len(bodyBytes)).Msg("Response body exceeds the maximum size and has been truncated")
+	}
 	defer response.Body.Close()
 
 	size = len(bodyBytes) // Should check if its better to do the len on bytes or when converted to string
@@ -39,6 +62,7 @@ func ReadResponseBodyData(response *http.Response) (body []byte, size int, err e
 type FullResponseData struct {
 	Body      []byte
 	BodySize  int
+	Truncated bool
 	Raw       []byte
 	RawString string
 	RawSize   int
@@ -55,15 +79,29 @@ func ReadFullResponse(response *http.Response, createNewBodyStream bool) (FullRe
 	}
 	defer response.Body.Close()
 
-	responseDump, err := httputil.DumpResponse(response, true)
+	bodyBytes, truncated, err := ReadLimitedBody(response.Body, GetMaxBodyBytes())
 	if err != nil {
-		log.Error().Err(err).Msg("Error dumping response")
+		log.Error().Err(err).Msg("Error reading response body in ReadFullResponse")
 		return FullResponseData{}, nil, err
 	}
 
-	bodyBytes, err := io.ReadAll(response.Body)
+	dumped := response
+	if truncated {
+		logger := log.With().Int("size", len(bodyBytes)).Logger()
+		if response.Request != nil && response.Request.URL != nil {
+			logger = logger.With().Str("url", response.Request.URL.String()).Logger()
+		}
+		logger.Warn().Msg("Response body exceeds the maximum size and has been truncated")
+		// Dump a copy matching the truncated body, as the dump fails when the body is shorter than the content length
+		truncatedResponse := *response
+		truncatedResponse.ContentLength = int64(len(bodyBytes))
+		truncatedResponse.TransferEncoding = nil
+		dumped = &truncatedResponse
+	}
+	dumped.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	responseDump, err := httputil.DumpResponse(dumped, true)
 	if err != nil {
-		log.Error().Err(err).Msg("Error reading response body in ReadFullResponse")
+		log.Error().Err(err).Msg("Error dumping response")
 		return FullResponseData{}, nil, err
 	}
 
@@ -75,6 +113,7 @@ func ReadFullResponse(response *http.Response, createNewBodyStream bool) (FullRe
 	return FullResponseData{
 		Body:      bodyBytes,
 		BodySize:  len(bodyBytes),
+		Truncated: truncated,
 		Raw:       responseDump,
 		RawString: string(responseDump),
 		RawSize:   len(responseDump),
@@ -155,24 +194,25 @@ func CreateHistoryFromHttpResponse(response *http.Response, responseData FullRes
 	}
 
 	record := db.History{
-		URL:                 response.Request.URL.String(),
-		Depth:               lib.CalculateURLDepth(response.Request.URL.String()),
-		StatusCode:          response.StatusCode,
-		RequestHeaders:      datatypes.JSON(requestHeaders),
-		RequestBody:         requestBody,
-		RequestBodySize:     len(requestBody),
-		ResponseHeaders:     datatypes.JSON(responseHeaders),
-		ResponseBody:        responseData.Body,
-		ResponseBodySize:    responseData.BodySize,
-		Method:              response.Request.Method,
-		ResponseContentType: response.Header.Get("Content-Type"),
-		RequestContentType:  response.Request.Header.Get("Content-Type"),
-		Evaluated:           false,
-		Source:              options.Source,
-		RawRequest:          requestDump,
-		RawResponse:         responseData.Raw,
-		WorkspaceID:         &options.WorkspaceID,
-		TaskID:              &options.TaskID,
+		URL:                   response.Request.URL.String(),
+		Depth:                 lib.CalculateURLDepth(response.Request.URL.String()),
+		StatusCode:            response.StatusCode,
+		RequestHeaders:        datatypes.JSON(requestHeaders),
+		RequestBody:           requestBody,
+		RequestBodySize:       len(requestBody),
+		ResponseHeaders:       datatypes.JSON(responseHeaders),
+		ResponseBody:          responseData.Body,
+		ResponseBodySize:      responseData.BodySize,
+		ResponseBodyTruncated: responseData.Truncated,
+		Method:                response.Request.Method,
+		ResponseContentType:   response.Header.Get("Content-Type"),
+		RequestContentType:    response.Request.Header.Get("Content-Type"),
+		Evaluated:             false,
+		Source:                options.Source,
+		RawRequest:            requestDump,
+		RawResponse:           responseData.Raw,
+		WorkspaceID:           &options.WorkspaceID,
+		TaskID:                &options.TaskID,
 		// TaskJobID:           &options.TaskJobID,
 		PlaygroundSessionID: playgroundSessionID,
 		Proto:               response.Proto,
@@ -0,0 +1,66 @@
+package http_utils
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLimitedBody(t *testing.T) {
+	body, truncated, err := ReadLimitedBody(strings.NewReader("0123456789"), 4)
+	assert.Nil(t, err)
+	assert.True(t, truncated)
+	assert.Equal(t, "0123", string(body))
+
+	body, truncated, err = ReadLimitedBody(strings.NewReader("0123456789"), 10)
+	assert.Nil(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, "0123456789", string(body))
+
+	body, truncated, err = ReadLimitedBody(strings.NewReader("0123456789"), 0)
+	assert.Nil(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, "0123456789", string(body))
+}
+
+func TestReadFullResponseTruncatesLargeBodies(t *testing.T) {
+	large := bytes.Repeat([]byte("A"), 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write(large)
+	}))
+	defer server.Close()
+
+	previous := viper.Get("http.max_body_bytes")
+	viper.Set("http.max_body_bytes", 1024)
+	defer viper.Set("http.max_body_bytes", previous)
+
+	for _, path := range []string{"/", "/chunked"} {
+		resp, err := http.Get(server.URL + path)
+		assert.Nil(t, err)
+		data, newBody, err := ReadFullResponse(resp, true)
+		assert.Nil(t, err, path)
+		assert.True(t, data.Truncated, path)
+		assert.Equal(t, 1024, data.BodySize, path)
+		assert.Equal(t, large[:1024], data.Body, path)
+		assert.True(t, bytes.HasSuffix(data.Raw, large[:1024]), path)
+		assert.Less(t, data.RawSize, len(large), path)
+		assert.NotNil(t, newBody, path)
+	}
+
+	viper.Set("http.max_body_bytes", 8192)
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	data, _, err := ReadFullResponse(resp, false)
+	assert.Nil(t, err)
+	assert.False(t, data.Truncated)
+	assert.Equal(t, large, data.Body)
+	assert.True(t, bytes.Contains(data.Raw, large))
+}
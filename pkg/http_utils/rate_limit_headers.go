@@ -0,0 +1,131 @@
+package http_utils
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitInfo holds the rate limiting details disclosed by a response through its headers
+type RateLimitInfo struct {
+	Limit        int
+	HasLimit     bool
+	Remaining    int
+	HasRemaining bool
+	// Reset is the time left until the rate limit window is reset
+	Reset    time.Duration
+	HasReset bool
+	// Headers contains all the rate limit related headers found in the response
+	Headers map[string]string
+	// QuotaIdentifiers contains the headers disclosing internal quota details such as bucket, scope or key names
+	QuotaIdentifiers map[string]string
+}
+
+var (
+	rateLimitHeaderRegex       = regexp.MustCompile(`(?i)^(x-)?(rate-?limit|quota)`)
+	rateLimitLimitRegex        = regexp.MustCompile(`(?i)^(x-)?rate-?limit-limit$`)
+	rateLimitRemainingRegex    = regexp.MustCompile(`(?i)^(x-)?rate-?limit-remaining$`)
+	rateLimitResetRegex        = regexp.MustCompile(`(?i)^(x-)?rate-?limit-reset(-after)?$`)
+	rateLimitQuotaDetailsRegex = regexp.MustCompile(`(?i)(bucket|scope|key|resource|id|user|account|client|plan|tier|category)$`)
+)
+
+// epochThreshold is used to distinguish reset values sent as unix timestamps from the ones sent as seconds
+const epochThreshold = 1000000000
+
+// ParseRateLimitHeaders extracts rate limiting details from the provided response headers, returning nil when
+// no rate limit headers are present
+func ParseRateLimitHeaders(headers map[string][]string) *RateLimitInfo {
+	info := &RateLimitInfo{
+		Headers:          make(map[string]string),
+		QuotaIdentifiers: make(map[string]string),
+	}
+	for name, values := range headers {
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.TrimSpace(values[0])
+		canonical := http.CanonicalHeaderKey(name)
+		isRetryAfter := canonical == "Retry-After"
+		if !isRetryAfter && !rateLimitHeaderRegex.MatchString(canonical) {
+			continue
+		}
+		info.Headers[canonical] = value
+
+		switch {
+		case rateLimitLimitRegex.MatchString(canonical):
+			if limit, ok := parseRateLimitNumber(value); ok {
+				info.Limit = limit
+				info.HasLimit = true
+			}
+		case rateLimitRemainingRegex.MatchString(canonical):
+			if remaining, ok := parseRateLimitNumber(value); ok {
+				info.Remaining = remaining
+				info.HasRemaining = true
+			}
+		case rateLimitResetRegex.MatchString(canonical), isRetryAfter:
+			if reset, ok := parseRateLimitReset(value); ok && (!info.HasReset || reset > info.Reset) {
+				info.Reset = reset
+				info.HasReset = true
+			}
+		case rateLimitQuotaDetailsRegex.MatchString(canonical):
+			info.QuotaIdentifiers[canonical] = value
+		}
+	}
+	if len(info.Headers) == 0 {
+		return nil
+	}
+	return info
+}
+
+// parseRateLimitNumber parses numeric header values, which can contain extra details after a separator (e.g. "100, 100;w=60")
+func parseRateLimitNumber(value string) (int, bool) {
+	end := strings.IndexAny(value, ",; ")
+	if end != -1 {
+		value = value[:end]
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, false
+	}
+	return number, true
+}
+
+// parseRateLimitReset parses reset values sent as seconds, unix timestamps or http dates
+func parseRateLimitReset(value string) (time.Duration, bool) {
+	if number, ok := parseRateLimitNumber(value); ok {
+		if number > epochThreshold {
+			reset := time.Until(time.Unix(int64(number), 0))
+			if reset < 0 {
+				reset = 0
+			}
+			return reset, true
+		}
+		return time.Duration(number) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		reset := time.Until(date)
+		if reset < 0 {
+			reset = 0
+		}
+		return reset, true
+	}
+	return 0, false
+}
+
+// SafeRate returns the requests per second that can be sent without exceeding the disclosed rate limit
+func (i *RateLimitInfo) SafeRate() (float64, bool) {
+	if i == nil || !i.HasReset || i.Reset <= 0 {
+		return 0, false
+	}
+	seconds := i.Reset.Seconds()
+	if i.HasRemaining {
+		// When the quota has been exhausted, allow a single request once the window is reset
+		return float64(max(i.Remaining, 1)) / seconds, true
+	}
+	if i.HasLimit {
+		return float64(i.Limit) / seconds, true
+	}
+	return 0, false
+}
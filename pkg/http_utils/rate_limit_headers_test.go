@@ -0,0 +1,49 @@
+package http_utils
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	headers := map[string][]string{
+		"X-RateLimit-Limit":     {"100"},
+		"X-RateLimit-Remaining": {"20"},
+		"X-RateLimit-Reset":     {"10"},
+		"X-RateLimit-Bucket":    {"internal-api-tier-2"},
+		"Content-Type":          {"application/json"},
+	}
+	info := ParseRateLimitHeaders(headers)
+	assert.NotNil(t, info)
+	assert.True(t, info.HasLimit)
+	assert.Equal(t, 100, info.Limit)
+	assert.True(t, info.HasRemaining)
+	assert.Equal(t, 20, info.Remaining)
+	assert.True(t, info.HasReset)
+	assert.Equal(t, 10*time.Second, info.Reset)
+	assert.Len(t, info.Headers, 4)
+	assert.Equal(t, map[string]string{"X-Ratelimit-Bucket": "internal-api-tier-2"}, info.QuotaIdentifiers)
+
+	rate, ok := info.SafeRate()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, rate)
+}
+
+func TestParseRateLimitHeadersVariants(t *testing.T) {
+	reset := time.Now().Add(60 * time.Second).Unix()
+	info := ParseRateLimitHeaders(map[string][]string{
+		"RateLimit-Limit":     {"50, 50;w=60"},
+		"RateLimit-Remaining": {"0"},
+		"x-ratelimit-reset":   {strconv.FormatInt(reset, 10)},
+	})
+	assert.NotNil(t, info)
+	assert.Equal(t, 50, info.Limit)
+	assert.Equal(t, 0, info.Remaining)
+	assert.InDelta(t, 60, info.Reset.Seconds(), 2)
+	assert.Empty(t, info.QuotaIdentifiers)
+
+	assert.Nil(t, ParseRateLimitHeaders(map[string][]string{"Content-Type": {"text/html"}}))
+}
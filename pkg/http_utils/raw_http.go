@@ -0,0 +1,262 @@
+package http_utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyneda/sukyan/pkg/scope"
+	"golang.org/x/net/proxy"
+)
+
+// RawHeader is a header written exactly as provided, keeping its case, position and duplicates
+type RawHeader struct {
+	Name  string
+	Value string
+}
+
+// RawRequest is an HTTP/1.1 request written to the connection without the normalization applied by the http package,
+// which allows sending ambiguous requests such as the ones having both Content-Length and Transfer-Encoding headers.
+// Neither the Host nor the Content-Length headers are added, so they have to be provided when needed.
+type RawRequest struct {
+	Method  string
+	Target  string
+	Headers []RawHeader
+	Body    string
+}
+
+// Bytes returns the request exactly as it is written to the connection
+func (r RawRequest) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(r.Method + " " + r.Target + " HTTP/1.1\r\n")
+	for _, header := range r.Headers {
+		buf.WriteString(header.Name + ": " + header.Value + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.WriteString(r.Body)
+	return buf.Bytes()
+}
+
+// RawResponse is the result of sending a raw request. When no response has been received before the timeout, TimedOut
+// is set and Duration holds the time waited.
+type RawResponse struct {
+	StatusCode int
+	Raw        []byte
+	Duration   time.Duration
+	TimedOut   bool
+}
+
+// rawRequestAddress returns the address to connect to for the URL, using the default port of its scheme if missing
+func rawRequestAddress(target *url.URL) string {
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(target.Hostname(), port)
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// rawRequestURL returns the URL requested by the raw request, taking the method and path from its request line
+func rawRequestURL(target *url.URL, raw []byte) (string, string) {
+	line, _, _ := bytes.Cut(raw, []byte("\r\n"))
+	parts := strings.SplitN(string(line), " ", 3)
+	if len(parts) < 2 {
+		return "", target.String()
+	}
+	if strings.HasPrefix(parts[1], "/") {
+		return parts[0], target.Scheme + "://" + target.Host + parts[1]
+	}
+	return parts[0], parts[1]
+}
+
+// dialRawConnection connects to the host of the target URL, tunneling the connection through the configured proxy
+// with a CONNECT request when there is one, so the raw request reaches the target without being normalized
+func dialRawConnection(ctx context.Context, target *url.URL, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	address := rawRequestAddress(target)
+	proxyURL, err := getProxyFunc()(&http.Request{URL: target})
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	switch {
+	case proxyURL == nil:
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	case strings.HasPrefix(proxyURL.Scheme, "socks5"):
+		var proxyDialer proxy.Dialer
+		if proxyDialer, err = proxy.FromURL(proxyURL, dialer); err == nil {
+			if contextDialer, ok := proxyDialer.(proxy.ContextDialer); ok {
+				conn, err = contextDialer.DialContext(ctx, "tcp", address)
+			} else {
+				conn, err = proxyDialer.Dial("tcp", address)
+			}
+		}
+	default:
+		conn, err = dialProxyTunnel(ctx, dialer, proxyURL, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "https" {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         target.Hostname(),
+		NextProtos:         []string{"http/1.1"},
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// dialProxyTunnel opens a tunnel to the address through the proxy using a CONNECT request
+func dialProxyTunnel(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, address string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", rawRequestAddress(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		connect.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// The reader is not kept, as nothing else is sent by the proxy until the request is written to the tunnel
+	response, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused to connect to %s: %s", address, response.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// SendRawRequest opens a new connection to the host of the target URL, writes the raw request and reads the response
+// until the timeout. Each request uses its own connection, tunneled through the configured proxy if any. Requests out
+// of the scope of the client options are refused, and the others are paced by their rate limiter.
+func SendRawRequest(ctx context.Context, target *url.URL, raw []byte, timeout time.Duration, options *ClientOptions) (*RawResponse, error) {
+	if options != nil {
+		method, requestURL := rawRequestURL(target, raw)
+		if pattern, excluded := options.Exclusions.Match(requestURL); excluded {
+			return nil, fmt.Errorf("%w: %s matches %s", scope.ErrURLExcluded, requestURL, pattern)
+		}
+		if !options.ScopeRules.InScope(requestURL, method) {
+			return nil, fmt.Errorf("%w: %s %s", scope.ErrOutOfScope, method, requestURL)
+		}
+		if err := options.RateLimiter.Wait(ctx, target.Host); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := dialRawConnection(ctx, target, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(raw); err != nil {
+		return nil, err
+	}
+
+	var received bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(conn, &received))
+	response, err := http.ReadResponse(reader, nil)
+	result := &RawResponse{Duration: time.Since(start)}
+	if err != nil {
+		if isTimeoutError(err) {
+			result.TimedOut = true
+			result.Raw = received.Bytes()
+			return result, nil
+		}
+		return nil, err
+	}
+	result.StatusCode = response.StatusCode
+	// The body might never be completed by desynchronized servers, so whatever arrives before the deadline is kept
+	limit := GetMaxBodyBytes()
+	if limit <= 0 {
+		limit = 1024 * 1024
+	}
+	io.Copy(io.Discard, io.LimitReader(response.Body, limit))
+	response.Body.Close()
+	result.Raw = received.Bytes()
+	return result, nil
+}
+
+// ScanRawHeaders returns the headers to send with raw requests based on a history item: the provided scan User-Agent
+// and extra headers, and the session headers of the original request. Headers such as Host, Content-Length or
+// Transfer-Encoding are left to the caller.
+func ScanRawHeaders(requestHeaders map[string][]string, scanHeaders ScanHeadersConfig) []RawHeader {
+	userAgent := scanHeaders.UserAgent
+	if userAgent == "" {
+		userAgent = GetUserAgent()
+	}
+	rawHeaders := []RawHeader{{Name: "User-Agent", Value: userAgent}}
+	extraHeaders := scanHeaders.ExtraHeaders
+	names := make([]string, 0, len(extraHeaders))
+	for name := range extraHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rawHeaders = append(rawHeaders, RawHeader{Name: name, Value: extraHeaders[name]})
+	}
+	for _, name := range []string{"Cookie", "Authorization"} {
+		if _, isExtra := extraHeaders[name]; isExtra {
+			continue
+		}
+		for headerName, values := range requestHeaders {
+			if !strings.EqualFold(headerName, name) {
+				continue
+			}
+			for _, value := range values {
+				rawHeaders = append(rawHeaders, RawHeader{Name: name, Value: value})
+			}
+		}
+	}
+	return rawHeaders
+}
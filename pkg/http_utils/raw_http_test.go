@@ -0,0 +1,175 @@
+package http_utils
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/pkg/scope"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawRequestBytes(t *testing.T) {
+	request := RawRequest{
+		Method: "POST",
+		Target: "/search?q=1",
+		Headers: []RawHeader{
+			{Name: "Host", Value: "example.com"},
+			{Name: "Content-Length", Value: "4"},
+			{Name: "Transfer-Encoding ", Value: "chunked"},
+			{Name: "transfer-encoding", Value: "identity"},
+		},
+		Body: "1\r\nA\r\nX",
+	}
+	expected := "POST /search?q=1 HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Content-Length: 4\r\n" +
+		"Transfer-Encoding : chunked\r\n" +
+		"transfer-encoding: identity\r\n" +
+		"\r\n" +
+		"1\r\nA\r\nX"
+	assert.Equal(t, expected, string(request.Bytes()))
+}
+
+// newRawTestServer accepts a single connection, reads the request bytes and, when a response is provided, writes it
+func newRawTestServer(t *testing.T, requestSize int, response string) (*url.URL, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	received := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, requestSize)
+		_, err = io.ReadFull(bufio.NewReader(conn), buf)
+		received <- string(buf)
+		if err != nil || response == "" {
+			// Keep the connection open without answering
+			time.Sleep(time.Second)
+			return
+		}
+		conn.Write([]byte(response))
+	}()
+	target, _ := url.Parse("http://" + listener.Addr().String() + "/")
+	return target, received
+}
+
+func TestSendRawRequest(t *testing.T) {
+	raw := RawRequest{
+		Method:  "POST",
+		Target:  "/",
+		Headers: []RawHeader{{Name: "Host", Value: "example.com"}, {Name: "Content-Length", Value: "6"}, {Name: "Transfer-Encoding", Value: "chunked"}},
+		Body:    "0\r\n\r\nX",
+	}.Bytes()
+	target, received := newRawTestServer(t, len(raw), "HTTP/1.1 400 Bad Request\r\nContent-Length: 3\r\nConnection: close\r\n\r\nbad")
+
+	response, err := SendRawRequest(context.Background(), target, raw, 2*time.Second, nil)
+	assert.Nil(t, err)
+	// The request reaches the server exactly as written
+	assert.Equal(t, string(raw), <-received)
+	assert.False(t, response.TimedOut)
+	assert.Equal(t, 400, response.StatusCode)
+	assert.Contains(t, string(response.Raw), "\r\n\r\nbad")
+}
+
+func TestSendRawRequestTimeout(t *testing.T) {
+	raw := RawRequest{Method: "GET", Target: "/", Headers: []RawHeader{{Name: "Host", Value: "example.com"}}}.Bytes()
+	target, received := newRawTestServer(t, len(raw), "")
+
+	response, err := SendRawRequest(context.Background(), target, raw, 200*time.Millisecond, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, string(raw), <-received)
+	assert.True(t, response.TimedOut)
+	assert.GreaterOrEqual(t, response.Duration, 200*time.Millisecond)
+	assert.Equal(t, 0, response.StatusCode)
+}
+
+// newConnectProxy accepts a single CONNECT request, tunneling the connection to the requested address
+func newConnectProxy(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	connected := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || request.Method != http.MethodConnect {
+			return
+		}
+		connected <- request.Host
+		upstream, err := net.Dial("tcp", request.Host)
+		if err != nil {
+			return
+		}
+		defer upstream.Close()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}()
+	return "http://" + listener.Addr().String(), connected
+}
+
+func TestSendRawRequestThroughProxy(t *testing.T) {
+	raw := RawRequest{Method: "GET", Target: "/", Headers: []RawHeader{{Name: "Host", Value: "example.com"}}}.Bytes()
+	target, received := newRawTestServer(t, len(raw), "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+	proxyURL, connected := newConnectProxy(t)
+	viper.Set("navigation.proxy", proxyURL)
+	defer viper.Set("navigation.proxy", "")
+
+	response, err := SendRawRequest(context.Background(), target, raw, 2*time.Second, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, target.Host, <-connected)
+	assert.Equal(t, string(raw), <-received)
+	assert.Equal(t, 200, response.StatusCode)
+}
+
+func TestSendRawRequestOutOfScope(t *testing.T) {
+	target, _ := url.Parse("http://127.0.0.1:1/")
+	exclusions, err := scope.NewURLExclusions([]string{"*/logout*"})
+	assert.Nil(t, err)
+	scopeRules, err := scope.ScopeRules{ExcludeMethods: []string{"DELETE"}}.Compile()
+	assert.Nil(t, err)
+	options := &ClientOptions{Exclusions: exclusions, ScopeRules: scopeRules}
+
+	raw := RawRequest{Method: "GET", Target: "/logout", Headers: []RawHeader{{Name: "Host", Value: "example.com"}}}.Bytes()
+	_, err = SendRawRequest(context.Background(), target, raw, time.Second, options)
+	assert.ErrorIs(t, err, scope.ErrURLExcluded)
+
+	raw = RawRequest{Method: "DELETE", Target: "/users/1", Headers: []RawHeader{{Name: "Host", Value: "example.com"}}}.Bytes()
+	_, err = SendRawRequest(context.Background(), target, raw, time.Second, options)
+	assert.ErrorIs(t, err, scope.ErrOutOfScope)
+}
+
+func TestScanRawHeaders(t *testing.T) {
+	scanHeaders := ScanHeadersConfig{
+		UserAgent:    "Scanner/1.0",
+		ExtraHeaders: map[string]string{"X-Staging-Token": "secret", "Authorization": "Basic c3RhZ2luZw=="},
+	}
+	headers := ScanRawHeaders(map[string][]string{
+		"Cookie":        {"session=1"},
+		"Authorization": {"Bearer token"},
+		"Accept":        {"*/*"},
+	}, scanHeaders)
+	assert.Equal(t, []RawHeader{
+		{Name: "User-Agent", Value: "Scanner/1.0"},
+		{Name: "Authorization", Value: "Basic c3RhZ2luZw=="},
+		{Name: "X-Staging-Token", Value: "secret"},
+		{Name: "Cookie", Value: "session=1"},
+	}, headers)
+
+	headers = ScanRawHeaders(map[string][]string{"Accept": {"*/*"}}, ScanHeadersConfig{})
+	assert.Equal(t, []RawHeader{{Name: "User-Agent", Value: GetUserAgent()}}, headers)
+}
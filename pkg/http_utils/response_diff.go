@@ -0,0 +1,165 @@
+package http_utils
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	BodyDiffLineAdded   = "added"
+	BodyDiffLineRemoved = "removed"
+)
+
+// HeaderChange is a response header present in both responses with different values
+type HeaderChange struct {
+	Name   string   `json:"name"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// BodyDiffLine is a line only present in one of the response bodies, with its line number in the body it belongs to
+type BodyDiffLine struct {
+	Type string `json:"type"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// BodyDiff holds the lines added and removed from the first response body to the second
+type BodyDiff struct {
+	Identical    bool           `json:"identical"`
+	Similarity   float64        `json:"similarity"`
+	AddedLines   int            `json:"added_lines"`
+	RemovedLines int            `json:"removed_lines"`
+	Lines        []BodyDiffLine `json:"lines"`
+}
+
+// ResponseDiff is the difference between the responses of two history items
+type ResponseDiff struct {
+	A                 uint                `json:"a"`
+	B                 uint                `json:"b"`
+	StatusCodeA       int                 `json:"status_code_a"`
+	StatusCodeB       int                 `json:"status_code_b"`
+	StatusCodeChanged bool                `json:"status_code_changed"`
+	HeadersAdded      map[string][]string `json:"headers_added"`
+	HeadersRemoved    map[string][]string `json:"headers_removed"`
+	HeadersChanged    []HeaderChange      `json:"headers_changed"`
+	Body              BodyDiff            `json:"body"`
+}
+
+// Identical returns true when both responses have the same status code, headers and body
+func (d *ResponseDiff) Identical() bool {
+	return !d.StatusCodeChanged && len(d.HeadersAdded) == 0 && len(d.HeadersRemoved) == 0 && len(d.HeadersChanged) == 0 && d.Body.Identical
+}
+
+// DiffResponses compares the response of history item a with the response of history item b
+func DiffResponses(a, b *db.History) (*ResponseDiff, error) {
+	aHeaders, err := a.GetResponseHeadersAsMap()
+	if err != nil {
+		return nil, err
+	}
+	bHeaders, err := b.GetResponseHeadersAsMap()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ResponseDiff{
+		A:                 a.ID,
+		B:                 b.ID,
+		StatusCodeA:       a.StatusCode,
+		StatusCodeB:       b.StatusCode,
+		StatusCodeChanged: a.StatusCode != b.StatusCode,
+	}
+	diff.HeadersAdded, diff.HeadersRemoved, diff.HeadersChanged = diffHeaders(aHeaders, bHeaders)
+	diff.Body = diffBodies(a.ResponseBody, b.ResponseBody)
+	return diff, nil
+}
+
+// canonicalHeaders merges the values of headers whose names only differ in case
+func canonicalHeaders(headers map[string][]string) map[string][]string {
+	canonical := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		key := http.CanonicalHeaderKey(name)
+		canonical[key] = append(canonical[key], values...)
+	}
+	return canonical
+}
+
+func diffHeaders(a, b map[string][]string) (added, removed map[string][]string, changed []HeaderChange) {
+	a = canonicalHeaders(a)
+	b = canonicalHeaders(b)
+	added = make(map[string][]string)
+	removed = make(map[string][]string)
+	changed = []HeaderChange{}
+	for name, values := range a {
+		bValues, ok := b[name]
+		if !ok {
+			removed[name] = values
+			continue
+		}
+		if !slices.Equal(values, bValues) {
+			changed = append(changed, HeaderChange{Name: name, Before: values, After: bValues})
+		}
+	}
+	for name, values := range b {
+		if _, ok := a[name]; !ok {
+			added[name] = values
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].Name < changed[j].Name
+	})
+	return added, removed, changed
+}
+
+// diffBodies compares the bodies line by line, the line numbers of removed lines refer to the first body and the ones
+// of added lines to the second
+func diffBodies(a, b []byte) BodyDiff {
+	if string(a) == string(b) {
+		return BodyDiff{Identical: true, Similarity: 1, Lines: []BodyDiffLine{}}
+	}
+	dmp := diffmatchpatch.New()
+	aChars, bChars, lines := dmp.DiffLinesToChars(string(a), string(b))
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(aChars, bChars, false), lines)
+
+	result := BodyDiff{
+		Similarity: lib.ComputeSimilarity(a, b),
+		Lines:      []BodyDiffLine{},
+	}
+	aLine, bLine := 1, 1
+	for _, d := range diffs {
+		for _, text := range splitDiffLines(d.Text) {
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				aLine++
+				bLine++
+			case diffmatchpatch.DiffDelete:
+				result.Lines = append(result.Lines, BodyDiffLine{Type: BodyDiffLineRemoved, Line: aLine, Text: text})
+				result.RemovedLines++
+				aLine++
+			case diffmatchpatch.DiffInsert:
+				result.Lines = append(result.Lines, BodyDiffLine{Type: BodyDiffLineAdded, Line: bLine, Text: text})
+				result.AddedLines++
+				bLine++
+			}
+		}
+	}
+	return result
+}
+
+// splitDiffLines splits the text of a line mode diff into its lines, without their line breaks
+func splitDiffLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	}
+	return lines
+}
@@ -0,0 +1,61 @@
+package http_utils
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffResponsesHeaders(t *testing.T) {
+	a := &db.History{
+		StatusCode:      200,
+		ResponseHeaders: []byte(`{"Content-Type":["text/html"],"Set-Cookie":["a=1"],"X-Removed":["yes"]}`),
+		ResponseBody:    []byte("same"),
+	}
+	b := &db.History{
+		StatusCode:      500,
+		ResponseHeaders: []byte(`{"content-type":["text/html"],"Set-Cookie":["a=2"],"X-Added":["yes"]}`),
+		ResponseBody:    []byte("same"),
+	}
+
+	diff, err := DiffResponses(a, b)
+	assert.Nil(t, err)
+	assert.True(t, diff.StatusCodeChanged)
+	assert.Equal(t, map[string][]string{"X-Added": {"yes"}}, diff.HeadersAdded)
+	assert.Equal(t, map[string][]string{"X-Removed": {"yes"}}, diff.HeadersRemoved)
+	assert.Equal(t, []HeaderChange{{Name: "Set-Cookie", Before: []string{"a=1"}, After: []string{"a=2"}}}, diff.HeadersChanged)
+	assert.True(t, diff.Body.Identical)
+	assert.False(t, diff.Identical())
+
+	diff, err = DiffResponses(a, a)
+	assert.Nil(t, err)
+	assert.True(t, diff.Identical())
+
+	_, err = DiffResponses(a, &db.History{ResponseHeaders: []byte("invalid")})
+	assert.NotNil(t, err)
+}
+
+func TestDiffResponsesBody(t *testing.T) {
+	a := &db.History{
+		ResponseHeaders: []byte(`{}`),
+		ResponseBody:    []byte("<html>\r\n<h1>Search</h1>\r\n<p>No results</p>\r\n</html>"),
+	}
+	b := &db.History{
+		ResponseHeaders: []byte(`{}`),
+		ResponseBody:    []byte("<html>\r\n<h1>Search</h1>\r\n<p>1 result</p>\r\n<p>You have an error in your SQL syntax</p>\r\n</html>"),
+	}
+
+	diff, err := DiffResponses(a, b)
+	assert.Nil(t, err)
+	assert.False(t, diff.Body.Identical)
+	assert.Equal(t, 2, diff.Body.AddedLines)
+	assert.Equal(t, 1, diff.Body.RemovedLines)
+	assert.Equal(t, []BodyDiffLine{
+		{Type: BodyDiffLineRemoved, Line: 3, Text: "<p>No results</p>"},
+		{Type: BodyDiffLineAdded, Line: 3, Text: "<p>1 result</p>"},
+		{Type: BodyDiffLineAdded, Line: 4, Text: "<p>You have an error in your SQL syntax</p>"},
+	}, diff.Body.Lines)
+	assert.Greater(t, diff.Body.Similarity, 0.5)
+	assert.Less(t, diff.Body.Similarity, 1.0)
+}
@@ -0,0 +1,65 @@
+package http_utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RobotsTxt holds the paths and sitemaps declared in a robots.txt file. Rules of all the user agent groups are
+// merged, as every path they reference is worth crawling regardless of the agent it applies to.
+type RobotsTxt struct {
+	Allowed    []string
+	Disallowed []string
+	Sitemaps   []string
+}
+
+// ParseRobots extracts the allowed and disallowed paths and the sitemaps declared in a robots.txt file
+func ParseRobots(content string) RobotsTxt {
+	robots := RobotsTxt{}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		directive, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		value = strings.TrimSpace(value)
+		if value == "" || seen[directive+value] {
+			continue
+		}
+		seen[directive+value] = true
+		switch directive {
+		case "allow":
+			robots.Allowed = append(robots.Allowed, value)
+		case "disallow":
+			robots.Disallowed = append(robots.Disallowed, value)
+		case "sitemap":
+			robots.Sitemaps = append(robots.Sitemaps, value)
+		}
+	}
+	return robots
+}
+
+// ResolveRobotsPath resolves a robots.txt path against the base URL, so that it can be requested. Since wildcards
+// cannot be requested, the path is truncated at the first one, and the end of path anchor is removed.
+func ResolveRobotsPath(baseURL, path string) (string, bool) {
+	if idx := strings.Index(path, "*"); idx != -1 {
+		path = path[:idx]
+	}
+	path = strings.TrimSuffix(path, "$")
+	if path == "" {
+		return "", false
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", false
+	}
+	resolved, err := base.Parse(path)
+	if err != nil {
+		return "", false
+	}
+	return resolved.String(), true
+}
@@ -0,0 +1,55 @@
+package http_utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRobots(t *testing.T) {
+	content := `# Robots for example.com
+User-agent: *
+Disallow: /admin/
+Disallow: /private/*.json$
+Allow: /admin/public # Publicly available
+Disallow:
+
+User-agent: Googlebot
+disallow: /admin/
+DISALLOW: /backup.zip
+Crawl-delay: 10
+
+Sitemap: https://example.com/sitemap_index.xml
+Sitemap: https://example.com/news-sitemap.xml
+`
+	robots := ParseRobots(content)
+	assert.Equal(t, []string{"/admin/public"}, robots.Allowed)
+	assert.Equal(t, []string{"/admin/", "/private/*.json$", "/backup.zip"}, robots.Disallowed)
+	assert.Equal(t, []string{"https://example.com/sitemap_index.xml", "https://example.com/news-sitemap.xml"}, robots.Sitemaps)
+
+	empty := ParseRobots("")
+	assert.Empty(t, empty.Allowed)
+	assert.Empty(t, empty.Disallowed)
+	assert.Empty(t, empty.Sitemaps)
+}
+
+func TestResolveRobotsPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"/admin/", "https://example.com/admin/", true},
+		{"/private/*.json$", "https://example.com/private/", true},
+		{"/search$", "https://example.com/search", true},
+		{"/*?sessionid=", "https://example.com/", true},
+		{"*", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resolved, ok := ResolveRobotsPath("https://example.com/", tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, resolved)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package http_utils
+
+import (
+	"net/http"
+
+	"github.com/spf13/viper"
+)
+
+// ScanHeadersConfig holds the User-Agent and extra headers added to every request sent during a scan, such as the
+// headers required to get through the protections of staging environments
+type ScanHeadersConfig struct {
+	UserAgent    string
+	ExtraHeaders map[string]string
+}
+
+// IsEmpty returns true when there is nothing to add to the requests
+func (c ScanHeadersConfig) IsEmpty() bool {
+	return c.UserAgent == "" && len(c.ExtraHeaders) == 0
+}
+
+// Apply adds the configured headers to the request, keeping the ones already set so the headers intentionally sent by
+// audits are not overwritten. The default User-Agent set when building requests is replaced by the one of the scan.
+func (c ScanHeadersConfig) Apply(req *http.Request) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	if c.UserAgent != "" {
+		if userAgent := req.Header.Get("User-Agent"); userAgent == "" || userAgent == GetUserAgent() {
+			req.Header.Set("User-Agent", c.UserAgent)
+		}
+	}
+	for name, value := range c.ExtraHeaders {
+		if _, exists := req.Header[http.CanonicalHeaderKey(name)]; !exists {
+			req.Header.Set(name, value)
+		}
+	}
+}
+
+// GetUserAgent returns the default User-Agent, taken from the navigation.user_agent config or falling back to the
+// built in one. Scans can override it through the User-Agent of their ScanHeadersConfig.
+func GetUserAgent() string {
+	if userAgent := viper.GetString("navigation.user_agent"); userAgent != "" {
+		return userAgent
+	}
+	return DefaultUserAgent
+}
+
+type scanHeadersRoundTripper struct {
+	next   http.RoundTripper
+	config ScanHeadersConfig
+}
+
+func (t *scanHeadersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Round trippers should not modify the provided request
+	req = req.Clone(req.Context())
+	t.config.Apply(req)
+	return t.next.RoundTrip(req)
+}
+
+// WrapScanHeadersTransport wraps the transport so the provided scan headers are added to every request
+func WrapScanHeadersTransport(next http.RoundTripper, config ScanHeadersConfig) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if config.IsEmpty() {
+		return next
+	}
+	return &scanHeadersRoundTripper{next: next, config: config}
+}
@@ -0,0 +1,79 @@
+package http_utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanHeadersConfigApply(t *testing.T) {
+	config := ScanHeadersConfig{
+		UserAgent: "Scanner/1.0",
+		ExtraHeaders: map[string]string{
+			"x-staging-token":  "secret",
+			"X-Forwarded-Host": "scanner.example.com",
+		},
+	}
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	req.Header.Set("X-Forwarded-Host", "evil.com")
+	config.Apply(req)
+
+	assert.Equal(t, "Scanner/1.0", req.Header.Get("User-Agent"))
+	assert.Equal(t, "secret", req.Header.Get("X-Staging-Token"))
+	// Headers intentionally set on the request are kept
+	assert.Equal(t, []string{"evil.com"}, req.Header.Values("X-Forwarded-Host"))
+
+	req, _ = http.NewRequest("GET", "https://example.com", nil)
+	req.Header.Set("User-Agent", "Custom")
+	config.Apply(req)
+	assert.Equal(t, "Custom", req.Header.Get("User-Agent"))
+
+	// The default User-Agent set when building requests is replaced by the scan one
+	req, _ = http.NewRequest("GET", "https://example.com", nil)
+	req.Header.Set("User-Agent", GetUserAgent())
+	config.Apply(req)
+	assert.Equal(t, "Scanner/1.0", req.Header.Get("User-Agent"))
+
+	assert.True(t, ScanHeadersConfig{}.IsEmpty())
+	assert.False(t, config.IsEmpty())
+}
+
+func TestScanHeadersTransport(t *testing.T) {
+	received := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := CreateHttpClientWithOptions(&ClientOptions{
+		Headers: ScanHeadersConfig{
+			UserAgent:    "Scanner/1.0",
+			ExtraHeaders: map[string]string{"X-Staging-Token": "secret", "X-Test": "scan"},
+		},
+	})
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("X-Test", "audit")
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+
+	headers := <-received
+	assert.Equal(t, "Scanner/1.0", headers.Get("User-Agent"))
+	assert.Equal(t, "secret", headers.Get("X-Staging-Token"))
+	assert.Equal(t, "audit", headers.Get("X-Test"))
+	// The original request is not modified
+	assert.Empty(t, req.Header.Get("X-Staging-Token"))
+
+	// Clients of other scans do not send the headers
+	client = CreateHttpClient()
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	resp, err = client.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	headers = <-received
+	assert.Empty(t, headers.Get("X-Staging-Token"))
+	assert.NotEqual(t, "Scanner/1.0", headers.Get("User-Agent"))
+}
@@ -0,0 +1,322 @@
+package http_utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// loggedOutBodyReadLimit is the maximum number of response body bytes checked against the logged out body pattern
+	loggedOutBodyReadLimit = 64 * 1024
+	// defaultSessionRenewalInterval is the minimum time between session renewals, so responses wrongly matching the
+	// logged out signature do not trigger a renewal for every request
+	defaultSessionRenewalInterval = 30 * time.Second
+)
+
+// LoggedOutSignature describes the responses received once the session has expired. A response matches when its
+// status code is one of the provided ones, it redirects to a location matching the pattern or its body matches the
+// pattern.
+type LoggedOutSignature struct {
+	StatusCodes     []int
+	LocationPattern string
+	BodyPattern     string
+}
+
+// IsEmpty returns true when the signature cannot match any response
+func (s LoggedOutSignature) IsEmpty() bool {
+	return len(s.StatusCodes) == 0 && s.LocationPattern == "" && s.BodyPattern == ""
+}
+
+// SessionRenewFunc logs in again, returning the cookies of the new session
+type SessionRenewFunc func(ctx context.Context) ([]*http.Cookie, error)
+
+// SessionRenewalConfig configures how expired sessions are detected and renewed
+type SessionRenewalConfig struct {
+	Signature LoggedOutSignature
+	Renew     SessionRenewFunc
+	// MinInterval is the minimum time between renewals, defaults to 30 seconds
+	MinInterval time.Duration
+}
+
+// SessionManager detects the responses received after being logged out, renewing the session and keeping the cookies
+// of the current one to add them to the requests sent
+type SessionManager struct {
+	config          SessionRenewalConfig
+	locationPattern *regexp.Regexp
+	bodyPattern     *regexp.Regexp
+
+	mu          sync.RWMutex
+	renewMu     sync.Mutex
+	cookies     []*http.Cookie
+	generation  int
+	lastRenewal time.Time
+}
+
+// NewSessionManager validates the config, compiling the logged out signature patterns
+func NewSessionManager(config SessionRenewalConfig) (*SessionManager, error) {
+	if config.Renew == nil {
+		return nil, errors.New("a session renewal function is required")
+	}
+	if config.Signature.IsEmpty() {
+		return nil, errors.New("a logged out signature is required")
+	}
+	if config.MinInterval <= 0 {
+		config.MinInterval = defaultSessionRenewalInterval
+	}
+	manager := &SessionManager{config: config}
+	var err error
+	if config.Signature.LocationPattern != "" {
+		if manager.locationPattern, err = regexp.Compile(config.Signature.LocationPattern); err != nil {
+			return nil, err
+		}
+	}
+	if config.Signature.BodyPattern != "" {
+		if manager.bodyPattern, err = regexp.Compile(config.Signature.BodyPattern); err != nil {
+			return nil, err
+		}
+	}
+	return manager, nil
+}
+
+// Enabled returns true when sessions are being renewed
+func (m *SessionManager) Enabled() bool {
+	return m != nil
+}
+
+// Generation returns a number incremented each time the session is renewed
+func (m *SessionManager) Generation() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generation
+}
+
+// Cookies returns the cookies of the current session
+func (m *SessionManager) Cookies() []*http.Cookie {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cookies
+}
+
+// IsLoggedOut checks if the response matches the logged out signature. When the body needs to be checked, it is
+// restored so it can still be read by the caller.
+func (m *SessionManager) IsLoggedOut(response *http.Response) bool {
+	for _, code := range m.config.Signature.StatusCodes {
+		if response.StatusCode == code {
+			return true
+		}
+	}
+	if m.locationPattern != nil && response.StatusCode >= 300 && response.StatusCode < 400 {
+		if location := response.Header.Get("Location"); location != "" && m.locationPattern.MatchString(location) {
+			return true
+		}
+	}
+	if m.bodyPattern != nil && response.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(response.Body, loggedOutBodyReadLimit))
+		response.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), response.Body), response.Body}
+		if err == nil && m.bodyPattern.Match(body) {
+			return true
+		}
+	}
+	return false
+}
+
+// Renew logs in again unless the session has already been renewed since the provided generation, which happens when
+// concurrent requests detect the same expired session. It returns false when the session could not be renewed.
+func (m *SessionManager) Renew(ctx context.Context, generation int) bool {
+	m.renewMu.Lock()
+	defer m.renewMu.Unlock()
+
+	m.mu.RLock()
+	current := m.generation
+	lastRenewal := m.lastRenewal
+	m.mu.RUnlock()
+	if current != generation {
+		return true
+	}
+	if !lastRenewal.IsZero() && time.Since(lastRenewal) < m.config.MinInterval {
+		log.Debug().Time("last_renewal", lastRenewal).Msg("Session renewed recently, not renewing it again")
+		return false
+	}
+
+	log.Info().Int("generation", generation).Msg("Logged out response detected, renewing session")
+	cookies, err := m.config.Renew(ctx)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastRenewal = time.Now()
+	if err != nil {
+		log.Error().Err(err).Msg("Could not renew session")
+		return false
+	}
+	m.cookies = cookies
+	m.generation++
+	log.Info().Int("generation", m.generation).Int("cookies", len(cookies)).Msg("Session renewed")
+	return true
+}
+
+type preservedCookiesContextKey struct{}
+
+// PreserveRequestCookies marks the cookies an audit has set on purpose in the request, so they are not replaced by the
+// cookies of the current session. All the request cookies are preserved when no names are provided.
+func PreserveRequestCookies(req *http.Request, names ...string) *http.Request {
+	preserved := map[string]bool{}
+	if existing, ok := req.Context().Value(preservedCookiesContextKey{}).(map[string]bool); ok {
+		for name, value := range existing {
+			preserved[name] = value
+		}
+	}
+	if len(names) == 0 {
+		preserved[""] = true
+	}
+	for _, name := range names {
+		preserved[name] = true
+	}
+	return req.WithContext(context.WithValue(req.Context(), preservedCookiesContextKey{}, preserved))
+}
+
+// isPreservedCookie checks if the cookie has been marked as set on purpose by PreserveRequestCookies
+func isPreservedCookie(req *http.Request, name string) bool {
+	preserved, ok := req.Context().Value(preservedCookiesContextKey{}).(map[string]bool)
+	return ok && (preserved[""] || preserved[name])
+}
+
+// Apply sets the cookies of the current session valid for the request host, replacing the cookies with the same name,
+// such as the expired ones copied from the original request, unless they have been preserved with
+// PreserveRequestCookies. It returns true when the request cookies have been changed.
+func (m *SessionManager) Apply(req *http.Request) bool {
+	session := make(map[string]string)
+	var names []string
+	for _, cookie := range m.Cookies() {
+		if !cookieDomainMatches(cookie.Domain, req.URL.Hostname()) || isPreservedCookie(req, cookie.Name) {
+			continue
+		}
+		if _, seen := session[cookie.Name]; !seen {
+			names = append(names, cookie.Name)
+		}
+		session[cookie.Name] = cookie.Value
+	}
+	if len(session) == 0 {
+		return false
+	}
+
+	// The header is rewritten keeping the other pairs as they are, so payloads in other cookies are not sanitized
+	changed := false
+	found := make(map[string]bool)
+	var pairs []string
+	for _, header := range req.Header.Values("Cookie") {
+		for _, pair := range strings.Split(header, ";") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(pair, "=")
+			if sessionValue, ok := session[name]; ok {
+				changed = changed || value != sessionValue
+				found[name] = true
+				continue
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	for _, name := range names {
+		changed = changed || !found[name]
+		pairs = append(pairs, name+"="+session[name])
+	}
+	req.Header.Set("Cookie", strings.Join(pairs, "; "))
+	return changed
+}
+
+// cookieDomainMatches checks if a cookie set for the domain should be sent to the host. Cookies without domain are
+// never sent, as the host they belong to is unknown.
+func cookieDomainMatches(domain, host string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	host = strings.ToLower(host)
+	if domain == "" {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+type sessionRenewalRoundTripper struct {
+	next    http.RoundTripper
+	manager *SessionManager
+}
+
+func (t *sessionRenewalRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body is buffered so the request can be sent again after renewing the session
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The cookies of the current session replace the expired ones the request may have been copied with
+	generation := t.manager.Generation()
+	first := cloneSessionRequest(req, body)
+	t.manager.Apply(first)
+	response, err := t.next.RoundTrip(first)
+	if err != nil || !t.manager.IsLoggedOut(response) {
+		return response, err
+	}
+	if !t.manager.Renew(req.Context(), generation) {
+		return response, nil
+	}
+
+	log.Debug().Str("url", req.URL.String()).Msg("Retrying request with the renewed session")
+	retry := cloneSessionRequest(req, body)
+	t.manager.Apply(retry)
+	return t.retry(retry, response), nil
+}
+
+// retry sends the request again, returning the previous response when it cannot be sent, as the caller could not tell
+// a failed retry apart from a failed request
+func (t *sessionRenewalRoundTripper) retry(req *http.Request, previous *http.Response) *http.Response {
+	retried, err := t.next.RoundTrip(req)
+	if err != nil {
+		log.Warn().Err(err).Str("url", req.URL.String()).Msg("Could not retry request with the renewed session")
+		return previous
+	}
+	io.Copy(io.Discard, previous.Body)
+	previous.Body.Close()
+	return retried
+}
+
+// cloneSessionRequest clones the request, restoring its body
+func cloneSessionRequest(req *http.Request, body []byte) *http.Request {
+	clone := req.Clone(req.Context())
+	if body != nil {
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+		clone.ContentLength = int64(len(body))
+		clone.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return clone
+}
+
+// WrapSessionRenewalTransport returns a transport that renews the session using the provided session manager when a
+// logged out response is received, retrying the request with the new session
+func WrapSessionRenewalTransport(next http.RoundTripper, manager *SessionManager) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if !manager.Enabled() {
+		return next
+	}
+	return &sessionRenewalRoundTripper{next: next, manager: manager}
+}
@@ -0,0 +1,294 @@
+package http_utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSessionTestServer returns a server that only accepts requests with the session cookie set to the current session,
+// answering with 401 to API requests and redirecting to the login page otherwise
+func newSessionTestServer(session *atomic.Value) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != session.Load().(string) {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/login?next="+r.URL.Path, http.StatusFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("welcome " + r.URL.Path + " " + string(body)))
+	}))
+}
+
+func newTestSessionClient(t *testing.T, config SessionRenewalConfig) (*http.Client, *SessionManager) {
+	manager, err := NewSessionManager(config)
+	assert.Nil(t, err)
+	client := &http.Client{
+		Transport: WrapSessionRenewalTransport(http.DefaultTransport, manager),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return client, manager
+}
+
+func TestSessionRenewalOnUnauthorized(t *testing.T) {
+	var session atomic.Value
+	session.Store("active")
+	server := newSessionTestServer(&session)
+	defer server.Close()
+
+	var renewals int32
+	client, manager := newTestSessionClient(t, SessionRenewalConfig{
+		Signature: LoggedOutSignature{StatusCodes: []int{http.StatusUnauthorized}},
+		Renew: func(ctx context.Context) ([]*http.Cookie, error) {
+			atomic.AddInt32(&renewals, 1)
+			session.Store("renewed")
+			return []*http.Cookie{{Name: "session", Value: "renewed", Domain: "127.0.0.1"}}, nil
+		},
+	})
+
+	req, _ := http.NewRequest("POST", server.URL+"/api/items", strings.NewReader("name=test"))
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	// The body is sent again in the retried request
+	assert.Equal(t, "welcome /api/items name=test", string(body))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renewals))
+	assert.Equal(t, 1, manager.Generation())
+
+	// Later requests are sent with the renewed session without renewing it again
+	resp, err = client.Get(server.URL + "/api/other")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renewals))
+
+	// Cookies an audit has set on purpose are not replaced by the ones of the session
+	req, _ = http.NewRequest("GET", server.URL+"/api/other", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "injected"})
+	resp, err = client.Do(PreserveRequestCookies(req, "session"))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestSessionRenewalReplacesExpiredSessionCookie(t *testing.T) {
+	var session atomic.Value
+	session.Store("active")
+	var mu sync.Mutex
+	var received []string
+	sessionServer := newSessionTestServer(&session)
+	defer sessionServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("Cookie"))
+		mu.Unlock()
+		sessionServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	var renewals int32
+	client, _ := newTestSessionClient(t, SessionRenewalConfig{
+		Signature: LoggedOutSignature{StatusCodes: []int{http.StatusUnauthorized}},
+		Renew: func(ctx context.Context) ([]*http.Cookie, error) {
+			atomic.AddInt32(&renewals, 1)
+			session.Store("renewed")
+			return []*http.Cookie{{Name: "session", Value: "renewed", Domain: "127.0.0.1"}}, nil
+		},
+	})
+
+	// Scan requests are copied from the history item, carrying the session cookie it was captured with
+	newScanRequest := func() *http.Request {
+		req, _ := http.NewRequest("GET", server.URL+"/api/items", nil)
+		req.Header.Set("Cookie", "session=expired; theme=dark")
+		return req
+	}
+
+	resp, err := client.Do(newScanRequest())
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renewals))
+	assert.Equal(t, []string{"session=expired; theme=dark", "theme=dark; session=renewed"}, received)
+
+	// Once renewed, the session is applied before sending, so requests are sent only once
+	mu.Lock()
+	received = nil
+	mu.Unlock()
+	resp, err = client.Do(newScanRequest())
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"theme=dark; session=renewed"}, received)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renewals))
+}
+
+func TestSessionRenewalOnRedirectToLogin(t *testing.T) {
+	var session atomic.Value
+	session.Store("active")
+	server := newSessionTestServer(&session)
+	defer server.Close()
+
+	var renewals int32
+	client, _ := newTestSessionClient(t, SessionRenewalConfig{
+		Signature: LoggedOutSignature{LocationPattern: `/login`},
+		Renew: func(ctx context.Context) ([]*http.Cookie, error) {
+			atomic.AddInt32(&renewals, 1)
+			session.Store("renewed")
+			return []*http.Cookie{{Name: "session", Value: "renewed", Domain: "127.0.0.1"}}, nil
+		},
+	})
+
+	resp, err := client.Get(server.URL + "/dashboard")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "welcome /dashboard ", string(body))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renewals))
+}
+
+func TestSessionRenewalConcurrentRequestsRenewOnce(t *testing.T) {
+	var session atomic.Value
+	session.Store("active")
+	server := newSessionTestServer(&session)
+	defer server.Close()
+
+	var renewals int32
+	client, _ := newTestSessionClient(t, SessionRenewalConfig{
+		Signature: LoggedOutSignature{StatusCodes: []int{http.StatusUnauthorized}},
+		Renew: func(ctx context.Context) ([]*http.Cookie, error) {
+			atomic.AddInt32(&renewals, 1)
+			time.Sleep(50 * time.Millisecond)
+			session.Store("renewed")
+			return []*http.Cookie{{Name: "session", Value: "renewed", Domain: "127.0.0.1"}}, nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL + "/api/items")
+			assert.Nil(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renewals))
+}
+
+func TestSessionRenewalFailureKeepsResponse(t *testing.T) {
+	var session atomic.Value
+	session.Store("active")
+	server := newSessionTestServer(&session)
+	defer server.Close()
+
+	var renewals int32
+	client, manager := newTestSessionClient(t, SessionRenewalConfig{
+		Signature: LoggedOutSignature{StatusCodes: []int{http.StatusUnauthorized}},
+		Renew: func(ctx context.Context) ([]*http.Cookie, error) {
+			atomic.AddInt32(&renewals, 1)
+			return nil, errors.New("login form not found")
+		},
+	})
+
+	resp, err := client.Get(server.URL + "/api/items")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, 0, manager.Generation())
+
+	// Renewals are not attempted again until the minimum interval has passed
+	resp, err = client.Get(server.URL + "/api/items")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renewals))
+}
+
+func TestSessionManagerIsLoggedOutPreservesBody(t *testing.T) {
+	manager, err := NewSessionManager(SessionRenewalConfig{
+		Signature: LoggedOutSignature{BodyPattern: `(?i)please log in`},
+		Renew:     func(ctx context.Context) ([]*http.Cookie, error) { return nil, nil },
+	})
+	assert.Nil(t, err)
+
+	response := &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("<h1>Please log in</h1>"))}
+	assert.True(t, manager.IsLoggedOut(response))
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, "<h1>Please log in</h1>", string(body))
+
+	response = &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("<h1>Dashboard</h1>"))}
+	assert.False(t, manager.IsLoggedOut(response))
+	body, _ = io.ReadAll(response.Body)
+	assert.Equal(t, "<h1>Dashboard</h1>", string(body))
+}
+
+func TestSessionManagerApply(t *testing.T) {
+	manager := &SessionManager{cookies: []*http.Cookie{
+		{Name: "session", Value: "renewed", Domain: "example.com"},
+		{Name: "other", Value: "value", Domain: "other.com"},
+		{Name: "hostless", Value: "value"},
+	}}
+	req, _ := http.NewRequest("GET", "https://app.example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	assert.True(t, manager.Apply(req))
+	assert.Equal(t, "theme=dark; session=renewed", req.Header.Get("Cookie"))
+
+	// Cookies with the same name are replaced, keeping the other ones untouched
+	req, _ = http.NewRequest("GET", "https://app.example.com/", nil)
+	req.Header.Set("Cookie", "session=expired; q=<script>\"x\"</script>")
+	assert.True(t, manager.Apply(req))
+	assert.Equal(t, "q=<script>\"x\"</script>; session=renewed", req.Header.Get("Cookie"))
+	assert.False(t, manager.Apply(req))
+
+	// Preserved cookies are kept
+	req, _ = http.NewRequest("GET", "https://app.example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "injected"})
+	req = PreserveRequestCookies(req, "session")
+	assert.False(t, manager.Apply(req))
+	assert.Equal(t, "session=injected", req.Header.Get("Cookie"))
+
+	req, _ = http.NewRequest("GET", "https://app.example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "injected"})
+	req = PreserveRequestCookies(req)
+	assert.False(t, manager.Apply(req))
+	assert.Equal(t, "session=injected", req.Header.Get("Cookie"))
+}
+
+func TestCookieDomainMatches(t *testing.T) {
+	assert.True(t, cookieDomainMatches("example.com", "example.com"))
+	assert.True(t, cookieDomainMatches(".example.com", "app.example.com"))
+	assert.False(t, cookieDomainMatches("example.com", "badexample.com"))
+	assert.False(t, cookieDomainMatches("", "example.com"))
+}
+
+func TestNewSessionManagerValidation(t *testing.T) {
+	renew := func(ctx context.Context) ([]*http.Cookie, error) { return nil, nil }
+	_, err := NewSessionManager(SessionRenewalConfig{Renew: renew})
+	assert.NotNil(t, err)
+	_, err = NewSessionManager(SessionRenewalConfig{Signature: LoggedOutSignature{StatusCodes: []int{401}}})
+	assert.NotNil(t, err)
+	_, err = NewSessionManager(SessionRenewalConfig{Signature: LoggedOutSignature{BodyPattern: "("}, Renew: renew})
+	assert.NotNil(t, err)
+}
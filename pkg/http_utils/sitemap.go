@@ -0,0 +1,115 @@
+package http_utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxSitemapSize limits the size of the sitemaps read, as they can be up to 50MB uncompressed
+const maxSitemapSize = 50 * 1024 * 1024
+
+// Sitemap holds the URLs listed in a sitemap, or the nested sitemaps when it is a sitemap index
+type Sitemap struct {
+	URLs     []string
+	Sitemaps []string
+}
+
+type sitemapLocation struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+// ParseSitemap parses both sitemaps (urlset) and sitemap indexes (sitemapindex), gzip compressed or not
+func ParseSitemap(body []byte) (*Sitemap, error) {
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		body, err = io.ReadAll(io.LimitReader(reader, maxSitemapSize))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var document sitemapDocument
+	if err := xml.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	if document.XMLName.Local != "urlset" && document.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("unexpected sitemap root element %s", document.XMLName.Local)
+	}
+
+	sitemap := &Sitemap{}
+	for _, location := range document.URLs {
+		if loc := strings.TrimSpace(location.Loc); loc != "" {
+			sitemap.URLs = append(sitemap.URLs, loc)
+		}
+	}
+	for _, location := range document.Sitemaps {
+		if loc := strings.TrimSpace(location.Loc); loc != "" {
+			sitemap.Sitemaps = append(sitemap.Sitemaps, loc)
+		}
+	}
+	return sitemap, nil
+}
+
+// FetchSitemapURLs fetches the provided sitemaps, following the nested ones of sitemap indexes, and returns the URLs
+// they list. At most maxSitemaps documents are fetched, which protects against huge or circular sitemap indexes.
+func FetchSitemapURLs(client *http.Client, sitemapURLs []string, maxSitemaps int) []string {
+	var urls []string
+	queue := append([]string{}, sitemapURLs...)
+	fetched := make(map[string]bool)
+	seen := make(map[string]bool)
+	for len(queue) > 0 && len(fetched) < maxSitemaps {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if fetched[sitemapURL] {
+			continue
+		}
+		fetched[sitemapURL] = true
+
+		sitemap, err := fetchSitemap(client, sitemapURL)
+		if err != nil {
+			log.Debug().Err(err).Str("url", sitemapURL).Msg("Could not fetch sitemap")
+			continue
+		}
+		for _, u := range sitemap.URLs {
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+		queue = append(queue, sitemap.Sitemaps...)
+	}
+	return urls
+}
+
+func fetchSitemap(client *http.Client, sitemapURL string) (*Sitemap, error) {
+	response, err := client.Get(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxSitemapSize))
+	if err != nil {
+		return nil, err
+	}
+	return ParseSitemap(body)
+}
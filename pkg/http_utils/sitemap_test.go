@@ -0,0 +1,69 @@
+package http_utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSitemap(t *testing.T) {
+	sitemap, err := ParseSitemap([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/</loc><lastmod>2024-01-01</lastmod></url>
+	<url><loc>
+		https://example.com/about
+	</loc></url>
+	<url><loc></loc></url>
+</urlset>`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://example.com/", "https://example.com/about"}, sitemap.URLs)
+	assert.Empty(t, sitemap.Sitemaps)
+
+	index, err := ParseSitemap([]byte(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>https://example.com/sitemap-posts.xml</loc></sitemap>
+	<sitemap><loc>https://example.com/sitemap-pages.xml.gz</loc></sitemap>
+</sitemapindex>`))
+	assert.Nil(t, err)
+	assert.Empty(t, index.URLs)
+	assert.Equal(t, []string{"https://example.com/sitemap-posts.xml", "https://example.com/sitemap-pages.xml.gz"}, index.Sitemaps)
+
+	_, err = ParseSitemap([]byte(`<html><body>Not found</body></html>`))
+	assert.NotNil(t, err)
+	_, err = ParseSitemap([]byte(`not xml`))
+	assert.NotNil(t, err)
+}
+
+func TestFetchSitemapURLsNestedIndex(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(`<urlset><url><loc>https://example.com/pages/1</loc></url></urlset>`))
+	writer.Close()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%[1]s/sitemaps/index.xml</loc></sitemap><sitemap><loc>%[1]s/sitemap-pages.xml.gz</loc></sitemap></sitemapindex>`, server.URL)
+	})
+	mux.HandleFunc("/sitemaps/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		// Nested index, also pointing back to the root one
+		fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%[1]s/sitemap-posts.xml</loc></sitemap><sitemap><loc>%[1]s/sitemap.xml</loc></sitemap><sitemap><loc>%[1]s/missing.xml</loc></sitemap></sitemapindex>`, server.URL)
+	})
+	mux.HandleFunc("/sitemap-posts.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<urlset><url><loc>https://example.com/posts/1</loc></url><url><loc>https://example.com/pages/1</loc></url></urlset>`)
+	})
+	mux.HandleFunc("/sitemap-pages.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(compressed.Bytes())
+	})
+
+	urls := FetchSitemapURLs(server.Client(), []string{server.URL + "/sitemap.xml"}, 10)
+	assert.ElementsMatch(t, []string{"https://example.com/pages/1", "https://example.com/posts/1"}, urls)
+
+	urls = FetchSitemapURLs(server.Client(), []string{server.URL + "/sitemap.xml"}, 3)
+	assert.Equal(t, []string{"https://example.com/pages/1"}, urls)
+}
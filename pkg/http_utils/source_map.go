@@ -0,0 +1,142 @@
+package http_utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxSourceMapSize limits the size of the source maps read, as they embed the whole original sources
+const maxSourceMapSize = 20 * 1024 * 1024
+
+var sourceMappingURLRegex = regexp.MustCompile(`(?m)^[ \t]*//[#@][ \t]*sourceMappingURL=([^\s'"]+)[ \t]*$`)
+
+// SourceMap is a version 3 source map, only holding the fields needed to reconstruct the original sources
+type SourceMap struct {
+	Version        int       `json:"version"`
+	File           string    `json:"file"`
+	SourceRoot     string    `json:"sourceRoot"`
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+}
+
+// SourceMapSource is an original source file embedded in a source map
+type SourceMapSource struct {
+	Path    string
+	Content string
+}
+
+// ParseSourceMap parses a version 3 source map
+func ParseSourceMap(body []byte) (*SourceMap, error) {
+	// Source maps can be prefixed to prevent cross site script inclusion, which has to be ignored
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte(")]}")) {
+		if idx := bytes.IndexByte(body, '\n'); idx != -1 {
+			body = body[idx+1:]
+		} else {
+			body = body[3:]
+		}
+	}
+	var sourceMap SourceMap
+	if err := json.Unmarshal(body, &sourceMap); err != nil {
+		return nil, err
+	}
+	if sourceMap.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", sourceMap.Version)
+	}
+	return &sourceMap, nil
+}
+
+// OriginalSources returns the original sources whose content is embedded in the source map
+func (s *SourceMap) OriginalSources() []SourceMapSource {
+	var sources []SourceMapSource
+	for i, path := range s.Sources {
+		if i >= len(s.SourcesContent) || s.SourcesContent[i] == nil || *s.SourcesContent[i] == "" {
+			continue
+		}
+		if s.SourceRoot != "" {
+			path = strings.TrimSuffix(s.SourceRoot, "/") + "/" + strings.TrimPrefix(path, "/")
+		}
+		sources = append(sources, SourceMapSource{Path: path, Content: *s.SourcesContent[i]})
+	}
+	return sources
+}
+
+// GetSourceMappingURL returns the source map URL of a javascript response, taken from the SourceMap headers or the
+// last sourceMappingURL comment of the body, resolved against the javascript URL. Inline source maps are returned as
+// data URLs.
+func GetSourceMappingURL(jsURL string, body []byte, headers map[string][]string) (string, bool) {
+	var reference string
+	for name, values := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if (canonical == "Sourcemap" || canonical == "X-Sourcemap") && len(values) > 0 {
+			reference = strings.TrimSpace(values[0])
+		}
+	}
+	if reference == "" {
+		matches := sourceMappingURLRegex.FindAllSubmatch(body, -1)
+		if len(matches) == 0 {
+			return "", false
+		}
+		reference = string(matches[len(matches)-1][1])
+	}
+	if strings.HasPrefix(reference, "data:") {
+		return reference, true
+	}
+	base, err := url.Parse(jsURL)
+	if err != nil {
+		return "", false
+	}
+	resolved, err := base.Parse(reference)
+	if err != nil {
+		return "", false
+	}
+	return resolved.String(), true
+}
+
+// DecodeInlineSourceMap decodes the source map embedded in a data URL
+func DecodeInlineSourceMap(dataURL string) ([]byte, error) {
+	metadata, data, found := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !found {
+		return nil, errors.New("invalid data url")
+	}
+	if strings.HasSuffix(metadata, ";base64") {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(decoded), nil
+}
+
+// FetchSourceMap fetches and parses the source map at the provided URL, decoding it instead when it is inline
+func FetchSourceMap(client *http.Client, sourceMapURL string) (*SourceMap, error) {
+	if strings.HasPrefix(sourceMapURL, "data:") {
+		body, err := DecodeInlineSourceMap(sourceMapURL)
+		if err != nil {
+			return nil, err
+		}
+		return ParseSourceMap(body)
+	}
+	response, err := client.Get(sourceMapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxSourceMapSize))
+	if err != nil {
+		return nil, err
+	}
+	return ParseSourceMap(body)
+}
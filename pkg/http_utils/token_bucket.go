@@ -12,6 +12,8 @@ type TokenBucket struct {
 	lastUpdated time.Time
 	mu          sync.Mutex
 	minRate     float64
+	// maxRate caps the rate when greater than zero
+	maxRate float64
 	// initialRate  float64
 }
 
@@ -36,9 +38,43 @@ func (tb *TokenBucket) AdjustRate(newRate float64) {
 	if newRate < tb.minRate {
 		newRate = tb.minRate
 	}
+	if tb.maxRate > 0 && newRate > tb.maxRate {
+		newRate = tb.maxRate
+	}
 	tb.rate = newRate
 }
 
+// Rate returns the current rate of the bucket
+func (tb *TokenBucket) Rate() float64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.rate
+}
+
+// SetMaxRate caps the rate of the bucket, lowering the current and minimum rates if they exceed it
+func (tb *TokenBucket) SetMaxRate(maxRate float64) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.maxRate = maxRate
+	if maxRate <= 0 {
+		return
+	}
+	if tb.minRate > maxRate {
+		tb.minRate = maxRate
+	}
+	if tb.rate > maxRate {
+		tb.rate = maxRate
+	}
+}
+
+// Drain removes all the available tokens from the bucket
+func (tb *TokenBucket) Drain() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.tokens = 0
+	tb.lastUpdated = time.Now()
+}
+
 func (tb *TokenBucket) HasToken() bool {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
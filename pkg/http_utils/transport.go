@@ -2,6 +2,7 @@ package http_utils
 
 import (
 	"crypto/tls"
+	"github.com/pyneda/sukyan/pkg/scope"
 	"github.com/quic-go/quic-go/http3"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
@@ -72,21 +73,69 @@ func CreateHttp2Transport() *http2.Transport {
 	}
 }
 
-// CreateHttpClient creates a regular HTTP client.
+// ClientOptions holds the settings shared by the HTTP clients created for a scan, so that concurrent scans do not
+// interfere with each other. A nil value creates clients with the default settings.
+type ClientOptions struct {
+	// RateLimiter paces the requests sent to each domain by all the clients of the scan
+	RateLimiter *DomainRateLimiter
+	// Headers are the User-Agent and extra headers added to every request of the scan
+	Headers ScanHeadersConfig
+	// Protocol is the HTTP version used to send the requests of the scan
+	Protocol HTTPProtocol
+	// SessionManager renews the session of the scan when a logged out response is received
+	SessionManager *SessionManager
+	// Exclusions are the URLs the scan must never send requests to
+	Exclusions *scope.URLExclusions
+	// ScopeRules restrict the hosts, paths and methods of the requests sent by the scan
+	ScopeRules *scope.CompiledScopeRules
+}
+
+// HTTPProtocol returns the HTTP version used to send the requests, which is the default one for nil options
+func (o *ClientOptions) HTTPProtocol() HTTPProtocol {
+	if o == nil {
+		return HTTPProtocolDefault
+	}
+	return o.Protocol
+}
+
+// ScanHeaders returns the headers added to the requests of the scan, which are empty for nil options
+func (o *ClientOptions) ScanHeaders() ScanHeadersConfig {
+	if o == nil {
+		return ScanHeadersConfig{}
+	}
+	return o.Headers
+}
+
+// WrapTransport wraps the transport with the ones applying the client options. Requests out of the scan scope are
+// refused before reaching the others.
+func (o *ClientOptions) WrapTransport(transport http.RoundTripper) http.RoundTripper {
+	if o == nil {
+		return transport
+	}
+	transport = WrapScanHeadersTransport(WrapSessionRenewalTransport(WrapRateLimitedTransport(transport, o.RateLimiter), o.SessionManager), o.Headers)
+	return o.ScopeRules.WrapTransport(o.Exclusions.WrapTransport(transport))
+}
+
+// CreateHttpClient creates a regular HTTP client using the default HTTP version.
 func CreateHttpClient() *http.Client {
-	transport := CreateHttpTransport()
+	return CreateHttpClientWithOptions(nil)
+}
+
+// CreateHttpClientWithOptions is like CreateHttpClient, also applying the provided client options
+func CreateHttpClientWithOptions(options *ClientOptions) *http.Client {
+	transport := CreateProtocolTransport(options.HTTPProtocol())
 	client := &http.Client{
-		Transport: transport,
+		Transport: options.WrapTransport(transport),
 		// Timeout:   time.Duration(viper.GetInt("navigation.timeout")) * time.Second,
 	}
 	return client
 }
 
-// CreateHttp2Client creates an HTTP/2 client.
-func CreateHttp2Client() *http.Client {
+// CreateHttp2Client creates an HTTP/2 client applying the provided client options.
+func CreateHttp2Client(options *ClientOptions) *http.Client {
 	transport := CreateHttp2Transport()
 	client := &http.Client{
-		Transport: transport,
+		Transport: options.WrapTransport(transport),
 	}
 	return client
 }
@@ -102,10 +151,10 @@ func CreateHttp3Transport() *http3.RoundTripper {
 	}
 }
 
-// CreateHttp3Client creates an HTTP/3 client.
-func CreateHttp3Client() *http.Client {
+// CreateHttp3Client creates an HTTP/3 client applying the provided client options.
+func CreateHttp3Client(options *ClientOptions) *http.Client {
 	transport := CreateHttp3Transport()
 	return &http.Client{
-		Transport: transport,
+		Transport: options.WrapTransport(transport),
 	}
 }
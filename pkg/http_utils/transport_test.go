@@ -0,0 +1,37 @@
+package http_utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pyneda/sukyan/pkg/scope"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientOptionsEnforceScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	scopeRules, err := scope.ScopeRules{ExcludeMethods: []string{"DELETE"}, ExcludePaths: []string{`^/logout`}}.Compile()
+	assert.NoError(t, err)
+	exclusions, err := scope.NewURLExclusions([]string{"*/admin/*"})
+	assert.NoError(t, err)
+	client := CreateHttpClientWithOptions(&ClientOptions{ScopeRules: scopeRules, Exclusions: exclusions})
+
+	response, err := client.Get(server.URL + "/home")
+	assert.NoError(t, err)
+	response.Body.Close()
+
+	_, err = client.Get(server.URL + "/logout")
+	assert.ErrorIs(t, err, scope.ErrOutOfScope)
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/home", nil)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, scope.ErrOutOfScope)
+
+	_, err = client.Get(server.URL + "/admin/users")
+	assert.ErrorIs(t, err, scope.ErrURLExcluded)
+}
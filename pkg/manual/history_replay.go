@@ -0,0 +1,134 @@
+package manual
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+// HistoryReplayOptions holds the changes to apply to a history item request before sending it again. Empty fields keep
+// the values of the original request.
+type HistoryReplayOptions struct {
+	Method string `json:"method" validate:"omitempty"`
+	URL    string `json:"url" validate:"omitempty,url"`
+	// Headers replace all the original request headers when provided
+	Headers map[string][]string `json:"headers" validate:"omitempty"`
+	// Body replaces the original request body when provided, an empty string sends the request without body
+	Body            *string `json:"body" validate:"omitempty"`
+	FollowRedirects bool    `json:"follow_redirects"`
+}
+
+// HistoryReplayResult summarizes the response received when replaying a history item
+type HistoryReplayResult struct {
+	HistoryID           uint   `json:"history_id"`
+	OriginalHistoryID   uint   `json:"original_history_id"`
+	Method              string `json:"method"`
+	URL                 string `json:"url"`
+	StatusCode          int    `json:"status_code"`
+	ResponseContentType string `json:"response_content_type"`
+	ResponseBodySize    int    `json:"response_body_size"`
+	Proto               string `json:"proto"`
+	Duration            int64  `json:"duration_ms"`
+}
+
+// buildHistoryReplayRequest builds the request of the history item applying the provided changes
+func buildHistoryReplayRequest(history *db.History, options HistoryReplayOptions) (*http.Request, error) {
+	method := history.Method
+	if options.Method != "" {
+		method = options.Method
+	}
+	targetURL := history.URL
+	if options.URL != "" {
+		targetURL = options.URL
+	}
+	body := history.RequestBody
+	if options.Body != nil {
+		body = []byte(*options.Body)
+	}
+	headers := options.Headers
+	if headers == nil {
+		var err error
+		if headers, err = history.GetRequestHeadersAsMap(); err != nil {
+			return nil, err
+		}
+	}
+
+	var bodyReader io.Reader
+	if len(body) > 0 {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequest(strings.ToUpper(method), targetURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		if strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		// The Host header is ignored by the client unless it is set in the request itself
+		if strings.EqualFold(name, "Host") {
+			if len(values) > 0 && options.URL == "" {
+				request.Host = values[0]
+			}
+			continue
+		}
+		request.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	return request, nil
+}
+
+// ReplayHistory sends the request of the history item again, with the provided changes, using the scan HTTP client and
+// stores the result as a new history item
+func ReplayHistory(history *db.History, options HistoryReplayOptions) (HistoryReplayResult, error) {
+	request, err := buildHistoryReplayRequest(history, options)
+	if err != nil {
+		log.Error().Err(err).Uint("history", history.ID).Msg("Error building the request to replay")
+		return HistoryReplayResult{}, err
+	}
+
+	client := http_utils.CreateHttpClient()
+	if !options.FollowRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	start := time.Now()
+	response, err := http_utils.SendRequest(client, request)
+	if err != nil {
+		log.Error().Err(err).Uint("history", history.ID).Str("url", request.URL.String()).Msg("Error replaying history request")
+		return HistoryReplayResult{}, err
+	}
+	duration := time.Since(start)
+
+	var workspaceID uint
+	if history.WorkspaceID != nil {
+		workspaceID = *history.WorkspaceID
+	}
+	replayed, err := http_utils.ReadHttpResponseAndCreateHistory(response, http_utils.HistoryCreationOptions{
+		Source:      db.SourceRepeater,
+		WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("history", history.ID).Msg("Error creating the history item of the replayed request")
+		return HistoryReplayResult{}, err
+	}
+
+	return HistoryReplayResult{
+		HistoryID:           replayed.ID,
+		OriginalHistoryID:   history.ID,
+		Method:              replayed.Method,
+		URL:                 replayed.URL,
+		StatusCode:          replayed.StatusCode,
+		ResponseContentType: replayed.ResponseContentType,
+		ResponseBodySize:    replayed.ResponseBodySize,
+		Proto:               replayed.Proto,
+		Duration:            duration.Milliseconds(),
+	}, nil
+}
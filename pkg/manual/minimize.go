@@ -0,0 +1,346 @@
+package manual
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	requestComponentHeader = "header"
+	requestComponentQuery  = "query"
+	requestComponentBody   = "body"
+)
+
+// ReproducesFunc reports whether the provided request still triggers the finding indicator
+type ReproducesFunc func(request Request) (bool, error)
+
+// MinimizationResult contains the outcome of minimizing a request
+type MinimizationResult struct {
+	Original  Request  `json:"original"`
+	Minimized Request  `json:"minimized"`
+	Raw       string   `json:"raw"`
+	Removed   []string `json:"removed"`
+	Kept      []string `json:"kept"`
+	Requests  int      `json:"requests"`
+}
+
+type requestComponent struct {
+	Type  string
+	Name  string
+	Value string
+}
+
+func (c requestComponent) String() string {
+	return fmt.Sprintf("%s:%s", c.Type, c.Name)
+}
+
+type requestLayout struct {
+	base       Request
+	path       string
+	fragment   string
+	formBody   bool
+	components []requestComponent
+}
+
+// splitRequestComponents breaks a request into the headers, query parameters and form body
+// parameters that can be removed while minimizing. The Host header is never considered removable.
+func splitRequestComponents(request Request) requestLayout {
+	layout := requestLayout{base: request, path: request.URI}
+	if idx := strings.Index(layout.path, "#"); idx != -1 {
+		layout.fragment = layout.path[idx:]
+		layout.path = layout.path[:idx]
+	}
+	if idx := strings.Index(layout.path, "?"); idx != -1 {
+		query := layout.path[idx+1:]
+		layout.path = layout.path[:idx]
+		for _, pair := range strings.Split(query, "&") {
+			if pair == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(pair, "=")
+			layout.components = append(layout.components, requestComponent{Type: requestComponentQuery, Name: name, Value: value})
+		}
+	}
+
+	for name, values := range request.Headers {
+		if strings.EqualFold(name, "Host") || strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		for _, value := range values {
+			layout.components = append(layout.components, requestComponent{Type: requestComponentHeader, Name: name, Value: value})
+		}
+	}
+
+	contentType := ""
+	for name, values := range request.Headers {
+		if strings.EqualFold(name, "Content-Type") && len(values) > 0 {
+			contentType = values[0]
+		}
+	}
+	if request.Body != "" && strings.Contains(strings.ToLower(contentType), "application/x-www-form-urlencoded") {
+		layout.formBody = true
+		for _, pair := range strings.Split(request.Body, "&") {
+			if pair == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(pair, "=")
+			layout.components = append(layout.components, requestComponent{Type: requestComponentBody, Name: name, Value: value})
+		}
+	}
+
+	sortRequestComponents(layout.components)
+	return layout
+}
+
+// sortRequestComponents keeps the component order stable, as headers come from a map
+func sortRequestComponents(components []requestComponent) {
+	order := map[string]int{requestComponentQuery: 0, requestComponentHeader: 1, requestComponentBody: 2}
+	sort.SliceStable(components, func(i, j int) bool {
+		if order[components[i].Type] != order[components[j].Type] {
+			return order[components[i].Type] < order[components[j].Type]
+		}
+		if components[i].Type == requestComponentHeader {
+			return components[i].Name < components[j].Name
+		}
+		return false
+	})
+}
+
+// build creates a request that only includes the provided components
+func (l requestLayout) build(components []requestComponent) Request {
+	request := Request{
+		URL:         l.base.URL,
+		Method:      l.base.Method,
+		HTTPVersion: l.base.HTTPVersion,
+		Headers:     make(map[string][]string),
+	}
+	for name, values := range l.base.Headers {
+		if strings.EqualFold(name, "Host") {
+			request.Headers[name] = values
+		}
+	}
+	if !l.formBody {
+		request.Body = l.base.Body
+	}
+
+	var query, body []string
+	for _, component := range components {
+		switch component.Type {
+		case requestComponentHeader:
+			request.Headers[component.Name] = append(request.Headers[component.Name], component.Value)
+		case requestComponentQuery:
+			query = append(query, joinComponent(component))
+		case requestComponentBody:
+			body = append(body, joinComponent(component))
+		}
+	}
+
+	request.URI = l.path
+	if len(query) > 0 {
+		request.URI += "?" + strings.Join(query, "&")
+	}
+	request.URI += l.fragment
+	if l.formBody {
+		request.Body = strings.Join(body, "&")
+	}
+	return request
+}
+
+func joinComponent(component requestComponent) string {
+	if component.Value == "" {
+		return component.Name
+	}
+	return component.Name + "=" + component.Value
+}
+
+// ddmin implements the delta debugging minimization algorithm, returning a 1-minimal subset
+// of the provided components for which the test function still succeeds.
+func ddmin(components []requestComponent, test func([]requestComponent) (bool, error)) ([]requestComponent, error) {
+	current := components
+	granularity := 2
+	for len(current) >= 2 {
+		chunks := splitComponents(current, granularity)
+		reduced := false
+
+		for _, chunk := range chunks {
+			ok, err := test(chunk)
+			if err != nil {
+				return current, err
+			}
+			if ok {
+				current = chunk
+				granularity = 2
+				reduced = true
+				break
+			}
+		}
+
+		if !reduced && granularity > 2 {
+			for i := range chunks {
+				complement := make([]requestComponent, 0, len(current))
+				for j, chunk := range chunks {
+					if i != j {
+						complement = append(complement, chunk...)
+					}
+				}
+				ok, err := test(complement)
+				if err != nil {
+					return current, err
+				}
+				if ok {
+					current = complement
+					granularity = max(granularity-1, 2)
+					reduced = true
+					break
+				}
+			}
+		}
+
+		if !reduced {
+			if granularity >= len(current) {
+				break
+			}
+			granularity = min(granularity*2, len(current))
+		}
+	}
+
+	if len(current) == 1 {
+		ok, err := test([]requestComponent{})
+		if err != nil {
+			return current, err
+		}
+		if ok {
+			return []requestComponent{}, nil
+		}
+	}
+	return current, nil
+}
+
+func splitComponents(components []requestComponent, n int) [][]requestComponent {
+	chunks := make([][]requestComponent, 0, n)
+	start := 0
+	for i := 0; i < n; i++ {
+		end := start + (len(components)-start)/(n-i)
+		chunks = append(chunks, components[start:end])
+		start = end
+	}
+	return chunks
+}
+
+// MinimizeRequest removes all headers, query parameters and form body parameters which are not
+// required for the reproduces function to keep succeeding.
+func MinimizeRequest(request Request, reproduces ReproducesFunc) (*MinimizationResult, error) {
+	layout := splitRequestComponents(request)
+	attempts := 0
+	test := func(components []requestComponent) (bool, error) {
+		attempts++
+		return reproduces(layout.build(components))
+	}
+
+	ok, err := test(layout.components)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("the original request does not reproduce the issue")
+	}
+
+	kept, err := ddmin(layout.components, test)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MinimizationResult{
+		Original:  request,
+		Minimized: layout.build(kept),
+		Requests:  attempts,
+		Removed:   []string{},
+		Kept:      []string{},
+	}
+	keptSet := make(map[requestComponent]bool, len(kept))
+	for _, component := range kept {
+		keptSet[component] = true
+		result.Kept = append(result.Kept, component.String())
+	}
+	for _, component := range layout.components {
+		if !keptSet[component] {
+			result.Removed = append(result.Removed, component.String())
+		}
+	}
+
+	httpRequest, err := result.Minimized.toHTTPRequest()
+	if err == nil {
+		if raw, err := httputil.DumpRequest(httpRequest, true); err == nil {
+			result.Raw = string(raw)
+		}
+	}
+	return result, nil
+}
+
+// MinimizeIssue generates a minimal request that still reproduces the provided issue. When the
+// issue has a payload, the payload being present in the response is used as the indicator,
+// otherwise the status code and the response similarity are compared against the original.
+func MinimizeIssue(issue *db.Issue) (*MinimizationResult, error) {
+	if len(issue.Request) == 0 {
+		return nil, errors.New("the issue does not have a request to minimize")
+	}
+	baseURL, err := lib.GetBaseURL(issue.URL)
+	if err != nil {
+		return nil, err
+	}
+	raw := strings.ReplaceAll(string(issue.Request), "\r\n", "\n")
+	request, err := ParseRawRequest(raw, strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	client := http_utils.CreateHttpClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	originalBody := extractResponseBody(issue.Response)
+
+	reproduces := func(candidate Request) (bool, error) {
+		req, err := candidate.toHTTPRequest()
+		if err != nil {
+			return false, err
+		}
+		resp, err := http_utils.SendRequest(client, req)
+		if err != nil {
+			log.Warn().Err(err).Str("url", req.URL.String()).Msg("Error sending request while minimizing issue")
+			return false, nil
+		}
+		body, _, err := http_utils.ReadResponseBodyData(resp)
+		if err != nil {
+			return false, nil
+		}
+		if issue.Payload != "" {
+			headers := http_utils.HeadersToString(resp.Header)
+			return strings.Contains(string(body), issue.Payload) || strings.Contains(headers, issue.Payload), nil
+		}
+		if issue.StatusCode != 0 && resp.StatusCode != issue.StatusCode {
+			return false, nil
+		}
+		return lib.ComputeSimilarity(originalBody, body) >= 0.9, nil
+	}
+
+	log.Info().Uint("issue", issue.ID).Str("url", issue.URL).Msg("Minimizing issue request")
+	return MinimizeRequest(*request, reproduces)
+}
+
+func extractResponseBody(raw []byte) []byte {
+	normalized := strings.ReplaceAll(string(raw), "\r\n", "\n")
+	if _, body, found := strings.Cut(normalized, "\n\n"); found {
+		return []byte(body)
+	}
+	return raw
+}
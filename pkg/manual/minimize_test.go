@@ -0,0 +1,77 @@
+package manual
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinimizeRequest(t *testing.T) {
+	payload := "<script>alert(1)</script>"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Version") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("Results for " + r.URL.Query().Get("q")))
+	}))
+	defer ts.Close()
+
+	request := Request{
+		URL:    ts.URL,
+		URI:    "/search?q=" + payload + "&page=2&sort=asc",
+		Method: "GET",
+		Headers: map[string][]string{
+			"User-Agent":      {"Mozilla/5.0"},
+			"Accept-Language": {"en-US"},
+			"X-Api-Version":   {"2"},
+			"X-Request-Id":    {"12345"},
+		},
+		HTTPVersion: "HTTP/1.1",
+	}
+
+	reproduces := func(candidate Request) (bool, error) {
+		req, err := candidate.toHTTPRequest()
+		if err != nil {
+			return false, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(string(body), payload), nil
+	}
+
+	result, err := MinimizeRequest(request, reproduces)
+	assert.Nil(t, err)
+	assert.Equal(t, "/search?q="+payload, result.Minimized.URI)
+	assert.Equal(t, []string{"2"}, result.Minimized.Headers["X-Api-Version"])
+	assert.NotContains(t, result.Minimized.Headers, "User-Agent")
+	assert.NotContains(t, result.Minimized.Headers, "X-Request-Id")
+	assert.ElementsMatch(t, []string{"query:q", "header:X-Api-Version"}, result.Kept)
+	assert.Contains(t, result.Removed, "header:User-Agent")
+	assert.Contains(t, result.Removed, "query:page")
+}
+
+func TestMinimizeRequestNotReproducible(t *testing.T) {
+	request := Request{
+		URL:     "http://localhost",
+		URI:     "/?a=1",
+		Method:  "GET",
+		Headers: map[string][]string{},
+	}
+	result, err := MinimizeRequest(request, func(candidate Request) (bool, error) {
+		return false, nil
+	})
+	assert.Nil(t, result)
+	assert.NotNil(t, err)
+}
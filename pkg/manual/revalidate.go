@@ -0,0 +1,157 @@
+package manual
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/passive"
+	"github.com/rs/zerolog/log"
+)
+
+// IssueRevalidator checks if the response to the request that detected the issue, sent again, still indicates it. It
+// returns whether the issue has been reproduced, its recomputed confidence and the details of the check.
+type IssueRevalidator func(issue *db.Issue, history *db.History) (bool, int, string)
+
+// issueRevalidators holds the checks used to revalidate issues by their code, issues with other codes cannot be
+// revalidated
+var issueRevalidators = map[db.IssueCode]IssueRevalidator{
+	db.XssReflectedCode:             revalidatePayloadReflection,
+	db.XssReflectedJsonResponseCode: revalidatePayloadReflection,
+	db.ReflectedInputCode:           revalidatePayloadReflection,
+	db.CrlfInjectionCode:            revalidatePayloadReflection,
+	db.SqlInjectionCode:             revalidateDatabaseError,
+	db.DatabaseErrorsCode:           revalidateDatabaseError,
+}
+
+// ErrRevalidationNotSupported is returned when revalidating issues which have no revalidator for their code
+var ErrRevalidationNotSupported = errors.New("revalidation is not supported for the issue")
+
+// IssueRevalidationResult is the outcome of revalidating an issue
+type IssueRevalidationResult struct {
+	IssueID            uint   `json:"issue_id"`
+	HistoryID          uint   `json:"history_id"`
+	Reproduced         bool   `json:"reproduced"`
+	Resolved           bool   `json:"resolved"`
+	PreviousConfidence int    `json:"previous_confidence"`
+	Confidence         int    `json:"confidence"`
+	Details            string `json:"details"`
+}
+
+// revalidatePayloadReflection checks if the issue payload is still reflected in the response
+func revalidatePayloadReflection(issue *db.Issue, history *db.History) (bool, int, string) {
+	if issue.Payload == "" {
+		return revalidateResponseSimilarity(issue, history)
+	}
+	if strings.Contains(string(history.ResponseBody), issue.Payload) {
+		return true, 90, fmt.Sprintf("The payload %s is reflected in the response body", issue.Payload)
+	}
+	headers, err := history.GetResponseHeadersAsMap()
+	if err == nil && strings.Contains(http_utils.HeadersToString(headers), issue.Payload) {
+		return true, 70, fmt.Sprintf("The payload %s is reflected in the response headers", issue.Payload)
+	}
+	return false, 0, fmt.Sprintf("The payload %s is no longer reflected in the response", issue.Payload)
+}
+
+// revalidateDatabaseError checks if the response still contains a database error
+func revalidateDatabaseError(issue *db.Issue, history *db.History) (bool, int, string) {
+	match := passive.SearchDatabaseErrors(string(history.RawResponse))
+	if match == nil {
+		return false, 0, "The response no longer contains a database error"
+	}
+	return true, 90, fmt.Sprintf("Database error was returned in response:\n - Database: %s\n - Error: %s", match.DatabaseName, match.MatchStr)
+}
+
+// revalidateResponseSimilarity checks if the response keeps the status code and is similar to the one stored in the
+// issue. The confidence is the similarity on the scale used by the other revalidators, so it does not depend on the
+// current confidence of the issue, which is updated on every revalidation.
+func revalidateResponseSimilarity(issue *db.Issue, history *db.History) (bool, int, string) {
+	if issue.StatusCode != 0 && history.StatusCode != issue.StatusCode {
+		return false, 0, fmt.Sprintf("The response status code changed from %d to %d", issue.StatusCode, history.StatusCode)
+	}
+	similarity := lib.ComputeSimilarity(extractResponseBody(issue.Response), history.ResponseBody)
+	if similarity < 0.9 {
+		return false, 0, fmt.Sprintf("The response is %.0f%% similar to the original one", similarity*100)
+	}
+	return true, int(similarity * 90), fmt.Sprintf("The response is %.0f%% similar to the original one", similarity*100)
+}
+
+// buildIssueRequest reconstructs the request that detected the issue, from its first linked history item or, when not
+// available, from the raw request stored in the issue
+func buildIssueRequest(issue *db.Issue) (*http.Request, error) {
+	if len(issue.Requests) > 0 {
+		return buildHistoryReplayRequest(&issue.Requests[0], HistoryReplayOptions{})
+	}
+	if len(issue.Request) == 0 {
+		return nil, errors.New("the issue does not have a request to revalidate")
+	}
+	baseURL, err := lib.GetBaseURL(issue.URL)
+	if err != nil {
+		return nil, err
+	}
+	raw := strings.ReplaceAll(string(issue.Request), "\r\n", "\n")
+	request, err := ParseRawRequest(raw, strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return request.toHTTPRequest()
+}
+
+// RevalidateIssue sends the request that detected the issue again and checks if the response still indicates it,
+// using the revalidator of the issue code, returning ErrRevalidationNotSupported when there is none. Reproduced issues get their confidence recomputed, the rest are resolved
+// unless they have been closed with another status.
+func RevalidateIssue(issueID uint) (*IssueRevalidationResult, error) {
+	issue, err := db.Connection.GetIssue(int(issueID), true)
+	if err != nil {
+		return nil, err
+	}
+	revalidator, ok := issueRevalidators[db.IssueCode(issue.Code)]
+	if !ok {
+		return nil, fmt.Errorf("%w: there is no revalidation check for %s issues", ErrRevalidationNotSupported, issue.Code)
+	}
+	request, err := buildIssueRequest(&issue)
+	if err != nil {
+		return nil, err
+	}
+
+	client := http_utils.CreateHttpClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	response, err := http_utils.SendRequest(client, request)
+	if err != nil {
+		log.Error().Err(err).Uint("issue", issue.ID).Str("url", request.URL.String()).Msg("Error sending request to revalidate issue")
+		return nil, err
+	}
+	options := http_utils.HistoryCreationOptions{Source: db.SourceScanner}
+	if issue.WorkspaceID != nil {
+		options.WorkspaceID = *issue.WorkspaceID
+	}
+	history, err := http_utils.ReadHttpResponseAndCreateHistory(response, options)
+	if err != nil {
+		return nil, err
+	}
+
+	reproduced, confidence, details := revalidator(&issue, history)
+	result := &IssueRevalidationResult{
+		IssueID:            issue.ID,
+		HistoryID:          history.ID,
+		Reproduced:         reproduced,
+		PreviousConfidence: issue.Confidence,
+		Confidence:         issue.Confidence,
+		Details:            details,
+	}
+	if reproduced {
+		result.Confidence = confidence
+	}
+	if _, err := db.Connection.UpdateIssueRevalidation(&issue, result.Confidence, reproduced); err != nil {
+		return nil, err
+	}
+	result.Resolved = issue.Status == db.IssueStatusResolved
+	log.Info().Uint("issue", issue.ID).Str("code", issue.Code).Bool("reproduced", reproduced).Int("confidence", result.Confidence).Msg("Revalidated issue")
+	return result, nil
+}
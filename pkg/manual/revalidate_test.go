@@ -0,0 +1,85 @@
+package manual
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevalidatePayloadReflection(t *testing.T) {
+	issue := &db.Issue{Code: string(db.XssReflectedCode), Payload: "<script>alert(1)</script>", Confidence: 80}
+
+	reproduced, confidence, _ := revalidatePayloadReflection(issue, &db.History{
+		ResponseHeaders: []byte(`{"Content-Type":["text/html"]}`),
+		ResponseBody:    []byte("<p>Results for <script>alert(1)</script></p>"),
+	})
+	assert.True(t, reproduced)
+	assert.Equal(t, 90, confidence)
+
+	reproduced, confidence, _ = revalidatePayloadReflection(issue, &db.History{
+		ResponseHeaders: []byte(`{"X-Search":["<script>alert(1)</script>"]}`),
+		ResponseBody:    []byte("<p>No results</p>"),
+	})
+	assert.True(t, reproduced)
+	assert.Equal(t, 70, confidence)
+
+	reproduced, _, details := revalidatePayloadReflection(issue, &db.History{
+		ResponseHeaders: []byte(`{"Content-Type":["text/html"]}`),
+		ResponseBody:    []byte("<p>Results for &lt;script&gt;alert(1)&lt;/script&gt;</p>"),
+	})
+	assert.False(t, reproduced)
+	assert.Contains(t, details, "no longer reflected")
+}
+
+func TestRevalidateResponseSimilarity(t *testing.T) {
+	issue := &db.Issue{
+		Code:       string(db.XssReflectedCode),
+		StatusCode: 200,
+		Confidence: 80,
+		Response:   []byte("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<html><body>Welcome back, admin</body></html>"),
+	}
+
+	reproduced, confidence, _ := revalidatePayloadReflection(issue, &db.History{
+		StatusCode:   200,
+		ResponseBody: []byte("<html><body>Welcome back, admin</body></html>"),
+	})
+	assert.True(t, reproduced)
+	assert.Equal(t, 90, confidence)
+
+	// Revalidating again with the updated confidence does not change it
+	issue.Confidence = confidence
+	_, confidence, _ = revalidateResponseSimilarity(issue, &db.History{
+		StatusCode:   200,
+		ResponseBody: []byte("<html><body>Welcome back, admin</body></html>"),
+	})
+	assert.Equal(t, 90, confidence)
+
+	reproduced, _, _ = revalidateResponseSimilarity(issue, &db.History{
+		StatusCode:   302,
+		ResponseBody: []byte("<html><body>Welcome back, admin</body></html>"),
+	})
+	assert.False(t, reproduced)
+
+	reproduced, _, _ = revalidateResponseSimilarity(issue, &db.History{
+		StatusCode:   200,
+		ResponseBody: []byte(`{"error":"not found"}`),
+	})
+	assert.False(t, reproduced)
+}
+
+func TestRevalidateDatabaseError(t *testing.T) {
+	issue := &db.Issue{Code: string(db.SqlInjectionCode)}
+
+	reproduced, confidence, details := revalidateDatabaseError(issue, &db.History{
+		RawResponse: []byte("HTTP/1.1 500 Internal Server Error\r\n\r\nYou have an error in your SQL syntax; check the manual that corresponds to your MySQL server version"),
+	})
+	assert.True(t, reproduced)
+	assert.Equal(t, 90, confidence)
+	assert.Contains(t, details, "MySQL")
+
+	reproduced, _, _ = revalidateDatabaseError(issue, &db.History{
+		RawResponse: []byte("HTTP/1.1 200 OK\r\n\r\nNo results"),
+	})
+	assert.False(t, reproduced)
+}
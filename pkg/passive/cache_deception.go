@@ -0,0 +1,115 @@
+package passive
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+)
+
+// minReflectedHeaderValueLength avoids matching short header values which are likely to be found in any response
+const minReflectedHeaderValueLength = 6
+
+// ignoredUnkeyedHeaders are request headers whose reflection does not lead to cache poisoning, either because caches
+// usually key on them, bypass the cache when they are sent, or because their values naturally appear in responses
+var ignoredUnkeyedHeaders = map[string]bool{
+	"Host":                      true,
+	"Cookie":                    true,
+	"Authorization":             true,
+	"Accept":                    true,
+	"Accept-Encoding":           true,
+	"Accept-Language":           true,
+	"Connection":                true,
+	"Content-Length":            true,
+	"Content-Type":              true,
+	"Cache-Control":             true,
+	"Pragma":                    true,
+	"If-None-Match":             true,
+	"If-Modified-Since":         true,
+	"Upgrade-Insecure-Requests": true,
+}
+
+// ReflectedUnkeyedHeader is a request header which the cache is not keyed on, reflected in the response body
+type ReflectedUnkeyedHeader struct {
+	Name  string
+	Value string
+}
+
+// FindReflectedUnkeyedHeaders returns the request headers not included in the cache key whose values are reflected in
+// the response body. Values also present in the request URL are skipped, as they could be reflected from it.
+func FindReflectedUnkeyedHeaders(requestURL string, requestHeaders map[string][]string, body []byte, cache *http_utils.CacheInfo) []ReflectedUnkeyedHeader {
+	var reflected []ReflectedUnkeyedHeader
+	for name, values := range requestHeaders {
+		canonical := http.CanonicalHeaderKey(name)
+		if ignoredUnkeyedHeaders[canonical] || strings.HasPrefix(canonical, "Sec-") || cache.VariesOn(canonical) {
+			continue
+		}
+		for _, value := range values {
+			value = strings.TrimSpace(value)
+			if len(value) < minReflectedHeaderValueLength || strings.Contains(requestURL, value) {
+				continue
+			}
+			if bytes.Contains(body, []byte(value)) {
+				reflected = append(reflected, ReflectedUnkeyedHeader{Name: canonical, Value: value})
+				break
+			}
+		}
+	}
+	sort.Slice(reflected, func(i, j int) bool {
+		return reflected[i].Name < reflected[j].Name
+	})
+	return reflected
+}
+
+// CacheDeceptionRiskScan reports cacheable responses reflecting request headers which are not part of the cache key,
+// as these could be used to poison the cache with attacker controlled content served to other users
+func CacheDeceptionRiskScan(item *db.History) {
+	responseHeaders, err := item.GetResponseHeadersAsMap()
+	if err != nil {
+		return
+	}
+	cache := http_utils.ParseCacheHeaders(responseHeaders)
+	if cache == nil || !cache.Cacheable {
+		return
+	}
+	requestHeaders, err := item.GetRequestHeadersAsMap()
+	if err != nil {
+		return
+	}
+	reflected := FindReflectedUnkeyedHeaders(item.URL, requestHeaders, item.ResponseBody, cache)
+	if len(reflected) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The response can be stored by a shared cache and reflects the following request headers, which are not part of the cache key:\n")
+	for _, header := range reflected {
+		sb.WriteString(fmt.Sprintf("\n - %s: %s", header.Name, header.Value))
+	}
+	if len(cache.Headers) > 0 {
+		names := make([]string, 0, len(cache.Headers))
+		for name := range cache.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sb.WriteString("\n\nThe following cache headers have been observed:\n")
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("\n - %s: %s", name, cache.Headers[name]))
+		}
+	}
+	if cache.Key != "" {
+		sb.WriteString(fmt.Sprintf("\n\nThe X-Cache-Key header discloses the cache key used by the backend: %s", cache.Key))
+	}
+	if len(cache.Vary) > 0 {
+		sb.WriteString(fmt.Sprintf("\n\nThe cache is also keyed on the following request headers: %s", strings.Join(cache.Vary, ", ")))
+	}
+	confidence := 50
+	if cache.Hit {
+		confidence = 70
+	}
+	db.CreateIssueFromHistoryAndTemplate(item, db.CacheDeceptionRiskCode, sb.String(), confidence, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
@@ -0,0 +1,47 @@
+package passive
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindReflectedUnkeyedHeaders(t *testing.T) {
+	body := []byte(`<html><head>
+<link rel="canonical" href="https://evil-sukyan.example/en/index.html">
+<script src="https://evil-sukyan.example/static/app.js"></script>
+</head><body lang="en-US">Welcome, Mozilla/5.0 (X11; Linux x86_64) Sukyan
+<a href="/en/index.html?ref=newsletter">Home</a>
+<p>Origin: https://partner.example.com</p></body></html>`)
+	requestHeaders := map[string][]string{
+		"Host":             {"example.com"},
+		"X-Forwarded-Host": {"evil-sukyan.example"},
+		"User-Agent":       {"Mozilla/5.0 (X11; Linux x86_64) Sukyan"},
+		"Accept-Language":  {"en-US"},
+		"Origin":           {"https://partner.example.com"},
+		"Referer":          {"/en/index.html?ref=newsletter"},
+		"X-Request-Id":     {"4b0a1f3c-92de"},
+		"X-Short":          {"en"},
+	}
+	cache := http_utils.ParseCacheHeaders(map[string][]string{
+		"Cache-Control": {"public, max-age=300"},
+		"X-Cache":       {"MISS"},
+		"Vary":          {"Origin"},
+	})
+	assert.True(t, cache.Cacheable)
+
+	reflected := FindReflectedUnkeyedHeaders("https://example.com/en/index.html?ref=newsletter", requestHeaders, body, cache)
+	assert.Equal(t, []ReflectedUnkeyedHeader{
+		{Name: "User-Agent", Value: "Mozilla/5.0 (X11; Linux x86_64) Sukyan"},
+		{Name: "X-Forwarded-Host", Value: "evil-sukyan.example"},
+	}, reflected)
+}
+
+func TestFindReflectedUnkeyedHeadersNotReflected(t *testing.T) {
+	cache := http_utils.ParseCacheHeaders(map[string][]string{"Cache-Control": {"public, max-age=300"}})
+	reflected := FindReflectedUnkeyedHeaders("https://example.com/", map[string][]string{
+		"X-Forwarded-Host": {"evil-sukyan.example"},
+	}, []byte(`<html><body>Hello</body></html>`), cache)
+	assert.Empty(t, reflected)
+}
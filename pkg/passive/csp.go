@@ -0,0 +1,116 @@
+package passive
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+)
+
+// maxTrackedCSPNonces caps the nonces remembered to detect their reuse across responses
+const maxTrackedCSPNonces = 10000
+
+// CSPWeakness is a weakness found in a Content-Security-Policy
+type CSPWeakness struct {
+	Directive   string
+	Description string
+	// ScriptExecution is true when the weakness allows executing injected scripts
+	ScriptExecution bool
+}
+
+// EvaluateCSP returns the common weaknesses of the policy
+func EvaluateCSP(policy *http_utils.CSPPolicy) []CSPWeakness {
+	var weaknesses []CSPWeakness
+	if policy == nil {
+		return weaknesses
+	}
+	_, scriptRestricted := policy.EffectiveSources("script-src")
+	if !scriptRestricted {
+		weaknesses = append(weaknesses, CSPWeakness{"script-src", "Neither script-src nor default-src are set, so scripts can be loaded from any origin and inline scripts are allowed", true})
+	}
+	if !policy.HasDirective("default-src") {
+		weaknesses = append(weaknesses, CSPWeakness{"default-src", "default-src is not set, so the resource types without a directive are not restricted", false})
+	}
+	if policy.AllowsUnsafeInline("script-src") {
+		weaknesses = append(weaknesses, CSPWeakness{"script-src", "'unsafe-inline' allows executing inline scripts and event handlers, which defeats the protection against XSS", true})
+	}
+	if policy.AllowsUnsafeEval("script-src") {
+		weaknesses = append(weaknesses, CSPWeakness{"script-src", "'unsafe-eval' allows evaluating strings as code with functions such as eval", true})
+	}
+	for _, directive := range []string{"script-src", "object-src", "default-src"} {
+		// Only explicitly set directives are reported, as the ones falling back to default-src share its weaknesses
+		if !policy.HasDirective(directive) {
+			continue
+		}
+		if wildcards := policy.WildcardSources(directive); len(wildcards) > 0 {
+			scriptExecution := directive == "script-src" || (directive == "default-src" && !policy.HasDirective("script-src"))
+			weaknesses = append(weaknesses, CSPWeakness{directive, fmt.Sprintf("%s allows loading content from any host with the %s sources", directive, strings.Join(wildcards, ", ")), scriptExecution})
+		}
+	}
+	if !policy.HasDirective("object-src") && !policy.AllowsSource("object-src", "'none'") {
+		weaknesses = append(weaknesses, CSPWeakness{"object-src", "object-src is not set to 'none', so plugins such as Flash or Java applets can be embedded", false})
+	}
+	return weaknesses
+}
+
+// cspNonceTracker remembers the CSP nonces seen in the responses of each workspace
+type cspNonceTracker struct {
+	mu     sync.Mutex
+	nonces map[string]uint
+}
+
+var cspNonces = &cspNonceTracker{nonces: make(map[string]uint)}
+
+// seen records the nonce as used by the history item, returning the id of a different history item which already used it
+func (t *cspNonceTracker) seen(workspaceID uint, nonce string, historyID uint) (uint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := fmt.Sprintf("%d:%s", workspaceID, nonce)
+	if previous, ok := t.nonces[key]; ok {
+		return previous, previous != historyID
+	}
+	if len(t.nonces) >= maxTrackedCSPNonces {
+		t.nonces = make(map[string]uint)
+	}
+	t.nonces[key] = historyID
+	return 0, false
+}
+
+// CSPScan reports the weaknesses of the Content-Security-Policy enforced by HTML responses, including the nonces which
+// are reused across responses and therefore can be predicted by attackers
+func CSPScan(item *db.History) {
+	headers, err := item.GetResponseHeadersAsMap()
+	if err != nil {
+		return
+	}
+	policy := http_utils.ParseCSPFromHeaders(headers)
+	if policy == nil {
+		return
+	}
+	weaknesses := EvaluateCSP(policy)
+	var workspaceID uint
+	if item.WorkspaceID != nil {
+		workspaceID = *item.WorkspaceID
+	}
+	for _, nonce := range policy.Nonces() {
+		if previous, reused := cspNonces.seen(workspaceID, nonce, item.ID); reused {
+			weaknesses = append(weaknesses, CSPWeakness{"script-src", fmt.Sprintf("The nonce %s has already been used in another response (history item %d), so it can be predicted and used by injected scripts", nonce, previous), true})
+		}
+	}
+	if len(weaknesses) == 0 {
+		return
+	}
+
+	severity := "Low"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The response enforces the following Content-Security-Policy:\n\n%s\n\nThe following weaknesses have been found:\n", policy.Raw))
+	for _, weakness := range weaknesses {
+		sb.WriteString(fmt.Sprintf("\n - %s: %s", weakness.Directive, weakness.Description))
+		if weakness.ScriptExecution {
+			severity = "Medium"
+		}
+	}
+	db.CreateIssueFromHistoryAndTemplate(item, db.CspWeaknessCode, sb.String(), 90, severity, item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
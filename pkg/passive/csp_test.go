@@ -0,0 +1,70 @@
+package passive
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateCSP(t *testing.T) {
+	tests := []struct {
+		policy     string
+		directives []string
+		script     bool
+	}{
+		{"default-src 'none'; script-src 'self' 'nonce-r4nd0m'; style-src 'self'", nil, false},
+		{"default-src 'self'; object-src 'none'", nil, false},
+		// Without object-src, plugins fall back to default-src 'self'
+		{"default-src 'self'", []string{"object-src"}, false},
+		{"default-src 'self'; script-src 'self' 'unsafe-inline'; object-src 'none'", []string{"script-src"}, true},
+		{"default-src 'self'; script-src 'self' 'unsafe-eval'; object-src 'none'", []string{"script-src"}, true},
+		{"default-src 'self'; script-src 'self' 'unsafe-inline' 'nonce-r4nd0m'; object-src 'none'", nil, false},
+		{"default-src *; object-src 'none'", []string{"default-src"}, true},
+		{"default-src 'self'; script-src https: data:; object-src 'none'", []string{"script-src"}, true},
+		{"script-src 'self'; object-src 'none'", []string{"default-src"}, false},
+		{"img-src 'self'", []string{"script-src", "default-src", "object-src"}, true},
+		{"frame-ancestors 'none'; default-src 'self'; object-src *", []string{"object-src"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			weaknesses := EvaluateCSP(http_utils.ParseCSP(tt.policy))
+			var directives []string
+			script := false
+			for _, weakness := range weaknesses {
+				directives = append(directives, weakness.Directive)
+				script = script || weakness.ScriptExecution
+			}
+			assert.ElementsMatch(t, tt.directives, uniqueDirectives(directives))
+			assert.Equal(t, tt.script, script)
+		})
+	}
+	assert.Empty(t, EvaluateCSP(nil))
+}
+
+func uniqueDirectives(directives []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, directive := range directives {
+		if !seen[directive] {
+			seen[directive] = true
+			unique = append(unique, directive)
+		}
+	}
+	return unique
+}
+
+func TestCSPNonceTracker(t *testing.T) {
+	tracker := &cspNonceTracker{nonces: make(map[string]uint)}
+	_, reused := tracker.seen(1, "r4nd0m", 10)
+	assert.False(t, reused)
+	// The same response analyzed again is not a reuse
+	_, reused = tracker.seen(1, "r4nd0m", 10)
+	assert.False(t, reused)
+	previous, reused := tracker.seen(1, "r4nd0m", 11)
+	assert.True(t, reused)
+	assert.Equal(t, uint(10), previous)
+	// Nonces are tracked per workspace
+	_, reused = tracker.seen(2, "r4nd0m", 12)
+	assert.False(t, reused)
+}
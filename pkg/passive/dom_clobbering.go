@@ -0,0 +1,171 @@
+package passive
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pyneda/sukyan/db"
+	"github.com/rs/zerolog/log"
+)
+
+// DOMClobberingCandidate is an element whose id or name attribute clobbers a global read by the page before reaching a sink
+type DOMClobberingCandidate struct {
+	Identifier string
+	Element    string
+	Global     string
+	Sink       string
+	Statement  string
+}
+
+var (
+	// Elements whose name attribute is exposed through document (and window) named property access
+	domClobberingNamedElements = map[string]bool{"form": true, "img": true, "embed": true, "object": true, "iframe": true}
+	domClobberingSinkRegex     = regexp.MustCompile(`\.(innerHTML|outerHTML|src|href|action|formAction|srcdoc)\s*=[^=]|\.insertAdjacentHTML\s*\(|document\.write(ln)?\s*\(|\beval\s*\(|\bsetTimeout\s*\(|\bsetInterval\s*\(|\bnew\s+Function\s*\(|\blocation(\.href)?\s*=[^=]|\blocation\.(assign|replace)\s*\(|\.setAttribute\s*\(\s*["'](src|href|action|srcdoc)["']|\bimport\s*\(`)
+	domClobberingAliasRegex    = regexp.MustCompile(`^\s*(?:var|let|const)?\s*([A-Za-z_$][A-Za-z0-9_$]*)\s*=[^=]`)
+	jsIdentifierRegex          = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	jsStatementSeparatorRegex  = regexp.MustCompile(`[;\n]`)
+)
+
+// FindDOMClobberingCandidates looks for elements with id or name attributes clobbering globals which the inline scripts
+// of the page read and pass, directly or through a variable, to a dangerous sink
+func FindDOMClobberingCandidates(body []byte) []DOMClobberingCandidate {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	// identifier -> objects through which the element is reachable
+	clobberable := make(map[string][]string)
+	elements := make(map[string]string)
+	addIdentifier := func(identifier string, s *goquery.Selection, objects ...string) {
+		if !jsIdentifierRegex.MatchString(identifier) {
+			return
+		}
+		if _, exists := elements[identifier]; !exists {
+			html, _ := goquery.OuterHtml(s)
+			elements[identifier] = html
+		}
+		clobberable[identifier] = append(clobberable[identifier], objects...)
+	}
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id, _ := s.Attr("id")
+		addIdentifier(id, s, "window", "self", "top", "globalThis")
+	})
+	doc.Find("[name]").Each(func(i int, s *goquery.Selection) {
+		if !domClobberingNamedElements[goquery.NodeName(s)] {
+			return
+		}
+		name, _ := s.Attr("name")
+		addIdentifier(name, s, "document", "window", "self", "top", "globalThis")
+	})
+	if len(clobberable) == 0 {
+		return nil
+	}
+
+	var scripts strings.Builder
+	doc.Find("script").Each(func(i int, s *goquery.Selection) {
+		if _, hasSrc := s.Attr("src"); hasSrc {
+			return
+		}
+		scripts.WriteString(s.Text())
+		scripts.WriteString("\n")
+	})
+	statements := jsStatementSeparatorRegex.Split(scripts.String(), -1)
+
+	identifiers := make([]string, 0, len(clobberable))
+	for identifier := range clobberable {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	var candidates []DOMClobberingCandidate
+	for _, identifier := range identifiers {
+		globalRegex := regexp.MustCompile(`\b(` + strings.Join(clobberable[identifier], "|") + `)\.` + regexp.QuoteMeta(identifier) + `\b`)
+		if candidate, found := findClobberedGlobalSink(identifier, globalRegex, statements); found {
+			candidate.Element = elements[identifier]
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// findClobberedGlobalSink returns the first statement where the global is read and reaches a sink, either directly
+// or through a variable it has been assigned to
+func findClobberedGlobalSink(identifier string, globalRegex *regexp.Regexp, statements []string) (DOMClobberingCandidate, bool) {
+	aliases := make(map[string]string)
+	var aliasNames []string
+	for _, statement := range statements {
+		global := readGlobal(globalRegex, statement)
+		if global == "" {
+			continue
+		}
+		if sink := domClobberingSinkRegex.FindString(statement); sink != "" {
+			return DOMClobberingCandidate{Identifier: identifier, Global: global, Sink: cleanSink(sink), Statement: strings.TrimSpace(statement)}, true
+		}
+		if match := domClobberingAliasRegex.FindStringSubmatch(statement); match != nil {
+			if _, exists := aliases[match[1]]; !exists {
+				aliasNames = append(aliasNames, match[1])
+			}
+			aliases[match[1]] = global
+		}
+	}
+
+	for _, alias := range aliasNames {
+		aliasRegex := regexp.MustCompile(`(^|[^.\w$])` + regexp.QuoteMeta(alias) + `\b`)
+		for _, statement := range statements {
+			if !aliasRegex.MatchString(statement) || readGlobal(globalRegex, statement) != "" {
+				continue
+			}
+			if sink := domClobberingSinkRegex.FindString(statement); sink != "" {
+				return DOMClobberingCandidate{Identifier: identifier, Global: aliases[alias], Sink: cleanSink(sink), Statement: strings.TrimSpace(statement)}, true
+			}
+		}
+	}
+	return DOMClobberingCandidate{}, false
+}
+
+// readGlobal returns the global accessed in the statement, ignoring plain assignments to it
+func readGlobal(globalRegex *regexp.Regexp, statement string) string {
+	for _, loc := range globalRegex.FindAllStringIndex(statement, -1) {
+		rest := strings.TrimLeft(statement[loc[1]:], " \t")
+		if strings.HasPrefix(rest, "=") && !strings.HasPrefix(rest, "==") {
+			continue
+		}
+		return statement[loc[0]:loc[1]]
+	}
+	return ""
+}
+
+// cleanSink keeps only the sink name from the matched expression
+func cleanSink(sink string) string {
+	if idx := strings.IndexAny(sink, "=("); idx > 0 {
+		sink = sink[:idx]
+	}
+	return strings.TrimLeft(strings.TrimSpace(sink), ".")
+}
+
+// DOMClobberingScan reports globals that can be clobbered by elements of the page and end up in a dangerous sink
+func DOMClobberingScan(item *db.History) {
+	candidates := FindDOMClobberingCandidates(item.ResponseBody)
+	if len(candidates) == 0 {
+		return
+	}
+	log.Debug().Str("url", item.URL).Int("candidates", len(candidates)).Msg("Found DOM clobbering candidates")
+
+	var sb strings.Builder
+	sb.WriteString("The following globals read by the page can be clobbered by HTML elements and reach a dangerous sink:\n")
+	for _, candidate := range candidates {
+		sb.WriteString(fmt.Sprintf("\n - Clobbered identifier: %s\n", candidate.Identifier))
+		sb.WriteString(fmt.Sprintf("   Global: %s\n", candidate.Global))
+		sb.WriteString(fmt.Sprintf("   Sink: %s\n", candidate.Sink))
+		sb.WriteString(fmt.Sprintf("   Statement: %s\n", candidate.Statement))
+		if candidate.Element != "" {
+			sb.WriteString(fmt.Sprintf("   Clobbering element: %s\n", candidate.Element))
+		}
+	}
+	db.CreateIssueFromHistoryAndTemplate(item, db.DomClobberingCode, sb.String(), 60, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
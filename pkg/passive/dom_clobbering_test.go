@@ -0,0 +1,69 @@
+package passive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDOMClobberingCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		identifier string
+		global     string
+		sink       string
+	}{
+		{
+			name: "global through variable into script src",
+			body: `<html><body>
+<a id="config" href="https://example.com">x</a>
+<script>
+var config = window.config || {cdn: "https://cdn.example.com"};
+var script = document.createElement("script");
+script.src = config.cdn + "/app.js";
+</script></body></html>`,
+			identifier: "config",
+			global:     "window.config",
+			sink:       "src",
+		},
+		{
+			name: "named form into innerHTML",
+			body: `<html><body>
+<form name="notice"></form>
+<div id="out"></div>
+<script>document.getElementById("out").innerHTML = document.notice.message;</script>
+</body></html>`,
+			identifier: "notice",
+			global:     "document.notice",
+			sink:       "innerHTML",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := FindDOMClobberingCandidates([]byte(tt.body))
+			assert.Len(t, candidates, 1)
+			if len(candidates) == 1 {
+				assert.Equal(t, tt.identifier, candidates[0].Identifier)
+				assert.Equal(t, tt.global, candidates[0].Global)
+				assert.Equal(t, tt.sink, candidates[0].Sink)
+				assert.NotEmpty(t, candidates[0].Element)
+			}
+		})
+	}
+}
+
+func TestFindDOMClobberingCandidatesIgnoresSafePages(t *testing.T) {
+	bodies := []string{
+		// Global is assigned by the page and never read into a sink
+		`<div id="config"></div><script>window.config = {a: 1}; console.log("loaded")</script>`,
+		// Global read into a sink but no element can clobber it
+		`<div id="other"></div><script>document.body.innerHTML = window.config.html</script>`,
+		// Element id is not reachable through document
+		`<div id="notice"></div><script>document.body.innerHTML = document.notice</script>`,
+	}
+	for _, body := range bodies {
+		assert.Empty(t, FindDOMClobberingCandidates([]byte(body)))
+	}
+}
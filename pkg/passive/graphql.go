@@ -0,0 +1,267 @@
+package passive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/rs/zerolog/log"
+)
+
+// GraphQLSchema is the subset of a GraphQL introspection response needed to analyze the exposed types and fields
+type GraphQLSchema struct {
+	Types []GraphQLSchemaType `json:"types"`
+}
+
+type GraphQLSchemaType struct {
+	Name        string               `json:"name"`
+	Kind        string               `json:"kind"`
+	Fields      []GraphQLSchemaField `json:"fields"`
+	InputFields []GraphQLSchemaField `json:"inputFields"`
+}
+
+type GraphQLSchemaField struct {
+	Name string `json:"name"`
+}
+
+type graphQLIntrospectionResponse struct {
+	Data *struct {
+		Schema *GraphQLSchema `json:"__schema"`
+	} `json:"data"`
+}
+
+// graphQLSensitiveNameKeywords are the word sequences that suggest sensitive data when found in a type or field name
+var graphQLSensitiveNameKeywords = [][]string{
+	{"password"},
+	{"passwd"},
+	{"secret"},
+	{"token"},
+	{"private", "key"},
+	{"api", "key"},
+	{"ssn"},
+	{"social", "security"},
+	{"credit", "card"},
+	{"card", "number"},
+	{"cvv"},
+}
+
+// graphQLPaginationWords precede token in the names of opaque pagination cursors, such as nextPageToken
+var graphQLPaginationWords = []string{"page", "next", "prev", "previous", "continuation", "cursor", "pagination"}
+
+// ParseGraphQLIntrospectionSchema parses the schema from a GraphQL introspection response body
+func ParseGraphQLIntrospectionSchema(body []byte) (*GraphQLSchema, error) {
+	var response graphQLIntrospectionResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	if response.Data == nil || response.Data.Schema == nil || len(response.Data.Schema.Types) == 0 {
+		return nil, fmt.Errorf("response does not contain an introspection schema")
+	}
+	return response.Data.Schema, nil
+}
+
+// splitGraphQLName splits a camelCase, PascalCase, snake_case or kebab-case identifier into its lowercase words
+func splitGraphQLName(name string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			previous := runes[i-1]
+			// A new word starts after a lowercase letter or digit, or at the last capital of an acronym (APIKey)
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// matchesGraphQLKeyword checks if the keyword words are found at the given position, either as consecutive words or
+// written as a single word (apikey). Plurals of the last word are also matched.
+func matchesGraphQLKeyword(words []string, position int, keyword []string) bool {
+	joined := strings.Join(keyword, "")
+	if words[position] == joined || words[position] == joined+"s" {
+		return true
+	}
+	if position+len(keyword) > len(words) {
+		return false
+	}
+	for i, word := range keyword {
+		candidate := words[position+i]
+		if candidate != word && (i != len(keyword)-1 || candidate != word+"s") {
+			return false
+		}
+	}
+	return true
+}
+
+// isSensitiveGraphQLName checks if a type or field name suggests that it holds sensitive data. Keywords are matched on
+// word boundaries, so names like className don't match ssn, and pagination tokens are ignored.
+func isSensitiveGraphQLName(name string) bool {
+	words := splitGraphQLName(name)
+	for position := range words {
+		for _, keyword := range graphQLSensitiveNameKeywords {
+			if !matchesGraphQLKeyword(words, position, keyword) {
+				continue
+			}
+			if keyword[0] == "token" && position > 0 && lib.SliceContains(graphQLPaginationWords, words[position-1]) {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// FindSensitiveSchemaFields returns the types and fields of the schema whose names suggest sensitive data, with fields
+// formatted as Type.field. Introspection types are ignored.
+func FindSensitiveSchemaFields(schema *GraphQLSchema) []string {
+	var findings []string
+	if schema == nil {
+		return findings
+	}
+	seen := make(map[string]bool)
+	add := func(finding string) {
+		if !seen[finding] {
+			seen[finding] = true
+			findings = append(findings, finding)
+		}
+	}
+	for _, schemaType := range schema.Types {
+		if schemaType.Name == "" || strings.HasPrefix(schemaType.Name, "__") {
+			continue
+		}
+		if isSensitiveGraphQLName(schemaType.Name) {
+			add(schemaType.Name)
+		}
+		for _, field := range append(schemaType.Fields, schemaType.InputFields...) {
+			if isSensitiveGraphQLName(field.Name) {
+				add(fmt.Sprintf("%s.%s", schemaType.Name, field.Name))
+			}
+		}
+	}
+	return findings
+}
+
+// GraphQLSensitiveFieldsScan analyzes GraphQL introspection responses looking for queryable fields that suggest sensitive data
+func GraphQLSensitiveFieldsScan(item *db.History) {
+	if !strings.Contains(string(item.ResponseBody), "__schema") {
+		return
+	}
+	schema, err := ParseGraphQLIntrospectionSchema(item.ResponseBody)
+	if err != nil {
+		log.Debug().Err(err).Str("url", item.URL).Msg("Could not parse GraphQL introspection schema")
+		return
+	}
+	findings := FindSensitiveSchemaFields(schema)
+	if len(findings) == 0 {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("The GraphQL introspection schema exposes the following types and fields whose names suggest sensitive data:")
+	for _, finding := range findings {
+		sb.WriteString(fmt.Sprintf("\n - %s", finding))
+	}
+	sb.WriteString("\n\nVerify that these fields can only be queried by authorized clients.")
+	db.CreateIssueFromHistoryAndTemplate(item, db.GraphqlSensitiveFieldsExposedCode, sb.String(), 80, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
+
+// graphQLResponseKeys are the only top level keys allowed in a GraphQL response
+var graphQLResponseKeys = map[string]bool{"data": true, "errors": true, "extensions": true}
+
+// graphQLErrorMessageRegex matches error messages commonly returned by GraphQL servers
+var graphQLErrorMessageRegex = regexp.MustCompile(`(?i)(cannot query field|syntax error: |must provide (a )?query|unknown argument|graphql|variable "\$|field "[^"]+" (of type|argument))`)
+
+var graphQLPathRegex = regexp.MustCompile(`(?i)/(graphql|graphiql|gql|_graphql)(/|\.php|\.json|$)`)
+
+type graphQLErrorShape struct {
+	Message   *string           `json:"message"`
+	Locations []json.RawMessage `json:"locations"`
+	Path      []json.RawMessage `json:"path"`
+}
+
+// IsGraphQLResponse checks if a response has been returned by a GraphQL endpoint, based on its content type, the path
+// and the shape of the JSON body. Responses only shaped as {"data": ...} are common in JSON APIs, so they also need a
+// GraphQL path or GraphQL specific fields in the data to be considered.
+func IsGraphQLResponse(rawURL string, contentType string, body []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "application/graphql") {
+		return true
+	}
+	if !strings.Contains(contentType, "json") {
+		return false
+	}
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil || len(response) == 0 {
+		return false
+	}
+	for key := range response {
+		if !graphQLResponseKeys[key] {
+			return false
+		}
+	}
+	if rawErrors, ok := response["errors"]; ok {
+		var errors []graphQLErrorShape
+		if err := json.Unmarshal(rawErrors, &errors); err != nil || len(errors) == 0 {
+			return false
+		}
+		for _, e := range errors {
+			if e.Message == nil {
+				return false
+			}
+		}
+		for _, e := range errors {
+			if len(e.Locations) > 0 || len(e.Path) > 0 || graphQLErrorMessageRegex.MatchString(*e.Message) {
+				return true
+			}
+		}
+	}
+	rawData, ok := response["data"]
+	if !ok || !strings.HasPrefix(strings.TrimSpace(string(rawData)), "{") {
+		return false
+	}
+	if strings.Contains(string(rawData), `"__typename"`) || strings.Contains(string(rawData), `"__schema"`) {
+		return true
+	}
+	parsed, err := url.Parse(rawURL)
+	return err == nil && graphQLPathRegex.MatchString(parsed.Path)
+}
+
+// GraphQLEndpointURL returns the endpoint a GraphQL request has been sent to, without query string
+func GraphQLEndpointURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+// GraphQLEndpointDetectionScan reports the GraphQL endpoints identified from their responses
+func GraphQLEndpointDetectionScan(item *db.History) {
+	if !IsGraphQLResponse(item.URL, item.ResponseContentType, item.ResponseBody) {
+		return
+	}
+	details := fmt.Sprintf("The response has the format of a GraphQL response, which indicates that %s is a GraphQL endpoint.", GraphQLEndpointURL(item.URL))
+	db.CreateIssueFromHistoryAndTemplate(item, db.GraphqlEndpointDetectedCode, details, 80, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
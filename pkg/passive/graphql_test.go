@@ -0,0 +1,96 @@
+package passive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSensitiveSchemaFields(t *testing.T) {
+	body := []byte(`{"data":{"__schema":{"queryType":{"name":"Query"},"types":[
+		{"kind":"OBJECT","name":"Query","fields":[{"name":"users"},{"name":"user"}]},
+		{"kind":"OBJECT","name":"User","fields":[{"name":"id"},{"name":"email"},{"name":"passwordHash"},{"name":"api_key"}]},
+		{"kind":"INPUT_OBJECT","name":"PaymentInput","inputFields":[{"name":"amount"},{"name":"creditCardNumber"}]},
+		{"kind":"OBJECT","name":"ClientSecret","fields":[{"name":"value"}]},
+		{"kind":"OBJECT","name":"__Type","fields":[{"name":"name"}]},
+		{"kind":"SCALAR","name":"String"}
+	]}}}`)
+
+	schema, err := ParseGraphQLIntrospectionSchema(body)
+	assert.Nil(t, err)
+
+	findings := FindSensitiveSchemaFields(schema)
+	assert.ElementsMatch(t, []string{"User.passwordHash", "User.api_key", "PaymentInput.creditCardNumber", "ClientSecret"}, findings)
+}
+
+func TestFindSensitiveSchemaFieldsNoFindings(t *testing.T) {
+	schema := &GraphQLSchema{Types: []GraphQLSchemaType{
+		{Name: "Product", Kind: "OBJECT", Fields: []GraphQLSchemaField{{Name: "name"}, {Name: "price"}}},
+	}}
+	assert.Empty(t, FindSensitiveSchemaFields(schema))
+	assert.Empty(t, FindSensitiveSchemaFields(nil))
+
+	_, err := ParseGraphQLIntrospectionSchema([]byte(`{"data":{"user":{"name":"test"}}}`))
+	assert.NotNil(t, err)
+}
+
+func TestIsSensitiveGraphQLName(t *testing.T) {
+	sensitive := []string{"password", "passwordHash", "user_password", "api_key", "APIKey", "apiKeys", "apikey", "privateKey", "accessToken", "refresh-token", "SSN", "userSSN", "socialSecurityNumber", "creditCardNumber", "card_number", "CVV", "ClientSecret", "secrets"}
+	for _, name := range sensitive {
+		assert.True(t, isSensitiveGraphQLName(name), name)
+	}
+
+	notSensitive := []string{"className", "classNames", "lesson", "passenger", "tokenizer", "secretary", "nextPageToken", "pageToken", "continuationToken", "cursor", "endCursor", "keyboard", "apiVersion", "cardholderName"}
+	for _, name := range notSensitive {
+		assert.False(t, isSensitiveGraphQLName(name), name)
+	}
+}
+
+func TestSplitGraphQLName(t *testing.T) {
+	assert.Equal(t, []string{"credit", "card", "number"}, splitGraphQLName("creditCardNumber"))
+	assert.Equal(t, []string{"api", "key"}, splitGraphQLName("APIKey"))
+	assert.Equal(t, []string{"user", "ssn"}, splitGraphQLName("userSSN"))
+	assert.Equal(t, []string{"refresh", "token"}, splitGraphQLName("refresh_token"))
+	assert.Equal(t, []string{"address2", "line"}, splitGraphQLName("address2Line"))
+}
+
+func TestIsGraphQLResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		expected    bool
+	}{
+		{"graphql content type", "https://example.com/api", "application/graphql-response+json", `{"data":{"me":null}}`, true},
+		{"data in graphql path", "https://example.com/graphql", "application/json", `{"data":{"products":[{"id":1}]}}`, true},
+		{"data in graphql php path", "https://example.com/wp/graphql.php?query=x", "application/json; charset=utf-8", `{"data":{"posts":[]}}`, true},
+		{"typename in data", "https://example.com/api/query", "application/json", `{"data":{"viewer":{"__typename":"User","id":"1"}}}`, true},
+		{"error with locations", "https://example.com/api", "application/json", `{"errors":[{"message":"Unexpected token","locations":[{"line":1,"column":9}]}]}`, true},
+		{"error with path", "https://example.com/api", "application/json", `{"data":{"user":null},"errors":[{"message":"Not authorized","path":["user"]}]}`, true},
+		{"cannot query field error", "https://example.com/api", "application/json", `{"errors":[{"message":"Cannot query field \"usr\" on type \"Query\"."}]}`, true},
+		{"missing query error", "https://example.com/api", "application/json", `{"errors":[{"message":"Must provide query string."}]}`, true},
+
+		{"data envelope in rest api", "https://example.com/api/users", "application/json", `{"data":{"users":[{"id":1,"name":"test"}]}}`, false},
+		{"data array in rest api", "https://example.com/graphql", "application/json", `{"data":[{"id":1}]}`, false},
+		{"json api document", "https://example.com/api/articles", "application/vnd.api+json", `{"data":{"type":"articles","id":"1"},"links":{"self":"/articles/1"}}`, false},
+		{"json api errors", "https://example.com/api/articles", "application/vnd.api+json", `{"errors":[{"status":"422","title":"Invalid Attribute","detail":"First name must contain at least two characters."}]}`, false},
+		{"rest validation errors", "https://example.com/api/users", "application/json", `{"errors":[{"message":"email is required"}]}`, false},
+		{"errors as object", "https://example.com/graphql", "application/json", `{"errors":{"email":"is required"}}`, false},
+		{"other keys", "https://example.com/graphql", "application/json", `{"data":{"id":1},"status":"ok"}`, false},
+		{"html in graphql path", "https://example.com/graphql", "text/html", `<html><title>GraphiQL</title></html>`, false},
+		{"invalid json", "https://example.com/graphql", "application/json", `{"data":`, false},
+		{"empty object", "https://example.com/graphql", "application/json", `{}`, false},
+		{"graphql in query string only", "https://example.com/api/search?q=graphql", "application/json", `{"data":{"results":[]}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsGraphQLResponse(tt.url, tt.contentType, []byte(tt.body)))
+		})
+	}
+}
+
+func TestGraphQLEndpointURL(t *testing.T) {
+	assert.Equal(t, "https://example.com/graphql", GraphQLEndpointURL("https://example.com/graphql?query=%7Bme%7D#section"))
+	assert.Equal(t, "https://example.com/api/graphql", GraphQLEndpointURL("https://example.com/api/graphql"))
+}
@@ -0,0 +1,107 @@
+package passive
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+)
+
+// jsEndpointPatterns match the URLs passed to the usual javascript HTTP clients and the string literals that look like
+// API paths. The first capturing group holds the URL.
+var jsEndpointPatterns = []*regexp.Regexp{
+	// fetch("/api/users"), window.fetch(`/api/users/${id}`)
+	regexp.MustCompile("\\bfetch\\(\\s*[\"'`]([^\"'`\\s]+)[\"'`]"),
+	// axios.get("/api/users"), axios.post('/api/users', data), axios("/api/users"), instance.delete(...) is not matched
+	regexp.MustCompile("\\baxios(?:\\.(?:get|post|put|patch|delete|head|options|request))?\\(\\s*[\"'`]([^\"'`\\s]+)[\"'`]"),
+	// xhr.open("POST", "/api/users")
+	regexp.MustCompile("\\.open\\(\\s*[\"'`](?i:GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS)[\"'`]\\s*,\\s*[\"'`]([^\"'`\\s]+)[\"'`]"),
+	// $.get("/api/users"), $.post(...), $.getJSON(...), $.ajax("/api/users")
+	regexp.MustCompile("\\$\\.(?:get|post|getJSON|ajax)\\(\\s*[\"'`]([^\"'`\\s]+)[\"'`]"),
+	// $.ajax({url: "/api/users"}), axios({url: "/api/users"})
+	regexp.MustCompile("(?:ajax|axios|request)\\(\\s*\\{[^{}]{0,200}?\\burl\\s*:\\s*[\"'`]([^\"'`\\s]+)[\"'`]"),
+	// "/api/v1/users", "/rest/users", "/graphql"
+	regexp.MustCompile("[\"'`](/(?:api|rest|graphql|gql|v[0-9]{1,2})(?:/[\\w\\-.~%:@]*)*/?(?:\\?[^\"'`\\s<>]*)?)[\"'`]"),
+}
+
+// jsEndpointIgnoredExtensions are the extensions of static resources, which are not endpoints
+var jsEndpointIgnoredExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".map": true, ".css": true, ".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
+	".svg": true, ".ico": true, ".webp": true, ".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".mp4": true,
+	".mp3": true, ".webm": true, ".html": true, ".htm": true,
+}
+
+// ExtractJavascriptEndpoints extracts the endpoints called from javascript code, resolved against the origin of the
+// URL the code has been loaded from, deduplicated and sorted. Dynamic parts of template literals are removed.
+func ExtractJavascriptEndpoints(code string, sourceURL string) []string {
+	source, err := url.Parse(sourceURL)
+	if err != nil || source.Host == "" {
+		return []string{}
+	}
+	origin := &url.URL{Scheme: source.Scheme, Host: source.Host, Path: "/"}
+
+	seen := make(map[string]bool)
+	endpoints := []string{}
+	for _, pattern := range jsEndpointPatterns {
+		for _, match := range pattern.FindAllStringSubmatch(code, -1) {
+			endpoint, ok := normalizeJavascriptEndpoint(match[1], origin)
+			if ok && !seen[endpoint] {
+				seen[endpoint] = true
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// normalizeJavascriptEndpoint resolves an endpoint found in javascript code against the origin, discarding the ones
+// which are not web URLs or point to static resources
+func normalizeJavascriptEndpoint(raw string, origin *url.URL) (string, bool) {
+	if idx := strings.Index(raw, "${"); idx != -1 {
+		raw = raw[:idx]
+	}
+	raw = strings.TrimRight(raw, "?&")
+	if raw == "" || raw == "/" || strings.HasPrefix(raw, "#") || strings.Contains(raw, "\\") {
+		return "", false
+	}
+	lower := strings.ToLower(raw)
+	for _, prefix := range []string{"data:", "javascript:", "blob:", "mailto:", "about:", "ws:", "wss:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return "", false
+		}
+	}
+	// Relative paths are resolved against the origin, as the page that loads the code is unknown
+	if !strings.HasPrefix(raw, "/") && !strings.Contains(raw, "://") {
+		raw = "/" + raw
+	}
+	resolved, err := origin.Parse(raw)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") || resolved.Host == "" {
+		return "", false
+	}
+	if jsEndpointIgnoredExtensions[strings.ToLower(path.Ext(resolved.Path))] {
+		return "", false
+	}
+	resolved.Fragment = ""
+	return resolved.String(), true
+}
+
+// JavascriptEndpointsScan reports the endpoints called from the javascript code of the history item, returning them so
+// that they can be added as targets
+func JavascriptEndpointsScan(item *db.History) []string {
+	endpoints := ExtractJavascriptEndpoints(string(item.ResponseBody), item.URL)
+	if len(endpoints) == 0 {
+		return endpoints
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The following %d endpoints have been found in the javascript code:\n", len(endpoints)))
+	for _, endpoint := range endpoints {
+		sb.WriteString(fmt.Sprintf("\n - %s", endpoint))
+	}
+	db.CreateIssueFromHistoryAndTemplate(item, db.JsEndpointsDiscoveredCode, sb.String(), 80, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+	return endpoints
+}
@@ -0,0 +1,69 @@
+package passive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractJavascriptEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected []string
+	}{
+		{
+			name:     "fetch",
+			code:     `async function load(){const r=await fetch("/api/v1/users/me",{credentials:"include"});return window.fetch('/account/settings?tab=profile')}`,
+			expected: []string{"https://app.example.com/account/settings?tab=profile", "https://app.example.com/api/v1/users/me"},
+		},
+		{
+			name:     "fetch with template literal",
+			code:     "const user=e=>fetch(`/api/users/${e.id}/orders`).then(e=>e.json())",
+			expected: []string{"https://app.example.com/api/users/"},
+		},
+		{
+			name:     "axios",
+			code:     `axios.post("/auth/login",{username:e,password:t});axios.delete('https://api.example.com/v2/sessions');axios("internal/health")`,
+			expected: []string{"https://api.example.com/v2/sessions", "https://app.example.com/auth/login", "https://app.example.com/internal/health"},
+		},
+		{
+			name:     "axios config",
+			code:     `axios({method:"put",url:"/profile/avatar",data:n})`,
+			expected: []string{"https://app.example.com/profile/avatar"},
+		},
+		{
+			name:     "xhr",
+			code:     `var x=new XMLHttpRequest;x.open("POST","/upload/chunk",!0);x.setRequestHeader("Content-Type","application/json")`,
+			expected: []string{"https://app.example.com/upload/chunk"},
+		},
+		{
+			name:     "jquery",
+			code:     `$.getJSON("/search/suggest?q="+q);$.ajax({type:"POST",url:"/cart/add",data:d})`,
+			expected: []string{"https://app.example.com/cart/add", "https://app.example.com/search/suggest?q="},
+		},
+		{
+			name:     "api path literals",
+			code:     `var n={list:"/api/products",detail:"/api/products/",graphql:"/graphql",legacy:'/v1/orders?status=open',rest:"/rest/basket/1"}`,
+			expected: []string{"https://app.example.com/api/products", "https://app.example.com/api/products/", "https://app.example.com/graphql", "https://app.example.com/rest/basket/1", "https://app.example.com/v1/orders?status=open"},
+		},
+		{
+			name: "false positives",
+			code: `var a="application/json",b="/static/js/chunk.4f2a.js",c=fetch("/assets/logo.svg"),d="text/html",e=/\/api\/users/.test(u),` +
+				`f="/apiary",g=fetch("data:image/png;base64,AAAA"),h=fetch("#top"),i="api/v1 users",j=fetch(e),k="/v100/x",l=fetch("/"),` +
+				`m=x.open("GET",u),o=fetch("javascript:void(0)"),p=fetch("wss://app.example.com/socket")`,
+			expected: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExtractJavascriptEndpoints(tt.code, "https://app.example.com/static/js/main.js"))
+		})
+	}
+}
+
+func TestExtractJavascriptEndpointsDeduplicates(t *testing.T) {
+	code := `fetch("/api/items");axios.get("/api/items");const u="/api/items";fetch("https://app.example.com/api/items#list")`
+	assert.Equal(t, []string{"https://app.example.com/api/items"}, ExtractJavascriptEndpoints(code, "https://app.example.com/app.js"))
+	assert.Empty(t, ExtractJavascriptEndpoints(code, "not a url"))
+}
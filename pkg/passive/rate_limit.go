@@ -0,0 +1,60 @@
+package passive
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+// RateLimitHeadersScan records the scans getting rate limited according to the response headers, and reports the
+// headers leaking internal quota identifiers
+func RateLimitHeadersScan(item *db.History) {
+	headers, err := item.GetResponseHeadersAsMap()
+	if err != nil {
+		return
+	}
+	host, err := lib.GetHostFromURL(item.URL)
+	if err != nil {
+		return
+	}
+	info := http_utils.ParseRateLimitHeaders(headers)
+	if info == nil {
+		return
+	}
+	log.Debug().Str("url", item.URL).Interface("rate_limit_headers", info.Headers).Int("limit", info.Limit).Int("remaining", info.Remaining).Dur("reset", info.Reset).Msg("Observed rate limit headers")
+
+	if item.TaskID != nil && *item.TaskID != 0 && (item.StatusCode == 429 || (info.HasRemaining && info.Remaining == 0)) {
+		db.Connection.NewScanEvent(*item.TaskID, nil, db.ScanEventRateLimited, "rate-limit", fmt.Sprintf("Rate limited on host %s", host), map[string]interface{}{
+			"host":        host,
+			"url":         item.URL,
+			"status_code": item.StatusCode,
+			"limit":       info.Limit,
+			"remaining":   info.Remaining,
+			"reset":       info.Reset.String(),
+		})
+	}
+
+	if len(info.QuotaIdentifiers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(info.QuotaIdentifiers))
+	for name := range info.QuotaIdentifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("The following rate limit headers disclose internal quota details:")
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("\n - %s: %s", name, info.QuotaIdentifiers[name]))
+	}
+	if info.HasLimit || info.HasRemaining || info.HasReset {
+		sb.WriteString(fmt.Sprintf("\n\nObserved limits: limit %d, remaining %d, reset in %s", info.Limit, info.Remaining, info.Reset))
+	}
+	db.CreateIssueFromHistoryAndTemplate(item, db.RateLimitQuotaDisclosureCode, sb.String(), 80, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
@@ -66,26 +66,17 @@ var bucketBodyPatternsMap = map[string]*regexp.Regexp{
 
 var apiKeysPatternsMap = map[string]*regexp.Regexp{
 	"Amazon MWS Auth Token":             regexp.MustCompile(`amzn\.mws\.[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`),
-	"AWS API Key":                       regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
 	"AWS AppSync GraphQL Key":           regexp.MustCompile(`da2-[a-z0-9]{26}`),
 	"Artifactory":                       regexp.MustCompile(`artifactory.{0,50}(\\\"|'|')?[a-zA-Z0-9=]{112}(\\\"|'|')?`),
 	"Code Clima":                        regexp.MustCompile(`codeclima.{0,50}(\\\"|'|')?[0-9a-f]{64}(\\\"|'|')?`),
 	"Cloudinary Basic Auth":             regexp.MustCompile(`(?i)cloudinary:\\/[0-9]{15}:[0-9A-Za-z]+@[a-z]+`),
 	"Facebook Access Token":             regexp.MustCompile(`EAACEdEose0cBA[0-9A-Za-z]+`),
 	"Facebook Oauth":                    regexp.MustCompile(`[fF][aA][cC][eE][bB][oO][oO][kK][^\s]*['|\"][0-9a-f]{32}['|\"]`),
-	"GitHub":                            regexp.MustCompile(`[gG][iI][tT][hH][uU][bB].*['|\"][0-9a-zA-Z]{35,40}['|\"]`),
-	"Generic API Key":                   regexp.MustCompile(`[aA][pP][iI]_?[kK][eE][yY].*['|\"][0-9a-zA-Z]{32,45}['|\"]`),
-	"Generic Secret":                    regexp.MustCompile(`[sS][eE][cC][rR][eE][tT].*['|\"][0-9a-zA-Z]{32,45}['|\"]`),
-	"Google API Key":                    regexp.MustCompile(`AIza[0-9A-Za-z\\-_]{35}`),
-	"Google Cloud Platform API Key":     regexp.MustCompile(`AIza[0-9A-Za-z\\-_]{35}`),
 	"Google Cloud Platform OAuth":       regexp.MustCompile(`[0-9]+-[0-9A-Za-z_]{32}\\.apps\\.googleusercontent\\.com`),
-	"Google Drive API Key":              regexp.MustCompile(`AIza[0-9A-Za-z\\-_]{35}`),
 	"Google Drive OAuth":                regexp.MustCompile(`[0-9]+-[0-9A-Za-z_]{32}\\.apps\\.googleusercontent\\.com`),
 	"Google (GCP) Service-account":      regexp.MustCompile(`"type": "service_account"`),
-	"Google Gmail API Key":              regexp.MustCompile(`AIza[0-9A-Za-z\\-_]{35}`),
 	"Google Gmail OAuth":                regexp.MustCompile(`[0-9]+-[0-9A-Za-z_]{32}\\.apps\\.googleusercontent\\.com`),
 	"Google OAuth Access Token":         regexp.MustCompile(`ya29\\.[0-9A-Za-z\\-_]+`),
-	"Google YouTube API Key":            regexp.MustCompile(`AIza[0-9A-Za-z\\-_]{35}`),
 	"Google YouTube OAuth":              regexp.MustCompile(`[0-9]+-[0-9A-Za-z_]{32}\\.apps\\.googleusercontent\\.com`),
 	"Heroku API Key":                    regexp.MustCompile(`[hH][eE][rR][oO][kK][uU].*[0-9A-F]{8}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{12}`),
 	"Hockeyapp":                         regexp.MustCompile(`(?i)hockey.{0,50}(\\\"|'|')?[0-9a-f]{32}(\\\"|'|')?`),
@@ -113,9 +104,7 @@ var apiKeysPatternsMap = map[string]*regexp.Regexp{
 	"Shopify Custom App Access Token":   regexp.MustCompile(`shpca_[a-fA-F0-9]{32}`),
 	"Shopify Access Token":              regexp.MustCompile(`shpat_[a-fA-F0-9]{32}`),
 	"Shopify Shared Secret":             regexp.MustCompile(`shpss_[a-fA-F0-9]{32}`),
-	"Slack Token":                       regexp.MustCompile(`(xox[pborsa]-[0-9]{12}-[0-9]{12}-[0-9]{12}-[a-z0-9]{32})`),
 	"Slack Webhook":                     regexp.MustCompile(`^https://hooks\\.slack\\.com/services/T[a-zA-Z0-9_]{8}/B[a-zA-Z0-9_]{8}/[a-zA-Z0-9_]{24}$`),
-	"Stripe API Key":                    regexp.MustCompile(`(?i)stripe.{0,50}(\\\"|'|')?sk_live_[0-9a-zA-Z]{24}(\\\"|'|')?`),
 	"Square Access Token":               regexp.MustCompile(`sq0atp-[0-9A-Za-z\\-_]{22}`),
 	"Square Oauth Secret":               regexp.MustCompile(`sq0csp-[0-9A-Za-z\\-_]{43}`),
 	"Telegram Bot API Key":              regexp.MustCompile(`[0-9]+:AA[0-9A-Za-z\\-_]{33}`),
@@ -128,6 +117,16 @@ var apiKeysPatternsMap = map[string]*regexp.Regexp{
 	"Yandex AWS Access Token":           regexp.MustCompile(`YC[a-zA-Z0-9_-]{38}`),
 }
 
+// providerKeysPatternsMap detects the provider keys also covered by the named secret rules, so they are still found
+// when the secrets check is disabled
+var providerKeysPatternsMap = map[string]*regexp.Regexp{
+	"AWS API Key":    regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	"GitHub":         regexp.MustCompile(`[gG][iI][tT][hH][uU][bB].*['|\"][0-9a-zA-Z]{35,40}['|\"]`),
+	"Google API Key": regexp.MustCompile(`AIza[0-9A-Za-z\\-_]{35}`),
+	"Slack Token":    regexp.MustCompile(`(xox[pborsa]-[0-9]{12}-[0-9]{12}-[0-9]{12}-[a-z0-9]{32})`),
+	"Stripe API Key": regexp.MustCompile(`(?i)stripe.{0,50}(\\\"|'|')?sk_live_[0-9a-zA-Z]{24}(\\\"|'|')?`),
+}
+
 var javaAppletPatterns = []struct {
 	regex       *regexp.Regexp
 	description string
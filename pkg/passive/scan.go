@@ -56,15 +56,23 @@ func ScanHistoryItemHeaders(item *db.History) {
 }
 
 func ScanHistoryItem(item *db.History) {
+	if item.ResponseBodyTruncated {
+		// Pattern based checks still work on the stored part of the body, but parsers need the complete document
+		item.Logger().Debug().Int("size", item.ResponseBodySize).Msg("Passively scanning a truncated response body")
+	}
 	if strings.Contains(item.ResponseContentType, "text/html") {
 		if viper.GetBool("passive.checks.js.enabled") {
 			PassiveJavascriptScan(item)
 		}
 		DirectoryListingScan(item)
 		UnencryptedPasswordFormDetectionScan(item)
+		DOMClobberingScan(item)
+		CSPScan(item)
+		MissingSRIScan(item)
 	} else if strings.Contains(item.ResponseContentType, "javascript") || strings.Contains(item.ResponseContentType, "ecmascript") {
 		if viper.GetBool("passive.checks.js.enabled") {
 			passiveJavascriptSecretsScan(item)
+			JavascriptEndpointsScan(item)
 			ReactDevelopmentModeScan(item)
 			PassiveJavascriptScan(item)
 		}
@@ -72,11 +80,14 @@ func ScanHistoryItem(item *db.History) {
 	StorageBucketDetectionScan(item)
 	DatabaseErrorScan(item)
 	LeakedApiKeysScan(item)
+	if viper.GetBool("passive.checks.secrets.enabled") {
+		SecretsScan(item)
+	}
 	PrivateIPScan(item)
 	JwtDetectionScan(item)
 	EmailAddressScan(item)
 	FileUploadScan(item)
-	SessionTokenInURLScan(item)
+	SensitiveDataInURLScan(item)
 	PrivateKeyScan(item)
 	DBConnectionStringScan(item)
 	PasswordInGetRequestScan(item)
@@ -88,6 +99,12 @@ func ScanHistoryItem(item *db.History) {
 	SilverlightDetectionScan(item)
 	ActiveXDetectionScan(item)
 	JavaAppletDetectionScan(item)
+	if !item.ResponseBodyTruncated {
+		GraphQLSensitiveFieldsScan(item)
+	}
+	GraphQLEndpointDetectionScan(item)
+	RateLimitHeadersScan(item)
+	CacheDeceptionRiskScan(item)
 
 	if viper.GetBool("passive.checks.exceptions.enabled") {
 		ExceptionsScan(item)
@@ -193,17 +210,17 @@ func FileUploadScan(item *db.History) {
 
 func attemtToCrackJwtIfRequired(item *db.History, jwt *db.JsonWebToken) {
 	if jwt != nil && !jwt.TestedEmbeddedWordlist && !jwt.Cracked {
-		log.Info().Uint("token_id", jwt.ID).Str("token", jwt.Token).Msg("Token seen for the first time, attempting to crack it with embedded wordlist")
+		log.Info().Uint("token_id", jwt.ID).Msg("Token seen for the first time, attempting to crack it with embedded wordlist")
 		crackResult, err := tokens.CrackJWTAndCreateIssue(item, jwt)
 		if err != nil {
-			log.Err(err).Uint("token_id", jwt.ID).Str("token", jwt.Token).Msg("Failed to crack JWT")
+			log.Err(err).Uint("token_id", jwt.ID).Msg("Failed to crack JWT")
 		} else if crackResult.Found {
-			log.Info().Uint("token_id", jwt.ID).Str("token", jwt.Token).Str("secret", crackResult.Secret).Msg("JWT cracked")
+			log.Info().Uint("token_id", jwt.ID).Str("secret", lib.RedactSecret(crackResult.Secret)).Msg("JWT cracked")
 		} else {
-			log.Info().Uint("token_id", jwt.ID).Str("token", jwt.Token).Msg("JWT secret could not be found")
+			log.Info().Uint("token_id", jwt.ID).Msg("JWT secret could not be found")
 		}
 	} else if jwt != nil && jwt.Cracked {
-		log.Info().Uint("token_id", jwt.ID).Str("token", jwt.Token).Msg("Token already cracked")
+		log.Info().Uint("token_id", jwt.ID).Msg("Token already cracked")
 		noTaskJob := uint(0)
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("A JWT token that had been cracked previously has been discovered in a %s request to %s\n\n", item.Method, item.URL))
@@ -222,9 +239,9 @@ func attemtToCrackJwtIfRequired(item *db.History, jwt *db.JsonWebToken) {
 			&noTaskJob,
 		)
 		if err != nil {
-			log.Error().Err(err).Str("token", jwt.Token).Msg("Failed to create issue for already cracked JWT")
+			log.Error().Err(err).Uint("token_id", jwt.ID).Msg("Failed to create issue for already cracked JWT")
 		} else {
-			log.Info().Str("token", jwt.Token).Str("secret", jwt.Secret).Uint("issue_id", issue.ID).Msg("Created issue for already cracked JWT")
+			log.Info().Uint("token_id", jwt.ID).Str("secret", lib.RedactSecret(jwt.Secret)).Uint("issue_id", issue.ID).Msg("Created issue for already cracked JWT")
 		}
 	}
 }
@@ -292,22 +309,6 @@ func checkHeadersForJwt(item *db.History, headers map[string][]string) {
 	}
 }
 
-func SessionTokenInURLScan(item *db.History) {
-	matches := sessionTokenRegex.FindAllStringSubmatch(item.URL, -1)
-
-	if len(matches) > 0 {
-		var sb strings.Builder
-		sb.WriteString("Discovered session tokens in URL parameters:")
-		for _, match := range matches {
-			parameter := match[0]
-			value := match[1]
-			sb.WriteString(fmt.Sprintf("\n - Parameter: %s, Value: %s", parameter, value))
-		}
-		details := sb.String()
-		db.CreateIssueFromHistoryAndTemplate(item, db.SessionTokenInUrlCode, details, 90, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
-	}
-}
-
 func PrivateKeyScan(item *db.History) {
 	type KeyMatch struct {
 		Type  string
@@ -454,26 +455,32 @@ func LeakedApiKeysScan(item *db.History) {
 	if matchAgainst == "" {
 		matchAgainst = string(item.ResponseBody)
 	}
-	var sb strings.Builder
-	matched := false
-
-	for patternName, pattern := range apiKeysPatternsMap {
-		matches := pattern.FindAllString(matchAgainst, -1)
+	details := findLeakedApiKeys(matchAgainst, !viper.GetBool("passive.checks.secrets.enabled"))
+	if details != "" {
+		db.CreateIssueFromHistoryAndTemplate(item, db.ExposedApiCredentialsCode, details, 80, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+	}
+}
 
-		if len(matches) > 0 {
-			matched = true
-			sb.WriteString(fmt.Sprintf("\nDiscovered %s", patternName))
-			for _, match := range matches {
-				sb.WriteString(fmt.Sprintf("\n - %s", match))
+// findLeakedApiKeys returns the details of the api keys found in the content. The provider keys are only matched when
+// requested, as they are otherwise reported by SecretsScan.
+func findLeakedApiKeys(content string, includeProviderKeys bool) string {
+	var sb strings.Builder
+	find := func(patterns map[string]*regexp.Regexp) {
+		for patternName, pattern := range patterns {
+			matches := pattern.FindAllString(content, -1)
+			if len(matches) > 0 {
+				sb.WriteString(fmt.Sprintf("\nDiscovered %s", patternName))
+				for _, match := range matches {
+					sb.WriteString(fmt.Sprintf("\n - %s", match))
+				}
 			}
 		}
 	}
-
-	details := sb.String()
-
-	if matched {
-		db.CreateIssueFromHistoryAndTemplate(item, db.ExposedApiCredentialsCode, details, 80, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+	find(apiKeysPatternsMap)
+	if includeProviderKeys {
+		find(providerKeysPatternsMap)
 	}
+	return sb.String()
 }
 
 func WebSocketUsageScan(item *db.History) {
@@ -0,0 +1,293 @@
+package passive
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// GenericSecretRuleName is the name of the rule matching high entropy values assigned to secret-like names
+const GenericSecretRuleName = "Generic High Entropy Secret"
+
+const (
+	// secretSnippetContext is the number of characters shown around a secret in the match snippet
+	secretSnippetContext = 24
+	// secretKeywordWindow is the number of characters after a keyword where keyword rules are matched
+	secretKeywordWindow = 256
+)
+
+// SecretRule detects a kind of secret in response contents
+type SecretRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// SecretGroup is the capture group holding the secret, the whole match is used when 0
+	SecretGroup int
+	// MinEntropy discards the matches whose secret has a lower Shannon entropy
+	MinEntropy float64
+	Severity   string
+	// Validate discards the matches whose secret does not pass an additional check
+	Validate func(secret string) bool
+	// Keywords are lowercase words the secret is preceded by. When set, the pattern, which should be anchored, is only
+	// matched right after the case insensitive occurrences of the keywords, which is much faster than matching
+	// alternations of words against the whole content.
+	Keywords []string
+}
+
+// SecretMatch describes a secret found in the content, without exposing the secret itself
+type SecretMatch struct {
+	Rule     string  `json:"rule"`
+	Severity string  `json:"severity"`
+	Entropy  float64 `json:"entropy"`
+	Redacted string  `json:"redacted"`
+	Snippet  string  `json:"snippet"`
+	Offset   int     `json:"offset"`
+}
+
+// SecretDetector runs secret rules against contents, ignoring the secrets matching the allowlist
+type SecretDetector struct {
+	rules     []SecretRule
+	allowlist []*regexp.Regexp
+}
+
+// DefaultSecretAllowlist matches the example, test and placeholder values usually found in documentation and libraries
+var DefaultSecretAllowlist = []string{
+	`(?i)example`,
+	`(?i)(dummy|sample|placeholder|changeme|redacted)`,
+	`(?i)^(test|fake|mock)[_-]`,
+	`(?i)your[_-]?(api[_-]?)?(key|token|secret)`,
+	`(?i)(x{6,}|\*{4,}|0{8,})`,
+	`(?i)(0123456789|abcdefgh)`,
+	`\$\{|\{\{|%s`,
+}
+
+var secretDomainRegex = regexp.MustCompile(`^[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}$`)
+
+// looksLikeGenericSecret discards values assigned to secret-like names which are usually not secrets, such as words,
+// numbers, domains and paths
+func looksLikeGenericSecret(value string) bool {
+	hasLetter := strings.IndexFunc(value, func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }) >= 0
+	hasDigit := strings.IndexFunc(value, func(r rune) bool { return r >= '0' && r <= '9' }) >= 0
+	if !hasLetter || !hasDigit {
+		return false
+	}
+	if strings.HasPrefix(value, "/") || strings.HasPrefix(value, "./") || strings.Contains(value, "://") {
+		return false
+	}
+	return !secretDomainRegex.MatchString(value)
+}
+
+// DefaultSecretRules returns the built-in named secret rules, followed by the generic rule which matches values with at
+// least the provided entropy assigned to secret-like names
+func DefaultSecretRules(genericMinEntropy float64) []SecretRule {
+	return []SecretRule{
+		{Name: "AWS Access Key ID", Pattern: regexp.MustCompile(`((?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`), SecretGroup: 1, Severity: "High"},
+		{Name: "AWS Secret Access Key", Pattern: regexp.MustCompile(`(?i)^_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})\b`), SecretGroup: 1, MinEntropy: 3.5, Severity: "High", Keywords: []string{"aws"}},
+		{Name: "Google API Key", Pattern: regexp.MustCompile(`(AIza[0-9A-Za-z_\-]{35})`), SecretGroup: 1, Severity: "Medium"},
+		{Name: "Stripe Secret Key", Pattern: regexp.MustCompile(`(sk_live_[0-9a-zA-Z]{24,99})\b`), SecretGroup: 1, Severity: "High"},
+		{Name: "Stripe Restricted Key", Pattern: regexp.MustCompile(`(rk_live_[0-9a-zA-Z]{24,99})\b`), SecretGroup: 1, Severity: "High"},
+		{Name: "GitHub Token", Pattern: regexp.MustCompile(`((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`), SecretGroup: 1, Severity: "High"},
+		{Name: "Slack Token", Pattern: regexp.MustCompile(`(xox[baprs]-[0-9A-Za-z-]{10,72})`), SecretGroup: 1, Severity: "High"},
+		{Name: "Private Key", Pattern: regexp.MustCompile(`-----BEGIN (?:RSA |DSA |EC |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----[\s\S]{32,}?-----END [A-Z ]*PRIVATE KEY(?: BLOCK)?-----`), Severity: "High"},
+		{Name: "JSON Web Token", Pattern: regexp.MustCompile(`(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`), SecretGroup: 1, Severity: "Low"},
+		{
+			Name:        GenericSecretRuleName,
+			Pattern:     regexp.MustCompile(`^[\w.-]*["']?\s*[:=]\s*["` + "`" + `']([A-Za-z0-9+/_\-=.~]{16,128})["` + "`" + `']`),
+			SecretGroup: 1,
+			MinEntropy:  genericMinEntropy,
+			Severity:    "Medium",
+			Validate:    looksLikeGenericSecret,
+			Keywords:    []string{"secret", "token", "apikey", "api_key", "passw", "credential", "privatekey", "private_key", "accesskey", "access_key", "auth"},
+		},
+	}
+}
+
+// NewSecretDetector compiles the allowlist patterns, which are matched against the secrets found by the rules
+func NewSecretDetector(rules []SecretRule, allowlist []string) (*SecretDetector, error) {
+	detector := &SecretDetector{rules: rules}
+	for _, pattern := range allowlist {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secrets allowlist pattern %s: %w", pattern, err)
+		}
+		detector.allowlist = append(detector.allowlist, compiled)
+	}
+	return detector, nil
+}
+
+// Find returns the secrets found in the content. Rules are run in order and each secret is only reported by the first
+// rule matching it.
+func (d *SecretDetector) Find(content []byte) []SecretMatch {
+	text := string(content)
+	var lowered string
+	matches := make([]SecretMatch, 0)
+	seen := make(map[string]bool)
+	for _, rule := range d.rules {
+		var found [][]int
+		if len(rule.Keywords) > 0 {
+			if lowered == "" {
+				lowered = asciiLower(text)
+			}
+			found = findAfterKeywords(text, lowered, rule)
+		} else {
+			found = rule.Pattern.FindAllStringSubmatchIndex(text, -1)
+		}
+		for _, indexes := range found {
+			start, end := indexes[0], indexes[1]
+			if rule.SecretGroup > 0 {
+				if len(indexes) <= rule.SecretGroup*2+1 || indexes[rule.SecretGroup*2] < 0 {
+					continue
+				}
+				start, end = indexes[rule.SecretGroup*2], indexes[rule.SecretGroup*2+1]
+			}
+			secret := text[start:end]
+			if seen[secret] || d.isAllowed(secret) {
+				continue
+			}
+			entropy := ShannonEntropy(secret)
+			if entropy < rule.MinEntropy || (rule.Validate != nil && !rule.Validate(secret)) {
+				continue
+			}
+			seen[secret] = true
+			matches = append(matches, SecretMatch{
+				Rule:     rule.Name,
+				Severity: rule.Severity,
+				Entropy:  math.Round(entropy*100) / 100,
+				Redacted: lib.RedactSecret(secret),
+				Snippet:  secretSnippet(text, start, end),
+				Offset:   start,
+			})
+		}
+	}
+	return matches
+}
+
+// findAfterKeywords matches the rule pattern right after each occurrence of its keywords, returning the submatch
+// indexes relative to the whole text
+func findAfterKeywords(text, lowered string, rule SecretRule) [][]int {
+	var found [][]int
+	for _, keyword := range rule.Keywords {
+		for from := 0; ; {
+			index := strings.Index(lowered[from:], keyword)
+			if index < 0 {
+				break
+			}
+			offset := from + index + len(keyword)
+			from = offset
+			window := text[offset:int(math.Min(float64(len(text)), float64(offset+secretKeywordWindow)))]
+			indexes := rule.Pattern.FindStringSubmatchIndex(window)
+			if indexes == nil {
+				continue
+			}
+			for i := range indexes {
+				if indexes[i] >= 0 {
+					indexes[i] += offset
+				}
+			}
+			found = append(found, indexes)
+		}
+	}
+	return found
+}
+
+// asciiLower lowercases ASCII letters only, so the indexes of the lowered string match the original one
+func asciiLower(s string) string {
+	lowered := []byte(s)
+	for i, c := range lowered {
+		if c >= 'A' && c <= 'Z' {
+			lowered[i] = c + 'a' - 'A'
+		}
+	}
+	return string(lowered)
+}
+
+func (d *SecretDetector) isAllowed(secret string) bool {
+	for _, pattern := range d.allowlist {
+		if pattern.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShannonEntropy returns the Shannon entropy of the string in bits per character
+func ShannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	frequencies := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		frequencies[r]++
+		total++
+	}
+	var entropy float64
+	for _, count := range frequencies {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// secretSnippet returns the content surrounding the secret, with the secret redacted and whitespace collapsed
+func secretSnippet(text string, start, end int) string {
+	from := int(math.Max(0, float64(start-secretSnippetContext)))
+	to := int(math.Min(float64(len(text)), float64(end+secretSnippetContext)))
+	snippet := text[from:start] + lib.RedactSecret(text[start:end]) + text[end:to]
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	return strings.ToValidUTF8(snippet, "")
+}
+
+var (
+	secretDetector     *SecretDetector
+	secretDetectorErr  error
+	secretDetectorOnce sync.Once
+)
+
+// getSecretDetector builds the secret detector from the passive.checks.secrets config the first time it is used
+func getSecretDetector() (*SecretDetector, error) {
+	secretDetectorOnce.Do(func() {
+		allowlist := append(append([]string{}, DefaultSecretAllowlist...), viper.GetStringSlice("passive.checks.secrets.allowlist")...)
+		secretDetector, secretDetectorErr = NewSecretDetector(DefaultSecretRules(viper.GetFloat64("passive.checks.secrets.min_entropy")), allowlist)
+	})
+	return secretDetector, secretDetectorErr
+}
+
+// SecretsScan reports the secrets found in the response body, using the javascript secrets issue for scripts
+func SecretsScan(item *db.History) {
+	detector, err := getSecretDetector()
+	if err != nil {
+		log.Error().Err(err).Msg("Could not create secret detector")
+		return
+	}
+	matches := detector.Find(item.ResponseBody)
+	if len(matches) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The following secrets have been found in the response:\n")
+	severity := "Info"
+	confidence := 50
+	for _, match := range matches {
+		sb.WriteString(fmt.Sprintf("\n - %s: %s (entropy %.2f)\n   %s", match.Rule, match.Redacted, match.Entropy, match.Snippet))
+		// Lower order means higher severity
+		if db.GetSeverityOrder(match.Severity) < db.GetSeverityOrder(severity) {
+			severity = match.Severity
+		}
+		if match.Rule != GenericSecretRuleName {
+			confidence = 80
+		}
+	}
+	code := db.ExposedApiCredentialsCode
+	if strings.Contains(item.ResponseContentType, "javascript") || strings.Contains(item.ResponseContentType, "ecmascript") {
+		code = db.SecretsInJsCode
+	}
+	db.CreateIssueFromHistoryAndTemplate(item, code, sb.String(), confidence, severity, item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
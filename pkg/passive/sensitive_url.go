@@ -0,0 +1,260 @@
+package passive
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	SensitiveURLValueEmail        = "Email address"
+	SensitiveURLValueCreditCard   = "Credit card number"
+	SensitiveURLValueSessionToken = "Session token"
+)
+
+// maxTrackedSensitiveURLs caps the normalized URLs remembered to avoid reporting the same endpoint repeatedly
+const maxTrackedSensitiveURLs = 10000
+
+// SensitiveURLValue is a likely sensitive value found in the path or query of a URL
+type SensitiveURLValue struct {
+	// Type is the kind of data, either one of the SensitiveURLValue constants or the name of the secret rule
+	Type string
+	// Location is either query or path
+	Location string
+	// Name is the query parameter name, empty for path segments
+	Name     string
+	Value    string
+	Severity string
+	// segment is the raw path segment containing the value
+	segment string
+}
+
+var creditCardCandidateRegex = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+// LuhnValid checks the Luhn checksum of a number, ignoring spaces and dashes
+func LuhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+// isCreditCardNumber checks if the digits have the length and issuer prefix of a known card network and a valid Luhn
+// checksum. The prefix check discards most numeric identifiers and timestamps which pass the checksum by chance.
+func isCreditCardNumber(number string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if len(digits) < 13 || len(digits) > 19 || strings.Count(digits, digits[:1]) == len(digits) {
+		return false
+	}
+	prefix := func(n int) int {
+		value := 0
+		for _, c := range digits[:n] {
+			value = value*10 + int(c-'0')
+		}
+		return value
+	}
+	length := len(digits)
+	var known bool
+	switch {
+	case digits[0] == '4':
+		known = length == 13 || length == 16 || length == 19
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		known = length == 16
+	case prefix(2) == 34, prefix(2) == 37:
+		known = length == 15
+	case prefix(2) == 36, prefix(2) == 38, prefix(3) >= 300 && prefix(3) <= 305:
+		known = length == 14
+	case prefix(4) == 6011, prefix(2) == 65, prefix(3) >= 644 && prefix(3) <= 649, prefix(4) >= 3528 && prefix(4) <= 3589:
+		known = length >= 16
+	}
+	return known && LuhnValid(digits)
+}
+
+// FindSensitiveDataInURL returns the email addresses, credit card numbers, session tokens and secrets found in the
+// query parameters and path segments of the URL. Secrets are only looked for when a detector is provided.
+func FindSensitiveDataInURL(rawURL string, detector *SecretDetector) []SensitiveURLValue {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	var values []SensitiveURLValue
+	seen := make(map[string]bool)
+	add := func(value SensitiveURLValue) {
+		key := value.Type + "|" + value.Value
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		values = append(values, value)
+	}
+	inspect := func(location, name, value string) {
+		if value == "" {
+			return
+		}
+		var segment string
+		if location == "path" {
+			segment = value
+		}
+		for _, email := range emailRegex.FindAllString(value, -1) {
+			add(SensitiveURLValue{SensitiveURLValueEmail, location, name, email, "Low", segment})
+		}
+		for _, number := range creditCardCandidateRegex.FindAllString(value, -1) {
+			if isCreditCardNumber(number) {
+				add(SensitiveURLValue{SensitiveURLValueCreditCard, location, name, lib.RedactSecret(number), "Medium", segment})
+			}
+		}
+		if detector == nil {
+			return
+		}
+		content := value
+		if name != "" {
+			// The parameter name is kept so rules anchored to keywords such as api_key can match
+			content = name + "=" + value
+		}
+		for _, match := range detector.Find([]byte(content)) {
+			add(SensitiveURLValue{match.Rule, location, name, match.Redacted, match.Severity, segment})
+		}
+	}
+
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range query[name] {
+			if sessionTokenRegex.MatchString("?" + name + "=" + value) {
+				add(SensitiveURLValue{SensitiveURLValueSessionToken, "query", name, value, "Medium", ""})
+				continue
+			}
+			inspect("query", name, value)
+		}
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		inspect("path", "", segment)
+	}
+	return values
+}
+
+// sensitiveURLTracker remembers the normalized URLs already reported for each workspace
+type sensitiveURLTracker struct {
+	mu       sync.Mutex
+	reported map[string]bool
+}
+
+var reportedSensitiveURLs = &sensitiveURLTracker{reported: make(map[string]bool)}
+
+// claim returns true the first time the key is seen
+func (t *sensitiveURLTracker) claim(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reported[key] {
+		return false
+	}
+	if len(t.reported) >= maxTrackedSensitiveURLs {
+		t.reported = make(map[string]bool)
+	}
+	t.reported[key] = true
+	return true
+}
+
+// sensitiveURLDedupKey normalizes the URL, also replacing the path segments containing sensitive values, so the
+// requests to the same endpoint with different values are reported once
+func sensitiveURLDedupKey(rawURL string, code db.IssueCode, workspaceID *uint, values []SensitiveURLValue) string {
+	key := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		segments := strings.Split(u.Path, "/")
+		for i, segment := range segments {
+			for _, value := range values {
+				if value.segment != "" && value.segment == segment {
+					segments[i] = "X"
+					break
+				}
+			}
+		}
+		u.Path = strings.Join(segments, "/")
+		key = u.String()
+	}
+	if normalized, err := lib.NormalizeURL(key); err == nil {
+		key = normalized
+	}
+	var workspace uint
+	if workspaceID != nil {
+		workspace = *workspaceID
+	}
+	return fmt.Sprintf("%d:%s:%s", workspace, code, key)
+}
+
+// SensitiveDataInURLScan reports URLs carrying email addresses, credit card numbers, API keys or session tokens in
+// their path or query, as these end up in browser history, server logs and Referer headers
+func SensitiveDataInURLScan(item *db.History) {
+	detector, err := getSecretDetector()
+	if err != nil {
+		log.Error().Err(err).Msg("Could not create secret detector")
+	}
+	values := FindSensitiveDataInURL(item.URL, detector)
+	if len(values) == 0 {
+		return
+	}
+
+	var tokens, others []SensitiveURLValue
+	for _, value := range values {
+		if value.Type == SensitiveURLValueSessionToken {
+			tokens = append(tokens, value)
+		} else {
+			others = append(others, value)
+		}
+	}
+	if len(tokens) > 0 && reportedSensitiveURLs.claim(sensitiveURLDedupKey(item.URL, db.SessionTokenInUrlCode, item.WorkspaceID, tokens)) {
+		var sb strings.Builder
+		sb.WriteString("Discovered session tokens in URL parameters:")
+		for _, token := range tokens {
+			sb.WriteString(fmt.Sprintf("\n - Parameter: %s, Value: %s", token.Name, token.Value))
+		}
+		db.CreateIssueFromHistoryAndTemplate(item, db.SessionTokenInUrlCode, sb.String(), 90, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+	}
+	if len(others) > 0 && reportedSensitiveURLs.claim(sensitiveURLDedupKey(item.URL, db.SensitiveDataInUrlCode, item.WorkspaceID, others)) {
+		var sb strings.Builder
+		sb.WriteString("The following sensitive values have been found in the URL:\n")
+		severity := "Info"
+		for _, value := range others {
+			if value.Location == "query" {
+				sb.WriteString(fmt.Sprintf("\n - %s in the %s query parameter: %s", value.Type, value.Name, value.Value))
+			} else {
+				sb.WriteString(fmt.Sprintf("\n - %s in the path: %s", value.Type, value.Value))
+			}
+			// Lower order means higher severity
+			if db.GetSeverityOrder(value.Severity) < db.GetSeverityOrder(severity) {
+				severity = value.Severity
+			}
+		}
+		db.CreateIssueFromHistoryAndTemplate(item, db.SensitiveDataInUrlCode, sb.String(), 80, severity, item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+	}
+}
@@ -0,0 +1,58 @@
+package passive
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+)
+
+// processedSourceMaps keeps the source maps already analyzed per workspace, as bundles are usually loaded by many pages
+var processedSourceMaps sync.Map
+
+// SourceMapScan fetches the source map of a javascript response and analyzes the original sources it embeds, which
+// expose far more than minified bundles. Secrets found in them are reported and the web URLs they reference returned.
+func SourceMapScan(item *db.History, client *http.Client) []string {
+	if !strings.Contains(item.ResponseContentType, "javascript") && !strings.Contains(item.ResponseContentType, "ecmascript") {
+		return nil
+	}
+	headers, _ := item.GetResponseHeadersAsMap()
+	sourceMapURL, ok := http_utils.GetSourceMappingURL(item.URL, item.ResponseBody, headers)
+	if !ok {
+		return nil
+	}
+	displayURL := sourceMapURL
+	if strings.HasPrefix(sourceMapURL, "data:") {
+		displayURL = "inline source map of " + item.URL
+	}
+	var workspaceID uint
+	if item.WorkspaceID != nil {
+		workspaceID = *item.WorkspaceID
+	}
+	if _, processed := processedSourceMaps.LoadOrStore(fmt.Sprintf("%d:%s", workspaceID, displayURL), true); processed {
+		return nil
+	}
+
+	sourceMap, err := http_utils.FetchSourceMap(client, sourceMapURL)
+	if err != nil {
+		log.Debug().Err(err).Uint("history", item.ID).Str("source_map", displayURL).Msg("Could not fetch source map")
+		return nil
+	}
+
+	var urls []string
+	sources := sourceMap.OriginalSources()
+	for _, source := range sources {
+		for _, secret := range findSecretsInJavascript([]byte(source.Content)) {
+			details := fmt.Sprintf("The secret has been found in the original source %s, reconstructed from the source map %s\n\n%s", source.Path, displayURL, secret.Details)
+			db.CreateIssueFromHistoryAndTemplate(item, db.SecretsInJsCode, details, 90, secret.Severity, item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+		}
+		urls = mergeURLs(urls, ExtractAndAnalyzeURLS(source.Content, item.URL).Web)
+		urls = mergeURLs(urls, ExtractJavascriptEndpoints(source.Content, item.URL))
+	}
+	log.Info().Uint("history", item.ID).Str("source_map", displayURL).Int("sources", len(sources)).Int("urls", len(urls)).Msg("Analyzed javascript source map")
+	return urls
+}
@@ -0,0 +1,114 @@
+package passive
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pyneda/sukyan/db"
+	"github.com/rs/zerolog/log"
+)
+
+// MissingSRIResource is a cross-origin script or stylesheet loaded without Subresource Integrity
+type MissingSRIResource struct {
+	// Type is either script or stylesheet
+	Type    string
+	URL     string
+	Element string
+}
+
+// FindMissingSRIResources returns the scripts and stylesheets of the page which are loaded from a different origin
+// without an integrity attribute. Same-origin resources and the ones not fetched over http(s), such as data or blob
+// URLs, are skipped.
+func FindMissingSRIResources(pageURL string, body []byte) []MissingSRIResource {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	base := page
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if baseURL, err := page.Parse(strings.TrimSpace(href)); err == nil {
+			base = baseURL
+		}
+	}
+
+	var resources []MissingSRIResource
+	seen := make(map[string]bool)
+	check := func(resourceType string, s *goquery.Selection, attr string) {
+		if _, ok := s.Attr("integrity"); ok {
+			return
+		}
+		value, _ := s.Attr(attr)
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return
+		}
+		resourceURL, err := base.Parse(value)
+		if err != nil {
+			return
+		}
+		if resourceURL.Scheme != "http" && resourceURL.Scheme != "https" {
+			return
+		}
+		if sameOrigin(page, resourceURL) || seen[resourceURL.String()] {
+			return
+		}
+		seen[resourceURL.String()] = true
+		html, _ := goquery.OuterHtml(s)
+		resources = append(resources, MissingSRIResource{Type: resourceType, URL: resourceURL.String(), Element: html})
+	}
+	doc.Find("script[src]").Each(func(i int, s *goquery.Selection) {
+		check("script", s, "src")
+	})
+	doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		for _, token := range strings.Fields(rel) {
+			if strings.EqualFold(token, "stylesheet") {
+				check("stylesheet", s, "href")
+				return
+			}
+		}
+	})
+	return resources
+}
+
+// sameOrigin checks if both URLs share scheme, host and port
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Hostname(), b.Hostname()) && originPort(a) == originPort(b)
+}
+
+func originPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// MissingSRIScan reports the cross-origin scripts and stylesheets of HTML responses loaded without Subresource Integrity
+func MissingSRIScan(item *db.History) {
+	resources := FindMissingSRIResources(item.URL, item.ResponseBody)
+	if len(resources) == 0 {
+		return
+	}
+	log.Debug().Str("url", item.URL).Int("resources", len(resources)).Msg("Found cross-origin resources without integrity attribute")
+
+	var sb strings.Builder
+	sb.WriteString("The following cross-origin resources are loaded without an integrity attribute:\n")
+	for _, resource := range resources {
+		sb.WriteString(fmt.Sprintf("\n - %s: %s\n", resource.Type, resource.URL))
+		sb.WriteString(fmt.Sprintf("   Element: %s\n", resource.Element))
+	}
+	db.CreateIssueFromHistoryAndTemplate(item, db.MissingSriCode, sb.String(), 90, "", item.WorkspaceID, item.TaskID, &defaultTaskJobID)
+}
@@ -0,0 +1,53 @@
+package passive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindMissingSRIResources(t *testing.T) {
+	body := `<html><head>
+<script src="/static/app.js"></script>
+<script src="https://example.com/static/vendor.js"></script>
+<script src="https://cdn.jsdelivr.net/npm/jquery@3.7.1/dist/jquery.min.js"></script>
+<script src="https://cdn.jsdelivr.net/npm/lodash@4.17.21/lodash.min.js" integrity="sha384-abc" crossorigin="anonymous"></script>
+<script src="//analytics.example.org/tag.js"></script>
+<script src="https://example.com:8443/other-port.js"></script>
+<script src="data:text/javascript,alert(1)"></script>
+<script src="blob:https://example.com/8f3c1f0e-6b1a-4f5e-9d2b-1c0d7e6f5a4b"></script>
+<script>console.log("inline")</script>
+<link rel="stylesheet" href="styles/main.css">
+<link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Roboto">
+<link rel="alternate stylesheet" href="https://cdn.example.net/theme.css">
+<link rel="stylesheet" href="https://cdn.example.net/secured.css" integrity="sha384-def">
+<link rel="icon" href="https://cdn.example.net/favicon.ico">
+<link rel="preconnect" href="https://fonts.gstatic.com">
+</head><body>
+<script src="https://cdn.jsdelivr.net/npm/jquery@3.7.1/dist/jquery.min.js"></script>
+</body></html>`
+
+	resources := FindMissingSRIResources("https://example.com/index.html", []byte(body))
+	var urls []string
+	for _, resource := range resources {
+		urls = append(urls, resource.Type+" "+resource.URL)
+	}
+	assert.Equal(t, []string{
+		"script https://cdn.jsdelivr.net/npm/jquery@3.7.1/dist/jquery.min.js",
+		"script https://analytics.example.org/tag.js",
+		"script https://example.com:8443/other-port.js",
+		"stylesheet https://fonts.googleapis.com/css2?family=Roboto",
+		"stylesheet https://cdn.example.net/theme.css",
+	}, urls)
+	assert.Contains(t, resources[0].Element, "<script")
+}
+
+func TestFindMissingSRIResourcesBaseElement(t *testing.T) {
+	body := `<html><head><base href="https://static.example.net/"><script src="app.js"></script></head></html>`
+	resources := FindMissingSRIResources("https://example.com/", []byte(body))
+	if assert.Len(t, resources, 1) {
+		assert.Equal(t, "https://static.example.net/app.js", resources[0].URL)
+	}
+
+	assert.Empty(t, FindMissingSRIResources("http://example.com/", []byte(`<script src="http://EXAMPLE.com:80/app.js"></script>`)))
+}
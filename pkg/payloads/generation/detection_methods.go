@@ -39,9 +39,30 @@ func (dm *DetectionMethod) GetMethod() interface{} {
 	return nil
 }
 
+// GetIssueOverride returns the issue indicated by the method when it matches, or an empty code when it indicates the
+// issue of the payload
+func (dm *DetectionMethod) GetIssueOverride() db.IssueCode {
+	switch m := dm.GetMethod().(type) {
+	case *OOBInteractionDetectionMethod:
+		return m.IssueOverride
+	case *ResponseConditionDetectionMethod:
+		return m.IssueOverride
+	case *ReflectionDetectionMethod:
+		return m.IssueOverride
+	case *BrowserEventsDetectionMethod:
+		return m.IssueOverride
+	case *TimeBasedDetectionMethod:
+		return m.IssueOverride
+	case *ResponseCheckDetectionMethod:
+		return m.IssueOverride
+	}
+	return ""
+}
+
 type OOBInteractionDetectionMethod struct {
-	OOBAddress string `yaml:"oob_address"`
-	Confidence int    `yaml:"confidence,omitempty"`
+	OOBAddress    string       `yaml:"oob_address"`
+	Confidence    int          `yaml:"confidence,omitempty"`
+	IssueOverride db.IssueCode `yaml:"issue_override,omitempty"`
 }
 
 type ResponseContainsPart string
@@ -53,33 +74,36 @@ const (
 )
 
 type ResponseConditionDetectionMethod struct {
-	Contains   string               `yaml:"contains,omitempty"`
-	Part       ResponseContainsPart `yaml:"part,omitempty"`
-	StatusCode int                  `yaml:"status_code,omitempty"`
-	Confidence int                  `yaml:"confidence,omitempty"`
-	// TODO: Add support for the issue override
-	IssueOverride db.IssueCode `yaml:"issue_override,omitempty"`
+	Contains      string               `yaml:"contains,omitempty"`
+	Part          ResponseContainsPart `yaml:"part,omitempty"`
+	StatusCode    int                  `yaml:"status_code,omitempty"`
+	Confidence    int                  `yaml:"confidence,omitempty"`
+	IssueOverride db.IssueCode         `yaml:"issue_override,omitempty"`
 }
 
 type ResponseCheckDetectionMethod struct {
-	Check      ResponseConditionCheck `yaml:"check"`
-	Confidence int                    `yaml:"confidence,omitempty"`
+	Check         ResponseConditionCheck `yaml:"check"`
+	Confidence    int                    `yaml:"confidence,omitempty"`
+	IssueOverride db.IssueCode           `yaml:"issue_override,omitempty"`
 }
 
 type ReflectionDetectionMethod struct {
-	Value      string `yaml:"value,omitempty"`
-	Confidence int    `yaml:"confidence,omitempty"`
+	Value         string       `yaml:"value,omitempty"`
+	Confidence    int          `yaml:"confidence,omitempty"`
+	IssueOverride db.IssueCode `yaml:"issue_override,omitempty"`
 }
 
 type BrowserEventsDetectionMethod struct {
-	Event      string `yaml:"event"`
-	Value      string `yaml:"value"`
-	Confidence int    `yaml:"confidence,omitempty"`
+	Event         string       `yaml:"event"`
+	Value         string       `yaml:"value"`
+	Confidence    int          `yaml:"confidence,omitempty"`
+	IssueOverride db.IssueCode `yaml:"issue_override,omitempty"`
 }
 
 type TimeBasedDetectionMethod struct {
-	Sleep      string `yaml:"sleep"`
-	Confidence int    `yaml:"confidence,omitempty"`
+	Sleep         string       `yaml:"sleep"`
+	Confidence    int          `yaml:"confidence,omitempty"`
+	IssueOverride db.IssueCode `yaml:"issue_override,omitempty"`
 }
 
 func (t *TimeBasedDetectionMethod) ParseSleepDuration(sleep string) time.Duration {
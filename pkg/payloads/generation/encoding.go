@@ -0,0 +1,146 @@
+package generation
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PayloadEncoding is an encoding that can be applied to the payloads to bypass filters
+type PayloadEncoding string
+
+const (
+	URLEncoding        PayloadEncoding = "url"
+	DoubleURLEncoding  PayloadEncoding = "double-url"
+	HTMLEntityEncoding PayloadEncoding = "html-entity"
+	UnicodeEncoding    PayloadEncoding = "unicode"
+	Base64Encoding     PayloadEncoding = "base64"
+)
+
+var payloadEncoders = map[PayloadEncoding]func(string) string{
+	URLEncoding:        urlEncodePayload,
+	DoubleURLEncoding:  func(value string) string { return urlEncodePayload(urlEncodePayload(value)) },
+	HTMLEntityEncoding: htmlEntityEncodePayload,
+	UnicodeEncoding:    unicodeEscapePayload,
+	Base64Encoding:     func(value string) string { return base64.StdEncoding.EncodeToString([]byte(value)) },
+}
+
+// IsValid checks if the encoding is supported
+func (e PayloadEncoding) IsValid() bool {
+	_, ok := payloadEncoders[e]
+	return ok
+}
+
+// Encode returns the value encoded with the encoding
+func (e PayloadEncoding) Encode(value string) (string, error) {
+	encoder, ok := payloadEncoders[e]
+	if !ok {
+		return "", fmt.Errorf("unknown payload encoding %s", e)
+	}
+	return encoder(value), nil
+}
+
+// EncodingChain is a sequence of encodings applied in order, so that [base64, url] url encodes the base64 encoded
+// payload. In generator files it can be written as a single encoding or as a list of them.
+type EncodingChain []PayloadEncoding
+
+// UnmarshalYAML allows declaring a chain of a single encoding as a plain string
+func (c *EncodingChain) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*c = EncodingChain{PayloadEncoding(value.Value)}
+		return nil
+	}
+	var encodings []PayloadEncoding
+	if err := value.Decode(&encodings); err != nil {
+		return err
+	}
+	*c = encodings
+	return nil
+}
+
+// Validate checks that the chain is not empty and only contains supported encodings
+func (c EncodingChain) Validate() error {
+	if len(c) == 0 {
+		return fmt.Errorf("empty encoding chain")
+	}
+	for _, encoding := range c {
+		if !encoding.IsValid() {
+			return fmt.Errorf("unknown payload encoding %s", encoding)
+		}
+	}
+	return nil
+}
+
+// Encode applies the encodings of the chain in order
+func (c EncodingChain) Encode(value string) (string, error) {
+	for _, encoding := range c {
+		encoded, err := encoding.Encode(value)
+		if err != nil {
+			return "", err
+		}
+		value = encoded
+	}
+	return value, nil
+}
+
+func (c EncodingChain) String() string {
+	names := make([]string, len(c))
+	for i, encoding := range c {
+		names[i] = string(encoding)
+	}
+	return strings.Join(names, " > ")
+}
+
+func isAlphanumeric(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// urlEncodePayload percent encodes every byte but the unreserved characters, which is more aggressive than the query
+// escaping of net/url, as spaces are encoded as %20 and characters such as / or : are also encoded
+func urlEncodePayload(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isAlphanumeric(rune(c)) || c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return sb.String()
+}
+
+// htmlEntityEncodePayload encodes every character but the alphanumeric ones as hexadecimal numeric character references
+func htmlEntityEncodePayload(value string) string {
+	var sb strings.Builder
+	for _, c := range value {
+		if isAlphanumeric(c) {
+			sb.WriteRune(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("&#x%x;", c))
+		}
+	}
+	return sb.String()
+}
+
+// unicodeEscapePayload encodes every character but the alphanumeric ones as \uXXXX escape sequences, using surrogate
+// pairs for the characters outside the basic multilingual plane as done by javascript and JSON
+func unicodeEscapePayload(value string) string {
+	var sb strings.Builder
+	for _, c := range value {
+		if isAlphanumeric(c) {
+			sb.WriteRune(c)
+			continue
+		}
+		if c > 0xFFFF {
+			high, low := utf16.EncodeRune(c)
+			sb.WriteString(fmt.Sprintf("\\u%04x\\u%04x", high, low))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\\u%04x", c))
+	}
+	return sb.String()
+}
@@ -0,0 +1,117 @@
+package generation
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPayloadEncodings(t *testing.T) {
+	payload := `"><svg/onload=alert(1)>`
+	tests := []struct {
+		encoding PayloadEncoding
+		expected string
+	}{
+		{URLEncoding, "%22%3E%3Csvg%2Fonload%3Dalert%281%29%3E"},
+		{DoubleURLEncoding, "%2522%253E%253Csvg%252Fonload%253Dalert%25281%2529%253E"},
+		{HTMLEntityEncoding, "&#x22;&#x3e;&#x3c;svg&#x2f;onload&#x3d;alert&#x28;1&#x29;&#x3e;"},
+		{UnicodeEncoding, `\u0022\u003e\u003csvg\u002fonload\u003dalert\u00281\u0029\u003e`},
+		{Base64Encoding, "Ij48c3ZnL29ubG9hZD1hbGVydCgxKT4="},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.encoding), func(t *testing.T) {
+			assert.True(t, tt.encoding.IsValid())
+			encoded, err := tt.encoding.Encode(payload)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, encoded)
+		})
+	}
+
+	_, err := PayloadEncoding("rot13").Encode(payload)
+	assert.Error(t, err)
+}
+
+func TestPayloadEncodingsNonASCII(t *testing.T) {
+	encoded, _ := URLEncoding.Encode("a b-é")
+	assert.Equal(t, "a%20b-%C3%A9", encoded)
+	encoded, _ = HTMLEntityEncoding.Encode("é😀")
+	assert.Equal(t, "&#xe9;&#x1f600;", encoded)
+	encoded, _ = UnicodeEncoding.Encode("é😀")
+	assert.Equal(t, `\u00e9\ud83d\ude00`, encoded)
+}
+
+func TestEncodingChain(t *testing.T) {
+	chain := EncodingChain{Base64Encoding, URLEncoding}
+	assert.NoError(t, chain.Validate())
+	encoded, err := chain.Encode("<x>?")
+	assert.NoError(t, err)
+	// base64 of <x>? is PHg+Pw==, whose + and = are url encoded afterwards
+	assert.Equal(t, "PHg%2BPw%3D%3D", encoded)
+	assert.Equal(t, "base64 > url", chain.String())
+
+	encoded, err = EncodingChain{URLEncoding, HTMLEntityEncoding}.Encode("<")
+	assert.NoError(t, err)
+	assert.Equal(t, "&#x25;3C", encoded)
+
+	assert.Error(t, EncodingChain{}.Validate())
+	assert.Error(t, EncodingChain{URLEncoding, "rot13"}.Validate())
+}
+
+func TestEncodingChainUnmarshalYAML(t *testing.T) {
+	var generator PayloadGenerator
+	err := yaml.Unmarshal([]byte(`
+id: encoded
+issue_code: xss_reflected
+templates:
+  - "<script>"
+encodings:
+  - url
+  - [base64, url]
+`), &generator)
+	assert.NoError(t, err)
+	assert.Equal(t, []EncodingChain{{URLEncoding}, {Base64Encoding, URLEncoding}}, generator.Encodings)
+	assert.NoError(t, generator.Validate())
+
+	generator.Encodings = append(generator.Encodings, EncodingChain{"rot13"})
+	assert.Error(t, generator.Validate())
+}
+
+func TestPayloadEncodedVariants(t *testing.T) {
+	payload := Payload{IssueCode: "xss_reflected", Value: "<script>"}
+	variants, err := payload.EncodedVariants([]EncodingChain{{URLEncoding}, {Base64Encoding, URLEncoding}})
+	assert.NoError(t, err)
+	assert.Len(t, variants, 2)
+
+	assert.Equal(t, "%3Cscript%3E", variants[0].Value)
+	assert.Equal(t, "<script>", variants[0].RawValue)
+	assert.Equal(t, "xss_reflected", variants[0].IssueCode)
+	assert.True(t, variants[0].IsEncoded())
+	assert.Equal(t, "%3Cscript%3E (url encoding of <script>)", variants[0].Description())
+
+	assert.Equal(t, "PHNjcmlwdD4%3D", variants[1].Value)
+	assert.Equal(t, "<script>", variants[1].UnencodedValue())
+
+	assert.False(t, payload.IsEncoded())
+	assert.Equal(t, "<script>", payload.Description())
+}
+
+func TestBuildPayloadsWithEncodings(t *testing.T) {
+	generator := PayloadGenerator{
+		ID:        "encoded",
+		IssueCode: "xss_reflected",
+		Templates: []string{"<script>"},
+		Encodings: []EncodingChain{{URLEncoding}, {HTMLEntityEncoding}},
+	}
+	payloads, err := generator.BuildPayloads(integrations.InteractionsManager{})
+	assert.NoError(t, err)
+	assert.Len(t, payloads, 3)
+	assert.Equal(t, "<script>", payloads[0].Value)
+	assert.False(t, payloads[0].IsEncoded())
+	assert.Equal(t, "%3Cscript%3E", payloads[1].Value)
+	assert.Equal(t, "&#x3c;script&#x3e;", payloads[2].Value)
+	for _, payload := range payloads {
+		assert.Equal(t, "<script>", payload.UnencodedValue())
+	}
+}
@@ -6,12 +6,16 @@ import (
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/lib/integrations"
 	"github.com/rs/zerolog/log"
+	"sort"
 	"text/template"
 )
 
 type PayloadGenerator struct {
-	ID                 string            `yaml:"id,omitempty"` // Since it's not used yet, allow it to be empty
-	IssueCode          string            `yaml:"issue_code"`
+	ID        string      `yaml:"id,omitempty"` // Since it's not used yet, allow it to be empty
+	Type      PayloadType `yaml:"type,omitempty"`
+	IssueCode string      `yaml:"issue_code"`
+	// IssueCodes are the issues a polyglot payload can indicate, detection methods set the one they detect
+	IssueCodes         []string          `yaml:"issue_codes,omitempty"`
 	DetectionCondition Operator          `yaml:"detection_condition"`
 	DetectionMethods   []DetectionMethod `yaml:"detection_methods"`
 	Launch             LaunchConditions  `yaml:"launch,omitempty"`
@@ -19,6 +23,8 @@ type PayloadGenerator struct {
 	Templates          []string          `yaml:"templates"`
 	Categories         []string          `yaml:"categories"`
 	Platforms          []string          `yaml:"platforms"`
+	// Encodings are the encoding chains applied to each payload, which are sent in addition to the unencoded one
+	Encodings []EncodingChain `yaml:"encodings,omitempty"`
 }
 
 func (generator *PayloadGenerator) BuildPayloads(interactionsManager integrations.InteractionsManager) ([]Payload, error) {
@@ -51,16 +57,28 @@ func (generator *PayloadGenerator) BuildPayloads(interactionsManager integration
 				Value: v,
 			})
 		}
+		sort.Slice(processedPayloadVars, func(i, j int) bool {
+			return processedPayloadVars[i].Name < processedPayloadVars[j].Name
+		})
 
-		payloads = append(payloads, Payload{
+		payload := Payload{
+			Type:               generator.Type,
 			IssueCode:          generator.IssueCode,
+			IssueCodes:         generator.IssueCodes,
 			Value:              result,
+			RawValue:           result,
 			Vars:               processedPayloadVars,
 			DetectionCondition: generator.DetectionCondition,
 			DetectionMethods:   processedDetectionMethods,
 			Categories:         generator.Categories,
 			InteractionDomain:  interactionDomain,
-		})
+		}
+		payloads = append(payloads, payload)
+		encoded, err := payload.EncodedVariants(generator.Encodings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode payload: %v", err)
+		}
+		payloads = append(payloads, encoded...)
 	}
 	return payloads, nil
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,6 +17,8 @@ const (
 	ParameterValueDataType LaunchConditionType = "parameter_value_data_type"
 	ParameterName          LaunchConditionType = "insertion_point_name"
 	ResponseCondition      LaunchConditionType = "response_condition"
+	ResponseContentType    LaunchConditionType = "response_content_type"
+	TechStack              LaunchConditionType = "tech_stack"
 )
 
 type LaunchCondition struct {
@@ -25,6 +28,36 @@ type LaunchCondition struct {
 	ParameterNames    []string                          `yaml:"parameter_names,omitempty"`
 }
 
+// MatchesContentType checks if a response content type matches the condition value. The value can be a full media
+// type, such as application/json, or a part of it, such as json, to also match types like application/vnd.api+json
+func (lc *LaunchCondition) MatchesContentType(contentType string) bool {
+	value := strings.ToLower(strings.TrimSpace(lc.Value))
+	if value == "" || contentType == "" {
+		return false
+	}
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	if strings.Contains(value, "/") {
+		return mediaType == value
+	}
+	return strings.Contains(mediaType, value)
+}
+
+// MatchesTechStack checks if the condition value is the name of any of the technologies fingerprinted in the target,
+// such as WordPress, ignoring case
+func (lc *LaunchCondition) MatchesTechStack(fingerprints []lib.Fingerprint) bool {
+	value := strings.TrimSpace(lc.Value)
+	if value == "" {
+		return false
+	}
+	for _, fingerprint := range fingerprints {
+		if strings.EqualFold(fingerprint.Name, value) {
+			return true
+		}
+	}
+	return false
+}
+
 type ResponseConditionLaunchCondition struct {
 	Contains   string               `yaml:"contains,omitempty"`
 	Part       ResponseContainsPart `yaml:"part,omitempty"`
@@ -0,0 +1,47 @@
+package generation
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLaunchConditionMatchesContentType(t *testing.T) {
+	tests := []struct {
+		value       string
+		contentType string
+		expected    bool
+	}{
+		{"application/json", "application/json", true},
+		{"application/json", "Application/JSON; charset=utf-8", true},
+		{"application/json", "application/vnd.api+json", false},
+		{"json", "application/vnd.api+json", true},
+		{"xml", "text/xml; charset=utf-8", true},
+		{"xml", "application/soap+xml", true},
+		{"xml", "text/html", false},
+		{"json", "", false},
+		{"", "application/json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.contentType, func(t *testing.T) {
+			condition := LaunchCondition{Type: ResponseContentType, Value: tt.value}
+			assert.Equal(t, tt.expected, condition.MatchesContentType(tt.contentType))
+		})
+	}
+}
+
+func TestLaunchConditionMatchesTechStack(t *testing.T) {
+	fingerprints := []lib.Fingerprint{{Name: "Nginx", Version: "1.25"}, {Name: "WordPress", Version: "6.4"}}
+
+	condition := LaunchCondition{Type: TechStack, Value: "wordpress"}
+	assert.True(t, condition.MatchesTechStack(fingerprints))
+	assert.False(t, condition.MatchesTechStack(nil))
+
+	condition.Value = "Drupal"
+	assert.False(t, condition.MatchesTechStack(fingerprints))
+
+	condition.Value = ""
+	assert.False(t, condition.MatchesTechStack(fingerprints))
+}
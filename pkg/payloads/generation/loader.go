@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/rs/zerolog/log"
@@ -26,6 +27,9 @@ func loadGenerator(filePath string) (*PayloadGenerator, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := pg.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &pg, nil
 }
@@ -41,6 +45,9 @@ func loadGeneratorFromFS(fs embed.FS, path string) (*PayloadGenerator, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := pg.Validate(); err != nil {
+		return nil, err
+	}
 	return &pg, nil
 }
 
@@ -136,5 +143,8 @@ func mergeGenerators(local, user []*PayloadGenerator) []*PayloadGenerator {
 	for _, v := range mappedGenerators {
 		combined = append(combined, v)
 	}
+	sort.Slice(combined, func(i, j int) bool {
+		return combined[i].ID < combined[j].ID
+	})
 	return combined
 }
@@ -6,18 +6,65 @@ import (
 )
 
 type Payload struct {
+	Type               PayloadType       `yaml:"type,omitempty"`
 	IssueCode          string            `yaml:"issue_code"`
+	IssueCodes         []string          `yaml:"issue_codes,omitempty"`
 	Value              string            `yaml:"value"`
 	Vars               []PayloadVariable `yaml:"vars,omitempty"`
 	DetectionCondition Operator          `yaml:"detection_condition"`
 	DetectionMethods   []DetectionMethod `yaml:"detection_methods"`
 	Categories         []string          `yaml:"categories"`
 	InteractionDomain  integrations.InteractionDomain
+	// RawValue is the payload before applying the encoding chain, which the detection methods are rendered from
+	RawValue string        `yaml:"raw_value,omitempty"`
+	Encoding EncodingChain `yaml:"encoding,omitempty"`
+}
+
+// UnencodedValue returns the payload before applying the encoding chain
+func (payload *Payload) UnencodedValue() string {
+	if payload.RawValue == "" {
+		return payload.Value
+	}
+	return payload.RawValue
+}
+
+// EncodedVariants returns a copy of the payload for each of the encoding chains, with the encoded value
+func (payload *Payload) EncodedVariants(chains []EncodingChain) ([]Payload, error) {
+	raw := payload.UnencodedValue()
+	variants := make([]Payload, 0, len(chains))
+	for _, chain := range chains {
+		value, err := chain.Encode(raw)
+		if err != nil {
+			return nil, err
+		}
+		variant := *payload
+		variant.Value = value
+		variant.RawValue = raw
+		variant.Encoding = chain
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// IsEncoded returns true if an encoding chain has been applied to the payload
+func (payload *Payload) IsEncoded() bool {
+	return len(payload.Encoding) > 0
+}
+
+// Description returns the payload value, along with the encodings applied to it
+func (payload *Payload) Description() string {
+	if !payload.IsEncoded() {
+		return payload.Value
+	}
+	return fmt.Sprintf("%s (%s encoding of %s)", payload.Value, payload.Encoding, payload.RawValue)
 }
 
 func (payload *Payload) Print() {
 	fmt.Printf("Payload:\n")
 	fmt.Println(payload.Value)
+	if payload.IsEncoded() {
+		fmt.Printf("\nEncoding: %s\nRaw payload: %s\n", payload.Encoding, payload.RawValue)
+	}
 	fmt.Println("\nDetection Methods:")
 	for _, dm := range payload.DetectionMethods {
 		fmt.Println(dm.GetMethod())
@@ -0,0 +1,151 @@
+package generation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyneda/sukyan/lib"
+)
+
+type PayloadType string
+
+const (
+	// Polyglot payloads are valid in multiple injection contexts, so a single request can detect several issues
+	Polyglot PayloadType = "polyglot"
+)
+
+// DetectionMethodResult is the outcome of evaluating a detection method against the response to a payload
+type DetectionMethodResult struct {
+	Method      DetectionMethod
+	Matched     bool
+	Description string
+	Confidence  int
+}
+
+// IssueDetection is an issue indicated by the detection methods of a payload
+type IssueDetection struct {
+	IssueCode  string
+	Details    string
+	Confidence int
+}
+
+// Validate checks that the encoding chains of the generator are supported and that the issues a polyglot generator can
+// indicate are defined and that its detection methods only attribute detections to them
+func (generator *PayloadGenerator) Validate() error {
+	if generator.Type != "" && generator.Type != Polyglot {
+		return fmt.Errorf("generator %s has an unknown type %s", generator.ID, generator.Type)
+	}
+	for _, chain := range generator.Encodings {
+		if err := chain.Validate(); err != nil {
+			return fmt.Errorf("generator %s has an invalid encoding chain: %v", generator.ID, err)
+		}
+	}
+	if generator.Type != Polyglot {
+		return nil
+	}
+	if len(generator.IssueCodes) < 2 {
+		return fmt.Errorf("polyglot generator %s should define at least two issue codes", generator.ID)
+	}
+	if generator.IssueCode != "" && !lib.SliceContains(generator.IssueCodes, generator.IssueCode) {
+		return fmt.Errorf("polyglot generator %s issue code %s is not one of its issue codes", generator.ID, generator.IssueCode)
+	}
+	for _, method := range generator.DetectionMethods {
+		override := string(method.GetIssueOverride())
+		if override == "" && generator.IssueCode == "" {
+			return fmt.Errorf("polyglot generator %s has detection methods without issue override and no default issue code", generator.ID)
+		}
+		if override != "" && !lib.SliceContains(generator.IssueCodes, override) {
+			return fmt.Errorf("polyglot generator %s detection method issue override %s is not one of its issue codes", generator.ID, override)
+		}
+	}
+	return nil
+}
+
+// IsPolyglot returns true if the payload can indicate multiple issues
+func (payload *Payload) IsPolyglot() bool {
+	return payload.Type == Polyglot
+}
+
+// IssueCodeForDetectionMethod returns the issue indicated when the detection method matches
+func (payload *Payload) IssueCodeForDetectionMethod(method DetectionMethod) string {
+	if override := method.GetIssueOverride(); override != "" {
+		return string(override)
+	}
+	if payload.IssueCode == "" && len(payload.IssueCodes) > 0 {
+		return payload.IssueCodes[0]
+	}
+	return payload.IssueCode
+}
+
+// GetIssueCodes returns all the issues the payload can indicate
+func (payload *Payload) GetIssueCodes() []string {
+	var codes []string
+	add := func(code string) {
+		if code != "" && !lib.SliceContains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	add(payload.IssueCode)
+	for _, code := range payload.IssueCodes {
+		add(code)
+	}
+	for _, method := range payload.DetectionMethods {
+		add(payload.IssueCodeForDetectionMethod(method))
+	}
+	return codes
+}
+
+// AttributeDetections groups the results of the detection methods by the issue each one indicates and returns the
+// issues detected, applying the detection condition of the payload to the methods of each issue separately
+func (payload *Payload) AttributeDetections(results []DetectionMethodResult) []IssueDetection {
+	var codes []string
+	grouped := make(map[string][]DetectionMethodResult)
+	for _, result := range results {
+		code := payload.IssueCodeForDetectionMethod(result.Method)
+		if _, ok := grouped[code]; !ok {
+			codes = append(codes, code)
+		}
+		grouped[code] = append(grouped[code], result)
+	}
+
+	detections := []IssueDetection{}
+	for _, code := range codes {
+		matched := 0
+		confidence := 0
+		var sb strings.Builder
+		for _, result := range grouped[code] {
+			if !result.Matched {
+				continue
+			}
+			matched++
+			if result.Confidence > confidence {
+				confidence = result.Confidence
+			}
+			if result.Description != "" {
+				sb.WriteString(result.Description + "\n")
+			}
+		}
+		detected := matched > 0
+		if payload.DetectionCondition == And {
+			detected = matched == len(grouped[code])
+		}
+		if detected {
+			detections = append(detections, IssueDetection{
+				IssueCode:  code,
+				Details:    sb.String(),
+				Confidence: confidence,
+			})
+		}
+	}
+	return detections
+}
+
+// OOBInteractionIssueCode returns the issue indicated by an interaction with the out of band address of the payload
+func (payload *Payload) OOBInteractionIssueCode() string {
+	for _, method := range payload.DetectionMethods {
+		if method.OOBInteraction != nil {
+			return payload.IssueCodeForDetectionMethod(method)
+		}
+	}
+	return payload.IssueCodeForDetectionMethod(DetectionMethod{})
+}
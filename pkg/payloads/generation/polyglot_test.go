@@ -0,0 +1,172 @@
+package generation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPolyglotPayload(condition Operator) Payload {
+	return Payload{
+		Value:              "'\"><skabc>${7*7}",
+		Type:               Polyglot,
+		IssueCode:          "xss_reflected",
+		IssueCodes:         []string{"xss_reflected", "sql_injection", "ssti"},
+		DetectionCondition: condition,
+		DetectionMethods: []DetectionMethod{
+			{Reflection: &ReflectionDetectionMethod{Value: "<skabc>", Confidence: 50}},
+			{ResponseCheck: &ResponseCheckDetectionMethod{Check: DatabaseErrorCondition, Confidence: 80, IssueOverride: "sql_injection"}},
+			{ResponseCondition: &ResponseConditionDetectionMethod{Contains: "49", Confidence: 60, IssueOverride: "ssti"}},
+			{ResponseCondition: &ResponseConditionDetectionMethod{StatusCode: 500, Confidence: 40, IssueOverride: "ssti"}},
+		},
+	}
+}
+
+func TestAttributeDetections(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Operator
+		matched   []bool
+		expected  []IssueDetection
+	}{
+		{
+			name:      "Nothing matched",
+			condition: Or,
+			matched:   []bool{false, false, false, false},
+			expected:  []IssueDetection{},
+		},
+		{
+			name:      "Default issue code",
+			condition: Or,
+			matched:   []bool{true, false, false, false},
+			expected: []IssueDetection{
+				{IssueCode: "xss_reflected", Details: "method 0\n", Confidence: 50},
+			},
+		},
+		{
+			name:      "Override issue codes",
+			condition: Or,
+			matched:   []bool{false, true, false, true},
+			expected: []IssueDetection{
+				{IssueCode: "sql_injection", Details: "method 1\n", Confidence: 80},
+				{IssueCode: "ssti", Details: "method 3\n", Confidence: 40},
+			},
+		},
+		{
+			name:      "Multiple methods of the same issue",
+			condition: Or,
+			matched:   []bool{true, false, true, true},
+			expected: []IssueDetection{
+				{IssueCode: "xss_reflected", Details: "method 0\n", Confidence: 50},
+				{IssueCode: "ssti", Details: "method 2\nmethod 3\n", Confidence: 60},
+			},
+		},
+		{
+			name:      "And condition applied per issue",
+			condition: And,
+			matched:   []bool{true, true, true, false},
+			expected: []IssueDetection{
+				{IssueCode: "xss_reflected", Details: "method 0\n", Confidence: 50},
+				{IssueCode: "sql_injection", Details: "method 1\n", Confidence: 80},
+			},
+		},
+		{
+			name:      "And condition with all methods of an issue matched",
+			condition: And,
+			matched:   []bool{false, false, true, true},
+			expected: []IssueDetection{
+				{IssueCode: "ssti", Details: "method 2\nmethod 3\n", Confidence: 60},
+			},
+		},
+	}
+
+	confidences := []int{50, 80, 60, 40}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := newTestPolyglotPayload(tt.condition)
+			var results []DetectionMethodResult
+			for i, method := range payload.DetectionMethods {
+				result := DetectionMethodResult{Method: method, Matched: tt.matched[i]}
+				if tt.matched[i] {
+					result.Description = "method " + string(rune('0'+i))
+					result.Confidence = confidences[i]
+				}
+				results = append(results, result)
+			}
+			assert.Equal(t, tt.expected, payload.AttributeDetections(results))
+		})
+	}
+}
+
+func TestPayloadIssueCodes(t *testing.T) {
+	payload := newTestPolyglotPayload(Or)
+	assert.True(t, payload.IsPolyglot())
+	assert.Equal(t, []string{"xss_reflected", "sql_injection", "ssti"}, payload.GetIssueCodes())
+	assert.Equal(t, "xss_reflected", payload.OOBInteractionIssueCode())
+
+	payload.DetectionMethods = append(payload.DetectionMethods, DetectionMethod{
+		OOBInteraction: &OOBInteractionDetectionMethod{OOBAddress: "abc.oast.fun", IssueOverride: "ssti"},
+	})
+	assert.Equal(t, "ssti", payload.OOBInteractionIssueCode())
+
+	payload.IssueCode = ""
+	assert.Equal(t, "xss_reflected", payload.IssueCodeForDetectionMethod(payload.DetectionMethods[0]))
+	assert.Equal(t, "sql_injection", payload.IssueCodeForDetectionMethod(payload.DetectionMethods[1]))
+
+	single := Payload{IssueCode: "sql_injection"}
+	assert.False(t, single.IsPolyglot())
+	assert.Equal(t, []string{"sql_injection"}, single.GetIssueCodes())
+}
+
+func TestPolyglotGeneratorValidate(t *testing.T) {
+	valid := func() *PayloadGenerator {
+		payload := newTestPolyglotPayload(Or)
+		return &PayloadGenerator{
+			ID:               "polyglot",
+			Type:             Polyglot,
+			IssueCode:        payload.IssueCode,
+			IssueCodes:       payload.IssueCodes,
+			DetectionMethods: payload.DetectionMethods,
+		}
+	}
+
+	assert.NoError(t, valid().Validate())
+	assert.NoError(t, (&PayloadGenerator{ID: "single", IssueCode: "sql_injection"}).Validate())
+
+	generator := valid()
+	generator.Type = "unknown"
+	assert.Error(t, generator.Validate())
+
+	generator = valid()
+	generator.IssueCodes = []string{"xss_reflected"}
+	assert.Error(t, generator.Validate())
+
+	generator = valid()
+	generator.IssueCode = "xxe"
+	assert.Error(t, generator.Validate())
+
+	generator = valid()
+	generator.IssueCode = ""
+	assert.Error(t, generator.Validate())
+
+	generator = valid()
+	generator.DetectionMethods[1].ResponseCheck.IssueOverride = "xxe"
+	assert.Error(t, generator.Validate())
+}
+
+func TestLoadPolyglotGenerators(t *testing.T) {
+	generators, err := LoadLocalGenerators()
+	assert.NoError(t, err)
+
+	var polyglots []*PayloadGenerator
+	for _, generator := range generators {
+		if generator.Type == Polyglot {
+			polyglots = append(polyglots, generator)
+		}
+	}
+	assert.NotEmpty(t, polyglots)
+	for _, generator := range polyglots {
+		assert.NoError(t, generator.Validate())
+		assert.GreaterOrEqual(t, len(generator.IssueCodes), 2)
+	}
+}
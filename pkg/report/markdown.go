@@ -0,0 +1,67 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+func generateMarkdownReport(options ReportOptions, w io.Writer) error {
+	return renderMarkdownReport(BuildReport(options), w)
+}
+
+func renderMarkdownReport(report *Report, w io.Writer) error {
+	funcMap := template.FuncMap{
+		"codeBlock": markdownCodeBlock,
+		"codeSpan":  markdownCodeSpan,
+		"inline":    markdownInline,
+	}
+	tmpl, err := template.New("report.md.tmpl").Funcs(funcMap).ParseFS(templates, "templates/report.md.tmpl")
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, report)
+}
+
+// longestBacktickRun returns the length of the longest sequence of backticks in the content
+func longestBacktickRun(content string) int {
+	longest := 0
+	current := 0
+	for _, c := range content {
+		if c == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
+
+// markdownCodeBlock wraps the content in a fenced code block, using a fence longer than any backtick sequence found in
+// the content so that it can not be closed early
+func markdownCodeBlock(content string) string {
+	content = strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	fence := strings.Repeat("`", max(3, longestBacktickRun(content)+1))
+	return fmt.Sprintf("%s\n%s\n%s", fence, content, fence)
+}
+
+// markdownCodeSpan wraps single line text such as URLs in a code span, so that it is shown verbatim
+func markdownCodeSpan(value string) string {
+	value = strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+	delimiter := strings.Repeat("`", longestBacktickRun(value)+1)
+	if strings.HasPrefix(value, "`") || strings.HasSuffix(value, "`") {
+		value = " " + value + " "
+	}
+	return delimiter + value + delimiter
+}
+
+// markdownInline escapes the characters which would be interpreted as markup in single line text such as titles
+func markdownInline(value string) string {
+	replacer := strings.NewReplacer(
+		"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]",
+		"<", "&lt;", ">", "&gt;", "|", "\\|", "\r", "", "\n", " ",
+	)
+	return replacer.Replace(value)
+}
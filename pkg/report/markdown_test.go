@@ -0,0 +1,138 @@
+package report
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// fixtureWorkspaceReportOptions returns the options of a small workspace report, using issue codes without template so
+// that the output does not change with the knowledge base
+func fixtureWorkspaceReportOptions() ReportOptions {
+	workspaceID := uint(42)
+	return ReportOptions{
+		Title:       "Fixture workspace",
+		WorkspaceID: workspaceID,
+		Issues: []*db.Issue{
+			{
+				BaseModel:   db.BaseModel{ID: 1},
+				Code:        "fixture-sqli",
+				Title:       "SQL Injection",
+				Description: "User input is concatenated into SQL queries.",
+				Remediation: "Use parameterized queries.",
+				References:  db.StringSlice{"https://owasp.org/www-community/attacks/SQL_Injection"},
+				Cwe:         89,
+				Severity:    db.Critical,
+				Confidence:  90,
+				URL:         "https://example.com/product_details?id=1",
+				HTTPMethod:  "GET",
+				StatusCode:  500,
+				Status:      db.IssueStatusConfirmed,
+				Details:     "The `id` parameter triggered a database error.",
+				Payload:     "1'",
+				Request:     []byte("GET /product_details?id=1' HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+				Response:    []byte("HTTP/1.1 500 Internal Server Error\r\n\r\nSyntax error near ```'```"),
+				WorkspaceID: &workspaceID,
+			},
+			{
+				BaseModel:   db.BaseModel{ID: 3},
+				Code:        "fixture-headers",
+				Title:       "Missing Content-Security-Policy | header",
+				Description: "The Content-Security-Policy header is not set.",
+				Remediation: "Set a restrictive Content-Security-Policy header.",
+				Severity:    db.Info,
+				Confidence:  100,
+				URL:         "https://example.com/",
+				HTTPMethod:  "GET",
+				StatusCode:  200,
+				Status:      db.IssueStatusOpen,
+				WorkspaceID: &workspaceID,
+			},
+			{
+				BaseModel:   db.BaseModel{ID: 2},
+				Code:        "fixture-xss",
+				Title:       "Reflected <script> in search",
+				Description: "The q parameter is reflected without encoding.",
+				Severity:    db.Medium,
+				Confidence:  75,
+				URL:         "https://example.com/search?q=test",
+				HTTPMethod:  "GET",
+				StatusCode:  200,
+				Payload:     "<script>alert(1)</script>",
+				WorkspaceID: &workspaceID,
+			},
+		},
+	}
+}
+
+func renderFixtureMarkdown(t *testing.T, options ReportOptions) []byte {
+	report := BuildReport(options)
+	report.GeneratedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	assert.NoError(t, renderMarkdownReport(report, &buf))
+	return buf.Bytes()
+}
+
+func assertGolden(t *testing.T, name string, output []byte) {
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(path, output, 0644))
+	}
+	expected, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(output))
+}
+
+func TestMarkdownReportGolden(t *testing.T) {
+	assertGolden(t, "workspace_report.golden.md", renderFixtureMarkdown(t, fixtureWorkspaceReportOptions()))
+}
+
+func TestMarkdownReportSeverityThresholdGolden(t *testing.T) {
+	options := fixtureWorkspaceReportOptions()
+	options.MinSeverity = "Medium"
+	assertGolden(t, "workspace_report_min_medium.golden.md", renderFixtureMarkdown(t, options))
+}
+
+func TestMarkdownCodeBlock(t *testing.T) {
+	assert.Equal(t, "```\nGET / HTTP/1.1\n```", markdownCodeBlock("GET / HTTP/1.1\r\n"))
+	assert.Equal(t, "````\na ``` b\n````", markdownCodeBlock("a ``` b"))
+}
+
+func TestMarkdownCodeSpan(t *testing.T) {
+	assert.Equal(t, "`https://example.com/a_b`", markdownCodeSpan("https://example.com/a_b"))
+	assert.Equal(t, "``a`b``", markdownCodeSpan("a`b"))
+	assert.Equal(t, "`` `a` ``", markdownCodeSpan("`a`"))
+}
+
+func TestStandaloneHTMLReport(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, renderStandaloneHTMLReport(BuildReport(fixtureWorkspaceReportOptions()), &buf))
+	output := buf.String()
+	assert.Contains(t, output, "<style>")
+	assert.Contains(t, output, "SQL Injection")
+	assert.Contains(t, output, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.NotContains(t, output, "<script")
+	assert.NotContains(t, output, "<link")
+	assert.NotContains(t, output, "src=")
+}
+
+func TestParseSeverityThreshold(t *testing.T) {
+	severity, err := ParseSeverityThreshold("medium")
+	assert.NoError(t, err)
+	assert.Equal(t, "Medium", severity)
+
+	severity, err = ParseSeverityThreshold("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", severity)
+
+	_, err = ParseSeverityThreshold("severe")
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+)
+
+// maxEvidenceSize limits the size of the requests, responses and details included in each issue of the report
+const maxEvidenceSize = 4096
+
+// reportSeverities are the severities in the order they are reported
+var reportSeverities = []string{
+	db.Critical.String(),
+	db.High.String(),
+	db.Medium.String(),
+	db.Low.String(),
+	db.Info.String(),
+	db.Unknown.String(),
+}
+
+// Report is the format agnostic content of a report, from which the different report formats are rendered
+type Report struct {
+	Title          string
+	WorkspaceID    uint
+	TaskID         uint
+	GeneratedAt    time.Time
+	TotalIssues    int
+	SeverityCounts []SeverityCount
+	Groups         []SeverityGroup
+}
+
+// SeverityCount is the number of issues of a severity
+type SeverityCount struct {
+	Severity string
+	Count    int
+}
+
+// SeverityGroup holds the issues of a severity
+type SeverityGroup struct {
+	Severity string
+	Issues   []ReportIssue
+}
+
+// ReportIssue holds the details of an issue included in a report
+type ReportIssue struct {
+	ID          uint
+	Code        string
+	Title       string
+	Severity    string
+	Confidence  int
+	Cwe         int
+	URL         string
+	HTTPMethod  string
+	StatusCode  int
+	Status      string
+	Description string
+	Remediation string
+	References  []string
+	Details     string
+	Payload     string
+	Request     string
+	Response    string
+}
+
+// ParseSeverityThreshold returns the severity name matching the provided value, ignoring the case, so that it can be
+// used as the minimum severity of a report. An empty value means no threshold.
+func ParseSeverityThreshold(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	for _, severity := range reportSeverities {
+		if strings.EqualFold(severity, value) {
+			return severity, nil
+		}
+	}
+	return "", fmt.Errorf("invalid severity %s, must be one of %s", value, strings.Join(reportSeverities, ", "))
+}
+
+// includedSeverities returns the reported severities which are at least as severe as the minimum severity
+func includedSeverities(minSeverity string) []string {
+	for i, severity := range reportSeverities {
+		if severity == minSeverity {
+			return reportSeverities[:i+1]
+		}
+	}
+	return reportSeverities
+}
+
+// BuildReport assembles the report content from the provided options, grouping the issues by severity from the most
+// to the least severe and sorting them by title within each group. Issues below the minimum severity are left out.
+func BuildReport(options ReportOptions) *Report {
+	report := &Report{
+		Title:       options.Title,
+		WorkspaceID: options.WorkspaceID,
+		TaskID:      options.TaskID,
+		GeneratedAt: time.Now(),
+	}
+
+	severities := includedSeverities(options.MinSeverity)
+	grouped := make(map[string][]ReportIssue)
+	for _, issue := range options.Issues {
+		if issue == nil {
+			continue
+		}
+		reportIssue := newReportIssue(issue)
+		if !lib.SliceContains(severities, reportIssue.Severity) {
+			continue
+		}
+		grouped[reportIssue.Severity] = append(grouped[reportIssue.Severity], reportIssue)
+		report.TotalIssues++
+	}
+
+	for _, severity := range severities {
+		issues := grouped[severity]
+		report.SeverityCounts = append(report.SeverityCounts, SeverityCount{Severity: severity, Count: len(issues)})
+		if len(issues) == 0 {
+			continue
+		}
+		sort.SliceStable(issues, func(i, j int) bool {
+			if issues[i].Title != issues[j].Title {
+				return issues[i].Title < issues[j].Title
+			}
+			return issues[i].ID < issues[j].ID
+		})
+		report.Groups = append(report.Groups, SeverityGroup{Severity: severity, Issues: issues})
+	}
+	return report
+}
+
+// newReportIssue converts the issue into its report representation, taking the remediation from the issue template
+// when available, so that it reflects the current knowledge base, and falling back to the template description and
+// references when the issue lacks them
+func newReportIssue(issue *db.Issue) ReportIssue {
+	reportIssue := ReportIssue{
+		ID:          issue.ID,
+		Code:        issue.Code,
+		Title:       issue.Title,
+		Severity:    db.NewSeverity(issue.Severity.String()).String(),
+		Confidence:  issue.Confidence,
+		Cwe:         issue.Cwe,
+		URL:         issue.URL,
+		HTTPMethod:  issue.HTTPMethod,
+		StatusCode:  issue.StatusCode,
+		Status:      string(issue.Status),
+		Description: issue.Description,
+		Remediation: issue.Remediation,
+		References:  issue.References,
+		Details:     truncateEvidence(issue.Details),
+		Payload:     truncateEvidence(issue.Payload),
+		Request:     truncateEvidence(string(issue.Request)),
+		Response:    truncateEvidence(string(issue.Response)),
+	}
+	if template := db.GetIssueTemplateByCode(db.IssueCode(issue.Code)); template != nil {
+		if template.Remediation != "" {
+			reportIssue.Remediation = template.Remediation
+		}
+		if reportIssue.Description == "" {
+			reportIssue.Description = template.Description
+		}
+		if len(reportIssue.References) == 0 {
+			reportIssue.References = template.References
+		}
+	}
+	return reportIssue
+}
+
+// truncateEvidence limits the evidence to maxEvidenceSize bytes, noting how much has been left out
+func truncateEvidence(evidence string) string {
+	if len(evidence) <= maxEvidenceSize {
+		return evidence
+	}
+	return fmt.Sprintf("%s\n\n[... truncated %d bytes]", evidence[:maxEvidenceSize], len(evidence)-maxEvidenceSize)
+}
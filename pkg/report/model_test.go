@@ -0,0 +1,74 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildReport(t *testing.T) {
+	issues := []*db.Issue{
+		{BaseModel: db.BaseModel{ID: 3}, Code: "custom-b", Title: "B issue", Severity: db.High},
+		{BaseModel: db.BaseModel{ID: 1}, Code: "custom-a", Title: "A issue", Severity: db.Low},
+		{BaseModel: db.BaseModel{ID: 2}, Code: "custom-a", Title: "A issue", Severity: db.High},
+		{BaseModel: db.BaseModel{ID: 4}, Code: "custom-c", Title: "C issue", Severity: db.Info},
+		nil,
+		{BaseModel: db.BaseModel{ID: 5}, Code: "custom-d", Title: "D issue", Severity: "whatever"},
+	}
+
+	report := BuildReport(ReportOptions{Title: "Model", WorkspaceID: 7, Issues: issues})
+
+	assert.Equal(t, "Model", report.Title)
+	assert.Equal(t, uint(7), report.WorkspaceID)
+	assert.Equal(t, 5, report.TotalIssues)
+	assert.Equal(t, []SeverityCount{
+		{Severity: "Critical", Count: 0},
+		{Severity: "High", Count: 2},
+		{Severity: "Medium", Count: 0},
+		{Severity: "Low", Count: 1},
+		{Severity: "Info", Count: 1},
+		{Severity: "Unknown", Count: 1},
+	}, report.SeverityCounts)
+
+	var severities []string
+	for _, group := range report.Groups {
+		severities = append(severities, group.Severity)
+	}
+	assert.Equal(t, []string{"High", "Low", "Info", "Unknown"}, severities)
+
+	high := report.Groups[0].Issues
+	assert.Len(t, high, 2)
+	assert.Equal(t, uint(2), high[0].ID)
+	assert.Equal(t, uint(3), high[1].ID)
+}
+
+func TestBuildReportEmpty(t *testing.T) {
+	report := BuildReport(ReportOptions{Title: "Empty"})
+	assert.Equal(t, 0, report.TotalIssues)
+	assert.Empty(t, report.Groups)
+	assert.Len(t, report.SeverityCounts, len(reportSeverities))
+}
+
+func TestBuildReportIssueDetails(t *testing.T) {
+	template := db.GetIssueTemplateByCode(db.XssReflectedCode)
+	issue := &db.Issue{
+		Code:        string(db.XssReflectedCode),
+		Title:       template.Title,
+		Severity:    template.Severity,
+		Remediation: "Outdated remediation",
+		Details:     "Payload reflected",
+		Response:    []byte(strings.Repeat("A", maxEvidenceSize+10)),
+	}
+
+	report := BuildReport(ReportOptions{Issues: []*db.Issue{issue}})
+	reportIssue := report.Groups[0].Issues[0]
+
+	assert.Equal(t, template.Remediation, reportIssue.Remediation)
+	assert.Equal(t, template.Description, reportIssue.Description)
+	assert.Equal(t, []string(template.References), reportIssue.References)
+	assert.Equal(t, "Payload reflected", reportIssue.Details)
+	assert.True(t, strings.HasPrefix(reportIssue.Response, strings.Repeat("A", maxEvidenceSize)))
+	assert.Contains(t, reportIssue.Response, "[... truncated 10 bytes]")
+}
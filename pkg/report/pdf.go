@@ -0,0 +1,206 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+type pdfColor struct {
+	r, g, b int
+}
+
+var pdfSeverityColors = map[string]pdfColor{
+	"Critical": {127, 29, 29},
+	"High":     {220, 38, 38},
+	"Medium":   {217, 119, 6},
+	"Low":      {22, 163, 74},
+	"Info":     {37, 99, 235},
+	"Unknown":  {107, 114, 128},
+}
+
+// pdfRenderer renders a report model as a PDF document
+type pdfRenderer struct {
+	pdf       *gofpdf.Fpdf
+	translate func(string) string
+}
+
+func generatePDFReport(options ReportOptions, w io.Writer) error {
+	return renderPDFReport(BuildReport(options), w)
+}
+
+func renderPDFReport(report *Report, w io.Writer) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	r := pdfRenderer{pdf: pdf, translate: pdf.UnicodeTranslatorFromDescriptor("")}
+
+	pdf.SetTitle(report.Title, true)
+	pdf.SetCreator("Sukyan", true)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(107, 114, 128)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Page %d/{nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	r.writeSummary(report)
+	for _, group := range report.Groups {
+		r.writeGroup(group)
+	}
+	return pdf.Output(w)
+}
+
+// text converts the text to the encoding of the core fonts, replacing the control characters which can not be printed
+func (r pdfRenderer) text(value string) string {
+	value = strings.Map(func(c rune) rune {
+		if c == '\n' {
+			return c
+		}
+		if c == '\t' {
+			return ' '
+		}
+		if c < 0x20 || c == 0x7f {
+			return '.'
+		}
+		return c
+	}, strings.ReplaceAll(value, "\r\n", "\n"))
+	return r.translate(value)
+}
+
+func (r pdfRenderer) setSeverityColor(severity string) {
+	color, ok := pdfSeverityColors[severity]
+	if !ok {
+		color = pdfSeverityColors["Unknown"]
+	}
+	r.pdf.SetTextColor(color.r, color.g, color.b)
+}
+
+func (r pdfRenderer) writeSummary(report *Report) {
+	pdf := r.pdf
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetTextColor(17, 24, 39)
+	pdf.MultiCell(0, 10, r.text(report.Title), "", "L", false)
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(75, 85, 99)
+	metadata := fmt.Sprintf("Generated at %s", report.GeneratedAt.Format("2006-01-02 15:04:05 MST"))
+	if report.WorkspaceID != 0 {
+		metadata += fmt.Sprintf(" - Workspace %d", report.WorkspaceID)
+	}
+	if report.TaskID != 0 {
+		metadata += fmt.Sprintf(" - Task %d", report.TaskID)
+	}
+	pdf.CellFormat(0, 6, r.text(metadata), "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.SetTextColor(17, 24, 39)
+	pdf.CellFormat(0, 8, "Summary", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(243, 244, 246)
+	pdf.CellFormat(60, 7, "Severity", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(30, 7, "Issues", "1", 1, "R", true, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, count := range report.SeverityCounts {
+		r.setSeverityColor(count.Severity)
+		pdf.CellFormat(60, 7, count.Severity, "1", 0, "L", false, 0, "")
+		pdf.SetTextColor(17, 24, 39)
+		pdf.CellFormat(30, 7, fmt.Sprintf("%d", count.Count), "1", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(60, 7, "Total", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(30, 7, fmt.Sprintf("%d", report.TotalIssues), "1", 1, "R", true, 0, "")
+
+	if report.TotalIssues == 0 {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 6, "No issues have been found.", "", 1, "L", false, 0, "")
+	}
+}
+
+func (r pdfRenderer) writeGroup(group SeverityGroup) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.Bookmark(r.text(group.Severity), 0, -1)
+	pdf.SetFont("Helvetica", "B", 16)
+	r.setSeverityColor(group.Severity)
+	pdf.CellFormat(0, 10, r.text(fmt.Sprintf("%s severity issues (%d)", group.Severity, len(group.Issues))), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	for _, issue := range group.Issues {
+		r.writeIssue(issue)
+	}
+}
+
+func (r pdfRenderer) writeIssue(issue ReportIssue) {
+	pdf := r.pdf
+	pdf.Bookmark(r.text(issue.Title), 1, -1)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.SetTextColor(17, 24, 39)
+	pdf.MultiCell(0, 7, r.text(fmt.Sprintf("#%d %s", issue.ID, issue.Title)), "", "L", false)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(75, 85, 99)
+	fields := []string{
+		fmt.Sprintf("Severity: %s", issue.Severity),
+		fmt.Sprintf("Confidence: %d%%", issue.Confidence),
+		fmt.Sprintf("Code: %s", issue.Code),
+	}
+	if issue.Cwe != 0 {
+		fields = append(fields, fmt.Sprintf("CWE: %d", issue.Cwe))
+	}
+	if issue.Status != "" {
+		fields = append(fields, fmt.Sprintf("Status: %s", issue.Status))
+	}
+	pdf.MultiCell(0, 5, r.text(strings.Join(fields, "  |  ")), "", "L", false)
+	target := issue.URL
+	if issue.HTTPMethod != "" {
+		target = issue.HTTPMethod + " " + target
+	}
+	if issue.StatusCode != 0 {
+		target = fmt.Sprintf("%s (%d)", target, issue.StatusCode)
+	}
+	pdf.MultiCell(0, 5, r.text(target), "", "L", false)
+	pdf.Ln(2)
+
+	r.writeSection("Description", issue.Description, false)
+	r.writeSection("Remediation", issue.Remediation, false)
+	if len(issue.References) > 0 {
+		r.writeSection("References", strings.Join(issue.References, "\n"), false)
+	}
+	r.writeSection("Details", issue.Details, false)
+	r.writeSection("Payload", issue.Payload, true)
+	r.writeSection("Request", issue.Request, true)
+	r.writeSection("Response", issue.Response, true)
+
+	left, _, right, _ := pdf.GetMargins()
+	width, _ := pdf.GetPageSize()
+	pdf.SetDrawColor(209, 213, 219)
+	pdf.Line(left, pdf.GetY()+2, width-right, pdf.GetY()+2)
+	pdf.Ln(6)
+}
+
+// writeSection writes a titled block of text, using a monospaced font on a shaded background for evidence
+func (r pdfRenderer) writeSection(title, content string, evidence bool) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+	pdf := r.pdf
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetTextColor(17, 24, 39)
+	pdf.CellFormat(0, 6, title, "", 1, "L", false, 0, "")
+	if evidence {
+		pdf.SetFont("Courier", "", 7)
+		pdf.SetFillColor(243, 244, 246)
+		pdf.MultiCell(0, 3.5, r.text(content), "", "L", true)
+	} else {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(55, 65, 81)
+		pdf.MultiCell(0, 4.5, r.text(content), "", "L", false)
+	}
+	pdf.Ln(2)
+}
@@ -20,14 +20,44 @@ type ReportFormat string
 const (
 	ReportFormatHTML ReportFormat = "html"
 	ReportFormatJSON ReportFormat = "json"
+	ReportFormatPDF  ReportFormat = "pdf"
+	// ReportFormatMarkdown renders the report model as Markdown
+	ReportFormatMarkdown ReportFormat = "md"
+	// ReportFormatStandaloneHTML renders the report model as a static HTML document with inlined styles, unlike the
+	// interactive ReportFormatHTML one, so that it can be emailed or viewed offline
+	ReportFormatStandaloneHTML ReportFormat = "standalone-html"
 )
 
+// Extension returns the file extension of the report format
+func (f ReportFormat) Extension() string {
+	if f == ReportFormatStandaloneHTML {
+		return "html"
+	}
+	return string(f)
+}
+
+// ContentType returns the content type of the report format
+func (f ReportFormat) ContentType() string {
+	switch f {
+	case ReportFormatJSON:
+		return "application/json"
+	case ReportFormatPDF:
+		return "application/pdf"
+	case ReportFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "text/html"
+	}
+}
+
 type ReportOptions struct {
 	WorkspaceID uint
 	Issues      []*db.Issue
 	Title       string
 	Format      ReportFormat
 	TaskID      uint
+	// MinSeverity leaves out the issues below the severity from the reports rendered from the report model
+	MinSeverity string
 }
 
 func GenerateReport(options ReportOptions, w io.Writer) error {
@@ -36,6 +66,12 @@ func GenerateReport(options ReportOptions, w io.Writer) error {
 		return generateHTMLReport(options, w)
 	case ReportFormatJSON:
 		return generateJSONReport(options, w)
+	case ReportFormatPDF:
+		return generatePDFReport(options, w)
+	case ReportFormatMarkdown:
+		return generateMarkdownReport(options, w)
+	case ReportFormatStandaloneHTML:
+		return generateStandaloneHTMLReport(options, w)
 	default:
 		return errors.New("invalid report format")
 	}
@@ -103,6 +103,19 @@ func TestGenerateReport(t *testing.T) {
 				assert.Equal(t, string(issue.Severity), issueData["severity"])
 			},
 		},
+		{
+			name: "PDF Report",
+			options: ReportOptions{
+				WorkspaceID: workspace.ID,
+				Issues:      []*db.Issue{&savedIssue},
+				Title:       "Test PDF Report",
+				Format:      ReportFormatPDF,
+			},
+			wantErr: false,
+			checkOutput: func(t *testing.T, output []byte) {
+				assert.True(t, bytes.HasPrefix(output, []byte("%PDF-")), "Report should be a PDF document")
+			},
+		},
 		{
 			name: "Invalid Format",
 			options: ReportOptions{
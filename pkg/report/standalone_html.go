@@ -0,0 +1,19 @@
+package report
+
+import (
+	"html/template"
+	"io"
+)
+
+func generateStandaloneHTMLReport(options ReportOptions, w io.Writer) error {
+	return renderStandaloneHTMLReport(BuildReport(options), w)
+}
+
+// renderStandaloneHTMLReport renders the report model as an HTML document which does not load any external resource
+func renderStandaloneHTMLReport(report *Report, w io.Writer) error {
+	tmpl, err := template.ParseFS(templates, "templates/report_standalone.html.tmpl")
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, report)
+}
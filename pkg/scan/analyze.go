@@ -8,6 +8,7 @@ import (
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/pyneda/sukyan/pkg/scan/options"
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,6 +20,10 @@ type responseFingerprint struct {
 
 type InsertionPointAnalysisOptions struct {
 	HistoryCreateOptions http_utils.HistoryCreationOptions
+	// Filter selects the insertion points to analyze, the rest are discarded before sending any request
+	Filter options.InsertionPointFilter
+	// ClientOptions are the settings of the HTTP client sending the analysis requests
+	ClientOptions *http_utils.ClientOptions
 }
 
 func GetAndAnalyzeInsertionPoints(item *db.History, scoped []string, options InsertionPointAnalysisOptions) ([]InsertionPoint, error) {
@@ -27,39 +32,31 @@ func GetAndAnalyzeInsertionPoints(item *db.History, scoped []string, options Ins
 		log.Error().Err(err).Msg("Failed to get insertion points")
 		return insertionPoints, err
 	}
+	insertionPoints = FilterInsertionPoints(insertionPoints, options.Filter)
 	return AnalyzeInsertionPoints(item, insertionPoints, options), nil
 }
 
 // AnalyzeInsertionPoints by now just checks for reflection (which was already done by templates) and checks in a really simple way if an insertion point is dynamic. In a future it should be improved to also analyze different kinds of accepted inputs, transformations and other interesting behaviors
 func AnalyzeInsertionPoints(item *db.History, insertionPoints []InsertionPoint, options InsertionPointAnalysisOptions) []InsertionPoint {
-	client := http_utils.CreateHttpClient()
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
 	seenDataTypes := make(map[lib.DataType]bool)
 	seenResponseFingerprints := make(map[responseFingerprint]int)
-	originalFingerprint := responseFingerprint{
-		statusCode:     item.StatusCode,
-		bodyLength:     len(item.ResponseBody),
-		bodyWordsCount: len(strings.Fields(string(item.ResponseBody))),
-	}
+	originalFingerprint := newResponseFingerprint(item)
 
+	// The reflection requests are also used to check if the insertion points are dynamic
+	reflections := discoverReflections(item, insertionPoints, client, options.HistoryCreateOptions)
 	for i := range insertionPoints {
 		insertionPoint := &insertionPoints[i]
 		originalDataType := lib.GuessDataType(insertionPoint.OriginalData)
 		seenDataTypes[originalDataType] = true
-		payload := lib.GenerateRandomLowercaseString(6)
-		h, fg, err := insertionPointCheck(item, insertionPoint, payload, client, options)
+		var fg responseFingerprint
+		if reflections[i].History != nil {
+			fg = newResponseFingerprint(reflections[i].History)
+		}
 		seenResponseFingerprints[fg]++
 		if fg != originalFingerprint && fg.statusCode > 0 {
 			insertionPoint.Behaviour.IsDynamic = true
 		}
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to check insertion point")
-		} else if h != nil {
-			// log.Info().Msg("Reflection detected")
-			body := string(h.ResponseBody)
-			if strings.Contains(body, payload) {
-				insertionPoint.Behaviour.IsReflected = true
-			}
-		}
 
 		if !insertionPoint.Behaviour.IsDynamic {
 			basicPayloads := []string{
@@ -101,7 +98,7 @@ func insertionPointCheck(item *db.History, insertionPoint *InsertionPoint, paylo
 		log.Error().Err(err).Msg("Failed to create request from insertion points")
 		return nil, responseFingerprint{}, err
 	}
-	response, err := httpClient.Do(req)
+	response, err := http_utils.SendRequest(httpClient, req)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send request")
 		return nil, responseFingerprint{}, err
@@ -111,10 +108,13 @@ func insertionPointCheck(item *db.History, insertionPoint *InsertionPoint, paylo
 	if err != nil {
 		return nil, responseFingerprint{}, err
 	}
-	fg := responseFingerprint{
+	return history, newResponseFingerprint(history), nil
+}
+
+func newResponseFingerprint(history *db.History) responseFingerprint {
+	return responseFingerprint{
 		statusCode:     history.StatusCode,
 		bodyLength:     len(history.ResponseBody),
 		bodyWordsCount: len(strings.Fields(string(history.ResponseBody))),
 	}
-	return history, fg, nil
 }
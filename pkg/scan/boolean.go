@@ -0,0 +1,124 @@
+package scan
+
+import (
+	"net/http"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/spf13/viper"
+)
+
+// BooleanPayloadPair holds two payloads injecting a condition that is always true and one that is always false,
+// such as ' AND '1'='1 and ' AND '1'='2
+type BooleanPayloadPair struct {
+	True  string
+	False string
+}
+
+// BooleanThresholds configures when the responses to a boolean payload pair are considered different enough
+type BooleanThresholds struct {
+	// MinTrueSimilarity is the minimum similarity between the true condition and the baseline responses
+	MinTrueSimilarity float64
+	// MaxFalseSimilarity is the maximum similarity between the false condition response and the baseline and true
+	// condition ones
+	MaxFalseSimilarity float64
+	// Confirmations is the number of times the payload pair is sent again to rule out unstable responses
+	Confirmations int
+}
+
+// GetBooleanThresholds returns the thresholds set through the scan.boolean settings
+func GetBooleanThresholds() BooleanThresholds {
+	return BooleanThresholds{
+		MinTrueSimilarity:  viper.GetFloat64("scan.boolean.min_true_similarity"),
+		MaxFalseSimilarity: viper.GetFloat64("scan.boolean.max_false_similarity"),
+		Confirmations:      viper.GetInt("scan.boolean.confirmations"),
+	}
+}
+
+// BooleanComparison is the similarity of the true and false condition responses with the baseline one
+type BooleanComparison struct {
+	TrueSimilarity  float64
+	FalseSimilarity float64
+	// TrueFalseSimilarity is the similarity between the true and false condition responses
+	TrueFalseSimilarity float64
+}
+
+// Differs checks if the true condition response matches the baseline while the false condition one differs
+// significantly from both
+func (c BooleanComparison) Differs(thresholds BooleanThresholds) bool {
+	return c.TrueSimilarity >= thresholds.MinTrueSimilarity &&
+		c.FalseSimilarity <= thresholds.MaxFalseSimilarity &&
+		c.TrueFalseSimilarity <= thresholds.MaxFalseSimilarity
+}
+
+// CompareBooleanResponses compares the signatures of the true and false condition responses with the baseline one
+func CompareBooleanResponses(baseline, trueResponse, falseResponse ResponseSignature) BooleanComparison {
+	return BooleanComparison{
+		TrueSimilarity:      baseline.Similarity(trueResponse),
+		FalseSimilarity:     baseline.Similarity(falseResponse),
+		TrueFalseSimilarity: trueResponse.Similarity(falseResponse),
+	}
+}
+
+// BooleanTestResult is the outcome of testing a boolean payload pair against an insertion point
+type BooleanTestResult struct {
+	Pair       BooleanPayloadPair
+	Baseline   *db.History
+	True       *db.History
+	False      *db.History
+	Comparison BooleanComparison
+	Vulnerable bool
+}
+
+// BooleanTest sends the original value of the insertion point as baseline, followed by the true and false condition
+// payloads, flagging the insertion point when the true condition response matches the baseline while the false one
+// differs. Positive results are confirmed by sending the pair again the configured number of times.
+func BooleanTest(client *http.Client, history *db.History, insertionPoint InsertionPoint, pair BooleanPayloadPair, thresholds BooleanThresholds, options http_utils.HistoryCreationOptions) (BooleanTestResult, error) {
+	result := BooleanTestResult{Pair: pair}
+	baseline, err := sendInsertionPointPayload(client, history, insertionPoint, insertionPoint.Value, options)
+	if err != nil {
+		return result, err
+	}
+	result.Baseline = baseline
+	baselineSignature := NewResponseSignature(baseline.StatusCode, baseline.ResponseBody, insertionPoint.Value)
+
+	for attempt := 0; attempt <= thresholds.Confirmations; attempt++ {
+		trueHistory, err := sendInsertionPointPayload(client, history, insertionPoint, pair.True, options)
+		if err != nil {
+			return result, err
+		}
+		falseHistory, err := sendInsertionPointPayload(client, history, insertionPoint, pair.False, options)
+		if err != nil {
+			return result, err
+		}
+		result.True = trueHistory
+		result.False = falseHistory
+		result.Comparison = CompareBooleanResponses(
+			baselineSignature,
+			NewResponseSignature(trueHistory.StatusCode, trueHistory.ResponseBody, pair.True, insertionPoint.Value),
+			NewResponseSignature(falseHistory.StatusCode, falseHistory.ResponseBody, pair.False, insertionPoint.Value),
+		)
+		if !result.Comparison.Differs(thresholds) {
+			return result, nil
+		}
+	}
+	result.Vulnerable = true
+	return result, nil
+}
+
+func sendInsertionPointPayload(client *http.Client, history *db.History, insertionPoint InsertionPoint, payload string, options http_utils.HistoryCreationOptions) (*db.History, error) {
+	request, err := CreateRequestFromInsertionPoints(history, []InsertionPointBuilder{
+		{
+			Point:   insertionPoint,
+			Payload: payload,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return http_utils.ReadHttpResponseAndCreateHistory(response, options)
+}
@@ -1,5 +1,7 @@
 package scan
 
+import "strings"
+
 // GetCommonOpenRedirectParameters returns a list of common parameters known to be used in open redirect vulnerabilities
 func GetCommonOpenRedirectParameters() []string {
 	return []string{
@@ -90,3 +92,54 @@ func IsCommonOpenRedirectParameter(param string) bool {
 	}
 	return false
 }
+
+// GetCommonSSRFParameters returns a list of common parameters whose value is usually fetched by the server
+func GetCommonSSRFParameters() []string {
+	return []string{
+		"url",
+		"uri",
+		"link",
+		"src",
+		"source",
+		"href",
+		"dest",
+		"destination",
+		"target",
+		"host",
+		"domain",
+		"site",
+		"server",
+		"endpoint",
+		"proxy",
+		"feed",
+		"rss",
+		"callback",
+		"callback_url",
+		"webhook",
+		"webhook_url",
+		"image",
+		"image_url",
+		"img",
+		"avatar",
+		"icon",
+		"file",
+		"document",
+		"pdf",
+		"fetch",
+		"load",
+		"import",
+		"preview",
+		"page",
+		"path",
+		"api",
+	}
+}
+
+func IsCommonSSRFParameter(param string) bool {
+	for _, p := range GetCommonSSRFParameters() {
+		if strings.EqualFold(p, param) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,14 +2,18 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pyneda/sukyan/db"
 	"github.com/pyneda/sukyan/lib"
 	"github.com/pyneda/sukyan/lib/integrations"
 	"github.com/pyneda/sukyan/pkg/active"
+	"github.com/pyneda/sukyan/pkg/browser"
 	"github.com/pyneda/sukyan/pkg/crawl"
 	"github.com/pyneda/sukyan/pkg/discovery"
 	"github.com/pyneda/sukyan/pkg/http_utils"
@@ -18,6 +22,7 @@ import (
 	"github.com/pyneda/sukyan/pkg/scan"
 	"github.com/pyneda/sukyan/pkg/scan/options"
 	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/pyneda/sukyan/pkg/scope"
 
 	"github.com/rs/zerolog/log"
 	"github.com/sourcegraph/conc"
@@ -44,6 +49,18 @@ type ScanEngine struct {
 	ctx                       context.Context
 	cancel                    context.CancelFunc
 	isPaused                  bool
+	// pausedTasks holds the IDs of the tasks whose pending active scans should not be started
+	pausedTasks sync.Map
+	// runningJobs holds the IDs of the task jobs being run by this engine
+	runningJobs sync.Map
+	// graphQLEndpoints holds the GraphQL endpoints already introspected, keyed by workspace and endpoint url
+	graphQLEndpoints sync.Map
+	// taskDeadlines holds the time at which the tasks limited by a max duration have to be finalized
+	taskDeadlines sync.Map
+	// taskClientOptions holds the settings of the HTTP clients used by the scan of each task
+	taskClientOptions sync.Map
+	// progress publishes the progress of the scans to the API subscribers
+	progress *ProgressBroker
 }
 
 func NewScanEngine(payloadGenerators []*generation.PayloadGenerator, maxConcurrentPassiveScans, maxConcurrentActiveScans int, interactionsManager *integrations.InteractionsManager) *ScanEngine {
@@ -58,6 +75,7 @@ func NewScanEngine(payloadGenerators []*generation.PayloadGenerator, maxConcurre
 		activeScanPool:            pool.New().WithMaxGoroutines(maxConcurrentActiveScans),
 		ctx:                       ctx,
 		cancel:                    cancel,
+		progress:                  NewProgressBroker(loadScanProgress),
 	}
 }
 
@@ -93,24 +111,102 @@ func (s *ScanEngine) ScheduleHistoryItemScan(item *db.History, scanJobType ScanJ
 func (s *ScanEngine) schedulePassiveScan(item *db.History, workspaceID uint) {
 	s.passiveScanPool.Go(func() {
 		passive.ScanHistoryItem(item)
+		if viper.GetBool("scan.graphql.auto_introspection") && passive.IsGraphQLResponse(item.URL, item.ResponseContentType, item.ResponseBody) {
+			s.scheduleGraphQLIntrospection(item, workspaceID)
+		}
+	})
+}
+
+// claimGraphQLEndpoint returns true the first time a GraphQL endpoint of the workspace is seen, so it is only introspected once
+func (s *ScanEngine) claimGraphQLEndpoint(workspaceID uint, endpoint string) bool {
+	_, seen := s.graphQLEndpoints.LoadOrStore(fmt.Sprintf("%d:%s", workspaceID, endpoint), true)
+	return !seen
+}
+
+// scheduleGraphQLIntrospection audits the schema disclosure of a GraphQL endpoint detected passively, sending the
+// same headers as the detected request so authenticated endpoints can be introspected too
+func (s *ScanEngine) scheduleGraphQLIntrospection(item *db.History, workspaceID uint) {
+	endpoint := passive.GraphQLEndpointURL(item.URL)
+	if !s.claimGraphQLEndpoint(workspaceID, endpoint) {
+		return
+	}
+	headers, err := item.GetRequestHeadersAsMap()
+	if err != nil {
+		log.Warn().Err(err).Uint("history", item.ID).Msg("Could not get request headers of the GraphQL request")
+	}
+	options := active.ActiveModuleOptions{WorkspaceID: workspaceID}
+	if item.TaskID != nil {
+		options.TaskID = *item.TaskID
+		options.ClientOptions = s.getTaskClientOptions(*item.TaskID)
+	}
+	log.Info().Str("endpoint", endpoint).Uint("workspace", workspaceID).Msg("GraphQL endpoint detected, scheduling introspection")
+	s.activeScanPool.Go(func() {
+		active.GraphQLIntrospectionScan(endpoint, headers, nil, options)
 	})
 }
 
 func (s *ScanEngine) scheduleActiveScan(item *db.History, options scan_options.HistoryItemScanOptions) {
+	// The task job is persisted before being queued, so that pending work can be resumed after pausing the task or
+	// restarting the process
+	taskJob, err := db.Connection.CreateTaskJob(&db.TaskJob{
+		TaskID:      options.TaskID,
+		Title:       "Active scan to " + item.URL,
+		Status:      db.TaskJobScheduled,
+		StartedAt:   time.Now(),
+		HistoryID:   item.ID,
+		ScanOptions: options,
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("history", item.ID).Msg("Could not create task job")
+		return
+	}
+	s.progress.Update(taskJob.TaskID, func(progress *ScanProgress) {
+		progress.TotalJobs++
+	})
+	s.queueActiveScanJob(taskJob, item)
+}
+
+// queueActiveScanJob runs the active scan of a task job once there is room in the pool, unless its task gets paused
+// in the meantime, in which case the job is kept as scheduled
+func (s *ScanEngine) queueActiveScanJob(taskJob *db.TaskJob, item *db.History) {
 	s.activeScanPool.Go(func() {
-		taskJob, err := db.Connection.NewTaskJob(options.TaskID, "Active scan to "+item.URL, db.TaskJobScheduled, item.ID)
-		if err != nil {
-			log.Error().Err(err).Uint("history", item.ID).Msg("Could not create task job")
+		if s.isTaskPaused(taskJob.TaskID) {
+			log.Debug().Uint("task", taskJob.TaskID).Uint("task_job", taskJob.ID).Msg("Task is paused, keeping task job as scheduled")
+			return
+		}
+		if s.isTaskTimeLimited(taskJob.TaskID) {
+			log.Debug().Uint("task", taskJob.TaskID).Uint("task_job", taskJob.ID).Msg("Task has reached its max duration, cancelling task job")
+			taskJob.Status = db.TaskJobCancelled
+			taskJob.CompletedAt = time.Now()
+			db.Connection.UpdateTaskJob(taskJob)
 			return
 		}
+		s.runningJobs.Store(taskJob.ID, true)
 
 		s.wg.Go(func() {
+			defer s.runningJobs.Delete(taskJob.ID)
+			options := taskJob.ScanOptions
 			options.TaskJobID = taskJob.ID
 			taskJob.Status = db.TaskJobRunning
+			taskJob.StartedAt = time.Now()
 			db.Connection.UpdateTaskJob(taskJob)
-
-			active.ScanHistoryItem(item, s.InteractionsManager, s.payloadGenerators, options)
-
+			s.progress.Update(taskJob.TaskID, func(progress *ScanProgress) {
+				progress.RunningJobs++
+				progress.CurrentTarget = item.URL
+			})
+
+			active.ScanHistoryItem(item, s.InteractionsManager, s.payloadGenerators, options, s.getTaskClientOptions(taskJob.TaskID))
+
+			// The progress is published before the job is finished, as the task can be reported as finished as soon
+			// as it has no pending jobs
+			issues, err := db.Connection.CountTaskIssues(taskJob.TaskID)
+			s.progress.Update(taskJob.TaskID, func(progress *ScanProgress) {
+				progress.RunningJobs--
+				progress.CompletedJobs++
+				if err == nil {
+					progress.IssuesFound = issues
+				}
+			})
 			taskJob.Status = db.TaskJobFinished
 			taskJob.CompletedAt = time.Now()
 			db.Connection.UpdateTaskJob(taskJob)
@@ -118,24 +214,176 @@ func (s *ScanEngine) scheduleActiveScan(item *db.History, options scan_options.H
 	})
 }
 
+func (s *ScanEngine) isTaskPaused(taskID uint) bool {
+	_, paused := s.pausedTasks.Load(taskID)
+	return paused
+}
+
+// isTaskTimeLimited returns true when the task has a max duration which has been reached
+func (s *ScanEngine) isTaskTimeLimited(taskID uint) bool {
+	deadline, ok := s.taskDeadlines.Load(taskID)
+	return ok && !time.Now().Before(deadline.(time.Time))
+}
+
+// finalizeTimeLimitedTask cancels the jobs of a task which has reached its max duration that have not been started,
+// waits for the running ones to finish within the grace period and marks the task as time limited. Results gathered
+// until then are kept.
+func (s *ScanEngine) finalizeTimeLimitedTask(taskID uint) {
+	scanLog := log.With().Uint("task", taskID).Logger()
+	cancelled, err := db.Connection.CancelScheduledTaskJobs(taskID)
+	if err != nil {
+		scanLog.Error().Err(err).Msg("Error cancelling the scheduled task jobs of a time limited task")
+	}
+	gracePeriod := time.Duration(viper.GetInt("scan.max_duration.grace_period")) * time.Second
+	scanLog.Info().Int64("cancelled_jobs", cancelled).Str("grace_period", gracePeriod.String()).Msg("Task has reached its max duration, waiting for the running jobs to finish")
+	graceDeadline := time.Now().Add(gracePeriod)
+	for time.Now().Before(graceDeadline) {
+		hasPending, err := db.Connection.TaskHasPendingJobs(taskID)
+		if err != nil || !hasPending {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	counts, err := db.Connection.CountTaskJobsByStatus(taskID)
+	if err != nil {
+		scanLog.Error().Err(err).Msg("Error counting the task jobs of a time limited task")
+	}
+	s.recordScanEvent(taskID, db.ScanEventScanTimeLimited, "", "Scan stopped as it reached its max duration", map[string]interface{}{
+		"cancelled_jobs":  cancelled,
+		"finished_jobs":   counts[db.TaskJobFinished],
+		"unfinished_jobs": counts[db.TaskJobRunning] + counts[db.TaskJobScheduled],
+	})
+	s.setTaskStatus(taskID, db.TaskStatusTimeLimited)
+	s.taskDeadlines.Delete(taskID)
+}
+
+// PauseTask stops starting the pending active scans of the task. Scans already running are completed, while the
+// queued ones are kept as scheduled task jobs, so the task can be resumed even after the process restarts.
+func (s *ScanEngine) PauseTask(taskID uint) error {
+	task, err := db.Connection.GetTaskByID(taskID, false)
+	if err != nil {
+		return err
+	}
+	if task.Status == db.TaskStatusFinished || task.Status == db.TaskStatusFailed || task.Status == db.TaskStatusTimeLimited {
+		return fmt.Errorf("task %d can not be paused as it is %s", taskID, task.Status)
+	}
+	s.pausedTasks.Store(taskID, true)
+	if err := s.setTaskStatus(taskID, db.TaskStatusPaused); err != nil {
+		return err
+	}
+	s.recordScanEvent(taskID, db.ScanEventScanPaused, "", "Scan paused", nil)
+	log.Info().Uint("task", taskID).Msg("Task paused")
+	return nil
+}
+
+// ResumeTask schedules again the pending task jobs of a paused task. Jobs marked as running which are not being run
+// by this engine were interrupted by a restart, so they are scheduled again too.
+func (s *ScanEngine) ResumeTask(taskID uint) error {
+	task, err := db.Connection.GetTaskByID(taskID, false)
+	if err != nil {
+		return err
+	}
+	if task.Status != db.TaskStatusPaused {
+		return fmt.Errorf("task %d can not be resumed as it is %s", taskID, task.Status)
+	}
+	jobs, err := db.Connection.ListPendingTaskJobs(taskID)
+	if err != nil {
+		return err
+	}
+
+	s.pausedTasks.Delete(taskID)
+	if err := s.setTaskStatus(taskID, db.TaskStatusScanning); err != nil {
+		return err
+	}
+	resumed := 0
+	for _, job := range jobs {
+		if _, running := s.runningJobs.Load(job.ID); running {
+			continue
+		}
+		history := job.History
+		// Avoid updating the history item through the association when updating the job
+		job.History = db.History{}
+		s.queueActiveScanJob(job, &history)
+		resumed++
+	}
+	s.recordScanEvent(taskID, db.ScanEventScanResumed, "", fmt.Sprintf("Scan resumed with %d pending jobs", resumed), map[string]interface{}{
+		"pending_jobs": resumed,
+	})
+	log.Info().Uint("task", taskID).Int("pending_jobs", resumed).Msg("Task resumed")
+
+	go s.waitForTaskCompletion(taskID)
+	return nil
+}
+
 func (s *ScanEngine) FullScan(options scan_options.FullScanOptions, waitCompletion bool) (*db.Task, error) {
-	task, err := db.Connection.NewTask(options.WorkspaceID, nil, options.Title, db.TaskStatusCrawling, db.TaskTypeScan)
+	options, err := ExpandScanProfile(options)
+	if err != nil {
+		return nil, err
+	}
+	exclusions, err := scope.NewURLExclusions(options.ExcludeURLs)
+	if err != nil {
+		log.Error().Err(err).Interface("exclude_urls", options.ExcludeURLs).Msg("Invalid URL exclusions provided")
+		return nil, err
+	}
+	scopeRules, err := options.Scope.Compile()
+	if err != nil {
+		log.Error().Err(err).Interface("scope", options.Scope).Msg("Invalid scope rules provided")
+		return nil, err
+	}
+	clientOptions, err := newClientOptions(options, exclusions, scopeRules)
+	if err != nil {
+		log.Error().Err(err).Interface("session_renewal", options.SessionRenewal).Msg("Invalid session renewal options provided")
+		return nil, err
+	}
+	taskType := db.TaskTypeScan
+	if options.CrawlOnly {
+		taskType = db.TaskTypeCrawl
+	}
+	task, err := db.Connection.NewTask(options.WorkspaceID, nil, options.Title, db.TaskStatusCrawling, taskType)
 	if err != nil {
 		log.Error().Err(err).Msg("Could not create task")
 	}
 	// NOTE: Optimally, we would refactor the NewTask to accept the options struct directly
 	task.ScanOptions = options
 	db.Connection.UpdateTask(task.ID, task)
+	s.taskClientOptions.Store(task.ID, clientOptions)
+	if options.MaxDuration > 0 {
+		s.taskDeadlines.Store(task.ID, time.Now().Add(options.MaxDuration))
+	}
+	s.recordScanEvent(task.ID, db.ScanEventScanStarted, "", "Scan started", map[string]interface{}{
+		"start_urls": options.StartURLs,
+		"mode":       options.Mode.String(),
+	})
 	ignoredExtensions := viper.GetStringSlice("crawl.ignored_extensions")
 
 	scanLog := log.With().Uint("task", task.ID).Str("title", options.Title).Uint("workspace", options.WorkspaceID).Logger()
-	crawler := crawl.NewCrawler(options.StartURLs, options.MaxPagesToCrawl, options.MaxDepth, options.PagesPoolSize, options.ExcludePatterns, options.WorkspaceID, task.ID, options.Headers)
+	s.recordScanEvent(task.ID, db.ScanEventCrawlStarted, "crawler", "Crawl started", nil)
+	// The crawler browser still loads the out of scope resources needed to render the pages, it only avoids crawling them
+	crawlClientOptions := *clientOptions
+	crawlClientOptions.ScopeRules = nil
+	crawler := crawl.NewCrawler(options.StartURLs, options.MaxPagesToCrawl, options.MaxDepth, options.PagesPoolSize, options.ExcludePatterns, exclusions, scopeRules, options.WorkspaceID, task.ID, options.Headers, &crawlClientOptions)
 	historyItems := crawler.Run()
+	s.recordScanEvent(task.ID, db.ScanEventCrawlFinished, "crawler", "Crawl finished", map[string]interface{}{
+		"history_items":    len(historyItems),
+		"interesting_urls": crawler.InterestingURLs(),
+	})
 	if len(historyItems) == 0 {
-		db.Connection.SetTaskStatus(task.ID, db.TaskStatusFinished)
+		s.recordScanEvent(task.ID, db.ScanEventScanCompleted, "", "Scan completed as no history items were gathered during crawl", nil)
+		s.setTaskStatus(task.ID, db.TaskStatusFinished)
 		scanLog.Info().Msg("No history items gathered during crawl, exiting")
 		return task, nil
 	}
+	if s.isTaskTimeLimited(task.ID) {
+		scanLog.Info().Int("count", len(historyItems)).Msg("Max duration reached while crawling, not scheduling audits")
+		s.finalizeTimeLimitedTask(task.ID)
+		return task, nil
+	}
+	if options.CrawlOnly {
+		s.recordScanEvent(task.ID, db.ScanEventScanCompleted, "", "Scan completed after crawling as it is crawl only", nil)
+		s.setTaskStatus(task.ID, db.TaskStatusFinished)
+		scanLog.Info().Int("count", len(historyItems)).Msg("Crawling finished, not scheduling audits as the scan is crawl only")
+		return task, nil
+	}
 	uniqueHistoryItems := removeDuplicateHistoryItems(historyItems)
 	scanLog.Info().Int("count", len(uniqueHistoryItems)).Msg("Crawling finished, scheduling active scans")
 	fingerprints := make([]lib.Fingerprint, 0)
@@ -156,27 +404,43 @@ func (s *ScanEngine) FullScan(options scan_options.FullScanOptions, waitCompleti
 	}
 
 	fingerprintTags := passive.GetUniqueNucleiTags(fingerprints)
+	s.recordScanEvent(task.ID, db.ScanEventFingerprintsGathered, "fingerprint", fmt.Sprintf("Gathered %d fingerprints", len(fingerprints)), map[string]interface{}{
+		"fingerprints": fingerprints,
+		"nuclei_tags":  fingerprintTags,
+	})
 
 	if viper.GetBool("integrations.nuclei.enabled") {
-		db.Connection.SetTaskStatus(task.ID, db.TaskStatusNuclei)
+		s.setTaskStatus(task.ID, db.TaskStatusNuclei)
 		scanLog.Info().Int("count", len(fingerprintTags)).Interface("tags", fingerprintTags).Msg("Gathered tags from fingerprints for Nuclei scan")
+		s.recordScanEvent(task.ID, db.ScanEventNucleiStarted, "nuclei", "Nuclei scan started", map[string]interface{}{
+			"base_urls": baseURLs,
+		})
 		nucleiScanErr := integrations.NucleiScan(baseURLs, options.WorkspaceID)
 		if nucleiScanErr != nil {
 			scanLog.Error().Err(nucleiScanErr).Msg("Error running nuclei scan")
 		}
+		s.recordScanEvent(task.ID, db.ScanEventNucleiFinished, "nuclei", "Nuclei scan finished", nil)
 	}
 
 	retireScanner := integrations.NewRetireScanner()
 
-	db.Connection.SetTaskStatus(task.ID, db.TaskStatusScanning)
+	s.setTaskStatus(task.ID, db.TaskStatusScanning)
 
-	transport := http_utils.CreateHttpTransport()
-	transport.ForceAttemptHTTP2 = true
+	// Discovery negotiates HTTP/2 unless another HTTP version has been configured
+	protocol := clientOptions.HTTPProtocol()
+	if protocol == http_utils.HTTPProtocolDefault {
+		protocol = http_utils.HTTPProtocolHTTP2
+	}
+	transport := http_utils.CreateProtocolTransport(protocol)
 	discoveryClient := &http.Client{
-		Transport: transport,
+		Transport: clientOptions.WrapTransport(transport),
 	}
 
 	for _, baseURL := range baseURLs {
+		if s.isTaskTimeLimited(task.ID) {
+			scanLog.Info().Str("base_url", baseURL).Msg("Max duration reached, skipping discovery")
+			break
+		}
 		createOpts := http_utils.HistoryCreationOptions{
 			Source:      db.SourceScanner,
 			WorkspaceID: options.WorkspaceID,
@@ -202,19 +466,37 @@ func (s *ScanEngine) FullScan(options scan_options.FullScanOptions, waitCompleti
 				BaseHeaders:            options.Headers,
 				ScanMode:               options.Mode,
 			}
-			discovery.DiscoverAll(discoverOpts)
+			s.recordScanEvent(task.ID, db.ScanEventDiscoveryStarted, "discovery", fmt.Sprintf("Discovery started for %s", baseURL), map[string]interface{}{
+				"base_url": baseURL,
+			})
+			discoveryResults, _ := discovery.DiscoverAll(discoverOpts)
+			s.scanDiscoveredGraphQLEndpoints(discoveryResults, options.Headers, discoveryClient, active.ActiveModuleOptions{
+				WorkspaceID:   options.WorkspaceID,
+				TaskID:        task.ID,
+				ScanMode:      options.Mode,
+				ClientOptions: clientOptions,
+			})
+			s.recordScanEvent(task.ID, db.ScanEventDiscoveryFinished, "discovery", fmt.Sprintf("Discovery finished for %s", baseURL), map[string]interface{}{
+				"base_url": baseURL,
+			})
 		}
 
 	}
 
 	itemScanOptions := scan_options.HistoryItemScanOptions{
-		WorkspaceID:        options.WorkspaceID,
-		TaskID:             task.ID,
-		Mode:               options.Mode,
-		InsertionPoints:    options.InsertionPoints,
-		FingerprintTags:    fingerprintTags,
-		ExperimentalAudits: options.ExperimentalAudits,
-		AuditCategories:    options.AuditCategories,
+		WorkspaceID:          options.WorkspaceID,
+		TaskID:               task.ID,
+		Mode:                 options.Mode,
+		InsertionPoints:      options.InsertionPoints,
+		FingerprintTags:      fingerprintTags,
+		Fingerprints:         fingerprints,
+		ExperimentalAudits:   options.ExperimentalAudits,
+		AuditCategories:      options.AuditCategories,
+		ExcludeURLs:          options.ExcludeURLs,
+		Scope:                options.Scope,
+		InsertionPointFilter: options.InsertionPointFilter,
+		RandomSeed:           options.RandomSeed,
+		WAFEvasion:           options.WAFEvasion,
 	}
 
 	websocketConnections, count, _ := db.Connection.ListWebSocketConnections(db.WebSocketConnectionFilter{
@@ -229,13 +511,28 @@ func (s *ScanEngine) FullScan(options scan_options.FullScanOptions, waitCompleti
 		scanLog.Info().Msg("No WebSocket connections discovered during crawl")
 	}
 	scheduledURLPaths := make(map[string]bool)
+	scheduled := make(chan struct{})
 
 	s.wg.Go(func() {
+		defer close(scheduled)
 		for _, historyItem := range uniqueHistoryItems {
+			if s.isTaskTimeLimited(task.ID) {
+				scanLog.Info().Msg("Max duration reached, not scheduling more active scans")
+				break
+			}
 			if historyItem.StatusCode == 404 {
 				continue
 			}
 
+			if pattern, excluded := exclusions.Match(historyItem.URL); excluded {
+				scanLog.Debug().Str("url", historyItem.URL).Str("pattern", pattern).Msg("Skipping scanning history item as it has been excluded")
+				continue
+			}
+			if !scopeRules.InScope(historyItem.URL, historyItem.Method) {
+				scanLog.Debug().Str("url", historyItem.URL).Str("method", historyItem.Method).Msg("Skipping scanning history item as it is out of scope")
+				continue
+			}
+
 			go retireScanner.HistoryScan(historyItem)
 
 			shouldSkip := false
@@ -259,13 +556,19 @@ func (s *ScanEngine) FullScan(options scan_options.FullScanOptions, waitCompleti
 				}
 				if _, exists := scheduledURLPaths[normalizedURLPath]; exists {
 					scanOptions := scan_options.HistoryItemScanOptions{
-						WorkspaceID:        options.WorkspaceID,
-						TaskID:             task.ID,
-						Mode:               options.Mode,
-						InsertionPoints:    lib.FilterOutString(options.InsertionPoints, "urlpath"),
-						FingerprintTags:    fingerprintTags,
-						ExperimentalAudits: options.ExperimentalAudits,
-						AuditCategories:    options.AuditCategories,
+						WorkspaceID:          options.WorkspaceID,
+						TaskID:               task.ID,
+						Mode:                 options.Mode,
+						InsertionPoints:      lib.FilterOutString(options.InsertionPoints, "urlpath"),
+						FingerprintTags:      fingerprintTags,
+						Fingerprints:         fingerprints,
+						ExperimentalAudits:   options.ExperimentalAudits,
+						AuditCategories:      options.AuditCategories,
+						ExcludeURLs:          options.ExcludeURLs,
+						Scope:                options.Scope,
+						InsertionPointFilter: options.InsertionPointFilter,
+						RandomSeed:           options.RandomSeed,
+						WAFEvasion:           options.WAFEvasion,
 					}
 					s.ScheduleHistoryItemScan(historyItem, ScanJobTypeAll, scanOptions)
 				} else {
@@ -279,37 +582,198 @@ func (s *ScanEngine) FullScan(options scan_options.FullScanOptions, waitCompleti
 	})
 
 	scanLog.Info().Msg("Active scans scheduled")
+	s.recordScanEvent(task.ID, db.ScanEventActiveScansScheduled, "", "Active scans scheduled", map[string]interface{}{
+		"history_items": len(uniqueHistoryItems),
+	})
 
+	// Time limited scans only wait for the scheduling to finish, as the running jobs are given a grace period
+	waitScheduled := s.wg.Wait
+	if options.MaxDuration > 0 {
+		waitScheduled = func() { <-scheduled }
+	}
 	if waitCompletion {
 		time.Sleep(2 * time.Second)
-		s.wg.Wait()
-		waitForTaskCompletion(task.ID)
-		scanLog.Info().Msg("Active scans finished")
-		db.Connection.SetTaskStatus(task.ID, db.TaskStatusFinished)
+		waitScheduled()
+		if s.waitForTaskCompletion(task.ID) {
+			scanLog.Info().Msg("Active scans finished")
+		}
 	} else {
 		go func() {
-			s.wg.Wait()
-			waitForTaskCompletion(task.ID)
-			scanLog.Info().Msg("Active scans finished")
-			db.Connection.SetTaskStatus(task.ID, db.TaskStatusFinished)
+			waitScheduled()
+			if s.waitForTaskCompletion(task.ID) {
+				scanLog.Info().Msg("Active scans finished")
+			}
 		}()
 	}
 
 	return task, nil
 }
 
-func waitForTaskCompletion(taskID uint) {
+// Crawl builds the site map of the provided targets, storing the requests, WebSocket connections and messages found by
+// the browser driven crawler, without scheduling any audit
+func (s *ScanEngine) Crawl(options scan_options.FullScanOptions) (*db.Task, error) {
+	options.CrawlOnly = true
+	return s.FullScan(options, true)
+}
+
+// waitForTaskCompletion waits until the task has no pending jobs, records its completion and marks it as finished,
+// returning false if the task gets paused in the meantime. Tasks reaching their max duration are finalized as time
+// limited instead.
+func (s *ScanEngine) waitForTaskCompletion(taskID uint) bool {
 	scanLog := log.With().Uint("task", taskID).Logger()
 	for {
+		if s.isTaskPaused(taskID) {
+			scanLog.Info().Msg("Task has been paused, stopped waiting for its completion")
+			return false
+		}
+		if s.isTaskTimeLimited(taskID) {
+			s.finalizeTimeLimitedTask(taskID)
+			return true
+		}
 		hasPending, err := db.Connection.TaskHasPendingJobs(taskID)
 		if err != nil {
 			scanLog.Error().Err(err).Msg("Error checking pending task jobs")
-			return
+			return false
 		}
 		if !hasPending {
 			break
 		}
 		time.Sleep(2 * time.Second)
 	}
-	db.Connection.SetTaskStatus(taskID, db.TaskStatusFinished)
+	// The event is recorded first, so progress subscribers get it before the scan is reported as finished
+	s.recordScanEvent(taskID, db.ScanEventScanCompleted, "", "Scan completed", nil)
+	s.setTaskStatus(taskID, db.TaskStatusFinished)
+	s.taskDeadlines.Delete(taskID)
+	s.taskClientOptions.Delete(taskID)
+	return true
+}
+
+// scanDiscoveredGraphQLEndpoints checks the schema disclosure and batching support of the GraphQL endpoints found during discovery
+func (s *ScanEngine) scanDiscoveredGraphQLEndpoints(results []discovery.DiscoveryResult, headers map[string][]string, client *http.Client, options active.ActiveModuleOptions) {
+	for _, result := range results {
+		if result.Source != "graphql" {
+			continue
+		}
+		for _, issue := range result.Results.Issues {
+			if !s.claimGraphQLEndpoint(options.WorkspaceID, issue.URL) {
+				continue
+			}
+			active.GraphQLIntrospectionScan(issue.URL, headers, client, options)
+			active.GraphQLBatchingScan(s.ctx, issue.URL, headers, client, options)
+		}
+	}
+}
+
+// newClientOptions builds the settings shared by the HTTP clients of a scan, which refuse to send requests out of the
+// provided scope
+func newClientOptions(options scan_options.FullScanOptions, exclusions *scope.URLExclusions, scopeRules *scope.CompiledScopeRules) (*http_utils.ClientOptions, error) {
+	headers := scanHeadersConfig(options)
+	sessionManager, err := newSessionManager(options.SessionRenewal, options.WorkspaceID, headers)
+	if err != nil {
+		return nil, err
+	}
+	return &http_utils.ClientOptions{
+		RateLimiter:    http_utils.NewDomainRateLimiter(domainRateLimitConfig(options.RateLimit)),
+		Headers:        headers,
+		Protocol:       http_utils.HTTPProtocol(options.HTTPVersion),
+		SessionManager: sessionManager,
+		Exclusions:     exclusions,
+		ScopeRules:     scopeRules,
+	}, nil
+}
+
+// scanHeadersConfig returns the User-Agent and extra headers added to every request of the scan
+func scanHeadersConfig(options scan_options.FullScanOptions) http_utils.ScanHeadersConfig {
+	return http_utils.ScanHeadersConfig{UserAgent: options.UserAgent, ExtraHeaders: options.ExtraHeaders}
+}
+
+// getTaskClientOptions returns the settings of the HTTP clients of the task scan, building them from the options
+// stored in the task when the task was not started by this engine, such as after resuming it once restarted
+func (s *ScanEngine) getTaskClientOptions(taskID uint) *http_utils.ClientOptions {
+	if clientOptions, ok := s.taskClientOptions.Load(taskID); ok {
+		return clientOptions.(*http_utils.ClientOptions)
+	}
+	var options scan_options.FullScanOptions
+	if taskID != 0 {
+		task, err := db.Connection.GetTaskByID(taskID, false)
+		if err != nil {
+			log.Warn().Err(err).Uint("task", taskID).Msg("Could not get task to build its HTTP client options, using the defaults")
+		} else {
+			options = task.ScanOptions
+		}
+	}
+	// The options were validated when the task was started, so the errors are not expected here
+	exclusions, err := scope.NewURLExclusions(options.ExcludeURLs)
+	if err != nil {
+		log.Warn().Err(err).Uint("task", taskID).Msg("Invalid URL exclusions stored in the task")
+	}
+	scopeRules, err := options.Scope.Compile()
+	if err != nil {
+		log.Warn().Err(err).Uint("task", taskID).Msg("Invalid scope rules stored in the task")
+	}
+	built, err := newClientOptions(options, exclusions, scopeRules)
+	if err != nil {
+		log.Warn().Err(err).Uint("task", taskID).Msg("Could not set up session renewal for the task, continuing without it")
+		options.SessionRenewal = scan_options.SessionRenewalOptions{}
+		built, _ = newClientOptions(options, exclusions, scopeRules)
+	}
+	clientOptions, _ := s.taskClientOptions.LoadOrStore(taskID, built)
+	return clientOptions.(*http_utils.ClientOptions)
+}
+
+// domainRateLimitConfig builds the per domain rate limits of the scan, falling back to the scan.rate_limit settings
+func domainRateLimitConfig(rateLimit scan_options.RateLimitOptions) http_utils.DomainRateLimitConfig {
+	config := http_utils.DomainRateLimitConfig{
+		RequestsPerSecond: viper.GetFloat64("scan.rate_limit.requests_per_second"),
+		Burst:             viper.GetInt("scan.rate_limit.burst"),
+		Backoff:           viper.GetBool("scan.rate_limit.backoff"),
+	}
+	if rateLimit.RequestsPerSecond > 0 {
+		config.RequestsPerSecond = rateLimit.RequestsPerSecond
+		config.Burst = rateLimit.Burst
+	}
+	return config
+}
+
+// newSessionManager builds the session manager that replays the stored browser actions referenced in the options
+// with the scan headers when the session expires, returning nil when session renewal is not enabled
+func newSessionManager(sessionRenewal scan_options.SessionRenewalOptions, workspaceID uint, headers http_utils.ScanHeadersConfig) (*http_utils.SessionManager, error) {
+	if !sessionRenewal.Enabled() {
+		return nil, nil
+	}
+	signature := http_utils.LoggedOutSignature{
+		StatusCodes:     sessionRenewal.LoggedOutStatusCodes,
+		LocationPattern: sessionRenewal.LoggedOutLocationPattern,
+		BodyPattern:     sessionRenewal.LoggedOutBodyPattern,
+	}
+	if signature.IsEmpty() {
+		return nil, errors.New("session renewal requires a logged out status code, location pattern or body pattern")
+	}
+	stored, err := db.Connection.GetStoredBrowserActionsByID(*sessionRenewal.BrowserActionsID)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := http_utils.NewSessionManager(http_utils.SessionRenewalConfig{
+		Signature: signature,
+		Renew:     browser.NewBrowserSessionRenewer(stored.Actions, workspaceID, headers),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Ints("status_codes", signature.StatusCodes).Str("location_pattern", signature.LocationPattern).Str("body_pattern", signature.BodyPattern).Msg("Session renewal enabled")
+	return manager, nil
+}
+
+// ExpandScanProfile fills the options that have not been explicitly provided with the ones of the scan profile
+// referenced by the options, if any
+func ExpandScanProfile(options scan_options.FullScanOptions) (scan_options.FullScanOptions, error) {
+	if options.ProfileID == nil {
+		return options, nil
+	}
+	profile, err := db.Connection.GetScanProfileByID(*options.ProfileID)
+	if err != nil {
+		return options, fmt.Errorf("could not get scan profile %d: %w", *options.ProfileID, err)
+	}
+	log.Info().Uint("profile", profile.ID).Str("name", profile.Name).Msg("Applying scan profile to the scan options")
+	return options.ApplyProfile(profile.Options), nil
 }
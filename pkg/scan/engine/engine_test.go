@@ -1 +1,226 @@
 package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumePausedTaskFromDatabase(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestResumePausedTaskFromDatabase",
+		Title: "TestResumePausedTaskFromDatabase",
+	})
+	assert.Nil(t, err)
+	defer db.Connection.DeleteWorkspace(workspace.ID)
+
+	task, err := db.Connection.NewTask(workspace.ID, nil, "Pause and resume test", db.TaskStatusScanning, db.TaskTypeScan)
+	assert.Nil(t, err)
+	history, err := db.Connection.CreateHistory(&db.History{
+		URL:         "https://resume.example.com/",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+		TaskID:      &task.ID,
+	})
+	assert.Nil(t, err)
+
+	// The URL is excluded so that resumed jobs complete without sending any request
+	options := scan_options.HistoryItemScanOptions{
+		WorkspaceID: workspace.ID,
+		TaskID:      task.ID,
+		ExcludeURLs: []string{"*"},
+	}
+
+	first := NewScanEngine(nil, 2, 2, nil)
+	assert.Nil(t, first.PauseTask(task.ID))
+	for i := 0; i < 3; i++ {
+		first.scheduleActiveScan(history, options)
+	}
+	first.Stop()
+
+	pending, err := db.Connection.ListPendingTaskJobs(task.ID)
+	assert.Nil(t, err)
+	assert.Len(t, pending, 3)
+	for _, job := range pending {
+		assert.Equal(t, db.TaskJobScheduled, job.Status)
+		assert.Equal(t, options.ExcludeURLs, job.ScanOptions.ExcludeURLs)
+	}
+	paused, err := db.Connection.GetTaskByID(task.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, db.TaskStatusPaused, paused.Status)
+
+	// A new engine has no in-memory state about the task, as it would happen after restarting the process
+	second := NewScanEngine(nil, 2, 2, nil)
+	assert.Nil(t, second.ResumeTask(task.ID))
+	assert.Eventually(t, func() bool {
+		resumed, err := db.Connection.GetTaskByID(task.ID, false)
+		return err == nil && resumed.Status == db.TaskStatusFinished
+	}, 15*time.Second, 200*time.Millisecond)
+
+	pending, err = db.Connection.ListPendingTaskJobs(task.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, pending)
+	second.Stop()
+
+	assert.NotNil(t, second.ResumeTask(task.ID))
+}
+
+func TestCrawlOnly(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestCrawlOnly",
+		Title: "TestCrawlOnly",
+	})
+	assert.Nil(t, err)
+	defer db.Connection.DeleteWorkspace(workspace.ID)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><a href="/search?q=test">Search</a><a href="/about">About</a><p>%s</p></body></html>`, r.URL.Query().Get("q"))
+	}))
+	defer server.Close()
+
+	engine := NewScanEngine(nil, 2, 2, nil)
+	defer engine.Stop()
+	task, err := engine.Crawl(scan_options.FullScanOptions{
+		Title:           "Crawl only test",
+		StartURLs:       []string{server.URL},
+		WorkspaceID:     workspace.ID,
+		MaxPagesToCrawl: 5,
+		MaxDepth:        2,
+		PagesPoolSize:   2,
+		AuditCategories: scan_options.AuditCategories{
+			ServerSide: true,
+			ClientSide: true,
+			Passive:    true,
+			Discovery:  true,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, db.TaskTypeCrawl, task.Type)
+
+	crawled, err := db.Connection.GetTaskByID(task.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, db.TaskStatusFinished, crawled.Status)
+
+	_, count, err := db.Connection.ListHistory(db.HistoryFilter{WorkspaceID: workspace.ID, TaskID: task.ID})
+	assert.Nil(t, err)
+	assert.Greater(t, count, int64(0))
+
+	_, count, err = db.Connection.ListIssues(db.IssueFilter{WorkspaceID: workspace.ID, TaskID: task.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	jobs, err := db.Connection.ListPendingTaskJobs(task.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestFinalizeTimeLimitedTask(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestFinalizeTimeLimitedTask",
+		Title: "TestFinalizeTimeLimitedTask",
+	})
+	assert.Nil(t, err)
+	defer db.Connection.DeleteWorkspace(workspace.ID)
+	viper.Set("scan.max_duration.grace_period", 1)
+	defer viper.Set("scan.max_duration.grace_period", 60)
+
+	task, err := db.Connection.NewTask(workspace.ID, nil, "Time limited test", db.TaskStatusScanning, db.TaskTypeScan)
+	assert.Nil(t, err)
+	history, err := db.Connection.CreateHistory(&db.History{
+		URL:         "https://time-limited.example.com/",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+		TaskID:      &task.ID,
+	})
+	assert.Nil(t, err)
+	for _, status := range []db.TaskJobStatus{db.TaskJobFinished, db.TaskJobScheduled, db.TaskJobScheduled} {
+		_, err := db.Connection.CreateTaskJob(&db.TaskJob{
+			TaskID:    task.ID,
+			Title:     "Active scan to " + history.URL,
+			Status:    status,
+			StartedAt: time.Now(),
+			HistoryID: history.ID,
+		})
+		assert.Nil(t, err)
+	}
+
+	engine := NewScanEngine(nil, 2, 2, nil)
+	defer engine.Stop()
+	engine.taskDeadlines.Store(task.ID, time.Now().Add(-time.Second))
+	assert.True(t, engine.isTaskTimeLimited(task.ID))
+	assert.True(t, engine.waitForTaskCompletion(task.ID))
+
+	limited, err := db.Connection.GetTaskByID(task.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, db.TaskStatusTimeLimited, limited.Status)
+	assert.False(t, engine.isTaskTimeLimited(task.ID))
+
+	counts, err := db.Connection.CountTaskJobsByStatus(task.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), counts[db.TaskJobFinished])
+	assert.Equal(t, int64(2), counts[db.TaskJobCancelled])
+
+	events, _, err := db.Connection.ListScanEvents(db.ScanEventFilter{TaskID: task.ID, Types: []string{string(db.ScanEventScanTimeLimited)}})
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+
+	_, count, err := db.Connection.ListHistory(db.HistoryFilter{WorkspaceID: workspace.ID, TaskID: task.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestFullScanMaxDuration(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestFullScanMaxDuration",
+		Title: "TestFullScanMaxDuration",
+	})
+	assert.Nil(t, err)
+	defer db.Connection.DeleteWorkspace(workspace.ID)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><a href="/search?q=test">Search</a><p>%s</p></body></html>`, r.URL.Query().Get("q"))
+	}))
+	defer server.Close()
+
+	engine := NewScanEngine(nil, 2, 2, nil)
+	defer engine.Stop()
+	// The deadline is reached while crawling, so the crawled requests are kept but no audit is scheduled
+	task, err := engine.FullScan(scan_options.FullScanOptions{
+		Title:           "Max duration test",
+		StartURLs:       []string{server.URL},
+		WorkspaceID:     workspace.ID,
+		MaxPagesToCrawl: 5,
+		MaxDepth:        2,
+		PagesPoolSize:   2,
+		MaxDuration:     time.Millisecond,
+		AuditCategories: scan_options.AuditCategories{
+			ServerSide: true,
+			ClientSide: true,
+			Passive:    true,
+		},
+	}, true)
+	assert.Nil(t, err)
+
+	limited, err := db.Connection.GetTaskByID(task.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, db.TaskStatusTimeLimited, limited.Status)
+
+	_, count, err := db.Connection.ListHistory(db.HistoryFilter{WorkspaceID: workspace.ID, TaskID: task.ID})
+	assert.Nil(t, err)
+	assert.Greater(t, count, int64(0))
+
+	_, count, err = db.Connection.ListTaskJobs(db.TaskJobFilter{TaskID: task.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+}
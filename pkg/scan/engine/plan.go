@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/active"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/pyneda/sukyan/pkg/scope"
+	"github.com/rs/zerolog/log"
+)
+
+// ScanPlan describes what an active scan of a set of history items would test
+type ScanPlan struct {
+	Items []active.HistoryItemScanPlan `json:"items"`
+	// Skipped is the number of history items that would not be scanned as they are excluded or out of scope
+	Skipped int `json:"skipped"`
+	// Modules holds the number of history items each module would be run against
+	Modules map[string]int `json:"modules"`
+	// Payloads is the total number of payloads that would be sent by the payload generators
+	Payloads int `json:"payloads"`
+}
+
+// PlanScan returns what an active scan of the history items would test with the provided options, going through the
+// same selection of history items, modules, insertion points and payload generators as a real scan but without any
+// network or browser activity
+func (s *ScanEngine) PlanScan(items []*db.History, options scan_options.HistoryItemScanOptions) (*ScanPlan, error) {
+	exclusions, err := scope.NewURLExclusions(options.ExcludeURLs)
+	if err != nil {
+		return nil, err
+	}
+	scopeRules, err := options.Scope.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ScanPlan{
+		Items:   make([]active.HistoryItemScanPlan, 0, len(items)),
+		Modules: make(map[string]int),
+	}
+	uniqueItems := removeDuplicateHistoryItems(items)
+	plan.Skipped = len(items) - len(uniqueItems)
+	for _, item := range uniqueItems {
+		if exclusions.IsExcluded(item.URL) || !scopeRules.InScope(item.URL, item.Method) {
+			plan.Skipped++
+			continue
+		}
+		itemPlan, err := active.PlanHistoryItemScan(item, s.payloadGenerators, options)
+		if err != nil {
+			log.Error().Err(err).Uint("history", item.ID).Str("url", item.URL).Msg("Could not plan history item scan")
+			return nil, err
+		}
+		for _, module := range itemPlan.Modules {
+			plan.Modules[module]++
+		}
+		plan.Payloads += itemPlan.Payloads
+		plan.Items = append(plan.Items, itemPlan)
+	}
+	log.Info().Int("items", len(plan.Items)).Int("skipped", plan.Skipped).Int("payloads", plan.Payloads).Msg("Scan plan created")
+	return plan, nil
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ScanProgress is a snapshot of the progress of a scan, sent to the subscribers every time it changes
+type ScanProgress struct {
+	TaskID        uint          `json:"task_id"`
+	Status        string        `json:"status"`
+	TotalJobs     int64         `json:"total_jobs"`
+	RunningJobs   int64         `json:"running_jobs"`
+	CompletedJobs int64         `json:"completed_jobs"`
+	IssuesFound   int64         `json:"issues_found"`
+	CurrentTarget string        `json:"current_target,omitempty"`
+	LastEvent     *db.ScanEvent `json:"last_event,omitempty"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// Finished returns true when the scan will not progress anymore
+func (p ScanProgress) Finished() bool {
+	return p.Status == db.TaskStatusFinished || p.Status == db.TaskStatusFailed || p.Status == db.TaskStatusTimeLimited
+}
+
+// ProgressBroker keeps the latest progress of each scan and publishes it to its subscribers. Subscribers only get the
+// latest snapshot, so slow ones skip intermediate updates instead of blocking the scan.
+type ProgressBroker struct {
+	mu          sync.Mutex
+	snapshots   map[uint]*ScanProgress
+	subscribers map[uint]map[chan ScanProgress]struct{}
+	// load builds the progress of the scans which have not been tracked by the broker yet
+	load func(taskID uint) ScanProgress
+}
+
+// NewProgressBroker creates a broker which uses the load function to build the initial progress of each scan
+func NewProgressBroker(load func(taskID uint) ScanProgress) *ProgressBroker {
+	return &ProgressBroker{
+		snapshots:   make(map[uint]*ScanProgress),
+		subscribers: make(map[uint]map[chan ScanProgress]struct{}),
+		load:        load,
+	}
+}
+
+// snapshot returns the progress of the scan, loading it if needed. It must be called with the lock held.
+func (b *ProgressBroker) snapshot(taskID uint) *ScanProgress {
+	progress, ok := b.snapshots[taskID]
+	if !ok {
+		loaded := ScanProgress{TaskID: taskID}
+		if b.load != nil {
+			loaded = b.load(taskID)
+		}
+		progress = &loaded
+		b.snapshots[taskID] = progress
+	}
+	return progress
+}
+
+// Subscribe returns a channel receiving the progress of the scan, starting with its latest snapshot, and the function
+// to call once the subscriber is done, which closes the channel
+func (b *ProgressBroker) Subscribe(taskID uint) (<-chan ScanProgress, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan ScanProgress, 1)
+	ch <- *b.snapshot(taskID)
+	if b.subscribers[taskID] == nil {
+		b.subscribers[taskID] = make(map[chan ScanProgress]struct{})
+	}
+	b.subscribers[taskID][ch] = struct{}{}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[taskID], ch)
+			close(ch)
+			b.forgetIfDone(taskID)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Update applies the changes to the progress of the scan and publishes it to the subscribers
+func (b *ProgressBroker) Update(taskID uint, update func(progress *ScanProgress)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	progress := b.snapshot(taskID)
+	update(progress)
+	progress.UpdatedAt = time.Now()
+	for ch := range b.subscribers[taskID] {
+		// Replace the pending snapshot if the subscriber has not read it yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- *progress
+	}
+	b.forgetIfDone(taskID)
+}
+
+// forgetIfDone removes the state of finished scans without subscribers, which can be loaded again if needed. It must
+// be called with the lock held.
+func (b *ProgressBroker) forgetIfDone(taskID uint) {
+	if len(b.subscribers[taskID]) > 0 {
+		return
+	}
+	delete(b.subscribers, taskID)
+	if progress, ok := b.snapshots[taskID]; ok && progress.Finished() {
+		delete(b.snapshots, taskID)
+	}
+}
+
+// Snapshot returns the latest progress of the scan
+func (b *ProgressBroker) Snapshot(taskID uint) ScanProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return *b.snapshot(taskID)
+}
+
+// loadScanProgress builds the progress of a scan from the database, used for the scans started before the process
+// was restarted or by another engine
+func loadScanProgress(taskID uint) ScanProgress {
+	progress := ScanProgress{TaskID: taskID, UpdatedAt: time.Now()}
+	task, err := db.Connection.GetTaskByID(taskID, false)
+	if err != nil {
+		return progress
+	}
+	progress.Status = task.Status
+	jobs, err := db.Connection.CountTaskJobsByStatus(taskID)
+	if err != nil {
+		log.Error().Err(err).Uint("task", taskID).Msg("Could not count task jobs to build the scan progress")
+	}
+	for status, count := range jobs {
+		progress.TotalJobs += count
+		switch status {
+		case db.TaskJobRunning:
+			progress.RunningJobs = count
+		case db.TaskJobFinished, db.TaskJobFailed:
+			progress.CompletedJobs += count
+		}
+	}
+	if progress.IssuesFound, err = db.Connection.CountTaskIssues(taskID); err != nil {
+		log.Error().Err(err).Uint("task", taskID).Msg("Could not count task issues to build the scan progress")
+	}
+	event, err := db.Connection.GetLatestScanEvent(taskID)
+	if err == nil {
+		progress.LastEvent = event
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error().Err(err).Uint("task", taskID).Msg("Could not get the latest scan event to build the scan progress")
+	}
+	return progress
+}
+
+// SubscribeProgress returns a channel receiving the progress of the scan, starting with its latest snapshot, and the
+// function to call to stop receiving it
+func (s *ScanEngine) SubscribeProgress(taskID uint) (<-chan ScanProgress, func()) {
+	return s.progress.Subscribe(taskID)
+}
+
+// recordScanEvent stores the scan event and publishes it as the latest event of the scan
+func (s *ScanEngine) recordScanEvent(taskID uint, eventType db.ScanEventType, module, message string, details map[string]interface{}) {
+	event, err := db.Connection.NewScanEvent(taskID, nil, eventType, module, message, details)
+	if err != nil {
+		return
+	}
+	s.progress.Update(taskID, func(progress *ScanProgress) {
+		progress.LastEvent = event
+	})
+}
+
+// setTaskStatus updates the status of the task and publishes it
+func (s *ScanEngine) setTaskStatus(taskID uint, status string) error {
+	if err := db.Connection.SetTaskStatus(taskID, status); err != nil {
+		return err
+	}
+	s.progress.Update(taskID, func(progress *ScanProgress) {
+		progress.Status = status
+		if progress.Finished() {
+			progress.CurrentTarget = ""
+		}
+	})
+	return nil
+}
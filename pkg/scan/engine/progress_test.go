@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/db"
+	scan_options "github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressBrokerReplaysLatestSnapshot(t *testing.T) {
+	loads := 0
+	broker := NewProgressBroker(func(taskID uint) ScanProgress {
+		loads++
+		return ScanProgress{TaskID: taskID, Status: db.TaskStatusScanning, TotalJobs: 5}
+	})
+
+	broker.Update(1, func(progress *ScanProgress) {
+		progress.CompletedJobs = 2
+		progress.CurrentTarget = "https://progress.example.com/a"
+	})
+
+	updates, unsubscribe := broker.Subscribe(1)
+	progress := <-updates
+	assert.Equal(t, uint(1), progress.TaskID)
+	assert.Equal(t, int64(5), progress.TotalJobs)
+	assert.Equal(t, int64(2), progress.CompletedJobs)
+	assert.Equal(t, "https://progress.example.com/a", progress.CurrentTarget)
+	assert.Equal(t, 1, loads)
+
+	// Updates not read yet are replaced by the latest one
+	for i := 3; i <= 5; i++ {
+		completed := int64(i)
+		broker.Update(1, func(progress *ScanProgress) {
+			progress.CompletedJobs = completed
+		})
+	}
+	progress = <-updates
+	assert.Equal(t, int64(5), progress.CompletedJobs)
+
+	unsubscribe()
+	_, open := <-updates
+	assert.False(t, open)
+	unsubscribe()
+
+	// Finished scans are forgotten once they have no subscribers, so their progress is loaded again
+	broker.Update(1, func(progress *ScanProgress) {
+		progress.Status = db.TaskStatusFinished
+	})
+	assert.Equal(t, db.TaskStatusScanning, broker.Snapshot(1).Status)
+	assert.Equal(t, 2, loads)
+}
+
+func TestProgressBrokerMultipleSubscribers(t *testing.T) {
+	broker := NewProgressBroker(nil)
+	first, unsubscribeFirst := broker.Subscribe(7)
+	second, unsubscribeSecond := broker.Subscribe(7)
+	defer unsubscribeSecond()
+	<-first
+	<-second
+
+	unsubscribeFirst()
+	broker.Update(7, func(progress *ScanProgress) {
+		progress.IssuesFound = 3
+	})
+	progress := <-second
+	assert.Equal(t, int64(3), progress.IssuesFound)
+
+	other, unsubscribeOther := broker.Subscribe(8)
+	defer unsubscribeOther()
+	assert.Equal(t, uint(8), (<-other).TaskID)
+	select {
+	case progress := <-other:
+		t.Fatalf("Unexpected progress of another scan received: %+v", progress)
+	default:
+	}
+}
+
+func TestScanProgressPublishedBySchedulingJobs(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestScanProgressPublishedBySchedulingJobs",
+		Title: "TestScanProgressPublishedBySchedulingJobs",
+	})
+	assert.Nil(t, err)
+	defer db.Connection.DeleteWorkspace(workspace.ID)
+
+	task, err := db.Connection.NewTask(workspace.ID, nil, "Scan progress test", db.TaskStatusScanning, db.TaskTypeScan)
+	assert.Nil(t, err)
+	history, err := db.Connection.CreateHistory(&db.History{
+		URL:         "https://progress.example.com/",
+		Method:      "GET",
+		StatusCode:  200,
+		WorkspaceID: &workspace.ID,
+		TaskID:      &task.ID,
+	})
+	assert.Nil(t, err)
+
+	engine := NewScanEngine(nil, 2, 2, nil)
+	defer engine.Stop()
+	updates, unsubscribe := engine.SubscribeProgress(task.ID)
+	defer unsubscribe()
+
+	initial := <-updates
+	assert.Equal(t, db.TaskStatusScanning, initial.Status)
+	assert.Equal(t, int64(0), initial.TotalJobs)
+
+	// The URL is excluded so that jobs complete without sending any request
+	options := scan_options.HistoryItemScanOptions{
+		WorkspaceID: workspace.ID,
+		TaskID:      task.ID,
+		ExcludeURLs: []string{"*"},
+	}
+	for i := 0; i < 3; i++ {
+		engine.scheduleActiveScan(history, options)
+	}
+	go engine.waitForTaskCompletion(task.ID)
+
+	var last ScanProgress
+	timeout := time.After(15 * time.Second)
+	for !last.Finished() {
+		select {
+		case last = <-updates:
+		case <-timeout:
+			t.Fatalf("Scan progress did not finish, last received: %+v", last)
+		}
+	}
+	assert.Equal(t, int64(3), last.TotalJobs)
+	assert.Equal(t, int64(3), last.CompletedJobs)
+	assert.Equal(t, int64(0), last.RunningJobs)
+	if assert.NotNil(t, last.LastEvent) {
+		assert.Equal(t, db.ScanEventScanCompleted, last.LastEvent.Type)
+	}
+}
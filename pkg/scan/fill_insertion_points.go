@@ -164,6 +164,8 @@ func CreateRequestFromInsertionPoints(history *db.History, builders []InsertionP
 	var contentType string
 	var err error
 	var bodyBuilders []InsertionPointBuilder
+	var preservedCookies []string
+	preserveAllCookies := false
 
 	for _, builder := range builders {
 		switch builder.Point.Type {
@@ -187,6 +189,9 @@ func CreateRequestFromInsertionPoints(history *db.History, builders []InsertionP
 			for name, values := range h {
 				headers[name] = values
 			}
+			if strings.EqualFold(builder.Point.Name, "Cookie") {
+				preserveAllCookies = true
+			}
 		case InsertionPointTypeCookie:
 			h, err := createRequestFromCookie(history, builder)
 			if err != nil {
@@ -195,6 +200,7 @@ func CreateRequestFromInsertionPoints(history *db.History, builders []InsertionP
 			for name, values := range h {
 				headers[name] = values
 			}
+			preservedCookies = append(preservedCookies, builder.Point.Name)
 		case InsertionPointTypeBody:
 			bodyBuilders = append(bodyBuilders, builder)
 		// case InsertionPointTypeFullBody:
@@ -246,5 +252,12 @@ func CreateRequestFromInsertionPoints(history *db.History, builders []InsertionP
 		req.Header.Set("Content-Type", contentType)
 	}
 
+	// Cookies holding payloads must not be replaced by the renewed session cookies
+	if preserveAllCookies {
+		req = http_utils.PreserveRequestCookies(req)
+	} else if len(preservedCookies) > 0 {
+		req = http_utils.PreserveRequestCookies(req, preservedCookies...)
+	}
+
 	return req, nil
 }
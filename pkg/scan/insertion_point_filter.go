@@ -0,0 +1,47 @@
+package scan
+
+import (
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/scan/options"
+)
+
+// GetName returns the name of the insertion point
+func (i InsertionPoint) GetName() string {
+	return i.Name
+}
+
+// GetLocation returns the location of the insertion point as used by the insertion point filters
+func (i InsertionPoint) GetLocation() string {
+	switch i.Type {
+	case InsertionPointTypeParameter:
+		return options.InsertionPointLocationQuery
+	case InsertionPointTypeURLPath:
+		return options.InsertionPointLocationPath
+	case InsertionPointTypeHeader:
+		return options.InsertionPointLocationHeader
+	case InsertionPointTypeCookie:
+		return options.InsertionPointLocationCookie
+	default:
+		// Body parameters, full bodies and the JSON and binary fields of WebSocket messages
+		return options.InsertionPointLocationBody
+	}
+}
+
+// GetValueType returns the data type of the insertion point value
+func (i InsertionPoint) GetValueType() lib.DataType {
+	return i.ValueType
+}
+
+// FilterInsertionPoints returns the insertion points matching the filter
+func FilterInsertionPoints(insertionPoints []InsertionPoint, filter options.InsertionPointFilter) []InsertionPoint {
+	if filter.IsEmpty() {
+		return insertionPoints
+	}
+	filtered := make([]InsertionPoint, 0, len(insertionPoints))
+	for _, insertionPoint := range insertionPoints {
+		if filter.Matches(insertionPoint) {
+			filtered = append(filtered, insertionPoint)
+		}
+	}
+	return filtered
+}
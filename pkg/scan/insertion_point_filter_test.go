@@ -0,0 +1,54 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterInsertionPoints(t *testing.T) {
+	history := &db.History{
+		URL:                "https://example.com/api/users?page=2&sort=name",
+		Method:             "POST",
+		RequestHeaders:     []byte(`{"Content-Type":["application/json"],"Cookie":["session=abc123"]}`),
+		RequestBody:        []byte(`{"name":"John Smith","age":30,"email":"john@example.com"}`),
+		RequestContentType: "application/json",
+	}
+	insertionPoints, err := GetInsertionPoints(history, []string{"parameters", "cookies"})
+	assert.Nil(t, err)
+
+	all := FilterInsertionPoints(insertionPoints, options.InsertionPointFilter{})
+	assert.Equal(t, insertionPoints, all)
+
+	bodyStrings := FilterInsertionPoints(insertionPoints, options.InsertionPointFilter{
+		Locations: []string{options.InsertionPointLocationBody},
+		DataTypes: []lib.DataType{lib.TypeString},
+	})
+	var names []string
+	for _, insertionPoint := range bodyStrings {
+		names = append(names, insertionPoint.Name)
+	}
+	assert.ElementsMatch(t, []string{"name"}, names)
+
+	query := FilterInsertionPoints(insertionPoints, options.InsertionPointFilter{
+		Locations:    []string{options.InsertionPointLocationQuery, options.InsertionPointLocationCookie},
+		ExcludeNames: []string{"sort"},
+	})
+	names = nil
+	for _, insertionPoint := range query {
+		names = append(names, insertionPoint.Name)
+	}
+	assert.ElementsMatch(t, []string{"page", "session"}, names)
+}
+
+func TestInsertionPointGetLocation(t *testing.T) {
+	assert.Equal(t, "query", InsertionPoint{Type: InsertionPointTypeParameter}.GetLocation())
+	assert.Equal(t, "path", InsertionPoint{Type: InsertionPointTypeURLPath}.GetLocation())
+	assert.Equal(t, "header", InsertionPoint{Type: InsertionPointTypeHeader}.GetLocation())
+	assert.Equal(t, "cookie", InsertionPoint{Type: InsertionPointTypeCookie}.GetLocation())
+	assert.Equal(t, "body", InsertionPoint{Type: InsertionPointTypeBody}.GetLocation())
+	assert.Equal(t, "body", InsertionPoint{Type: InsertionPointTypeJSON}.GetLocation())
+}
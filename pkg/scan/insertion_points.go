@@ -8,6 +8,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/pyneda/sukyan/db"
@@ -24,6 +25,8 @@ const (
 	InsertionPointTypeCookie    InsertionPointType = "cookie"
 	InsertionPointTypeURLPath   InsertionPointType = "urlpath"
 	InsertionPointTypeFullBody  InsertionPointType = "fullbody"
+	InsertionPointTypeBinary    InsertionPointType = "binary"
+	InsertionPointTypeJSON      InsertionPointType = "json"
 )
 
 type InsertionPoint struct {
@@ -54,13 +57,24 @@ func (i *InsertionPoint) String() string {
 	return fmt.Sprintf("%s: %s", i.Type, i.Name)
 }
 
+// sortedKeys returns the keys of the map sorted, so that insertion points built from maps are always in the same order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Handle URL parameters
 func handleURLParameters(urlData *url.URL) ([]InsertionPoint, error) {
 	var points []InsertionPoint
 
 	// URL parameters
-	for name, values := range urlData.Query() {
-		for _, value := range values {
+	query := urlData.Query()
+	for _, name := range sortedKeys(query) {
+		for _, value := range query[name] {
 			points = append(points, InsertionPoint{
 				Type:         "parameter",
 				Name:         name,
@@ -98,11 +112,11 @@ func handleURLPaths(urlData *url.URL) ([]InsertionPoint, error) {
 // Handle Headers
 func handleHeaders(header map[string][]string) ([]InsertionPoint, error) {
 	var points []InsertionPoint
-	for name, values := range header {
+	for _, name := range sortedKeys(header) {
 		if name == "cookie" {
 			continue
 		}
-		for _, value := range values {
+		for _, value := range header[name] {
 			points = append(points, InsertionPoint{
 				Type:      InsertionPointTypeHeader,
 				Name:      name,
@@ -155,8 +169,8 @@ func handleBodyParameters(contentType string, body []byte) ([]InsertionPoint, er
 			return nil, err
 		}
 
-		for name, values := range formData {
-			for _, value := range values {
+		for _, name := range sortedKeys(formData) {
+			for _, value := range formData[name] {
 				points = append(points, InsertionPoint{
 					Type:      InsertionPointTypeBody,
 					Name:      name,
@@ -177,8 +191,8 @@ func handleBodyParameters(contentType string, body []byte) ([]InsertionPoint, er
 			return nil, err
 		}
 
-		for name, value := range jsonData {
-			valueStr := fmt.Sprintf("%v", value)
+		for _, name := range sortedKeys(jsonData) {
+			valueStr := fmt.Sprintf("%v", jsonData[name])
 			points = append(points, InsertionPoint{
 				Type:      InsertionPointTypeBody,
 				Name:      name,
@@ -198,8 +212,8 @@ func handleBodyParameters(contentType string, body []byte) ([]InsertionPoint, er
 			return nil, err
 		}
 
-		for name, value := range xmlData {
-			valueStr := fmt.Sprintf("%v", value)
+		for _, name := range sortedKeys(xmlData) {
+			valueStr := fmt.Sprintf("%v", xmlData[name])
 
 			points = append(points, InsertionPoint{
 				Type:      InsertionPointTypeBody,
@@ -230,8 +244,8 @@ func handleBodyParameters(contentType string, body []byte) ([]InsertionPoint, er
 			return nil, err
 		}
 
-		for name, values := range form.Value {
-			for _, value := range values {
+		for _, name := range sortedKeys(form.Value) {
+			for _, value := range form.Value[name] {
 				points = append(points, InsertionPoint{
 					Type:      InsertionPointTypeBody,
 					Name:      name,
@@ -0,0 +1,115 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONLeaf is a scalar value of a JSON document, located by its JSON pointer (RFC 6901) and byte range
+type JSONLeaf struct {
+	Pointer string
+	Value   string
+	Start   int
+	End     int
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// isJSONDocument checks if the data is a valid JSON object or array
+func isJSONDocument(data string) bool {
+	trimmed := strings.TrimSpace(data)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	return json.Valid([]byte(trimmed))
+}
+
+// FindJSONLeaves returns the scalar values of the JSON document in the order they appear
+func FindJSONLeaves(data []byte) ([]JSONLeaf, error) {
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("invalid JSON document")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var leaves []JSONLeaf
+	var walk func(pointer string) error
+	walk = func(pointer string) error {
+		start := skipJSONSeparators(data, int(decoder.InputOffset()))
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case json.Delim:
+			for index := 0; decoder.More(); index++ {
+				key := strconv.Itoa(index)
+				if t == '{' {
+					keyToken, err := decoder.Token()
+					if err != nil {
+						return err
+					}
+					key = jsonPointerEscaper.Replace(keyToken.(string))
+				}
+				if err := walk(pointer + "/" + key); err != nil {
+					return err
+				}
+			}
+			// Consume the closing delimiter
+			_, err = decoder.Token()
+			return err
+		case nil:
+			leaves = append(leaves, JSONLeaf{Pointer: pointer, Value: "", Start: start, End: int(decoder.InputOffset())})
+		default:
+			leaves = append(leaves, JSONLeaf{Pointer: pointer, Value: fmt.Sprintf("%v", t), Start: start, End: int(decoder.InputOffset())})
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
+
+// ReplaceJSONLeaf replaces the value located by the JSON pointer with the payload encoded as a JSON string. The rest of
+// the document is kept byte by byte, so that key order and formatting are preserved.
+func ReplaceJSONLeaf(data []byte, pointer string, payload string) ([]byte, error) {
+	leaves, err := FindJSONLeaves(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, leaf := range leaves {
+		if leaf.Pointer != pointer {
+			continue
+		}
+		// Payloads commonly contain HTML characters which must be sent as they are
+		encoded, err := marshalWithoutHTMLEscape(payload)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]byte, 0, len(data)+len(encoded))
+		result = append(result, data[:leaf.Start]...)
+		result = append(result, encoded...)
+		result = append(result, data[leaf.End:]...)
+		return result, nil
+	}
+	return nil, fmt.Errorf("JSON pointer %s not found", pointer)
+}
+
+// skipJSONSeparators returns the offset of the next value, skipping whitespace and the separators which the decoder
+// consumes lazily
+func skipJSONSeparators(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ':', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
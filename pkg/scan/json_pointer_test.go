@@ -0,0 +1,64 @@
+package scan
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindJSONLeaves(t *testing.T) {
+	data := []byte(`{"action": "subscribe", "user": {"name": "john", "roles": ["admin", "user"], "age": 30}, "a/b": true, "meta": null}`)
+	leaves, err := FindJSONLeaves(data)
+	assert.NoError(t, err)
+
+	pointers := make(map[string]string)
+	for _, leaf := range leaves {
+		pointers[leaf.Pointer] = leaf.Value
+	}
+	assert.Equal(t, map[string]string{
+		"/action":       "subscribe",
+		"/user/name":    "john",
+		"/user/roles/0": "admin",
+		"/user/roles/1": "user",
+		"/user/age":     "30",
+		"/a~1b":         "true",
+		"/meta":         "",
+	}, pointers)
+}
+
+func TestReplaceJSONLeaf(t *testing.T) {
+	data := []byte(`{"type":"message","data":{"items":[{"id":1,"text":"hi"},{"id":2,"text":"bye"}]}}`)
+	payload := `"><script>alert(1)</script>`
+
+	result, err := ReplaceJSONLeaf(data, "/data/items/1/text", payload)
+	assert.NoError(t, err)
+	assert.True(t, json.Valid(result))
+	assert.Equal(t, `{"type":"message","data":{"items":[{"id":1,"text":"hi"},{"id":2,"text":"\"><script>alert(1)</script>"}]}}`, string(result))
+
+	result, err = ReplaceJSONLeaf(data, "/data/items/0/id", "1 OR 1=1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"message","data":{"items":[{"id":"1 OR 1=1","text":"hi"},{"id":2,"text":"bye"}]}}`, string(result))
+
+	_, err = ReplaceJSONLeaf(data, "/data/missing", payload)
+	assert.Error(t, err)
+}
+
+func TestCreateModifiedWebSocketMessageJSON(t *testing.T) {
+	message := &db.WebSocketMessage{
+		Opcode:      1,
+		PayloadData: `[{"channel": "news", "filters": {"tags": ["a", "b"]}}, 5]`,
+	}
+	insertionPoints := GetWebSocketMessageInsertionPoints(message)
+	names := make([]string, 0, len(insertionPoints))
+	for _, insertionPoint := range insertionPoints {
+		assert.Equal(t, InsertionPointTypeJSON, insertionPoint.Type)
+		names = append(names, insertionPoint.Name)
+	}
+	assert.Equal(t, []string{"/0/channel", "/0/filters/tags/0", "/0/filters/tags/1", "/1"}, names)
+
+	modified, err := CreateModifiedWebSocketMessage(message, insertionPoints[2], "{{7*7}}")
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"channel": "news", "filters": {"tags": ["a", "{{7*7}}"]}}, 5]`, modified.PayloadData)
+}
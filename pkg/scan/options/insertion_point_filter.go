@@ -0,0 +1,112 @@
+package options
+
+import (
+	"strings"
+
+	"github.com/pyneda/sukyan/lib"
+)
+
+// Insertion point locations used to filter them
+const (
+	InsertionPointLocationQuery  = "query"
+	InsertionPointLocationPath   = "path"
+	InsertionPointLocationBody   = "body"
+	InsertionPointLocationHeader = "header"
+	InsertionPointLocationCookie = "cookie"
+)
+
+// FilterableInsertionPoint is implemented by the insertion points that can be selected with an InsertionPointFilter
+type FilterableInsertionPoint interface {
+	GetName() string
+	GetLocation() string
+	GetValueType() lib.DataType
+}
+
+// InsertionPointFilter selects the insertion points to scan. Names are globs, where * matches any sequence of
+// characters and ? any single one, matched case insensitively. Exclusions take precedence over inclusions, and empty
+// inclusions match everything.
+type InsertionPointFilter struct {
+	IncludeNames []string       `json:"include_names,omitempty"`
+	ExcludeNames []string       `json:"exclude_names,omitempty"`
+	Locations    []string       `json:"locations,omitempty" validate:"omitempty,dive,oneof=query path body header cookie"`
+	DataTypes    []lib.DataType `json:"data_types,omitempty"`
+}
+
+// IsEmpty returns true when the filter selects every insertion point
+func (f InsertionPointFilter) IsEmpty() bool {
+	return len(f.IncludeNames) == 0 && len(f.ExcludeNames) == 0 && len(f.Locations) == 0 && len(f.DataTypes) == 0
+}
+
+// Matches checks if the insertion point should be scanned according to the filter
+func (f InsertionPointFilter) Matches(insertionPoint FilterableInsertionPoint) bool {
+	name := insertionPoint.GetName()
+	for _, pattern := range f.ExcludeNames {
+		if matchGlob(pattern, name) {
+			return false
+		}
+	}
+	if len(f.IncludeNames) > 0 {
+		included := false
+		for _, pattern := range f.IncludeNames {
+			if matchGlob(pattern, name) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(f.Locations) > 0 {
+		location := insertionPoint.GetLocation()
+		matched := false
+		for _, l := range f.Locations {
+			if strings.EqualFold(l, location) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(f.DataTypes) > 0 {
+		valueType := string(insertionPoint.GetValueType())
+		for _, dataType := range f.DataTypes {
+			if strings.EqualFold(string(dataType), valueType) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchGlob matches the value against the glob pattern case insensitively. Unlike path.Match, * also matches
+// slashes, as JSON body insertion points are named by their JSON pointer.
+func matchGlob(pattern, value string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	value = strings.ToLower(value)
+	p, v := 0, 0
+	starP, starV := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starV = p, v
+			p++
+		case starP != -1:
+			// Backtrack, letting the last * match one more character
+			starV++
+			p, v = starP+1, starV
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,90 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+type testInsertionPoint struct {
+	name      string
+	location  string
+	valueType lib.DataType
+}
+
+func (p testInsertionPoint) GetName() string            { return p.name }
+func (p testInsertionPoint) GetLocation() string        { return p.location }
+func (p testInsertionPoint) GetValueType() lib.DataType { return p.valueType }
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"id", "id", true},
+		{"id", "uid", false},
+		{"user*", "userName", true},
+		{"*token*", "X-CSRF-Token", true},
+		{"*_id", "account_id", true},
+		{"*_id", "account_id_old", false},
+		{"/user/*", "/user/address/city", true},
+		{"page?", "page2", true},
+		{"page?", "page", false},
+		{"*", "", true},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, matchGlob(tc.pattern, tc.value), "%s against %s", tc.pattern, tc.value)
+	}
+}
+
+func TestInsertionPointFilterMatches(t *testing.T) {
+	query := testInsertionPoint{name: "id", location: InsertionPointLocationQuery, valueType: lib.TypeInt}
+	jsonName := testInsertionPoint{name: "/user/name", location: InsertionPointLocationBody, valueType: lib.TypeString}
+	jsonAge := testInsertionPoint{name: "/user/age", location: InsertionPointLocationBody, valueType: lib.TypeInt}
+	csrf := testInsertionPoint{name: "csrf_token", location: InsertionPointLocationBody, valueType: lib.TypeString}
+	cookie := testInsertionPoint{name: "session", location: InsertionPointLocationCookie, valueType: lib.TypeString}
+
+	empty := InsertionPointFilter{}
+	assert.True(t, empty.IsEmpty())
+	assert.True(t, empty.Matches(query))
+	assert.True(t, empty.Matches(cookie))
+
+	// Only the string fields of the body
+	bodyStrings := InsertionPointFilter{Locations: []string{"body"}, DataTypes: []lib.DataType{"string"}}
+	assert.False(t, bodyStrings.IsEmpty())
+	assert.False(t, bodyStrings.Matches(query))
+	assert.True(t, bodyStrings.Matches(jsonName))
+	assert.False(t, bodyStrings.Matches(jsonAge))
+	assert.True(t, bodyStrings.Matches(csrf))
+	assert.False(t, bodyStrings.Matches(cookie))
+
+	// Exclusions take precedence over inclusions
+	names := InsertionPointFilter{IncludeNames: []string{"/user/*", "*token*"}, ExcludeNames: []string{"CSRF*"}}
+	assert.False(t, names.Matches(query))
+	assert.True(t, names.Matches(jsonName))
+	assert.True(t, names.Matches(jsonAge))
+	assert.False(t, names.Matches(csrf))
+
+	excludeOnly := InsertionPointFilter{ExcludeNames: []string{"session"}}
+	assert.True(t, excludeOnly.Matches(query))
+	assert.False(t, excludeOnly.Matches(cookie))
+}
+
+func TestInsertionPointFilterValidation(t *testing.T) {
+	validate := validator.New()
+	assert.Nil(t, validate.Struct(InsertionPointFilter{Locations: []string{"query", "body"}}))
+	assert.NotNil(t, validate.Struct(InsertionPointFilter{Locations: []string{"fragment"}}))
+	// The filter is validated as part of the scan options
+	err := validate.Struct(FullScanOptions{
+		StartURLs:            []string{"https://example.com"},
+		WorkspaceID:          1,
+		PagesPoolSize:        1,
+		InsertionPointFilter: InsertionPointFilter{Locations: []string{"fragment"}},
+	})
+	assert.ErrorContains(t, err, "InsertionPointFilter.Locations")
+}
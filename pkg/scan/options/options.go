@@ -1,6 +1,11 @@
 package options
 
-import "github.com/pyneda/sukyan/lib"
+import (
+	"time"
+
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/scope"
+)
 
 type ScanMode string
 
@@ -51,15 +56,23 @@ type AuditCategories struct {
 }
 
 type HistoryItemScanOptions struct {
-	WorkspaceID        uint              `json:"workspace_id" validate:"required,min=0"`
-	TaskID             uint              `json:"task_id" validate:"required,min=0"`
-	TaskJobID          uint              `json:"task_job_id" validate:"required,min=0"`
-	Mode               ScanMode          `json:"mode" validate:"omitempty,oneof=fast smart fuzz"`
-	InsertionPoints    []string          `json:"insertion_points" validate:"omitempty,dive,oneof=parameters urlpath body headers cookies json xml"`
-	FingerprintTags    []string          `json:"fingerprint_tags" validate:"omitempty,dive"`
-	Fingerprints       []lib.Fingerprint `json:"fingerprints" validate:"omitempty,dive"`
-	ExperimentalAudits bool              `json:"experimental_audits"`
-	AuditCategories    AuditCategories   `json:"audit_categories" validate:"required"`
+	WorkspaceID          uint                 `json:"workspace_id" validate:"required,min=0"`
+	TaskID               uint                 `json:"task_id" validate:"required,min=0"`
+	TaskJobID            uint                 `json:"task_job_id" validate:"required,min=0"`
+	Mode                 ScanMode             `json:"mode" validate:"omitempty,oneof=fast smart fuzz"`
+	InsertionPoints      []string             `json:"insertion_points" validate:"omitempty,dive,oneof=parameters urlpath body headers cookies json xml"`
+	FingerprintTags      []string             `json:"fingerprint_tags" validate:"omitempty,dive"`
+	Fingerprints         []lib.Fingerprint    `json:"fingerprints" validate:"omitempty,dive"`
+	ExperimentalAudits   bool                 `json:"experimental_audits"`
+	AuditCategories      AuditCategories      `json:"audit_categories" validate:"required"`
+	ExcludeURLs          []string             `json:"exclude_urls" validate:"omitempty"`
+	Scope                scope.ScopeRules     `json:"scope"`
+	InsertionPointFilter InsertionPointFilter `json:"insertion_point_filter"`
+	// RandomSeed shuffles the order in which insertion points and payload generators are tested. Zero keeps the
+	// deterministic sorted order, any other value gives a different order that is the same across runs.
+	RandomSeed int64 `json:"random_seed"`
+	// WAFEvasion retries the payloads blocked by a WAF with mutations that can bypass it
+	WAFEvasion bool `json:"waf_evasion"`
 }
 
 func (o HistoryItemScanOptions) IsScopedInsertionPoint(insertionPoint string) bool {
@@ -75,19 +88,57 @@ func (o HistoryItemScanOptions) IsScopedInsertionPoint(insertionPoint string) bo
 	return false
 }
 
+// RateLimitOptions caps the requests sent to each registrable domain during the scan
+type RateLimitOptions struct {
+	RequestsPerSecond float64 `json:"requests_per_second" validate:"min=0"`
+	Burst             int     `json:"burst" validate:"min=0"`
+}
+
+// SessionRenewalOptions configures how the scanner logs in again when the session expires during the scan, replaying
+// stored browser actions when a response matches the logged out signature, which has to be provided explicitly.
+type SessionRenewalOptions struct {
+	BrowserActionsID         *uint  `json:"browser_actions_id" validate:"omitempty"`
+	LoggedOutStatusCodes     []int  `json:"logged_out_status_codes" validate:"omitempty,dive,min=100,max=599"`
+	LoggedOutLocationPattern string `json:"logged_out_location_pattern" validate:"omitempty"`
+	LoggedOutBodyPattern     string `json:"logged_out_body_pattern" validate:"omitempty"`
+}
+
+// Enabled returns true when the session should be renewed during the scan
+func (o SessionRenewalOptions) Enabled() bool {
+	return o.BrowserActionsID != nil && *o.BrowserActionsID != 0
+}
+
 type FullScanOptions struct {
-	Title              string              `json:"title" validate:"omitempty,min=1,max=255"`
-	StartURLs          []string            `json:"start_urls" validate:"required,dive,url"`
-	MaxDepth           int                 `json:"max_depth" validate:"min=0"`
-	MaxPagesToCrawl    int                 `json:"max_pages_to_crawl" validate:"min=0"`
-	ExcludePatterns    []string            `json:"exclude_patterns"`
-	WorkspaceID        uint                `json:"workspace_id" validate:"required,min=0"`
-	PagesPoolSize      int                 `json:"pages_pool_size" validate:"min=1,max=100"`
-	Headers            map[string][]string `json:"headers" validate:"omitempty"`
-	InsertionPoints    []string            `json:"insertion_points" validate:"omitempty,dive,oneof=parameters urlpath body headers cookies json xml"`
-	Mode               ScanMode            `json:"mode" validate:"omitempty,oneof=fast smart fuzz"`
-	ExperimentalAudits bool                `json:"experimental_audits"`
-	AuditCategories    AuditCategories     `json:"audit_categories" validate:"required"`
+	Title                string                `json:"title" validate:"omitempty,min=1,max=255"`
+	StartURLs            []string              `json:"start_urls" validate:"required,dive,url"`
+	MaxDepth             int                   `json:"max_depth" validate:"min=0"`
+	MaxPagesToCrawl      int                   `json:"max_pages_to_crawl" validate:"min=0"`
+	ExcludePatterns      []string              `json:"exclude_patterns"`
+	ExcludeURLs          []string              `json:"exclude_urls" validate:"omitempty"`
+	WorkspaceID          uint                  `json:"workspace_id" validate:"required,min=0"`
+	PagesPoolSize        int                   `json:"pages_pool_size" validate:"min=1,max=100"`
+	Headers              map[string][]string   `json:"headers" validate:"omitempty"`
+	ExtraHeaders         map[string]string     `json:"extra_headers" validate:"omitempty"`
+	UserAgent            string                `json:"user_agent" validate:"omitempty,max=1024"`
+	HTTPVersion          string                `json:"http_version" validate:"omitempty,oneof=http1 http2 http3"`
+	InsertionPoints      []string              `json:"insertion_points" validate:"omitempty,dive,oneof=parameters urlpath body headers cookies json xml"`
+	Mode                 ScanMode              `json:"mode" validate:"omitempty,oneof=fast smart fuzz"`
+	ExperimentalAudits   bool                  `json:"experimental_audits"`
+	AuditCategories      AuditCategories       `json:"audit_categories" validate:"required"`
+	RateLimit            RateLimitOptions      `json:"rate_limit"`
+	Scope                scope.ScopeRules      `json:"scope"`
+	InsertionPointFilter InsertionPointFilter  `json:"insertion_point_filter"`
+	SessionRenewal       SessionRenewalOptions `json:"session_renewal"`
+	ProfileID            *uint                 `json:"profile_id" validate:"omitempty"`
+	// CrawlOnly stops the scan after crawling, building the site map without scheduling any audit
+	CrawlOnly bool `json:"crawl_only"`
+	// MaxDuration limits how long the scan runs. Once reached, no more audits are started and the scan is finalized
+	// as time limited after the running ones finish. Zero means no limit, nanoseconds when provided as JSON.
+	MaxDuration time.Duration `json:"max_duration" validate:"min=0" swaggertype:"integer"`
+	// RandomSeed is passed to the history item scans, see HistoryItemScanOptions.RandomSeed
+	RandomSeed int64 `json:"random_seed"`
+	// WAFEvasion is passed to the history item scans, see HistoryItemScanOptions.WAFEvasion
+	WAFEvasion bool `json:"waf_evasion"`
 }
 
 func GetValidInsertionPoints() []string {
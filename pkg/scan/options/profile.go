@@ -0,0 +1,89 @@
+package options
+
+import "github.com/pyneda/sukyan/pkg/scope"
+
+// ScanProfileOptions holds the options stored in a scan profile. They are used as defaults for the scans launched
+// with the profile, so any option explicitly provided when launching the scan takes precedence.
+type ScanProfileOptions struct {
+	Mode                 ScanMode             `json:"mode,omitempty" validate:"omitempty,oneof=fast smart fuzz"`
+	InsertionPoints      []string             `json:"insertion_points,omitempty" validate:"omitempty,dive,oneof=parameters urlpath body headers cookies json xml"`
+	AuditCategories      AuditCategories      `json:"audit_categories"`
+	ExperimentalAudits   bool                 `json:"experimental_audits"`
+	MaxDepth             int                  `json:"max_depth,omitempty" validate:"min=0"`
+	MaxPagesToCrawl      int                  `json:"max_pages_to_crawl,omitempty" validate:"min=0"`
+	PagesPoolSize        int                  `json:"pages_pool_size,omitempty" validate:"min=0,max=100"`
+	ExcludePatterns      []string             `json:"exclude_patterns,omitempty"`
+	ExcludeURLs          []string             `json:"exclude_urls,omitempty"`
+	Headers              map[string][]string  `json:"headers,omitempty"`
+	ExtraHeaders         map[string]string    `json:"extra_headers,omitempty"`
+	UserAgent            string               `json:"user_agent,omitempty" validate:"omitempty,max=1024"`
+	HTTPVersion          string               `json:"http_version,omitempty" validate:"omitempty,oneof=http1 http2 http3"`
+	RateLimit            RateLimitOptions     `json:"rate_limit"`
+	Scope                scope.ScopeRules     `json:"scope"`
+	InsertionPointFilter InsertionPointFilter `json:"insertion_point_filter"`
+}
+
+// ApplyProfile returns the options filling the ones that have not been provided with the values of the profile
+func (o FullScanOptions) ApplyProfile(profile ScanProfileOptions) FullScanOptions {
+	if o.Mode == "" {
+		o.Mode = profile.Mode
+	}
+	if len(o.InsertionPoints) == 0 {
+		o.InsertionPoints = profile.InsertionPoints
+	}
+	if !o.AuditCategories.Discovery && !o.AuditCategories.ServerSide && !o.AuditCategories.ClientSide && !o.AuditCategories.Passive {
+		o.AuditCategories = profile.AuditCategories
+	}
+	o.ExperimentalAudits = o.ExperimentalAudits || profile.ExperimentalAudits
+	if o.MaxDepth == 0 {
+		o.MaxDepth = profile.MaxDepth
+	}
+	if o.MaxPagesToCrawl == 0 {
+		o.MaxPagesToCrawl = profile.MaxPagesToCrawl
+	}
+	if o.PagesPoolSize == 0 {
+		o.PagesPoolSize = profile.PagesPoolSize
+	}
+	if len(o.ExcludePatterns) == 0 {
+		o.ExcludePatterns = profile.ExcludePatterns
+	}
+	if len(o.ExcludeURLs) == 0 {
+		o.ExcludeURLs = profile.ExcludeURLs
+	}
+	if len(profile.Headers) > 0 {
+		headers := make(map[string][]string, len(profile.Headers)+len(o.Headers))
+		for name, values := range profile.Headers {
+			headers[name] = values
+		}
+		for name, values := range o.Headers {
+			headers[name] = values
+		}
+		o.Headers = headers
+	}
+	if len(profile.ExtraHeaders) > 0 {
+		extraHeaders := make(map[string]string, len(profile.ExtraHeaders)+len(o.ExtraHeaders))
+		for name, value := range profile.ExtraHeaders {
+			extraHeaders[name] = value
+		}
+		for name, value := range o.ExtraHeaders {
+			extraHeaders[name] = value
+		}
+		o.ExtraHeaders = extraHeaders
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = profile.UserAgent
+	}
+	if o.HTTPVersion == "" {
+		o.HTTPVersion = profile.HTTPVersion
+	}
+	if o.RateLimit.RequestsPerSecond == 0 {
+		o.RateLimit = profile.RateLimit
+	}
+	if o.Scope.IsEmpty() {
+		o.Scope = profile.Scope
+	}
+	if o.InsertionPointFilter.IsEmpty() {
+		o.InsertionPointFilter = profile.InsertionPointFilter
+	}
+	return o
+}
@@ -0,0 +1,68 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/pkg/scope"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyProfile(t *testing.T) {
+	profile := ScanProfileOptions{
+		Mode:            ScanModeFuzz,
+		InsertionPoints: []string{"parameters", "body"},
+		AuditCategories: AuditCategories{ServerSide: true, Passive: true},
+		MaxDepth:        3,
+		PagesPoolSize:   8,
+		ExcludeURLs:     []string{"*/logout"},
+		Headers: map[string][]string{
+			"Authorization": {"Bearer profile"},
+			"X-Team":        {"security"},
+		},
+		ExtraHeaders: map[string]string{
+			"X-Staging-Token": "profile",
+			"X-Scanner":       "sukyan",
+		},
+		UserAgent:   "ProfileAgent/1.0",
+		HTTPVersion: "http2",
+		RateLimit:   RateLimitOptions{RequestsPerSecond: 5},
+		Scope:       scope.ScopeRules{IncludeHosts: []string{`example\.com$`}},
+	}
+
+	options := FullScanOptions{
+		StartURLs: []string{"https://example.com"},
+		Mode:      ScanModeFast,
+		MaxDepth:  1,
+		Headers: map[string][]string{
+			"Authorization": {"Bearer explicit"},
+		},
+		ExtraHeaders: map[string]string{
+			"X-Staging-Token": "explicit",
+		},
+	}.ApplyProfile(profile)
+
+	// Explicitly provided options take precedence over the profile
+	assert.Equal(t, ScanModeFast, options.Mode)
+	assert.Equal(t, 1, options.MaxDepth)
+	assert.Equal(t, []string{"Bearer explicit"}, options.Headers["Authorization"])
+	// Missing options are filled from the profile
+	assert.Equal(t, []string{"security"}, options.Headers["X-Team"])
+	assert.Equal(t, map[string]string{"X-Staging-Token": "explicit", "X-Scanner": "sukyan"}, options.ExtraHeaders)
+	assert.Equal(t, "ProfileAgent/1.0", options.UserAgent)
+	assert.Equal(t, "http2", options.HTTPVersion)
+	assert.Equal(t, []string{"parameters", "body"}, options.InsertionPoints)
+	assert.Equal(t, AuditCategories{ServerSide: true, Passive: true}, options.AuditCategories)
+	assert.Equal(t, 8, options.PagesPoolSize)
+	assert.Equal(t, []string{"*/logout"}, options.ExcludeURLs)
+	assert.Equal(t, 5.0, options.RateLimit.RequestsPerSecond)
+	assert.Equal(t, profile.Scope, options.Scope)
+	assert.Equal(t, []string{"https://example.com"}, options.StartURLs)
+
+	options = FullScanOptions{
+		AuditCategories: AuditCategories{ClientSide: true},
+		Scope:           scope.ScopeRules{ExcludePaths: []string{"^/admin"}},
+	}.ApplyProfile(profile)
+	assert.Equal(t, AuditCategories{ClientSide: true}, options.AuditCategories)
+	assert.Equal(t, scope.ScopeRules{ExcludePaths: []string{"^/admin"}}, options.Scope)
+	assert.Equal(t, ScanModeFuzz, options.Mode)
+}
@@ -0,0 +1,40 @@
+package scan
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+)
+
+// OrderInsertionPoints returns the insertion points in the order they should be tested. When the seed is zero the
+// order is kept, which is already deterministic, otherwise they are shuffled with the seed so that the same seed always
+// gives the same order.
+func OrderInsertionPoints(insertionPoints []InsertionPoint, seed int64) []InsertionPoint {
+	if seed == 0 || len(insertionPoints) < 2 {
+		return insertionPoints
+	}
+	ordered := make([]InsertionPoint, len(insertionPoints))
+	copy(ordered, insertionPoints)
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(ordered), func(i, j int) {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	})
+	return ordered
+}
+
+// OrderPayloadGenerators returns the payload generators sorted by ID, shuffled with the seed when it is not zero
+func OrderPayloadGenerators(generators []*generation.PayloadGenerator, seed int64) []*generation.PayloadGenerator {
+	ordered := make([]*generation.PayloadGenerator, len(generators))
+	copy(ordered, generators)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].ID < ordered[j].ID
+	})
+	if seed != 0 {
+		random := rand.New(rand.NewSource(seed))
+		random.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+	}
+	return ordered
+}
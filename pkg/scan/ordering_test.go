@@ -0,0 +1,80 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/stretchr/testify/assert"
+)
+
+func insertionPointNames(insertionPoints []InsertionPoint) []string {
+	names := make([]string, 0, len(insertionPoints))
+	for _, insertionPoint := range insertionPoints {
+		names = append(names, insertionPoint.Name)
+	}
+	return names
+}
+
+func TestGetInsertionPointsOrderIsStable(t *testing.T) {
+	history := &db.History{
+		URL:                "http://example.com/path?zeta=1&alpha=2&mu=3&beta=4&omega=5",
+		Method:             "POST",
+		RequestHeaders:     []byte(`{"X-Zeta":["1"],"X-Alpha":["2"],"X-Mu":["3"]}`),
+		RequestContentType: "application/json",
+		RequestBody:        []byte(`{"zeta":"1","alpha":"2","mu":"3","beta":"4"}`),
+	}
+	scoped := []string{"parameters", "headers", "json"}
+	first, err := GetInsertionPoints(history, scoped)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alpha", "beta", "mu", "omega", "zeta", "X-Alpha", "X-Mu", "X-Zeta"}, insertionPointNames(first)[:8])
+
+	for i := 0; i < 20; i++ {
+		again, err := GetInsertionPoints(history, scoped)
+		assert.Nil(t, err)
+		assert.Equal(t, insertionPointNames(first), insertionPointNames(again))
+	}
+}
+
+func TestOrderInsertionPoints(t *testing.T) {
+	var insertionPoints []InsertionPoint
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		insertionPoints = append(insertionPoints, InsertionPoint{Type: InsertionPointTypeParameter, Name: name})
+	}
+	original := insertionPointNames(insertionPoints)
+
+	assert.Equal(t, original, insertionPointNames(OrderInsertionPoints(insertionPoints, 0)))
+
+	seeded := insertionPointNames(OrderInsertionPoints(insertionPoints, 42))
+	assert.ElementsMatch(t, original, seeded)
+	assert.NotEqual(t, original, seeded)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, seeded, insertionPointNames(OrderInsertionPoints(insertionPoints, 42)))
+	}
+	assert.NotEqual(t, seeded, insertionPointNames(OrderInsertionPoints(insertionPoints, 1337)))
+	// The provided slice is not modified
+	assert.Equal(t, original, insertionPointNames(insertionPoints))
+}
+
+func TestOrderPayloadGenerators(t *testing.T) {
+	var generators []*generation.PayloadGenerator
+	for _, id := range []string{"xss", "sqli", "ssti", "cmdi", "lfi", "ssrf", "xxe", "crlf"} {
+		generators = append(generators, &generation.PayloadGenerator{ID: id})
+	}
+	ids := func(generators []*generation.PayloadGenerator) []string {
+		result := make([]string, 0, len(generators))
+		for _, generator := range generators {
+			result = append(result, generator.ID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"cmdi", "crlf", "lfi", "sqli", "ssrf", "ssti", "xss", "xxe"}, ids(OrderPayloadGenerators(generators, 0)))
+
+	seeded := ids(OrderPayloadGenerators(generators, 7))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, seeded, ids(OrderPayloadGenerators(generators, 7)))
+	}
+	assert.NotEqual(t, seeded, ids(OrderPayloadGenerators(generators, 8)))
+	assert.Equal(t, "xss", generators[0].ID)
+}
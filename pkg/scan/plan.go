@@ -0,0 +1,56 @@
+package scan
+
+import (
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/pyneda/sukyan/pkg/scan/options"
+)
+
+// GeneratorPlan describes if a payload generator would be launched against an insertion point
+type GeneratorPlan struct {
+	ID        string `json:"id"`
+	IssueCode string `json:"issue_code"`
+	Launched  bool   `json:"launched"`
+	Payloads  int    `json:"payloads"`
+}
+
+// InsertionPointPlan describes the payloads that would be sent to an insertion point
+type InsertionPointPlan struct {
+	InsertionPoint string             `json:"insertion_point"`
+	Type           InsertionPointType `json:"type"`
+	// DependsOnAnalysis is set when the insertion point is only audited if the analysis performed during the scan
+	// finds it to be dynamic or reflected
+	DependsOnAnalysis bool            `json:"depends_on_analysis"`
+	Payloads          int             `json:"payloads"`
+	Generators        []GeneratorPlan `json:"generators"`
+}
+
+// Plan returns the payload generators that Run would launch against each insertion point, using the same launch
+// conditions but without building payloads or sending any request
+func (f *TemplateScanner) Plan(history *db.History, payloadGenerators []*generation.PayloadGenerator, insertionPoints []InsertionPoint, options options.HistoryItemScanOptions) []InsertionPointPlan {
+	insertionPoints = OrderInsertionPoints(insertionPoints, options.RandomSeed)
+	payloadGenerators = OrderPayloadGenerators(payloadGenerators, options.RandomSeed)
+	plans := make([]InsertionPointPlan, 0, len(insertionPoints))
+	for _, insertionPoint := range insertionPoints {
+		plan := InsertionPointPlan{
+			InsertionPoint: insertionPoint.String(),
+			Type:           insertionPoint.Type,
+			Generators:     make([]GeneratorPlan, 0, len(payloadGenerators)),
+		}
+		for _, generator := range payloadGenerators {
+			generatorPlan := GeneratorPlan{
+				ID:        generator.ID,
+				IssueCode: generator.IssueCode,
+				Launched:  f.shouldLaunch(history, generator, insertionPoint, options),
+			}
+			if generatorPlan.Launched {
+				// Each template of the generator is built into a single payload
+				generatorPlan.Payloads = len(generator.Templates)
+				plan.Payloads += generatorPlan.Payloads
+			}
+			plan.Generators = append(plan.Generators, generatorPlan)
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
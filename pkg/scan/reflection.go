@@ -0,0 +1,317 @@
+package scan
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+)
+
+// ReflectionContext is the place of an HTML document where an input is reflected
+type ReflectionContext string
+
+const (
+	// ReflectionContextHTML is the text content of an element, or the markup itself, where new tags can be injected
+	ReflectionContextHTML ReflectionContext = "html"
+	// ReflectionContextDoubleQuotedAttribute is the value of an attribute enclosed in double quotes
+	ReflectionContextDoubleQuotedAttribute ReflectionContext = "attribute_double_quoted"
+	// ReflectionContextSingleQuotedAttribute is the value of an attribute enclosed in single quotes
+	ReflectionContextSingleQuotedAttribute ReflectionContext = "attribute_single_quoted"
+	// ReflectionContextUnquotedAttribute is the value of an attribute without quotes
+	ReflectionContextUnquotedAttribute ReflectionContext = "attribute_unquoted"
+	// ReflectionContextScript is JavaScript code, in a script block or an event handler attribute, outside of strings
+	ReflectionContextScript ReflectionContext = "script"
+	// ReflectionContextScriptString is a string literal of JavaScript code
+	ReflectionContextScriptString ReflectionContext = "script_string"
+	// ReflectionContextURL is the value of an attribute holding a URL, such as href or src
+	ReflectionContextURL ReflectionContext = "url"
+	// ReflectionContextStyle is the content of a style block or attribute
+	ReflectionContextStyle ReflectionContext = "style"
+	// ReflectionContextComment is the content of an HTML comment
+	ReflectionContextComment ReflectionContext = "comment"
+)
+
+// urlAttributes are the attributes whose value is interpreted as a URL
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"data":       true,
+	"poster":     true,
+	"background": true,
+	"codebase":   true,
+	"cite":       true,
+	"srcset":     true,
+	"xlink:href": true,
+}
+
+// ReflectionResult is the outcome of checking if an insertion point is reflected in the response
+type ReflectionResult struct {
+	InsertionPoint InsertionPoint
+	Marker         string
+	Reflected      bool
+	Contexts       []ReflectionContext
+	// History is the request sent with the marker and its response
+	History *db.History
+	Error   error
+}
+
+// ReflectedIn returns true if the insertion point has been found reflected in the provided context
+func (b *InsertionPointBehaviour) ReflectedIn(context ReflectionContext) bool {
+	return lib.SliceContains(b.ReflectionContexts, string(context))
+}
+
+// ClassifyReflectionContexts parses the body as HTML and returns the contexts in which the marker appears, in order of
+// first appearance. Attribute values are reported with the quotes enclosing them and, when they hold a URL or
+// JavaScript code, also with the context of their content.
+func ClassifyReflectionContexts(body []byte, marker string) []ReflectionContext {
+	contexts := []ReflectionContext{}
+	if marker == "" || !bytes.Contains(body, []byte(marker)) {
+		return contexts
+	}
+	seen := make(map[ReflectionContext]bool)
+	add := func(context ReflectionContext) {
+		if !seen[context] {
+			seen[context] = true
+			contexts = append(contexts, context)
+		}
+	}
+	addScript := func(code string) {
+		for offset := 0; ; {
+			index := strings.Index(code[offset:], marker)
+			if index < 0 {
+				return
+			}
+			offset += index
+			if jsStringQuoteAt(code, offset) != 0 {
+				add(ReflectionContextScriptString)
+			} else {
+				add(ReflectionContextScript)
+			}
+			offset += len(marker)
+		}
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	rawTextElement := ""
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		// The raw token is copied before getting the token, which lowercases the tag name in place
+		raw := string(tokenizer.Raw())
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.TextToken:
+			if !strings.Contains(token.Data, marker) {
+				continue
+			}
+			switch rawTextElement {
+			case "script":
+				addScript(token.Data)
+			case "style":
+				add(ReflectionContextStyle)
+			default:
+				add(ReflectionContextHTML)
+			}
+		case html.CommentToken:
+			if strings.Contains(token.Data, marker) {
+				add(ReflectionContextComment)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			if tokenType == html.StartTagToken && (token.Data == "script" || token.Data == "style") {
+				rawTextElement = token.Data
+			} else if tokenType == html.EndTagToken {
+				rawTextElement = ""
+			}
+			if strings.Contains(token.Data, marker) {
+				add(ReflectionContextHTML)
+			}
+			if tokenType == html.EndTagToken {
+				continue
+			}
+			for _, attr := range parseRawTagAttributes(raw) {
+				if strings.Contains(attr.name, marker) {
+					// Controlling the attribute name allows injecting new attributes, such as event handlers
+					add(ReflectionContextHTML)
+				}
+				if !strings.Contains(attr.value, marker) {
+					continue
+				}
+				switch attr.quote {
+				case '"':
+					add(ReflectionContextDoubleQuotedAttribute)
+				case '\'':
+					add(ReflectionContextSingleQuotedAttribute)
+				default:
+					add(ReflectionContextUnquotedAttribute)
+				}
+				switch {
+				case strings.HasPrefix(attr.name, "on"):
+					addScript(html.UnescapeString(attr.value))
+				case attr.name == "style":
+					add(ReflectionContextStyle)
+				case urlAttributes[attr.name]:
+					add(ReflectionContextURL)
+				}
+			}
+		}
+	}
+	return contexts
+}
+
+// rawTagAttribute is an attribute as found in the markup, with its value still encoded
+type rawTagAttribute struct {
+	name  string
+	value string
+	// quote is the character enclosing the value, zero when it is unquoted
+	quote byte
+}
+
+// parseRawTagAttributes returns the attributes of the raw start tag, keeping the quotes used for their values, which
+// the HTML tokenizer does not provide
+func parseRawTagAttributes(raw string) []rawTagAttribute {
+	var attributes []rawTagAttribute
+	isSpace := func(c byte) bool {
+		return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+	}
+	i := strings.IndexAny(raw, " \t\n\r\f/>")
+	if i < 0 {
+		return attributes
+	}
+	for i < len(raw) {
+		for i < len(raw) && (isSpace(raw[i]) || raw[i] == '/') {
+			i++
+		}
+		if i >= len(raw) || raw[i] == '>' {
+			break
+		}
+		start := i
+		// A leading equals sign is part of the attribute name
+		i++
+		for i < len(raw) && !isSpace(raw[i]) && raw[i] != '=' && raw[i] != '>' && raw[i] != '/' {
+			i++
+		}
+		attribute := rawTagAttribute{name: strings.ToLower(raw[start:i])}
+		for i < len(raw) && isSpace(raw[i]) {
+			i++
+		}
+		if i < len(raw) && raw[i] == '=' {
+			i++
+			for i < len(raw) && isSpace(raw[i]) {
+				i++
+			}
+			if i < len(raw) && (raw[i] == '"' || raw[i] == '\'') {
+				attribute.quote = raw[i]
+				end := strings.IndexByte(raw[i+1:], attribute.quote)
+				if end < 0 {
+					end = len(raw) - i - 1
+				}
+				attribute.value = raw[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(raw) && !isSpace(raw[i]) && raw[i] != '>' {
+					i++
+				}
+				attribute.value = raw[start:i]
+			}
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes
+}
+
+// jsStringQuoteAt returns the quote of the JavaScript string literal containing the provided position of the code, or
+// zero when it is not inside a string. Comments are skipped, regular expression literals are not taken into account.
+func jsStringQuoteAt(code string, position int) byte {
+	var quote byte
+	for i := 0; i < position && i < len(code); i++ {
+		c := code[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote || (c == '\n' && quote != '`') {
+				quote = 0
+			}
+		case c == '/' && i+1 < len(code) && code[i+1] == '/':
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				return 0
+			}
+			i += end
+		case c == '/' && i+1 < len(code) && code[i+1] == '*':
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return 0
+			}
+			i += end + 3
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		}
+	}
+	return quote
+}
+
+// newReflectionMarker returns a value unlikely to be found in any response by chance
+func newReflectionMarker() string {
+	return "sk" + lib.GenerateRandomLowercaseString(10)
+}
+
+// DiscoverReflections injects a unique marker into each insertion point and classifies the contexts where it is
+// reflected in the response. The insertion points are annotated with the reflection metadata, so the modules run
+// afterwards can pick the payloads appropriate for each context. Requests are sent with the client options of the
+// scan, so they honour its scope, rate limits and session.
+func DiscoverReflections(history *db.History, insertionPoints []InsertionPoint, options InsertionPointAnalysisOptions) []ReflectionResult {
+	client := http_utils.CreateHttpClientWithOptions(options.ClientOptions)
+	return discoverReflections(history, insertionPoints, client, options.HistoryCreateOptions)
+}
+
+func discoverReflections(history *db.History, insertionPoints []InsertionPoint, client *http.Client, options http_utils.HistoryCreationOptions) []ReflectionResult {
+	results := make([]ReflectionResult, 0, len(insertionPoints))
+	for i := range insertionPoints {
+		insertionPoint := &insertionPoints[i]
+		result := ReflectionResult{Marker: newReflectionMarker()}
+		req, err := CreateRequestFromInsertionPoints(history, []InsertionPointBuilder{
+			{Point: *insertionPoint, Payload: result.Marker},
+		})
+		if err == nil {
+			var response *http.Response
+			if response, err = http_utils.SendRequest(client, req); err == nil {
+				result.History, err = http_utils.ReadHttpResponseAndCreateHistory(response, options)
+			}
+		}
+		if err != nil {
+			log.Error().Err(err).Str("url", history.URL).Str("point", insertionPoint.String()).Msg("Failed to check insertion point reflection")
+			result.Error = err
+		} else {
+			result.Contexts = ClassifyReflectionContexts(result.History.ResponseBody, result.Marker)
+			result.Reflected = bytes.Contains(result.History.ResponseBody, []byte(result.Marker))
+			annotateReflection(insertionPoint, result)
+		}
+		result.InsertionPoint = *insertionPoint
+		results = append(results, result)
+	}
+	return results
+}
+
+// annotateReflection records the reflection contexts found in the insertion point behaviour
+func annotateReflection(insertionPoint *InsertionPoint, result ReflectionResult) {
+	if !result.Reflected {
+		return
+	}
+	insertionPoint.Behaviour.IsReflected = true
+	for _, context := range result.Contexts {
+		if !insertionPoint.Behaviour.ReflectedIn(context) {
+			insertionPoint.Behaviour.ReflectionContexts = append(insertionPoint.Behaviour.ReflectionContexts, string(context))
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/http_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyReflectionContexts(t *testing.T) {
+	marker := "skmarker"
+	tests := []struct {
+		name     string
+		body     string
+		expected []ReflectionContext
+	}{
+		{"not reflected", `<html><body>nothing here</body></html>`, []ReflectionContext{}},
+		{"html text", `<html><body><p>Results for skmarker</p></body></html>`, []ReflectionContext{ReflectionContextHTML}},
+		{"double quoted attribute", `<input type="text" name="q" value="skmarker">`, []ReflectionContext{ReflectionContextDoubleQuotedAttribute}},
+		{"single quoted attribute", `<input type='text' value = 'a skmarker'>`, []ReflectionContext{ReflectionContextSingleQuotedAttribute}},
+		{"unquoted attribute", `<div class=skmarker>`, []ReflectionContext{ReflectionContextUnquotedAttribute}},
+		{"url attribute", `<a href="/search?q=skmarker">next</a>`, []ReflectionContext{ReflectionContextDoubleQuotedAttribute, ReflectionContextURL}},
+		{"event handler string", `<body onload="init('skmarker')">`, []ReflectionContext{ReflectionContextDoubleQuotedAttribute, ReflectionContextScriptString}},
+		{"event handler code", `<button onclick='go(skmarker)'>`, []ReflectionContext{ReflectionContextSingleQuotedAttribute, ReflectionContextScript}},
+		{"script string", `<script>var q = "skmarker";</script>`, []ReflectionContext{ReflectionContextScriptString}},
+		{"script template literal", "<script>var q = `${a} skmarker`;</script>", []ReflectionContext{ReflectionContextScriptString}},
+		{"script block", `<script>var page = skmarker;</script>`, []ReflectionContext{ReflectionContextScript}},
+		{"script after string with escaped quote", `<script>var a = "it\"s"; skmarker();</script>`, []ReflectionContext{ReflectionContextScript}},
+		{"script after comment with quote", "<script>// don't\nvar b = skmarker;</script>", []ReflectionContext{ReflectionContextScript}},
+		{"script with markup", `<script>var html = "<b>skmarker</b>";</script>`, []ReflectionContext{ReflectionContextScriptString}},
+		{"style block", `<style>.skmarker { color: red }</style>`, []ReflectionContext{ReflectionContextStyle}},
+		{"comment", `<!-- debug: skmarker -->`, []ReflectionContext{ReflectionContextComment}},
+		{"attribute name", `<div data-skmarker="1">`, []ReflectionContext{ReflectionContextHTML}},
+		{"json", `{"query":"skmarker"}`, []ReflectionContext{ReflectionContextHTML}},
+		{
+			"multiple contexts",
+			`<title>skmarker</title><script>track("skmarker")</script><a href=?p=skmarker>skmarker</a><img alt="skmarker">`,
+			[]ReflectionContext{ReflectionContextHTML, ReflectionContextScriptString, ReflectionContextUnquotedAttribute, ReflectionContextURL, ReflectionContextDoubleQuotedAttribute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyReflectionContexts([]byte(tt.body), marker))
+		})
+	}
+}
+
+func TestAnnotateReflection(t *testing.T) {
+	insertionPoint := &InsertionPoint{Type: InsertionPointTypeParameter, Name: "q"}
+	annotateReflection(insertionPoint, ReflectionResult{Reflected: false})
+	assert.False(t, insertionPoint.Behaviour.IsReflected)
+
+	annotateReflection(insertionPoint, ReflectionResult{Reflected: true, Contexts: []ReflectionContext{ReflectionContextHTML, ReflectionContextURL}})
+	annotateReflection(insertionPoint, ReflectionResult{Reflected: true, Contexts: []ReflectionContext{ReflectionContextURL}})
+	assert.True(t, insertionPoint.Behaviour.IsReflected)
+	assert.Equal(t, []string{"html", "url"}, insertionPoint.Behaviour.ReflectionContexts)
+	assert.True(t, insertionPoint.Behaviour.ReflectedIn(ReflectionContextURL))
+	assert.False(t, insertionPoint.Behaviour.ReflectedIn(ReflectionContextScript))
+}
+
+func TestParseRawTagAttributes(t *testing.T) {
+	attributes := parseRawTagAttributes(`<input type=text value="a 'b'" data-x = 'c "d"' disabled/>`)
+	assert.Equal(t, []rawTagAttribute{
+		{name: "type", value: "text"},
+		{name: "value", value: "a 'b'", quote: '"'},
+		{name: "data-x", value: `c "d"`, quote: '\''},
+		{name: "disabled"},
+	}, attributes)
+	assert.Empty(t, parseRawTagAttributes("<br>"))
+}
+
+func TestAnalyzeInsertionPointsDiscoversReflections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><input value="%s"></body></html>`, r.URL.Query().Get("q"))
+	}))
+	defer server.Close()
+
+	item := &db.History{
+		URL:            server.URL + "/search?q=test&page=1",
+		Method:         "GET",
+		StatusCode:     200,
+		RequestHeaders: []byte(`{"Accept":["text/html"]}`),
+		ResponseBody:   []byte(`<html><body><input value="test"></body></html>`),
+	}
+	insertionPoints := []InsertionPoint{
+		{Type: InsertionPointTypeParameter, Name: "q", Value: "test", OriginalData: item.URL},
+		{Type: InsertionPointTypeParameter, Name: "page", Value: "1", OriginalData: item.URL},
+	}
+	analyzed := AnalyzeInsertionPoints(item, insertionPoints, InsertionPointAnalysisOptions{
+		ClientOptions: &http_utils.ClientOptions{},
+	})
+	assert.True(t, analyzed[0].Behaviour.IsReflected)
+	assert.True(t, analyzed[0].Behaviour.IsDynamic)
+	assert.Equal(t, []string{string(ReflectionContextDoubleQuotedAttribute)}, analyzed[0].Behaviour.ReflectionContexts)
+	assert.False(t, analyzed[1].Behaviour.IsReflected)
+}
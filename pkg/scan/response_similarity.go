@@ -0,0 +1,128 @@
+package scan
+
+import (
+	"hash/fnv"
+	"html"
+	"math/bits"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// responseShingleSize is the number of consecutive words of each shingle used to compute the body simhash
+	responseShingleSize = 3
+	// minReflectionLength avoids removing short values, such as a single digit, from everywhere in the body
+	minReflectionLength = 4
+)
+
+var (
+	// Values which usually change between requests, such as timestamps, identifiers or anti CSRF tokens
+	dynamicNumberRegex = regexp.MustCompile(`\d{5,}`)
+	dynamicTokenRegex  = regexp.MustCompile(`[a-f0-9]{16,}|[A-Za-z0-9+/_-]{32,}={0,2}`)
+	responseWordRegex  = regexp.MustCompile(`[\p{L}\p{N}_]+|[^\s\p{L}\p{N}_]`)
+)
+
+// ResponseSignature summarizes a response so it can be compared with others, ignoring the content that usually
+// changes between requests and the reflections of the payloads
+type ResponseSignature struct {
+	StatusCode int
+	Length     int
+	Simhash    uint64
+}
+
+// NewResponseSignature computes the signature of a response. The provided reflections, usually the payloads sent,
+// are removed from the body together with their HTML and URL encoded forms, unless they are too short to be told apart from
+// the rest of the content.
+func NewResponseSignature(statusCode int, body []byte, reflections ...string) ResponseSignature {
+	normalized := NormalizeResponseBody(body, reflections...)
+	return ResponseSignature{
+		StatusCode: statusCode,
+		Length:     len(normalized),
+		Simhash:    simhash(shingles(normalized, responseShingleSize)),
+	}
+}
+
+// NormalizeResponseBody lowercases the body, removing the reflections and replacing dynamic values by placeholders
+func NormalizeResponseBody(body []byte, reflections ...string) string {
+	text := string(body)
+	for _, reflection := range reflections {
+		if len(reflection) < minReflectionLength {
+			continue
+		}
+		for _, form := range []string{reflection, html.EscapeString(reflection), url.QueryEscape(reflection), url.PathEscape(reflection)} {
+			text = strings.ReplaceAll(text, form, "")
+		}
+	}
+	text = dynamicTokenRegex.ReplaceAllString(text, "token")
+	text = dynamicNumberRegex.ReplaceAllString(text, "0")
+	return strings.ToLower(text)
+}
+
+// Similarity returns a score between 0 and 1 comparing both responses. It is the lowest of the body length ratio and
+// the simhash similarity, halved when the status codes differ.
+func (s ResponseSignature) Similarity(other ResponseSignature) float64 {
+	lengthSimilarity := 1.0
+	if s.Length != other.Length {
+		shorter, longer := s.Length, other.Length
+		if shorter > longer {
+			shorter, longer = longer, shorter
+		}
+		lengthSimilarity = float64(shorter) / float64(longer)
+	}
+	// Unrelated documents differ in about half of the bits, so that is considered completely different
+	distance := bits.OnesCount64(s.Simhash ^ other.Simhash)
+	contentSimilarity := 1 - float64(distance)/32
+	if contentSimilarity < 0 {
+		contentSimilarity = 0
+	}
+
+	similarity := lengthSimilarity
+	if contentSimilarity < similarity {
+		similarity = contentSimilarity
+	}
+	if s.StatusCode != other.StatusCode {
+		similarity /= 2
+	}
+	return similarity
+}
+
+// shingles returns the sequences of size consecutive words of the text, or the text itself when shorter
+func shingles(text string, size int) []string {
+	words := responseWordRegex.FindAllString(text, -1)
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) <= size {
+		return []string{strings.Join(words, " ")}
+	}
+	result := make([]string, 0, len(words)-size+1)
+	for i := 0; i+size <= len(words); i++ {
+		result = append(result, strings.Join(words[i:i+size], " "))
+	}
+	return result
+}
+
+// simhash computes the 64 bit simhash of the features, where similar sets of features get hashes differing in few bits
+func simhash(features []string) uint64 {
+	var weights [64]int
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		hash := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if hash&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	var result uint64
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			result |= 1 << uint(i)
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package scan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func productsPage(results []string, footer string) []byte {
+	var sb strings.Builder
+	sb.WriteString(`<html><head><title>Products</title></head><body><h1>Search results</h1><ul>`)
+	for _, result := range results {
+		sb.WriteString(fmt.Sprintf(`<li class="product"><a href="/product/%s">%s</a><p>Free shipping on all orders of %s</p></li>`, result, result, result))
+	}
+	sb.WriteString(`</ul><footer>`)
+	sb.WriteString(footer)
+	sb.WriteString(`</footer></body></html>`)
+	return []byte(sb.String())
+}
+
+var testProducts = []string{"keyboard", "mouse", "monitor", "headphones", "webcam", "microphone", "laptop stand", "usb hub"}
+
+func TestResponseSimilarityIdentical(t *testing.T) {
+	body := productsPage(testProducts, "Copyright")
+	a := NewResponseSignature(200, body)
+	b := NewResponseSignature(200, body)
+	assert.Equal(t, 1.0, a.Similarity(b))
+}
+
+func TestResponseSimilarityIgnoresDynamicContent(t *testing.T) {
+	a := NewResponseSignature(200, productsPage(testProducts, `Generated at 1714554000123 <input type="hidden" name="csrf" value="4f2a9c1e7b3d5a6f8e0c2b4d6a8f0e1c">`))
+	b := NewResponseSignature(200, productsPage(testProducts, `Generated at 1714554009876 <input type="hidden" name="csrf" value="9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b">`))
+	assert.Equal(t, 1.0, a.Similarity(b))
+}
+
+func TestResponseSimilarityRemovesReflections(t *testing.T) {
+	a := NewResponseSignature(200, productsPage(testProducts, "You searched for: keyboards"), "keyboards")
+	b := NewResponseSignature(200, productsPage(testProducts, "You searched for: keyboards&#39; AND &#39;1&#39;=&#39;1"), "keyboards' AND '1'='1")
+	assert.Equal(t, 1.0, a.Similarity(b))
+}
+
+func TestResponseSimilarityDifferentContent(t *testing.T) {
+	full := NewResponseSignature(200, productsPage(testProducts, "Copyright"))
+	empty := NewResponseSignature(200, productsPage(nil, "No products found"))
+	assert.Less(t, full.Similarity(empty), 0.5)
+
+	// Small changes keep most of the similarity
+	partial := NewResponseSignature(200, productsPage(testProducts[:7], "Copyright"))
+	assert.Greater(t, full.Similarity(partial), full.Similarity(empty))
+}
+
+func TestResponseSimilarityStatusCode(t *testing.T) {
+	body := productsPage(testProducts, "Copyright")
+	ok := NewResponseSignature(200, body)
+	errored := NewResponseSignature(500, body)
+	assert.Equal(t, 0.5, ok.Similarity(errored))
+	assert.Equal(t, errored.Similarity(ok), ok.Similarity(errored))
+}
+
+func TestNormalizeResponseBody(t *testing.T) {
+	assert.Equal(t, "id 0 for abc", NormalizeResponseBody([]byte("ID 1234567 for ABC")))
+	// Short reflections are kept, as they could match unrelated content
+	assert.Equal(t, "value: 1", NormalizeResponseBody([]byte("Value: 1"), "1"))
+	assert.Equal(t, "search: ", NormalizeResponseBody([]byte("Search: a+b%3D%27c%27"), "a b='c'"))
+}
+
+func TestCompareBooleanResponses(t *testing.T) {
+	thresholds := BooleanThresholds{MinTrueSimilarity: 0.95, MaxFalseSimilarity: 0.85}
+	// The original value is removed from every response, as BooleanTest does
+	baseline := NewResponseSignature(200, productsPage(testProducts, "Copyright"), "keyboard")
+	trueResponse := NewResponseSignature(200, productsPage(testProducts, "Copyright"), "keyboard' AND '1'='1", "keyboard")
+	falseResponse := NewResponseSignature(200, productsPage(nil, "No products found"), "keyboard' AND '1'='2", "keyboard")
+
+	comparison := CompareBooleanResponses(baseline, trueResponse, falseResponse)
+	assert.Equal(t, 1.0, comparison.TrueSimilarity)
+	assert.Less(t, comparison.FalseSimilarity, thresholds.MaxFalseSimilarity)
+	assert.True(t, comparison.Differs(thresholds))
+
+	// Both conditions returning the same content is not a finding
+	comparison = CompareBooleanResponses(baseline, trueResponse, trueResponse)
+	assert.False(t, comparison.Differs(thresholds))
+
+	// Neither is the true condition breaking the response, as the payload is likely a syntax error
+	comparison = CompareBooleanResponses(baseline, falseResponse, falseResponse)
+	assert.False(t, comparison.Differs(thresholds))
+}
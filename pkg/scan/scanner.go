@@ -27,6 +27,8 @@ type TemplateScannerResult struct {
 	InsertionPoint InsertionPoint
 	Duration       time.Duration
 	Issue          *db.Issue
+	// WAFEvasion is set when the payload was blocked by a WAF and the result is from a mutation that bypassed it
+	WAFEvasion *WAFEvasion
 }
 
 type TemplateScanner struct {
@@ -35,9 +37,12 @@ type TemplateScanner struct {
 	AvoidRepeatedIssues bool
 	WorkspaceID         uint
 	Mode                options.ScanMode
-	client              *http.Client
-	issuesFound         sync.Map
-	results             sync.Map
+	// ClientOptions are the settings of the HTTP client of the scan
+	ClientOptions     *http_utils.ClientOptions
+	client            *http.Client
+	issuesFound       sync.Map
+	results           sync.Map
+	wafBlocksReported sync.Map
 }
 
 type TemplateScannerTask struct {
@@ -62,7 +67,7 @@ func (f *TemplateScanner) checkConfig() {
 		f.Concurrency = 4
 	}
 	if f.client == nil {
-		f.client = http_utils.CreateHttpClient()
+		f.client = http_utils.CreateHttpClientWithOptions(f.ClientOptions)
 	}
 
 }
@@ -104,6 +109,16 @@ func (f *TemplateScanner) shouldLaunch(history *db.History, generator *generatio
 			if condition.ResponseCondition.Check(history) {
 				conditionsMet++
 			}
+
+		case generation.ResponseContentType:
+			if condition.MatchesContentType(history.ResponseContentType) {
+				conditionsMet++
+			}
+
+		case generation.TechStack:
+			if condition.MatchesTechStack(options.Fingerprints) {
+				conditionsMet++
+			}
 		}
 	}
 
@@ -126,6 +141,8 @@ func (f *TemplateScanner) Run(history *db.History, payloadGenerators []*generati
 
 	var wg sync.WaitGroup
 	f.checkConfig()
+	insertionPoints = OrderInsertionPoints(insertionPoints, options.RandomSeed)
+	payloadGenerators = OrderPayloadGenerators(payloadGenerators, options.RandomSeed)
 	// Declare the channels
 	pendingTasks := make(chan TemplateScannerTask, f.Concurrency)
 	defer close(pendingTasks)
@@ -145,6 +162,25 @@ func (f *TemplateScanner) Run(history *db.History, payloadGenerators []*generati
 					continue
 				}
 				for _, payload := range payloads {
+					if payload.InteractionDomain.URL != "" {
+						key := integrations.OOBTestKey{
+							Target:         history.URL,
+							InsertionPoint: insertionPoint.String(),
+							Payload:        strings.ReplaceAll(payload.UnencodedValue(), payload.InteractionDomain.URL, "{{interactionAddress}}"),
+						}
+						if payload.IsEncoded() {
+							key.Payload = fmt.Sprintf("%s (%s)", key.Payload, payload.Encoding)
+						}
+						_, reused, err := f.InteractionsManager.ClaimURLForTest(key, payload.InteractionDomain)
+						if err != nil {
+							log.Warn().Err(err).Str("item", history.URL).Str("generator", generator.ID).Str("insertion_point", insertionPoint.String()).Msg("Skipping out of band payload")
+							continue
+						}
+						if reused {
+							log.Debug().Str("item", history.URL).Str("generator", generator.ID).Str("insertion_point", insertionPoint.String()).Msg("Skipping out of band payload already sent by an identical test")
+							continue
+						}
+					}
 					wg.Add(1)
 					task := TemplateScannerTask{
 						history:        history,
@@ -185,110 +221,212 @@ func (f *TemplateScanner) worker(wg *sync.WaitGroup, pendingTasks chan TemplateS
 	for task := range pendingTasks {
 		taskLog := log.With().Str("method", task.history.Method).Str("param", task.insertionPoint.Name).Str("payload", task.payload.Value).Str("url", task.history.URL).Logger()
 		taskLog.Debug().Interface("task", task).Msg("New template scanner task received by parameter worker")
-		if f.AvoidRepeatedIssues {
-			_, ok := f.issuesFound.Load(DetectedIssue{
-				code:           db.IssueCode(task.payload.IssueCode),
-				insertionPoint: task.insertionPoint,
-			}.String())
-			if ok {
-				taskLog.Debug().Msg("Skipping task as an issue for this insertion point with this code for this history item has already been found")
-				wg.Done()
-				continue
+		if f.AvoidRepeatedIssues && f.allIssuesFound(task.payload, task.insertionPoint) {
+			taskLog.Debug().Msg("Skipping task as an issue for this insertion point with this code for this history item has already been found")
+			wg.Done()
+			continue
+		}
+		result, err := f.sendPayload(task, task.payload)
+		if err != nil {
+			taskLog.Error().Err(err).Msg("Error sending payload")
+			wg.Done()
+			continue
+		}
+		if task.options.WAFEvasion && !task.payload.IsEncoded() {
+			if block, blocked := DetectWAFBlock(task.history, result.Result); blocked {
+				result = f.evadeWAF(task, result, block)
 			}
 		}
-		var result TemplateScannerResult
-		builders := []InsertionPointBuilder{
-			{
-				Point:   task.insertionPoint,
-				Payload: task.payload.Value,
-			},
+		newHistory := result.Result
+		taskLog.Debug().Str("rawrequest", string(newHistory.RawRequest)).Msg("Request from history created in TemplateScanner")
+		detections, err := f.evaluateIssueDetections(result)
+		if err != nil {
+			taskLog.Error().Err(err).Msg("Error evaluating result")
+			wg.Done()
+			continue
 		}
 
-		req, err := CreateRequestFromInsertionPoints(task.history, builders)
-		if err != nil {
-			taskLog.Error().Err(err).Msg("Error building request from insertion points")
-			result.Err = err
-		} else {
-			startTime := time.Now()
-			response, err := http_utils.SendRequest(f.client, req)
-			if err != nil {
-				taskLog.Error().Err(err).Msg("Error making request")
-				wg.Done()
-				continue
+		if result.Payload.InteractionDomain.URL != "" {
+			oobTest := db.OOBTest{
+				Code:              db.IssueCode(result.Payload.OOBInteractionIssueCode()),
+				TestName:          "Fuzz Test",
+				InteractionDomain: result.Payload.InteractionDomain.URL,
+				InteractionFullID: result.Payload.InteractionDomain.ID,
+				Target:            newHistory.URL,
+				Payload:           result.Payload.Value,
+				HistoryID:         &newHistory.ID,
+				InsertionPoint:    task.insertionPoint.String(),
+				WorkspaceID:       &f.WorkspaceID,
+				TaskID:            &task.options.TaskID,
+				TaskJobID:         &task.options.TaskJobID,
 			}
-			responseData, _, err := http_utils.ReadFullResponse(response, false)
-			if err != nil {
-				taskLog.Error().Err(err).Msg("Error reading response body, skipping")
-				wg.Done()
-				continue
-			}
-			result.Duration = time.Since(startTime)
-			options := http_utils.HistoryCreationOptions{
-				Source:              db.SourceScanner,
-				WorkspaceID:         f.WorkspaceID,
-				TaskID:              task.options.TaskID,
-				CreateNewBodyStream: false,
+			db.Connection.CreateOOBTest(oobTest)
+			taskLog.Debug().Interface("oobTest", oobTest).Msg("Created OOB Test")
+		}
+
+		for _, detection := range detections {
+			issueCode := db.IssueCode(detection.IssueCode)
+			taskLog.Warn().Str("issue", string(issueCode)).Msg("Vulnerable")
+			// Should handle the additional details and confidence
+			fullDetails := fmt.Sprintf("The following payload was inserted in the `%s` %s: %s\n\n%s", task.insertionPoint.Name, task.insertionPoint.Type, result.Payload.Description(), detection.Details)
+			if result.WAFEvasion != nil {
+				fullDetails += "\n\n" + result.WAFEvasion.Details()
 			}
-			newHistory, err := http_utils.CreateHistoryFromHttpResponse(response, responseData, options)
-			taskLog.Debug().Str("rawrequest", string(newHistory.RawRequest)).Msg("Request from history created in TemplateScanner")
-			result.Result = newHistory
-			result.Err = err
-			result.Response = *response
-			result.Payload = task.payload
-			result.InsertionPoint = task.insertionPoint
-			result.Original = task.history
-			result.ResponseData = responseData
-			vulnerable, details, confidence, err := f.EvaluateResult(result)
+			// taskLog.Warn().Interface("newHistory", newHistory).Str("issue", string(issueCode)).Str("details", fullDetails).Int("confidence", confidence).Uint("wksp", f.WorkspaceID).Msg("Creating issue")
+			createdIssue, err := db.CreateIssueFromHistoryAndTemplateWithOptions(newHistory, issueCode, fullDetails, detection.Confidence, "", &f.WorkspaceID, &task.options.TaskID, &task.options.TaskJobID, db.IssueCreationOptions{InsertionPoint: task.insertionPoint.String()})
 			if err != nil {
-				taskLog.Error().Err(err).Msg("Error evaluating result")
-				wg.Done()
-				continue
+				taskLog.Error().Str("code", string(issueCode)).Interface("result", result).Err(err).Msg("Error creating issue")
+			} else if createdIssue.ID != 0 {
+				issueResult := result
+				issueResult.Issue = &createdIssue
+				f.results.Store(createdIssue.Code, issueResult)
 			}
-			issueCode := db.IssueCode(task.payload.IssueCode)
-
-			if task.payload.InteractionDomain.URL != "" {
-				oobTest := db.OOBTest{
-					Code:              issueCode,
-					TestName:          "Fuzz Test",
-					InteractionDomain: task.payload.InteractionDomain.URL,
-					InteractionFullID: task.payload.InteractionDomain.ID,
-					Target:            newHistory.URL,
-					Payload:           task.payload.Value,
-					HistoryID:         &newHistory.ID,
-					InsertionPoint:    task.insertionPoint.String(),
-					WorkspaceID:       &f.WorkspaceID,
-					TaskID:            &task.options.TaskID,
-					TaskJobID:         &task.options.TaskJobID,
-				}
-				db.Connection.CreateOOBTest(oobTest)
-				taskLog.Debug().Interface("oobTest", oobTest).Msg("Created OOB Test")
+			// Avoid repeated issues: could also provide a issue type `variant` and handle the insertion point
+			if f.AvoidRepeatedIssues {
+				f.issuesFound.Store(DetectedIssue{
+					code:           issueCode,
+					insertionPoint: task.insertionPoint,
+				}.String(), true)
 			}
+		}
 
-			if vulnerable {
-				taskLog.Warn().Msg("Vulnerable")
-				// Should handle the additional details and confidence
-				fullDetails := fmt.Sprintf("The following payload was inserted in the `%s` %s: %s\n\n%s", task.insertionPoint.Name, task.insertionPoint.Type, task.payload.Value, details)
-				// taskLog.Warn().Interface("newHistory", newHistory).Str("issue", string(issueCode)).Str("details", fullDetails).Int("confidence", confidence).Uint("wksp", f.WorkspaceID).Msg("Creating issue")
-				createdIssue, err := db.CreateIssueFromHistoryAndTemplate(newHistory, issueCode, fullDetails, confidence, "", &f.WorkspaceID, &task.options.TaskID, &task.options.TaskJobID)
-				if err != nil {
-					taskLog.Error().Str("code", string(issueCode)).Interface("result", result).Err(err).Msg("Error creating issue")
-				} else if createdIssue.ID != 0 {
-					result.Issue = &createdIssue
-					f.results.Store(createdIssue.Code, result)
-				}
-				// Avoid repeated issues: could also provide a issue type `variant` and handle the insertion point
-				if f.AvoidRepeatedIssues {
-					f.issuesFound.Store(DetectedIssue{
-						code:           db.IssueCode(issueCode),
-						insertionPoint: task.insertionPoint,
-					}.String(), true)
-				}
-			}
+		wg.Done()
+	}
+}
+
+// sendPayload sends the original request of the task with the payload inserted in the insertion point
+func (f *TemplateScanner) sendPayload(task TemplateScannerTask, payload generation.Payload) (TemplateScannerResult, error) {
+	result := TemplateScannerResult{
+		Original:       task.history,
+		Payload:        payload,
+		InsertionPoint: task.insertionPoint,
+	}
+	builders := []InsertionPointBuilder{
+		{
+			Point:   task.insertionPoint,
+			Payload: payload.Value,
+		},
+	}
+	req, err := CreateRequestFromInsertionPoints(task.history, builders)
+	if err != nil {
+		return result, fmt.Errorf("error building request from insertion points: %w", err)
+	}
+	startTime := time.Now()
+	response, err := http_utils.SendRequest(f.client, req)
+	if err != nil {
+		return result, fmt.Errorf("error making request: %w", err)
+	}
+	responseData, _, err := http_utils.ReadFullResponse(response, false)
+	if err != nil {
+		return result, fmt.Errorf("error reading response body: %w", err)
+	}
+	result.Duration = time.Since(startTime)
+	options := http_utils.HistoryCreationOptions{
+		Source:              db.SourceScanner,
+		WorkspaceID:         f.WorkspaceID,
+		TaskID:              task.options.TaskID,
+		CreateNewBodyStream: false,
+	}
+	newHistory, err := http_utils.CreateHistoryFromHttpResponse(response, responseData, options)
+	if err != nil {
+		return result, fmt.Errorf("error creating history from response: %w", err)
+	}
+	result.Result = newHistory
+	result.Response = *response
+	result.ResponseData = responseData
+	return result, nil
+}
 
+// evadeWAF sends the payload blocked by a WAF again with each of the evasion mutations of its class, returning the
+// result of the first one which is not blocked, or the blocked result when none of them bypasses the WAF
+func (f *TemplateScanner) evadeWAF(task TemplateScannerTask, blocked TemplateScannerResult, block WAFBlock) TemplateScannerResult {
+	taskLog := log.With().Str("param", task.insertionPoint.Name).Str("payload", task.payload.Value).Str("url", task.history.URL).Str("block", block.String()).Logger()
+	f.reportWAFBlock(task, block)
+	for _, mutation := range GetWAFEvasionMutations(task.payload) {
+		payload, changed := ApplyWAFEvasionMutation(task.payload, mutation)
+		if !changed {
+			continue
 		}
+		result, err := f.sendPayload(task, payload)
+		if err != nil {
+			taskLog.Error().Err(err).Str("mutation", mutation.Name).Msg("Error sending WAF evasion payload")
+			continue
+		}
+		if _, stillBlocked := DetectWAFBlock(task.history, result.Result); stillBlocked {
+			continue
+		}
+		taskLog.Info().Str("mutation", mutation.Name).Msg("WAF block bypassed")
+		result.WAFEvasion = &WAFEvasion{Block: block, Mutation: mutation.Name, OriginalPayload: task.payload.Value}
+		return result
+	}
+	taskLog.Debug().Msg("None of the WAF evasion mutations bypassed the block")
+	return blocked
+}
 
-		wg.Done()
+// reportWAFBlock creates a scan event the first time a WAF blocks a payload sent to each host
+func (f *TemplateScanner) reportWAFBlock(task TemplateScannerTask, block WAFBlock) {
+	if task.options.TaskID == 0 {
+		return
+	}
+	host, err := lib.GetHostFromURL(task.history.URL)
+	if err != nil {
+		return
+	}
+	if _, reported := f.wafBlocksReported.LoadOrStore(host, true); reported {
+		return
+	}
+	message := fmt.Sprintf("Payload blocked by a WAF on host %s", host)
+	if block.WAF != "" {
+		message = fmt.Sprintf("Payload blocked by %s WAF on host %s", block.WAF, host)
+	}
+	db.Connection.NewScanEvent(task.options.TaskID, &task.options.TaskJobID, db.ScanEventWAFDetected, "template-scanner", message, map[string]interface{}{
+		"host":        host,
+		"url":         task.history.URL,
+		"waf":         block.WAF,
+		"status_code": block.StatusCode,
+	})
+}
+
+// allIssuesFound returns true if all the issues the payload can indicate have already been found in the insertion point
+func (f *TemplateScanner) allIssuesFound(payload generation.Payload, insertionPoint InsertionPoint) bool {
+	codes := payload.GetIssueCodes()
+	if len(codes) == 0 {
+		return false
+	}
+	for _, code := range codes {
+		if _, ok := f.issuesFound.Load(DetectedIssue{code: db.IssueCode(code), insertionPoint: insertionPoint}.String()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateIssueDetections returns the issues detected by the result. Payloads which can indicate multiple issues, such
+// as polyglots, have all their detection methods evaluated and each match is attributed to the issue it indicates.
+func (f *TemplateScanner) evaluateIssueDetections(result TemplateScannerResult) ([]generation.IssueDetection, error) {
+	codes := result.Payload.GetIssueCodes()
+	if len(codes) <= 1 {
+		vulnerable, details, confidence, err := f.EvaluateResult(result)
+		if err != nil || !vulnerable || len(codes) == 0 {
+			return nil, err
+		}
+		return []generation.IssueDetection{{IssueCode: codes[0], Details: details, Confidence: confidence}}, nil
+	}
+
+	results := make([]generation.DetectionMethodResult, 0, len(result.Payload.DetectionMethods))
+	for _, method := range result.Payload.DetectionMethods {
+		matched, description, confidence, err := f.EvaluateDetectionMethod(result, method)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, generation.DetectionMethodResult{
+			Method:      method,
+			Matched:     matched,
+			Description: description,
+			Confidence:  confidence,
+		})
 	}
+	return result.Payload.AttributeDetections(results), nil
 }
 
 func (f *TemplateScanner) EvaluateResult(result TemplateScannerResult) (bool, string, int, error) {
@@ -422,84 +560,18 @@ func (f *TemplateScanner) EvaluateDetectionMethod(result TemplateScannerResult,
 		log.Warn().Msg("Browser Events detection method not implemented yet")
 		return false, "", 0, nil
 	case *generation.TimeBasedDetectionMethod:
-		if m.CheckIfResultDurationIsHigher(result.Duration) {
-			var sb strings.Builder
-			defaultDelay := 30
-			attempts := 8
-			finalConfidence := m.Confidence
-			confidenceIncrement := 20
-			confidenceDecrement := 40
-			originalTrueCount := 0
-			payloadTrueCount := 0
-			sb.WriteString(fmt.Sprintf("Response took %s, which is greater than the sleep time injected in the payload of %s\n\n", result.Duration, m.Sleep))
-			// var originalResults []bool
-			// var payloadResults []bool
-			expectedSleepDuratoin := m.ParseSleepDuration(m.Sleep)
-			sb.WriteString("Revalidation results:\n")
-			sb.WriteString("=============================\n")
-
-			for i := 1; i < attempts; i++ {
-
-				delay := time.Duration(defaultDelay*i) * time.Second
-
-				originalResult, err := f.repeatHistoryItem(result.Original)
-				if err != nil {
-					sb.WriteString(fmt.Sprintf("Attempt %d: Error making request for original history item\n", i))
-					sb.WriteString(fmt.Sprintf(" * Sleeping for %s seconds.\n", delay))
-					time.Sleep(delay)
-					continue
-				}
-				withPayloadResult, err := f.repeatHistoryItem(result.Result)
-				if err != nil {
-					sb.WriteString(fmt.Sprintf("Attempt %d: Error making request for history item with payload\n", i))
-					sb.WriteString(fmt.Sprintf(" * Sleeping for %s seconds.\n", delay))
-					time.Sleep(delay)
-					continue
-				}
-				originalIsHigher := m.CheckIfResultDurationIsHigher(originalResult.duration)
-				if originalIsHigher {
-					originalTrueCount++
-					finalConfidence -= confidenceDecrement
-				}
-				withPayloadIsHigher := m.CheckIfResultDurationIsHigher(withPayloadResult.duration)
-				if withPayloadIsHigher {
-					payloadTrueCount++
-					finalConfidence += confidenceIncrement
-				}
-
-				if originalResult.duration > withPayloadResult.duration || withPayloadResult.duration < expectedSleepDuratoin {
-					finalConfidence -= confidenceDecrement
-				}
-				// originalResults = append(originalResults, originalIsHigher)
-				// payloadResults = append(payloadResults, withPayloadIsHigher)
-				sb.WriteString(fmt.Sprintf("Attempt %d:\n - Original took %s\n - With payload took %s\n\n", i, originalResult.duration, withPayloadResult.duration))
-				if originalIsHigher {
-					sb.WriteString(fmt.Sprintf(" * Sleeping for %s seconds.\n", delay))
-					log.Debug().Msg("While revalidating time based issue, both the original and the payload requests took longer than the sleep time. Sleeping for 30 seconds and trying again")
-					time.Sleep(delay)
-				}
-			}
-
-			if finalConfidence > 100 {
-				finalConfidence = 100
-			} else if finalConfidence < 0 {
-				finalConfidence = 0
-			}
-
-			if originalTrueCount == 0 && payloadTrueCount > attempts/2 {
-				return true, sb.String(), 100, nil
-			}
-
-			if finalConfidence > 50 {
-				log.Debug().Msgf("System is vulnerable with %d%% confidence", finalConfidence)
-				return true, sb.String(), finalConfidence, nil
-			} else {
-				log.Debug().Msgf("System is not vulnerable with %d%% confidence", finalConfidence)
-				return false, "", finalConfidence, nil
-			}
-
+		sampler := TimeBasedSampler{
+			Original: func() (time.Duration, error) {
+				repeated, err := f.repeatHistoryItem(result.Original)
+				return repeated.duration, err
+			},
+			WithPayload: func() (time.Duration, error) {
+				repeated, err := f.repeatHistoryItem(result.Result)
+				return repeated.duration, err
+			},
 		}
-		return false, "", 0, nil
+		vulnerable, description, confidence := EvaluateTimeBased(m, result.Duration, sampler, GetTimeBasedSettings())
+		return vulnerable, description, confidence, nil
 	case *generation.ResponseCheckDetectionMethod:
 		if m.Check == generation.DatabaseErrorCondition {
 			result := passive.SearchDatabaseErrors(result.ResponseData.RawString)
@@ -0,0 +1,75 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldLaunchResponseContentType(t *testing.T) {
+	generator := &generation.PayloadGenerator{
+		ID: "json-only",
+		Launch: generation.LaunchConditions{
+			Operator: generation.And,
+			Conditions: []generation.LaunchCondition{
+				{Type: generation.ResponseContentType, Value: "json"},
+			},
+		},
+	}
+	insertionPoint := InsertionPoint{Name: "q", Type: InsertionPointTypeParameter}
+	scanner := TemplateScanner{}
+
+	jsonHistory := &db.History{ResponseContentType: "application/json; charset=utf-8"}
+	assert.True(t, scanner.shouldLaunch(jsonHistory, generator, insertionPoint, options.HistoryItemScanOptions{}))
+
+	htmlHistory := &db.History{ResponseContentType: "text/html"}
+	assert.False(t, scanner.shouldLaunch(htmlHistory, generator, insertionPoint, options.HistoryItemScanOptions{}))
+
+	wsScanner := WebSocketScanner{}
+	message := &db.WebSocketMessage{PayloadData: `{"q":"test"}`}
+	assert.False(t, wsScanner.shouldLaunch(message, generator, insertionPoint, options.HistoryItemScanOptions{}))
+}
+
+func TestShouldLaunchTechStack(t *testing.T) {
+	wordpress := generation.LaunchCondition{Type: generation.TechStack, Value: "wordpress"}
+	fuzzMode := generation.LaunchCondition{Type: generation.ScanMode, Value: options.ScanModeFuzz.String()}
+	insertionPoint := InsertionPoint{Name: "q", Type: InsertionPointTypeParameter}
+	history := &db.History{ResponseContentType: "text/html"}
+	scanner := TemplateScanner{}
+	wsScanner := WebSocketScanner{}
+	message := &db.WebSocketMessage{PayloadData: "test"}
+
+	tests := []struct {
+		name         string
+		operator     generation.Operator
+		fingerprints []lib.Fingerprint
+		mode         options.ScanMode
+		expected     bool
+	}{
+		{"And with both conditions met", generation.And, []lib.Fingerprint{{Name: "WordPress", Version: "6.4"}}, options.ScanModeFuzz, true},
+		{"And without the technology", generation.And, []lib.Fingerprint{{Name: "Drupal"}}, options.ScanModeFuzz, false},
+		{"And with a different scan mode", generation.And, []lib.Fingerprint{{Name: "WordPress"}}, options.ScanModeFast, false},
+		{"Or with only the technology", generation.Or, []lib.Fingerprint{{Name: "Nginx"}, {Name: "WordPress"}}, options.ScanModeFast, true},
+		{"Or with only the scan mode", generation.Or, nil, options.ScanModeFuzz, true},
+		{"Or with no conditions met", generation.Or, []lib.Fingerprint{{Name: "Drupal"}}, options.ScanModeFast, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &generation.PayloadGenerator{
+				ID: "wordpress",
+				Launch: generation.LaunchConditions{
+					Operator:   tt.operator,
+					Conditions: []generation.LaunchCondition{wordpress, fuzzMode},
+				},
+			}
+			scanOptions := options.HistoryItemScanOptions{Mode: tt.mode, Fingerprints: tt.fingerprints}
+			assert.Equal(t, tt.expected, scanner.shouldLaunch(history, generator, insertionPoint, scanOptions))
+			assert.Equal(t, tt.expected, wsScanner.shouldLaunch(message, generator, insertionPoint, scanOptions))
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package scan
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// TimeBasedSettings configures how time based detections are revalidated
+type TimeBasedSettings struct {
+	// Attempts is the number of times the original request and the one with the payload are sent again
+	Attempts int
+	// RetryDelay is the time to wait before the next attempt when a request fails or the original request is also
+	// delayed, multiplied by the attempt number
+	RetryDelay time.Duration
+	// ConfidenceIncrement is added to the confidence on every attempt where the payload delays the response
+	ConfidenceIncrement int
+	// ConfidenceDecrement is subtracted from the confidence on every attempt where the original request is also
+	// delayed, or the payload does not delay the response as expected
+	ConfidenceDecrement int
+	// MinConfidence is the confidence above which the detection is considered valid
+	MinConfidence int
+	// BaselineSamples is the number of times the original request is sent before revalidating, to measure the usual
+	// response time of the target
+	BaselineSamples int
+	// BaselinePercentile is the percentile of the original response times taken as the baseline, a high percentile
+	// prevents network jitter from being taken as a delay caused by the payload
+	BaselinePercentile float64
+	// Margin is how much longer than the baseline plus the injected sleep the payload response has to take
+	Margin time.Duration
+}
+
+// GetTimeBasedSettings returns the settings set through the scan.time_based settings
+func GetTimeBasedSettings() TimeBasedSettings {
+	return TimeBasedSettings{
+		Attempts:            viper.GetInt("scan.time_based.attempts"),
+		RetryDelay:          time.Duration(viper.GetInt("scan.time_based.retry_delay")) * time.Second,
+		ConfidenceIncrement: viper.GetInt("scan.time_based.confidence_increment"),
+		ConfidenceDecrement: viper.GetInt("scan.time_based.confidence_decrement"),
+		MinConfidence:       viper.GetInt("scan.time_based.min_confidence"),
+		BaselineSamples:     viper.GetInt("scan.time_based.baseline.samples"),
+		BaselinePercentile:  viper.GetFloat64("scan.time_based.baseline.percentile"),
+		Margin:              time.Duration(viper.GetInt("scan.time_based.margin")) * time.Millisecond,
+	}
+}
+
+// TimeBasedSample is how long the original request and the one with the payload took in a revalidation attempt
+type TimeBasedSample struct {
+	Original    time.Duration
+	WithPayload time.Duration
+}
+
+// TimeBasedSampler sends the original request and the one with the payload again, returning how long each one took.
+// It allows revalidating time based detections regardless of the protocol used to send the payloads.
+type TimeBasedSampler struct {
+	Original    func() (time.Duration, error)
+	WithPayload func() (time.Duration, error)
+}
+
+// TimeBasedBaseline is the response time of the original request, as percentiles of the measured durations
+type TimeBasedBaseline struct {
+	Median     time.Duration
+	Percentile time.Duration
+}
+
+// NewTimeBasedBaseline computes the median and the configured percentile of the original request durations
+func NewTimeBasedBaseline(durations []time.Duration, settings TimeBasedSettings) TimeBasedBaseline {
+	return TimeBasedBaseline{
+		Median:     durationPercentile(durations, 50),
+		Percentile: durationPercentile(durations, settings.BaselinePercentile),
+	}
+}
+
+// DelayThreshold is the duration a response to the payload has to exceed to be considered delayed by it: the
+// baseline percentile plus the injected sleep and the configured margin
+func (b TimeBasedBaseline) DelayThreshold(sleep time.Duration, settings TimeBasedSettings) time.Duration {
+	return b.Percentile + sleep + settings.Margin
+}
+
+// durationPercentile returns the nearest rank percentile of the durations, zero when there are none
+func durationPercentile(durations []time.Duration, percentile float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	} else if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// TimeBasedDecision is the outcome of revalidating a time based detection
+type TimeBasedDecision struct {
+	Vulnerable bool
+	Confidence int
+	Baseline   TimeBasedBaseline
+	// OriginalDelayed is the number of samples where the original request took longer than the injected sleep
+	OriginalDelayed int
+	// PayloadDelayed is the number of samples where the request with the payload took longer than the delay
+	// threshold of the baseline
+	PayloadDelayed int
+}
+
+// DecideTimeBased scores the revalidation samples of a time based detection method, starting from its confidence. The
+// baseline is computed from the durations of the original request, both the ones provided and the ones of the samples,
+// and a payload response is only considered delayed when it exceeds its delay threshold. The detection is valid with
+// full confidence when the payload delayed the response in most of the attempts and the original request was never
+// delayed, otherwise when the final confidence is above the configured minimum.
+func DecideTimeBased(method *generation.TimeBasedDetectionMethod, baseline []time.Duration, samples []TimeBasedSample, settings TimeBasedSettings) TimeBasedDecision {
+	decision := TimeBasedDecision{Confidence: method.Confidence}
+	originals := append([]time.Duration{}, baseline...)
+	for _, sample := range samples {
+		originals = append(originals, sample.Original)
+	}
+	decision.Baseline = NewTimeBasedBaseline(originals, settings)
+	threshold := decision.Baseline.DelayThreshold(method.ParseSleepDuration(method.Sleep), settings)
+
+	for _, sample := range samples {
+		if method.CheckIfResultDurationIsHigher(sample.Original) {
+			decision.OriginalDelayed++
+			decision.Confidence -= settings.ConfidenceDecrement
+		}
+		payloadDelayed := sample.WithPayload >= threshold
+		if payloadDelayed {
+			decision.PayloadDelayed++
+			decision.Confidence += settings.ConfidenceIncrement
+		}
+		if sample.Original > sample.WithPayload || !payloadDelayed {
+			decision.Confidence -= settings.ConfidenceDecrement
+		}
+	}
+
+	if decision.Confidence > 100 {
+		decision.Confidence = 100
+	} else if decision.Confidence < 0 {
+		decision.Confidence = 0
+	}
+
+	if decision.OriginalDelayed == 0 && decision.PayloadDelayed > settings.Attempts/2 {
+		decision.Vulnerable = true
+		decision.Confidence = 100
+		return decision
+	}
+	decision.Vulnerable = decision.Confidence > settings.MinConfidence
+	return decision
+}
+
+// EvaluateTimeBased checks if the duration of the request with the payload is higher than the injected sleep and, in
+// that case, revalidates it sending the original request and the one with the payload again through the sampler
+func EvaluateTimeBased(method *generation.TimeBasedDetectionMethod, duration time.Duration, sampler TimeBasedSampler, settings TimeBasedSettings) (bool, string, int) {
+	if !method.CheckIfResultDurationIsHigher(duration) {
+		return false, "", 0
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Response took %s, which is greater than the sleep time injected in the payload of %s\n\n", duration, method.Sleep))
+	sb.WriteString("Revalidation results:\n")
+	sb.WriteString("=============================\n")
+
+	var baseline []time.Duration
+	for i := 0; i < settings.BaselineSamples; i++ {
+		duration, err := sampler.Original()
+		if err != nil {
+			continue
+		}
+		baseline = append(baseline, duration)
+	}
+
+	var samples []TimeBasedSample
+	for i := 1; i <= settings.Attempts; i++ {
+		delay := settings.RetryDelay * time.Duration(i)
+
+		original, err := sampler.Original()
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("Attempt %d: Error sending the original request\n", i))
+			sb.WriteString(fmt.Sprintf(" * Sleeping for %s.\n", delay))
+			time.Sleep(delay)
+			continue
+		}
+		withPayload, err := sampler.WithPayload()
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("Attempt %d: Error sending the request with the payload\n", i))
+			sb.WriteString(fmt.Sprintf(" * Sleeping for %s.\n", delay))
+			time.Sleep(delay)
+			continue
+		}
+		samples = append(samples, TimeBasedSample{Original: original, WithPayload: withPayload})
+		sb.WriteString(fmt.Sprintf("Attempt %d:\n - Original took %s\n - With payload took %s\n\n", i, original, withPayload))
+		if method.CheckIfResultDurationIsHigher(original) {
+			sb.WriteString(fmt.Sprintf(" * Sleeping for %s.\n", delay))
+			log.Debug().Msg("While revalidating time based issue, both the original and the payload requests took longer than the sleep time. Sleeping and trying again")
+			time.Sleep(delay)
+		}
+	}
+
+	decision := DecideTimeBased(method, baseline, samples, settings)
+	sb.WriteString(fmt.Sprintf("Original response time: median %s, p%g %s\n", decision.Baseline.Median, settings.BaselinePercentile, decision.Baseline.Percentile))
+	sb.WriteString(fmt.Sprintf("Payload responses delayed over %s in %d of %d attempts\n", decision.Baseline.DelayThreshold(method.ParseSleepDuration(method.Sleep), settings), decision.PayloadDelayed, len(samples)))
+	if decision.Vulnerable {
+		log.Debug().Msgf("System is vulnerable with %d%% confidence", decision.Confidence)
+		return true, sb.String(), decision.Confidence
+	}
+	log.Debug().Msgf("System is not vulnerable with %d%% confidence", decision.Confidence)
+	return false, "", decision.Confidence
+}
@@ -0,0 +1,191 @@
+package scan
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTimeBasedSettings = TimeBasedSettings{
+	Attempts:            7,
+	ConfidenceIncrement: 20,
+	ConfidenceDecrement: 40,
+	MinConfidence:       50,
+	BaselineSamples:     5,
+	BaselinePercentile:  95,
+	Margin:              250 * time.Millisecond,
+}
+
+func timeBasedSamples(original, withPayload []time.Duration) []TimeBasedSample {
+	samples := make([]TimeBasedSample, len(original))
+	for i := range original {
+		samples[i] = TimeBasedSample{Original: original[i], WithPayload: withPayload[i]}
+	}
+	return samples
+}
+
+func TestDecideTimeBased(t *testing.T) {
+	method := &generation.TimeBasedDetectionMethod{Sleep: "5", Confidence: 60}
+	fast := 100 * time.Millisecond
+	slow := 5500 * time.Millisecond
+
+	tests := []struct {
+		name               string
+		samples            []TimeBasedSample
+		expectedVulnerable bool
+		expectedConfidence int
+	}{
+		{
+			name: "Payload always delayed",
+			samples: timeBasedSamples(
+				[]time.Duration{fast, fast, fast, fast, fast, fast, fast},
+				[]time.Duration{slow, slow, slow, slow, slow, slow, slow},
+			),
+			expectedVulnerable: true,
+			expectedConfidence: 100,
+		},
+		{
+			name: "Payload delayed in most attempts",
+			samples: timeBasedSamples(
+				[]time.Duration{fast, fast, fast, fast, fast, fast, fast},
+				[]time.Duration{slow, slow, slow, slow, fast, fast, fast},
+			),
+			expectedVulnerable: true,
+			expectedConfidence: 100,
+		},
+		{
+			name: "Payload delayed in few attempts",
+			samples: timeBasedSamples(
+				[]time.Duration{fast, fast, fast, fast, fast, fast, fast},
+				[]time.Duration{slow, slow, fast, fast, fast, fast, fast},
+			),
+			expectedVulnerable: false,
+			expectedConfidence: 0,
+		},
+		{
+			name: "Original also delayed",
+			samples: timeBasedSamples(
+				[]time.Duration{slow, slow, slow, slow, slow, slow, slow},
+				[]time.Duration{slow, slow, slow, slow, slow, slow, slow},
+			),
+			expectedVulnerable: false,
+			expectedConfidence: 0,
+		},
+		{
+			name: "Original delayed once on an unstable target",
+			samples: timeBasedSamples(
+				[]time.Duration{slow, fast, fast, fast, fast, fast, fast},
+				[]time.Duration{slow, slow, slow, slow, slow, slow, slow},
+			),
+			expectedVulnerable: false,
+			expectedConfidence: 0,
+		},
+		{
+			name: "Original slower than the payload",
+			samples: timeBasedSamples(
+				[]time.Duration{6 * time.Second, 6 * time.Second},
+				[]time.Duration{slow, slow},
+			),
+			expectedVulnerable: false,
+			expectedConfidence: 0,
+		},
+		{
+			name:               "All revalidation attempts failed",
+			samples:            nil,
+			expectedVulnerable: true,
+			expectedConfidence: 60,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := DecideTimeBased(method, nil, tt.samples, testTimeBasedSettings)
+			assert.Equal(t, tt.expectedVulnerable, decision.Vulnerable)
+			assert.Equal(t, tt.expectedConfidence, decision.Confidence)
+		})
+	}
+}
+
+func TestEvaluateTimeBased(t *testing.T) {
+	method := &generation.TimeBasedDetectionMethod{Sleep: "1000", Confidence: 60}
+	attempts := 0
+	sampler := TimeBasedSampler{
+		Original: func() (time.Duration, error) {
+			attempts++
+			if attempts == testTimeBasedSettings.BaselineSamples+1 {
+				return 0, errors.New("connection reset")
+			}
+			return 50 * time.Millisecond, nil
+		},
+		WithPayload: func() (time.Duration, error) {
+			return 1500 * time.Millisecond, nil
+		},
+	}
+
+	vulnerable, details, confidence := EvaluateTimeBased(method, 500*time.Millisecond, sampler, testTimeBasedSettings)
+	assert.False(t, vulnerable)
+	assert.Empty(t, details)
+	assert.Equal(t, 0, confidence)
+	assert.Equal(t, 0, attempts)
+
+	vulnerable, details, confidence = EvaluateTimeBased(method, 1200*time.Millisecond, sampler, testTimeBasedSettings)
+	assert.True(t, vulnerable)
+	assert.Equal(t, 100, confidence)
+	assert.Equal(t, 12, attempts)
+	assert.Contains(t, details, "Attempt 1: Error sending the original request")
+	assert.Contains(t, details, "Attempt 7:\n - Original took 50ms\n - With payload took 1.5s")
+	assert.Contains(t, details, "Payload responses delayed over 1.3s in 6 of 6 attempts")
+}
+
+func TestDecideTimeBasedWithJitter(t *testing.T) {
+	method := &generation.TimeBasedDetectionMethod{Sleep: "5", Confidence: 60}
+	ms := func(values ...int) []time.Duration {
+		durations := make([]time.Duration, len(values))
+		for i, value := range values {
+			durations[i] = time.Duration(value) * time.Millisecond
+		}
+		return durations
+	}
+	// Response times of a target behind a noisy network, mostly fast with occasional spikes
+	baseline := ms(120, 90, 1400, 150, 110)
+	originals := ms(100, 1300, 140, 95, 130, 160, 1500)
+
+	tests := []struct {
+		name               string
+		withPayload        []time.Duration
+		expectedVulnerable bool
+	}{
+		{
+			name:               "Borderline delay within the jitter",
+			withPayload:        ms(5300, 5900, 5150, 6100, 5600, 5400, 6300),
+			expectedVulnerable: false,
+		},
+		{
+			name:               "Delay above the jitter",
+			withPayload:        ms(7100, 7400, 6900, 7300, 7000, 7600, 7200),
+			expectedVulnerable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := DecideTimeBased(method, baseline, timeBasedSamples(originals, tt.withPayload), testTimeBasedSettings)
+			assert.Equal(t, tt.expectedVulnerable, decision.Vulnerable)
+			assert.Equal(t, 1500*time.Millisecond, decision.Baseline.Percentile)
+			assert.Equal(t, 130*time.Millisecond, decision.Baseline.Median)
+		})
+	}
+}
+
+func TestDurationPercentile(t *testing.T) {
+	durations := []time.Duration{5, 1, 4, 2, 3, 10, 6, 8, 7, 9}
+	assert.Equal(t, time.Duration(5), durationPercentile(durations, 50))
+	assert.Equal(t, time.Duration(10), durationPercentile(durations, 95))
+	assert.Equal(t, time.Duration(9), durationPercentile(durations, 90))
+	assert.Equal(t, time.Duration(1), durationPercentile(durations, 0))
+	assert.Equal(t, time.Duration(0), durationPercentile(nil, 95))
+	assert.Equal(t, []time.Duration{5, 1, 4, 2, 3, 10, 6, 8, 7, 9}, durations)
+}
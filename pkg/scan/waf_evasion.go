@@ -0,0 +1,250 @@
+package scan
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+)
+
+// wafBlockStatusCodes are the status codes usually returned by WAFs when blocking a request
+var wafBlockStatusCodes = []int{http.StatusForbidden, http.StatusNotAcceptable}
+
+// wafSignature identifies the block page of a WAF through its body or headers
+type wafSignature struct {
+	Name    string
+	Body    *regexp.Regexp
+	Headers map[string]*regexp.Regexp
+}
+
+var wafSignatures = []wafSignature{
+	{Name: "Cloudflare", Body: regexp.MustCompile(`(?i)attention required! \| cloudflare|cloudflare ray id:|sorry, you have been blocked`)},
+	{Name: "AWS WAF", Body: regexp.MustCompile(`(?i)request blocked\.[\s\S]*generated by cloudfront|<title>403 forbidden</title>[\s\S]*awselb`)},
+	{Name: "Akamai", Body: regexp.MustCompile(`(?i)access denied[\s\S]*you don't have permission to access[\s\S]*reference #[0-9a-f.]+`)},
+	{Name: "Imperva Incapsula", Body: regexp.MustCompile(`(?i)incapsula incident id|_incapsula_resource`)},
+	{Name: "F5 BIG-IP ASM", Body: regexp.MustCompile(`(?i)the requested url was rejected\. please consult with your administrator`)},
+	{Name: "ModSecurity", Body: regexp.MustCompile(`(?i)mod_security|this error was generated by mod_security|not acceptable!.*an appropriate representation of the requested resource`)},
+	{Name: "Sucuri", Body: regexp.MustCompile(`(?i)sucuri website firewall - access denied|cloudproxy\.sucuri\.net`)},
+	{Name: "Wordfence", Body: regexp.MustCompile(`(?i)generated by wordfence|your access to this site has been limited by the site owner`)},
+	{Name: "Barracuda", Body: regexp.MustCompile(`(?i)barracuda networks[\s\S]*you have been blocked|barra_counter_session`)},
+	{Name: "Fortinet FortiWeb", Body: regexp.MustCompile(`(?i)\.fgd_icon|server unavailable![\s\S]*fortiweb`)},
+	{Name: "Sucuri", Headers: map[string]*regexp.Regexp{"X-Sucuri-Block": regexp.MustCompile(`.+`)}},
+	{Name: "Imperva Incapsula", Headers: map[string]*regexp.Regexp{"X-Iinfo": regexp.MustCompile(`.+`)}},
+}
+
+// WAFBlock describes why a response is considered to be blocked by a WAF
+type WAFBlock struct {
+	// WAF is the name of the WAF whose signature matched, empty when the block was detected by the status code
+	WAF        string
+	StatusCode int
+}
+
+func (b WAFBlock) String() string {
+	if b.WAF == "" {
+		return fmt.Sprintf("status code %d", b.StatusCode)
+	}
+	return fmt.Sprintf("%s block page with status code %d", b.WAF, b.StatusCode)
+}
+
+// matchWAFSignature returns the name of the WAF whose block page signature matches the response
+func matchWAFSignature(headers map[string][]string, body []byte) string {
+	for _, signature := range wafSignatures {
+		if signature.Body != nil && signature.Body.Match(body) {
+			return signature.Name
+		}
+		for name, pattern := range signature.Headers {
+			for _, value := range http.Header(headers).Values(name) {
+				if pattern.MatchString(value) {
+					return signature.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isWAFBlockStatusCode(statusCode int) bool {
+	for _, code := range wafBlockStatusCodes {
+		if statusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectWAFBlock checks if the response to a payload has been blocked by a WAF, comparing it with the response to the
+// original request so that endpoints which always return a block status code or a page matching a signature are not
+// considered blocked
+func DetectWAFBlock(original, result *db.History) (WAFBlock, bool) {
+	headers, _ := result.GetResponseHeadersAsMap()
+	if waf := matchWAFSignature(headers, result.ResponseBody); waf != "" {
+		originalHeaders, _ := original.GetResponseHeadersAsMap()
+		if matchWAFSignature(originalHeaders, original.ResponseBody) == "" {
+			return WAFBlock{WAF: waf, StatusCode: result.StatusCode}, true
+		}
+	}
+	if isWAFBlockStatusCode(result.StatusCode) && !isWAFBlockStatusCode(original.StatusCode) {
+		return WAFBlock{StatusCode: result.StatusCode}, true
+	}
+	return WAFBlock{}, false
+}
+
+// WAFEvasionMutation transforms a payload to bypass WAF rules
+type WAFEvasionMutation struct {
+	Name string
+	// Encoding is applied instead of Mutate when set. The target decodes it, so the detection methods are kept.
+	Encoding generation.PayloadEncoding
+	Mutate   func(payload string) string
+}
+
+// PayloadClass groups payloads by the kind of injection, which determines the WAF evasion mutations that keep them
+// working
+type PayloadClass string
+
+const (
+	SQLPayloadClass     PayloadClass = "sql"
+	XSSPayloadClass     PayloadClass = "xss"
+	GenericPayloadClass PayloadClass = "generic"
+)
+
+// GetPayloadClass returns the class of the payload according to the issues it can indicate
+func GetPayloadClass(payload generation.Payload) PayloadClass {
+	for _, code := range payload.GetIssueCodes() {
+		code = strings.ToLower(code)
+		if strings.Contains(code, "sql") {
+			return SQLPayloadClass
+		}
+		if strings.Contains(code, "xss") {
+			return XSSPayloadClass
+		}
+	}
+	return GenericPayloadClass
+}
+
+var sqlKeywordsRegex = regexp.MustCompile(`(?i)\b(select|union|from|where|and|or|order|by|group|having|insert|update|delete|sleep|benchmark|waitfor|delay|null|concat|char|database|version|information_schema)\b`)
+
+// htmlKeywordsRegex matches tag names, event handlers and the javascript scheme, which are case insensitive, unlike the
+// javascript code run by the payloads
+var htmlKeywordsRegex = regexp.MustCompile(`(?i)\b(script|svg|img|iframe|body|details|input|onload|onerror|ontoggle|onfocus|onmouseover|javascript)\b`)
+var htmlTagWhitespaceRegex = regexp.MustCompile(`(<[a-zA-Z]+)\s+`)
+
+// alternateCase alternates the case of the letters of the keywords matched by the regex, leaving the rest of the payload
+// untouched so that random markers used for detection are kept
+func alternateCase(keywords *regexp.Regexp) func(string) string {
+	return func(payload string) string {
+		return keywords.ReplaceAllStringFunc(payload, func(keyword string) string {
+			var sb strings.Builder
+			upper := true
+			for _, c := range keyword {
+				if !unicode.IsLetter(c) {
+					sb.WriteRune(c)
+					continue
+				}
+				if upper {
+					sb.WriteRune(unicode.ToUpper(c))
+				} else {
+					sb.WriteRune(unicode.ToLower(c))
+				}
+				upper = !upper
+			}
+			return sb.String()
+		})
+	}
+}
+
+// encodingMutation returns a mutation applying a payload encoding
+func encodingMutation(encoding generation.PayloadEncoding) WAFEvasionMutation {
+	return WAFEvasionMutation{Name: fmt.Sprintf("%s-encoding", encoding), Encoding: encoding}
+}
+
+var wafEvasionMutations = map[PayloadClass][]WAFEvasionMutation{
+	SQLPayloadClass: {
+		{Name: "case-variation", Mutate: alternateCase(sqlKeywordsRegex)},
+		{Name: "comment-insertion", Mutate: func(payload string) string { return strings.ReplaceAll(payload, " ", "/**/") }},
+		{Name: "case-variation-comment-insertion", Mutate: func(payload string) string {
+			return strings.ReplaceAll(alternateCase(sqlKeywordsRegex)(payload), " ", "/**/")
+		}},
+		encodingMutation(generation.DoubleURLEncoding),
+	},
+	XSSPayloadClass: {
+		{Name: "case-variation", Mutate: alternateCase(htmlKeywordsRegex)},
+		{Name: "whitespace-substitution", Mutate: func(payload string) string { return htmlTagWhitespaceRegex.ReplaceAllString(payload, "$1/") }},
+		encodingMutation(generation.DoubleURLEncoding),
+		encodingMutation(generation.HTMLEntityEncoding),
+		encodingMutation(generation.UnicodeEncoding),
+	},
+	GenericPayloadClass: {
+		encodingMutation(generation.URLEncoding),
+		encodingMutation(generation.DoubleURLEncoding),
+		encodingMutation(generation.UnicodeEncoding),
+	},
+}
+
+// GetWAFEvasionMutations returns the WAF evasion mutations for the class of the payload
+func GetWAFEvasionMutations(payload generation.Payload) []WAFEvasionMutation {
+	return wafEvasionMutations[GetPayloadClass(payload)]
+}
+
+// ApplyWAFEvasionMutation returns a copy of the payload with the mutation applied to its unencoded value. Unless the
+// mutation is an encoding, the detection methods looking for the payload are updated to look for the mutated one.
+// The second return value is false when the mutation does not change the payload.
+func ApplyWAFEvasionMutation(payload generation.Payload, mutation WAFEvasionMutation) (generation.Payload, bool) {
+	raw := payload.UnencodedValue()
+	if mutation.Encoding != "" {
+		variants, err := payload.EncodedVariants([]generation.EncodingChain{{mutation.Encoding}})
+		if err != nil || variants[0].Value == raw {
+			return payload, false
+		}
+		return variants[0], true
+	}
+	mutated := mutation.Mutate(raw)
+	if mutated == raw {
+		return payload, false
+	}
+	result := payload
+	result.Value = mutated
+	result.RawValue = mutated
+	result.Encoding = nil
+	result.DetectionMethods = make([]generation.DetectionMethod, len(payload.DetectionMethods))
+	for i, method := range payload.DetectionMethods {
+		result.DetectionMethods[i] = replaceDetectionMethodPayload(method, raw, mutated)
+	}
+	return result, true
+}
+
+// replaceDetectionMethodPayload returns a copy of the detection method looking for the mutated payload instead of the
+// original one
+func replaceDetectionMethodPayload(method generation.DetectionMethod, original, mutated string) generation.DetectionMethod {
+	if method.Reflection != nil {
+		reflection := *method.Reflection
+		reflection.Value = strings.ReplaceAll(reflection.Value, original, mutated)
+		method.Reflection = &reflection
+	}
+	if method.ResponseCondition != nil {
+		condition := *method.ResponseCondition
+		condition.Contains = strings.ReplaceAll(condition.Contains, original, mutated)
+		method.ResponseCondition = &condition
+	}
+	if method.BrowserEvents != nil {
+		events := *method.BrowserEvents
+		events.Value = strings.ReplaceAll(events.Value, original, mutated)
+		method.BrowserEvents = &events
+	}
+	return method
+}
+
+// WAFEvasion records the mutation which bypassed a WAF block
+type WAFEvasion struct {
+	Block           WAFBlock
+	Mutation        string
+	OriginalPayload string
+}
+
+// Details describes the WAF evasion for the issue details
+func (e WAFEvasion) Details() string {
+	return fmt.Sprintf("The original payload %s was blocked by a WAF (%s). It has been bypassed by applying the `%s` mutation.", e.OriginalPayload, e.Block, e.Mutation)
+}
@@ -0,0 +1,180 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestDetectWAFBlock(t *testing.T) {
+	original := &db.History{StatusCode: 200, ResponseBody: []byte("<html>Search results</html>")}
+
+	tests := []struct {
+		name     string
+		original *db.History
+		result   *db.History
+		blocked  bool
+		waf      string
+	}{
+		{
+			name:    "Not blocked",
+			result:  &db.History{StatusCode: 200, ResponseBody: []byte("<html>No results</html>")},
+			blocked: false,
+		},
+		{
+			name:    "Forbidden status code",
+			result:  &db.History{StatusCode: 403, ResponseBody: []byte("Forbidden")},
+			blocked: true,
+		},
+		{
+			name:    "Not acceptable status code",
+			result:  &db.History{StatusCode: 406, ResponseBody: []byte("Not Acceptable")},
+			blocked: true,
+		},
+		{
+			name:     "Original already forbidden",
+			original: &db.History{StatusCode: 403, ResponseBody: []byte("Forbidden")},
+			result:   &db.History{StatusCode: 403, ResponseBody: []byte("Forbidden")},
+			blocked:  false,
+		},
+		{
+			name:    "Cloudflare block page",
+			result:  &db.History{StatusCode: 403, ResponseBody: []byte("<title>Attention Required! | Cloudflare</title>")},
+			blocked: true,
+			waf:     "Cloudflare",
+		},
+		{
+			name:    "ModSecurity block page with a 200 status code",
+			result:  &db.History{StatusCode: 200, ResponseBody: []byte("<p>This error was generated by Mod_Security.</p>")},
+			blocked: true,
+			waf:     "ModSecurity",
+		},
+		{
+			name:    "F5 BIG-IP ASM block page",
+			result:  &db.History{StatusCode: 200, ResponseBody: []byte("<html><body>The requested URL was rejected. Please consult with your administrator.<br>Your support ID is: 123</body></html>")},
+			blocked: true,
+			waf:     "F5 BIG-IP ASM",
+		},
+		{
+			name:    "Imperva Incapsula block page",
+			result:  &db.History{StatusCode: 200, ResponseBody: []byte(`<iframe src="/_Incapsula_Resource?CWUDNSAI=1">Request unsuccessful. Incapsula incident ID: 1-2</iframe>`)},
+			blocked: true,
+			waf:     "Imperva Incapsula",
+		},
+		{
+			name:    "Sucuri block header",
+			result:  &db.History{StatusCode: 200, ResponseHeaders: datatypes.JSON(`{"X-Sucuri-Block":["XSS01"]}`)},
+			blocked: true,
+			waf:     "Sucuri",
+		},
+		{
+			name:     "Signature also in the original response",
+			original: &db.History{StatusCode: 200, ResponseBody: []byte("Protected by Wordfence. Generated by Wordfence at Mon")},
+			result:   &db.History{StatusCode: 200, ResponseBody: []byte("Protected by Wordfence. Generated by Wordfence at Tue")},
+			blocked:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := original
+			if tt.original != nil {
+				base = tt.original
+			}
+			block, blocked := DetectWAFBlock(base, tt.result)
+			assert.Equal(t, tt.blocked, blocked)
+			assert.Equal(t, tt.waf, block.WAF)
+			if blocked {
+				assert.Equal(t, tt.result.StatusCode, block.StatusCode)
+			}
+		})
+	}
+}
+
+func TestGetPayloadClass(t *testing.T) {
+	assert.Equal(t, SQLPayloadClass, GetPayloadClass(generation.Payload{IssueCode: "sql_injection"}))
+	assert.Equal(t, XSSPayloadClass, GetPayloadClass(generation.Payload{IssueCode: "xss_reflected"}))
+	assert.Equal(t, GenericPayloadClass, GetPayloadClass(generation.Payload{IssueCode: "path_traversal"}))
+}
+
+func findWAFEvasionMutation(t *testing.T, payload generation.Payload, name string) WAFEvasionMutation {
+	for _, mutation := range GetWAFEvasionMutations(payload) {
+		if mutation.Name == name {
+			return mutation
+		}
+	}
+	t.Fatalf("mutation %s not found", name)
+	return WAFEvasionMutation{}
+}
+
+func TestApplyWAFEvasionMutationSQL(t *testing.T) {
+	payload := generation.Payload{
+		IssueCode: "sql_injection",
+		Value:     "1 union select sukyanmarker from users",
+		DetectionMethods: []generation.DetectionMethod{
+			{ResponseCondition: &generation.ResponseConditionDetectionMethod{Contains: "sukyanmarker"}},
+		},
+	}
+
+	mutated, ok := ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "case-variation"))
+	assert.True(t, ok)
+	assert.Equal(t, "1 UnIoN SeLeCt sukyanmarker FrOm users", mutated.Value)
+	assert.Equal(t, "sukyanmarker", mutated.DetectionMethods[0].ResponseCondition.Contains)
+
+	mutated, ok = ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "comment-insertion"))
+	assert.True(t, ok)
+	assert.Equal(t, "1/**/union/**/select/**/sukyanmarker/**/from/**/users", mutated.Value)
+
+	mutated, ok = ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "double-url-encoding"))
+	assert.True(t, ok)
+	assert.Equal(t, "1%2520union%2520select%2520sukyanmarker%2520from%2520users", mutated.Value)
+	assert.Equal(t, payload.Value, mutated.UnencodedValue())
+	assert.Equal(t, generation.EncodingChain{generation.DoubleURLEncoding}, mutated.Encoding)
+
+	// The original payload is left untouched
+	assert.Equal(t, "1 union select sukyanmarker from users", payload.Value)
+	assert.False(t, payload.IsEncoded())
+}
+
+func TestApplyWAFEvasionMutationXSS(t *testing.T) {
+	payload := generation.Payload{
+		IssueCode: "xss_reflected",
+		Value:     "<img src=x onerror=alert(1)>",
+		DetectionMethods: []generation.DetectionMethod{
+			{Reflection: &generation.ReflectionDetectionMethod{Value: "<img src=x onerror=alert(1)>"}},
+			{BrowserEvents: &generation.BrowserEventsDetectionMethod{Event: "dialog", Value: "1"}},
+		},
+	}
+
+	mutated, ok := ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "case-variation"))
+	assert.True(t, ok)
+	assert.Equal(t, "<ImG src=x OnErRoR=alert(1)>", mutated.Value)
+	// Detection methods looking for the payload look for the mutated one, as it is what gets reflected
+	assert.Equal(t, "<ImG src=x OnErRoR=alert(1)>", mutated.DetectionMethods[0].Reflection.Value)
+	assert.Equal(t, "1", mutated.DetectionMethods[1].BrowserEvents.Value)
+	assert.Equal(t, "<img src=x onerror=alert(1)>", payload.DetectionMethods[0].Reflection.Value)
+
+	mutated, ok = ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "whitespace-substitution"))
+	assert.True(t, ok)
+	assert.Equal(t, "<img/src=x onerror=alert(1)>", mutated.Value)
+
+	mutated, ok = ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "html-entity-encoding"))
+	assert.True(t, ok)
+	assert.Equal(t, "<img src=x onerror=alert(1)>", mutated.DetectionMethods[0].Reflection.Value)
+}
+
+func TestApplyWAFEvasionMutationUnchanged(t *testing.T) {
+	payload := generation.Payload{IssueCode: "sql_injection", Value: "'"}
+	_, ok := ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "comment-insertion"))
+	assert.False(t, ok)
+	_, ok = ApplyWAFEvasionMutation(payload, findWAFEvasionMutation(t, payload, "case-variation"))
+	assert.False(t, ok)
+}
+
+func TestWAFEvasionDetails(t *testing.T) {
+	evasion := WAFEvasion{Block: WAFBlock{WAF: "Cloudflare", StatusCode: 403}, Mutation: "case-variation", OriginalPayload: "<svg onload=alert(1)>"}
+	assert.Equal(t, "The original payload <svg onload=alert(1)> was blocked by a WAF (Cloudflare block page with status code 403). It has been bypassed by applying the `case-variation` mutation.", evasion.Details())
+	assert.Equal(t, "status code 406", WAFBlock{StatusCode: 406}.String())
+}
@@ -0,0 +1,245 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+)
+
+const signalRRecordSeparator = "\x1e"
+
+// WebSocketFrame holds the application data carried by a message of a framing protocol, together with what is needed
+// to wrap it back
+type WebSocketFrame struct {
+	// Header is the framing which precedes the application data, such as the socket.io packet type and namespace
+	Header string
+	// Event is the event or hub method the application data is sent to
+	Event string
+	// Data is the application data, usually a JSON document
+	Data string
+	// Raw is the original message, required to re-frame protocols which are not just a prefix
+	Raw string
+	// multipleArgs reports that Data holds the array of arguments instead of a single one
+	multipleArgs bool
+}
+
+// FrameCodec unwraps and wraps the application data of the framing protocol spoken on top of a WebSocket connection
+type FrameCodec interface {
+	Name() string
+	// Decode extracts the application data of the message, returning false for messages which carry none, such as
+	// pings or handshakes
+	Decode(payload string) (WebSocketFrame, bool)
+	// Encode wraps the provided application data using the framing of the decoded frame
+	Encode(frame WebSocketFrame, data string) (string, error)
+}
+
+// RawCodec is used when there is no framing protocol, so the whole message is application data
+type RawCodec struct{}
+
+func (RawCodec) Name() string {
+	return "raw"
+}
+
+func (RawCodec) Decode(payload string) (WebSocketFrame, bool) {
+	return WebSocketFrame{Data: payload, Raw: payload}, true
+}
+
+func (RawCodec) Encode(frame WebSocketFrame, data string) (string, error) {
+	return data, nil
+}
+
+// SocketIOCodec handles socket.io event packets sent over Engine.IO, such as 42["event",{"key":"value"}] or
+// 42/namespace,17["event","arg1","arg2"]
+type SocketIOCodec struct{}
+
+func (SocketIOCodec) Name() string {
+	return "socket.io"
+}
+
+func (SocketIOCodec) Decode(payload string) (WebSocketFrame, bool) {
+	// Engine.IO message packet (4) containing a socket.io event (2) or binary event (5)
+	if len(payload) < 3 || payload[0] != '4' || (payload[1] != '2' && payload[1] != '5') {
+		return WebSocketFrame{}, false
+	}
+	start := strings.Index(payload, "[")
+	if start == -1 {
+		return WebSocketFrame{}, false
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal([]byte(payload[start:]), &args); err != nil || len(args) == 0 {
+		return WebSocketFrame{}, false
+	}
+	var event string
+	if err := json.Unmarshal(args[0], &event); err != nil {
+		return WebSocketFrame{}, false
+	}
+
+	frame := WebSocketFrame{Header: payload[:start], Event: event, Raw: payload}
+	switch len(args) {
+	case 1:
+		frame.Data = ""
+	case 2:
+		frame.Data = string(args[1])
+	default:
+		frame.Data = "[" + string(bytes.Join(toByteSlices(args[1:]), []byte(","))) + "]"
+		frame.multipleArgs = true
+	}
+	return frame, true
+}
+
+func (SocketIOCodec) Encode(frame WebSocketFrame, data string) (string, error) {
+	event, err := marshalWithoutHTMLEscape(frame.Event)
+	if err != nil {
+		return "", err
+	}
+	if data == "" {
+		return frame.Header + "[" + string(event) + "]", nil
+	}
+	if frame.multipleArgs {
+		var args []json.RawMessage
+		if err := json.Unmarshal([]byte(data), &args); err == nil {
+			data = string(bytes.Join(toByteSlices(args), []byte(",")))
+		}
+	}
+	return frame.Header + "[" + string(event) + "," + data + "]", nil
+}
+
+// SignalRCodec handles invocation messages of the SignalR JSON hub protocol, such as
+// {"type":1,"target":"SendMessage","arguments":["user","hello"]} followed by the record separator
+type SignalRCodec struct{}
+
+func (SignalRCodec) Name() string {
+	return "signalr"
+}
+
+func (SignalRCodec) Decode(payload string) (WebSocketFrame, bool) {
+	record := strings.TrimSuffix(payload, signalRRecordSeparator)
+	var message struct {
+		Type      int             `json:"type"`
+		Target    string          `json:"target"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(record), &message); err != nil {
+		return WebSocketFrame{}, false
+	}
+	// Invocation (1) and stream invocation (4) messages are the only ones sending application data to the server
+	if (message.Type != 1 && message.Type != 4) || len(message.Arguments) == 0 {
+		return WebSocketFrame{}, false
+	}
+	return WebSocketFrame{Event: message.Target, Data: string(message.Arguments), Raw: payload}, true
+}
+
+func (SignalRCodec) Encode(frame WebSocketFrame, data string) (string, error) {
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(strings.TrimSuffix(frame.Raw, signalRRecordSeparator)), &message); err != nil {
+		return "", err
+	}
+	if json.Valid([]byte(data)) {
+		message["arguments"] = json.RawMessage(data)
+	} else {
+		// The payload broke the arguments array, so it is sent as the only argument
+		argument, err := marshalWithoutHTMLEscape(data)
+		if err != nil {
+			return "", err
+		}
+		message["arguments"] = json.RawMessage("[" + string(argument) + "]")
+	}
+	encoded, err := marshalWithoutHTMLEscape(message)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded) + signalRRecordSeparator, nil
+}
+
+// GetFramedWebSocketMessageInsertionPoints returns the insertion points of the application data carried by the message
+func GetFramedWebSocketMessageInsertionPoints(message *db.WebSocketMessage, codec FrameCodec) []InsertionPoint {
+	frame, ok := codec.Decode(message.PayloadData)
+	if !ok || frame.Data == "" {
+		return nil
+	}
+	inner := *message
+	inner.PayloadData = frame.Data
+	return GetWebSocketMessageInsertionPoints(&inner)
+}
+
+// CreateModifiedFramedWebSocketMessage inserts the payload into the application data carried by the message and
+// frames it back using the codec
+func CreateModifiedFramedWebSocketMessage(message *db.WebSocketMessage, codec FrameCodec, insertionPoint InsertionPoint, payload string) (*db.WebSocketMessage, error) {
+	frame, ok := codec.Decode(message.PayloadData)
+	if !ok {
+		return nil, fmt.Errorf("message does not contain %s application data", codec.Name())
+	}
+	inner := *message
+	inner.PayloadData = frame.Data
+	modified, err := CreateModifiedWebSocketMessage(&inner, insertionPoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	modified.PayloadData, err = codec.Encode(frame, modified.PayloadData)
+	if err != nil {
+		return nil, err
+	}
+	return modified, nil
+}
+
+// SelectFrameCodec picks the codec for the connection by inspecting its URL, subprotocol and handshake messages
+func SelectFrameCodec(connection *db.WebSocketConnection, messages []db.WebSocketMessage) FrameCodec {
+	if connection != nil {
+		if u, err := url.Parse(connection.URL); err == nil {
+			if strings.Contains(u.Path, "/socket.io") || u.Query().Get("EIO") != "" {
+				return SocketIOCodec{}
+			}
+		}
+		if headers, err := connection.GetResponseHeadersAsMap(); err == nil {
+			for key, values := range headers {
+				if !strings.EqualFold(key, "Sec-WebSocket-Protocol") {
+					continue
+				}
+				for _, value := range values {
+					value = strings.ToLower(value)
+					if strings.Contains(value, "socket.io") || strings.Contains(value, "engine.io") {
+						return SocketIOCodec{}
+					}
+					if strings.Contains(value, "signalr") {
+						return SignalRCodec{}
+					}
+				}
+			}
+		}
+	}
+
+	for _, message := range messages {
+		payload := strings.TrimSpace(message.PayloadData)
+		// SignalR clients start by sending the hub protocol handshake
+		if message.Direction == db.MessageSent && strings.HasPrefix(payload, `{"protocol":`) && strings.HasSuffix(payload, signalRRecordSeparator) {
+			return SignalRCodec{}
+		}
+		// Engine.IO servers open the session with an open packet containing the session id
+		if message.Direction == db.MessageReceived && strings.HasPrefix(payload, `0{"sid":`) {
+			return SocketIOCodec{}
+		}
+	}
+	return RawCodec{}
+}
+
+func marshalWithoutHTMLEscape(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func toByteSlices(messages []json.RawMessage) [][]byte {
+	slices := make([][]byte, len(messages))
+	for i, message := range messages {
+		slices[i] = message
+	}
+	return slices
+}
@@ -0,0 +1,144 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib/integrations"
+	"github.com/pyneda/sukyan/pkg/payloads/generation"
+	"github.com/pyneda/sukyan/pkg/scan/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSocketIOCodecDecode(t *testing.T) {
+	codec := SocketIOCodec{}
+
+	frame, ok := codec.Decode(`42["message",{"text":"hello","room":"lobby"}]`)
+	assert.True(t, ok)
+	assert.Equal(t, "42", frame.Header)
+	assert.Equal(t, "message", frame.Event)
+	assert.Equal(t, `{"text":"hello","room":"lobby"}`, frame.Data)
+
+	frame, ok = codec.Decode(`42/chat,17["join","lobby","john"]`)
+	assert.True(t, ok)
+	assert.Equal(t, "42/chat,17", frame.Header)
+	assert.Equal(t, "join", frame.Event)
+	assert.Equal(t, `["lobby","john"]`, frame.Data)
+
+	// Engine.IO ping and socket.io connect packets carry no application data
+	for _, payload := range []string{"2", "3", "40", `0{"sid":"abc"}`} {
+		_, ok = codec.Decode(payload)
+		assert.False(t, ok, payload)
+	}
+}
+
+func TestSocketIOCodecEncode(t *testing.T) {
+	codec := SocketIOCodec{}
+
+	frame, ok := codec.Decode(`42["message",{"text":"hello"}]`)
+	assert.True(t, ok)
+	encoded, err := codec.Encode(frame, frame.Data)
+	assert.NoError(t, err)
+	assert.Equal(t, `42["message",{"text":"hello"}]`, encoded)
+
+	encoded, err = codec.Encode(frame, `{"text":"<img src=x>"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `42["message",{"text":"<img src=x>"}]`, encoded)
+
+	frame, ok = codec.Decode(`42/chat,17["join","lobby","john"]`)
+	assert.True(t, ok)
+	encoded, err = codec.Encode(frame, `["lobby","admin"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, `42/chat,17["join","lobby","admin"]`, encoded)
+}
+
+func TestCreateModifiedFramedWebSocketMessage(t *testing.T) {
+	message := &db.WebSocketMessage{
+		Opcode:      1,
+		PayloadData: `42["message",{"text":"hello","room":"lobby"}]`,
+	}
+	codec := SocketIOCodec{}
+	insertionPoints := GetFramedWebSocketMessageInsertionPoints(message, codec)
+	assert.Len(t, insertionPoints, 2)
+	assert.Equal(t, "/text", insertionPoints[0].Name)
+
+	modified, err := CreateModifiedFramedWebSocketMessage(message, codec, insertionPoints[0], `"><svg onload=alert(1)>`)
+	assert.NoError(t, err)
+	assert.Equal(t, `42["message",{"text":"\"><svg onload=alert(1)>","room":"lobby"}]`, modified.PayloadData)
+}
+
+func TestSignalRCodec(t *testing.T) {
+	codec := SignalRCodec{}
+	payload := `{"type":1,"target":"SendMessage","arguments":["john","hello"]}` + signalRRecordSeparator
+
+	frame, ok := codec.Decode(payload)
+	assert.True(t, ok)
+	assert.Equal(t, "SendMessage", frame.Event)
+	assert.Equal(t, `["john","hello"]`, frame.Data)
+
+	encoded, err := codec.Encode(frame, `["john","<b>"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"arguments":["john","<b>"],"target":"SendMessage","type":1}`+signalRRecordSeparator, encoded)
+
+	_, ok = codec.Decode(`{"type":6}` + signalRRecordSeparator)
+	assert.False(t, ok)
+}
+
+func TestSelectFrameCodec(t *testing.T) {
+	connection := &db.WebSocketConnection{URL: "wss://example.com/socket.io/?EIO=4&transport=websocket"}
+	assert.Equal(t, "socket.io", SelectFrameCodec(connection, nil).Name())
+
+	connection = &db.WebSocketConnection{URL: "wss://example.com/chatHub"}
+	messages := []db.WebSocketMessage{
+		{PayloadData: `{"protocol":"json","version":1}` + signalRRecordSeparator, Direction: db.MessageSent},
+	}
+	assert.Equal(t, "signalr", SelectFrameCodec(connection, messages).Name())
+
+	connection = &db.WebSocketConnection{URL: "wss://example.com/ws"}
+	assert.Equal(t, "raw", SelectFrameCodec(connection, nil).Name())
+}
+
+func TestWebSocketScannerRunSocketIOMessage(t *testing.T) {
+	workspace, err := db.Connection.GetOrCreateWorkspace(&db.Workspace{
+		Code:  "TestWebSocketScannerRunSocketIOMessage",
+		Title: "TestWebSocketScannerRunSocketIOMessage",
+	})
+	assert.Nil(t, err)
+	defer db.Connection.DeleteWorkspace(workspace.ID)
+
+	connection := &db.WebSocketConnection{
+		URL:             "wss://chat.example.com/socket.io/?EIO=4&transport=websocket",
+		RequestHeaders:  []byte(`{}`),
+		ResponseHeaders: []byte(`{}`),
+		StatusCode:      101,
+		WorkspaceID:     &workspace.ID,
+	}
+	assert.Nil(t, db.Connection.CreateWebSocketConnection(connection))
+	message := &db.WebSocketMessage{
+		ConnectionID: connection.ID,
+		Opcode:       1,
+		PayloadData:  `42["message",{"text":"hello"}]`,
+		Direction:    db.MessageSent,
+	}
+	assert.Nil(t, db.Connection.CreateWebSocketMessage(message))
+
+	scanner := WebSocketScanner{
+		Concurrency:         1,
+		InteractionsManager: &integrations.InteractionsManager{},
+		WorkspaceID:         workspace.ID,
+	}
+	generator := &generation.PayloadGenerator{
+		IssueCode: string(db.XssReflectedCode),
+		Templates: []string{"skwsprobe"},
+	}
+	results := scanner.Run(message, []*generation.PayloadGenerator{generator}, nil, options.HistoryItemScanOptions{WorkspaceID: workspace.ID})
+
+	// The codec is selected from the connection and the payload is inserted in the event data, keeping the framing
+	assert.Len(t, results[string(db.XssReflectedCode)], 1)
+	result := results[string(db.XssReflectedCode)][0]
+	assert.Equal(t, SocketIOCodec{}, result.Codec)
+	assert.Equal(t, "/text", result.InsertionPoint.Name)
+	assert.Equal(t, `42["message",{"text":"skwsprobe"}]`, result.Result.PayloadData)
+	assert.NotNil(t, result.Issue)
+	assert.Equal(t, connection.ID, *result.Issue.WebsocketConnectionID)
+}
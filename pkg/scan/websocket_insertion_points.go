@@ -0,0 +1,258 @@
+package scan
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
+)
+
+const (
+	websocketOpcodeBinary = 2
+
+	// Encodings used to represent binary WebSocket payloads as text
+	WebSocketEncodingRaw    = "raw"
+	WebSocketEncodingBase64 = "base64"
+	WebSocketEncodingHex    = "hex"
+
+	// Kinds of binary insertion points, used as prefix of their names
+	binaryFieldRaw  = "raw"
+	binaryFieldLP8  = "lp8"
+	binaryFieldLP16 = "lp16"
+
+	minBinaryStringLength = 3
+)
+
+// Binary insertion point names follow the <kind>@<offset>:<length> format, where the offset points to the length prefix
+// of length-prefixed fields and to the value itself for raw byte ranges
+var binaryInsertionPointNameRegex = regexp.MustCompile(`^(raw|lp8|lp16)@(\d+):(\d+)$`)
+
+// BinaryField describes where a value is located within a binary WebSocket payload
+type BinaryField struct {
+	Kind   string
+	Offset int
+	Length int
+}
+
+// Name returns the insertion point name for the field
+func (f BinaryField) Name() string {
+	return fmt.Sprintf("%s@%d:%d", f.Kind, f.Offset, f.Length)
+}
+
+// valueOffset returns the offset where the value starts, skipping the length prefix
+func (f BinaryField) valueOffset() int {
+	switch f.Kind {
+	case binaryFieldLP8:
+		return f.Offset + 1
+	case binaryFieldLP16:
+		return f.Offset + 2
+	default:
+		return f.Offset
+	}
+}
+
+// ParseBinaryField parses the name of a binary insertion point
+func ParseBinaryField(name string) (BinaryField, error) {
+	match := binaryInsertionPointNameRegex.FindStringSubmatch(name)
+	if match == nil {
+		return BinaryField{}, fmt.Errorf("invalid binary insertion point name: %s", name)
+	}
+	offset, _ := strconv.Atoi(match[2])
+	length, _ := strconv.Atoi(match[3])
+	return BinaryField{Kind: match[1], Offset: offset, Length: length}, nil
+}
+
+// DecodeWebSocketBinaryPayload returns the bytes of a binary WebSocket message and the encoding used to represent them.
+// Binary frames are stored base64 encoded, while text frames are only considered binary when they contain a hex or
+// base64 encoded blob holding non printable data.
+func DecodeWebSocketBinaryPayload(message *db.WebSocketMessage) ([]byte, string, bool) {
+	payload := message.PayloadData
+	if message.Opcode == websocketOpcodeBinary {
+		if data, err := base64.StdEncoding.DecodeString(payload); err == nil {
+			return data, WebSocketEncodingBase64, true
+		}
+		return []byte(payload), WebSocketEncodingRaw, true
+	}
+
+	trimmed := strings.TrimSpace(payload)
+	if len(trimmed) < 8 || trimmed != payload {
+		return nil, "", false
+	}
+	if len(trimmed)%2 == 0 {
+		if data, err := hex.DecodeString(trimmed); err == nil && !isPrintable(data) {
+			return data, WebSocketEncodingHex, true
+		}
+	}
+	if data, err := base64.StdEncoding.DecodeString(trimmed); err == nil && !isPrintable(data) {
+		return data, WebSocketEncodingBase64, true
+	}
+	return nil, "", false
+}
+
+// EncodeWebSocketBinaryPayload encodes the bytes back using the encoding of the original message
+func EncodeWebSocketBinaryPayload(data []byte, encoding string) string {
+	switch encoding {
+	case WebSocketEncodingBase64:
+		return base64.StdEncoding.EncodeToString(data)
+	case WebSocketEncodingHex:
+		return hex.EncodeToString(data)
+	default:
+		return string(data)
+	}
+}
+
+// FindBinaryFields looks for printable strings embedded in binary data, detecting when they are prefixed by their
+// length as a single byte (also covering protobuf strings) or as a big endian uint16
+func FindBinaryFields(data []byte) []BinaryField {
+	var fields []BinaryField
+	for start := 0; start < len(data); {
+		if !isPrintableByte(data[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(data) && isPrintableByte(data[end]) {
+			end++
+		}
+		length := end - start
+		switch {
+		case start >= 2 && length >= minBinaryStringLength && int(binary.BigEndian.Uint16(data[start-2:start])) == length:
+			fields = append(fields, BinaryField{Kind: binaryFieldLP16, Offset: start - 2, Length: length})
+		case start >= 1 && length >= minBinaryStringLength && int(data[start-1]) == length:
+			fields = append(fields, BinaryField{Kind: binaryFieldLP8, Offset: start - 1, Length: length})
+		case length > minBinaryStringLength && int(data[start]) == length-1:
+			// The length prefix is a printable byte itself, so it has been included in the run
+			fields = append(fields, BinaryField{Kind: binaryFieldLP8, Offset: start, Length: length - 1})
+		case length > minBinaryStringLength:
+			fields = append(fields, BinaryField{Kind: binaryFieldRaw, Offset: start, Length: length})
+		}
+		start = end
+	}
+	return fields
+}
+
+// GetWebSocketMessageInsertionPoints returns the insertion points of a WebSocket message. Binary messages get an
+// insertion point for each embedded string and JSON messages one for each leaf value, named by its JSON pointer,
+// while the whole payload is used for any other message.
+func GetWebSocketMessageInsertionPoints(message *db.WebSocketMessage) []InsertionPoint {
+	data, _, isBinary := DecodeWebSocketBinaryPayload(message)
+	if !isBinary {
+		if isJSONDocument(message.PayloadData) {
+			if leaves, err := FindJSONLeaves([]byte(message.PayloadData)); err == nil && len(leaves) > 0 {
+				insertionPoints := make([]InsertionPoint, 0, len(leaves))
+				for _, leaf := range leaves {
+					insertionPoints = append(insertionPoints, InsertionPoint{
+						Type:         InsertionPointTypeJSON,
+						Name:         leaf.Pointer,
+						Value:        leaf.Value,
+						ValueType:    lib.GuessDataType(leaf.Value),
+						OriginalData: message.PayloadData,
+					})
+				}
+				return insertionPoints
+			}
+		}
+		return []InsertionPoint{
+			{
+				Type:         InsertionPointTypeFullBody,
+				Name:         "message",
+				Value:        message.PayloadData,
+				ValueType:    lib.GuessDataType(message.PayloadData),
+				OriginalData: message.PayloadData,
+			},
+		}
+	}
+
+	var insertionPoints []InsertionPoint
+	for _, field := range FindBinaryFields(data) {
+		value := string(data[field.valueOffset() : field.valueOffset()+field.Length])
+		insertionPoints = append(insertionPoints, InsertionPoint{
+			Type:         InsertionPointTypeBinary,
+			Name:         field.Name(),
+			Value:        value,
+			ValueType:    lib.GuessDataType(value),
+			OriginalData: message.PayloadData,
+		})
+	}
+	return insertionPoints
+}
+
+// CreateModifiedWebSocketMessage returns a copy of the message with the payload inserted at the insertion point.
+// Binary messages are decoded, modified at the byte level updating the length prefix of the field if required, and
+// re-encoded with their original encoding. JSON messages only get the targeted leaf replaced, keeping the rest of the
+// document untouched.
+func CreateModifiedWebSocketMessage(message *db.WebSocketMessage, insertionPoint InsertionPoint, payload string) (*db.WebSocketMessage, error) {
+	modified := *message
+	modified.ID = 0
+	switch insertionPoint.Type {
+	case InsertionPointTypeBinary:
+		data, encoding, isBinary := DecodeWebSocketBinaryPayload(message)
+		if !isBinary {
+			return nil, errors.New("message does not contain a binary payload")
+		}
+		field, err := ParseBinaryField(insertionPoint.Name)
+		if err != nil {
+			return nil, err
+		}
+		result, err := ReplaceBinaryField(data, field, []byte(payload))
+		if err != nil {
+			return nil, err
+		}
+		modified.PayloadData = EncodeWebSocketBinaryPayload(result, encoding)
+	case InsertionPointTypeJSON:
+		result, err := ReplaceJSONLeaf([]byte(message.PayloadData), insertionPoint.Name, payload)
+		if err != nil {
+			return nil, err
+		}
+		modified.PayloadData = string(result)
+	default:
+		modified.PayloadData = payload
+	}
+	return &modified, nil
+}
+
+// ReplaceBinaryField replaces the value of the field with the payload, updating its length prefix
+func ReplaceBinaryField(data []byte, field BinaryField, payload []byte) ([]byte, error) {
+	valueOffset := field.valueOffset()
+	if field.Offset < 0 || valueOffset+field.Length > len(data) {
+		return nil, fmt.Errorf("binary field %s is out of bounds", field.Name())
+	}
+
+	result := make([]byte, 0, len(data)-field.Length+len(payload))
+	result = append(result, data[:field.Offset]...)
+	switch field.Kind {
+	case binaryFieldLP8:
+		if len(payload) > 0xff {
+			return nil, fmt.Errorf("payload of %d bytes does not fit in a 1 byte length prefix", len(payload))
+		}
+		result = append(result, byte(len(payload)))
+	case binaryFieldLP16:
+		if len(payload) > 0xffff {
+			return nil, fmt.Errorf("payload of %d bytes does not fit in a 2 bytes length prefix", len(payload))
+		}
+		result = binary.BigEndian.AppendUint16(result, uint16(len(payload)))
+	}
+	result = append(result, payload...)
+	result = append(result, data[valueOffset+field.Length:]...)
+	return result, nil
+}
+
+func isPrintableByte(b byte) bool {
+	return b >= 0x20 && b <= 0x7e
+}
+
+func isPrintable(data []byte) bool {
+	for _, b := range data {
+		if !isPrintableByte(b) && b != '\n' && b != '\r' && b != '\t' {
+			return false
+		}
+	}
+	return true
+}
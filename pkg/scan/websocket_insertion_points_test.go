@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/pyneda/sukyan/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// protobuf like message with a 1 byte length prefixed string followed by a uint16 length prefixed one
+var binaryTestMessage = []byte{0x08, 0x01, 0x12, 0x05, 'h', 'e', 'l', 'l', 'o', 0x1a, 0x00, 0x04, 't', 'e', 's', 't', 0xff}
+
+func TestFindBinaryFields(t *testing.T) {
+	fields := FindBinaryFields(binaryTestMessage)
+	assert.Equal(t, []BinaryField{
+		{Kind: "lp8", Offset: 3, Length: 5},
+		{Kind: "lp16", Offset: 10, Length: 4},
+	}, fields)
+
+	field, err := ParseBinaryField(fields[1].Name())
+	assert.NoError(t, err)
+	assert.Equal(t, fields[1], field)
+}
+
+func TestCreateModifiedWebSocketMessageBinaryRoundTrip(t *testing.T) {
+	message := &db.WebSocketMessage{
+		Opcode:      2,
+		PayloadData: base64.StdEncoding.EncodeToString(binaryTestMessage),
+	}
+	insertionPoints := GetWebSocketMessageInsertionPoints(message)
+	assert.Len(t, insertionPoints, 2)
+	assert.Equal(t, InsertionPointTypeBinary, insertionPoints[0].Type)
+	assert.Equal(t, "hello", insertionPoints[0].Value)
+	assert.Equal(t, "test", insertionPoints[1].Value)
+
+	payload := "<script>alert(1)</script>"
+	modified, err := CreateModifiedWebSocketMessage(message, insertionPoints[0], payload)
+	assert.NoError(t, err)
+	decoded, err := base64.StdEncoding.DecodeString(modified.PayloadData)
+	assert.NoError(t, err)
+	expected := append([]byte{0x08, 0x01, 0x12, byte(len(payload))}, payload...)
+	expected = append(expected, 0x1a, 0x00, 0x04, 't', 'e', 's', 't', 0xff)
+	assert.Equal(t, expected, decoded)
+
+	modified, err = CreateModifiedWebSocketMessage(message, insertionPoints[1], payload)
+	assert.NoError(t, err)
+	decoded, err = base64.StdEncoding.DecodeString(modified.PayloadData)
+	assert.NoError(t, err)
+	expected = append([]byte{0x08, 0x01, 0x12, 0x05, 'h', 'e', 'l', 'l', 'o', 0x1a, 0x00, byte(len(payload))}, payload...)
+	expected = append(expected, 0xff)
+	assert.Equal(t, expected, decoded)
+}
+
+func TestCreateModifiedWebSocketMessageHexEncoded(t *testing.T) {
+	message := &db.WebSocketMessage{
+		Opcode:      1,
+		PayloadData: hex.EncodeToString(binaryTestMessage),
+	}
+	insertionPoints := GetWebSocketMessageInsertionPoints(message)
+	assert.Len(t, insertionPoints, 2)
+
+	modified, err := CreateModifiedWebSocketMessage(message, insertionPoints[0], "abc")
+	assert.NoError(t, err)
+	decoded, err := hex.DecodeString(modified.PayloadData)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 0x01, 0x12, 0x03, 'a', 'b', 'c', 0x1a, 0x00, 0x04, 't', 'e', 's', 't', 0xff}, decoded)
+}
+
+func TestGetWebSocketMessageInsertionPointsText(t *testing.T) {
+	message := &db.WebSocketMessage{
+		Opcode:      1,
+		PayloadData: "hello world",
+	}
+	insertionPoints := GetWebSocketMessageInsertionPoints(message)
+	assert.Len(t, insertionPoints, 1)
+	assert.Equal(t, InsertionPointTypeFullBody, insertionPoints[0].Type)
+
+	modified, err := CreateModifiedWebSocketMessage(message, insertionPoints[0], "payload")
+	assert.NoError(t, err)
+	assert.Equal(t, "payload", modified.PayloadData)
+}
@@ -19,21 +19,29 @@ type WebSocketScanner struct {
 	InteractionsManager *integrations.InteractionsManager
 	AvoidRepeatedIssues bool
 	WorkspaceID         uint
-	issuesFound         sync.Map
-	results             sync.Map
+	// Codec handles the framing protocol spoken over the connection, it is selected from the connection of the scanned
+	// message when not set
+	Codec       FrameCodec
+	issuesFound sync.Map
+	results     sync.Map
 }
 
 type WebSocketScannerResult struct {
-	Original *db.WebSocketMessage
-	Result   *db.WebSocketMessage
-	Err      error
-	Payload  generation.Payload
+	Original       *db.WebSocketMessage
+	Result         *db.WebSocketMessage
+	Err            error
+	Payload        generation.Payload
+	InsertionPoint InsertionPoint
+	// Codec is the framing protocol the payload has been sent with
+	Codec    FrameCodec
 	Duration time.Duration
 	Issue    *db.Issue
 }
 
 type WebSocketScannerTask struct {
 	message        *db.WebSocketMessage
+	connection     *db.WebSocketConnection
+	codec          FrameCodec
 	payload        generation.Payload
 	insertionPoint InsertionPoint
 	options        options.HistoryItemScanOptions
@@ -46,6 +54,19 @@ func (f *WebSocketScanner) checkConfig() {
 	}
 }
 
+// messageConnection returns the connection the message has been sent over, along with its messages
+func (f *WebSocketScanner) messageConnection(message *db.WebSocketMessage) *db.WebSocketConnection {
+	if message.ConnectionID == 0 {
+		return nil
+	}
+	connection, err := db.Connection.GetWebSocketConnection(message.ConnectionID)
+	if err != nil {
+		log.Error().Err(err).Uint("connection", message.ConnectionID).Msg("Failed to get the WebSocket connection of the message")
+		return nil
+	}
+	return connection
+}
+
 // shouldLaunch checks if the generator should be launched according to the launch conditions
 func (f *WebSocketScanner) shouldLaunch(message *db.WebSocketMessage, generator *generation.PayloadGenerator, insertionPoint InsertionPoint, options options.HistoryItemScanOptions) bool {
 	if generator.Launch.Conditions == nil || len(generator.Launch.Conditions) == 0 {
@@ -83,6 +104,14 @@ func (f *WebSocketScanner) shouldLaunch(message *db.WebSocketMessage, generator
 			if condition.ResponseCondition.CheckWebsocketMessage(message) {
 				conditionsMet++
 			}
+
+		case generation.ResponseContentType:
+			log.Debug().Msg("Skipping response content type launch condition as WebSocket messages have no content type.")
+
+		case generation.TechStack:
+			if condition.MatchesTechStack(options.Fingerprints) {
+				conditionsMet++
+			}
 		}
 	}
 
@@ -93,10 +122,28 @@ func (f *WebSocketScanner) shouldLaunch(message *db.WebSocketMessage, generator
 	return conditionsMet == len(generator.Launch.Conditions)
 }
 
+// Run scans the message with the payload generators. When the scanner has no codec, it is selected from the connection
+// of the message, and when no insertion points are provided, they are taken from the application data carried by the
+// message frames.
 func (f *WebSocketScanner) Run(message *db.WebSocketMessage, payloadGenerators []*generation.PayloadGenerator, insertionPoints []InsertionPoint, options options.HistoryItemScanOptions) map[string][]WebSocketScannerResult {
 
 	var wg sync.WaitGroup
 	f.checkConfig()
+	connection := f.messageConnection(message)
+	codec := f.Codec
+	if codec == nil {
+		var messages []db.WebSocketMessage
+		if connection != nil {
+			messages = connection.Messages
+		}
+		codec = SelectFrameCodec(connection, messages)
+	}
+	if insertionPoints == nil {
+		insertionPoints = GetFramedWebSocketMessageInsertionPoints(message, codec)
+	}
+	insertionPoints = FilterInsertionPoints(insertionPoints, options.InsertionPointFilter)
+	insertionPoints = OrderInsertionPoints(insertionPoints, options.RandomSeed)
+	payloadGenerators = OrderPayloadGenerators(payloadGenerators, options.RandomSeed)
 	// Declare the channels
 	pendingTasks := make(chan WebSocketScannerTask, f.Concurrency)
 	defer close(pendingTasks)
@@ -116,9 +163,12 @@ func (f *WebSocketScanner) Run(message *db.WebSocketMessage, payloadGenerators [
 				for _, payload := range payloads {
 					wg.Add(1)
 					task := WebSocketScannerTask{
-						message: message,
-						payload: payload,
-						options: options,
+						message:        message,
+						connection:     connection,
+						codec:          codec,
+						payload:        payload,
+						insertionPoint: insertionPoint,
+						options:        options,
 					}
 					pendingTasks <- task
 				}
@@ -167,7 +217,7 @@ func (f *WebSocketScanner) worker(wg *sync.WaitGroup, pendingTasks chan WebSocke
 
 		// Fuzzing logic: Send the WebSocket message with the payload
 		startTime := time.Now()
-		responseMessage, err := f.fuzzWebSocketMessage(task.message, task.payload.Value)
+		responseMessage, err := f.fuzzWebSocketMessage(task.message, task.codec, task.insertionPoint, task.payload.Value)
 		if err != nil {
 			taskLog.Error().Err(err).Msg("Error sending WebSocket message")
 			wg.Done()
@@ -176,6 +226,8 @@ func (f *WebSocketScanner) worker(wg *sync.WaitGroup, pendingTasks chan WebSocke
 		result.Duration = time.Since(startTime)
 		result.Result = responseMessage
 		result.Payload = task.payload
+		result.InsertionPoint = task.insertionPoint
+		result.Codec = task.codec
 		result.Original = task.message
 		result.Err = err
 
@@ -189,9 +241,10 @@ func (f *WebSocketScanner) worker(wg *sync.WaitGroup, pendingTasks chan WebSocke
 
 		if vulnerable {
 			taskLog.Warn().Msg("Vulnerable")
-			fullDetails := fmt.Sprintf("The following payload was used: %s\n\n%s", task.payload.Value, details)
-			createdIssue, err := db.CreateIssueFromHistoryAndTemplate(nil, issueCode, fullDetails, confidence, "", &f.WorkspaceID, &task.options.TaskID, &task.options.TaskJobID)
-			if err != nil {
+			fullDetails := fmt.Sprintf("The following payload was used: %s\n\n%s", task.payload.Description(), details)
+			if task.connection == nil {
+				taskLog.Error().Str("code", string(issueCode)).Msg("Cannot create the issue as the WebSocket connection of the message is unknown")
+			} else if createdIssue, err := db.CreateIssueFromWebSocketConnectionAndTemplate(task.connection, issueCode, fullDetails, confidence, "", &f.WorkspaceID, &task.options.TaskID, &task.options.TaskJobID); err != nil {
 				taskLog.Error().Str("code", string(issueCode)).Interface("result", result).Err(err).Msg("Error creating issue")
 			} else if createdIssue.ID != 0 {
 				result.Issue = &createdIssue
@@ -208,19 +261,30 @@ func (f *WebSocketScanner) worker(wg *sync.WaitGroup, pendingTasks chan WebSocke
 	}
 }
 
-func (f *WebSocketScanner) fuzzWebSocketMessage(message *db.WebSocketMessage, payload string) (*db.WebSocketMessage, error) {
+func (f *WebSocketScanner) fuzzWebSocketMessage(message *db.WebSocketMessage, codec FrameCodec, insertionPoint InsertionPoint, payload string) (*db.WebSocketMessage, error) {
 	// Implement the logic to send the WebSocket message with the fuzzed payload
 	// This is a placeholder implementation
-	fuzzedMessage := *message
-	fuzzedMessage.PayloadData = payload
+	fuzzedMessage, err := CreateModifiedFramedWebSocketMessage(message, codec, insertionPoint, payload)
+	if err != nil {
+		return nil, err
+	}
 	// Send the fuzzed WebSocket message and receive the response
 	// You need to implement the actual WebSocket communication here
-	return &fuzzedMessage, nil
+	return fuzzedMessage, nil
 }
 
 func (f *WebSocketScanner) EvaluateResult(result WebSocketScannerResult) (bool, string, int, error) {
 	// Evaluate the result to determine if the payload caused a vulnerability
 	// This is a placeholder implementation
+	for _, method := range result.Payload.DetectionMethods {
+		if method.TimeBased == nil {
+			continue
+		}
+		if vulnerable, details, confidence := EvaluateTimeBased(method.TimeBased, result.Duration, f.timeBasedSampler(result), GetTimeBasedSettings()); vulnerable {
+			return true, details, confidence, nil
+		}
+	}
+
 	vulnerable := false
 	details := ""
 	confidence := 0
@@ -233,3 +297,18 @@ func (f *WebSocketScanner) EvaluateResult(result WebSocketScannerResult) (bool,
 
 	return vulnerable, details, confidence, nil
 }
+
+// timeBasedSampler sends the original message and the one with the payload again, timing how long each one takes
+func (f *WebSocketScanner) timeBasedSampler(result WebSocketScannerResult) TimeBasedSampler {
+	send := func(value string) func() (time.Duration, error) {
+		return func() (time.Duration, error) {
+			startTime := time.Now()
+			_, err := f.fuzzWebSocketMessage(result.Original, result.Codec, result.InsertionPoint, value)
+			return time.Since(startTime), err
+		}
+	}
+	return TimeBasedSampler{
+		Original:    send(result.InsertionPoint.Value),
+		WithPayload: send(result.Payload.Value),
+	}
+}
@@ -0,0 +1,119 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const regexExclusionPrefix = "regex:"
+
+// ErrURLExcluded is returned when a request is attempted against an excluded URL
+var ErrURLExcluded = errors.New("url has been excluded from the scan")
+
+// URLExclusions holds compiled URL exclusion patterns. Patterns are globs (where * matches any sequence of characters)
+// matched against the full URL and against its path, or regular expressions when prefixed with "regex:"
+type URLExclusions struct {
+	patterns []string
+	matchers []*regexp.Regexp
+}
+
+// NewURLExclusions compiles the provided exclusion patterns
+func NewURLExclusions(patterns []string) (*URLExclusions, error) {
+	exclusions := &URLExclusions{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		var expression string
+		if strings.HasPrefix(pattern, regexExclusionPrefix) {
+			expression = strings.TrimPrefix(pattern, regexExclusionPrefix)
+		} else {
+			expression = globToRegex(pattern)
+		}
+		matcher, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclusion pattern %s: %w", pattern, err)
+		}
+		exclusions.patterns = append(exclusions.patterns, pattern)
+		exclusions.matchers = append(exclusions.matchers, matcher)
+	}
+	return exclusions, nil
+}
+
+func globToRegex(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// IsEmpty returns true when there are no exclusion patterns
+func (e *URLExclusions) IsEmpty() bool {
+	return e == nil || len(e.matchers) == 0
+}
+
+// Match returns the pattern matching the provided URL, if any
+func (e *URLExclusions) Match(rawURL string) (string, bool) {
+	if e.IsEmpty() {
+		return "", false
+	}
+	candidates := []string{rawURL}
+	if u, err := url.Parse(rawURL); err == nil {
+		candidates = append(candidates, u.Path)
+		if u.RawQuery != "" {
+			candidates = append(candidates, u.Path+"?"+u.RawQuery)
+		}
+	}
+	for i, matcher := range e.matchers {
+		for _, candidate := range candidates {
+			if matcher.MatchString(candidate) {
+				return e.patterns[i], true
+			}
+		}
+	}
+	return "", false
+}
+
+// IsExcluded checks if the provided URL matches any of the exclusion patterns
+func (e *URLExclusions) IsExcluded(rawURL string) bool {
+	_, excluded := e.Match(rawURL)
+	return excluded
+}
+
+type exclusionRoundTripper struct {
+	exclusions *URLExclusions
+	next       http.RoundTripper
+}
+
+func (t *exclusionRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if pattern, excluded := t.exclusions.Match(req.URL.String()); excluded {
+		return nil, fmt.Errorf("%w: %s matches %s", ErrURLExcluded, req.URL.String(), pattern)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// WrapTransport returns a transport that refuses to send requests to excluded URLs
+func (e *URLExclusions) WrapTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if e.IsEmpty() {
+		return next
+	}
+	return &exclusionRoundTripper{exclusions: e, next: next}
+}
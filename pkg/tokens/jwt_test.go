@@ -1,7 +1,10 @@
 package tokens
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -108,3 +111,46 @@ func TestDecodeAndVerifyJWT_Failure_ExpiredToken(t *testing.T) {
 	assert.False(t, success, "The verification should fail with an expired token.")
 	assert.Nil(t, parsedToken, "The parsed token should be nil when the token is expired.")
 }
+
+const weakSecretsWordlist = "admin\npassword\nchangeme\nsupersecret\nyour-256-bit-secret\n"
+
+func TestCrackJWTFromReader(t *testing.T) {
+	claims := jwt.MapClaims{
+		"foo": "bar",
+		"exp": time.Now().Add(-time.Hour).Unix(), // Expired tokens can still be cracked
+	}
+	for _, method := range []jwt.SigningMethod{jwt.SigningMethodHS256, jwt.SigningMethodHS384, jwt.SigningMethodHS512} {
+		token, err := jwt.NewWithClaims(method, claims).SignedString([]byte("supersecret"))
+		assert.NoError(t, err)
+
+		result, err := CrackJWTFromReader(token, strings.NewReader(weakSecretsWordlist), 2)
+		assert.NoError(t, err)
+		assert.True(t, result.Found, "The secret should be found for %s", method.Alg())
+		assert.Equal(t, "supersecret", result.Secret)
+		assert.Greater(t, result.Attempts, 0)
+	}
+
+	token := generateTestJWT("not-in-the-wordlist")
+	result, err := CrackJWTFromReader(token, strings.NewReader(weakSecretsWordlist), 2)
+	assert.NoError(t, err)
+	assert.False(t, result.Found)
+	assert.Equal(t, 5, result.Attempts)
+}
+
+func TestCrackJWTFromReaderNotHMACSigned(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"foo": "bar"}).SignedString(key)
+	assert.NoError(t, err)
+
+	assert.False(t, IsHMACSignedJWT(token))
+	assert.True(t, IsHMACSignedJWT(generateTestJWT("secret")))
+
+	_, err = CrackJWTFromReader(token, strings.NewReader(weakSecretsWordlist), 2)
+	assert.ErrorIs(t, err, ErrNotHMACSigned)
+	assert.Nil(t, CrackJWT(token, "", 2, true))
+}
+
+func TestCrackJWTMissingWordlist(t *testing.T) {
+	assert.Nil(t, CrackJWT(generateTestJWT("secret"), "/nonexistent/jwt-wordlist.txt", 2, false))
+}
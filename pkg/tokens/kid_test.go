@@ -0,0 +1,66 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetKidInjectionPayloads(t *testing.T) {
+	payloads := GetKidInjectionPayloads("sukyan-secret")
+	assert.NotEmpty(t, payloads)
+
+	var hasTraversal, hasSQLi bool
+	for _, payload := range payloads {
+		switch payload.Technique {
+		case KidInjectionPathTraversal:
+			hasTraversal = true
+			assert.True(t, strings.Contains(payload.Kid, "/dev/null") || strings.Contains(payload.Kid, "/proc/"))
+		case KidInjectionSQLi:
+			hasSQLi = true
+			assert.Contains(t, payload.Kid, "UNION SELECT 'sukyan-secret'")
+			assert.Equal(t, []byte("sukyan-secret"), payload.Key)
+		}
+	}
+	assert.True(t, hasTraversal)
+	assert.True(t, hasSQLi)
+}
+
+func TestForgeJWTWithKid(t *testing.T) {
+	original := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user", "role": "guest"})
+	original.Header["kid"] = "key-1"
+	original.Header["typ"] = "JWT"
+	token, err := original.SignedString([]byte("unknown-server-secret"))
+	assert.Nil(t, err)
+
+	for _, payload := range GetKidInjectionPayloads("sukyan-secret") {
+		forged, err := ForgeJWTWithKid(token, payload.Kid, payload.Key)
+		assert.Nil(t, err)
+		assert.NotEqual(t, token, forged)
+
+		// A server resolving the kid to the predictable key accepts the forged token
+		parsed, err := jwt.Parse(forged, func(parsed *jwt.Token) (interface{}, error) {
+			assert.Equal(t, payload.Kid, parsed.Header["kid"])
+			return payload.Key, nil
+		})
+		assert.Nil(t, err)
+		assert.True(t, parsed.Valid)
+		claims := parsed.Claims.(jwt.MapClaims)
+		assert.Equal(t, "user", claims["sub"])
+		assert.Equal(t, "guest", claims["role"])
+		assert.Equal(t, "JWT", parsed.Header["typ"])
+
+		// While the original secret cannot verify it
+		_, err = jwt.Parse(forged, func(parsed *jwt.Token) (interface{}, error) {
+			return []byte("unknown-server-secret"), nil
+		})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestForgeJWTWithKidInvalidToken(t *testing.T) {
+	_, err := ForgeJWTWithKid("not-a-token", "/dev/null", []byte(""))
+	assert.NotNil(t, err)
+}
@@ -5,7 +5,9 @@ import (
 	"strings"
 
 	"github.com/pyneda/sukyan/db"
+	"github.com/pyneda/sukyan/lib"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
 )
 
 type JWTCrackResult struct {
@@ -24,13 +26,32 @@ func CrackJWTAndCreateIssue(history *db.History, token *db.JsonWebToken) (*JWTCr
 		TokenModel: token,
 	}
 
-	// by now using the embedded wordlist is the only option
-	crackResult := CrackJWT(token.Token, "", 5, true)
+	// Only HMAC secrets can be brute forced, so other tokens are just flagged as tested
+	if !IsHMACSignedJWT(token.Token) {
+		result.TokenModel.TestedEmbeddedWordlist = true
+		if err := db.Connection.UpdateJWT(token.ID, result.TokenModel); err != nil {
+			result.Error = fmt.Errorf("failed to update JWT: %w", err)
+			return result, result.Error
+		}
+		return result, nil
+	}
+
+	concurrency := viper.GetInt("tokens.jwt.concurrency")
+	crackResult := CrackJWT(token.Token, "", concurrency, true)
 	if crackResult == nil {
-		result.Error = fmt.Errorf("failed to crack JWT, received nil response for: %s", token)
+		result.Error = fmt.Errorf("failed to crack JWT, received nil response for: %s", token.Token)
 		return result, result.Error
 	}
 
+	if wordlist := viper.GetString("tokens.jwt.wordlist"); !crackResult.Found && wordlist != "" {
+		log.Info().Uint("token", token.ID).Str("wordlist", wordlist).Msg("Secret not found in the embedded wordlist, trying the configured one")
+		if wordlistResult := CrackJWT(token.Token, wordlist, concurrency, false); wordlistResult != nil {
+			wordlistResult.Attempts += crackResult.Attempts
+			wordlistResult.Duration += crackResult.Duration
+			crackResult = wordlistResult
+		}
+	}
+
 	result.Found = crackResult.Found
 	result.Secret = crackResult.Secret
 	result.ElapsedTime = crackResult.Duration.Seconds()
@@ -52,7 +73,7 @@ func CrackJWTAndCreateIssue(history *db.History, token *db.JsonWebToken) (*JWTCr
 		if err != nil {
 			log.Error().Err(err).
 				Str("token", token.Token).
-				Str("secret", result.Secret).
+				Str("secret", lib.RedactSecret(result.Secret)).
 				Msg("Failed to create issue for cracked JWT")
 			result.Error = fmt.Errorf("failed to create issue: %w", err)
 			return result, result.Error
@@ -61,7 +82,7 @@ func CrackJWTAndCreateIssue(history *db.History, token *db.JsonWebToken) (*JWTCr
 		result.Issue = &issue
 		log.Info().
 			Str("token", token.Token).
-			Str("secret", result.Secret).
+			Str("secret", lib.RedactSecret(result.Secret)).
 			Uint("issue_id", issue.ID).
 			Msg("Created issue for cracked JWT")
 	}
@@ -70,7 +91,7 @@ func CrackJWTAndCreateIssue(history *db.History, token *db.JsonWebToken) (*JWTCr
 	result.TokenModel.Cracked = result.Found
 	result.TokenModel.Secret = result.Secret
 	if err := db.Connection.UpdateJWT(token.ID, result.TokenModel); err != nil {
-		log.Error().Err(err).Uint("token", token.ID).Bool("success", result.Found).Str("secret", result.Secret).Msg("Failed to update JWT with crack result")
+		log.Error().Err(err).Uint("token", token.ID).Bool("success", result.Found).Str("secret", lib.RedactSecret(result.Secret)).Msg("Failed to update JWT with crack result")
 		result.Error = fmt.Errorf("failed to update JWT with crack results: %w", err)
 		return result, result.Error
 	}
@@ -1,11 +1,14 @@
 package web
 
 import (
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/proto"
 	// "github.com/pyneda/sukyan/lib"
 	"github.com/rs/zerolog/log"
-	"time"
 )
 
 type InputNameValue struct {
@@ -72,6 +75,112 @@ var predefinedNameValues = []InputNameValue{
 	{Name: "securityAnswer", Value: "DefaultAnswer"},
 }
 
+// ExtractForms parses the forms found in the provided HTML
+func ExtractForms(html string) ([]Form, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+	forms := []Form{}
+	doc.Find("form").Each(func(i int, s *goquery.Selection) {
+		form := Form{
+			Action: strings.TrimSpace(s.AttrOr("action", "")),
+			Method: strings.ToUpper(strings.TrimSpace(s.AttrOr("method", "GET"))),
+		}
+		s.Find("button, input[type=submit], input[type=image]").Each(func(i int, submit *goquery.Selection) {
+			submitType := strings.ToLower(submit.AttrOr("type", "submit"))
+			if goquery.NodeName(submit) == "button" && submitType != "submit" {
+				return
+			}
+			for _, text := range []string{submit.Text(), submit.AttrOr("value", ""), submit.AttrOr("alt", ""), submit.AttrOr("formaction", "")} {
+				if text = strings.TrimSpace(text); text != "" {
+					form.SubmitTexts = append(form.SubmitTexts, text)
+				}
+			}
+		})
+		s.Find("input, textarea, select").Each(func(i int, field *goquery.Selection) {
+			name := field.AttrOr("name", "")
+			if name == "" {
+				return
+			}
+			fieldType := strings.ToLower(field.AttrOr("type", "text"))
+			switch goquery.NodeName(field) {
+			case "textarea":
+				fieldType = "textarea"
+			case "select":
+				fieldType = "select"
+			}
+			if fieldType == "submit" || fieldType == "button" || fieldType == "image" || fieldType == "reset" {
+				return
+			}
+			value := field.AttrOr("value", "")
+			if fieldType == "textarea" {
+				value = field.Text()
+			} else if fieldType == "select" {
+				option := field.Find("option[selected]").First()
+				if option.Length() == 0 {
+					option = field.Find("option").First()
+				}
+				value = option.AttrOr("value", strings.TrimSpace(option.Text()))
+			}
+			form.Fields = append(form.Fields, FormField{Name: name, Type: fieldType, Value: value})
+		})
+		forms = append(forms, form)
+	})
+	return forms, nil
+}
+
+// FormFieldValue returns the placeholder value to fill a field with, based on its name or, failing that, on its type
+func FormFieldValue(name, fieldType string) (string, bool) {
+	for _, v := range predefinedNameValues {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	if fieldType == "textarea" {
+		return defaultTextareaValue, true
+	}
+	for _, v := range predefinedTypeValues {
+		if v.Type == fieldType {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+// FilledValues returns the values the form would be submitted with when auto filled. Hidden fields and selects keep
+// their value, as they usually hold tokens or options that the server expects unchanged.
+func (f Form) FilledValues() map[string]string {
+	values := make(map[string]string)
+	for _, field := range f.Fields {
+		if (field.Type == "hidden" || field.Type == "select") && field.Value != "" {
+			values[field.Name] = field.Value
+			continue
+		}
+		if value, ok := FormFieldValue(field.Name, field.Type); ok {
+			values[field.Name] = value
+		} else {
+			values[field.Name] = field.Value
+		}
+	}
+	return values
+}
+
+// IsDestructive checks if the form action or its submit texts match any of the patterns, which is used to avoid
+// submitting forms that might delete data or end the session
+func (f Form) IsDestructive(patterns []string) (bool, string) {
+	candidates := append([]string{f.Action}, f.SubmitTexts...)
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(candidate)
+		for _, pattern := range patterns {
+			if pattern != "" && strings.Contains(candidate, strings.ToLower(pattern)) {
+				return true, pattern
+			}
+		}
+	}
+	return false, ""
+}
+
 func SubmitForm(form *rod.Element, page *rod.Page) {
 	submit, err := form.Element("[type=submit]")
 	// page.Activate()
@@ -132,24 +241,15 @@ func AutoFillInput(input *rod.Element, page *rod.Page) {
 	// 	input.MustSetFiles("/path/to/default/file")
 	// }
 
-	valuesByName := make(map[string]string)
-	for _, v := range predefinedNameValues {
-		valuesByName[v.Name] = v.Value
-	}
-	valuesByType := make(map[string]string)
-	for _, v := range predefinedTypeValues {
-		valuesByType[v.Type] = v.Value
-	}
-
 	// Try to get the value based on the input's name or, failing that, based on its type
-	var value string
-	var exists bool
+	var inputName, inputType string
 	if name != nil {
-		value, exists = valuesByName[*name]
+		inputName = *name
 	}
-	if !exists && typeAttr != nil {
-		value, exists = valuesByType[*typeAttr]
+	if typeAttr != nil {
+		inputType = *typeAttr
 	}
+	value, exists := FormFieldValue(inputName, inputType)
 
 	// If a predefined value was found, set the input value
 	if exists {
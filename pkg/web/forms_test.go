@@ -0,0 +1,106 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleFormsHTML = `<html><body>
+<form action="/login" method="post">
+	<input type="hidden" name="csrf_token" value="abc123">
+	<input type="text" name="username">
+	<input type="password" name="password">
+	<input type="checkbox" name="remember">
+	<input type="submit" value="Sign in">
+</form>
+<form action="/search">
+	<input type="search" name="q">
+	<select name="category">
+		<option value="all">All</option>
+		<option value="books" selected>Books</option>
+	</select>
+	<textarea name="notes"></textarea>
+	<input type="text" placeholder="Unnamed">
+	<button type="button">Clear</button>
+	<button>Search</button>
+</form>
+<form action="/account/delete" method="POST">
+	<input type="hidden" name="id" value="1">
+	<button type="submit">Delete account</button>
+</form>
+<form action="/session" method="post">
+	<input type="image" src="/exit.png" alt="Log out">
+</form>
+</body></html>`
+
+func TestExtractForms(t *testing.T) {
+	forms, err := ExtractForms(sampleFormsHTML)
+	assert.Nil(t, err)
+	assert.Len(t, forms, 4)
+
+	assert.Equal(t, "/login", forms[0].Action)
+	assert.Equal(t, "POST", forms[0].Method)
+	assert.Equal(t, []string{"Sign in"}, forms[0].SubmitTexts)
+	assert.Equal(t, []FormField{
+		{Name: "csrf_token", Type: "hidden", Value: "abc123"},
+		{Name: "username", Type: "text"},
+		{Name: "password", Type: "password"},
+		{Name: "remember", Type: "checkbox"},
+	}, forms[0].Fields)
+
+	assert.Equal(t, "/search", forms[1].Action)
+	assert.Equal(t, "GET", forms[1].Method)
+	assert.Equal(t, []string{"Search"}, forms[1].SubmitTexts)
+	assert.Equal(t, []FormField{
+		{Name: "q", Type: "search"},
+		{Name: "category", Type: "select", Value: "books"},
+		{Name: "notes", Type: "textarea"},
+	}, forms[1].Fields)
+
+	assert.Equal(t, []string{"Log out"}, forms[3].SubmitTexts)
+}
+
+func TestFormFilledValues(t *testing.T) {
+	forms, err := ExtractForms(sampleFormsHTML)
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]string{
+		"csrf_token": "abc123",
+		"username":   "admin",
+		"password":   "password",
+		"remember":   "true",
+	}, forms[0].FilledValues())
+	assert.Equal(t, map[string]string{
+		"q":        "defaultSearch",
+		"category": "books",
+		"notes":    defaultTextareaValue,
+	}, forms[1].FilledValues())
+}
+
+func TestFormIsDestructive(t *testing.T) {
+	forms, err := ExtractForms(sampleFormsHTML)
+	assert.Nil(t, err)
+	patterns := []string{"delete", "logout", "log out"}
+
+	tests := []struct {
+		form            Form
+		expected        bool
+		expectedPattern string
+	}{
+		{form: forms[0], expected: false},
+		{form: forms[1], expected: false},
+		{form: forms[2], expected: true, expectedPattern: "delete"},
+		{form: forms[3], expected: true, expectedPattern: "log out"},
+		{form: Form{Action: "/Logout.php"}, expected: true, expectedPattern: "logout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.form.Action, func(t *testing.T) {
+			destructive, pattern := tt.form.IsDestructive(patterns)
+			assert.Equal(t, tt.expected, destructive)
+			assert.Equal(t, tt.expectedPattern, pattern)
+		})
+	}
+	destructive, _ := Form{Action: "/account/delete"}.IsDestructive(nil)
+	assert.False(t, destructive)
+}
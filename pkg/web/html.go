@@ -1,8 +1,18 @@
 package web
 
-// Form : Represents an HTML form
+// Form : Represents an HTML form and the fields that would be sent when submitting it
 type Form struct {
-	html string
+	Action      string
+	Method      string
+	SubmitTexts []string
+	Fields      []FormField
+}
+
+// FormField : Represents a named field of a form
+type FormField struct {
+	Name  string
+	Type  string
+	Value string
 }
 
 // Button : Represents an HTML button